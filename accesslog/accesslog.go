@@ -0,0 +1,116 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Entry is a request handled by the engine, parsed from the JSON
+// summary the engine logs for each request.
+type Entry struct {
+	Method string
+	Path   string
+	Status int
+	// Duration is the time taken to handle the request, in
+	// milliseconds, or -1 if not logged.
+	Duration float64
+	Resource string
+}
+
+// summary holds the fields of the engine request summary. Alternative
+// field names are accepted, as they differ between engine versions.
+type summary struct {
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	Uri            string      `json:"uri"`
+	StatusCode     json.Number `json:"statusCode"`
+	Status         json.Number `json:"status"`
+	Duration       json.Number `json:"duration"`
+	DurationMillis json.Number `json:"durationMillis"`
+	ResourceId     string      `json:"resourceId"`
+	Resource       string      `json:"resource"`
+}
+
+// Parse extracts the request summary from a line of engine output. The
+// summary is a JSON object, which may follow a log prefix, such as the
+// timestamp and level. If the line is not a request summary, false is
+// returned.
+func Parse(line string) (*Entry, bool) {
+	start := strings.Index(line, "{")
+	if start < 0 {
+		return nil, false
+	}
+	var s summary
+	if err := json.Unmarshal([]byte(line[start:]), &s); err != nil {
+		return nil, false
+	}
+	if s.Method == "" {
+		return nil, false
+	}
+	path := s.Path
+	if path == "" && s.Uri != "" {
+		if u, err := url.Parse(s.Uri); err == nil {
+			path = u.Path
+		}
+	}
+	entry := &Entry{
+		Method:   strings.ToUpper(s.Method),
+		Path:     path,
+		Duration: -1,
+		Resource: firstNonEmpty(s.ResourceId, s.Resource),
+	}
+	if status, err := strconv.Atoi(firstNonEmpty(s.StatusCode.String(), s.Status.String())); err == nil {
+		entry.Status = status
+	}
+	if duration, err := strconv.ParseFloat(firstNonEmpty(s.Duration.String(), s.DurationMillis.String()), 64); err == nil {
+		entry.Duration = duration
+	}
+	return entry, true
+}
+
+// ParseStatusFilter parses a status filter, which is either an exact
+// status code, such as '404', or a class of status codes, such as '5xx'.
+func ParseStatusFilter(filter string) (func(status int) bool, error) {
+	f := strings.ToLower(filter)
+	if len(f) == 3 && strings.HasSuffix(f, "xx") && f[0] >= '1' && f[0] <= '5' {
+		class := int(f[0] - '0')
+		return func(status int) bool {
+			return status/100 == class
+		}, nil
+	}
+	code, err := strconv.Atoi(f)
+	if err != nil || code < 100 || code > 599 {
+		return nil, fmt.Errorf("invalid status filter: %s - must be a status code, such as 404, or a class, such as 5xx", filter)
+	}
+	return func(status int) bool {
+		return status == code
+	}, nil
+}
+
+func firstNonEmpty(candidates ...string) string {
+	for _, c := range candidates {
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package accesslog
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   *Entry
+		wantOk bool
+	}{
+		{
+			name:   "summary with log prefix",
+			line:   `2024-05-01 10:00:00 INFO  i.g.i.s.SummaryLogger - {"method":"get","path":"/orders/1","statusCode":200,"duration":12.5,"resourceId":"orders-get"}`,
+			want:   &Entry{Method: "GET", Path: "/orders/1", Status: 200, Duration: 12.5, Resource: "orders-get"},
+			wantOk: true,
+		},
+		{
+			name:   "alternative field names",
+			line:   `{"method":"POST","uri":"http://localhost:8080/orders?x=1","status":"503","durationMillis":"7"}`,
+			want:   &Entry{Method: "POST", Path: "/orders", Status: 503, Duration: 7},
+			wantOk: true,
+		},
+		{
+			name:   "missing duration",
+			line:   `{"method":"GET","path":"/","statusCode":404}`,
+			want:   &Entry{Method: "GET", Path: "/", Status: 404, Duration: -1},
+			wantOk: true,
+		},
+		{
+			name:   "plain log line",
+			line:   "2024-05-01 10:00:00 INFO  Mock engine up and running",
+			wantOk: false,
+		},
+		{
+			name:   "json without method",
+			line:   `{"message":"hello"}`,
+			wantOk: false,
+		},
+		{
+			name:   "invalid json",
+			line:   `config {not json}`,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.line)
+			if ok != tt.wantOk {
+				t.Fatalf("Parse() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		paths    []string
+		entry    Entry
+		want     bool
+	}{
+		{name: "no filters", entry: Entry{Path: "/", Status: 200}, want: true},
+		{name: "status class match", statuses: []string{"5xx"}, entry: Entry{Path: "/", Status: 503}, want: true},
+		{name: "status class mismatch", statuses: []string{"5xx"}, entry: Entry{Path: "/", Status: 404}, want: false},
+		{name: "exact status match", statuses: []string{"4xx", "200"}, entry: Entry{Path: "/", Status: 200}, want: true},
+		{name: "path glob match", paths: []string{"/orders/**"}, entry: Entry{Path: "/orders/1/items", Status: 200}, want: true},
+		{name: "path glob mismatch", paths: []string{"/orders/**"}, entry: Entry{Path: "/users/1", Status: 200}, want: false},
+		{name: "status and path", statuses: []string{"5xx"}, paths: []string{"/orders/*"}, entry: Entry{Path: "/orders/1", Status: 200}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.statuses, tt.paths)
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+			if got := f.Matches(&tt.entry); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{"6xx", "abc", "99", "5x"} {
+		if _, err := ParseStatusFilter(filter); err == nil {
+			t.Errorf("ParseStatusFilter(%q) expected error", filter)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	entry := &Entry{Method: "GET", Path: "/orders/1", Status: 200, Duration: 3.25, Resource: "orders-get"}
+	want := "GET     /orders/1                                   200      3.2ms  orders-get"
+	if got := Format(entry, false); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+	if got := Format(entry, true); got == want {
+		t.Errorf("Format() with colour should include escape codes")
+	}
+}
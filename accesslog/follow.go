@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesslog
+
+import (
+	"bufio"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const followPollInterval = 500 * time.Millisecond
+
+var logger = logging.GetLogger()
+
+// Follow passes each line of the file to onLine, then polls the file for
+// new lines, indefinitely. If the file shrinks, such as when it is rotated,
+// it is reopened and read from the start.
+func Follow(path string, onLine func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			onLine(partial + strings.TrimRight(line, "\r\n"))
+			partial = ""
+			continue
+		} else if err != io.EOF {
+			return fmt.Errorf("failed to read log file: %v", err)
+		}
+		partial += line
+
+		time.Sleep(followPollInterval)
+		if info, err := os.Stat(path); err == nil && info.Size() < offset {
+			logger.Debugf("log file %s was rotated - reopening", path)
+			_ = file.Close()
+			if file, err = os.Open(path); err != nil {
+				return fmt.Errorf("failed to reopen log file: %v", err)
+			}
+			reader.Reset(file)
+			offset = 0
+			partial = ""
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesslog
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"regexp"
+	"strconv"
+)
+
+const (
+	colourReset  = "\033[0m"
+	colourRed    = "\033[31m"
+	colourGreen  = "\033[32m"
+	colourYellow = "\033[33m"
+	colourCyan   = "\033[36m"
+	colourGrey   = "\033[90m"
+)
+
+// Filter selects the entries to render.
+type Filter struct {
+	statuses []func(status int) bool
+	paths    []*regexp.Regexp
+}
+
+// NewFilter builds a filter from status filters, such as '5xx' or '404',
+// and path patterns, such as '/orders/**'. An entry must match any of the
+// status filters, if set, and any of the path patterns, if set.
+func NewFilter(statuses []string, paths []string) (*Filter, error) {
+	f := &Filter{}
+	for _, status := range statuses {
+		matcher, err := ParseStatusFilter(status)
+		if err != nil {
+			return nil, err
+		}
+		f.statuses = append(f.statuses, matcher)
+	}
+	compiled, err := stringutil.CompilePathPatterns(paths)
+	if err != nil {
+		return nil, err
+	}
+	f.paths = compiled
+	return f, nil
+}
+
+// Matches returns true if the entry should be rendered.
+func (f *Filter) Matches(entry *Entry) bool {
+	if len(f.paths) > 0 && !stringutil.MatchesAny(f.paths, entry.Path) {
+		return false
+	}
+	if len(f.statuses) == 0 {
+		return true
+	}
+	for _, matcher := range f.statuses {
+		if matcher(entry.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns the column headings for rendered entries.
+func Header(colour bool) string {
+	return paint(fmt.Sprintf("%-7s %-40s %6s %10s  %s", "METHOD", "PATH", "STATUS", "LATENCY", "RESOURCE"), colourGrey, colour)
+}
+
+// Format renders the entry as a single line, with columns aligned
+// to the header. Status codes are coloured by class if colour is true.
+func Format(entry *Entry, colour bool) string {
+	status := "-"
+	if entry.Status > 0 {
+		status = strconv.Itoa(entry.Status)
+	}
+	resource := entry.Resource
+	if resource == "" {
+		resource = "-"
+	}
+	return fmt.Sprintf("%s %-40s %s %10s  %s",
+		paint(fmt.Sprintf("%-7s", entry.Method), colourCyan, colour),
+		entry.Path,
+		paint(fmt.Sprintf("%6s", status), statusColour(entry.Status), colour),
+		formatDuration(entry.Duration),
+		paint(resource, colourGrey, colour),
+	)
+}
+
+func formatDuration(millis float64) string {
+	if millis < 0 {
+		return "-"
+	}
+	if millis < 10 {
+		return strconv.FormatFloat(millis, 'f', 1, 64) + "ms"
+	}
+	return strconv.FormatFloat(millis, 'f', 0, 64) + "ms"
+}
+
+func statusColour(status int) string {
+	switch {
+	case status >= 500:
+		return colourRed
+	case status >= 400:
+		return colourYellow
+	case status >= 200 && status < 400:
+		return colourGreen
+	default:
+		return ""
+	}
+}
+
+func paint(s string, colour string, enabled bool) string {
+	if !enabled || colour == "" {
+		return s
+	}
+	return colour + s + colourReset
+}
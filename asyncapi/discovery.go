@@ -0,0 +1,47 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/logging"
+	"path/filepath"
+)
+
+var logger = logging.GetLogger()
+
+// DiscoverAsyncApiSpecs finds JSON and YAML AsyncAPI documents
+// within the given directory. It returns fully qualified paths
+// to the files discovered.
+func DiscoverAsyncApiSpecs(configDir string) []string {
+	var specs []string
+
+	candidates := fileutil.FindFilesWithExtension(configDir, ".yaml", ".yml", ".json")
+	for _, candidate := range candidates {
+		fullyQualifiedPath := filepath.Join(configDir, candidate)
+		document, err := load(fullyQualifiedPath)
+		if err != nil {
+			logger.Debugf("skipping file: %v", err)
+			continue
+		}
+		if _, found := document["asyncapi"]; found {
+			specs = append(specs, fullyQualifiedPath)
+		}
+	}
+
+	return specs
+}
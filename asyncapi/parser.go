@@ -0,0 +1,225 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/jsonschema"
+	"os"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// Spec is the subset of an AsyncAPI document needed to generate
+// configuration for the messaging plugins.
+type Spec struct {
+	Version string
+
+	// Protocol is the protocol of the first server, such as 'kafka'
+	// or 'sqs', or empty if no servers are declared.
+	Protocol string
+	Channels []Channel
+}
+
+type Channel struct {
+	// Name is the address of the channel, such as a topic or queue name.
+	Name     string
+	Messages []Message
+}
+
+type Message struct {
+	Name        string
+	ContentType string
+
+	// Payload is an example payload, either declared in the document
+	// or generated from the payload schema.
+	Payload interface{}
+}
+
+// IsJson determines if the payload is sent as JSON.
+func (m Message) IsJson() bool {
+	return m.ContentType == "" || strings.Contains(m.ContentType, "json")
+}
+
+// Parse reads the channels and their messages from an AsyncAPI 2.x
+// or 3.x document. Only refs within the document are resolved.
+func Parse(specFilePath string) (*Spec, error) {
+	document, err := load(specFilePath)
+	if err != nil {
+		return nil, err
+	}
+	version, _ := document["asyncapi"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("not an AsyncAPI document: %v", specFilePath)
+	}
+	p := &parser{document: document, resolve: jsonschema.LocalResolver(document)}
+	p.defaultContentType, _ = document["defaultContentType"].(string)
+
+	spec := &Spec{Version: version, Protocol: p.firstServerProtocol()}
+	channels, _ := document["channels"].(map[string]interface{})
+	for _, id := range sortedKeys(channels) {
+		channel, _ := p.deref(channels[id]).(map[string]interface{})
+		if channel == nil {
+			continue
+		}
+		var parsed Channel
+		if strings.HasPrefix(version, "2.") {
+			parsed = p.parseChannelV2(id, channel)
+		} else {
+			parsed = p.parseChannelV3(id, channel)
+		}
+		spec.Channels = append(spec.Channels, parsed)
+	}
+	return spec, nil
+}
+
+func load(specFilePath string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(specFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading AsyncAPI document: %v: %v", specFilePath, err)
+	}
+	jsonContent, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing AsyncAPI document: %v: %v", specFilePath, err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(jsonContent, &document); err != nil {
+		return nil, fmt.Errorf("error parsing AsyncAPI document: %v: %v", specFilePath, err)
+	}
+	return document, nil
+}
+
+type parser struct {
+	document           map[string]interface{}
+	resolve            jsonschema.Resolver
+	defaultContentType string
+}
+
+// deref follows the ref of the node, if it has one.
+func (p *parser) deref(node interface{}) interface{} {
+	for i := 0; i < 10; i++ {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return node
+		}
+		node = p.resolve(ref)
+	}
+	return node
+}
+
+func (p *parser) firstServerProtocol() string {
+	servers, _ := p.document["servers"].(map[string]interface{})
+	for _, name := range sortedKeys(servers) {
+		if server, ok := p.deref(servers[name]).(map[string]interface{}); ok {
+			if protocol, ok := server["protocol"].(string); ok {
+				return strings.ToLower(protocol)
+			}
+		}
+	}
+	return ""
+}
+
+// parseChannelV2 reads the messages of a 2.x channel. Messages of the
+// subscribe operation, which the application sends, are listed first.
+func (p *parser) parseChannelV2(name string, channel map[string]interface{}) Channel {
+	parsed := Channel{Name: name}
+	for _, action := range []string{"subscribe", "publish"} {
+		operation, _ := p.deref(channel[action]).(map[string]interface{})
+		if operation == nil {
+			continue
+		}
+		message, _ := p.deref(operation["message"]).(map[string]interface{})
+		if message == nil {
+			continue
+		}
+		if alternatives, ok := message["oneOf"].([]interface{}); ok {
+			for _, alternative := range alternatives {
+				parsed.Messages = append(parsed.Messages, p.parseMessage(alternative))
+			}
+		} else {
+			parsed.Messages = append(parsed.Messages, p.parseMessage(message))
+		}
+	}
+	return parsed
+}
+
+// parseChannelV3 reads the messages of a 3.x channel, which is
+// named by its address, if it has one, otherwise its ID.
+func (p *parser) parseChannelV3(id string, channel map[string]interface{}) Channel {
+	parsed := Channel{Name: id}
+	if address, ok := channel["address"].(string); ok && address != "" {
+		parsed.Name = address
+	}
+	messages, _ := channel["messages"].(map[string]interface{})
+	for _, name := range sortedKeys(messages) {
+		message := p.parseMessage(messages[name])
+		if message.Name == "" {
+			message.Name = name
+		}
+		parsed.Messages = append(parsed.Messages, message)
+	}
+	return parsed
+}
+
+func (p *parser) parseMessage(node interface{}) Message {
+	message, _ := p.deref(node).(map[string]interface{})
+	parsed := Message{ContentType: p.defaultContentType}
+	if message == nil {
+		return parsed
+	}
+	for _, key := range []string{"name", "messageId"} {
+		if name, ok := message[key].(string); ok && parsed.Name == "" {
+			parsed.Name = name
+		}
+	}
+	if contentType, ok := message["contentType"].(string); ok {
+		parsed.ContentType = contentType
+	}
+
+	if examples, ok := message["examples"].([]interface{}); ok {
+		for _, example := range examples {
+			if example, ok := example.(map[string]interface{}); ok {
+				if payload, found := example["payload"]; found {
+					parsed.Payload = payload
+					return parsed
+				}
+			}
+		}
+	}
+
+	payload := p.deref(message["payload"])
+	if multiFormat, ok := payload.(map[string]interface{}); ok && multiFormat["schemaFormat"] != nil && multiFormat["schema"] != nil {
+		payload = multiFormat["schema"]
+	}
+	parsed.Payload = jsonschema.Sample(payload, p.resolve)
+	return parsed
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
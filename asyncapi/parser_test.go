@@ -0,0 +1,152 @@
+package asyncapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const userEventsV2 = `asyncapi: 2.6.0
+info:
+  title: User events
+  version: 1.0.0
+servers:
+  production:
+    url: broker.example.com:9092
+    protocol: kafka
+channels:
+  user/signedup:
+    subscribe:
+      message:
+        $ref: '#/components/messages/UserSignedUp'
+  user/deleted:
+    subscribe:
+      message:
+        name: UserDeleted
+        payload:
+          type: object
+          properties:
+            userId:
+              type: string
+              format: uuid
+components:
+  messages:
+    UserSignedUp:
+      name: UserSignedUp
+      contentType: application/json
+      payload:
+        $ref: '#/components/schemas/User'
+      examples:
+        - name: alice
+          payload:
+            email: alice@example.com
+  schemas:
+    User:
+      type: object
+      properties:
+        email:
+          type: string
+`
+
+const ordersV3 = `{
+  "asyncapi": "3.0.0",
+  "info": {"title": "Orders", "version": "1.0.0"},
+  "servers": {"aws": {"host": "sqs.eu-west-1.amazonaws.com", "protocol": "sqs"}},
+  "channels": {
+    "orderPlaced": {
+      "address": "orders-placed",
+      "messages": {
+        "OrderPlaced": {"$ref": "#/components/messages/OrderPlaced"}
+      }
+    }
+  },
+  "components": {
+    "messages": {
+      "OrderPlaced": {
+        "payload": {
+          "type": "object",
+          "properties": {
+            "orderId": {"type": "integer"},
+            "items": {"type": "array", "items": {"$ref": "#/components/schemas/Item"}}
+          }
+        }
+      }
+    },
+    "schemas": {
+      "Item": {"type": "object", "properties": {"sku": {"type": "string", "example": "ABC-1"}}}
+    }
+  }
+}`
+
+func writeTestSpec(t *testing.T, dir string, name string, content string) string {
+	filePath := filepath.Join(dir, name)
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func assertPayload(t *testing.T, payload interface{}, want string) {
+	got, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("payload = %s, want %s", got, want)
+	}
+}
+
+func TestDiscoverAsyncApiSpecs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestSpec(t, dir, "events.yaml", userEventsV2)
+	writeTestSpec(t, dir, "petstore.yaml", "openapi: 3.0.0\n")
+
+	got := DiscoverAsyncApiSpecs(dir)
+	if len(got) != 1 || filepath.Base(got[0]) != "events.yaml" {
+		t.Errorf("DiscoverAsyncApiSpecs() = %v, want [events.yaml]", got)
+	}
+}
+
+func TestParse_v2(t *testing.T) {
+	spec, err := Parse(writeTestSpec(t, t.TempDir(), "events.yaml", userEventsV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Protocol != "kafka" {
+		t.Errorf("Protocol = %v, want kafka", spec.Protocol)
+	}
+	if len(spec.Channels) != 2 {
+		t.Fatalf("expected 2 channels, got %d", len(spec.Channels))
+	}
+
+	deleted := spec.Channels[0]
+	if deleted.Name != "user/deleted" || len(deleted.Messages) != 1 || deleted.Messages[0].Name != "UserDeleted" {
+		t.Errorf("unexpected channel: %+v", deleted)
+	}
+	assertPayload(t, deleted.Messages[0].Payload, `{"userId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`)
+
+	signedUp := spec.Channels[1]
+	if signedUp.Name != "user/signedup" || !signedUp.Messages[0].IsJson() {
+		t.Errorf("unexpected channel: %+v", signedUp)
+	}
+	assertPayload(t, signedUp.Messages[0].Payload, `{"email":"alice@example.com"}`)
+}
+
+func TestParse_v3(t *testing.T) {
+	spec, err := Parse(writeTestSpec(t, t.TempDir(), "orders.json", ordersV3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Protocol != "sqs" {
+		t.Errorf("Protocol = %v, want sqs", spec.Protocol)
+	}
+	if len(spec.Channels) != 1 || spec.Channels[0].Name != "orders-placed" {
+		t.Fatalf("unexpected channels: %+v", spec.Channels)
+	}
+	message := spec.Channels[0].Messages[0]
+	if message.Name != "OrderPlaced" {
+		t.Errorf("Name = %v, want OrderPlaced", message.Name)
+	}
+	assertPayload(t, message.Payload, `{"items":[{"sku":"ABC-1"}],"orderId":1}`)
+}
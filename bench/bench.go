@@ -0,0 +1,258 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bench
+
+import (
+	"crypto/tls"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"gatehill.io/imposter/openapi"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var logger = logging.GetLogger()
+
+// Target is a request sent during a benchmark.
+type Target struct {
+	Method string
+	Path   string
+}
+
+func (t Target) String() string {
+	return t.Method + " " + t.Path
+}
+
+// Options configures a benchmark. It runs until Requests have been sent,
+// if set, or otherwise for Duration.
+type Options struct {
+	BaseUrl     string
+	Targets     []Target
+	Concurrency int
+	Duration    time.Duration
+	Requests    int
+	Timeout     time.Duration
+}
+
+// Result summarises the requests sent during a benchmark. Errors are
+// requests that failed without a response, or received a server error
+// (5xx) status.
+type Result struct {
+	Requests    int
+	Errors      int
+	Elapsed     time.Duration
+	StatusCodes map[int]int
+
+	// Latencies holds the latency of each request with a response,
+	// in ascending order.
+	Latencies []time.Duration
+}
+
+// ErrorRate returns the percentage of requests that were errors.
+func (r Result) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) * 100 / float64(r.Requests)
+}
+
+// Throughput returns the number of requests per second.
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency below which the percentage of
+// requests fall, using the nearest-rank method.
+func (r Result) Percentile(percent float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percent / 100 * float64(len(r.Latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	return r.Latencies[rank-1]
+}
+
+// Mean returns the mean latency.
+func (r Result) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, latency := range r.Latencies {
+		total += latency
+	}
+	return total / time.Duration(len(r.Latencies))
+}
+
+type sample struct {
+	status  int
+	latency time.Duration
+	err     error
+}
+
+// Run sends requests to the targets in turn from concurrent workers,
+// reusing connections, and returns a summary of the responses.
+func Run(options Options) (Result, error) {
+	if len(options.Targets) == 0 {
+		return Result{}, fmt.Errorf("no targets to benchmark")
+	}
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	client := &http.Client{
+		Timeout: options.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: concurrency,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	baseUrl := strings.TrimSuffix(options.BaseUrl, "/")
+
+	var sent int64
+	deadline := time.Now().Add(options.Duration)
+	next := func() (Target, bool) {
+		n := atomic.AddInt64(&sent, 1)
+		if options.Requests > 0 {
+			if n > int64(options.Requests) {
+				return Target{}, false
+			}
+		} else if time.Now().After(deadline) {
+			return Target{}, false
+		}
+		return options.Targets[(n-1)%int64(len(options.Targets))], true
+	}
+
+	samples := make(chan sample, concurrency)
+	wg := &sync.WaitGroup{}
+	started := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target, ok := next(); ok; target, ok = next() {
+				samples <- send(client, baseUrl, target)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	result := Result{StatusCodes: make(map[int]int)}
+	for s := range samples {
+		result.Requests++
+		if s.err != nil {
+			logger.Debugf("benchmark request failed: %v", s.err)
+			result.Errors++
+			continue
+		}
+		result.StatusCodes[s.status]++
+		result.Latencies = append(result.Latencies, s.latency)
+		if s.status >= 500 {
+			result.Errors++
+		}
+	}
+	result.Elapsed = time.Since(started)
+	sort.Slice(result.Latencies, func(i, j int) bool {
+		return result.Latencies[i] < result.Latencies[j]
+	})
+	return result, nil
+}
+
+func send(client *http.Client, baseUrl string, target Target) sample {
+	req, err := http.NewRequest(target.Method, baseUrl+target.Path, nil)
+	if err != nil {
+		return sample{err: err}
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return sample{err: err}
+	}
+	defer resp.Body.Close()
+
+	// the body is drained, so the connection can be reused
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return sample{err: err}
+	}
+	return sample{status: resp.StatusCode, latency: time.Since(started)}
+}
+
+// BuildTarget returns a target for a resource or operation path. Path
+// parameters are replaced by a placeholder value and a trailing wildcard
+// is removed. If the method is empty, GET is used.
+func BuildTarget(method string, path string) Target {
+	if method == "" {
+		method = http.MethodGet
+	}
+	path = strings.TrimSuffix(path, "*")
+	if path == "" {
+		path = "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = "1"
+		}
+	}
+	return Target{Method: strings.ToUpper(method), Path: strings.Join(segments, "/")}
+}
+
+// ParseTarget parses a target in the form 'METHOD /path' or '/path'.
+func ParseTarget(target string) (Target, error) {
+	fields := strings.Fields(target)
+	switch {
+	case len(fields) == 1 && strings.HasPrefix(fields[0], "/"):
+		return BuildTarget("", fields[0]), nil
+	case len(fields) == 2 && strings.HasPrefix(fields[1], "/"):
+		return BuildTarget(fields[0], fields[1]), nil
+	default:
+		return Target{}, fmt.Errorf("invalid target: %s (expected 'METHOD /path' or '/path')", target)
+	}
+}
+
+// TargetsFromSpec returns a target for each operation in the OpenAPI
+// spec, ordered by path and method.
+func TargetsFromSpec(specFile string) ([]Target, error) {
+	spec, err := openapi.Parse(specFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse openapi spec: %s: %v", specFile, err)
+	}
+	var targets []Target
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			targets = append(targets, BuildTarget(method, path))
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].String() < targets[j].String()
+	})
+	return targets, nil
+}
@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	mu := sync.Mutex{}
+	paths := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	result, err := Run(Options{
+		BaseUrl:     server.URL,
+		Targets:     []Target{{Method: "GET", Path: "/pets"}, {Method: "POST", Path: "/pets"}, {Method: "GET", Path: "/error"}, {Method: "GET", Path: "/pets/1"}},
+		Concurrency: 4,
+		Requests:    100,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Requests != 100 || result.Errors != 25 || result.ErrorRate() != 25 {
+		t.Errorf("unexpected result: %d requests, %d errors", result.Requests, result.Errors)
+	}
+	if want := map[int]int{200: 75, 503: 25}; !reflect.DeepEqual(result.StatusCodes, want) {
+		t.Errorf("StatusCodes = %v, want %v", result.StatusCodes, want)
+	}
+	if want := map[string]int{"GET /pets": 25, "POST /pets": 25, "GET /error": 25, "GET /pets/1": 25}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+	if len(result.Latencies) != 100 || result.Percentile(50) > result.Percentile(99) {
+		t.Errorf("unexpected latencies: %v", result.Latencies)
+	}
+}
+
+func TestResult_Percentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	result := Result{Latencies: latencies}
+	for percent, want := range map[float64]time.Duration{0: time.Millisecond, 50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99.9: 100 * time.Millisecond} {
+		if got := result.Percentile(percent); got != want {
+			t.Errorf("Percentile(%v) = %v, want %v", percent, got, want)
+		}
+	}
+	if got := result.Mean(); got != 50500*time.Microsecond {
+		t.Errorf("Mean() = %v", got)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    Target
+		wantErr bool
+	}{
+		{target: "/pets", want: Target{Method: "GET", Path: "/pets"}},
+		{target: "post /pets/{petId}", want: Target{Method: "POST", Path: "/pets/1"}},
+		{target: "GET /static/*", want: Target{Method: "GET", Path: "/static/"}},
+		{target: "GET pets", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := ParseTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/bench"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/failure"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var benchFlags = struct {
+	url                 string
+	port                int
+	tls                 bool
+	specFile            string
+	paths               []string
+	concurrency         int
+	duration            time.Duration
+	requests            int
+	timeout             time.Duration
+	recursiveConfigScan bool
+}{}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench [CONFIG_DIR]",
+	Short: "Load test a running mock",
+	Long: `Sends concurrent requests to a running mock and reports the latency
+percentiles, throughput and error rate of its responses, to confirm the
+mock is not the bottleneck in performance tests.
+
+The requests are sent to the paths given by --path or, if --spec is
+set, the operations in an OpenAPI spec. Otherwise, the paths of the
+resources in the configuration files in CONFIG_DIR are used. Path
+parameters are replaced with a placeholder value.
+
+Errors are requests that failed without a response, or received a
+server error (5xx) status.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		recursive := benchFlags.recursiveConfigScan || viper.GetBool("config.scan.recursive")
+		targets := buildBenchTargets(configDir, recursive)
+
+		baseUrl := benchFlags.url
+		if baseUrl == "" {
+			scheme := "http"
+			if benchFlags.tls {
+				scheme = "https"
+			}
+			baseUrl = fmt.Sprintf("%s://localhost:%d", scheme, benchFlags.port)
+		}
+		if benchFlags.requests > 0 {
+			logger.Infof("sending %d requests to %d path(s) at %s with concurrency %d", benchFlags.requests, len(targets), baseUrl, benchFlags.concurrency)
+		} else {
+			logger.Infof("sending requests to %d path(s) at %s for %v with concurrency %d", len(targets), baseUrl, benchFlags.duration, benchFlags.concurrency)
+		}
+		result, err := bench.Run(bench.Options{
+			BaseUrl:     baseUrl,
+			Targets:     targets,
+			Concurrency: benchFlags.concurrency,
+			Duration:    benchFlags.duration,
+			Requests:    benchFlags.requests,
+			Timeout:     benchFlags.timeout,
+		})
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		printBenchResult(result)
+	},
+}
+
+func init() {
+	benchCmd.Flags().StringVar(&benchFlags.url, "url", "", "Base URL of the mock (default is localhost on the given port)")
+	benchCmd.Flags().IntVarP(&benchFlags.port, "port", "p", 8080, "Port on which the mock is listening")
+	benchCmd.Flags().BoolVar(&benchFlags.tls, "tls", false, "Connect to the mock using HTTPS")
+	benchCmd.Flags().StringVar(&benchFlags.specFile, "spec", "", "Send requests to the operations in this OpenAPI spec")
+	benchCmd.Flags().StringArrayVar(&benchFlags.paths, "path", nil, "Send requests to this path, in the form 'METHOD /path' or '/path' (e.g. 'GET /pets')")
+	benchCmd.Flags().IntVarP(&benchFlags.concurrency, "concurrency", "c", 10, "Number of concurrent connections")
+	benchCmd.Flags().DurationVarP(&benchFlags.duration, "duration", "d", 10*time.Second, "Duration of the test, unless --requests is set")
+	benchCmd.Flags().IntVarP(&benchFlags.requests, "requests", "n", 0, "Total number of requests to send, instead of running for --duration")
+	benchCmd.Flags().DurationVar(&benchFlags.timeout, "timeout", 10*time.Second, "Time to wait for each response")
+	benchCmd.Flags().BoolVarP(&benchFlags.recursiveConfigScan, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// buildBenchTargets returns the targets given by the flags, or those of
+// the resources in the configuration files in configDir.
+func buildBenchTargets(configDir string, recursive bool) []bench.Target {
+	var targets []bench.Target
+	for _, path := range benchFlags.paths {
+		target, err := bench.ParseTarget(path)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		targets = append(targets, target)
+	}
+	if benchFlags.specFile != "" {
+		specTargets, err := bench.TargetsFromSpec(benchFlags.specFile)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeGenerateSpecInvalid, err))
+		}
+		targets = append(targets, specTargets...)
+	}
+	if len(targets) > 0 {
+		return targets
+	}
+
+	resources, err := config.ListResources(configDir, recursive)
+	if err != nil {
+		failure.Fatal(failure.Wrap(failure.CodeConfigInvalid, err))
+	}
+	for _, resource := range resources {
+		targets = append(targets, bench.BuildTarget(resource.Method, resource.Path))
+	}
+	if len(targets) == 0 {
+		failure.Fatal(failure.New(failure.CodeCliUsage, "no resource paths found in %s - use --path or --spec to set the paths to test", configDir))
+	}
+	return targets
+}
+
+func printBenchResult(result bench.Result) {
+	fmt.Printf("Requests:     %d (%.1f/s)\n", result.Requests, result.Throughput())
+	fmt.Printf("Errors:       %d (%.2f%%)\n", result.Errors, result.ErrorRate())
+
+	var statuses []int
+	for status := range result.StatusCodes {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	var counts []string
+	for _, status := range statuses {
+		counts = append(counts, fmt.Sprintf("%d=%d", status, result.StatusCodes[status]))
+	}
+	fmt.Printf("Status codes: %s\n", strings.Join(counts, " "))
+
+	if len(result.Latencies) == 0 {
+		return
+	}
+	round := func(d time.Duration) time.Duration {
+		return d.Round(10 * time.Microsecond)
+	}
+	fmt.Printf("Latency:      min %v, mean %v, max %v\n", round(result.Latencies[0]), round(result.Mean()), round(result.Latencies[len(result.Latencies)-1]))
+	fmt.Printf("Percentiles:  p50 %v, p90 %v, p95 %v, p99 %v\n", round(result.Percentile(50)), round(result.Percentile(90)), round(result.Percentile(95)), round(result.Percentile(99)))
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"gatehill.io/imposter/config"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
@@ -43,7 +44,8 @@ For example, a Docker image for the Docker engine type, or a ZIP file
 for the AWS Lambda engine type.
 
 If CONFIG_DIR is not specified, the current working directory is used.`,
-	Args: cobra.RangeArgs(0, 1),
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
 	Run: func(cmd *cobra.Command, args []string) {
 		var configDir string
 		if len(args) == 0 {
@@ -52,7 +54,7 @@ If CONFIG_DIR is not specified, the current working directory is used.`,
 			configDir, _ = filepath.Abs(args[0])
 		}
 		if err := config.ValidateConfigExists(configDir, false); err != nil {
-			logger.Fatal(err)
+			failure.Fatal(err)
 		}
 
 		// Search for CLI config files in the mock config dir.
@@ -78,6 +80,7 @@ func init() {
 
 	_ = bundleCmd.MarkFlagRequired("engine-type")
 	registerEngineTypeCompletions(bundleCmd, engine.EngineTypeAwsLambda)
+	registerEngineVersionCompletions(bundleCmd)
 	rootCmd.AddCommand(bundleCmd)
 }
 
@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/daemon"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/plugin"
+	"gatehill.io/imposter/stringutil"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 var localTypes = []engine.EngineType{
@@ -26,3 +34,113 @@ func registerEngineTypeCompletions(cmd *cobra.Command, additionalTypes ...engine
 		return types, cobra.ShellCompDirectiveNoFileComp
 	})
 }
+
+// registerEngineVersionCompletions completes the version flag with
+// 'latest' and the versions of the engine type in the cache.
+func registerEngineVersionCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("version", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		versions := []string{"latest"}
+		flagType, _ := cmd.Flags().GetString("engine-type")
+		engineType := engine.GetConfiguredType(flagType)
+		if !isLocalType(engineType) {
+			return versions, cobra.ShellCompDirectiveNoFileComp
+		}
+		engines, err := engine.GetLibrary(engineType).List()
+		if err != nil {
+			logger.Debugf("failed to list cached engines for completion: %v", err)
+			return versions, cobra.ShellCompDirectiveNoFileComp
+		}
+		for _, e := range engines {
+			if !stringutil.Contains(versions, e.Version) {
+				versions = append(versions, e.Version)
+			}
+		}
+		return versions, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// registerPluginVersionCompletions completes the version flag with the
+// engine versions for which plugins are installed.
+func registerPluginVersionCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("version", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		versions, err := plugin.ListVersionDirs()
+		if err != nil {
+			logger.Debugf("failed to list plugin versions for completion: %v", err)
+		}
+		return versions, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func isLocalType(engineType engine.EngineType) bool {
+	for _, t := range localTypes {
+		if t == engineType {
+			return true
+		}
+	}
+	return false
+}
+
+// completeConfigDirArg completes the CONFIG_DIR argument at the given
+// position with the directories containing config files. Arguments
+// before it are completed as files, and none are accepted after it.
+func completeConfigDirArg(position int) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) < position {
+			return nil, cobra.ShellCompDirectiveDefault
+		} else if len(args) > position {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		dirs := config.SuggestConfigDirs(toComplete)
+		directive := cobra.ShellCompDirectiveNoFileComp
+		for _, dir := range dirs {
+			// allow completion to continue into the directory
+			if strings.HasSuffix(dir, string(filepath.Separator)) {
+				directive |= cobra.ShellCompDirectiveNoSpace
+				break
+			}
+		}
+		return dirs, directive
+	}
+}
+
+// suggestMockIds completes the IDs of the running mocks, in the daemon
+// if the command delegates to it, or of the engine type otherwise.
+func suggestMockIds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	useDaemon, _ := cmd.Flags().GetBool("daemon")
+	if useDaemon || !cmd.Flags().Changed("daemon") && viper.GetBool("daemon.delegate") {
+		if c, err := daemon.Connect(); err == nil {
+			var ids []string
+			if mocks, err := c.ListMocks(); err == nil {
+				for _, mock := range mocks {
+					if !stringutil.Contains(args, mock.ID) {
+						ids = append(ids, mock.ID)
+					}
+				}
+			}
+			return ids, cobra.ShellCompDirectiveNoFileComp
+		} else if useDaemon {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	flagType, _ := cmd.Flags().GetString("engine-type")
+	engineType := engine.GetConfiguredType(flagType)
+	if !isLocalType(engineType) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if ok, _ := engine.GetLibrary(engineType).CheckPrereqs(); !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	mockEngine := engine.BuildEngine(engineType, filepath.Join(os.TempDir(), "imposter-list"), engine.StartOptions{})
+	mocks, err := mockEngine.ListAllManaged()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, mock := range mocks {
+		if !stringutil.Contains(args, mock.ID) {
+			ids = append(ids, mock.ID)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var configConvertFlags = struct {
+	to     string
+	stdout bool
+}{}
+
+// configConvertCmd represents the config convert command
+var configConvertCmd = &cobra.Command{
+	Use:   "convert [FILE|DIR...]",
+	Short: "Convert mock configuration between YAML and JSON",
+	Long: `Converts Imposter configuration files to YAML or JSON, normalising
+their formatting. The order of properties is kept, as are comments at the
+start of YAML files converted to YAML. Other comments cannot be preserved.
+
+Each file is replaced by one with the extension of the new format. If a
+directory is given, all configuration files in it are converted. If no
+files are specified, those in the current working directory are converted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := config.ParseConfigFormat(configConvertFlags.to)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(args) == 0 {
+			workingDir, _ := os.Getwd()
+			args = []string{workingDir}
+		}
+		convertConfigFiles(listFilesToConvert(args), format, configConvertFlags.stdout)
+	},
+}
+
+func init() {
+	configConvertCmd.Flags().StringVar(&configConvertFlags.to, "to", "", "Format to convert to (yaml|json)")
+	configConvertCmd.Flags().BoolVar(&configConvertFlags.stdout, "stdout", false, "Print the converted configuration, instead of replacing the files")
+	_ = configConvertCmd.MarkFlagRequired("to")
+	localConfigCmd.AddCommand(configConvertCmd)
+}
+
+func listFilesToConvert(args []string) []string {
+	var files []string
+	for _, arg := range args {
+		path, _ := filepath.Abs(arg)
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Fatalf("unable to read: %s: %v", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		configFiles, err := config.ListConfigFiles(path)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		files = append(files, configFiles...)
+	}
+	if len(files) == 0 {
+		logger.Fatalf("no configuration files found in: %v", args)
+	}
+	return files
+}
+
+func convertConfigFiles(files []string, format config.ConfigFormat, stdout bool) {
+	for _, file := range files {
+		if stdout {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			converted, err := config.ConvertConfig(content, format)
+			if err != nil {
+				logger.Fatalf("unable to convert %s: %v", file, err)
+			}
+			fmt.Print(string(converted))
+			continue
+		}
+		converted, err := config.ConvertConfigFile(file, format)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infof("converted %s to %s", file, converted)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/impostermodel"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var configCorsFlags = struct {
+	origins []string
+}{}
+
+// configCorsCmd represents the config cors command
+var configCorsCmd = &cobra.Command{
+	Use:   "cors [CONFIG_DIR]",
+	Short: "Add CORS settings to mock configuration",
+	Long: `Adds CORS settings to the Imposter configuration files in a directory,
+so browser-based clients on other origins can call the mocks. Any origin
+is allowed, unless origins are given. Existing CORS settings are replaced.
+
+Only configuration files for plugins serving HTTP requests are changed.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		files, err := config.ListConfigFiles(configDir)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(files) == 0 {
+			logger.Fatalf("no configuration files found in: %s", configDir)
+		}
+		cors := impostermodel.BuildCorsConfig(configCorsFlags.origins)
+		for _, file := range files {
+			updated, err := config.AddCorsConfig(file, cors)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if updated {
+				logger.Infof("added CORS settings to %s", file)
+			}
+		}
+	},
+}
+
+func init() {
+	configCorsCmd.Flags().StringArrayVar(&configCorsFlags.origins, "origin", nil, "Origin allowed to call the mocks, such as http://localhost:3000 - can be repeated")
+	localConfigCmd.AddCommand(configCorsCmd)
+}
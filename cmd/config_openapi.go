@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var configOpenApiFlags = struct {
+	output    string
+	title     string
+	recursive bool
+}{}
+
+// configOpenApiCmd represents the config openapi command
+var configOpenApiCmd = &cobra.Command{
+	Use:   "openapi [CONFIG_DIR]",
+	Short: "Generate an OpenAPI spec from mock configuration",
+	Long: `Generates an OpenAPI 3 spec describing the resources of the rest plugin
+configuration files in a directory. Each method and path is an operation,
+with the parameters matched by its resources and their responses. Response
+bodies are used as examples, and schemas are inferred from JSON bodies.
+
+The spec is printed, unless an output file is given. If the output file
+has a .json extension, the spec is written as JSON.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		title := configOpenApiFlags.title
+		if title == "" {
+			title = filepath.Base(configDir)
+		}
+		spec, err := config.GenerateOpenApiSpec(configDir, configOpenApiFlags.recursive, title)
+		if err != nil {
+			logger.Fatalf("unable to generate OpenAPI spec: %v", err)
+		}
+		if configOpenApiFlags.output == "" {
+			fmt.Print(string(spec))
+			return
+		}
+		if filepath.Ext(configOpenApiFlags.output) == ".json" {
+			if spec, err = config.ConvertConfig(spec, config.ConfigFormatJson); err != nil {
+				logger.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(configOpenApiFlags.output, spec, 0644); err != nil {
+			logger.Fatalf("unable to write spec: %v", err)
+		}
+		logger.Infof("wrote OpenAPI spec to %s", configOpenApiFlags.output)
+	},
+}
+
+func init() {
+	configOpenApiCmd.Flags().StringVarP(&configOpenApiFlags.output, "output", "o", "", "File to write the spec to, instead of printing it")
+	configOpenApiCmd.Flags().StringVar(&configOpenApiFlags.title, "title", "", "Title of the spec (default is the name of the directory)")
+	configOpenApiCmd.Flags().BoolVarP(&configOpenApiFlags.recursive, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
+	localConfigCmd.AddCommand(configOpenApiCmd)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/config"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var configMergeFlags = struct {
+	output     string
+	onConflict string
+}{}
+
+// configMergeCmd represents the config merge command
+var configMergeCmd = &cobra.Command{
+	Use:   "merge [FILE|DIR...]",
+	Short: "Merge mock configuration files into one",
+	Long: `Merges Imposter configuration files into a single file. The resources
+and interceptors of the files are combined. Other properties, such as the
+plugin, must be the same in each file.
+
+Resources that match the same requests conflict. By default, this fails
+the merge; alternatively, the resource from the first or last file can
+be kept. Relative file paths are rewritten to be relative to the output.
+
+The merged files are removed. If a directory is given, all configuration
+files in it are merged. If no files are specified, those in the current
+working directory are merged.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		strategy, err := config.ParseConflictStrategy(configMergeFlags.onConflict)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(args) == 0 {
+			workingDir, _ := os.Getwd()
+			args = []string{workingDir}
+		}
+		files := listFilesToConvert(args)
+		output, _ := filepath.Abs(configMergeFlags.output)
+		if err := config.MergeConfigFiles(files, output, strategy); err != nil {
+			logger.Fatalf("unable to merge configuration files: %v", err)
+		}
+		logger.Infof("merged %d files into %s", len(files), output)
+	},
+}
+
+// configSplitCmd represents the config split command
+var configSplitCmd = &cobra.Command{
+	Use:   "split FILE",
+	Short: "Split a mock configuration file into one per path",
+	Long: `Splits an Imposter configuration file into a file for each path of its
+resources. Each file is named after the original and the path, such as
+petstore-pets-config.yaml, and has the other properties of the original,
+such as the plugin. Interceptors are written to the first file.
+
+The original file is removed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := filepath.Abs(args[0])
+		written, err := config.SplitConfigFile(file)
+		if err != nil {
+			logger.Fatalf("unable to split configuration file: %v", err)
+		}
+		for _, w := range written {
+			logger.Infof("wrote %s", w)
+		}
+		logger.Infof("split %s into %d files", file, len(written))
+	},
+}
+
+func init() {
+	configMergeCmd.Flags().StringVarP(&configMergeFlags.output, "output", "o", "", "Path of the merged configuration file")
+	configMergeCmd.Flags().StringVar(&configMergeFlags.onConflict, "on-conflict", string(config.ConflictStrategyError), "How to handle conflicting resources (error|first|last)")
+	_ = configMergeCmd.MarkFlagRequired("output")
+	localConfigCmd.AddCommand(configMergeCmd)
+	localConfigCmd.AddCommand(configSplitCmd)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/stringutil"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var configSettingsFlags = struct {
+	local bool
+}{}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print a CLI config value",
+	Long: `Prints the value of a key in the CLI configuration file.
+
+By default, the global configuration file is read. Use --local to read
+the configuration file for the working directory instead.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSettingKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := getSettingsFile(configSettingsFlags.local)
+		value, found, err := config.GetConfigValue(file, args[0])
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if !found {
+			logger.Infof("%s is not set in: %s", args[0], file)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a CLI config value",
+	Long: `Sets the value of a key in the CLI configuration file, creating
+the file if it does not exist.
+
+By default, the global configuration file is written. Use --local to
+write the configuration file for the working directory instead.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSettingKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateSetting(args[0], args[1]); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		file := getSettingsFile(configSettingsFlags.local)
+		if err := config.SetConfigValue(file, args[0], args[1]); err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infof("set %s in: %s", args[0], file)
+	},
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Remove a CLI config value",
+	Long: `Removes a key from the CLI configuration file, so that its
+default value is used.
+
+By default, the global configuration file is written. Use --local to
+write the configuration file for the working directory instead.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSettingKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := getSettingsFile(configSettingsFlags.local)
+		removed, err := config.UnsetConfigValue(file, args[0])
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if removed {
+			logger.Infof("unset %s in: %s", args[0], file)
+		} else {
+			logger.Infof("%s is not set in: %s", args[0], file)
+		}
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{configGetCmd, configSetCmd, configUnsetCmd} {
+		c.Flags().BoolVar(&configSettingsFlags.local, "local", false, "Use the configuration file for the working directory")
+		localConfigCmd.AddCommand(c)
+	}
+}
+
+// listSupportedSettingKeys returns the keys that can be set with
+// the config set command.
+func listSupportedSettingKeys() []string {
+	return []string{
+		"engine",
+		"version",
+		"cli.logLevel",
+		"cli.updateCheck",
+		"docker.registryMirror",
+		"download.baseUrl",
+		"daemon.delegate",
+	}
+}
+
+// getSettingsFile returns the CLI configuration file to read or write:
+// the file for the working directory if local is true, otherwise the
+// file passed with --config, or the global configuration file.
+func getSettingsFile(local bool) string {
+	if local {
+		dir, err := os.Getwd()
+		if err != nil {
+			logger.Fatalf("failed to determine working directory: %v", err)
+		}
+		return config.GetLocalConfigFile(dir)
+	}
+	if rootFlags.cfgFile != "" {
+		return rootFlags.cfgFile
+	}
+	file, err := config.GetGlobalConfigFile()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return file
+}
+
+// validateSetting returns an error if the key is not supported, or the
+// value is not valid for the key.
+func validateSetting(key string, value string) error {
+	supported := listSupportedSettingKeys()
+	if !stringutil.Contains(supported, key) {
+		return fmt.Errorf("unsupported config key: %s - supported keys are: %s", key, strings.Join(supported, ", "))
+	}
+	switch key {
+	case "engine":
+		return engine.ValidateEngineType(engine.EngineType(value))
+	case "cli.logLevel":
+		if !stringutil.Contains(logLevels, strings.ToLower(value)) {
+			return fmt.Errorf("invalid log level: %s - valid values are: %s", value, strings.Join(logLevels, ", "))
+		}
+	case "cli.updateCheck", "daemon.delegate":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid value for %s: %s - valid values are: true, false", key, value)
+		}
+	}
+	return nil
+}
+
+// completeSettingKeys suggests the supported keys and, for config set,
+// the valid values of the key.
+func completeSettingKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return listSupportedSettingKeys(), cobra.ShellCompDirectiveNoFileComp
+	}
+	if len(args) == 1 && cmd.Name() == "set" {
+		switch args[0] {
+		case "engine":
+			var types []string
+			for _, t := range localTypes {
+				types = append(types, string(t))
+			}
+			return types, cobra.ShellCompDirectiveNoFileComp
+		case "cli.logLevel":
+			return logLevels, cobra.ShellCompDirectiveNoFileComp
+		case "cli.updateCheck", "daemon.delegate":
+			return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
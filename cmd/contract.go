@@ -0,0 +1,102 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/verify"
+	"github.com/spf13/cobra"
+	"path/filepath"
+)
+
+var contractFlags = struct {
+	url           string
+	port          int
+	tls           bool
+	start         bool
+	engineType    string
+	engineVersion string
+	output        string
+	report        string
+}{}
+
+// contractCmd represents the contract command
+var contractCmd = &cobra.Command{
+	Use:   "contract SPEC_FILE [CONFIG_DIR]",
+	Short: "Check that the responses of a mock conform to its OpenAPI spec",
+	Long: `Sends a request for each resource generated from an OpenAPI spec,
+including its status and example variants, to a mock and validates the
+responses against the spec.
+
+Each response must have a status code declared by the operation, a
+content type declared for the response and, for JSON, a body that is
+valid against the response schema. This catches drift between
+hand-edited response files and the contract.
+
+By default, the requests are sent to a mock that is already running. Use
+--start to start a mock from CONFIG_DIR for the duration of the checks.
+
+Exits with a non-zero code if any check fails, so it can be used in CI.
+
+If CONFIG_DIR is not specified, the directory containing the spec is used.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeConfigDirArg(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := parseResultsFormat(contractFlags.output)
+		specFile, _ := filepath.Abs(args[0])
+		baseUrl := contractFlags.url
+		if baseUrl == "" {
+			scheme := "http"
+			if contractFlags.tls {
+				scheme = "https"
+			}
+			baseUrl = fmt.Sprintf("%s://localhost:%d", scheme, contractFlags.port)
+		}
+
+		var stop func()
+		if contractFlags.start {
+			configDir := filepath.Dir(specFile)
+			if len(args) == 2 {
+				configDir, _ = filepath.Abs(args[1])
+			}
+			stop = startEphemeralMock(configDir, contractFlags.engineType, contractFlags.engineVersion, contractFlags.port)
+		}
+		report, err := verify.RunContract(specFile, baseUrl)
+		if stop != nil {
+			stop()
+		}
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeGenerateSpecInvalid, err))
+		}
+		reportVerifyResults(report, filepath.Base(specFile), format, contractFlags.report)
+	},
+}
+
+func init() {
+	contractCmd.Flags().StringVar(&contractFlags.url, "url", "", "Base URL of the mock (default is localhost on the given port)")
+	contractCmd.Flags().IntVarP(&contractFlags.port, "port", "p", 8080, "Port on which the mock is listening, or on which to start it")
+	contractCmd.Flags().BoolVar(&contractFlags.tls, "tls", false, "Connect to the mock using HTTPS")
+	contractCmd.Flags().BoolVar(&contractFlags.start, "start", false, "Start a mock from CONFIG_DIR for the duration of the checks")
+	contractCmd.Flags().StringVarP(&contractFlags.engineType, "engine-type", "t", "", "Imposter engine type used with --start (valid: docker,jvm - default \"docker\")")
+	contractCmd.Flags().StringVarP(&contractFlags.engineVersion, "version", "v", "", "Imposter engine version used with --start (default \"latest\")")
+	contractCmd.Flags().StringVarP(&contractFlags.output, "output", "o", string(outputFormatPlain), "Format of the results printed (valid: plain,json)")
+	contractCmd.Flags().StringVar(&contractFlags.report, "report", "", "Also write the results as JUnit XML to this file (e.g. junit.xml)")
+	registerEngineTypeCompletions(contractCmd)
+	registerEngineVersionCompletions(contractCmd)
+	rootCmd.AddCommand(contractCmd)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/stringutil"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var coverageFlags = struct {
+	specFile            string
+	recursiveConfigScan bool
+	minCoverage         float64
+}{}
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage [CONFIG_DIR]",
+	Short: "Report coverage of an OpenAPI spec by mock configuration",
+	Long: `Reports which operations in an OpenAPI spec are covered by resources
+in the Imposter configuration files in a directory, and which resources
+have no counterpart in the spec.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		recursive := coverageFlags.recursiveConfigScan || viper.GetBool("config.scan.recursive")
+		reportCoverage(coverageFlags.specFile, configDir, recursive, coverageFlags.minCoverage)
+	},
+}
+
+func init() {
+	coverageCmd.Flags().StringVar(&coverageFlags.specFile, "spec", "", "Path to the OpenAPI spec (required)")
+	coverageCmd.Flags().BoolVarP(&coverageFlags.recursiveConfigScan, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
+	coverageCmd.Flags().Float64Var(&coverageFlags.minCoverage, "min-coverage", 0, "Exit with a non-zero code if the percentage of operations covered is lower than this")
+	_ = coverageCmd.MarkFlagRequired("spec")
+	rootCmd.AddCommand(coverageCmd)
+}
+
+func reportCoverage(specFile string, configDir string, recursive bool, minCoverage float64) {
+	report, err := config.ComputeCoverage(specFile, configDir, recursive)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	var rows [][]string
+	for _, operation := range report.Operations {
+		covered := "no"
+		var files []string
+		for _, resource := range operation.Resources {
+			files = append(files, relativeToDir(configDir, resource.File))
+		}
+		if operation.Covered() {
+			covered = "yes"
+		}
+		rows = append(rows, []string{operation.Method, operation.Path, covered, strings.Join(stringutil.Unique(files), ", ")})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Method", "Path", "Covered", "Config"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(rows)
+	table.Render()
+
+	if len(report.Unmatched) > 0 {
+		fmt.Println("\nResources with no operation in the spec:")
+		for _, resource := range report.Unmatched {
+			method := resource.Method
+			if method == "" {
+				method = "*"
+			}
+			fmt.Printf("  %s %s (%s)\n", method, resource.Path, relativeToDir(configDir, resource.File))
+		}
+	}
+	percentage := report.Percentage()
+	fmt.Printf("\n%.1f%% of operations covered\n", percentage)
+
+	if percentage < minCoverage {
+		failure.Fatal(failure.New(failure.CodeConfigInvalid, "coverage of %.1f%% is lower than the minimum of %.1f%%", percentage, minCoverage))
+	}
+}
+
+func relativeToDir(dir string, file string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil {
+		return rel
+	}
+	return file
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/daemon"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var daemonFlags = struct {
+	host  string
+	port  int
+	token string
+}{}
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run a local control API",
+	Long: `Runs a local REST API on the loopback interface, allowing other tools,
+such as IDE plugins and test frameworks, to start and stop mocks, query
+their status and trigger recordings.
+
+Workspaces can also be deployed to the daemon with 'imposter remote deploy',
+using the 'server' remote type. To accept deployments from other machines,
+listen on another interface with --host.
+
+Requests must present the daemon token as a bearer token. If a token is
+not specified, a random token is generated. The port and token are written
+to daemon.json in the CLI configuration directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDaemon(daemonFlags.host, daemonFlags.port, daemonFlags.token)
+	},
+}
+
+func init() {
+	daemonCmd.Flags().StringVar(&daemonFlags.host, "host", daemon.DefaultHost, "Address on which the control API listens")
+	daemonCmd.Flags().IntVarP(&daemonFlags.port, "port", "p", daemon.DefaultPort, "Port on which the control API listens")
+	daemonCmd.Flags().StringVar(&daemonFlags.token, "token", "", "Token required by the control API (default: randomly generated)")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(host string, port int, token string) {
+	d, err := daemon.New(daemon.Options{Host: host, Port: port, Token: token})
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		println()
+		logger.Info("shutting down daemon")
+		d.Shutdown()
+		os.Exit(0)
+	}()
+
+	if err := d.ListenAndServe(); err != nil {
+		d.Shutdown()
+		logger.Fatal(err)
+	}
+}
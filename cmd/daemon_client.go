@@ -0,0 +1,195 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"gatehill.io/imposter/daemon"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// daemonStartTimeout allows for the daemon pulling or downloading the
+// engine before the mock starts.
+const daemonStartTimeout = 5 * time.Minute
+
+// daemonUpFlags are the flags of the up command honoured when starting
+// a mock in the daemon.
+var daemonUpFlags = map[string]bool{
+	"daemon":                true,
+	"engine-type":           true,
+	"version":               true,
+	"port":                  true,
+	"auto-port":             true,
+	"env":                   true,
+	"scaffold":              true,
+	"spec-url":              true,
+	"recursive-config-scan": true,
+}
+
+// connectDaemon returns a client for the running daemon if the command
+// should delegate to it, or nil if it should run locally. Delegation is
+// enabled by the --daemon flag, or the 'daemon.delegate' configuration
+// key. If the flag is set, but no daemon is running, the CLI exits; if
+// only the configuration key is set, the command runs locally.
+func connectDaemon(cmd *cobra.Command, useDaemon bool) *daemon.Client {
+	explicit := cmd.Flags().Changed("daemon")
+	if explicit && !useDaemon || !explicit && !viper.GetBool("daemon.delegate") {
+		return nil
+	}
+	c, err := daemon.Connect()
+	if err != nil {
+		if !explicit && errors.Is(err, daemon.ErrNotRunning) {
+			logger.Debugf("daemon delegation is configured but %v - running locally", err)
+			return nil
+		}
+		logger.Fatal(err)
+	}
+	logger.Debugf("delegating to running daemon")
+	return c
+}
+
+// upWithDaemon starts the mock in the daemon and follows its logs. On
+// interrupt, the mock is stopped.
+func upWithDaemon(c *daemon.Client, cmd *cobra.Command, configDir string, port int, environment []string) {
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if !daemonUpFlags[flag.Name] {
+			logger.Warnf("--%s is not supported when delegating to the daemon - ignoring", flag.Name)
+		}
+	})
+
+	mock, err := c.StartMock(daemon.StartMockRequest{
+		ConfigDir:   configDir,
+		Port:        port,
+		EngineType:  upFlags.engineType,
+		Version:     upFlags.engineVersion,
+		Environment: environment,
+	})
+	if err != nil {
+		logger.Fatalf("failed to start mock in daemon: %v", err)
+	}
+	id := mock.ID
+	logger.Infof("starting mock %s in daemon", id)
+
+	stopMock := func() {
+		if err := c.StopMock(id); err != nil {
+			logger.Warnf("failed to stop mock %s in daemon: %v", id, err)
+		}
+	}
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigC
+		println()
+		logger.Infof("stopping mock %s in daemon", id)
+		stopMock()
+		os.Exit(0)
+	}()
+
+	mock, err = c.WaitForMock(id, 500*time.Millisecond, daemonStartTimeout)
+	if err != nil {
+		stopMock()
+		logger.Fatal(err)
+	}
+	logger.Infof("mock %s is up and running at http://localhost:%d", id, mock.Port)
+
+	if err := c.FollowLogs(id, os.Stdout); err != nil {
+		logger.Fatalf("failed to follow logs of mock %s: %v", id, err)
+	}
+	logger.Infof("daemon closed the log stream of mock %s", id)
+}
+
+// listDaemonMocks lists the mocks started by the daemon, returning the
+// number of mocks, and whether all of them are running.
+func listDaemonMocks(c *daemon.Client, quiet bool, format outputFormat) (mockCount int, allRunning bool) {
+	mocks, err := c.ListMocks()
+	if err != nil {
+		logger.Fatalf("failed to list mocks in daemon: %v", err)
+	}
+	allRunning = true
+	var rows [][]string
+	for _, mock := range mocks {
+		if mock.State != daemon.MockStateRunning {
+			allRunning = false
+		}
+		if quiet && format == outputFormatPlain {
+			os.Stdout.WriteString(mock.ID + "\n")
+		} else {
+			rows = append(rows, []string{mock.ID, mock.ConfigDir, strconv.Itoa(mock.Port), string(mock.State)})
+		}
+	}
+	if format != outputFormatPlain {
+		if mocks == nil {
+			mocks = []daemon.MockStatus{}
+		}
+		printStructured(format, mocks)
+	} else if !quiet {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"ID", "Config dir", "Port", "State"})
+		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		table.SetCenterSeparator("|")
+		table.AppendBulk(rows)
+		table.Render()
+	}
+	return len(mocks), allRunning
+}
+
+// stopDaemonMocks stops the mocks started by the daemon with the given
+// IDs, or prefixes of them, or all its mocks if none are given.
+func stopDaemonMocks(c *daemon.Client, ids []string) {
+	mocks, err := c.ListMocks()
+	if err != nil {
+		logger.Fatalf("failed to list mocks in daemon: %v", err)
+	}
+	var matched []string
+	if len(ids) > 0 {
+		var managed []engine.ManagedMock
+		for _, mock := range mocks {
+			managed = append(managed, engine.ManagedMock{ID: mock.ID, Port: mock.Port})
+		}
+		if matched, err = engine.MatchManaged(managed, ids); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+	} else {
+		logger.Info("stopping all mocks in daemon...")
+		for _, mock := range mocks {
+			matched = append(matched, mock.ID)
+		}
+	}
+	stopped := 0
+	for _, id := range matched {
+		if err := c.StopMock(id); err != nil {
+			logger.Warnf("failed to stop mock %s in daemon: %v", id, err)
+			continue
+		}
+		stopped++
+	}
+	if stopped > 0 {
+		logger.Infof("stopped %d mock(s) in daemon", stopped)
+	} else if len(matched) == 0 {
+		logger.Info("no mocks were found in daemon")
+	}
+}
@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
@@ -25,20 +26,35 @@ import (
 
 var downFlags = struct {
 	engineType string
+	daemon     bool
 }{}
 
 // downCmd represents the down command
 var downCmd = &cobra.Command{
-	Use:   "down",
+	Use:   "down [MOCK_ID...]",
 	Short: "Stop running mocks",
-	Long:  `Stops running Imposter mocks for the current engine type.`,
+	Long: `Stops running Imposter mocks for the current engine type.
+
+If one or more MOCK_IDs are given, only those mocks are stopped. An ID
+can be shortened to a unique prefix of the ID shown by 'imposter list'.`,
+	ValidArgsFunction: suggestMockIds,
 	Run: func(cmd *cobra.Command, args []string) {
-		stopAll(engine.GetConfiguredType(downFlags.engineType))
+		if c := connectDaemon(cmd, downFlags.daemon); c != nil {
+			stopDaemonMocks(c, args)
+			return
+		}
+		engineType := engine.GetConfiguredType(downFlags.engineType)
+		if len(args) > 0 {
+			stopMocks(engineType, args)
+		} else {
+			stopAll(engineType)
+		}
 	},
 }
 
 func init() {
 	downCmd.Flags().StringVarP(&downFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default \"docker\")")
+	downCmd.Flags().BoolVar(&downFlags.daemon, "daemon", false, "Stop the mocks started by the running daemon")
 	registerEngineTypeCompletions(downCmd)
 	rootCmd.AddCommand(downCmd)
 }
@@ -55,3 +71,21 @@ func stopAll(engineType engine.EngineType) {
 		logger.Info("no managed mocks were found")
 	}
 }
+
+// stopMocks stops the managed mocks with the given IDs, or prefixes of them.
+func stopMocks(engineType engine.EngineType, ids []string) {
+	configDir := filepath.Join(os.TempDir(), "imposter-down")
+	mockEngine := engine.BuildEngine(engineType, configDir, engine.StartOptions{})
+
+	mocks, err := mockEngine.ListAllManaged()
+	if err != nil {
+		logger.Fatalf("failed to list mocks: %s", err)
+	}
+	matched, err := engine.MatchManaged(mocks, ids)
+	if err != nil {
+		failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+	}
+	logger.Infof("stopping %d managed mock(s)...", len(matched))
+	stopped := mockEngine.StopManaged(matched)
+	logger.Infof("stopped %d managed mock(s)", stopped)
+}
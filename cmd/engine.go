@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // engineCmd represents the engine command
@@ -29,3 +30,11 @@ var engineCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(engineCmd)
 }
+
+// applySkipChecksum disables checksum verification of downloaded engine
+// binaries, if requested, such as for mirrors that do not publish checksums.
+func applySkipChecksum(skip bool) {
+	if skip {
+		viper.Set("jvm.skipChecksum", true)
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
 	"gatehill.io/imposter/engine"
+	"github.com/docker/go-units"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"os"
@@ -25,14 +27,34 @@ import (
 
 var engineListFlags = struct {
 	engineType string
+	output     string
 }{}
 
+// engineListOutput describes the engines in the cache, for the JSON
+// and YAML formats.
+type engineListOutput struct {
+	Engines []engineOutput `json:"engines"`
+
+	// Latest is the version to which 'latest' resolves, if known.
+	Latest       string `json:"latest,omitempty"`
+	LatestCached bool   `json:"latestCached"`
+}
+
+type engineOutput struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+
+	// Size is in bytes, or 0 if it is not known.
+	Size int64 `json:"size"`
+}
+
 // engineListCmd represents the engineList command
 var engineListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List the engines in the cache",
-	Long: `Lists all versions of engine binaries/images in the cache.
+	Long: `Lists all versions of engine binaries/images in the cache, with
+their sizes, and shows the version to which 'latest' resolves.
 
 If engine type is not specified, it defaults to all.`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -45,11 +67,11 @@ If engine type is not specified, it defaults to all.`,
 		} else {
 			engineTypes = []engine.EngineType{engineType}
 		}
-		listEngines(engineTypes)
+		listEngines(engineTypes, parseOutputFormat(engineListFlags.output))
 	},
 }
 
-func listEngines(engineTypes []engine.EngineType) {
+func listEngines(engineTypes []engine.EngineType, format outputFormat) {
 	logger.Tracef("listing engines")
 	var available []engine.EngineMetadata
 
@@ -62,16 +84,71 @@ func listEngines(engineTypes []engine.EngineType) {
 		available = append(available, engines...)
 	}
 
+	if format != outputFormatPlain {
+		output := engineListOutput{Engines: []engineOutput{}}
+		for _, metadata := range available {
+			output.Engines = append(output.Engines, engineOutput{Type: string(metadata.EngineType), Version: metadata.Version, Size: metadata.Size})
+		}
+		output.Latest, output.LatestCached = resolveLatestVersion(available)
+		printStructured(format, output)
+		return
+	}
+
 	var rows [][]string
 	for _, metadata := range available {
-		rows = append(rows, []string{string(metadata.EngineType), metadata.Version})
+		rows = append(rows, []string{string(metadata.EngineType), metadata.Version, formatEngineSize(metadata.Size)})
 	}
 	renderEngines(rows)
+	printLatestVersion(available)
+}
+
+func formatEngineSize(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+	return units.HumanSize(float64(size))
+}
+
+// printLatestVersion shows the version to which 'latest' resolves, and
+// whether it is in the cache.
+func printLatestVersion(available []engine.EngineMetadata) {
+	latest, cached := resolveLatestVersion(available)
+	if latest == "" {
+		return
+	}
+	if cached {
+		fmt.Printf("'latest' resolves to version %s (cached)\n", latest)
+	} else {
+		fmt.Printf("'latest' resolves to version %s (not cached - run 'imposter engine pull' to fetch it)\n", latest)
+	}
+}
+
+// resolveLatestVersion returns the version to which 'latest' resolves,
+// or an empty string if it cannot be resolved, and whether it is in
+// the cache.
+func resolveLatestVersion(available []engine.EngineMetadata) (latest string, cached bool) {
+	latest, err := engine.ResolveLatestToVersion(true)
+	if err != nil {
+		logger.Warnf("failed to resolve latest version: %s", err)
+		return "", false
+	}
+	return latest, isVersionCached(latest, available)
+}
+
+// isVersionCached returns whether the version is one of those available
+// in the cache.
+func isVersionCached(version string, available []engine.EngineMetadata) bool {
+	for _, metadata := range available {
+		if metadata.Version == version {
+			return true
+		}
+	}
+	return false
 }
 
 func renderEngines(rows [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Type", "Version"})
+	table.SetHeader([]string{"Type", "Version", "Size"})
 	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 	table.SetCenterSeparator("|")
 	table.AppendBulk(rows)
@@ -80,6 +157,7 @@ func renderEngines(rows [][]string) {
 
 func init() {
 	engineListCmd.Flags().StringVarP(&engineListFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default is all")
+	addOutputFlag(engineListCmd, &engineListFlags.output)
 	registerEngineTypeCompletions(engineListCmd)
 	engineCmd.AddCommand(engineListCmd)
 }
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"gatehill.io/imposter/engine"
+	"testing"
+)
+
+func Test_formatEngineSize(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{name: "unknown size", size: 0, want: "-"},
+		{name: "bytes", size: 512, want: "512B"},
+		{name: "megabytes", size: 52_400_000, want: "52.4MB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatEngineSize(tt.size); got != tt.want {
+				t.Errorf("formatEngineSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isVersionCached(t *testing.T) {
+	available := []engine.EngineMetadata{
+		{EngineType: engine.EngineTypeDockerCore, Version: "4.1.0"},
+		{EngineType: engine.EngineTypeJvmSingleJar, Version: "4.2.0"},
+	}
+	tests := []struct {
+		name    string
+		version string
+		want    bool
+	}{
+		{name: "cached", version: "4.2.0", want: true},
+		{name: "not cached", version: "4.3.0", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isVersionCached(tt.version, available); got != tt.want {
+				t.Errorf("isVersionCached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
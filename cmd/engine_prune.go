@@ -0,0 +1,108 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+var enginePruneFlags = struct {
+	engineType string
+	keepLatest int
+	dryRun     bool
+}{}
+
+// enginePruneCmd represents the enginePrune command
+var enginePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old engines from the cache",
+	Long: `Removes old versions of engine binaries/images from the cache, keeping
+the highest versions of each engine type, and reports the space reclaimed.
+
+Docker images that are used by a container are not removed.
+
+If engine type is not specified, it defaults to all.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if enginePruneFlags.keepLatest < 0 {
+			logger.Fatalf("--keep-latest must not be negative")
+		}
+		// unspecified type is valid
+		engineType := engine.GetConfiguredTypeWithDefault(enginePruneFlags.engineType, engine.EngineTypeNone)
+
+		var engineTypes []engine.EngineType
+		if engine.EngineTypeNone == engineType {
+			engineTypes = engine.EnumerateLibraries()
+		} else {
+			engineTypes = []engine.EngineType{engineType}
+		}
+		pruneEngines(engineTypes, enginePruneFlags.keepLatest, enginePruneFlags.dryRun)
+	},
+}
+
+func init() {
+	enginePruneCmd.Flags().StringVarP(&enginePruneFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default is all")
+	enginePruneCmd.Flags().IntVar(&enginePruneFlags.keepLatest, "keep-latest", 1, "Number of the highest versions of each engine type to keep")
+	enginePruneCmd.Flags().BoolVar(&enginePruneFlags.dryRun, "dry-run", false, "Show the engines that would be removed, without removing them")
+	registerEngineTypeCompletions(enginePruneCmd)
+	engineCmd.AddCommand(enginePruneCmd)
+}
+
+func pruneEngines(engineTypes []engine.EngineType, keepLatest int, dryRun bool) {
+	var available []engine.EngineMetadata
+	for _, e := range engineTypes {
+		engines, err := engine.GetLibrary(e).List()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		available = append(available, engines...)
+	}
+
+	prune := engine.SelectForPrune(available, keepLatest)
+	if len(prune) == 0 {
+		logger.Infof("no engines to prune")
+		return
+	}
+
+	var reclaimed int64
+	removed := 0
+	for _, metadata := range prune {
+		if dryRun {
+			logger.Infof("would remove %s engine version %s (%s)", metadata.EngineType, metadata.Version, formatEngineSize(metadata.Size))
+			reclaimed += metadata.Size
+			removed++
+			continue
+		}
+		size, err := engine.GetLibrary(metadata.EngineType).Remove(metadata.Version)
+		if err != nil {
+			logger.Warnf("skipped %s engine version %s: %s", metadata.EngineType, metadata.Version, err)
+			continue
+		}
+		logger.Infof("removed %s engine version %s", metadata.EngineType, metadata.Version)
+		reclaimed += size
+		removed++
+	}
+
+	verb := "removed"
+	if dryRun {
+		verb = "would remove"
+	}
+	fmt.Printf("%s %d engine(s), reclaiming %s\n", verb, removed, units.HumanSize(float64(reclaimed)))
+}
@@ -22,28 +22,39 @@ import (
 )
 
 var enginePullFlags = struct {
-	engineType    string
-	engineVersion string
-	forcePull     bool
+	engineType     string
+	engineVersions []string
+	forcePull      bool
+	skipChecksum   bool
 }{}
 
 // enginePullCmd represents the enginePull command
 var enginePullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Pull the engine into the cache",
-	Long: `Pulls a specified version of the engine binary/image into the cache.
+	Long: `Pulls the specified versions of the engine binary/image into the cache,
+such as when preparing an image for use without network access.
 
-If version is not specified, it defaults to 'latest'.`,
+If version is not specified, it defaults to 'latest'. Multiple versions
+can be specified by repeating the flag, or separating them with commas.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		applySkipChecksum(enginePullFlags.skipChecksum)
 		var pullPolicy engine.PullPolicy
 		if enginePullFlags.forcePull {
 			pullPolicy = engine.PullAlways
 		} else {
 			pullPolicy = engine.PullIfNotPresent
 		}
-		version := engine.GetConfiguredVersion(enginePullFlags.engineVersion, pullPolicy != engine.PullAlways)
 		engineType := engine.GetConfiguredType(enginePullFlags.engineType)
-		pull(version, engineType, pullPolicy)
+
+		versions := enginePullFlags.engineVersions
+		if len(versions) == 0 {
+			versions = []string{""}
+		}
+		for _, v := range versions {
+			version := engine.GetConfiguredVersion(v, pullPolicy != engine.PullAlways)
+			pull(version, engineType, pullPolicy)
+		}
 	},
 }
 
@@ -58,8 +69,10 @@ func pull(version string, engineType engine.EngineType, pullPolicy engine.PullPo
 
 func init() {
 	enginePullCmd.Flags().StringVarP(&enginePullFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default \"docker\")")
-	enginePullCmd.Flags().StringVarP(&enginePullFlags.engineVersion, "version", "v", "", "Imposter engine version (default \"latest\")")
+	enginePullCmd.Flags().StringSliceVarP(&enginePullFlags.engineVersions, "version", "v", nil, "Imposter engine version(s) (default \"latest\")")
 	enginePullCmd.Flags().BoolVarP(&enginePullFlags.forcePull, "force", "f", false, "Force engine pull")
+	enginePullCmd.Flags().BoolVar(&enginePullFlags.skipChecksum, "skip-checksum", false, "(JVM engine type only) Skip verification of the checksum of the downloaded engine")
 	registerEngineTypeCompletions(enginePullCmd)
+	registerEngineVersionCompletions(enginePullCmd)
 	engineCmd.AddCommand(enginePullCmd)
 }
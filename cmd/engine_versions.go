@@ -0,0 +1,72 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/engine"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+var engineVersionsFlags = struct {
+	prerelease bool
+	limit      int
+}{}
+
+// engineVersionsCmd represents the engineVersions command
+var engineVersionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List available engine versions",
+	Long: `Lists the engine versions that have been released, highest first.
+
+Any of these can be used as the engine version, or pinned to the highest
+release of a major or minor version, such as '4.x' or '4.2.x', which is
+resolved when the mock is started.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listEngineVersions(engineVersionsFlags.prerelease, engineVersionsFlags.limit)
+	},
+}
+
+func init() {
+	engineVersionsCmd.Flags().BoolVar(&engineVersionsFlags.prerelease, "prerelease", false, "Include prerelease versions")
+	engineVersionsCmd.Flags().IntVarP(&engineVersionsFlags.limit, "limit", "n", 20, "Maximum number of versions to show - 0 shows all")
+	engineCmd.AddCommand(engineVersionsCmd)
+}
+
+func listEngineVersions(includePrerelease bool, limit int) {
+	releases, err := engine.ListReleases(includePrerelease)
+	if err != nil {
+		logger.Fatalf("failed to list engine versions: %s", err)
+	}
+	if limit > 0 && len(releases) > limit {
+		releases = releases[:limit]
+	}
+
+	var rows [][]string
+	for _, r := range releases {
+		rows = append(rows, []string{r.Version, r.Published.Format("2006-01-02"), strconv.FormatBool(r.Prerelease)})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Version", "Published", "Prerelease"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(rows)
+	table.Render()
+}
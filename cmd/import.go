@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/impostermodel"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Convert configuration from other mock tools",
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+}
+
+// getImportDir returns the directory into which configuration is
+// imported, which is the working directory if not specified.
+func getImportDir(args []string, index int) string {
+	if len(args) > index {
+		dir, _ := filepath.Abs(args[index])
+		return dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// writeImportedConfig writes the imported configuration files, keyed by
+// file name, and their response files, to configDir. Existing files are
+// only replaced if forceOverwrite is set.
+func writeImportedConfig(configDir string, configs map[string]impostermodel.PluginConfig, files map[string][]byte, forceOverwrite bool) {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		logger.Fatalf("failed to create directory: %s: %v", configDir, err)
+	}
+	tx := fileutil.NewTransaction()
+	for name, config := range configs {
+		content, err := yaml.Marshal(config)
+		if err != nil {
+			logger.Fatalf("unable to marshal imposter config: %v", err)
+		}
+		configFile := filepath.Join(configDir, name)
+		fileutil.MustNotExist(configFile, forceOverwrite)
+		tx.WriteFile(configFile, content, 0644)
+		tx.OnCommit(func() {
+			logger.Infof("wrote Imposter config: %v", configFile)
+		})
+	}
+	for name, content := range files {
+		file := filepath.Join(configDir, name)
+		fileutil.MustNotExist(file, forceOverwrite)
+		tx.WriteFile(file, content, 0644)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Fatal(err)
+	}
+}
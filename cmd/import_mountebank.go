@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/mountebank"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"strconv"
+)
+
+var importMountebankFlags = struct {
+	port           int
+	forceOverwrite bool
+}{}
+
+// importMountebankCmd represents the import mountebank command
+var importMountebankCmd = &cobra.Command{
+	Use:   "mountebank IMPOSTERS [DIR]",
+	Short: "Convert mountebank imposters",
+	Long: `Converts mountebank imposters into Imposter configuration.
+
+IMPOSTERS is a JSON file holding the imposters, such as one saved with
+'mb save', or a single imposter. Imposters using the http or https
+protocols are converted.
+
+Predicates and responses are converted where Imposter has an equivalent.
+A warning is printed for each predicate, response or behavior that could
+not be converted.
+
+If there is more than one imposter, each is written to a subdirectory
+of DIR named after its port. If DIR is not specified, the current
+working directory is used.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		importMountebank(args[0], getImportDir(args, 1))
+	},
+}
+
+func init() {
+	importMountebankCmd.Flags().IntVar(&importMountebankFlags.port, "port", 0, "Only convert the imposter listening on this port")
+	importMountebankCmd.Flags().BoolVarP(&importMountebankFlags.forceOverwrite, "force-overwrite", "f", false, "Force overwrite of destination file(s) if already exist")
+	importCmd.AddCommand(importMountebankCmd)
+}
+
+func importMountebank(source string, configDir string) {
+	imposters, err := mountebank.LoadImposters(source)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if port := importMountebankFlags.port; port != 0 {
+		var selected []mountebank.Imposter
+		for _, imposter := range imposters {
+			if imposter.Port == port {
+				selected = append(selected, imposter)
+			}
+		}
+		if len(selected) == 0 {
+			logger.Fatalf("no imposter listening on port %d in: %s", port, source)
+		}
+		imposters = selected
+	}
+
+	conversion := mountebank.Convert(imposters)
+	for _, warning := range conversion.Warnings {
+		logger.Warn(warning)
+	}
+	if len(conversion.Mocks) == 0 {
+		logger.Fatalf("no mountebank imposters could be converted from: %s", source)
+	}
+
+	for i, mock := range conversion.Mocks {
+		mockDir := configDir
+		if len(conversion.Mocks) > 1 {
+			mockDir = filepath.Join(configDir, getMockDirName(i, mock))
+		}
+		configs := map[string]impostermodel.PluginConfig{
+			"mountebank-config.yaml": {Plugin: "rest", Resources: mock.Resources},
+		}
+		writeImportedConfig(mockDir, configs, mock.Files, importMountebankFlags.forceOverwrite)
+		if mock.Port != 0 {
+			logger.Infof("start the mock on the port of the imposter with: imposter up %s --port %d", mockDir, mock.Port)
+		}
+	}
+	logger.Infof("converted %d of %d mountebank imposters into %s", len(conversion.Mocks), len(imposters), configDir)
+}
+
+// getMockDirName returns the name of the subdirectory for a mock, which
+// is the port of its imposter, if mountebank did not choose it.
+func getMockDirName(index int, mock mountebank.Mock) string {
+	if mock.Port != 0 {
+		return strconv.Itoa(mock.Port)
+	}
+	return fmt.Sprintf("imposter-%d", index+1)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/wiremock"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var importWiremockFlags = struct {
+	filesDir         string
+	globalTemplating bool
+	forceOverwrite   bool
+}{}
+
+// importWiremockCmd represents the import wiremock command
+var importWiremockCmd = &cobra.Command{
+	Use:   "wiremock MAPPINGS [DIR]",
+	Short: "Convert WireMock stub mappings",
+	Long: `Converts WireMock stub mappings into Imposter configuration.
+
+MAPPINGS is a JSON mapping file, or a directory of them, such as
+WireMock's 'mappings' directory. Files named by 'bodyFileName' are read
+from the '__files' directory alongside it, unless --files-dir is set.
+
+Request matchers, response definitions and response templates are
+converted where Imposter has an equivalent. A warning is printed for
+each feature that could not be converted.
+
+If DIR is not specified, the current working directory is used.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		importWiremock(args[0], getImportDir(args, 1))
+	},
+}
+
+func init() {
+	importWiremockCmd.Flags().StringVar(&importWiremockFlags.filesDir, "files-dir", "", "Directory holding the files named by 'bodyFileName' (default: '__files' alongside MAPPINGS)")
+	importWiremockCmd.Flags().BoolVar(&importWiremockFlags.globalTemplating, "global-templating", false, "Treat every response as a template, as WireMock does with --global-response-templating")
+	importWiremockCmd.Flags().BoolVarP(&importWiremockFlags.forceOverwrite, "force-overwrite", "f", false, "Force overwrite of destination file(s) if already exist")
+	importCmd.AddCommand(importWiremockCmd)
+}
+
+func importWiremock(source string, configDir string) {
+	mappings, err := wiremock.LoadMappings(source)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	filesDir := importWiremockFlags.filesDir
+	if filesDir == "" {
+		if info, err := os.Stat(source); err == nil && info.IsDir() {
+			filesDir = filepath.Join(filepath.Dir(filepath.Clean(source)), "__files")
+		} else {
+			filesDir = filepath.Join(filepath.Dir(filepath.Dir(source)), "__files")
+		}
+	}
+	conversion := wiremock.Convert(mappings, wiremock.Options{
+		FilesDir:         filesDir,
+		GlobalTemplating: importWiremockFlags.globalTemplating,
+	})
+	for _, warning := range conversion.Warnings {
+		logger.Warn(warning)
+	}
+	if len(conversion.Resources) == 0 {
+		logger.Fatalf("no WireMock mappings could be converted from: %s", source)
+	}
+
+	configs := map[string]impostermodel.PluginConfig{
+		"wiremock-config.yaml": {Plugin: "rest", Resources: conversion.Resources},
+	}
+	writeImportedConfig(configDir, configs, conversion.Files, importWiremockFlags.forceOverwrite)
+	logger.Infof("converted %d of %d WireMock mappings into %s", len(conversion.Resources), len(mappings), configDir)
+}
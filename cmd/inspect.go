@@ -0,0 +1,80 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/inspect"
+	"gatehill.io/imposter/proxy"
+	"github.com/spf13/cobra"
+)
+
+var inspectFlags = struct {
+	port         int
+	tls          bool
+	logFile      string
+	controlToken string
+}{}
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Interactively inspect live requests",
+	Long: `Shows live requests to a running proxy or mock in an interactive
+terminal UI. Select a request to view its headers and body, re-send
+it, or copy it as a curl command.
+
+By default, exchanges are streamed from the proxy listening on the
+port. To inspect a mock, provide its log file, written by
+'imposter up --log-file', with the engine's request summary enabled
+using the IMPOSTER_LOG_SUMMARY environment variable. Only the method,
+path, status and latency of requests to a mock are shown.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runInspector(inspectFlags.port, inspectFlags.tls, inspectFlags.logFile, flagOrConfig(inspectFlags.controlToken, "proxy.controlToken"))
+	},
+}
+
+func init() {
+	inspectCmd.Flags().IntVarP(&inspectFlags.port, "port", "p", 8080, "Port on which the proxy or mock is listening")
+	inspectCmd.Flags().BoolVar(&inspectFlags.tls, "tls", false, "Connect to the proxy or mock using HTTPS")
+	inspectCmd.Flags().StringVar(&inspectFlags.controlToken, "control-token", "", "Bearer token required by the proxy to stream exchanges, if set using its --control-token flag")
+	inspectCmd.Flags().StringVar(&inspectFlags.logFile, "log-file", "", "Inspect the requests in this mock engine log file, instead of those to the proxy")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func runInspector(port int, tls bool, logFile string, controlToken string) {
+	baseUrl := proxy.BuildProxyBaseUrl(port, tls)
+	events := make(chan proxy.ExchangeEvent, 100)
+	errs := make(chan error, 1)
+
+	if logFile != "" {
+		go func() {
+			errs <- inspect.FollowLog(logFile, events)
+		}()
+	} else {
+		stream, err := inspect.SubscribeProxy(baseUrl, controlToken)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go func() {
+			errs <- stream.Read(events)
+		}()
+	}
+	if err := inspect.Run(baseUrl, events, errs); err != nil {
+		logger.Fatal(err)
+	}
+}
@@ -29,8 +29,18 @@ var listFlags = struct {
 	engineType     string
 	healthExitCode bool
 	quiet          bool
+	daemon         bool
+	output         string
 }{}
 
+// mockOutput describes a running mock, for the JSON and YAML formats.
+type mockOutput struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+	Health string `json:"health"`
+}
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:     "list",
@@ -39,19 +49,29 @@ var listCmd = &cobra.Command{
 	Long: `Lists running Imposter mocks for the current engine type
 and reports their health.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		listMocks(engine.GetConfiguredType(listFlags.engineType), listFlags.quiet)
+		format := parseOutputFormat(listFlags.output)
+		if c := connectDaemon(cmd, listFlags.daemon); c != nil {
+			mockCount, allRunning := listDaemonMocks(c, listFlags.quiet, format)
+			if listFlags.healthExitCode {
+				exitWithHealth(mockCount > 0 && allRunning)
+			}
+			return
+		}
+		listMocks(engine.GetConfiguredType(listFlags.engineType), listFlags.quiet, format)
 	},
 }
 
 func init() {
 	listCmd.Flags().StringVarP(&listFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default \"docker\")")
 	listCmd.Flags().BoolVarP(&listFlags.healthExitCode, "exit-code-health", "x", false, "Set exit code based on mock health")
-	listCmd.Flags().BoolVarP(&listFlags.quiet, "quiet", "q", false, "Quieten output; only print ID")
+	listCmd.Flags().BoolVarP(&listFlags.quiet, "quiet", "q", false, "Quieten output; only print ID - ignored for JSON and YAML output")
+	addOutputFlag(listCmd, &listFlags.output)
+	listCmd.Flags().BoolVar(&listFlags.daemon, "daemon", false, "List the mocks started by the running daemon")
 	registerEngineTypeCompletions(listCmd)
 	rootCmd.AddCommand(listCmd)
 }
 
-func listMocks(engineType engine.EngineType, quiet bool) {
+func listMocks(engineType engine.EngineType, quiet bool, format outputFormat) {
 	configDir := filepath.Join(os.TempDir(), "imposter-list")
 	mockEngine := engine.BuildEngine(engineType, configDir, engine.StartOptions{})
 
@@ -62,9 +82,12 @@ func listMocks(engineType engine.EngineType, quiet bool) {
 
 	var anyFailed = false
 	var rows [][]string
+	output := []mockOutput{}
 	for _, mock := range mocks {
 		engine.PopulateHealth(&mock)
-		if quiet {
+		if format != outputFormatPlain {
+			output = append(output, mockOutput{ID: mock.ID, Name: mock.Name, Port: mock.Port, Health: string(mock.Health)})
+		} else if quiet {
 			os.Stdout.WriteString(mock.ID + "\n")
 		} else {
 			rows = append(rows, []string{mock.ID, mock.Name, strconv.Itoa(mock.Port), string(mock.Health)})
@@ -73,17 +96,24 @@ func listMocks(engineType engine.EngineType, quiet bool) {
 			anyFailed = true
 		}
 	}
-	if !quiet {
+	if format != outputFormatPlain {
+		printStructured(format, output)
+	} else if !quiet {
 		renderMocks(rows)
 	}
 
 	if listFlags.healthExitCode {
-		// if there is at least one mock, and all mocks are healthy, return status 0
-		if len(mocks) > 0 && !anyFailed {
-			os.Exit(0)
-		} else {
-			os.Exit(1)
-		}
+		exitWithHealth(len(mocks) > 0 && !anyFailed)
+	}
+}
+
+// exitWithHealth exits with status 0 if there is at least one mock,
+// and all mocks are healthy, otherwise 1.
+func exitWithHealth(healthy bool) {
+	if healthy {
+		os.Exit(0)
+	} else {
+		os.Exit(1)
 	}
 }
 
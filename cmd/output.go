@@ -0,0 +1,69 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"github.com/spf13/cobra"
+	"os"
+	"sigs.k8s.io/yaml"
+)
+
+// addOutputFlag adds the --output flag of informational commands.
+func addOutputFlag(cmd *cobra.Command, format *string) {
+	cmd.Flags().StringVarP(format, "output", "o", string(outputFormatPlain), "Output format (valid: plain,json,yaml)")
+}
+
+// parseOutputFormat validates the format of an informational command.
+func parseOutputFormat(format string) outputFormat {
+	switch outputFormat(format) {
+	case "":
+		return outputFormatPlain
+	case outputFormatPlain, outputFormatJson, outputFormatYaml:
+		return outputFormat(format)
+	default:
+		failure.Fatal(failure.New(failure.CodeCliUsage, "unsupported output format: %s (valid: %s,%s,%s)", format, outputFormatPlain, outputFormatJson, outputFormatYaml))
+		return ""
+	}
+}
+
+// marshalStructured returns v as indented JSON or YAML. YAML is converted
+// from the JSON, so both formats use the field names of the json tags.
+func marshalStructured(format outputFormat, v interface{}) ([]byte, error) {
+	switch format {
+	case outputFormatJson:
+		return json.MarshalIndent(v, "", "  ")
+	case outputFormatYaml:
+		return yaml.Marshal(v)
+	default:
+		return nil, fmt.Errorf("unsupported structured output format: %s", format)
+	}
+}
+
+// printStructured writes v to stdout as JSON or YAML.
+func printStructured(format outputFormat, v interface{}) {
+	output, err := marshalStructured(format, v)
+	if err != nil {
+		logger.Fatalf("failed to marshall output: %v", err)
+	}
+	if format == outputFormatJson {
+		output = append(output, '\n')
+	}
+	_, _ = os.Stdout.Write(output)
+}
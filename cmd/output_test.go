@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_marshalStructured(t *testing.T) {
+	workspaces := []workspaceOutput{{Name: "default", RemoteType: "server", Active: true}}
+	tests := []struct {
+		name   string
+		format outputFormat
+		want   string
+	}{
+		{
+			name:   "json",
+			format: outputFormatJson,
+			want: `[
+  {
+    "name": "default",
+    "remoteType": "server",
+    "active": true
+  }
+]`,
+		},
+		{
+			name:   "yaml uses json field names",
+			format: outputFormatYaml,
+			want: `- active: true
+  name: default
+  remoteType: server
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalStructured(tt.format, workspaces)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshalStructured() = %v, want %v", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func Test_marshalStructured_plain(t *testing.T) {
+	if _, err := marshalStructured(outputFormatPlain, nil); err == nil {
+		t.Errorf("expected error for plain format")
+	}
+}
+
+func Test_parseOutputFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   outputFormat
+	}{
+		{format: "", want: outputFormatPlain},
+		{format: "plain", want: outputFormatPlain},
+		{format: "json", want: outputFormatJson},
+		{format: "yaml", want: outputFormatYaml},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := parseOutputFormat(tt.format); got != tt.want {
+				t.Errorf("parseOutputFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"gatehill.io/imposter/engine"
 	"gatehill.io/imposter/plugin"
 	"github.com/spf13/cobra"
+	"strings"
 )
 
 var pluginInstallFlags = struct {
@@ -30,17 +31,25 @@ var pluginInstallFlags = struct {
 
 // pluginInstallCmd represents the pluginInstall command
 var pluginInstallCmd = &cobra.Command{
-	Use:   "install [PLUGIN_NAME_1] [PLUGIN_NAME_N...]",
+	Use:   "install [PLUGIN_NAME_1[:VERSION]] [PLUGIN_NAME_N[:VERSION]...]",
 	Short: "Install plugins",
 	Long: `Installs plugins for a specific engine version.
 
-If version is not specified, it defaults to 'latest'.
+If version is not specified, it defaults to 'latest'. The version
+can also be given for each plugin, after its name.
+
+Installed plugins are available to mocks started with 'imposter up'
+using the same engine version.
 
 Example 1: Install named plugin
 
 	imposter plugin install store-redis
 
-Example 2: Install all plugins in config file
+Example 2: Install named plugin for a specific engine version
+
+	imposter plugin install soap:4.2.0
+
+Example 3: Install all plugins in config file
 
 	imposter plugin install`,
 	Args: cobra.ArbitraryArgs,
@@ -53,6 +62,7 @@ Example 2: Install all plugins in config file
 func init() {
 	pluginInstallCmd.Flags().StringVarP(&pluginInstallFlags.engineVersion, "version", "v", "", "Imposter engine version (default \"latest\")")
 	pluginInstallCmd.Flags().BoolVarP(&pluginInstallFlags.saveDefault, "save-default", "d", false, "Whether to save the plugin as a default")
+	registerEngineVersionCompletions(pluginInstallCmd)
 	pluginCmd.AddCommand(pluginInstallCmd)
 }
 
@@ -62,12 +72,33 @@ func installPlugins(plugins []string, version string, saveDefault bool) {
 	if len(plugins) == 0 {
 		ensured, err = plugin.EnsureConfiguredPlugins(version)
 	} else {
-		ensured, err = plugin.EnsurePlugins(plugins, version, saveDefault)
+		var versions []string
+		pluginsByVersion := make(map[string][]string)
+		for _, ref := range plugins {
+			pluginName, pluginVersion := plugin.ParsePluginRef(ref)
+			if pluginVersion == "" {
+				pluginVersion = version
+			} else {
+				pluginVersion = engine.GetConfiguredVersion(pluginVersion, true)
+			}
+			if _, found := pluginsByVersion[pluginVersion]; !found {
+				versions = append(versions, pluginVersion)
+			}
+			pluginsByVersion[pluginVersion] = append(pluginsByVersion[pluginVersion], pluginName)
+		}
+		for _, v := range versions {
+			var count int
+			count, err = plugin.EnsurePlugins(pluginsByVersion[v], v, saveDefault)
+			if err != nil {
+				break
+			}
+			ensured += count
+		}
 
 		if !saveDefault {
 			println(fmt.Sprintf(`ℹ️ Note that these plugins have not been saved as default plugins.
 This means they are installed only for engine version %s and not any future engine versions.
-To change this behaviour, pass the --save-default (-d) flag.`, version))
+To change this behaviour, pass the --save-default (-d) flag.`, strings.Join(versions, ", ")))
 		}
 	}
 	if err != nil {
@@ -25,8 +25,15 @@ import (
 
 var pluginListFlags = struct {
 	engineVersion string
+	output        string
 }{}
 
+// pluginOutput describes an installed plugin, for the JSON and YAML formats.
+type pluginOutput struct {
+	Name          string `json:"name"`
+	EngineVersion string `json:"engineVersion"`
+}
+
 // pluginListCmd represents the pluginList command
 var pluginListCmd = &cobra.Command{
 	Use:     "list",
@@ -44,11 +51,11 @@ var pluginListCmd = &cobra.Command{
 			}
 			versions = v
 		}
-		listPlugins(versions)
+		listPlugins(versions, parseOutputFormat(pluginListFlags.output))
 	},
 }
 
-func listPlugins(versions []string) {
+func listPlugins(versions []string, format outputFormat) {
 	logger.Tracef("listing plugins")
 	var available []plugin.PluginMetadata
 
@@ -62,6 +69,15 @@ func listPlugins(versions []string) {
 		}
 	}
 
+	if format != outputFormatPlain {
+		output := []pluginOutput{}
+		for _, metadata := range available {
+			output = append(output, pluginOutput{Name: metadata.Name, EngineVersion: metadata.Version})
+		}
+		printStructured(format, output)
+		return
+	}
+
 	var rows [][]string
 	for _, metadata := range available {
 		rows = append(rows, []string{metadata.Name, metadata.Version})
@@ -80,5 +96,7 @@ func renderPlugins(rows [][]string) {
 
 func init() {
 	pluginListCmd.Flags().StringVarP(&pluginListFlags.engineVersion, "version", "v", "", "Only show plugins for a specific engine version (default show all versions)")
+	addOutputFlag(pluginListCmd, &pluginListFlags.output)
+	registerPluginVersionCompletions(pluginListCmd)
 	pluginCmd.AddCommand(pluginListCmd)
 }
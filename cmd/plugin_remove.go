@@ -0,0 +1,105 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/plugin"
+	"github.com/spf13/cobra"
+)
+
+var pluginRemoveFlags = struct {
+	engineVersion string
+	removeDefault bool
+}{}
+
+// pluginRemoveCmd represents the pluginRemove command
+var pluginRemoveCmd = &cobra.Command{
+	Use:     "remove PLUGIN_NAME_1[:VERSION] [PLUGIN_NAME_N[:VERSION]...]",
+	Aliases: []string{"rm"},
+	Short:   "Remove plugins",
+	Long: `Removes installed plugins.
+
+If version is not specified, the plugins are removed for all
+installed engine versions. The version can also be given for
+each plugin, after its name.
+
+Example 1: Remove named plugin for all engine versions
+
+	imposter plugin remove store-redis
+
+Example 2: Remove named plugin for a specific engine version
+
+	imposter plugin remove soap:4.2.0`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		removePlugins(args, pluginRemoveFlags.engineVersion, pluginRemoveFlags.removeDefault)
+	},
+}
+
+func init() {
+	pluginRemoveCmd.Flags().StringVarP(&pluginRemoveFlags.engineVersion, "version", "v", "", "Only remove plugins for a specific engine version (default all versions)")
+	pluginRemoveCmd.Flags().BoolVarP(&pluginRemoveFlags.removeDefault, "remove-default", "d", false, "Whether to also remove the plugin from the default plugins")
+	registerPluginVersionCompletions(pluginRemoveCmd)
+	pluginCmd.AddCommand(pluginRemoveCmd)
+}
+
+func removePlugins(plugins []string, version string, removeDefault bool) {
+	var installedVersions []string
+	var removed int
+	var names []string
+	for _, ref := range plugins {
+		pluginName, pluginVersion := plugin.ParsePluginRef(ref)
+		names = append(names, pluginName)
+
+		var versions []string
+		if pluginVersion != "" {
+			versions = []string{pluginVersion}
+		} else if version != "" {
+			versions = []string{version}
+		} else {
+			if installedVersions == nil {
+				v, err := plugin.ListVersionDirs()
+				if err != nil {
+					logger.Fatal(err)
+				}
+				installedVersions = v
+			}
+			versions = installedVersions
+		}
+
+		for _, v := range versions {
+			ok, err := plugin.Remove(pluginName, v)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if ok {
+				logger.Debugf("removed plugin %s version %s", pluginName, v)
+				removed++
+			}
+		}
+	}
+	if removeDefault {
+		if err := plugin.RemoveDefaultPlugins(names); err != nil {
+			logger.Fatalf("error removing default plugins: %s", err)
+		}
+	}
+	if removed == 0 {
+		logger.Infof("no plugins to remove")
+	} else {
+		logger.Infof("%d plugin(s) removed", removed)
+	}
+}
@@ -17,32 +17,223 @@ limitations under the License.
 package cmd
 
 import (
+	"crypto/tls"
 	"fmt"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/impostermodel"
 	"gatehill.io/imposter/proxy"
+	"gatehill.io/imposter/workspace"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 var proxyFlags = struct {
+	host                      string
 	port                      int
 	outputDir                 string
 	rewrite                   bool
 	ignoreDuplicateRequests   bool
 	recordOnlyResponseHeaders []string
 	flatResponseFileStructure bool
+	contentAddressed          bool
+	saveToLibrary             bool
+	tags                      []string
+	anonymise                 []string
+	outputFormats             []string
+	pactConsumer              string
+	pactProvider              string
+	delayProfile              string
+	tls                       bool
+	certFile                  string
+	keyFile                   string
+	mitm                      bool
+	mitmTunnel                bool
+	controlToken              string
+	maxBodySize               int64
+	recordWebSockets          bool
+	recordPaths               []string
+	ignorePaths               []string
+	recordMethods             []string
+	ignoreMethods             []string
+	redactHeaders             []string
+	redactJsonPaths           []string
+	redactQueryParams         []string
+	redactPatterns            []string
+	upstreamBearerToken       string
+	upstreamBasicAuth         string
+	upstreamHeaders           []string
+	upstreamTokenUrl          string
+	upstreamClientId          string
+	upstreamClientSecret      string
+	upstreamScopes            []string
+	routes                    []string
+	chaosDelay                string
+	chaosDelayPercent         float64
+	chaosErrorStatus          int
+	chaosErrorPercent         float64
+	chaosDropPercent          float64
+	rateLimit                 string
+	pathRateLimits            []string
+	rewriteRulesFile          string
+	rewriteHost               string
+	stripPathPrefix           string
+	addPathPrefix             string
+	addRequestHeaders         []string
+	removeRequestHeaders      []string
+	replaceRequestBody        []string
+	addResponseHeaders        []string
+	removeResponseHeaders     []string
+	replaceResponseBody       []string
+	upstreamTimeout           time.Duration
+	upstreamClientCert        string
+	upstreamClientKey         string
+	upstreamCa                string
+	insecureSkipVerify        bool
+	http2                     bool
+	protoDescriptors          []string
+	startPaused               bool
+	retries                   int
+	retryOn                   []string
+	trace                     bool
+	otlpEndpoint              string
+	otlpHeaders               []string
+	summary                   bool
 }{}
 
+// proxyHandlerOptions configures the handlers wrapping the proxy, which
+// act on requests before they are forwarded to the upstream.
+type proxyHandlerOptions struct {
+	chaos      proxy.ChaosOptions
+	rateLimits proxy.RateLimitOptions
+}
+
+// proxyListenOptions configures how the proxy accepts client connections.
+type proxyListenOptions struct {
+	// host is the address on which the proxy listens
+	host string
+
+	// tlsConfig is set if the proxy listens for HTTPS connections
+	tlsConfig *tls.Config
+
+	// mitmCa is set if the proxy acts as a forward proxy, intercepting
+	// HTTPS connections to the upstream
+	mitmCa *tls.Certificate
+
+	// mitmTunnel relays connections to hosts other than the upstream,
+	// when acting as a forward proxy, instead of refusing them
+	mitmTunnel bool
+
+	// http2 accepts HTTP/2 connections from clients, negotiated using ALPN
+	// over TLS, or with prior knowledge (h2c) otherwise
+	http2 bool
+
+	// summary prints a summary of the requests received when the
+	// proxy is stopped
+	summary bool
+}
+
 // proxyCmd represents the up command
 var proxyCmd = &cobra.Command{
 	Use:   "proxy [URL]",
 	Short: "Proxy an endpoint and record HTTP exchanges",
-	Long:  `Proxies an endpoint and records HTTP exchanges to file, in Imposter format.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Proxies an endpoint and records HTTP exchanges to file, in Imposter format.
+
+If routes are provided, requests are proxied to the upstream of the first
+route matching the request path, and the exchanges with each upstream are
+recorded to a separate directory. Requests matching no route are proxied
+to URL, if provided.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		upstream := args[0]
+		var upstream string
+		if len(args) > 0 {
+			upstream = args[0]
+		}
+		var routes []proxy.Route
+		if len(proxyFlags.routes) > 0 {
+			var err error
+			if routes, err = proxy.ParseRoutes(proxyFlags.routes, upstream); err != nil {
+				failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+			}
+			if proxyFlags.mitm {
+				failure.Fatal(failure.New(failure.CodeCliUsage, "--mitm cannot be used with --route"))
+			}
+		} else if upstream == "" {
+			failure.Fatal(failure.New(failure.CodeCliUsage, "an upstream URL or at least one --route must be provided"))
+		}
+		if proxyFlags.mitmTunnel && !proxyFlags.mitm {
+			failure.Fatal(failure.New(failure.CodeCliUsage, "--mitm-tunnel requires --mitm"))
+		}
+		delayProfile, err := impostermodel.ParseDelayProfile(proxyFlags.delayProfile)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		upstreamAuth, err := buildUpstreamAuth(upstream)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		if err := proxy.SetUpstreamAuth(upstreamAuth); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		proxy.SetControlToken(flagOrConfig(proxyFlags.controlToken, "proxy.controlToken"))
+		if err := configureUpstreamTls(); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		retryPolicy, err := proxy.ParseRetryOn(proxyFlags.retryOn)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		retryPolicy.Retries = proxyFlags.retries
+		upstreamOptions := proxy.UpstreamOptions{
+			Timeout: proxyFlags.upstreamTimeout,
+			Retry:   retryPolicy,
+			Http2:   proxyFlags.http2,
+		}
+		if err := proxy.SetUpstreamOptions(upstreamOptions); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		rewriteRules, err := buildRewriteRules()
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		if err := proxy.SetRewriteRules(rewriteRules); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		tracingOptions, err := buildTracingOptions()
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		if err := proxy.SetTracing(tracingOptions); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		handlerOptions, err := buildProxyHandlerOptions()
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		listenOptions, err := buildProxyListenOptions(proxyFlags.tls, proxyFlags.certFile, proxyFlags.keyFile, proxyFlags.mitm, proxyFlags.http2)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeProxyTlsInvalid, err))
+		}
+		listenOptions.host = proxyFlags.host
+		listenOptions.mitmTunnel = proxyFlags.mitmTunnel
+		listenOptions.summary = proxyFlags.summary
 		var outputDir string
-		if proxyFlags.outputDir != "" {
+		if proxyFlags.saveToLibrary {
+			libraryUpstream := upstream
+			if libraryUpstream == "" {
+				libraryUpstream = routes[0].Upstream
+			}
+			outputDir = createLibraryRecording(libraryUpstream, proxyFlags.outputDir, parseTags(proxyFlags.tags))
+		} else if proxyFlags.outputDir != "" {
 			outputDir = proxyFlags.outputDir
 		} else {
 			workingDir, err := os.Getwd()
@@ -55,49 +246,439 @@ var proxyCmd = &cobra.Command{
 			IgnoreDuplicateRequests:   proxyFlags.ignoreDuplicateRequests,
 			RecordOnlyResponseHeaders: proxyFlags.recordOnlyResponseHeaders,
 			FlatResponseFileStructure: proxyFlags.flatResponseFileStructure,
+			ContentAddressed:          proxyFlags.contentAddressed,
+			AnonymisationProfiles:     proxyFlags.anonymise,
+			OutputFormats:             proxyFlags.outputFormats,
+			DelayProfile:              delayProfile,
+			MaxBodySize:               proxyFlags.maxBodySize,
+			RecordWebSockets:          proxyFlags.recordWebSockets,
+			RecordPaths:               proxyFlags.recordPaths,
+			IgnorePaths:               proxyFlags.ignorePaths,
+			RecordMethods:             proxyFlags.recordMethods,
+			IgnoreMethods:             proxyFlags.ignoreMethods,
+			Redaction: proxy.RedactionRules{
+				Headers:     proxyFlags.redactHeaders,
+				QueryParams: proxyFlags.redactQueryParams,
+				JsonPaths:   proxyFlags.redactJsonPaths,
+				Patterns:    proxyFlags.redactPatterns,
+			},
+			ProtoDescriptors: proxyFlags.protoDescriptors,
+			PactConsumer:     proxyFlags.pactConsumer,
+			PactProvider:     proxyFlags.pactProvider,
+		}
+		if proxyFlags.startPaused {
+			logger.Infof("recording is paused - resume it with: POST /system/recording/resume")
+			proxy.PauseRecording()
+		}
+		if len(routes) > 0 {
+			proxyRoutes(routes, proxyFlags.port, outputDir, proxyFlags.rewrite, options, handlerOptions, listenOptions)
+		} else {
+			proxyUpstream(upstream, proxyFlags.port, outputDir, proxyFlags.rewrite, options, handlerOptions, listenOptions)
 		}
-		proxyUpstream(upstream, proxyFlags.port, outputDir, proxyFlags.rewrite, options)
 	},
 }
 
 func init() {
+	proxyCmd.Flags().StringVar(&proxyFlags.host, "host", proxy.DefaultHost, "Address on which to listen - use 0.0.0.0 to accept connections from other hosts")
 	proxyCmd.Flags().IntVarP(&proxyFlags.port, "port", "p", 8080, "Port on which to listen")
 	proxyCmd.Flags().StringVarP(&proxyFlags.outputDir, "output-dir", "o", "", "Directory in which HTTP exchanges are recorded (default: current working directory)")
 	proxyCmd.Flags().BoolVarP(&proxyFlags.rewrite, "rewrite-urls", "r", false, "Rewrite upstream URL in response body to proxy URL")
 	proxyCmd.Flags().BoolVarP(&proxyFlags.ignoreDuplicateRequests, "ignore-duplicate-requests", "i", true, "Ignore duplicate requests with same method and URI")
 	proxyCmd.Flags().StringSliceVarP(&proxyFlags.recordOnlyResponseHeaders, "response-headers", "H", nil, "Record only these response headers")
 	proxyCmd.Flags().BoolVar(&proxyFlags.flatResponseFileStructure, "flat", false, "Flatten the response file structure")
+	proxyCmd.Flags().BoolVar(&proxyFlags.contentAddressed, "content-addressed", false, "Store each response body once, in a file named by the hash of its content, under the 'responses' directory")
+	proxyCmd.Flags().BoolVar(&proxyFlags.saveToLibrary, "library", false, "Save the recording to the recording library of the workspace in the output directory (default: current working directory)")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.tags, "tag", nil, "Tag for the recording in the workspace recording library, in the form KEY=VALUE (e.g. scenario=checkout)")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.outputFormats, "output-format", []string{"imposter"}, "Formats in which exchanges are recorded (valid: imposter,har,openapi,pact)")
+	proxyCmd.Flags().StringVar(&proxyFlags.pactConsumer, "pact-consumer", "", "Name of the consumer in the recorded Pact file (default \"imposter-recording\")")
+	proxyCmd.Flags().StringVar(&proxyFlags.pactProvider, "pact-provider", "", "Name of the provider in the recorded Pact file (default is the upstream host)")
+	proxyCmd.Flags().StringVar(&proxyFlags.delayProfile, "delay-profile", "", "Simulate response latency for recorded resources (fixed:<ms>|uniform:<min>-<max>|normal:<mean>,<stddev>|empirical)")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.anonymise, "anonymise", nil, "Anonymise personal data in recorded request query values and bodies, and response bodies, using these profiles (valid: names,emails,cards,phones,gdpr)")
+	proxyCmd.Flags().BoolVar(&proxyFlags.tls, "tls", false, "Listen for HTTPS connections, using a self-signed certificate unless a certificate is provided")
+	proxyCmd.Flags().StringVar(&proxyFlags.certFile, "cert-file", "", "Path to a PEM encoded TLS certificate (or chain) - requires --tls and --key-file")
+	proxyCmd.Flags().StringVar(&proxyFlags.keyFile, "key-file", "", "Path to the PEM encoded private key for the TLS certificate - requires --tls and --cert-file")
+	proxyCmd.Flags().BoolVar(&proxyFlags.mitm, "mitm", false, "Act as a forward proxy, intercepting HTTPS connections to the upstream using a locally generated CA, and refusing others")
+	proxyCmd.Flags().BoolVar(&proxyFlags.mitmTunnel, "mitm-tunnel", false, "Relay connections to hosts other than the upstream without interception, instead of refusing them - requires --mitm")
+	proxyCmd.Flags().StringVar(&proxyFlags.controlToken, "control-token", "", "Bearer token required to stream exchanges from the proxy or control the recording - if not set, only clients on the local machine may do so")
+	proxyCmd.Flags().Int64Var(&proxyFlags.maxBodySize, "max-body-size", proxy.DefaultMaxRecordBodySize, "Maximum size in bytes of request and response bodies to record - larger exchanges are proxied but not recorded (0 for no limit)")
+	proxyCmd.Flags().BoolVar(&proxyFlags.recordWebSockets, "record-websockets", false, "Record messages exchanged over WebSocket connections to a transcript file")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.recordPaths, "record-path", nil, "Only record requests whose path matches this glob, or regular expression prefixed with 'regex:' (e.g. /api/**)")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.ignorePaths, "ignore-path", nil, "Do not record requests whose path matches this glob, or regular expression prefixed with 'regex:' (e.g. /static/**)")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.recordMethods, "record-method", nil, "Only record requests with these methods (e.g. GET,POST)")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.ignoreMethods, "ignore-method", nil, "Do not record requests with these methods (e.g. OPTIONS,HEAD)")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.redactHeaders, "redact-header", nil, "Redact the values of these request and response headers in recordings, in addition to Authorization, Cookie, Proxy-Authorization and Set-Cookie")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.redactJsonPaths, "redact-json-path", nil, "Redact the value at this path in JSON request and response bodies in recordings (e.g. $.user.ssn or $.items[*].card)")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.redactPatterns, "redact-pattern", nil, "Redact matches of this regular expression from bodies, header values and query parameter values in recordings")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.redactQueryParams, "redact-query-param", nil, "Redact the values of these request query parameters in recordings, in addition to access_token, api_key, apikey, client_secret, password and token")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamBearerToken, "upstream-bearer-token", "", "Bearer token sent to the upstream in the Authorization header")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamBasicAuth, "upstream-basic-auth", "", "Credentials sent to the upstream using HTTP basic auth, in the form USERNAME:PASSWORD")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.upstreamHeaders, "upstream-header", nil, "Header sent to the upstream, in the form 'NAME: VALUE' (e.g. 'X-Api-Key: abc123')")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamTokenUrl, "upstream-oauth2-token-url", "", "URL from which to obtain an OAuth2 access token for the upstream, using the client credentials grant")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamClientId, "upstream-oauth2-client-id", "", "OAuth2 client ID used to obtain an access token for the upstream")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamClientSecret, "upstream-oauth2-client-secret", "", "OAuth2 client secret used to obtain an access token for the upstream")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.upstreamScopes, "upstream-oauth2-scope", nil, "OAuth2 scopes requested for the upstream access token")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.routes, "route", nil, "Route requests whose path matches a glob, or regular expression prefixed with 'regex:', to an upstream, in the form PATTERN=URL (e.g. '/users/**=https://users.example.com')")
+	proxyCmd.Flags().StringVar(&proxyFlags.chaosDelay, "chaos-delay", "", "Delay added to requests selected by --chaos-delay-percent, or a range from which it is chosen at random (e.g. 500ms or 100ms-2s)")
+	proxyCmd.Flags().Float64Var(&proxyFlags.chaosDelayPercent, "chaos-delay-percent", 0, "Percentage of requests to delay")
+	proxyCmd.Flags().IntVar(&proxyFlags.chaosErrorStatus, "chaos-error-status", http.StatusServiceUnavailable, "HTTP status returned to requests selected by --chaos-error-percent")
+	proxyCmd.Flags().Float64Var(&proxyFlags.chaosErrorPercent, "chaos-error-percent", 0, "Percentage of requests to respond to with an error, instead of forwarding them to the upstream")
+	proxyCmd.Flags().Float64Var(&proxyFlags.chaosDropPercent, "chaos-drop-percent", 0, "Percentage of requests whose connection is closed without a response")
+	proxyCmd.Flags().StringVar(&proxyFlags.rateLimit, "rate-limit", "", "Maximum rate of requests to the proxy, in the form N/s, N/m or N/h - requests exceeding it receive a 429 response")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.pathRateLimits, "path-rate-limit", nil, "Maximum rate of requests whose path matches a glob, or regular expression prefixed with 'regex:', in the form PATTERN=N/s (e.g. '/search/**=5/s')")
+	proxyCmd.Flags().StringVar(&proxyFlags.rewriteRulesFile, "rewrite-rules", "", "Path to a YAML file of rules rewriting requests to the upstream and responses to the client - rewrite flags are applied in addition")
+	proxyCmd.Flags().StringVar(&proxyFlags.rewriteHost, "rewrite-host", "", "Host header sent to the upstream")
+	proxyCmd.Flags().StringVar(&proxyFlags.stripPathPrefix, "strip-path-prefix", "", "Prefix removed from the path of requests to the upstream")
+	proxyCmd.Flags().StringVar(&proxyFlags.addPathPrefix, "add-path-prefix", "", "Prefix added to the path of requests to the upstream")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.addRequestHeaders, "add-request-header", nil, "Header added to requests to the upstream, in the form 'NAME: VALUE'")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.removeRequestHeaders, "remove-request-header", nil, "Header removed from requests to the upstream")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.replaceRequestBody, "replace-request-body", nil, "String replaced in request bodies sent to the upstream, in the form FIND=REPLACE")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.addResponseHeaders, "add-response-header", nil, "Header added to responses to the client, in the form 'NAME: VALUE'")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.removeResponseHeaders, "remove-response-header", nil, "Header removed from responses to the client")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.replaceResponseBody, "replace-response-body", nil, "String replaced in text response bodies sent to the client, in the form FIND=REPLACE")
+	proxyCmd.Flags().DurationVar(&proxyFlags.upstreamTimeout, "upstream-timeout", proxy.DefaultUpstreamTimeout, "Time to wait for the upstream to start responding, or 0 for no limit")
+	proxyCmd.Flags().IntVar(&proxyFlags.retries, "retries", 0, "Number of times to retry requests with idempotent methods that fail with a condition in --retry-on")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.retryOn, "retry-on", []string{proxy.RetryOnConnectionError, "502", "503", "504"}, "Conditions on which to retry requests: HTTP status codes, or 'connection-error' for connection failures and timeouts")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamClientCert, "upstream-client-cert", "", "Path to a PEM client certificate presented to upstreams requiring mutual TLS")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamClientKey, "upstream-client-key", "", "Path to the PEM private key for --upstream-client-cert")
+	proxyCmd.Flags().StringVar(&proxyFlags.upstreamCa, "upstream-ca", "", "Path to a PEM bundle of CA certificates trusted to sign upstream certificates, instead of the system roots")
+	proxyCmd.Flags().BoolVar(&proxyFlags.insecureSkipVerify, "insecure-skip-verify", false, "Do not verify upstream TLS certificates")
+	proxyCmd.Flags().BoolVar(&proxyFlags.http2, "http2", false, "Use HTTP/2 with clients and upstreams, as required to proxy gRPC - h2c (HTTP/2 without TLS) is used with plain HTTP upstreams")
+	proxyCmd.Flags().StringSliceVar(&proxyFlags.protoDescriptors, "proto-descriptor", nil, "Path to a protobuf descriptor set (from protoc --include_imports --descriptor_set_out) used to record gRPC messages as JSON")
+	proxyCmd.Flags().BoolVar(&proxyFlags.trace, "trace", false, "Start a W3C trace for requests without a traceparent header, so the upstream receives one for every request")
+	proxyCmd.Flags().StringVar(&proxyFlags.otlpEndpoint, "otlp-endpoint", "", "URL of an OpenTelemetry collector to which a span is exported for each proxied request, using OTLP over HTTP (e.g. http://localhost:4318) - implies --trace")
+	proxyCmd.Flags().StringArrayVar(&proxyFlags.otlpHeaders, "otlp-header", nil, "Header sent to the OpenTelemetry collector, in the form 'NAME: VALUE'")
+	proxyCmd.Flags().BoolVar(&proxyFlags.startPaused, "start-paused", false, "Proxy requests without recording them until recording is resumed using the control API")
+	proxyCmd.Flags().BoolVar(&proxyFlags.summary, "summary", true, "Print a summary of the requests received when the proxy stops")
 	rootCmd.AddCommand(proxyCmd)
 }
 
-func proxyUpstream(upstream string, port int, dir string, rewrite bool, options proxy.RecorderOptions) {
+func buildProxyListenOptions(enableTls bool, certFile string, keyFile string, mitm bool, enableHttp2 bool) (proxyListenOptions, error) {
+	listenOptions := proxyListenOptions{http2: enableHttp2}
+	if !enableTls && (certFile != "" || keyFile != "") {
+		return proxyListenOptions{}, fmt.Errorf("TLS must be enabled to use a certificate")
+	}
+	if (certFile == "") != (keyFile == "") {
+		return proxyListenOptions{}, fmt.Errorf("both a certificate and key file must be provided")
+	}
+	if enableTls {
+		certPem, keyPem, err := engine.ResolveCertificate(certFile, keyFile, certFile == "")
+		if err != nil {
+			return proxyListenOptions{}, err
+		}
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return proxyListenOptions{}, fmt.Errorf("failed to load certificate: %v", err)
+		}
+		listenOptions.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"http/1.1"},
+		}
+		if enableHttp2 {
+			listenOptions.tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+		}
+	}
+	if mitm {
+		ca, caCertPath, err := proxy.EnsureMitmCa()
+		if err != nil {
+			return proxyListenOptions{}, err
+		}
+		logger.Infof("intercepting HTTPS connections using the CA certificate: %s - clients must trust it", caCertPath)
+		listenOptions.mitmCa = ca
+	}
+	return listenOptions, nil
+}
+
+func buildProxyHandlerOptions() (proxyHandlerOptions, error) {
+	chaos, err := buildChaosOptions()
+	if err != nil {
+		return proxyHandlerOptions{}, err
+	}
+	globalLimit, err := proxy.ParseRateLimit(proxyFlags.rateLimit)
+	if err != nil {
+		return proxyHandlerOptions{}, err
+	}
+	pathLimits, err := proxy.ParsePathRateLimits(proxyFlags.pathRateLimits)
+	if err != nil {
+		return proxyHandlerOptions{}, err
+	}
+	return proxyHandlerOptions{
+		chaos:      chaos,
+		rateLimits: proxy.RateLimitOptions{Global: globalLimit, Paths: pathLimits},
+	}, nil
+}
+
+func buildChaosOptions() (proxy.ChaosOptions, error) {
+	delayMin, delayMax, err := proxy.ParseChaosDelay(proxyFlags.chaosDelay)
+	if err != nil {
+		return proxy.ChaosOptions{}, err
+	}
+	chaos := proxy.ChaosOptions{
+		DelayMin:     delayMin,
+		DelayMax:     delayMax,
+		DelayPercent: proxyFlags.chaosDelayPercent,
+		ErrorStatus:  proxyFlags.chaosErrorStatus,
+		ErrorPercent: proxyFlags.chaosErrorPercent,
+		DropPercent:  proxyFlags.chaosDropPercent,
+	}
+	return chaos, chaos.Validate()
+}
+
+// buildUpstreamAuth returns the credentials for the upstream, which are not
+// sent to the upstreams of routes. Each flag
+// falls back to its 'proxy.upstreamAuth' configuration setting, so secrets
+// can be provided using environment variables instead.
+func buildUpstreamAuth(upstream string) (proxy.UpstreamAuth, error) {
+	headers, err := proxy.ParseHeaders(proxyFlags.upstreamHeaders)
+	if err != nil {
+		return proxy.UpstreamAuth{}, err
+	}
+	scopes := proxyFlags.upstreamScopes
+	if len(scopes) == 0 {
+		scopes = viper.GetStringSlice("proxy.upstreamAuth.oauth2Scopes")
+	}
+	return proxy.UpstreamAuth{
+		Upstream:    upstream,
+		BearerToken: flagOrConfig(proxyFlags.upstreamBearerToken, "proxy.upstreamAuth.bearerToken"),
+		BasicAuth:   flagOrConfig(proxyFlags.upstreamBasicAuth, "proxy.upstreamAuth.basicAuth"),
+		Headers:     headers,
+		OAuth2: proxy.OAuth2ClientCredentials{
+			TokenUrl:     flagOrConfig(proxyFlags.upstreamTokenUrl, "proxy.upstreamAuth.oauth2TokenUrl"),
+			ClientId:     flagOrConfig(proxyFlags.upstreamClientId, "proxy.upstreamAuth.oauth2ClientId"),
+			ClientSecret: flagOrConfig(proxyFlags.upstreamClientSecret, "proxy.upstreamAuth.oauth2ClientSecret"),
+			Scopes:       scopes,
+		},
+	}, nil
+}
+
+// buildTracingOptions returns the tracing settings. The collector endpoint
+// falls back to its 'proxy.tracing.otlpEndpoint' configuration setting, then
+// the standard OpenTelemetry environment variables.
+func buildTracingOptions() (proxy.TracingOptions, error) {
+	headers, err := proxy.ParseHeaders(proxyFlags.otlpHeaders)
+	if err != nil {
+		return proxy.TracingOptions{}, err
+	}
+	endpoint := flagOrConfig(proxyFlags.otlpEndpoint, "proxy.tracing.otlpEndpoint")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	serviceName := viper.GetString("proxy.tracing.serviceName")
+	if serviceName == "" {
+		serviceName = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	return proxy.TracingOptions{
+		Enabled:      proxyFlags.trace || viper.GetBool("proxy.tracing.enabled"),
+		OtlpEndpoint: endpoint,
+		OtlpHeaders:  headers,
+		ServiceName:  serviceName,
+	}, nil
+}
+
+// buildRewriteRules returns the rules from the rules file, if provided,
+// with the rules from flags applied in addition. Flags replace the host and
+// path prefixes set in the file.
+func buildRewriteRules() (proxy.RewriteRules, error) {
+	var rules proxy.RewriteRules
+	if proxyFlags.rewriteRulesFile != "" {
+		var err error
+		if rules, err = proxy.LoadRewriteRules(proxyFlags.rewriteRulesFile); err != nil {
+			return proxy.RewriteRules{}, err
+		}
+	}
+	if proxyFlags.rewriteHost != "" {
+		rules.Host = proxyFlags.rewriteHost
+	}
+	if proxyFlags.stripPathPrefix != "" {
+		rules.StripPathPrefix = proxyFlags.stripPathPrefix
+	}
+	if proxyFlags.addPathPrefix != "" {
+		rules.AddPathPrefix = proxyFlags.addPathPrefix
+	}
+	if err := addMessageRewrites(&rules.Request, proxyFlags.addRequestHeaders, proxyFlags.removeRequestHeaders, proxyFlags.replaceRequestBody); err != nil {
+		return proxy.RewriteRules{}, err
+	}
+	if err := addMessageRewrites(&rules.Response, proxyFlags.addResponseHeaders, proxyFlags.removeResponseHeaders, proxyFlags.replaceResponseBody); err != nil {
+		return proxy.RewriteRules{}, err
+	}
+	return rules, nil
+}
+
+func addMessageRewrites(rewrite *proxy.MessageRewrite, addHeaders []string, removeHeaders []string, replaceBody []string) error {
+	headers, err := proxy.ParseHeaders(addHeaders)
+	if err != nil {
+		return err
+	}
+	if len(headers) > 0 && rewrite.AddHeaders == nil {
+		rewrite.AddHeaders = make(map[string]string)
+	}
+	for name, value := range headers {
+		rewrite.AddHeaders[name] = value
+	}
+	rewrite.RemoveHeaders = append(rewrite.RemoveHeaders, removeHeaders...)
+	replacements, err := proxy.ParseReplacements(replaceBody)
+	if err != nil {
+		return err
+	}
+	rewrite.ReplaceBody = append(rewrite.ReplaceBody, replacements...)
+	return nil
+}
+
+// upstreamTlsHost is an entry in the 'proxy.upstreamTls.hosts' configuration
+// setting, overriding the TLS settings for a single upstream host.
+type upstreamTlsHost struct {
+	Host               string
+	ClientCert         string
+	ClientKey          string
+	Ca                 string
+	InsecureSkipVerify bool
+}
+
+// configureUpstreamTls sets the TLS settings for connections to upstreams.
+// Each flag falls back to its 'proxy.upstreamTls' configuration setting,
+// and the settings for individual hosts are read from configuration.
+func configureUpstreamTls() error {
+	defaults := proxy.UpstreamTls{
+		ClientCertFile:     flagOrConfig(proxyFlags.upstreamClientCert, "proxy.upstreamTls.clientCert"),
+		ClientKeyFile:      flagOrConfig(proxyFlags.upstreamClientKey, "proxy.upstreamTls.clientKey"),
+		CaFile:             flagOrConfig(proxyFlags.upstreamCa, "proxy.upstreamTls.ca"),
+		InsecureSkipVerify: proxyFlags.insecureSkipVerify || viper.GetBool("proxy.upstreamTls.insecureSkipVerify"),
+	}
+	var entries []upstreamTlsHost
+	if err := viper.UnmarshalKey("proxy.upstreamTls.hosts", &entries); err != nil {
+		return fmt.Errorf("invalid upstream TLS host configuration: %v", err)
+	}
+	hosts := make(map[string]proxy.UpstreamTls)
+	for _, entry := range entries {
+		if entry.Host == "" {
+			return fmt.Errorf("upstream TLS host configuration must specify a host")
+		}
+		hosts[entry.Host] = proxy.UpstreamTls{
+			ClientCertFile:     entry.ClientCert,
+			ClientKeyFile:      entry.ClientKey,
+			CaFile:             entry.Ca,
+			InsecureSkipVerify: entry.InsecureSkipVerify,
+		}
+	}
+	if defaults.InsecureSkipVerify {
+		logger.Warnf("upstream TLS certificates will not be verified")
+	}
+	return proxy.SetUpstreamTls(defaults, hosts)
+}
+
+func flagOrConfig(flagValue string, configKey string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return viper.GetString(configKey)
+}
+
+// createLibraryRecording creates a new recording in the workspace recording
+// library, returning the directory into which exchanges should be recorded.
+func createLibraryRecording(upstream string, workspaceDir string, tags map[string]string) string {
+	if workspaceDir == "" {
+		workspaceDir, _ = os.Getwd()
+	}
+	r, recordingDir, err := workspace.NewRecording(workspaceDir, upstream, tags)
+	if err != nil {
+		logger.Fatalf("failed to create recording: %s", err)
+	}
+	logger.Infof("recording to library with ID '%s'", r.ID)
+	return recordingDir
+}
+
+func proxyUpstream(upstream string, port int, dir string, rewrite bool, options proxy.RecorderOptions, handlerOptions proxyHandlerOptions, listenOptions proxyListenOptions) {
 	logger.Infof("starting proxy for upstream %s on port %v", upstream, port)
 	recorderC, err := proxy.StartRecorder(upstream, dir, options)
 	if err != nil {
-		logger.Fatal(err)
+		failure.Fatal(err)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/system/status", func(writer http.ResponseWriter, request *http.Request) {
-		_, _ = fmt.Fprintf(writer, "ok\n")
-	})
-	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
-		proxy.Handle(upstream, writer, request, func(statusCode int, respBody *[]byte, respHeaders *http.Header) (*[]byte, *http.Header) {
-			if rewrite {
-				respBody = proxy.Rewrite(respHeaders, respBody, upstream, port)
-			}
-			recorderC <- proxy.HttpExchange{
-				Request:         request,
-				StatusCode:      statusCode,
-				ResponseBody:    respBody,
-				ResponseHeaders: respHeaders,
-			}
-			return respBody, respHeaders
-		})
-	})
+	proxyBaseUrl := proxy.BuildProxyBaseUrl(port, listenOptions.tlsConfig != nil)
+	var handler http.Handler = proxy.BuildRecordingMux(upstream, proxyBaseUrl, rewrite, options.MaxBodySize, recorderC)
+	handler = wrapProxyHandler(handler, handlerOptions)
+	if listenOptions.mitmCa != nil {
+		handler, err = proxy.NewMitmHandler(upstream, listenOptions.mitmCa, listenOptions.mitmTunnel, handler)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infof("configure clients to use %s as their HTTP and HTTPS proxy", proxyBaseUrl)
+	}
+	serveProxy(handler, port, listenOptions)
+}
 
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+// proxyRoutes proxies each request to the upstream of the first matching
+// route, recording the exchanges with each upstream separately.
+func proxyRoutes(routes []proxy.Route, port int, dir string, rewrite bool, options proxy.RecorderOptions, handlerOptions proxyHandlerOptions, listenOptions proxyListenOptions) {
+	for _, route := range routes {
+		logger.Infof("routing %s to upstream %s", route.Pattern, route.Upstream)
+	}
+	logger.Infof("starting proxy for %d routes on port %v", len(routes), port)
+	recorders, err := proxy.StartRouteRecorders(routes, dir, options)
 	if err != nil {
-		logger.Fatal(err)
+		failure.Fatal(err)
 	}
+
+	proxyBaseUrl := proxy.BuildProxyBaseUrl(port, listenOptions.tlsConfig != nil)
+	handler := proxy.BuildRoutingMux(routes, proxyBaseUrl, rewrite, options.MaxBodySize, recorders)
+	serveProxy(wrapProxyHandler(handler, handlerOptions), port, listenOptions)
+}
+
+// wrapProxyHandler wraps the handler to inject faults and limit the
+// request rate, if enabled. Both act before exchanges reach the recorder,
+// so injected faults and throttled requests are not recorded.
+func wrapProxyHandler(handler http.Handler, options proxyHandlerOptions) http.Handler {
+	if options.chaos.Enabled() {
+		chaos := options.chaos
+		logger.Warnf("chaos mode enabled - injecting faults [delay: %v%%, error: %v%%, drop: %v%%]", chaos.DelayPercent, chaos.ErrorPercent, chaos.DropPercent)
+		handler = proxy.WithChaos(handler, chaos)
+	}
+	if options.rateLimits.Enabled() {
+		logger.Infof("limiting request rate [global: %v, path limits: %d]", options.rateLimits.Global, len(options.rateLimits.Paths))
+		handler = proxy.WithRateLimit(handler, options.rateLimits)
+	}
+	return handler
+}
+
+func serveProxy(handler http.Handler, port int, listenOptions proxyListenOptions) {
+	if listenOptions.http2 && listenOptions.tlsConfig == nil {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	if ip := net.ParseIP(listenOptions.host); ip == nil || !ip.IsLoopback() {
+		logger.Warnf("proxy is reachable from other hosts on %s", listenOptions.host)
+	}
+	server := &http.Server{
+		Addr:      net.JoinHostPort(listenOptions.host, strconv.Itoa(port)),
+		Handler:   handler,
+		TLSConfig: listenOptions.tlsConfig,
+	}
+	trapProxyExit(listenOptions.summary)
+	var err error
+	if listenOptions.tlsConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil {
+		failure.Fatal(failure.Wrap(failure.CodeProxyListenFailed, err))
+	}
+}
+
+// trapProxyExit listens for an interrupt from the OS, then writes the
+// recorded documents not yet written and, if summary is set, prints the
+// summary of the requests received before exiting.
+func trapProxyExit(summary bool) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		proxy.FlushRecorders()
+		if summary {
+			println()
+			proxy.SessionSummary().Write(os.Stdout)
+		}
+		os.Exit(0)
+	}()
 }
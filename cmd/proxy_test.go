@@ -79,9 +79,9 @@ func Test_proxyUpstream(t *testing.T) {
 			}
 
 			go func() {
-				proxyUpstream(upstream, port, outputDir, tt.args.rewrite, tt.args.options)
+				proxyUpstream(upstream, port, outputDir, tt.args.rewrite, tt.args.options, proxyHandlerOptions{}, proxyListenOptions{})
 			}()
-			if up := engine.WaitUntilUp(port, nil); !up {
+			if up := engine.WaitUntilUp(port, false, nil); !up {
 				t.Fatalf("proxy did not come up on port %d", port)
 			}
 
@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/config"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var recordingsFlags struct {
+	path string
+}
+
+// recordingsCmd represents the recordings command
+var recordingsCmd = &cobra.Command{
+	Use:     "recordings",
+	Short:   "Recording library management commands",
+	Aliases: []string{"rec"},
+}
+
+func init() {
+	recordingsCmd.PersistentFlags().StringVarP(&recordingsFlags.path, "workspace", "w", "", "workspace path")
+	rootCmd.AddCommand(recordingsCmd)
+}
+
+func getRecordingsDir() string {
+	if recordingsFlags.path != "" {
+		return recordingsFlags.path
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// parseTags converts tags in the form KEY=VALUE to a map.
+func parseTags(args []string) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range config.ParseConfig(args) {
+		tags[pair.Key] = pair.Value
+	}
+	return tags
+}
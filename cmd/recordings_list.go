@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/workspace"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var recordingsListFlags = struct {
+	tags   []string
+	output string
+}{}
+
+// recordingsListCmd represents the recordings list command
+var recordingsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List recordings",
+	Long:    `Lists the recordings in the workspace recording library.`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listRecordings(getRecordingsDir(), "", parseTags(recordingsListFlags.tags), parseOutputFormat(recordingsListFlags.output))
+	},
+}
+
+func init() {
+	recordingsListCmd.Flags().StringArrayVar(&recordingsListFlags.tags, "tag", nil, "Only list recordings with this tag, in the form KEY=VALUE")
+	addOutputFlag(recordingsListCmd, &recordingsListFlags.output)
+	recordingsCmd.AddCommand(recordingsListCmd)
+}
+
+func listRecordings(dir string, query string, tags map[string]string, format outputFormat) {
+	recordings, err := workspace.SearchRecordings(dir, query, tags)
+	if err != nil {
+		logger.Fatalf("failed to list recordings: %s", err)
+	}
+	if format != outputFormatPlain {
+		if recordings == nil {
+			recordings = []*workspace.Recording{}
+		}
+		printStructured(format, recordings)
+		return
+	}
+	var rows [][]string
+	for _, r := range recordings {
+		rows = append(rows, []string{r.ID, r.Workspace, r.Upstream, r.Created.Format(time.DateTime), formatTags(r.Tags)})
+	}
+	renderRecordings(rows)
+}
+
+func formatTags(tags map[string]string) string {
+	var formatted []string
+	for k, v := range tags {
+		formatted = append(formatted, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(formatted)
+	return strings.Join(formatted, ", ")
+}
+
+func renderRecordings(rows [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Workspace", "Upstream", "Created", "Tags"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(rows)
+	table.Render()
+}
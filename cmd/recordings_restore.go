@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var recordingsRestoreFlags = struct {
+	force bool
+}{}
+
+// recordingsRestoreCmd represents the recordings restore command
+var recordingsRestoreCmd = &cobra.Command{
+	Use:   "restore RECORDING_ID [DIR]",
+	Short: "Restore a recording",
+	Long: `Restores the Imposter configuration and response files from a recording
+in the workspace recording library into DIR, so it can be started with 'imposter up'.
+
+If DIR is not specified, the current working directory is used.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var dest string
+		if len(args) > 1 {
+			dest, _ = filepath.Abs(args[1])
+		} else {
+			dest, _ = os.Getwd()
+		}
+		restoreRecording(getRecordingsDir(), args[0], dest, recordingsRestoreFlags.force)
+	},
+}
+
+func init() {
+	recordingsRestoreCmd.Flags().BoolVarP(&recordingsRestoreFlags.force, "force-overwrite", "f", false, "Force overwrite of destination files if already exist")
+	recordingsCmd.AddCommand(recordingsRestoreCmd)
+}
+
+func restoreRecording(dir string, id string, dest string, force bool) {
+	if _, err := os.Stat(dest); err == nil && !force && config.ContainsConfigFile(dest, false) {
+		logger.Fatalf("destination %s already contains Imposter configuration - use --force-overwrite to replace it", dest)
+	}
+	r, err := workspace.RestoreRecording(dir, id, dest)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("restored recording '%s' of %s to %s", r.ID, r.Upstream, dest)
+}
@@ -0,0 +1,50 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var recordingsSearchFlags = struct {
+	tags   []string
+	output string
+}{}
+
+// recordingsSearchCmd represents the recordings search command
+var recordingsSearchCmd = &cobra.Command{
+	Use:   "search [QUERY]",
+	Short: "Search recordings",
+	Long: `Searches the workspace recording library for recordings whose ID,
+workspace, upstream or tag values contain QUERY.
+
+Use --tag to further restrict the results to recordings with specific tags.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		listRecordings(getRecordingsDir(), query, parseTags(recordingsSearchFlags.tags), parseOutputFormat(recordingsSearchFlags.output))
+	},
+}
+
+func init() {
+	recordingsSearchCmd.Flags().StringArrayVar(&recordingsSearchFlags.tags, "tag", nil, "Only match recordings with this tag, in the form KEY=VALUE")
+	addOutputFlag(recordingsSearchCmd, &recordingsSearchFlags.output)
+	recordingsCmd.AddCommand(recordingsSearchCmd)
+}
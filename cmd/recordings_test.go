@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]string
+	}{
+		{name: "none", args: nil, want: map[string]string{}},
+		{name: "single", args: []string{"scenario=happy-path"}, want: map[string]string{"scenario": "happy-path"}},
+		{name: "multiple", args: []string{"scenario=happy-path", "service=orders"}, want: map[string]string{"scenario": "happy-path", "service": "orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTags(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatTags(t *testing.T) {
+	got := formatTags(map[string]string{"service": "orders", "date": "2024-01-02", "scenario": "happy-path"})
+	want := "date=2024-01-02, scenario=happy-path, service=orders"
+	if got != want {
+		t.Errorf("formatTags() = %v, want %v", got, want)
+	}
+}
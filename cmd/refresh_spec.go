@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// refreshSpecCmd represents the refresh-spec command
+var refreshSpecCmd = &cobra.Command{
+	Use:   "refresh-spec [CONFIG_DIR]",
+	Short: "Re-fetch remote OpenAPI specs",
+	Long: `Re-fetches the remote OpenAPI specs used by a mock, such as those added
+with 'imposter scaffold --spec-url'. If a spec has changed, the resources in
+its configuration are regenerated. Changes to resources still present in the
+spec are kept.
+
+A running mock started with 'imposter up' restarts automatically when its
+configuration changes.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		refreshSpecs(configDir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshSpecCmd)
+}
+
+func refreshSpecs(configDir string) {
+	specs, err := impostermodel.ListRemoteSpecs(configDir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(specs) == 0 {
+		logger.Infof("no remote specs found in %s", configDir)
+		return
+	}
+	changed, err := impostermodel.RefreshRemoteSpecs(configDir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("refreshed %d remote spec(s) - %d changed", len(specs), len(changed))
+}
@@ -48,8 +48,8 @@ func getWorkspaceDir() string {
 	return dir
 }
 
-func suggestWorkspaceNames() ([]string, cobra.ShellCompDirective) {
-	if workspaces, err := workspace.List(getWorkspaceDir()); err == nil {
+func suggestWorkspaceNames(dir string) ([]string, cobra.ShellCompDirective) {
+	if workspaces, err := workspace.List(dir); err == nil {
 		var wsNames []string
 		for _, w := range workspaces {
 			wsNames = append(wsNames, w.Name)
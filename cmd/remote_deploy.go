@@ -22,11 +22,19 @@ import (
 	"os"
 )
 
+var remoteDeployFlags = struct {
+	provider string
+}{}
+
 // remoteDeployCmd represents the remoteDeploy command
 var remoteDeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy active workspace",
-	Long:  `Deploys the active workspace to the remote.`,
+	Long: `Deploys the active workspace to the remote.
+
+If --provider is specified, the workspace is deployed to that remote
+type for this invocation only. The remote type set for the workspace
+is not changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var dir string
 		if remoteFlags.path != "" {
@@ -34,16 +42,20 @@ var remoteDeployCmd = &cobra.Command{
 		} else {
 			dir, _ = os.Getwd()
 		}
-		remoteDeploy(dir)
+		remoteDeploy(dir, remoteDeployFlags.provider)
 	},
 }
 
 func init() {
+	remoteDeployCmd.Flags().StringVar(&remoteDeployFlags.provider, "provider", "", "Remote type to deploy to, instead of the type set for the workspace")
+	_ = remoteDeployCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return remote.ListTypes(), cobra.ShellCompDirectiveNoFileComp
+	})
 	remoteCmd.AddCommand(remoteDeployCmd)
 }
 
-func remoteDeploy(dir string) {
-	active, r, err := remote.LoadActive(dir)
+func remoteDeploy(dir string, provider string) {
+	active, r, err := remote.LoadActiveAs(dir, provider)
 	if err != nil {
 		logger.Fatalf("failed to load remote: %s", err)
 	}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/remote"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+var remoteLogsFlags = struct {
+	follow bool
+	since  time.Duration
+}{}
+
+// remoteLogsCmd represents the remoteLogs command
+var remoteLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show remote logs",
+	Long: `Shows the logs of the mock deployed to the remote for the active
+workspace. Use --follow to stream new log lines as they are written.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		showRemoteLogs(getWorkspaceDir(), remote.LogOptions{
+			Follow: remoteLogsFlags.follow,
+			Since:  remoteLogsFlags.since,
+		})
+	},
+}
+
+func init() {
+	remoteLogsCmd.Flags().BoolVarP(&remoteLogsFlags.follow, "follow", "f", false, "Stream new log lines")
+	remoteLogsCmd.Flags().DurationVar(&remoteLogsFlags.since, "since", 0, "Only show logs written within this duration (e.g. 30m)")
+	remoteCmd.AddCommand(remoteLogsCmd)
+}
+
+func showRemoteLogs(dir string, options remote.LogOptions) {
+	active, r, err := remote.LoadActive(dir)
+	if err != nil {
+		logger.Fatalf("failed to load remote: %s", err)
+	}
+	streamer, ok := (*r).(remote.LogStreamer)
+	if !ok {
+		logger.Fatalf("%s remote for workspace '%s' does not support logs", active.RemoteType, active.Name)
+	}
+	if err = streamer.StreamLogs(os.Stdout, options); err != nil {
+		logger.Fatalf("failed to get remote logs: %s", err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gatehill.io/imposter/remote"
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"os"
+	"strings"
+)
+
+// remoteSecretsCmd represents the remoteSecrets command
+var remoteSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage remote secrets",
+	Long: `Manages the secrets for the remote of the active workspace.
+
+Secrets are injected as environment variables when the workspace is
+deployed. They are stored encrypted in the user's home directory, not
+in the workspace, so they are never committed with the workspace.
+
+The secrets file is encrypted with AES-256-GCM. Its key is never stored
+alongside it: it is kept in the OS keychain (the login keychain on macOS,
+or the Secret Service, via 'secret-tool', on Linux), or, if
+IMPOSTER_SECRETS_PASSPHRASE is set, derived from that passphrase. Set the
+passphrase where there is no keychain, such as on Windows or in CI.
+
+This protects secrets from anyone who can read the file, such as from a
+backup or a synced home directory. It does not protect them from other
+programs running as you, which can read your keychain or environment.`,
+}
+
+// remoteSecretsSetCmd represents the remoteSecretsSet command
+var remoteSecretsSetCmd = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Short: "Set a secret",
+	Long: `Sets a secret for the active workspace. NAME is used as the name of
+the environment variable.
+
+If VALUE is not specified, it is read from stdin, which avoids it
+being recorded in your shell history.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var value string
+		if len(args) > 1 {
+			value = args[1]
+		} else {
+			value = readSecretValue(args[0])
+		}
+		setRemoteSecret(getWorkspaceDir(), args[0], value)
+	},
+}
+
+// remoteSecretsListCmd represents the remoteSecretsList command
+var remoteSecretsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List secrets",
+	Long:    `Lists the names of the secrets for the active workspace. Values are never shown.`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listRemoteSecrets(getWorkspaceDir())
+	},
+}
+
+// remoteSecretsDeleteCmd represents the remoteSecretsDelete command
+var remoteSecretsDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a secret",
+	Long:  `Deletes a secret from the active workspace.`,
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		active, err := workspace.GetActive(getWorkspaceDir())
+		if err != nil || active == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names, _ := remote.ListSecretNames(getWorkspaceDir(), active)
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteRemoteSecret(getWorkspaceDir(), args[0])
+	},
+}
+
+func init() {
+	remoteSecretsCmd.AddCommand(remoteSecretsSetCmd)
+	remoteSecretsCmd.AddCommand(remoteSecretsListCmd)
+	remoteSecretsCmd.AddCommand(remoteSecretsDeleteCmd)
+	remoteCmd.AddCommand(remoteSecretsCmd)
+}
+
+// readSecretValue prompts for the value of the secret. If stdin is a
+// terminal, the value is not echoed.
+func readSecretValue(name string) string {
+	fmt.Fprintf(os.Stderr, "Enter value for %s: ", name)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			logger.Fatalf("failed to read secret value: %s", err)
+		}
+		return string(value)
+	}
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		logger.Fatalf("failed to read secret value: %s", err)
+	}
+	return strings.TrimRight(value, "\r\n")
+}
+
+func getActiveWorkspace(dir string) *workspace.Workspace {
+	active, err := workspace.GetActive(dir)
+	if err != nil {
+		logger.Fatalf("failed to load workspace: %s", err)
+	} else if active == nil {
+		logger.Fatalf("no active workspace")
+	}
+	return active
+}
+
+func setRemoteSecret(dir string, name string, value string) {
+	active := getActiveWorkspace(dir)
+	if err := remote.SetSecret(dir, active, name, value); err != nil {
+		logger.Fatalf("failed to set secret: %s", err)
+	}
+	logger.Infof("set secret %s for workspace: %s", name, active.Name)
+}
+
+func listRemoteSecrets(dir string) {
+	active := getActiveWorkspace(dir)
+	names, err := remote.ListSecretNames(dir, active)
+	if err != nil {
+		logger.Fatalf("failed to list secrets: %s", err)
+	}
+	if len(names) == 0 {
+		fmt.Printf("No secrets for workspace: %s\n", active.Name)
+		return
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+func deleteRemoteSecret(dir string, name string) {
+	active := getActiveWorkspace(dir)
+	if err := remote.DeleteSecret(dir, active, name); err != nil {
+		logger.Fatalf("failed to delete secret: %s", err)
+	}
+	logger.Infof("deleted secret %s from workspace: %s", name, active.Name)
+}
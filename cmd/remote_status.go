@@ -70,7 +70,21 @@ func showRemoteStatus(dir string) {
 			logger.Warnf("failed to get remote details: %s", err)
 		} else {
 			msg += fmt.Sprintf("\nBase URL: %s\nSpec: %s\nStatus: %s", endpoint.BaseUrl, endpoint.SpecUrl, endpoint.StatusUrl)
+			msg += describeRemoteHealth(remote.CheckHealth(endpoint.StatusUrl))
 		}
 	}
 	logger.Info(msg)
 }
+
+func describeRemoteHealth(health remote.Health) string {
+	var desc string
+	if health.Healthy {
+		desc = "\nHealth: healthy"
+	} else {
+		desc = fmt.Sprintf("\nHealth: unhealthy (%s)", health.Message)
+	}
+	if health.Version != "" {
+		desc += fmt.Sprintf("\nEngine version: %s", health.Version)
+	}
+	return desc
+}
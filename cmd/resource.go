@@ -0,0 +1,60 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var resourceFlags struct {
+	configDir string
+}
+
+// resourceCmd represents the resource command
+var resourceCmd = &cobra.Command{
+	Use:   "resource",
+	Short: "Enable or disable resources of a mock",
+	Long: `Enables or disables resources of a mock, by writing an overlay configuration
+file into the config directory.
+
+A running mock started with 'imposter up' reloads automatically when the
+overlay changes, unless auto-restart is disabled.`,
+}
+
+func init() {
+	resourceCmd.PersistentFlags().StringVarP(&resourceFlags.configDir, "config-dir", "c", "", "Mock configuration directory (default is the current working directory)")
+	rootCmd.AddCommand(resourceCmd)
+}
+
+func getResourceConfigDir() string {
+	if resourceFlags.configDir != "" {
+		dir, _ := filepath.Abs(resourceFlags.configDir)
+		return dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// describeResource formats the method and path for display.
+func describeResource(method string, path string) string {
+	if method == "" {
+		return "* " + path
+	}
+	return method + " " + path
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"github.com/spf13/cobra"
+	"net/http"
+)
+
+var resourceDisableFlags = struct {
+	statusCode int
+}{}
+
+// resourceDisableCmd represents the resource disable command
+var resourceDisableCmd = &cobra.Command{
+	Use:   "disable RESOURCE",
+	Short: "Disable a resource",
+	Long: `Disables a resource, so that matching requests receive an error response,
+to simulate a partial outage.
+
+RESOURCE is in the form 'METHOD PATH' or 'PATH', such as 'GET /orders/**'.
+If no method is specified, all methods are disabled. A trailing wildcard
+matches all paths beneath the prefix.
+
+Disabled resources always respond with the status code. Passing requests
+through to a real upstream is not supported, as the engine cannot forward
+them - to do that, put 'imposter proxy' in front of the upstream instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		disableResource(getResourceConfigDir(), args[0], resourceDisableFlags.statusCode)
+	},
+}
+
+func init() {
+	resourceDisableCmd.Flags().IntVar(&resourceDisableFlags.statusCode, "status", http.StatusNotImplemented, "HTTP status code returned by the disabled resource")
+	resourceCmd.AddCommand(resourceDisableCmd)
+}
+
+func disableResource(configDir string, spec string, statusCode int) {
+	method, path, err := impostermodel.ParseResourceSpec(spec)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := impostermodel.DisableResource(configDir, method, path, statusCode); err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("disabled %s with status %d in %s", describeResource(method, path), statusCode, configDir)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"github.com/spf13/cobra"
+)
+
+// resourceEnableCmd represents the resource enable command
+var resourceEnableCmd = &cobra.Command{
+	Use:   "enable RESOURCE",
+	Short: "Re-enable a disabled resource",
+	Long: `Re-enables a resource previously disabled with 'imposter resource disable'.
+
+RESOURCE must match the value used to disable it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		enableResource(getResourceConfigDir(), args[0])
+	},
+}
+
+func init() {
+	resourceCmd.AddCommand(resourceEnableCmd)
+}
+
+func enableResource(configDir string, spec string) {
+	method, path, err := impostermodel.ParseResourceSpec(spec)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	found, err := impostermodel.EnableResource(configDir, method, path)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if !found {
+		logger.Fatalf("%s is not disabled in %s", describeResource(method, path), configDir)
+	}
+	logger.Infof("enabled %s in %s", describeResource(method, path), configDir)
+}
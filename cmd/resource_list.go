@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+// resourceListCmd represents the resource list command
+var resourceListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List disabled resources",
+	Long:    `Lists the resources disabled with 'imposter resource disable'.`,
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listDisabledResources(getResourceConfigDir())
+	},
+}
+
+func init() {
+	resourceCmd.AddCommand(resourceListCmd)
+}
+
+func listDisabledResources(configDir string) {
+	disabled, err := impostermodel.ListDisabledResources(configDir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(disabled) == 0 {
+		logger.Infof("no resources are disabled in %s", configDir)
+		return
+	}
+	var rows [][]string
+	for _, resource := range disabled {
+		method := resource.Method
+		if method == "" {
+			method = "*"
+		}
+		rows = append(rows, []string{method, resource.Path, strconv.Itoa(resource.StatusCode)})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Method", "Path", "Status"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"reflect"
+	"testing"
+)
+
+func Test_describeResource(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: "GET", path: "/orders/*", want: "GET /orders/*"},
+		{method: "", path: "/payments", want: "* /payments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := describeResource(tt.method, tt.path); got != tt.want {
+				t.Errorf("describeResource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_disableEnableResource(t *testing.T) {
+	configDir := t.TempDir()
+	disableResource(configDir, "get /orders/**", 503)
+	disableResource(configDir, "/payments", 501)
+
+	disabled, err := impostermodel.ListDisabledResources(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []impostermodel.DisabledResource{
+		{Method: "GET", Path: "/orders/*", StatusCode: 503},
+		{Path: "/payments", StatusCode: 501},
+	}
+	if !reflect.DeepEqual(disabled, want) {
+		t.Errorf("disabled resources = %+v, want %+v", disabled, want)
+	}
+
+	enableResource(configDir, "GET /orders/**")
+	enableResource(configDir, "/payments")
+	if disabled, err = impostermodel.ListDisabledResources(configDir); err != nil {
+		t.Fatal(err)
+	} else if len(disabled) != 0 {
+		t.Errorf("expected no disabled resources, got: %+v", disabled)
+	}
+}
@@ -19,19 +19,31 @@ package cmd
 import (
 	"gatehill.io/imposter/config"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/logging"
+	"gatehill.io/imposter/selfupdate"
+	"gatehill.io/imposter/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
 	"strings"
+	"time"
 )
 
+// logLevels are the valid values of --log-level.
+var logLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// updateCheckTimeout limits how long startup waits for the new
+// version check.
+const updateCheckTimeout = 2 * time.Second
+
 var logger = logging.GetLogger()
 
 var rootFlags = struct {
 	cfgFile      string
 	printVersion bool
 	logLevel     string
+	errorReport  string
 }{}
 
 // rootCmd represents the base command when called without any subcommands
@@ -54,6 +66,10 @@ Power users can control mock responses with JavaScript or Java/Groovy script eng
 Advanced users can write their own plugins in a JVM language of their choice.
 
 Learn more at www.imposter.sh`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		failure.SetCommand(cmd.CommandPath())
+		notifyNewVersions(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if rootFlags.printVersion {
 			engineType := engine.GetConfiguredType("")
@@ -68,11 +84,19 @@ Learn more at www.imposter.sh`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	if err := rootCmd.Execute(); err != nil {
+		// initialisers are not run if flags fail to parse
+		failure.EnableReport(rootFlags.errorReport, config.Config.Version)
+		if cmd, _, findErr := rootCmd.Find(os.Args[1:]); findErr == nil {
+			failure.SetCommand(cmd.CommandPath())
+		}
+		failure.WriteReport(failure.Wrap(failure.CodeCliUsage, err))
+		cobra.CheckErr(err)
+	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initLogging)
+	cobra.OnInitialize(initConfig, initLogging, initErrorReport)
 
 	// syntactic sugar to support common `<app> --version` usage
 	rootCmd.Flags().BoolVar(&rootFlags.printVersion, "version", false, "Print version information")
@@ -80,6 +104,7 @@ func init() {
 	// Global flags.
 	rootCmd.PersistentFlags().StringVar(&rootFlags.cfgFile, "config", "", "config file (default is $HOME/.imposter/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&rootFlags.logLevel, "log-level", "debug", "log level")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.errorReport, "error-report", "", "Write a JSON diagnosis of any failure to this file")
 
 	registerLogLevelCompletions(rootCmd)
 }
@@ -109,21 +134,63 @@ func initConfig() {
 	}
 }
 
+// initLogging sets the log level from the --log-level flag, if passed,
+// otherwise from the environment, or the 'cli.logLevel' configuration key.
 func initLogging() {
-	if rootFlags.logLevel != "" {
-		logging.SetLogLevel(rootFlags.logLevel)
-		config.Config.LogLevel = strings.ToUpper(rootFlags.logLevel)
+	logLevel := rootFlags.logLevel
+	if !rootCmd.PersistentFlags().Changed("log-level") {
+		logLevel = stringutil.GetFirstNonEmpty(
+			os.Getenv("LOG_LEVEL"),
+			os.Getenv("IMPOSTER_CLI_LOG_LEVEL"),
+			viper.GetString("cli.logLevel"),
+			logLevel,
+		)
+	}
+	if logLevel != "" {
+		logging.SetLogLevel(logLevel)
+		config.Config.LogLevel = strings.ToUpper(logLevel)
+	}
+}
+
+func initErrorReport() {
+	failure.EnableReport(rootFlags.errorReport, config.Config.Version)
+}
+
+// notifyNewVersions prints a hint if a newer CLI or engine release is
+// available. The check is skipped if disabled with the 'cli.updateCheck'
+// configuration key, if stderr is not a terminal, and for commands
+// whose output is consumed by other programs, such as shell completion.
+func notifyNewVersions(cmd *cobra.Command) {
+	if viper.IsSet("cli.updateCheck") && !viper.GetBool("cli.updateCheck") {
+		return
+	}
+	if config.Config.Version == config.DevCliVersion || !isTerminal(os.Stderr) {
+		return
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion", "self-update", "daemon":
+			return
+		}
+	}
+
+	hint := make(chan string, 1)
+	go func() {
+		engineVersion := engine.GetConfiguredVersionOrResolve("", true, false)
+		hint <- selfupdate.CheckForNewVersions(time.Now().Unix(), config.Config.Version, engineVersion)
+	}()
+	select {
+	case h := <-hint:
+		if h != "" {
+			logger.Info(h)
+		}
+	case <-time.After(updateCheckTimeout):
+		logger.Tracef("timed out checking for new versions")
 	}
 }
 
 func registerLogLevelCompletions(cmd *cobra.Command) {
 	cmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{
-			"trace",
-			"debug",
-			"info",
-			"warn",
-			"error",
-		}, cobra.ShellCompDirectiveNoFileComp
+		return logLevels, cobra.ShellCompDirectiveNoFileComp
 	})
 }
@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/proxy"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
@@ -27,6 +28,22 @@ var scaffoldFlags = struct {
 	forceOverwrite    bool
 	generateResources bool
 	scriptEngine      string
+	exampleStrategy   string
+	specUrls          []string
+	specs             []string
+	fromHar           string
+	fromPostman       string
+	folderPrefixes    bool
+	delayProfile      string
+	rateLimits        bool
+	protoImportPaths  []string
+	responseFiles     bool
+	security          bool
+	typeScript        bool
+	template          string
+	resource          string
+	cors              bool
+	corsOrigins       []string
 }{}
 
 // scaffoldCmd represents the up command
@@ -35,8 +52,14 @@ var scaffoldCmd = &cobra.Command{
 	Aliases: []string{"init"},
 	Short:   "Create Imposter configuration",
 	Long: `Creates Imposter configuration files. If one or more OpenAPI/Swagger
-specification files are present, they are used as the basis for the generated
-resources. If no specification files are present, a simple REST mock is created.
+specification files, WSDL files, GraphQL schemas, protobuf files declaring
+services or AsyncAPI documents are present, they are used as the basis for the
+generated resources. If no specification files are present, a simple REST mock
+is created.
+
+Specification files elsewhere can be used with --spec, which accepts a file,
+a directory or a glob pattern, and can be repeated. Configuration is written
+alongside each of these files, instead of in DIR.
 
 If DIR is not specified, the current working directory is used.`,
 	Args: cobra.RangeArgs(0, 1),
@@ -47,8 +70,69 @@ If DIR is not specified, the current working directory is used.`,
 		} else {
 			configDir, _ = filepath.Abs(args[0])
 		}
+		delayProfile, err := impostermodel.ParseDelayProfile(scaffoldFlags.delayProfile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		var cors *impostermodel.CorsConfig
+		if scaffoldFlags.cors || len(scaffoldFlags.corsOrigins) > 0 {
+			if scaffoldFlags.fromHar != "" || scaffoldFlags.fromPostman != "" {
+				logger.Fatalf("--cors is not supported with --from-har or --from-postman - add it afterwards with 'imposter config cors'")
+			}
+			cors = impostermodel.BuildCorsConfig(scaffoldFlags.corsOrigins)
+		}
+		if scaffoldFlags.fromHar != "" {
+			scaffoldFromHar(scaffoldFlags.fromHar, configDir, delayProfile)
+			return
+		}
+		if delayProfile.Type == impostermodel.DelayProfileEmpirical {
+			logger.Fatalf("the empirical delay profile requires recorded exchanges - use it with --from-har")
+		}
+		if scaffoldFlags.fromPostman != "" {
+			scaffoldFromPostman(scaffoldFlags.fromPostman, configDir, delayProfile, scaffoldFlags.folderPrefixes)
+			return
+		}
+		if scaffoldFlags.template != "" {
+			template, err := impostermodel.ParseTemplate(scaffoldFlags.template)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			impostermodel.CreateFromTemplate(configDir, template, scaffoldFlags.resource, scaffoldFlags.forceOverwrite, cors)
+			return
+		}
 		scriptEngine := impostermodel.ParseScriptEngine(scaffoldFlags.scriptEngine)
-		impostermodel.Create(configDir, scaffoldFlags.generateResources, scaffoldFlags.forceOverwrite, scriptEngine, false)
+		if scaffoldFlags.typeScript && scriptEngine != impostermodel.ScriptEngineJavaScript {
+			logger.Fatalf("--typescript requires the javascript script engine - use it with --script-engine js")
+		}
+		exampleStrategy, err := impostermodel.ParseExampleStrategy(scaffoldFlags.exampleStrategy)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		for _, specUrl := range scaffoldFlags.specUrls {
+			if _, err := impostermodel.AddRemoteSpec(configDir, specUrl); err != nil {
+				logger.Fatal(err)
+			}
+		}
+		options := impostermodel.ResourceGenerationOptions{
+			ScriptEngine:       scriptEngine,
+			ExampleStrategy:    exampleStrategy,
+			DelayProfile:       delayProfile,
+			SimulateRateLimits: scaffoldFlags.rateLimits,
+			ProtoImportPaths:   scaffoldFlags.protoImportPaths,
+			ResponseFiles:      scaffoldFlags.responseFiles,
+			SimulateSecurity:   scaffoldFlags.security,
+			TypeScript:         scaffoldFlags.typeScript,
+			Cors:               cors,
+		}
+		if len(scaffoldFlags.specs) > 0 {
+			specPaths, err := impostermodel.ExpandSpecPaths(scaffoldFlags.specs)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			impostermodel.CreateFromSpecs(specPaths, scaffoldFlags.generateResources, scaffoldFlags.forceOverwrite, options)
+			return
+		}
+		impostermodel.Create(configDir, scaffoldFlags.generateResources, scaffoldFlags.forceOverwrite, options, false)
 	},
 }
 
@@ -56,5 +140,43 @@ func init() {
 	scaffoldCmd.Flags().BoolVarP(&scaffoldFlags.forceOverwrite, "force-overwrite", "f", false, "Force overwrite of destination file(s) if already exist")
 	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.generateResources, "generate-resources", true, "Generate Imposter resources from OpenAPI paths")
 	scaffoldCmd.Flags().StringVarP(&scaffoldFlags.scriptEngine, "script-engine", "s", "none", "Generate placeholder Imposter script (none|groovy|js)")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.typeScript, "typescript", false, "Generate TypeScript sources for JavaScript scripts, with type declarations and a tsconfig to compile them")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.exampleStrategy, "example-strategy", "first", "Strategy for choosing the default response from named examples (first|named:<name>|all-as-variants)")
+	scaffoldCmd.Flags().StringArrayVar(&scaffoldFlags.specs, "spec", nil, "Spec file, directory or glob pattern to generate configuration for, instead of the specs in DIR - can be repeated")
+	scaffoldCmd.Flags().StringArrayVar(&scaffoldFlags.specUrls, "spec-url", nil, "URL of a remote OpenAPI spec to fetch into DIR - refresh it with 'imposter refresh-spec'")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.delayProfile, "delay-profile", "", "Simulate response latency for generated resources (fixed:<ms>|uniform:<min>-<max>|normal:<mean>,<stddev>|empirical) - empirical requires --from-har")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.rateLimits, "rate-limits", false, "Simulate rate limits declared in OpenAPI specs, returning 429 responses once a client exceeds them")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.security, "security", false, "Enforce the security schemes declared in OpenAPI specs, returning 401 responses to requests without credentials")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.fromHar, "from-har", "", "Generate Imposter configuration and response files from the entries in a HAR file")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.fromPostman, "from-postman", "", "Generate Imposter configuration and response files from the requests and example responses in a Postman collection")
+	scaffoldCmd.Flags().StringArrayVar(&scaffoldFlags.protoImportPaths, "proto-import-path", nil, "Directory searched for the files imported by .proto files, in addition to the directory of the importing file")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.responseFiles, "response-files", false, "Write the response bodies of OpenAPI operations to files, from the examples in the spec or generated from their schemas")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.template, "template", "", "Generate a mock from a built-in template, without a spec (crud|auth|paginated)")
+	scaffoldCmd.Flags().StringVar(&scaffoldFlags.resource, "resource", impostermodel.DefaultTemplateResource, "Name of the resource generated by --template, used for its path")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.cors, "cors", false, "Allow browser-based clients on any origin to call HTTP mocks - restrict the origins with --cors-origin")
+	scaffoldCmd.Flags().StringArrayVar(&scaffoldFlags.corsOrigins, "cors-origin", nil, "Origin allowed to call HTTP mocks, such as http://localhost:3000 - implies --cors, can be repeated")
+	scaffoldCmd.Flags().BoolVar(&scaffoldFlags.folderPrefixes, "folder-prefixes", false, "Prefix the path of each request from a Postman collection with the names of the folders containing it")
 	rootCmd.AddCommand(scaffoldCmd)
 }
+
+// scaffoldFromHar generates configuration from the exchanges in a HAR
+// file, in the same way as those recorded by 'imposter proxy'.
+func scaffoldFromHar(harFile string, configDir string, delayProfile impostermodel.DelayProfile) {
+	options := proxy.RecorderOptions{IgnoreDuplicateRequests: true, DelayProfile: delayProfile}
+	imported, err := proxy.ImportHar(harFile, configDir, options)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("generated configuration for %d HAR entries in %s", imported, configDir)
+}
+
+// scaffoldFromPostman generates configuration from the requests and saved
+// example responses in a Postman collection.
+func scaffoldFromPostman(collectionFile string, configDir string, delayProfile impostermodel.DelayProfile, folderPrefixes bool) {
+	options := proxy.RecorderOptions{IgnoreDuplicateRequests: true, DelayProfile: delayProfile}
+	imported, err := proxy.ImportPostman(collectionFile, configDir, options, folderPrefixes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("generated configuration for %d Postman responses in %s", imported, configDir)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -124,7 +125,7 @@ func Test_createMockConfig(t *testing.T) {
 			if tt.args.copySpecs {
 				prepTestData(t, configDir, testConfigPath)
 			}
-			impostermodel.Create(configDir, tt.args.generateResources, tt.args.forceOverwrite, tt.args.scriptEngine, false)
+			impostermodel.Create(configDir, tt.args.generateResources, tt.args.forceOverwrite, impostermodel.ResourceGenerationOptions{ScriptEngine: tt.args.scriptEngine}, false)
 
 			if !doesFileExist(filepath.Join(configDir, tt.args.anchorFileName+"-config.yaml")) {
 				t.Fatalf("imposter config file should exist")
@@ -161,3 +162,62 @@ func prepTestData(t *testing.T, configDir string, src string) {
 		t.Fatal(err)
 	}
 }
+
+func Test_createMockConfigExampleStrategy(t *testing.T) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	testConfigPath := filepath.Join(workingDir, "/testdata")
+
+	tests := []struct {
+		name         string
+		strategy     string
+		wantContains []string
+	}{
+		{
+			name:         "first example",
+			strategy:     "first",
+			wantContains: []string{"exampleName: itemsExample"},
+		},
+		{
+			name:         "named example falls back to first",
+			strategy:     "named:missing",
+			wantContains: []string{"exampleName: itemsExample"},
+		},
+		{
+			name:     "all examples as variants",
+			strategy: "all-as-variants",
+			wantContains: []string{
+				"exampleName: itemsExample",
+				"X-Imposter-Example: itemsExample",
+				"X-Imposter-Example: success",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir, err := os.MkdirTemp(os.TempDir(), "specs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			prepTestData(t, configDir, testConfigPath)
+
+			strategy, err := impostermodel.ParseExampleStrategy(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			impostermodel.Create(configDir, true, true, impostermodel.ResourceGenerationOptions{ScriptEngine: impostermodel.ScriptEngineNone, ExampleStrategy: strategy}, false)
+
+			config, err := os.ReadFile(filepath.Join(configDir, "order_service-config.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(config), want) {
+					t.Errorf("config should contain '%s', got:\n%s", want, config)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/selfupdate"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var selfUpdateFlags = struct {
+	check   bool
+	channel string
+}{}
+
+// selfUpdateCmd represents the self-update command
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update the CLI to the latest release",
+	Long: `Checks for a newer release of the CLI and, if one is available,
+downloads the binary for this OS and architecture, verifies its
+checksum, and replaces the current executable.
+
+The beta channel includes prereleases.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		channel, err := selfupdate.ParseChannel(selfUpdateFlags.channel)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+		}
+		selfUpdate(channel, selfUpdateFlags.check)
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateFlags.check, "check", false, "Only check whether an update is available")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateFlags.channel, "channel", string(selfupdate.ChannelStable), "Release channel (valid: stable,beta)")
+	_ = selfUpdateCmd.RegisterFlagCompletionFunc("channel", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(selfupdate.ChannelStable), string(selfupdate.ChannelBeta)}, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func selfUpdate(channel selfupdate.Channel, checkOnly bool) {
+	current := config.Config.Version
+	release, err := selfupdate.FindLatest(channel)
+	if err != nil {
+		logger.Fatalf("failed to check for updates: %v", err)
+	}
+
+	if current == config.DevCliVersion {
+		if checkOnly {
+			logger.Infof("latest %s release is %s - cannot compare with dev CLI version", channel, release.Version)
+			return
+		}
+		logger.Fatalf("cannot update dev CLI version - install a release of the CLI instead")
+	}
+	newer, err := selfupdate.IsNewer(current, release)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if !newer {
+		logger.Infof("CLI version %s is up to date (latest %s release is %s)", current, channel, release.Version)
+		return
+	}
+	if checkOnly {
+		command := "imposter self-update"
+		if channel != selfupdate.ChannelStable {
+			command += " --channel " + string(channel)
+		}
+		logger.Infof("update available: %s -> %s - run '%s' to install it", current, release.Version, command)
+		return
+	}
+
+	exePath, err := os.Executable()
+	if err == nil {
+		exePath, err = filepath.EvalSymlinks(exePath)
+	}
+	if err != nil {
+		logger.Fatalf("failed to determine path of CLI executable: %v", err)
+	}
+	logger.Infof("updating CLI from %s to %s...", current, release.Version)
+	if err = selfupdate.Apply(release, exePath); err != nil {
+		logger.Fatalf("failed to update CLI: %v", err)
+	}
+	logger.Infof("updated CLI to %s at %s", release.Version, exePath)
+}
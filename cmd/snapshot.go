@@ -0,0 +1,126 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/verify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+var snapshotFlags = struct {
+	dir                 string
+	check               bool
+	url                 string
+	port                int
+	tls                 bool
+	start               bool
+	engineType          string
+	engineVersion       string
+	recursiveConfigScan bool
+	output              string
+	report              string
+}{}
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [CONFIG_DIR]",
+	Short: "Record or check snapshots of the responses of a mock",
+	Long: `Sends a request to each resource in the configuration files in
+CONFIG_DIR, including its variants, and records the canonical form of
+each response as a snapshot file.
+
+With --check, the responses are compared with the recorded snapshots
+instead, exiting with a non-zero code if any differ, are missing, or
+are for resources that no longer exist. Use this in CI to catch
+accidental changes to the behaviour of a mock.
+
+By default, the requests are sent to a mock that is already running. Use
+--start to start a mock from CONFIG_DIR while the snapshots are taken.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := parseResultsFormat(snapshotFlags.output)
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		snapshotDir := snapshotFlags.dir
+		if snapshotDir == "" {
+			snapshotDir = filepath.Join(configDir, "__snapshots__")
+		}
+		recursive := snapshotFlags.recursiveConfigScan || viper.GetBool("config.scan.recursive")
+		tests, err := buildResourceTests(configDir, recursive)
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeConfigInvalid, err))
+		}
+		baseUrl := snapshotFlags.url
+		if baseUrl == "" {
+			scheme := "http"
+			if snapshotFlags.tls {
+				scheme = "https"
+			}
+			baseUrl = fmt.Sprintf("%s://localhost:%d", scheme, snapshotFlags.port)
+		}
+
+		var stop func()
+		if snapshotFlags.start {
+			stop = startEphemeralMock(configDir, snapshotFlags.engineType, snapshotFlags.engineVersion, snapshotFlags.port)
+		}
+		var report verify.Report
+		if snapshotFlags.check {
+			report, err = verify.CheckSnapshots(tests, baseUrl, snapshotDir)
+		} else {
+			report, err = verify.UpdateSnapshots(tests, baseUrl, snapshotDir)
+		}
+		if stop != nil {
+			stop()
+		}
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeVerifyFailed, err))
+		}
+		if !snapshotFlags.check {
+			logger.Infof("wrote %d snapshot(s) to %s", len(report.Results)-report.Failed(), snapshotDir)
+		}
+		reportVerifyResults(report, "snapshots", format, snapshotFlags.report)
+	},
+}
+
+func init() {
+	snapshotCmd.Flags().StringVar(&snapshotFlags.dir, "dir", "", "Directory containing the snapshot files (default is __snapshots__ in CONFIG_DIR)")
+	snapshotCmd.Flags().BoolVar(&snapshotFlags.check, "check", false, "Compare the responses with the recorded snapshots, instead of recording them")
+	snapshotCmd.Flags().StringVar(&snapshotFlags.url, "url", "", "Base URL of the mock (default is localhost on the given port)")
+	snapshotCmd.Flags().IntVarP(&snapshotFlags.port, "port", "p", 8080, "Port on which the mock is listening, or on which to start it")
+	snapshotCmd.Flags().BoolVar(&snapshotFlags.tls, "tls", false, "Connect to the mock using HTTPS")
+	snapshotCmd.Flags().BoolVar(&snapshotFlags.start, "start", false, "Start a mock from CONFIG_DIR while the snapshots are taken")
+	snapshotCmd.Flags().StringVarP(&snapshotFlags.engineType, "engine-type", "t", "", "Imposter engine type used with --start (valid: docker,jvm - default \"docker\")")
+	snapshotCmd.Flags().StringVarP(&snapshotFlags.engineVersion, "version", "v", "", "Imposter engine version used with --start (default \"latest\")")
+	snapshotCmd.Flags().BoolVarP(&snapshotFlags.recursiveConfigScan, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
+	snapshotCmd.Flags().StringVarP(&snapshotFlags.output, "output", "o", string(outputFormatPlain), "Format of the results printed (valid: plain,json)")
+	snapshotCmd.Flags().StringVar(&snapshotFlags.report, "report", "", "Also write the results as JUnit XML to this file (e.g. junit.xml)")
+	registerEngineTypeCompletions(snapshotCmd)
+	registerEngineVersionCompletions(snapshotCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gatehill.io/imposter/accesslog"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var tailFlags = struct {
+	follow   bool
+	statuses []string
+	paths    []string
+	noColour bool
+}{}
+
+// tailCmd represents the tail command
+var tailCmd = &cobra.Command{
+	Use:   "tail [LOG_FILE]",
+	Short: "Show requests from the engine log",
+	Long: `Reads the engine log and shows each request handled by the mock,
+with its method, path, status, latency and matched resource.
+
+The engine log is read from the file, such as one written by
+'imposter up --log-file', or from stdin if no file is given. The
+engine must log a request summary, which is enabled with the
+IMPOSTER_LOG_SUMMARY environment variable.
+
+Example 1: Show requests as the mock runs
+
+	imposter up -e IMPOSTER_LOG_SUMMARY=true | imposter tail
+
+Example 2: Follow a log file, showing server errors for a path
+
+	imposter tail imposter.log --follow --status 5xx --path '/orders/**'`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := accesslog.NewFilter(tailFlags.statuses, tailFlags.paths)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		colour := !tailFlags.noColour && isTerminal(os.Stdout)
+		if len(args) == 0 {
+			renderAccessLog(os.Stdin, os.Stdout, filter, colour)
+		} else {
+			tailAccessLogFile(args[0], tailFlags.follow, filter, colour)
+		}
+	},
+}
+
+func init() {
+	tailCmd.Flags().BoolVarP(&tailFlags.follow, "follow", "f", false, "Keep reading the log file as it is written")
+	tailCmd.Flags().StringSliceVar(&tailFlags.statuses, "status", nil, "Only show requests with this status, or class of status, such as 404 or 5xx")
+	tailCmd.Flags().StringSliceVar(&tailFlags.paths, "path", nil, "Only show requests with a path matching this glob, such as /orders/**")
+	tailCmd.Flags().BoolVar(&tailFlags.noColour, "no-color", false, "Disable coloured output")
+	rootCmd.AddCommand(tailCmd)
+}
+
+// isTerminal returns true if the file is a terminal, rather than
+// a pipe or a regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderAccessLog writes the requests in the log that match the
+// filter, until the end of the log is reached.
+func renderAccessLog(in io.Reader, out io.Writer, filter *accesslog.Filter, colour bool) {
+	_, _ = fmt.Fprintln(out, accesslog.Header(colour))
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		renderAccessLogLine(scanner.Text(), out, filter, colour)
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Fatalf("failed to read log: %v", err)
+	}
+}
+
+func renderAccessLogLine(line string, out io.Writer, filter *accesslog.Filter, colour bool) {
+	entry, ok := accesslog.Parse(line)
+	if !ok || !filter.Matches(entry) {
+		return
+	}
+	_, _ = fmt.Fprintln(out, accesslog.Format(entry, colour))
+}
+
+// tailAccessLogFile renders the requests in the log file. If follow is
+// true, the file is polled for new lines, and reopened if it is rotated.
+func tailAccessLogFile(path string, follow bool, filter *accesslog.Filter, colour bool) {
+	if !follow {
+		file, err := os.Open(path)
+		if err != nil {
+			logger.Fatalf("failed to open log file: %v", err)
+		}
+		defer func() { _ = file.Close() }()
+		renderAccessLog(file, os.Stdout, filter, colour)
+		return
+	}
+	fmt.Println(accesslog.Header(colour))
+	err := accesslog.Follow(path, func(line string) {
+		renderAccessLogLine(line, os.Stdout, filter, colour)
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var topFlags = struct {
+	port     int
+	interval time.Duration
+	sortBy   string
+	once     bool
+	tls      bool
+}{}
+
+// topCmd represents the top command
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show live request counts per resource",
+	Long: `Polls the metrics of a running mock and shows a live view of
+request counts and mean latency for each resource.
+
+The engine must have metrics enabled (the default).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateTopSort(topFlags.sortBy); err != nil {
+			logger.Fatal(err)
+		}
+		showTop(topFlags.port, topFlags.tls, topFlags.interval, topFlags.sortBy, topFlags.once)
+	},
+}
+
+func init() {
+	topCmd.Flags().IntVarP(&topFlags.port, "port", "p", 8080, "Port on which the mock is listening")
+	topCmd.Flags().DurationVarP(&topFlags.interval, "interval", "i", 2*time.Second, "Interval between metrics updates")
+	topCmd.Flags().StringVarP(&topFlags.sortBy, "sort", "s", "requests", "Sort order (valid: requests,latency,path)")
+	topCmd.Flags().BoolVar(&topFlags.once, "once", false, "Print the current view once and exit")
+	topCmd.Flags().BoolVar(&topFlags.tls, "tls", false, "Connect to the mock using HTTPS")
+	rootCmd.AddCommand(topCmd)
+}
+
+func validateTopSort(sortBy string) error {
+	switch sortBy {
+	case "requests", "latency", "path":
+		return nil
+	}
+	return fmt.Errorf("unsupported sort order: %s", sortBy)
+}
+
+func showTop(port int, tls bool, interval time.Duration, sortBy string, once bool) {
+	for {
+		samples, err := engine.FetchMetrics(port, tls)
+		if err != nil {
+			logger.Fatalf("failed to fetch metrics: %v", err)
+		}
+		resources := engine.GetResourceStats(samples)
+		sortResourceStats(resources, sortBy)
+
+		if !once {
+			// clear screen and move cursor to top left
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Mock on port %d - updated %s (ctrl+c to exit)\n\n", port, time.Now().Format(time.TimeOnly))
+		}
+		renderResourceStats(resources)
+
+		if once {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+func sortResourceStats(resources []engine.ResourceStats, sortBy string) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		switch sortBy {
+		case "latency":
+			return resources[i].MeanLatency > resources[j].MeanLatency
+		case "path":
+			if resources[i].Path != resources[j].Path {
+				return resources[i].Path < resources[j].Path
+			}
+			return resources[i].Method < resources[j].Method
+		default:
+			return resources[i].Requests > resources[j].Requests
+		}
+	})
+}
+
+func renderResourceStats(resources []engine.ResourceStats) {
+	var rows [][]string
+	for _, resource := range resources {
+		rows = append(rows, []string{
+			resource.Method,
+			resource.Path,
+			strconv.Itoa(resource.Requests),
+			resource.MeanLatency.Round(time.Microsecond).String(),
+		})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Method", "Path", "Requests", "Mean latency"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(rows)
+	table.Render()
+}
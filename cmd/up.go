@@ -17,27 +17,45 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"gatehill.io/imposter/config"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/lifecycle"
+	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/plugin"
 	"gatehill.io/imposter/stringutil"
+	"gatehill.io/imposter/verify"
+	"gatehill.io/imposter/workspace"
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
+var heapSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// sessionMetricsInterval is how often the engine metrics are fetched for
+// the session summary, so it is available if the engine stops abruptly.
+const sessionMetricsInterval = 5 * time.Second
+
 var upFlags = struct {
 	deduplicate         string
 	engineType          string
 	engineVersion       string
 	forcePull           bool
+	skipChecksum        bool
 	port                int
 	restartOnChange     bool
 	scaffoldMissing     bool
@@ -48,6 +66,36 @@ var upFlags = struct {
 	dirMounts           []string
 	recursiveConfigScan bool
 	debugMode           bool
+	drainTimeout        time.Duration
+	containerName       string
+	network             string
+	memory              string
+	image               string
+	configMountMode     string
+	containerUser       string
+	readOnly            bool
+	capDrop             []string
+	logFile             string
+	logMaxSize          string
+	logMaxAge           time.Duration
+	logMaxBackups       int
+	cpus                float64
+	jvmArgs             string
+	heap                string
+	javaHome            string
+	tls                 bool
+	certFile            string
+	keyFile             string
+	tlsSelfSigned       bool
+	waitReady           time.Duration
+	autoPort            bool
+	specUrls            []string
+	specRefreshInterval time.Duration
+	smokeTest           bool
+	summary             bool
+	events              string
+	eventsFile          string
+	daemon              bool
 }{}
 
 // upCmd represents the up command
@@ -57,9 +105,16 @@ var upCmd = &cobra.Command{
 	Long: `Starts a live mock of your APIs, using their Imposter configuration.
 
 If CONFIG_DIR is not specified, the current working directory is used.`,
-	Args: cobra.RangeArgs(0, 1),
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
 	Run: func(cmd *cobra.Command, args []string) {
+		enableLifecycleEvents(upFlags.events, upFlags.eventsFile)
 		injectExplicitEnvironment(upFlags.environment)
+		logWriter := openLogFile(upFlags.logFile, upFlags.logMaxSize, upFlags.logMaxAge, upFlags.logMaxBackups)
+		applySkipChecksum(upFlags.skipChecksum)
+		if upFlags.javaHome != "" {
+			viper.Set("jvm.javaHome", upFlags.javaHome)
+		}
 
 		var configDir string
 		if len(args) == 0 {
@@ -67,12 +122,25 @@ If CONFIG_DIR is not specified, the current working directory is used.`,
 		} else {
 			configDir, _ = filepath.Abs(args[0])
 		}
+		workspaceDir := configDir
+		configDir = resolveGitSyncDir(workspaceDir)
+		syncRemoteSpecs(configDir, upFlags.specUrls)
 		if err := config.ValidateConfigExists(configDir, upFlags.scaffoldMissing); err != nil {
-			logger.Fatal(err)
+			failure.Fatal(err)
 		}
 
 		// Search for CLI config files in the mock config dir.
 		config.MergeCliConfigIfExists(configDir)
+		applyWorkspaceSettings(cmd, workspaceDir)
+
+		if c := connectDaemon(cmd, upFlags.daemon); c != nil {
+			port, err := resolvePort(upFlags.port, upFlags.autoPort)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			upWithDaemon(c, cmd, configDir, port, buildStartEnvironment(upFlags.environment))
+			return
+		}
 
 		var pullPolicy engine.PullPolicy
 		if upFlags.forcePull {
@@ -89,8 +157,9 @@ If CONFIG_DIR is not specified, the current working directory is used.`,
 			// only resolve version if not a sealed distro, to avoid prefs write
 			version = engine.GetConfiguredVersion(upFlags.engineVersion, pullPolicy != engine.PullAlways)
 
-			// only ensure (and potentially fetch) default plugins if not a sealed distro
-			if upFlags.ensurePlugins && lib.ShouldEnsurePlugins() {
+			// only ensure (and potentially fetch) default plugins if not a sealed distro,
+			// and the engine version is known, which it is not for an image digest
+			if upFlags.ensurePlugins && lib.ShouldEnsurePlugins() && !engine.IsImageDigest(version) {
 				_, err := plugin.EnsureConfiguredPlugins(version)
 				if err != nil {
 					logger.Fatal(err)
@@ -98,8 +167,33 @@ If CONFIG_DIR is not specified, the current working directory is used.`,
 			}
 		}
 
+		memory, err := parseMemoryLimit(upFlags.memory)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		configMountMode, err := engine.ParseConfigMountMode(upFlags.configMountMode)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		heap, err := parseHeapSize(upFlags.heap)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		port, err := resolvePort(upFlags.port, upFlags.autoPort)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		tlsOptions, err := engine.BuildTlsOptions(upFlags.tls, upFlags.certFile, upFlags.keyFile, upFlags.tlsSelfSigned)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
 		startOptions := engine.StartOptions{
-			Port:            upFlags.port,
+			Port:            port,
 			Version:         version,
 			PullPolicy:      pullPolicy,
 			LogLevel:        config.Config.LogLevel,
@@ -110,16 +204,37 @@ If CONFIG_DIR is not specified, the current working directory is used.`,
 			Environment:     buildStartEnvironment(upFlags.environment),
 			DirMounts:       upFlags.dirMounts,
 			DebugMode:       upFlags.debugMode,
+			DrainTimeout:    upFlags.drainTimeout,
+			ContainerName:   upFlags.containerName,
+			Network:         upFlags.network,
+			Image:           upFlags.image,
+			ConfigMountMode: configMountMode,
+			ContainerUser:   upFlags.containerUser,
+			ReadOnly:        upFlags.readOnly,
+			CapDrop:         upFlags.capDrop,
+			Memory:          memory,
+			Cpus:            upFlags.cpus,
+			JvmArgs:         strings.Fields(upFlags.jvmArgs),
+			Heap:            heap,
+			Tls:             tlsOptions,
+			WaitReady:       cmd.Flags().Changed("wait-ready") || upFlags.smokeTest,
+			ReadyTimeout:    upFlags.waitReady,
+			LogWriter:       logWriter,
 		}
-		start(&lib, startOptions, configDir, upFlags.restartOnChange)
+		if len(upFlags.specUrls) > 0 && upFlags.specRefreshInterval > 0 {
+			go refreshRemoteSpecsPeriodically(configDir, upFlags.specUrls, upFlags.specRefreshInterval)
+		}
+		start(&lib, startOptions, configDir, upFlags.restartOnChange, upFlags.smokeTest, upFlags.summary)
 	},
 }
 
 func init() {
 	upCmd.Flags().StringVarP(&upFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default \"docker\")")
-	upCmd.Flags().StringVarP(&upFlags.engineVersion, "version", "v", "", "Imposter engine version (default \"latest\")")
-	upCmd.Flags().IntVarP(&upFlags.port, "port", "p", 8080, "Port on which to listen")
+	upCmd.Flags().StringVarP(&upFlags.engineVersion, "version", "v", "", "Imposter engine version, or a pin such as 4.x (default \"latest\")")
+	upCmd.Flags().IntVarP(&upFlags.port, "port", "p", 8080, "Port on which to listen - 0 selects a free port")
+	upCmd.Flags().BoolVar(&upFlags.autoPort, "auto-port", false, "Select a free port if the requested port is already in use")
 	upCmd.Flags().BoolVar(&upFlags.forcePull, "pull", false, "Force engine pull")
+	upCmd.Flags().BoolVar(&upFlags.skipChecksum, "skip-checksum", false, "(JVM engine type only) Skip verification of the checksum of the downloaded engine")
 	upCmd.Flags().BoolVar(&upFlags.restartOnChange, "auto-restart", true, "Automatically restart when config dir contents change")
 	upCmd.Flags().BoolVarP(&upFlags.scaffoldMissing, "scaffold", "s", false, "Scaffold Imposter configuration for all OpenAPI files")
 	upCmd.Flags().StringVar(&upFlags.deduplicate, "deduplicate", "", "Override deduplication ID for replacement of containers")
@@ -130,7 +245,38 @@ func init() {
 	upCmd.Flags().StringArrayVar(&upFlags.dirMounts, "mount-dir", []string{}, "(Docker engine type only) Extra directory bind-mounts in the form HOST_PATH:CONTAINER_PATH (e.g. $HOME/somedir:/opt/imposter/somedir) or simply HOST_PATH, which will mount the directory at /opt/imposter/<dir>")
 	upCmd.Flags().BoolVarP(&upFlags.recursiveConfigScan, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
 	upCmd.Flags().BoolVar(&upFlags.debugMode, "debug-mode", false, fmt.Sprintf("Enable JVM debug mode and listen on port %v", engine.DefaultDebugPort))
+	upCmd.Flags().DurationVar(&upFlags.drainTimeout, "drain-timeout", 0, "Maximum time to wait for in-flight requests to complete before stopping or restarting the engine (e.g. 10s) - 0 disables draining")
+	upCmd.Flags().StringVar(&upFlags.containerName, "name", "", "(Docker engine type only) Name of the mock engine container, also used as its network alias")
+	upCmd.Flags().StringVar(&upFlags.network, "network", "", "(Docker engine type only) Name of an existing Docker network to which the mock engine container should be attached")
+	upCmd.Flags().StringVar(&upFlags.image, "image", "", "(Docker engine type only) Replace the default engine image, optionally including a registry and tag (e.g. ghcr.io/example/custom-imposter:1.0)")
+	upCmd.Flags().StringVar(&upFlags.configMountMode, "config-mount-mode", "", "(Docker engine type only) How the config dir is provided to the engine container (valid: bind,copy - default \"bind\", or \"copy\" if the Docker daemon is remote)")
+	upCmd.Flags().StringVar(&upFlags.containerUser, "container-user", "", "(Docker engine type only) User (username or uid) as which the mock engine container runs")
+	upCmd.Flags().BoolVar(&upFlags.readOnly, "read-only", false, "(Docker engine type only) Run the mock engine container with a read-only root filesystem")
+	upCmd.Flags().StringArrayVar(&upFlags.capDrop, "cap-drop", nil, "(Docker engine type only) Linux capability to drop from the mock engine container (e.g. ALL)")
+	upCmd.Flags().StringVar(&upFlags.memory, "memory", "", "(Docker engine type only) Memory limit for the mock engine container (e.g. 512m, 1g)")
+	upCmd.Flags().Float64Var(&upFlags.cpus, "cpus", 0, "(Docker engine type only) Number of CPUs available to the mock engine container (e.g. 1.5)")
+	upCmd.Flags().StringVar(&upFlags.jvmArgs, "jvm-args", "", "(JVM engine type only) Arguments passed to the JVM running the engine, separated by spaces (e.g. \"-XX:+UseG1GC -agentlib:jdwp=transport=dt_socket,server=y,suspend=y,address=5005\")")
+	upCmd.Flags().StringVar(&upFlags.heap, "heap", "", "(JVM engine type only) Maximum heap size of the JVM running the engine (e.g. 512m, 2g)")
+	upCmd.Flags().StringVar(&upFlags.javaHome, "java-home", "", "(JVM engine type only) Java installation used to run the engine, instead of the one found in the environment")
+	upCmd.Flags().BoolVar(&upFlags.tls, "tls", false, "Serve mocks over HTTPS, using the engine's built-in certificate unless a certificate is provided")
+	upCmd.Flags().StringVar(&upFlags.certFile, "cert-file", "", "Path to a PEM encoded TLS certificate (or chain) - requires --tls and --key-file")
+	upCmd.Flags().StringVar(&upFlags.keyFile, "key-file", "", "Path to the PEM encoded private key for the TLS certificate - requires --tls and --cert-file")
+	upCmd.Flags().BoolVar(&upFlags.tlsSelfSigned, "tls-self-signed", false, "Generate a self-signed certificate for localhost, reused between runs - requires --tls")
+	upCmd.Flags().DurationVar(&upFlags.waitReady, "wait-ready", 0, fmt.Sprintf("Report when the mock is ready, waiting up to the given timeout (default %v), and exit with a non-zero code if the engine fails to start", engine.DefaultStartTimeout))
+	upCmd.Flags().Lookup("wait-ready").NoOptDefVal = engine.DefaultStartTimeout.String()
+	upCmd.Flags().StringArrayVar(&upFlags.specUrls, "spec-url", nil, "URL of a remote OpenAPI spec to fetch into the config dir, generating configuration if none exists")
+	upCmd.Flags().DurationVar(&upFlags.specRefreshInterval, "spec-refresh-interval", 0, "Interval at which remote specs are re-fetched, restarting the mock if they change (e.g. 5m) - 0 disables refresh")
+	upCmd.Flags().StringVar(&upFlags.logFile, "log-file", "", "Write engine and CLI output to this file, in addition to the console, rotating it once it reaches --log-max-size")
+	upCmd.Flags().StringVar(&upFlags.logMaxSize, "log-max-size", "10m", "Size at which the log file is rotated (e.g. 10m, 1g)")
+	upCmd.Flags().DurationVar(&upFlags.logMaxAge, "log-max-age", 0, "Maximum age of rotated log files before they are deleted (e.g. 168h) - 0 keeps them regardless of age")
+	upCmd.Flags().IntVar(&upFlags.logMaxBackups, "log-max-backups", 5, "Maximum number of rotated log files to keep")
+	upCmd.Flags().BoolVar(&upFlags.smokeTest, "smoke-test", false, "Once the mock is ready, send a request to each resource and stop if any receives an unexpected server error (5xx)")
+	upCmd.Flags().BoolVar(&upFlags.summary, "summary", true, "Print a summary of the requests received when the mock stops")
+	upCmd.Flags().StringVar(&upFlags.events, "events", "", "Emit lifecycle events (pulling, starting, ready, restarting, stopped, error) in this format (valid: ndjson), on stdout unless --events-file is set")
+	upCmd.Flags().BoolVar(&upFlags.daemon, "daemon", false, "Start the mock in the running daemon, following its logs until interrupted")
+	upCmd.Flags().StringVar(&upFlags.eventsFile, "events-file", "", "File, such as a named pipe, to which lifecycle events are written instead of stdout - requires --events")
 	registerEngineTypeCompletions(upCmd)
+	registerEngineVersionCompletions(upCmd)
 	rootCmd.AddCommand(upCmd)
 }
 
@@ -146,6 +292,128 @@ func injectExplicitEnvironment(cliEnvArgs []string) {
 	}
 }
 
+// resolveGitSyncDir returns the synced copy of the git repository of the
+// active workspace in dir, if it is git-backed, otherwise dir.
+func resolveGitSyncDir(dir string) string {
+	active, err := workspace.FindActive(dir)
+	if err != nil {
+		logger.Warnf("failed to load workspace: %s", err)
+		return dir
+	} else if active == nil || active.GitUrl == "" {
+		return dir
+	}
+	if !workspace.IsGitSynced(dir, active) {
+		logger.Fatalf("workspace '%s' is backed by %s but has not been pulled - run 'imposter workspace pull'", active.Name, active.GitUrl)
+	}
+	syncDir, err := workspace.GetGitSyncDir(dir, active)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("using synced copy of workspace '%s' from: %s", active.Name, syncDir)
+	return syncDir
+}
+
+// applyWorkspaceSettings uses the settings of the active workspace in
+// configDir, if any, for the flags that were not specified.
+func applyWorkspaceSettings(cmd *cobra.Command, configDir string) {
+	active, err := workspace.FindActive(configDir)
+	if err != nil {
+		logger.Warnf("failed to load workspace settings: %s", err)
+		return
+	} else if active == nil {
+		return
+	}
+	logger.Debugf("using settings from workspace '%s'", active.Name)
+
+	if active.EngineType != "" && !cmd.Flags().Changed("engine-type") {
+		upFlags.engineType = active.EngineType
+	}
+	if active.EngineVersion != "" && !cmd.Flags().Changed("version") {
+		upFlags.engineVersion = active.EngineVersion
+	}
+	if active.Port != 0 && !cmd.Flags().Changed("port") {
+		upFlags.port = active.Port
+	}
+	for name, value := range active.Environment {
+		// environment variables passed as command-line arguments take precedence
+		if !stringutil.ContainsPrefix(upFlags.environment, name+"=") {
+			upFlags.environment = append(upFlags.environment, name+"="+value)
+		}
+	}
+}
+
+// resolvePort returns the port on which the mock should listen. A free
+// port is selected if the requested port is 0, or if it is in use and
+// autoPort is set. If a port is selected, it is printed to stdout as
+// JSON, so it can be consumed by test harnesses.
+func resolvePort(requested int, autoPort bool) (int, error) {
+	if requested != 0 {
+		if !autoPort || engine.IsPortAvailable(requested) {
+			return requested, nil
+		}
+		logger.Infof("port %d is already in use - selecting a free port", requested)
+	}
+	port, err := engine.FindFreePort()
+	if err != nil {
+		return 0, err
+	}
+	logger.Infof("selected free port: %d", port)
+	j, err := json.Marshal(selectedPort{Port: port})
+	if err != nil {
+		return 0, err
+	}
+	fmt.Println(string(j))
+	return port, nil
+}
+
+type selectedPort struct {
+	Port int `json:"port"`
+}
+
+// parseMemoryLimit converts a human-readable memory limit, such as "512m",
+// to bytes. An empty string means no limit.
+func parseMemoryLimit(memory string) (int64, error) {
+	if memory == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(memory)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit: %s: %v", memory, err)
+	}
+	return bytes, nil
+}
+
+// openLogFile opens the rotating log file, if set, and writes the CLI
+// log to it. The engine output is written to the returned writer.
+func openLogFile(logFile string, maxSize string, maxAge time.Duration, maxBackups int) io.Writer {
+	if logFile == "" {
+		return nil
+	}
+	maxSizeBytes, err := units.RAMInBytes(maxSize)
+	if err != nil {
+		logger.Fatalf("invalid log file size: %s: %v", maxSize, err)
+	}
+	writer, err := logging.NewRotatingFile(logFile, maxSizeBytes, maxAge, maxBackups)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logging.AddOutput(writer)
+	logger.Debugf("writing log to: %s", logFile)
+	return writer
+}
+
+// parseHeapSize validates a JVM heap size, such as '512m' or '2g',
+// returning it in the form accepted by the -Xmx JVM option.
+func parseHeapSize(heap string) (string, error) {
+	if heap == "" {
+		return "", nil
+	}
+	if !heapSizePattern.MatchString(heap) {
+		return "", fmt.Errorf("invalid heap size: %s - must be a number, optionally followed by k, m or g", heap)
+	}
+	return strings.ToLower(heap), nil
+}
+
 func buildStartEnvironment(cliEnvArgs []string) []string {
 	env := append([]string{}, cliEnvArgs...)
 
@@ -167,13 +435,30 @@ func buildStartEnvironment(cliEnvArgs []string) []string {
 	return env
 }
 
-func start(lib *engine.EngineLibrary, startOptions engine.StartOptions, configDir string, restartOnChange bool) {
+func start(lib *engine.EngineLibrary, startOptions engine.StartOptions, configDir string, restartOnChange bool, smokeTest bool, summary bool) {
 	provider := (*lib).GetProvider(startOptions.Version)
 	mockEngine := provider.Build(configDir, startOptions)
 
+	var session *engine.SessionMetrics
+	if summary {
+		session = engine.NewSessionMetrics(startOptions.Port, startOptions.Tls.Enabled)
+	}
+
 	wg := &sync.WaitGroup{}
-	trapExit(mockEngine, wg)
+	trapExit(mockEngine, wg, session)
+	lifecycle.Emit(lifecycle.Event{
+		Type:       lifecycle.EventStarting,
+		EngineType: string(provider.GetEngineType()),
+		Version:    startOptions.Version,
+		Port:       startOptions.Port,
+	})
 	success := mockEngine.Start(wg)
+	if success && session != nil {
+		session.Poll(sessionMetricsInterval)
+	}
+	if success && smokeTest {
+		runSmokeTest(mockEngine, wg, configDir, startOptions)
+	}
 
 	if success && restartOnChange {
 		dirUpdated := fileutil.WatchDir(configDir)
@@ -181,6 +466,7 @@ func start(lib *engine.EngineLibrary, startOptions engine.StartOptions, configDi
 			for {
 				<-dirUpdated
 				logger.Infof("detected change in: %v - triggering restart", configDir)
+				lifecycle.Emit(lifecycle.Event{Type: lifecycle.EventRestarting, Message: "configuration changed"})
 				mockEngine.Restart(wg)
 			}
 		}()
@@ -188,15 +474,94 @@ func start(lib *engine.EngineLibrary, startOptions engine.StartOptions, configDi
 
 	wg.Wait()
 	logger.Debug("shutting down")
+	lifecycle.Emit(lifecycle.Event{Type: lifecycle.EventStopped})
+	if success && session != nil {
+		session.Summary().Write(lifecycle.ConsoleOutput())
+	}
+}
+
+// enableLifecycleEvents writes lifecycle events in the format, if set,
+// to the file, or stdout.
+func enableLifecycleEvents(format string, file string) {
+	if format == "" {
+		if file != "" {
+			failure.Fatal(failure.New(failure.CodeCliUsage, "--events-file requires --events"))
+		}
+		return
+	}
+	if err := lifecycle.Enable(format, file); err != nil {
+		failure.Fatal(failure.Wrap(failure.CodeCliUsage, err))
+	}
+}
+
+// runSmokeTest sends a request to each resource in the configuration,
+// including its variants, stopping the mock if any receives an
+// unexpected server error.
+func runSmokeTest(mockEngine engine.MockEngine, wg *sync.WaitGroup, configDir string, startOptions engine.StartOptions) {
+	recursive := upFlags.recursiveConfigScan || viper.GetBool("config.scan.recursive")
+	tests, err := buildResourceTests(configDir, recursive)
+	if err != nil {
+		logger.Warnf("skipping smoke test: %v", err)
+		return
+	}
+
+	scheme := "http"
+	if startOptions.Tls.Enabled {
+		scheme = "https"
+	}
+	report := verify.SmokeTest(tests, fmt.Sprintf("%s://localhost:%d", scheme, startOptions.Port))
+	for _, result := range report.Results {
+		for _, message := range result.Failures {
+			logger.Errorf("smoke test of %s failed: %s", result.Name, message)
+		}
+	}
+	if failed := report.Failed(); failed > 0 {
+		mockEngine.Stop(wg)
+		wg.Wait()
+		failure.Fatal(failure.New(failure.CodeSmokeTestFailed, "%d of %d resource(s) failed the smoke test", failed, len(report.Results)))
+	}
+	logger.Infof("smoke test passed for %d resource(s)", len(report.Results))
 }
 
-// listen for an interrupt from the OS, then attempt engine cleanup
-func trapExit(mockEngine engine.MockEngine, wg *sync.WaitGroup) {
+// listen for an interrupt from the OS, then attempt engine cleanup,
+// first fetching the final metrics for the session summary, if enabled
+func trapExit(mockEngine engine.MockEngine, wg *sync.WaitGroup, session *engine.SessionMetrics) {
 	c := make(chan os.Signal)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		println()
+		if session != nil {
+			_ = session.Refresh()
+		}
 		mockEngine.StopImmediately(wg)
 	}()
 }
+
+// syncRemoteSpecs fetches each remote spec into the config dir,
+// regenerating its configuration if the spec has changed.
+func syncRemoteSpecs(configDir string, specUrls []string) {
+	for _, specUrl := range specUrls {
+		if _, err := impostermodel.SyncRemoteSpec(configDir, specUrl); err != nil {
+			logger.Fatal(err)
+		}
+	}
+}
+
+// refreshRemoteSpecsPeriodically re-fetches the remote specs at the interval.
+// Changes to the config dir cause the mock to be restarted if auto-restart
+// is enabled.
+func refreshRemoteSpecsPeriodically(configDir string, specUrls []string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, specUrl := range specUrls {
+			changed, err := impostermodel.SyncRemoteSpec(configDir, specUrl)
+			if err != nil {
+				logger.Warnf("failed to refresh remote spec: %v", err)
+			} else if changed {
+				logger.Infof("remote spec %s changed", specUrl)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+)
+
+func Test_parseMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		memory  string
+		want    int64
+		wantErr bool
+	}{
+		{name: "no limit", memory: "", want: 0},
+		{name: "megabytes", memory: "512m", want: 512 * 1024 * 1024},
+		{name: "gigabytes", memory: "1g", want: 1024 * 1024 * 1024},
+		{name: "invalid", memory: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMemoryLimit(tt.memory)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMemoryLimit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseMemoryLimit() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolvePort(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	busyPort := listener.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name      string
+		requested int
+		autoPort  bool
+		wantSame  bool
+	}{
+		{name: "explicit port", requested: busyPort, autoPort: false, wantSame: true},
+		{name: "busy port with auto-port", requested: busyPort, autoPort: true, wantSame: false},
+		{name: "zero port", requested: 0, autoPort: false, wantSame: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePort(tt.requested, tt.autoPort)
+			if err != nil {
+				t.Fatalf("resolvePort() error = %v", err)
+			}
+			if (got == tt.requested) != tt.wantSame {
+				t.Errorf("resolvePort() = %v, requested %v, want same: %v", got, tt.requested, tt.wantSame)
+			}
+			if got == 0 {
+				t.Errorf("resolvePort() returned port 0")
+			}
+		})
+	}
+}
+
+func Test_parseHeapSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		heap    string
+		want    string
+		wantErr bool
+	}{
+		{name: "no heap size", heap: "", want: ""},
+		{name: "megabytes", heap: "512m", want: "512m"},
+		{name: "gigabytes upper case", heap: "2G", want: "2g"},
+		{name: "invalid", heap: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeapSize(tt.heap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseHeapSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseHeapSize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/failure"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+var validateFlags = struct {
+	recursiveConfigScan bool
+}{}
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [CONFIG_DIR]",
+	Short: "Validate Imposter configuration files",
+	Long: `Validates the Imposter configuration files in a directory, without
+starting a mock. Unknown properties, invalid values and matchers, and
+references to response or script files that do not exist are reported,
+with the line on which they occur.
+
+Exits with a non-zero code if any problems are found, so it can be used in CI.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeConfigDirArg(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configDir string
+		if len(args) == 0 {
+			configDir, _ = os.Getwd()
+		} else {
+			configDir, _ = filepath.Abs(args[0])
+		}
+		recursive := validateFlags.recursiveConfigScan || viper.GetBool("config.scan.recursive")
+		validateConfig(configDir, recursive)
+	},
+}
+
+func init() {
+	validateCmd.Flags().BoolVarP(&validateFlags.recursiveConfigScan, "recursive-config-scan", "r", false, "Scan for config files in subdirectories")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validateConfig(configDir string, recursive bool) {
+	if !config.ContainsConfigFile(configDir, recursive) {
+		failure.Fatal(failure.New(failure.CodeConfigNotFound, "no Imposter configuration files found in: %s", configDir))
+	}
+	configErrors := config.LintConfigFiles(configDir, recursive)
+	if len(configErrors) == 0 {
+		logger.Infof("configuration is valid: %s", configDir)
+		return
+	}
+	for _, configErr := range configErrors {
+		fmt.Println(configErr.String())
+	}
+	failure.Fatal(failure.New(failure.CodeConfigInvalid, "found %d problem(s) in configuration: %s", len(configErrors), configDir))
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/bench"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/verify"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var verifyFlags = struct {
+	url           string
+	port          int
+	tls           bool
+	start         bool
+	engineType    string
+	engineVersion string
+	output        string
+	report        string
+}{}
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify TEST_FILE [CONFIG_DIR]",
+	Short: "Run requests against a mock and check the responses",
+	Long: `Sends the requests in a test file to a mock and checks the responses
+against the expected status, headers and body of each test.
+
+By default, the requests are sent to a mock that is already running. Use
+--start to start a mock from CONFIG_DIR for the duration of the tests.
+
+Exits with a non-zero code if any test fails, so it can be used in CI.
+
+If CONFIG_DIR is not specified, the current working directory is used.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeConfigDirArg(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := parseResultsFormat(verifyFlags.output)
+		suite, err := verify.LoadSuite(args[0])
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeVerifyTestsInvalid, err))
+		}
+		baseUrl := verifyFlags.url
+		if baseUrl == "" {
+			scheme := "http"
+			if verifyFlags.tls {
+				scheme = "https"
+			}
+			baseUrl = fmt.Sprintf("%s://localhost:%d", scheme, verifyFlags.port)
+		}
+
+		if verifyFlags.start {
+			var configDir string
+			if len(args) < 2 {
+				configDir, _ = os.Getwd()
+			} else {
+				configDir, _ = filepath.Abs(args[1])
+			}
+			stop := startEphemeralMock(configDir, verifyFlags.engineType, verifyFlags.engineVersion, verifyFlags.port)
+			report := verify.Run(suite, baseUrl)
+			stop()
+			reportVerifyResults(report, filepath.Base(args[0]), format, verifyFlags.report)
+			return
+		}
+		reportVerifyResults(verify.Run(suite, baseUrl), filepath.Base(args[0]), format, verifyFlags.report)
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyFlags.url, "url", "", "Base URL of the mock (default is localhost on the given port)")
+	verifyCmd.Flags().IntVarP(&verifyFlags.port, "port", "p", 8080, "Port on which the mock is listening, or on which to start it")
+	verifyCmd.Flags().BoolVar(&verifyFlags.tls, "tls", false, "Connect to the mock using HTTPS")
+	verifyCmd.Flags().BoolVar(&verifyFlags.start, "start", false, "Start a mock from CONFIG_DIR for the duration of the tests")
+	verifyCmd.Flags().StringVarP(&verifyFlags.engineType, "engine-type", "t", "", "Imposter engine type used with --start (valid: docker,jvm - default \"docker\")")
+	verifyCmd.Flags().StringVarP(&verifyFlags.engineVersion, "version", "v", "", "Imposter engine version used with --start (default \"latest\")")
+	verifyCmd.Flags().StringVarP(&verifyFlags.output, "output", "o", string(outputFormatPlain), "Format of the results printed (valid: plain,json)")
+	verifyCmd.Flags().StringVar(&verifyFlags.report, "report", "", "Also write the results as JUnit XML to this file (e.g. junit.xml)")
+	registerEngineTypeCompletions(verifyCmd)
+	registerEngineVersionCompletions(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// startEphemeralMock starts a mock, waiting until it is ready, and
+// returns a function that stops it.
+func startEphemeralMock(configDir string, engineTypeFlag string, versionFlag string, port int) (stop func()) {
+	if err := config.ValidateConfigExists(configDir, false); err != nil {
+		failure.Fatal(err)
+	}
+	config.MergeCliConfigIfExists(configDir)
+
+	engineType := engine.GetConfiguredType(engineTypeFlag)
+	lib := engine.GetLibrary(engineType)
+	var version string
+	if !lib.IsSealedDistro() {
+		version = engine.GetConfiguredVersion(versionFlag, true)
+	}
+	startOptions := engine.StartOptions{
+		Port:            port,
+		Version:         version,
+		PullPolicy:      engine.PullIfNotPresent,
+		LogLevel:        config.Config.LogLevel,
+		ReplaceRunning:  true,
+		EnablePlugins:   true,
+		EnableFileCache: true,
+		WaitReady:       true,
+	}
+	mockEngine := lib.GetProvider(version).Build(configDir, startOptions)
+	wg := &sync.WaitGroup{}
+	trapExit(mockEngine, wg, nil)
+	if !mockEngine.Start(wg) {
+		failure.Fatal(failure.New(failure.CodeEngineNotReady, "mock failed to start from: %s", configDir))
+	}
+	return func() {
+		mockEngine.Stop(wg)
+		wg.Wait()
+	}
+}
+
+// buildResourceTests returns a test sending a request to each resource in
+// the configuration files in configDir, with the request headers that
+// select it, if any. Path parameters are replaced by a placeholder value,
+// and the expected status is that configured for the resource.
+func buildResourceTests(configDir string, recursive bool) ([]verify.Test, error) {
+	resources, err := config.ListResources(configDir, recursive)
+	if err != nil {
+		return nil, err
+	}
+	var tests []verify.Test
+	for _, resource := range resources {
+		target := bench.BuildTarget(resource.Method, resource.Path)
+		name := target.Method + " " + resource.Path
+		if len(resource.RequestHeaders) > 0 {
+			var headers []string
+			for header, value := range resource.RequestHeaders {
+				headers = append(headers, header+"="+value)
+			}
+			sort.Strings(headers)
+			name += " [" + strings.Join(headers, ", ") + "]"
+		}
+		tests = append(tests, verify.Test{
+			Name:    name,
+			Request: verify.Request{Method: target.Method, Path: target.Path, Headers: resource.RequestHeaders},
+			Expect:  verify.Expectation{Status: resource.StatusCode},
+		})
+	}
+	return tests, nil
+}
+
+// reportVerifyResults prints the result of each test in the format, and
+// writes them as JUnit XML to reportFile, if set, exiting with a non-zero
+// code if any failed.
+func reportVerifyResults(report verify.Report, suiteName string, format outputFormat, reportFile string) {
+	if reportFile != "" {
+		if err := verify.WriteJUnit(report, suiteName, reportFile); err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeVerifyFailed, err))
+		}
+	}
+	switch format {
+	case outputFormatPlain:
+		printVerifyResults(report)
+	case outputFormatJson:
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			failure.Fatal(failure.Wrap(failure.CodeVerifyFailed, err))
+		}
+		fmt.Println(string(output))
+	default:
+		panic(fmt.Errorf("unsupported output format: %s", format))
+	}
+	if failed := report.Failed(); failed > 0 {
+		failure.Fatal(failure.New(failure.CodeVerifyFailed, "%d of %d test(s) failed", failed, len(report.Results)))
+	}
+}
+
+// parseResultsFormat validates the format in which results are printed.
+func parseResultsFormat(format string) outputFormat {
+	switch outputFormat(format) {
+	case outputFormatPlain, outputFormatJson:
+		return outputFormat(format)
+	default:
+		failure.Fatal(failure.New(failure.CodeCliUsage, "unsupported output format: %s (valid: %s,%s)", format, outputFormatPlain, outputFormatJson))
+		return ""
+	}
+}
+
+func printVerifyResults(report verify.Report) {
+	for _, result := range report.Results {
+		if result.Passed() {
+			fmt.Printf("PASS %s (%v)\n", result.Name, result.Duration.Round(time.Millisecond))
+			continue
+		}
+		fmt.Printf("FAIL %s (%v)\n", result.Name, result.Duration.Round(time.Millisecond))
+		for _, message := range result.Failures {
+			fmt.Printf("  - %s\n", strings.ReplaceAll(message, "\n", "\n    "))
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", len(report.Results)-report.Failed(), report.Failed())
+}
@@ -21,6 +21,7 @@ import (
 	"gatehill.io/imposter/config"
 	"gatehill.io/imposter/engine"
 	"github.com/spf13/cobra"
+	"strings"
 )
 
 type outputFormat string
@@ -28,6 +29,7 @@ type outputFormat string
 const (
 	outputFormatPlain outputFormat = "plain"
 	outputFormatJson  outputFormat = "json"
+	outputFormatYaml  outputFormat = "yaml"
 )
 
 var versionFlags = struct {
@@ -35,6 +37,13 @@ var versionFlags = struct {
 	format     string
 }{}
 
+// versionOutput is the structure printed for the JSON and YAML formats.
+type versionOutput struct {
+	Cli          string `json:"imposter-cli"`
+	Engine       string `json:"imposter-engine"`
+	EngineOutput string `json:"engine-output,omitempty"`
+}
+
 // versionCmd represents the up command
 var versionCmd = &cobra.Command{
 	Use:   "version",
@@ -42,25 +51,21 @@ var versionCmd = &cobra.Command{
 	Long:  `Prints the version of the CLI and engine, if available.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		engineType := engine.GetConfiguredType(versionFlags.engineType)
-		var format outputFormat
-		if versionFlags.format != "" {
-			format = outputFormat(versionFlags.format)
-		} else {
-			format = outputFormatPlain
-		}
-		println(describeVersions(engineType, format))
+		fmt.Println(describeVersions(engineType, parseOutputFormat(versionFlags.format)))
 	},
 }
 
 func init() {
 	versionCmd.Flags().StringVarP(&versionFlags.engineType, "engine-type", "t", "", "Imposter engine type (valid: docker,jvm - default \"docker\")")
-	versionCmd.Flags().StringVarP(&versionFlags.format, "output-format", "o", "", "Output format (valid: plain,json - default \"plain\")")
+	addOutputFlag(versionCmd, &versionFlags.format)
+	versionCmd.Flags().StringVar(&versionFlags.format, "output-format", string(outputFormatPlain), "Output format (valid: plain,json,yaml)")
+	_ = versionCmd.Flags().MarkDeprecated("output-format", "use --output instead")
 	registerEngineTypeCompletions(versionCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 func describeVersions(engineType engine.EngineType, format outputFormat) string {
-	output := formatProperty(format, "imposter-cli", config.Config.Version, false)
+	versions := versionOutput{Cli: config.Config.Version}
 
 	library := engine.GetLibrary(engineType)
 	engines, err := library.List()
@@ -68,40 +73,28 @@ func describeVersions(engineType engine.EngineType, format outputFormat) string
 		logger.Fatal(err)
 	}
 	if len(engines) == 0 {
-		output += formatProperty(format, "imposter-engine", "none", true)
+		versions.Engine = "none"
 	} else {
 		engineConfigVersion := engine.GetConfiguredVersionOrResolve("", true, false)
 		if engineConfigVersion == "latest" {
 			engineConfigVersion = engine.GetHighestVersion(engines)
 		}
-		output += formatProperty(format, "imposter-engine", engineConfigVersion, false)
-		output += formatProperty(format, "engine-output", getInstalledEngineVersion(engineType, engineConfigVersion), true)
+		versions.Engine = engineConfigVersion
+		versions.EngineOutput = getInstalledEngineVersion(engineType, engineConfigVersion)
 	}
 
-	switch format {
-	case outputFormatPlain:
+	if format == outputFormatPlain {
+		output := fmt.Sprintf("imposter-cli %s\nimposter-engine %s\n", versions.Cli, versions.Engine)
+		if versions.EngineOutput != "" {
+			output += fmt.Sprintf("engine-output %s\n", versions.EngineOutput)
+		}
 		return output
-	case outputFormatJson:
-		return fmt.Sprintf("{\n%s}", output)
-	default:
-		panic(fmt.Errorf("unsupported output format: %s", format))
 	}
-}
-
-func formatProperty(format outputFormat, key string, value string, lastProp bool) string {
-	var formatted string
-	switch format {
-	case outputFormatPlain:
-		formatted = fmt.Sprintf("%s %s", key, value)
-	case outputFormatJson:
-		formatted = fmt.Sprintf(`  "%s": "%s"`, key, value)
-		if !lastProp {
-			formatted += ","
-		}
-	default:
-		panic(fmt.Errorf("unsupported output format: %s", format))
+	output, err := marshalStructured(format, versions)
+	if err != nil {
+		panic(err)
 	}
-	return formatted + "\n"
+	return strings.TrimSuffix(string(output), "\n")
 }
 
 func getInstalledEngineVersion(engineType engine.EngineType, version string) string {
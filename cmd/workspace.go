@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"os"
 )
 
 var workspaceFlags struct {
@@ -35,3 +36,13 @@ func init() {
 	workspaceCmd.PersistentFlags().StringVarP(&workspaceFlags.path, "workspace", "w", "", "workspace path")
 	rootCmd.AddCommand(workspaceCmd)
 }
+
+// getWorkspacePath returns the path set by the workspace flag,
+// or the current working directory.
+func getWorkspacePath() string {
+	if workspaceFlags.path != "" {
+		return workspaceFlags.path
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
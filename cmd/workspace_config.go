@@ -0,0 +1,95 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// workspaceConfigCmd represents the workspaceConfig command
+var workspaceConfigCmd = &cobra.Command{
+	Use:   "config [key=value]",
+	Short: "Configure workspace settings",
+	Long: `Configures the settings of the active workspace, which are used by
+'imposter up' when starting a mock in the workspace directory. Settings
+are overridden by command line flags.
+
+Supported keys are engineType, engineVersion, port, gitUrl and gitRef
+(see 'imposter workspace pull'), and env.NAME to set the environment
+variable NAME. Set a key to an empty value to remove it.
+
+If no key=value pairs are specified, the current settings are shown.`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var formattedKeys []string
+		for _, k := range workspace.SettingKeys {
+			formattedKeys = append(formattedKeys, k+"=VAL")
+		}
+		return formattedKeys, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var dir string
+		if workspaceFlags.path != "" {
+			dir = workspaceFlags.path
+		} else {
+			dir, _ = os.Getwd()
+		}
+		if len(args) == 0 {
+			printWorkspaceSettings(dir)
+			return
+		}
+		for _, pair := range config.ParseConfig(args) {
+			setWorkspaceSetting(dir, pair.Key, pair.Value)
+		}
+	},
+}
+
+func init() {
+	workspaceCmd.AddCommand(workspaceConfigCmd)
+}
+
+func setWorkspaceSetting(dir string, key string, value string) {
+	active, err := workspace.SetSetting(dir, key, value)
+	if err != nil {
+		logger.Fatalf("failed to set workspace %s: %s", key, err)
+	}
+	if value == "" {
+		logger.Infof("removed %s for workspace: %s", key, active.Name)
+	} else {
+		logger.Infof("set %s for workspace: %s", key, active.Name)
+	}
+}
+
+func printWorkspaceSettings(dir string) {
+	active, err := workspace.GetActive(dir)
+	if err != nil {
+		logger.Fatalf("failed to get active workspace: %s", err)
+	} else if active == nil {
+		logger.Fatalf("no active workspace")
+	}
+	settings := workspace.GetSettings(active)
+	if len(settings) == 0 {
+		fmt.Printf("Workspace '%s' has no settings\n", active.Name)
+		return
+	}
+	for _, setting := range settings {
+		fmt.Printf("%s=%s\n", setting[0], setting[1])
+	}
+}
@@ -29,7 +29,7 @@ var workspaceDeleteCmd = &cobra.Command{
 	Long:  `Deletes a workspace, if it exists.`,
 	Args:  cobra.ExactArgs(1),
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return suggestWorkspaceNames()
+		return suggestWorkspaceNames(getWorkspacePath())
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		var dir string
@@ -48,9 +48,16 @@ func init() {
 }
 
 func deleteWorkspace(dir string, name string) {
-	err := workspace.Delete(dir, name)
+	active, err := workspace.FindActive(dir)
+	if err != nil {
+		logger.Fatalf("failed to delete workspace: %s", err)
+	}
+	err = workspace.Delete(dir, name)
 	if err != nil {
 		logger.Fatalf("failed to delete workspace: %s", err)
 	}
 	logger.Infof("deleted workspace '%s'", name)
+	if active != nil && active.Name == name {
+		logger.Infof("no workspace is active - use 'imposter workspace select' to choose one")
+	}
 }
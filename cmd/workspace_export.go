@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var workspaceExportFlags = struct {
+	output string
+	force  bool
+}{}
+
+// workspaceExportCmd represents the workspaceExport command
+var workspaceExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export workspaces to an archive",
+	Long: `Exports the workspace directory to a ZIP archive, containing the
+configuration files, response files and workspace metadata, so it can be
+restored on another machine with 'imposter workspace import'.
+
+Hidden files and the recording library are not included. Remote
+credentials are stored outside the workspace, so are never exported.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var dir string
+		if workspaceFlags.path != "" {
+			dir = workspaceFlags.path
+		} else {
+			dir, _ = os.Getwd()
+		}
+		exportWorkspace(dir, workspaceExportFlags.output, workspaceExportFlags.force)
+	},
+}
+
+func init() {
+	workspaceExportCmd.Flags().StringVarP(&workspaceExportFlags.output, "output", "o", "", "Path of the archive to write (default is the directory name with a .zip extension)")
+	workspaceExportCmd.Flags().BoolVarP(&workspaceExportFlags.force, "force", "f", false, "Overwrite the archive if it exists")
+	workspaceCmd.AddCommand(workspaceExportCmd)
+}
+
+func exportWorkspace(dir string, output string, force bool) {
+	absDir, _ := filepath.Abs(dir)
+	if output == "" {
+		output = filepath.Base(absDir) + ".zip"
+	}
+	fileutil.MustNotExist(output, force)
+	absOutput, _ := filepath.Abs(output)
+
+	archive, err := workspace.Export(absDir, absOutput)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err = os.WriteFile(output, archive, 0644); err != nil {
+		logger.Fatal(fmt.Errorf("failed to write archive: %s: %s", output, err))
+	}
+	logger.Infof("exported workspace to: %s", output)
+}
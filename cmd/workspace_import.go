@@ -0,0 +1,73 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var workspaceImportFlags = struct {
+	force bool
+}{}
+
+// workspaceImportCmd represents the workspaceImport command
+var workspaceImportCmd = &cobra.Command{
+	Use:   "import ARCHIVE",
+	Short: "Import workspaces from an archive",
+	Long: `Imports an archive created by 'imposter workspace export' into the
+workspace directory, restoring its configuration files, response files
+and workspace metadata.
+
+The import fails if any of the files already exist, unless --force
+is set.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var dir string
+		if workspaceFlags.path != "" {
+			dir = workspaceFlags.path
+		} else {
+			dir, _ = os.Getwd()
+		}
+		importWorkspace(dir, args[0], workspaceImportFlags.force)
+	},
+}
+
+func init() {
+	workspaceImportCmd.Flags().BoolVarP(&workspaceImportFlags.force, "force", "f", false, "Overwrite existing files")
+	workspaceCmd.AddCommand(workspaceImportCmd)
+}
+
+func importWorkspace(dir string, archivePath string, force bool) {
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		logger.Fatalf("failed to read archive: %s: %s", archivePath, err)
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		logger.Fatalf("failed to create workspace directory: %s: %s", dir, err)
+	}
+	imported, err := workspace.Import(dir, archive, force)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("imported %d files into: %s", len(imported), dir)
+
+	if active, err := workspace.GetActive(dir); err == nil && active != nil {
+		logger.Infof("active workspace is '%s'", active.Name)
+	}
+}
@@ -23,6 +23,17 @@ import (
 	"os"
 )
 
+var workspaceListFlags = struct {
+	output string
+}{}
+
+// workspaceOutput describes a workspace, for the JSON and YAML formats.
+type workspaceOutput struct {
+	Name       string `json:"name"`
+	RemoteType string `json:"remoteType"`
+	Active     bool   `json:"active"`
+}
+
 // workspaceListCmd represents the workspaceList command
 var workspaceListCmd = &cobra.Command{
 	Use:     "list",
@@ -36,15 +47,16 @@ var workspaceListCmd = &cobra.Command{
 		} else {
 			dir, _ = os.Getwd()
 		}
-		listWorkspaces(dir)
+		listWorkspaces(dir, parseOutputFormat(workspaceListFlags.output))
 	},
 }
 
 func init() {
+	addOutputFlag(workspaceListCmd, &workspaceListFlags.output)
 	workspaceCmd.AddCommand(workspaceListCmd)
 }
 
-func listWorkspaces(dir string) {
+func listWorkspaces(dir string, format outputFormat) {
 	workspaces, err := workspace.List(dir)
 	if err != nil {
 		logger.Fatalf("failed to list workspaces: %s", err)
@@ -58,20 +70,29 @@ func listWorkspaces(dir string) {
 		activeName = active.Name
 	}
 
+	if format != outputFormatPlain {
+		output := []workspaceOutput{}
+		for _, w := range workspaces {
+			output = append(output, workspaceOutput{Name: w.Name, RemoteType: w.RemoteType, Active: w.Name == activeName})
+		}
+		printStructured(format, output)
+		return
+	}
+
 	var rows [][]string
 	for _, w := range workspaces {
 		var activeStatus string
 		if w.Name == activeName {
 			activeStatus = "active"
 		}
-		rows = append(rows, []string{w.Name, activeStatus})
+		rows = append(rows, []string{w.Name, w.RemoteType, activeStatus})
 	}
 	renderWorkspaces(rows)
 }
 
 func renderWorkspaces(rows [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Workspace", "Status"})
+	table.SetHeader([]string{"Workspace", "Remote", "Status"})
 	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 	table.SetCenterSeparator("|")
 	table.AppendBulk(rows)
@@ -0,0 +1,57 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"gatehill.io/imposter/workspace"
+	"github.com/spf13/cobra"
+)
+
+// workspacePullCmd represents the workspacePull command
+var workspacePullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Sync a git-backed workspace",
+	Long: `Clones or updates the git repository referenced by the active workspace,
+checking out its configured ref.
+
+Set the repository with 'imposter workspace config gitUrl=URL' and,
+optionally, a branch, tag or commit with 'gitRef=REF'. Once pulled,
+'imposter up' runs the mocks from the synced copy. Local changes to the
+synced copy are discarded.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pullWorkspace(getWorkspacePath())
+	},
+}
+
+func init() {
+	workspaceCmd.AddCommand(workspacePullCmd)
+}
+
+func pullWorkspace(dir string) {
+	active, err := workspace.GetActive(dir)
+	if err != nil {
+		logger.Fatalf("failed to pull workspace: %s", err)
+	} else if active == nil {
+		logger.Fatalf("no active workspace")
+	}
+	syncDir, revision, err := workspace.Pull(dir, active)
+	if err != nil {
+		logger.Fatalf("failed to pull workspace: %s", err)
+	}
+	logger.Infof("pulled workspace '%s' at revision %s into: %s", active.Name, revision, syncDir)
+}
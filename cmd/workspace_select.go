@@ -29,7 +29,7 @@ var workspaceSelectCmd = &cobra.Command{
 	Long:  `Sets the active workspace, if it exists.`,
 	Args:  cobra.ExactArgs(1),
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return suggestWorkspaceNames()
+		return suggestWorkspaceNames(getWorkspacePath())
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
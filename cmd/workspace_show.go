@@ -59,6 +59,9 @@ func printActiveWorkspace(dir string) {
 			remoteType = (*rem).GetType()
 		}
 		fmt.Printf("Active workspace: %s\nRemote provider: %s\n", active.Name, remoteType)
+		for _, setting := range workspace.GetSettings(active) {
+			fmt.Printf("Setting: %s=%s\n", setting[0], setting[1])
+		}
 	} else {
 		fmt.Printf("No active workspace\n")
 	}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"golang.org/x/mod/semver"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
@@ -113,7 +112,7 @@ func ParseConfig(args []string) []ConfigPair {
 			logger.Warnf("invalid config item: %s", arg)
 			continue
 		}
-		splitArgs := strings.Split(arg, "=")
+		splitArgs := strings.SplitN(arg, "=", 2)
 		pairs = append(pairs, ConfigPair{
 			Key:   splitArgs[0],
 			Value: strings.Trim(splitArgs[1], `"`),
@@ -123,20 +122,5 @@ func ParseConfig(args []string) []ConfigPair {
 }
 
 func WriteLocalConfigValue(configDir string, key string, value string) error {
-	v := viper.New()
-
-	localConfig := path.Join(configDir, LocalDirConfigFileName+".yaml")
-	v.SetConfigFile(localConfig)
-
-	// sink if does not exist
-	_ = v.ReadInConfig()
-
-	v.Set(key, value)
-	err := v.WriteConfig()
-	if err != nil {
-		return fmt.Errorf("failed to write config file: %s: %v", localConfig, err)
-	}
-
-	logger.Tracef("wrote CLI config to: %s", localConfig)
-	return nil
+	return SetConfigValue(GetLocalConfigFile(configDir), key, value)
 }
@@ -0,0 +1,212 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFormat is the format of a mock configuration file.
+type ConfigFormat string
+
+const (
+	ConfigFormatYaml ConfigFormat = "yaml"
+	ConfigFormatJson ConfigFormat = "json"
+)
+
+// ParseConfigFormat parses a config file format, which is 'yaml' or 'json'.
+func ParseConfigFormat(format string) (ConfigFormat, error) {
+	switch ConfigFormat(strings.ToLower(format)) {
+	case ConfigFormatYaml, "yml":
+		return ConfigFormatYaml, nil
+	case ConfigFormatJson:
+		return ConfigFormatJson, nil
+	default:
+		return "", fmt.Errorf("unsupported config format: %s (valid: yaml,json)", format)
+	}
+}
+
+// ListConfigFiles returns the config files in configDir.
+func ListConfigFiles(configDir string) ([]string, error) {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list directory contents: %s: %v", configDir, err)
+	}
+	var configFiles []string
+	for _, file := range files {
+		if !file.IsDir() && matchesConfigFileFmt(file) {
+			configFiles = append(configFiles, filepath.Join(configDir, file.Name()))
+		}
+	}
+	return configFiles, nil
+}
+
+// ConvertConfig converts the content of a YAML or JSON config file to the
+// format, normalising its formatting. The order of properties is kept.
+// Comments at the start of a YAML file are kept if the output is YAML;
+// other comments cannot be preserved.
+func ConvertConfig(content []byte, format ConfigFormat) ([]byte, error) {
+	var parsed yaml.MapSlice
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON: %v", err)
+	}
+	header, hasOtherComments := extractComments(content)
+	switch format {
+	case ConfigFormatYaml:
+		converted, err := yaml.Marshal(parsed)
+		if err != nil {
+			return nil, err
+		}
+		if hasOtherComments {
+			logger.Warnf("comments after the start of the file are not preserved")
+		}
+		return append(header, converted...), nil
+
+	case ConfigFormatJson:
+		if len(header) > 0 || hasOtherComments {
+			logger.Warnf("comments are not preserved in JSON")
+		}
+		var buf bytes.Buffer
+		if err := writeJson(&buf, parsed); err != nil {
+			return nil, err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		indented.WriteString("\n")
+		return indented.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+}
+
+// ConvertConfigFile converts the config file to the format, returning
+// the path of the converted file. If the format changes, the file is
+// replaced by one with the extension of the format.
+func ConvertConfigFile(filePath string, format ConfigFormat) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read file: %s: %v", filePath, err)
+	}
+	converted, err := ConvertConfig(content, format)
+	if err != nil {
+		return "", fmt.Errorf("unable to convert %s: %v", filePath, err)
+	}
+	destFilePath := BuildConvertedFilePath(filePath, format)
+	if destFilePath != filePath {
+		if _, err := os.Stat(destFilePath); err == nil {
+			return "", fmt.Errorf("unable to convert %s: %s already exists", filePath, destFilePath)
+		}
+	}
+	if err := os.WriteFile(destFilePath, converted, 0644); err != nil {
+		return "", fmt.Errorf("unable to write file: %s: %v", destFilePath, err)
+	}
+	if destFilePath != filePath {
+		// the engine would otherwise load both files
+		if err := os.Remove(filePath); err != nil {
+			return "", fmt.Errorf("unable to remove file: %s: %v", filePath, err)
+		}
+	}
+	return destFilePath, nil
+}
+
+// BuildConvertedFilePath returns the path of the file converted to the
+// format. YAML files with the '.yml' extension keep it.
+func BuildConvertedFilePath(filePath string, format ConfigFormat) string {
+	ext := filepath.Ext(filePath)
+	switch format {
+	case ConfigFormatYaml:
+		if ext == ".yaml" || ext == ".yml" {
+			return filePath
+		}
+		return strings.TrimSuffix(filePath, ext) + ".yaml"
+	default:
+		return strings.TrimSuffix(filePath, ext) + ".json"
+	}
+}
+
+// extractComments returns the comment lines at the start of the
+// content, and whether there are comments after them.
+func extractComments(content []byte) (header []byte, hasOtherComments bool) {
+	lines := strings.Split(string(content), "\n")
+	i := 0
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+	}
+	for _, line := range lines[:i] {
+		if strings.TrimSpace(line) != "" {
+			header = append(header, []byte(line+"\n")...)
+		}
+	}
+	for _, line := range lines[i:] {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.Contains(line, " #") {
+			hasOtherComments = true
+			break
+		}
+	}
+	return header, hasOtherComments
+}
+
+// writeJson writes the value decoded from YAML as JSON, keeping the
+// order of the properties of objects.
+func writeJson(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		buf.WriteString("{")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(fmt.Sprintf("%v", item.Key))
+			buf.Write(key)
+			buf.WriteString(":")
+			if err := writeJson(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJson(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
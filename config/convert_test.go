@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  ConfigFormat
+		want    string
+	}{
+		{
+			name: "yaml to json",
+			content: `# petstore mock
+plugin: rest
+resources:
+  - path: /pets
+    method: GET
+    response:
+      statusCode: 200
+`,
+			format: ConfigFormatJson,
+			want: `{
+  "plugin": "rest",
+  "resources": [
+    {
+      "path": "/pets",
+      "method": "GET",
+      "response": {
+        "statusCode": 200
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			name:    "json to yaml",
+			content: `{"plugin": "rest", "resources": [{"path": "/pets", "method": "GET"}]}`,
+			format:  ConfigFormatYaml,
+			want: `plugin: rest
+resources:
+- path: /pets
+  method: GET
+`,
+		},
+		{
+			name: "normalise yaml",
+			content: `# petstore mock
+
+plugin:   rest
+resources: [{path: /pets}]
+`,
+			format: ConfigFormatYaml,
+			want: `# petstore mock
+plugin: rest
+resources:
+- path: /pets
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertConfig([]byte(tt.content), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ConvertConfig() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "petstore-config.yml")
+	if err := os.WriteFile(filePath, []byte("plugin: rest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := ConvertConfigFile(filePath, ConfigFormatJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted != filepath.Join(dir, "petstore-config.json") {
+		t.Errorf("unexpected converted file: %s", converted)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("expected original file to be removed")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/stringutil"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+)
+
+// corsPlugins are the plugins serving HTTP requests, which browsers can
+// call from other origins.
+var corsPlugins = []string{"openapi", "rest", "soap", "graphql"}
+
+// AddCorsConfig sets the CORS settings of the config file, replacing any
+// existing settings. Files for plugins that do not serve HTTP requests are
+// left unchanged, in which case false is returned.
+func AddCorsConfig(file string, cors *impostermodel.CorsConfig) (bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("unable to read file: %s: %v", file, err)
+	}
+	var parsed yaml.MapSlice
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return false, fmt.Errorf("invalid YAML or JSON: %s: %v", file, err)
+	}
+	if plugin, _ := getItem(parsed, "plugin"); !stringutil.Contains(corsPlugins, fmt.Sprintf("%v", plugin)) {
+		logger.Debugf("skipping config file for plugin %v: %s", plugin, file)
+		return false, nil
+	}
+
+	// the settings are converted via JSON to keep the order of properties
+	encoded, err := json.Marshal(cors)
+	if err != nil {
+		return false, err
+	}
+	var corsValue yaml.MapSlice
+	if err := yaml.Unmarshal(encoded, &corsValue); err != nil {
+		return false, err
+	}
+	parsed = setPluginProperty(parsed, "cors", corsValue)
+
+	updated, err := yaml.Marshal(parsed)
+	if err != nil {
+		return false, err
+	}
+	header, _ := extractComments(content)
+	format := ConfigFormatYaml
+	if filepath.Ext(file) == ".json" {
+		format = ConfigFormatJson
+	}
+	converted, err := ConvertConfig(append(header, updated...), format)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(file, converted, 0644); err != nil {
+		return false, fmt.Errorf("unable to write file: %s: %v", file, err)
+	}
+	return true, nil
+}
+
+// setPluginProperty sets the value of the property, replacing it if it
+// exists, or otherwise adding it before the response, resources and
+// interceptors of the config.
+func setPluginProperty(config yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range config {
+		if fmt.Sprintf("%v", item.Key) == key {
+			config[i].Value = value
+			return config
+		}
+	}
+	insertAt := len(config)
+	for i, item := range config {
+		if stringutil.Contains([]string{"response", "resources", "interceptors"}, fmt.Sprintf("%v", item.Key)) {
+			insertAt = i
+			break
+		}
+	}
+	updated := append(yaml.MapSlice{}, config[:insertAt]...)
+	updated = append(updated, yaml.MapItem{Key: key, Value: value})
+	return append(updated, config[insertAt:]...)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"gatehill.io/imposter/impostermodel"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddCorsConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		content     string
+		origins     []string
+		wantUpdated bool
+		want        string
+	}{
+		{
+			name:        "permissive",
+			fileName:    "mock-config.yaml",
+			content:     "# pets\nplugin: rest\nresources:\n- path: /pets\n",
+			wantUpdated: true,
+			want: `# pets
+plugin: rest
+cors:
+  allowOrigins: all
+  allowHeaders:
+  - Accept
+  - Authorization
+  - Content-Type
+  - X-Requested-With
+  allowMethods:
+  - GET
+  - HEAD
+  - POST
+  - PUT
+  - PATCH
+  - DELETE
+  - OPTIONS
+  maxAge: 300
+resources:
+- path: /pets
+`,
+		},
+		{
+			name:        "replace origins in json",
+			fileName:    "mock-config.json",
+			content:     `{"plugin": "openapi", "specFile": "pets.yaml", "cors": {"allowOrigins": "all"}}`,
+			origins:     []string{"http://localhost:3000"},
+			wantUpdated: true,
+			want: `{
+  "plugin": "openapi",
+  "specFile": "pets.yaml",
+  "cors": {
+    "allowOrigins": [
+      "http://localhost:3000"
+    ],
+    "allowHeaders": [
+      "Accept",
+      "Authorization",
+      "Content-Type",
+      "X-Requested-With"
+    ],
+    "allowMethods": [
+      "GET",
+      "HEAD",
+      "POST",
+      "PUT",
+      "PATCH",
+      "DELETE",
+      "OPTIONS"
+    ],
+    "maxAge": 300
+  }
+}
+`,
+		},
+		{
+			name:        "skip non-http plugin",
+			fileName:    "mock-config.yaml",
+			content:     "plugin: kafka\n",
+			wantUpdated: false,
+			want:        "plugin: kafka\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			updated, err := AddCorsConfig(file, impostermodel.BuildCorsConfig(tt.origins))
+			if err != nil {
+				t.Fatalf("AddCorsConfig() error = %v", err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("AddCorsConfig() updated = %v, want %v", updated, tt.wantUpdated)
+			}
+			got, _ := os.ReadFile(file)
+			if string(got) != tt.want {
+				t.Errorf("AddCorsConfig() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
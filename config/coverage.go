@@ -0,0 +1,241 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"gatehill.io/imposter/openapi"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// ResourceRef is a resource declared in a config file.
+type ResourceRef struct {
+	File   string `json:"file"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path"`
+
+	// RequestHeaders must be sent to match the resource, and StatusCode
+	// is that of its response, if configured.
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	StatusCode     int               `json:"statusCode,omitempty"`
+}
+
+// OperationCoverage holds the resources matching an operation in a spec.
+type OperationCoverage struct {
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Resources []ResourceRef `json:"resources,omitempty"`
+}
+
+func (o OperationCoverage) Covered() bool {
+	return len(o.Resources) > 0
+}
+
+// CoverageReport describes the operations of a spec that are covered by
+// the resources in a config directory, and the resources that match no
+// operation in the spec.
+type CoverageReport struct {
+	Operations []OperationCoverage `json:"operations"`
+	Unmatched  []ResourceRef       `json:"unmatched,omitempty"`
+}
+
+// Percentage returns the percentage of operations that are covered.
+func (r CoverageReport) Percentage() float64 {
+	if len(r.Operations) == 0 {
+		return 100
+	}
+	covered := 0
+	for _, operation := range r.Operations {
+		if operation.Covered() {
+			covered++
+		}
+	}
+	return float64(covered) * 100 / float64(len(r.Operations))
+}
+
+type resourceConfig struct {
+	Method         string                 `json:"method"`
+	Path           string                 `json:"path"`
+	RequestHeaders map[string]interface{} `json:"requestHeaders"`
+	Response       *resourceResponse      `json:"response"`
+	Resources      []resourceConfigEntry  `json:"resources"`
+}
+
+type resourceConfigEntry struct {
+	Method         string                 `json:"method"`
+	Path           string                 `json:"path"`
+	RequestHeaders map[string]interface{} `json:"requestHeaders"`
+	Response       *resourceResponse      `json:"response"`
+}
+
+type resourceResponse struct {
+	StatusCode int `json:"statusCode"`
+}
+
+func (r *resourceResponse) statusCode() int {
+	if r == nil {
+		return 0
+	}
+	return r.StatusCode
+}
+
+// ComputeCoverage compares the operations in the OpenAPI spec with the
+// resources in the config files in configDir. A resource matches an
+// operation if its method is the same, or is not specified, and its path
+// matches the path of the operation. Path parameters match regardless
+// of their name, and a trailing '*' wildcard matches any suffix.
+func ComputeCoverage(specFile string, configDir string, recursive bool) (*CoverageReport, error) {
+	spec, err := openapi.Parse(specFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse openapi spec: %s: %v", specFile, err)
+	}
+	resources, err := ListResources(configDir, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CoverageReport{}
+	matched := make(map[int]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			operation := OperationCoverage{Method: strings.ToUpper(method), Path: path}
+			for i, resource := range resources {
+				if matchesOperation(resource, operation.Method, path) {
+					operation.Resources = append(operation.Resources, resource)
+					matched[i] = true
+				}
+			}
+			report.Operations = append(report.Operations, operation)
+		}
+	}
+	sort.Slice(report.Operations, func(i, j int) bool {
+		if report.Operations[i].Path != report.Operations[j].Path {
+			return report.Operations[i].Path < report.Operations[j].Path
+		}
+		return report.Operations[i].Method < report.Operations[j].Method
+	})
+	for i, resource := range resources {
+		if !matched[i] {
+			report.Unmatched = append(report.Unmatched, resource)
+		}
+	}
+	return report, nil
+}
+
+// ListResources returns the resources with a path in the config files
+// in configDir. Resources of plugins that are not matched by path, such
+// as soap, are ignored.
+func ListResources(configDir string, recursive bool) ([]ResourceRef, error) {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list directory contents: %s: %v", configDir, err)
+	}
+	var resources []ResourceRef
+	for _, file := range files {
+		filePath := filepath.Join(configDir, file.Name())
+		if file.IsDir() {
+			if recursive {
+				nested, err := ListResources(filePath, recursive)
+				if err != nil {
+					return nil, err
+				}
+				resources = append(resources, nested...)
+			}
+			continue
+		} else if !matchesConfigFileFmt(file) {
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file: %s: %v", filePath, err)
+		}
+		var parsed resourceConfig
+		if err := yaml.Unmarshal(content, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid YAML or JSON: %s: %v", filePath, err)
+		}
+		if parsed.Path != "" {
+			resources = append(resources, ResourceRef{
+				File:           filePath,
+				Method:         strings.ToUpper(parsed.Method),
+				Path:           parsed.Path,
+				RequestHeaders: exactHeaderValues(parsed.RequestHeaders),
+				StatusCode:     parsed.Response.statusCode(),
+			})
+		}
+		for _, resource := range parsed.Resources {
+			if resource.Path != "" {
+				resources = append(resources, ResourceRef{
+					File:           filePath,
+					Method:         strings.ToUpper(resource.Method),
+					Path:           resource.Path,
+					RequestHeaders: exactHeaderValues(resource.RequestHeaders),
+					StatusCode:     resource.Response.statusCode(),
+				})
+			}
+		}
+	}
+	return resources, nil
+}
+
+// exactHeaderValues returns the values of the headers matched exactly,
+// either as a plain value or with the EqualTo operator. Headers matched
+// by other operators are omitted.
+func exactHeaderValues(headers map[string]interface{}) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for name, matcher := range headers {
+		switch m := matcher.(type) {
+		case string:
+			values[name] = m
+		case map[string]interface{}:
+			operator, _ := m["operator"].(string)
+			if value, ok := m["value"].(string); ok && (operator == "" || operator == "EqualTo") {
+				values[name] = value
+			}
+		}
+	}
+	return values
+}
+
+func matchesOperation(resource ResourceRef, method string, path string) bool {
+	if resource.Method != "" && resource.Method != method {
+		return false
+	}
+	resourcePath := normalisePathParams(resource.Path)
+	if prefix, wildcard := strings.CutSuffix(resourcePath, "*"); wildcard {
+		return strings.HasPrefix(normalisePathParams(path), prefix)
+	}
+	return strings.TrimSuffix(resourcePath, "/") == strings.TrimSuffix(normalisePathParams(path), "/")
+}
+
+// normalisePathParams replaces the names of path parameters, so paths
+// with differently named parameters are equal.
+func normalisePathParams(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
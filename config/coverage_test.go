@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	dir := t.TempDir()
+	specFile := filepath.Join(dir, "petstore.yaml")
+	spec := `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      responses: {}
+    post:
+      responses: {}
+  /pets/{petId}:
+    get:
+      responses: {}
+  /orders/{orderId}:
+    delete:
+      responses: {}
+`
+	config := `plugin: rest
+resources:
+  - method: GET
+    path: /pets
+  - path: /pets/{id}
+  - method: DELETE
+    path: /orders/*
+  - method: GET
+    path: /stores
+`
+	if err := os.WriteFile(specFile, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "petstore-config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ComputeCoverage(specFile, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"DELETE /orders/{orderId}": true,
+		"GET /pets":                true,
+		"POST /pets":               false,
+		"GET /pets/{petId}":        true,
+	}
+	if len(report.Operations) != len(want) {
+		t.Fatalf("expected %d operations, got %d", len(want), len(report.Operations))
+	}
+	for _, operation := range report.Operations {
+		if covered := operation.Covered(); covered != want[operation.Method+" "+operation.Path] {
+			t.Errorf("%s %s covered = %v, want %v", operation.Method, operation.Path, covered, !covered)
+		}
+	}
+	if report.Percentage() != 75 {
+		t.Errorf("expected 75%% coverage, got %v", report.Percentage())
+	}
+	if len(report.Unmatched) != 1 || report.Unmatched[0].Path != "/stores" {
+		t.Errorf("expected /stores to be unmatched, got: %v", report.Unmatched)
+	}
+}
+
+func TestListResources(t *testing.T) {
+	dir := t.TempDir()
+	config := `plugin: openapi
+specFile: petstore.yaml
+resources:
+  - method: GET
+    path: /pets
+    response:
+      statusCode: 200
+  - method: GET
+    path: /pets
+    requestHeaders:
+      X-Imposter-Status: "503"
+      X-Tenant:
+        value: acme
+      X-Trace:
+        operator: Exists
+    response:
+      statusCode: 503
+`
+	if err := os.WriteFile(filepath.Join(dir, "petstore-config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := ListResources(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 resources, got: %+v", resources)
+	}
+	if resources[0].StatusCode != 200 || resources[0].RequestHeaders != nil {
+		t.Errorf("unexpected first resource: %+v", resources[0])
+	}
+	want := map[string]string{"X-Imposter-Status": "503", "X-Tenant": "acme"}
+	if resources[1].StatusCode != 503 || !reflect.DeepEqual(resources[1].RequestHeaders, want) {
+		t.Errorf("unexpected second resource: %+v", resources[1])
+	}
+}
@@ -18,20 +18,36 @@ package config
 
 import (
 	"fmt"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/impostermodel"
 	"github.com/spf13/viper"
 	"os"
 	"path/filepath"
+	"sigs.k8s.io/yaml"
 	"strings"
 )
 
+// ConfigFileError describes a problem with a mock configuration file.
+type ConfigFileError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ConfigFileError) String() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
 func ValidateConfigExists(configDir string, scaffoldMissing bool) error {
 	fileInfo, err := os.Stat(configDir)
 	if err != nil {
-		return fmt.Errorf("cannot find config dir: %v", err)
+		return failure.New(failure.CodeConfigNotFound, "cannot find config dir: %v", err)
 	}
 	if !fileInfo.IsDir() {
-		return fmt.Errorf("path is not a directory: %v", configDir)
+		return failure.New(failure.CodeConfigNotFound, "path is not a directory: %v", configDir)
 	}
 
 	// check for IMPOSTER_CONFIG_SCAN_RECURSIVE
@@ -42,10 +58,10 @@ func ValidateConfigExists(configDir string, scaffoldMissing bool) error {
 
 	if scaffoldMissing {
 		logger.Infof("scaffolding Imposter configuration files")
-		impostermodel.Create(configDir, false, false, impostermodel.ScriptEngineNone, true)
+		impostermodel.Create(configDir, false, false, impostermodel.ResourceGenerationOptions{ScriptEngine: impostermodel.ScriptEngineNone}, true)
 		return nil
 	}
-	return fmt.Errorf(`No Imposter configuration files found in: %v
+	return failure.New(failure.CodeConfigNotFound, `No Imposter configuration files found in: %v
 Consider running 'imposter scaffold' first.`, configDir)
 }
 
@@ -69,6 +85,110 @@ func ContainsConfigFile(configDir string, recursive bool) bool {
 	return false
 }
 
+// configDirSearchDepth is the depth of subdirectories searched for
+// config files by SuggestConfigDirs.
+const configDirSearchDepth = 3
+
+// SuggestConfigDirs returns the directories matching the partially typed
+// path toComplete that contain config files, for shell completion.
+// Directories only containing config files in their subdirectories are
+// returned with a trailing separator, so that completion can continue
+// into them. Hidden directories are omitted, unless toComplete names one.
+func SuggestConfigDirs(toComplete string) []string {
+	dir, prefix := filepath.Split(toComplete)
+	searchDir := dir
+	if searchDir == "" {
+		searchDir = "."
+	}
+	entries, err := os.ReadDir(searchDir)
+	if err != nil {
+		return nil
+	}
+	var suggestions []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		candidate := filepath.Join(searchDir, entry.Name())
+		if containsConfigFileWithin(candidate, 0) {
+			suggestions = append(suggestions, dir+entry.Name())
+		} else if containsConfigFileWithin(candidate, configDirSearchDepth) {
+			suggestions = append(suggestions, dir+entry.Name()+string(filepath.Separator))
+		}
+	}
+	return suggestions
+}
+
+// containsConfigFileWithin determines if dir contains a config file,
+// or if one of its subdirectories does, up to the given depth. Hidden
+// subdirectories are not searched.
+func containsConfigFileWithin(dir string, depth int) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	var subdirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if !strings.HasPrefix(entry.Name(), ".") {
+				subdirs = append(subdirs, entry.Name())
+			}
+		} else if matchesConfigFileFmt(entry) {
+			return true
+		}
+	}
+	if depth > 0 {
+		for _, subdir := range subdirs {
+			if containsConfigFileWithin(filepath.Join(dir, subdir), depth-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateConfigFiles parses each config file in the specified configDir,
+// returning an error for each file that is not valid YAML or JSON, or
+// that does not specify a plugin.
+func ValidateConfigFiles(configDir string, recursive bool) []ConfigFileError {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		return []ConfigFileError{{File: configDir, Message: fmt.Sprintf("unable to list directory contents: %v", err)}}
+	}
+	var configErrors []ConfigFileError
+	for _, file := range files {
+		filePath := filepath.Join(configDir, file.Name())
+		if file.IsDir() {
+			if recursive {
+				configErrors = append(configErrors, ValidateConfigFiles(filePath, recursive)...)
+			}
+		} else if matchesConfigFileFmt(file) {
+			if err := validateConfigFile(filePath); err != nil {
+				configErrors = append(configErrors, ConfigFileError{File: filePath, Message: err.Error()})
+			}
+		}
+	}
+	return configErrors
+}
+
+func validateConfigFile(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("unable to read file: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return fmt.Errorf("invalid YAML or JSON: %v", err)
+	}
+	if plugin, ok := parsed["plugin"].(string); !ok || plugin == "" {
+		return fmt.Errorf("missing 'plugin' property")
+	}
+	return nil
+}
+
 func matchesConfigFileFmt(file os.DirEntry) bool {
 	for _, configFileSuffix := range getConfigFileSuffixes() {
 		if strings.HasSuffix(file.Name(), configFileSuffix) {
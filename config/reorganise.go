@@ -0,0 +1,354 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictStrategy determines how resources matching the same requests
+// are handled when config files are merged.
+type ConflictStrategy string
+
+const (
+	// ConflictStrategyError fails the merge.
+	ConflictStrategyError ConflictStrategy = "error"
+
+	// ConflictStrategyFirst keeps the resource from the first file.
+	ConflictStrategyFirst ConflictStrategy = "first"
+
+	// ConflictStrategyLast keeps the resource from the last file.
+	ConflictStrategyLast ConflictStrategy = "last"
+)
+
+// ParseConflictStrategy parses a strategy, which is 'error', 'first' or 'last'.
+func ParseConflictStrategy(strategy string) (ConflictStrategy, error) {
+	switch s := ConflictStrategy(strategy); s {
+	case ConflictStrategyError, ConflictStrategyFirst, ConflictStrategyLast:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported conflict strategy: %s (valid: error,first,last)", strategy)
+	}
+}
+
+// matchKeys are the properties of a resource that determine the requests
+// it matches, in addition to its method and path.
+var matchKeys = []string{"pathParams", "queryParams", "requestHeaders", "formParams", "requestBody", "allOf", "anyOf", "binding", "operation", "operationType", "soapAction", "channel"}
+
+// fileKeys are the properties of a config file that hold file paths,
+// which are relative to the config file.
+var fileKeys = []string{"specFile", "wsdlFile", "schemaFile", "protoFile", "descriptorSetFile"}
+
+// responseFileKeys are the properties of a response that hold file paths.
+var responseFileKeys = []string{"file", "staticFile", "scriptFile"}
+
+// MergeConfigFiles merges the config files into destFile. The properties
+// of the files, other than their resources and interceptors, must not
+// differ. Resources matching the same requests are resolved using the
+// strategy. Relative file paths are rewritten to be relative to destFile.
+// The merged files, other than destFile, are removed, as the engine would
+// otherwise load their resources twice.
+func MergeConfigFiles(files []string, destFile string, strategy ConflictStrategy) error {
+	if len(files) < 2 {
+		return fmt.Errorf("at least two config files are required to merge")
+	}
+	var merged yaml.MapSlice
+	var resources, interceptors []interface{}
+	resourceIndex := make(map[string]int)
+	resourceSources := make(map[string]string)
+
+	for _, file := range files {
+		parsed, err := loadConfig(file)
+		if err != nil {
+			return err
+		}
+		rebaseFilePaths(parsed, filepath.Dir(file), filepath.Dir(destFile))
+
+		for _, item := range parsed {
+			key := fmt.Sprintf("%v", item.Key)
+			switch key {
+			case "resources":
+				items, _ := item.Value.([]interface{})
+				for _, resource := range items {
+					resourceKey := buildMatchKey(resource)
+					if i, found := resourceIndex[resourceKey]; found {
+						switch strategy {
+						case ConflictStrategyFirst:
+							logger.Debugf("keeping resource %s from %s", resourceKey, resourceSources[resourceKey])
+						case ConflictStrategyLast:
+							logger.Debugf("replacing resource %s from %s with that from %s", resourceKey, resourceSources[resourceKey], file)
+							resources[i] = resource
+							resourceSources[resourceKey] = file
+						default:
+							return fmt.Errorf("resource %s in %s conflicts with that in %s", resourceKey, file, resourceSources[resourceKey])
+						}
+						continue
+					}
+					resourceIndex[resourceKey] = len(resources)
+					resourceSources[resourceKey] = file
+					resources = append(resources, resource)
+				}
+			case "interceptors":
+				items, _ := item.Value.([]interface{})
+				interceptors = append(interceptors, items...)
+			default:
+				existing, found := getItem(merged, key)
+				if !found {
+					merged = append(merged, item)
+				} else if canonicalise(existing) != canonicalise(item.Value) {
+					return fmt.Errorf("property '%s' in %s differs from that in other files", key, file)
+				}
+			}
+		}
+	}
+	if len(resources) > 0 {
+		merged = append(merged, yaml.MapItem{Key: "resources", Value: resources})
+	}
+	if len(interceptors) > 0 {
+		merged = append(merged, yaml.MapItem{Key: "interceptors", Value: interceptors})
+	}
+	if err := writeConfig(destFile, merged); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file == destFile {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("unable to remove merged file: %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// SplitConfigFile splits the config file into a file for each path of
+// its resources, returning the paths of the files written. Each file has
+// the other properties of the config file. Resources without a path are
+// written to a file with those with the root path, and interceptors to
+// the first file. The config file is removed once it has been split.
+func SplitConfigFile(file string) ([]string, error) {
+	parsed, err := loadConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	var common yaml.MapSlice
+	var resources, interceptors []interface{}
+	for _, item := range parsed {
+		switch fmt.Sprintf("%v", item.Key) {
+		case "resources":
+			resources, _ = item.Value.([]interface{})
+		case "interceptors":
+			interceptors, _ = item.Value.([]interface{})
+		default:
+			common = append(common, item)
+		}
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("no resources to split in: %s", file)
+	}
+
+	var paths []string
+	grouped := make(map[string][]interface{})
+	for _, resource := range resources {
+		path := "/"
+		if r, ok := resource.(yaml.MapSlice); ok {
+			if p, found := getItem(r, "path"); found && fmt.Sprintf("%v", p) != "" {
+				path = fmt.Sprintf("%v", p)
+			}
+		}
+		if _, found := grouped[path]; !found {
+			paths = append(paths, path)
+		}
+		grouped[path] = append(grouped[path], resource)
+	}
+
+	base, suffix := splitConfigFileName(file)
+	var written []string
+	for i, path := range paths {
+		destFile := base + "-" + buildPathFileName(path) + suffix
+		if _, err := os.Stat(destFile); err == nil {
+			return written, fmt.Errorf("unable to split %s: %s already exists", file, destFile)
+		}
+		config := append(yaml.MapSlice{}, common...)
+		config = append(config, yaml.MapItem{Key: "resources", Value: grouped[path]})
+		if i == 0 && len(interceptors) > 0 {
+			config = append(config, yaml.MapItem{Key: "interceptors", Value: interceptors})
+		}
+		if err := writeConfig(destFile, config); err != nil {
+			return written, err
+		}
+		written = append(written, destFile)
+	}
+	if err := os.Remove(file); err != nil {
+		return written, fmt.Errorf("unable to remove split file: %s: %v", file, err)
+	}
+	return written, nil
+}
+
+func loadConfig(file string) (yaml.MapSlice, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s: %v", file, err)
+	}
+	var parsed yaml.MapSlice
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON: %s: %v", file, err)
+	}
+	return parsed, nil
+}
+
+// writeConfig writes the config in the format indicated by the
+// extension of the file.
+func writeConfig(file string, config yaml.MapSlice) error {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(file) == ".json" {
+		if content, err = ConvertConfig(content, ConfigFormatJson); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		return fmt.Errorf("unable to write file: %s: %v", file, err)
+	}
+	logger.Debugf("wrote config file: %s", file)
+	return nil
+}
+
+// rebaseFilePaths rewrites the relative file paths in the config, which
+// are relative to srcDir, to be relative to destDir.
+func rebaseFilePaths(config yaml.MapSlice, srcDir string, destDir string) {
+	if filepath.Clean(srcDir) == filepath.Clean(destDir) {
+		return
+	}
+	rebase := func(m yaml.MapSlice, keys []string) {
+		for i, item := range m {
+			value, ok := item.Value.(string)
+			if !ok || value == "" || filepath.IsAbs(value) || !stringutil.Contains(keys, fmt.Sprintf("%v", item.Key)) {
+				continue
+			}
+			if rel, err := filepath.Rel(destDir, filepath.Join(srcDir, value)); err == nil {
+				m[i].Value = filepath.ToSlash(rel)
+			}
+		}
+	}
+	rebaseResponse := func(resource interface{}) {
+		if r, ok := resource.(yaml.MapSlice); ok {
+			if response, found := getItem(r, "response"); found {
+				if m, ok := response.(yaml.MapSlice); ok {
+					rebase(m, responseFileKeys)
+				}
+			}
+		}
+	}
+	rebase(config, fileKeys)
+	rebaseResponse(config)
+	for _, key := range []string{"resources", "interceptors"} {
+		items, _ := getItem(config, key)
+		list, _ := items.([]interface{})
+		for _, resource := range list {
+			rebaseResponse(resource)
+		}
+	}
+}
+
+// buildMatchKey returns a key identifying the requests the resource
+// matches, such as 'GET /pets [queryParams={"page":"1"}]'.
+func buildMatchKey(resource interface{}) string {
+	r, _ := resource.(yaml.MapSlice)
+	method := "*"
+	if m, found := getItem(r, "method"); found {
+		method = strings.ToUpper(fmt.Sprintf("%v", m))
+	}
+	path, _ := getItem(r, "path")
+	key := fmt.Sprintf("%s %v", method, path)
+	for _, matchKey := range matchKeys {
+		if value, found := getItem(r, matchKey); found {
+			key += fmt.Sprintf(" [%s=%s]", matchKey, canonicalise(value))
+		}
+	}
+	return key
+}
+
+// canonicalise returns a representation of the value in which the
+// properties of objects are ordered by name.
+func canonicalise(value interface{}) string {
+	encoded, err := json.Marshal(toPlain(value))
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+func toPlain(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		plain := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			plain[fmt.Sprintf("%v", item.Key)] = toPlain(item.Value)
+		}
+		return plain
+	case []interface{}:
+		plain := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			plain = append(plain, toPlain(item))
+		}
+		return plain
+	default:
+		return v
+	}
+}
+
+func getItem(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if fmt.Sprintf("%v", item.Key) == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// splitConfigFileName splits the name of a config file into the part
+// before the config file suffix, and the suffix, such as '-config.yaml'.
+func splitConfigFileName(file string) (string, string) {
+	for _, suffix := range getConfigFileSuffixes() {
+		if strings.HasSuffix(file, suffix) {
+			return strings.TrimSuffix(file, suffix), suffix
+		}
+	}
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext), "-config" + ext
+}
+
+// buildPathFileName returns the segments of the path, without any
+// template characters, joined by hyphens.
+func buildPathFileName(path string) string {
+	segments := strings.FieldsFunc(path, func(c rune) bool {
+		return !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_')
+	})
+	if len(segments) == 0 {
+		return "root"
+	}
+	return strings.Join(segments, "-")
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfigFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		strategy ConflictStrategy
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "merge resources",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  method: GET\n",
+				"b-config.yaml": "plugin: rest\nresources:\n- path: /orders\n  method: GET\n",
+			},
+			strategy: ConflictStrategyError,
+			want:     "plugin: rest\nresources:\n- path: /pets\n  method: GET\n- path: /orders\n  method: GET\n",
+		},
+		{
+			name: "conflict fails",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  method: GET\n",
+				"b-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  method: get\n",
+			},
+			strategy: ConflictStrategyError,
+			wantErr:  "resource GET /pets in",
+		},
+		{
+			name: "conflict keeps first",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: a\n",
+				"b-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: b\n",
+			},
+			strategy: ConflictStrategyFirst,
+			want:     "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: a\n",
+		},
+		{
+			name: "conflict keeps last",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: a\n",
+				"b-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: b\n",
+			},
+			strategy: ConflictStrategyLast,
+			want:     "plugin: rest\nresources:\n- path: /pets\n  response:\n    content: b\n",
+		},
+		{
+			name: "different matchers do not conflict",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  queryParams:\n    page: \"1\"\n",
+				"b-config.yaml": "plugin: rest\nresources:\n- path: /pets\n  queryParams:\n    page: \"2\"\n",
+			},
+			strategy: ConflictStrategyError,
+			want:     "plugin: rest\nresources:\n- path: /pets\n  queryParams:\n    page: \"1\"\n- path: /pets\n  queryParams:\n    page: \"2\"\n",
+		},
+		{
+			name: "differing properties fail",
+			files: map[string]string{
+				"a-config.yaml": "plugin: rest\nresources:\n- path: /pets\n",
+				"b-config.yaml": "plugin: soap\nresources:\n- path: /orders\n",
+			},
+			strategy: ConflictStrategyError,
+			wantErr:  "property 'plugin'",
+		},
+		{
+			name: "rebase file paths",
+			files: map[string]string{
+				"a-config.yaml":     "plugin: rest\nresources:\n- path: /pets\n  response:\n    file: pets.json\n",
+				"sub/b-config.yaml": "plugin: rest\nresources:\n- path: /orders\n  response:\n    file: orders.json\n",
+			},
+			strategy: ConflictStrategyError,
+			want:     "plugin: rest\nresources:\n- path: /pets\n  response:\n    file: pets.json\n- path: /orders\n  response:\n    file: sub/orders.json\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var files []string
+			for _, name := range []string{"a-config.yaml", "b-config.yaml", "sub/b-config.yaml"} {
+				content, ok := tt.files[name]
+				if !ok {
+					continue
+				}
+				file := filepath.Join(dir, name)
+				_ = os.MkdirAll(filepath.Dir(file), 0755)
+				if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, file)
+			}
+			destFile := filepath.Join(dir, "merged-config.yaml")
+
+			err := MergeConfigFiles(files, destFile, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("MergeConfigFiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeConfigFiles() error = %v", err)
+			}
+			got, _ := os.ReadFile(destFile)
+			if string(got) != tt.want {
+				t.Errorf("MergeConfigFiles() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			for _, file := range files {
+				if _, err := os.Stat(file); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed", file)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "petstore-config.yaml")
+	content := `plugin: openapi
+specFile: petstore.yaml
+resources:
+- path: /pets
+  method: GET
+- path: /pets/{petId}
+  method: GET
+- path: /pets
+  method: POST
+interceptors:
+- path: /
+  continue: true
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := SplitConfigFile(file)
+	if err != nil {
+		t.Fatalf("SplitConfigFile() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "petstore-pets-config.yaml"),
+		filepath.Join(dir, "petstore-pets-petId-config.yaml"),
+	}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("SplitConfigFile() = %v, want %v", written, want)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", file)
+	}
+
+	got, _ := os.ReadFile(want[0])
+	wantContent := `plugin: openapi
+specFile: petstore.yaml
+resources:
+- path: /pets
+  method: GET
+- path: /pets
+  method: POST
+interceptors:
+- path: /
+  continue: true
+`
+	if string(got) != wantContent {
+		t.Errorf("SplitConfigFile() got:\n%s\nwant:\n%s", got, wantContent)
+	}
+	got, _ = os.ReadFile(want[1])
+	wantContent = `plugin: openapi
+specFile: petstore.yaml
+resources:
+- path: /pets/{petId}
+  method: GET
+`
+	if string(got) != wantContent {
+		t.Errorf("SplitConfigFile() got:\n%s\nwant:\n%s", got, wantContent)
+	}
+}
+
+func Test_buildPathFileName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: "root"},
+		{path: "/pets", want: "pets"},
+		{path: "/pets/{petId}/tags", want: "pets-petId-tags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := buildPathFileName(tt.path); got != tt.want {
+				t.Errorf("buildPathFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetGlobalConfigFile returns the path of the CLI configuration file in
+// the global config dir. An existing '.yml' file is preferred, otherwise
+// the '.yaml' file is returned, whether or not it exists.
+func GetGlobalConfigFile() (string, error) {
+	globalConfigDir, err := GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	ymlFile := filepath.Join(globalConfigDir, GlobalConfigFileName+".yml")
+	if _, err := os.Stat(ymlFile); err == nil {
+		return ymlFile, nil
+	}
+	return filepath.Join(globalConfigDir, GlobalConfigFileName+".yaml"), nil
+}
+
+// GetLocalConfigFile returns the path of the CLI configuration file
+// for the directory.
+func GetLocalConfigFile(dir string) string {
+	return filepath.Join(dir, LocalDirConfigFileName+".yaml")
+}
+
+// GetConfigValue returns the value of the key in the CLI configuration
+// file, and whether it is set. Nested keys are separated by dots, such
+// as 'docker.registryMirror', and are matched ignoring case, as they are
+// when the configuration is loaded.
+func GetConfigValue(file string, key string) (interface{}, bool, error) {
+	settings, err := loadSettings(file)
+	if err != nil {
+		return nil, false, err
+	}
+	parts := strings.Split(key, ".")
+	current := settings
+	for i, part := range parts {
+		index := findSetting(current, part)
+		if index < 0 {
+			return nil, false, nil
+		}
+		value := current[index].Value
+		if i == len(parts)-1 {
+			return value, true, nil
+		}
+		if current, _ = value.(yaml.MapSlice); current == nil {
+			return nil, false, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// SetConfigValue sets the key in the CLI configuration file, creating
+// the file if it does not exist. The values 'true' and 'false' are
+// written as booleans, and all other values as strings.
+func SetConfigValue(file string, key string, value string) error {
+	settings, err := loadSettings(file)
+	if err != nil {
+		return err
+	}
+	var typed interface{} = value
+	if value == "true" || value == "false" {
+		typed = value == "true"
+	}
+	settings = setSetting(settings, strings.Split(key, "."), typed)
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %v: %v", filepath.Dir(file), err)
+	}
+	if err := writeConfig(file, settings); err != nil {
+		return fmt.Errorf("failed to write config file: %s: %v", file, err)
+	}
+	return nil
+}
+
+// UnsetConfigValue removes the key from the CLI configuration file,
+// returning false if it was not set. Sections left empty are removed.
+func UnsetConfigValue(file string, key string) (bool, error) {
+	settings, err := loadSettings(file)
+	if err != nil {
+		return false, err
+	}
+	settings, removed := unsetSetting(settings, strings.Split(key, "."))
+	if !removed {
+		return false, nil
+	}
+	if err := writeConfig(file, settings); err != nil {
+		return false, fmt.Errorf("failed to write config file: %s: %v", file, err)
+	}
+	return true, nil
+}
+
+// loadSettings returns the contents of the CLI configuration file, or
+// an empty configuration if it does not exist.
+func loadSettings(file string) (yaml.MapSlice, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return yaml.MapSlice{}, nil
+	}
+	return loadConfig(file)
+}
+
+func findSetting(settings yaml.MapSlice, key string) int {
+	for i, item := range settings {
+		if k, ok := item.Key.(string); ok && strings.EqualFold(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func setSetting(settings yaml.MapSlice, keyParts []string, value interface{}) yaml.MapSlice {
+	index := findSetting(settings, keyParts[0])
+	if len(keyParts) == 1 {
+		if index < 0 {
+			return append(settings, yaml.MapItem{Key: keyParts[0], Value: value})
+		}
+		settings[index].Value = value
+		return settings
+	}
+	if index < 0 {
+		settings = append(settings, yaml.MapItem{Key: keyParts[0]})
+		index = len(settings) - 1
+	}
+	section, _ := settings[index].Value.(yaml.MapSlice)
+	settings[index].Value = setSetting(section, keyParts[1:], value)
+	return settings
+}
+
+func unsetSetting(settings yaml.MapSlice, keyParts []string) (yaml.MapSlice, bool) {
+	index := findSetting(settings, keyParts[0])
+	if index < 0 {
+		return settings, false
+	}
+	if len(keyParts) > 1 {
+		section, ok := settings[index].Value.(yaml.MapSlice)
+		if !ok {
+			return settings, false
+		}
+		section, removed := unsetSetting(section, keyParts[1:])
+		if !removed {
+			return settings, false
+		}
+		if len(section) > 0 {
+			settings[index].Value = section
+			return settings, true
+		}
+	}
+	return append(settings[:index], settings[index+1:]...), true
+}
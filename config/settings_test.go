@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		key      string
+		value    string
+		want     string
+	}{
+		{
+			name:  "new file",
+			key:   "engine",
+			value: "jvm",
+			want:  "engine: jvm\n",
+		},
+		{
+			name:     "nested key in existing section",
+			existing: "engine: docker\ndocker:\n  readOnly: true\n",
+			key:      "docker.registryMirror",
+			value:    "mirror.example.com",
+			want:     "engine: docker\ndocker:\n  readOnly: true\n  registryMirror: mirror.example.com\n",
+		},
+		{
+			name:     "replace value ignoring case",
+			existing: "cli:\n  loglevel: debug\n",
+			key:      "cli.logLevel",
+			value:    "info",
+			want:     "cli:\n  loglevel: info\n",
+		},
+		{
+			name:  "boolean value",
+			key:   "daemon.delegate",
+			value: "true",
+			want:  "daemon:\n  delegate: true\n",
+		},
+		{
+			name:  "version is written as a string",
+			key:   "version",
+			value: "4.10",
+			want:  "version: \"4.10\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.existing != "" {
+				if err := os.WriteFile(file, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := SetConfigValue(file, tt.key, tt.value); err != nil {
+				t.Fatalf("SetConfigValue() error = %v", err)
+			}
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("SetConfigValue() wrote %q, want %q", got, tt.want)
+			}
+
+			value, found, err := GetConfigValue(file, tt.key)
+			if err != nil || !found {
+				t.Fatalf("GetConfigValue() found = %v, error = %v", found, err)
+			}
+			if want := tt.value; want != "true" && value != want {
+				t.Errorf("GetConfigValue() = %v, want %v", value, want)
+			}
+		})
+	}
+}
+
+func TestUnsetConfigValue(t *testing.T) {
+	const existing = "engine: docker\ndocker:\n  registryMirror: mirror.example.com\ncli:\n  logLevel: info\n  updateCheck: false\n"
+	tests := []struct {
+		name        string
+		key         string
+		wantRemoved bool
+		want        string
+	}{
+		{
+			name:        "remove empty section",
+			key:         "docker.registryMirror",
+			wantRemoved: true,
+			want:        "engine: docker\ncli:\n  logLevel: info\n  updateCheck: false\n",
+		},
+		{
+			name:        "keep non-empty section",
+			key:         "cli.logLevel",
+			wantRemoved: true,
+			want:        "engine: docker\ndocker:\n  registryMirror: mirror.example.com\ncli:\n  updateCheck: false\n",
+		},
+		{
+			name:        "not set",
+			key:         "version",
+			wantRemoved: false,
+			want:        existing,
+		},
+		{
+			name:        "not a section",
+			key:         "engine.type",
+			wantRemoved: false,
+			want:        existing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			removed, err := UnsetConfigValue(file, tt.key)
+			if err != nil {
+				t.Fatalf("UnsetConfigValue() error = %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("UnsetConfigValue() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("UnsetConfigValue() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// specMethods are the methods of an OpenAPI path item, in the order in
+// which they are written.
+var specMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type specSourceConfig struct {
+	specSourceResource `yaml:",inline"`
+
+	Plugin    string
+	BasePath  string `yaml:"basePath"`
+	Resources []specSourceResource
+}
+
+type specSourceResource struct {
+	Method         string
+	Path           string
+	QueryParams    map[string]interface{} `yaml:"queryParams"`
+	RequestHeaders map[string]interface{} `yaml:"requestHeaders"`
+	Response       *specSourceResponse
+}
+
+type specSourceResponse struct {
+	StatusCode int `yaml:"statusCode"`
+	File       string
+	StaticFile string `yaml:"staticFile"`
+	Content    string
+	StaticData string `yaml:"staticData"`
+	Headers    map[string]string
+}
+
+// specOperation collects the resources for a method and path.
+type specOperation struct {
+	method    string
+	path      string
+	resources []specSourceResource
+	dirs      []string
+}
+
+// GenerateOpenApiSpec generates an OpenAPI 3 document describing the
+// resources of the rest plugin config files in configDir. Each method
+// and path is an operation, with the path parameters, query parameters
+// and request headers matched by its resources, and their responses.
+// Response bodies, from files or inline content, are used as examples,
+// and schemas are inferred from JSON bodies.
+func GenerateOpenApiSpec(configDir string, recursive bool, title string) ([]byte, error) {
+	operations := make(map[string]*specOperation)
+	if err := collectSpecOperations(configDir, recursive, operations); err != nil {
+		return nil, err
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("no rest plugin resources found in: %s", configDir)
+	}
+
+	var paths []string
+	byPath := make(map[string][]*specOperation)
+	for _, operation := range operations {
+		if _, found := byPath[operation.path]; !found {
+			paths = append(paths, operation.path)
+		}
+		byPath[operation.path] = append(byPath[operation.path], operation)
+	}
+	sort.Strings(paths)
+
+	var pathItems yaml.MapSlice
+	for _, p := range paths {
+		var pathItem yaml.MapSlice
+		for _, method := range specMethods {
+			for _, operation := range byPath[p] {
+				if operation.method == method {
+					pathItem = append(pathItem, yaml.MapItem{Key: method, Value: buildSpecOperation(operation)})
+				}
+			}
+		}
+		pathItems = append(pathItems, yaml.MapItem{Key: p, Value: pathItem})
+	}
+
+	spec := yaml.MapSlice{
+		{Key: "openapi", Value: "3.0.3"},
+		{Key: "info", Value: yaml.MapSlice{
+			{Key: "title", Value: title},
+			{Key: "version", Value: "1.0.0"},
+		}},
+		{Key: "paths", Value: pathItems},
+	}
+	return yaml.Marshal(spec)
+}
+
+func collectSpecOperations(configDir string, recursive bool, operations map[string]*specOperation) error {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		return fmt.Errorf("unable to list directory contents: %s: %v", configDir, err)
+	}
+	for _, file := range files {
+		filePath := filepath.Join(configDir, file.Name())
+		if file.IsDir() {
+			if recursive {
+				if err := collectSpecOperations(filePath, recursive, operations); err != nil {
+					return err
+				}
+			}
+			continue
+		} else if !matchesConfigFileFmt(file) {
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("unable to read file: %s: %v", filePath, err)
+		}
+		var parsed specSourceConfig
+		if err := yaml.Unmarshal(content, &parsed); err != nil {
+			return fmt.Errorf("invalid YAML or JSON: %s: %v", filePath, err)
+		}
+		if parsed.Plugin != "rest" {
+			logger.Debugf("skipping config file for plugin %s: %s", parsed.Plugin, filePath)
+			continue
+		}
+		resources := parsed.Resources
+		if parsed.Path != "" {
+			resources = append([]specSourceResource{parsed.specSourceResource}, resources...)
+		}
+		for _, resource := range resources {
+			if resource.Path == "" {
+				continue
+			} else if strings.HasSuffix(resource.Path, "*") {
+				logger.Warnf("skipping resource with wildcard path %s in %s - wildcards cannot be described in OpenAPI", resource.Path, filePath)
+				continue
+			}
+			method := strings.ToLower(resource.Method)
+			if method == "" {
+				method = "get"
+			}
+			p := resource.Path
+			if parsed.BasePath != "" {
+				p = path.Join("/", parsed.BasePath, p)
+			}
+			key := method + " " + p
+			operation, found := operations[key]
+			if !found {
+				operation = &specOperation{method: method, path: p}
+				operations[key] = operation
+			}
+			operation.resources = append(operation.resources, resource)
+			operation.dirs = append(operation.dirs, configDir)
+		}
+	}
+	return nil
+}
+
+func buildSpecOperation(operation *specOperation) yaml.MapSlice {
+	result := yaml.MapSlice{
+		{Key: "summary", Value: strings.ToUpper(operation.method) + " " + operation.path},
+	}
+	if parameters := buildSpecParameters(operation); len(parameters) > 0 {
+		result = append(result, yaml.MapItem{Key: "parameters", Value: parameters})
+	}
+
+	var responses yaml.MapSlice
+	seen := make(map[string]bool)
+	for i, resource := range operation.resources {
+		statusCode := http.StatusOK
+		if resource.Response != nil && resource.Response.StatusCode != 0 {
+			statusCode = resource.Response.StatusCode
+		}
+		status := strconv.Itoa(statusCode)
+		if seen[status] {
+			logger.Debugf("skipping additional %s response for %s %s", status, operation.method, operation.path)
+			continue
+		}
+		seen[status] = true
+		responses = append(responses, yaml.MapItem{Key: status, Value: buildSpecResponse(statusCode, resource.Response, operation.dirs[i])})
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].Key.(string) < responses[j].Key.(string)
+	})
+	return append(result, yaml.MapItem{Key: "responses", Value: responses})
+}
+
+// buildSpecParameters returns the path parameters of the operation, and
+// the query parameters and request headers matched by its resources.
+// Parameters matched by every resource are required.
+func buildSpecParameters(operation *specOperation) []interface{} {
+	var parameters []interface{}
+	for _, segment := range strings.Split(operation.path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			parameters = append(parameters, yaml.MapSlice{
+				{Key: "name", Value: strings.Trim(segment, "{}")},
+				{Key: "in", Value: "path"},
+				{Key: "required", Value: true},
+				{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			})
+		}
+	}
+	for _, in := range []string{"query", "header"} {
+		var names []string
+		counts := make(map[string]int)
+		examples := make(map[string]string)
+		for _, resource := range operation.resources {
+			matchers := resource.QueryParams
+			if in == "header" {
+				matchers = resource.RequestHeaders
+			}
+			for name, matcher := range matchers {
+				if counts[name] == 0 {
+					names = append(names, name)
+				}
+				counts[name]++
+				if value, ok := matcher.(string); ok && examples[name] == "" {
+					examples[name] = value
+				}
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			parameter := yaml.MapSlice{
+				{Key: "name", Value: name},
+				{Key: "in", Value: in},
+				{Key: "required", Value: counts[name] == len(operation.resources)},
+				{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			}
+			if examples[name] != "" {
+				parameter = append(parameter, yaml.MapItem{Key: "example", Value: examples[name]})
+			}
+			parameters = append(parameters, parameter)
+		}
+	}
+	return parameters
+}
+
+// buildSpecResponse describes the response, using its body, if any, as
+// an example. Relative response files are resolved against configDir.
+func buildSpecResponse(statusCode int, response *specSourceResponse, configDir string) yaml.MapSlice {
+	description := http.StatusText(statusCode)
+	if description == "" {
+		description = "Response"
+	}
+	result := yaml.MapSlice{{Key: "description", Value: description}}
+	if response == nil {
+		return result
+	}
+
+	contentType := ""
+	var headers yaml.MapSlice
+	var headerNames []string
+	for name := range response.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = response.Headers[name]
+			continue
+		}
+		headers = append(headers, yaml.MapItem{Key: name, Value: yaml.MapSlice{
+			{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			{Key: "example", Value: response.Headers[name]},
+		}})
+	}
+	if len(headers) > 0 {
+		result = append(result, yaml.MapItem{Key: "headers", Value: headers})
+	}
+
+	var body []byte
+	responseFile := response.File
+	if responseFile == "" {
+		responseFile = response.StaticFile
+	}
+	if responseFile != "" {
+		if !filepath.IsAbs(responseFile) {
+			responseFile = filepath.Join(configDir, responseFile)
+		}
+		content, err := os.ReadFile(responseFile)
+		if err != nil {
+			logger.Warnf("unable to read response file: %s: %v", responseFile, err)
+			return result
+		}
+		body = content
+		if contentType == "" {
+			contentType = contentTypeForFile(responseFile)
+		}
+	} else if response.Content != "" || response.StaticData != "" {
+		body = []byte(response.Content + response.StaticData)
+		if contentType == "" {
+			contentType = "text/plain"
+			if json.Valid(body) {
+				contentType = "application/json"
+			}
+		}
+	} else {
+		return result
+	}
+
+	var mediaType yaml.MapSlice
+	baseType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if example, ok := parseJsonExample(body); ok && strings.HasSuffix(baseType, "json") {
+		mediaType = yaml.MapSlice{
+			{Key: "schema", Value: inferSchema(example)},
+			{Key: "example", Value: example},
+		}
+	} else if strings.HasPrefix(baseType, "text/") || strings.HasSuffix(baseType, "xml") {
+		mediaType = yaml.MapSlice{
+			{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			{Key: "example", Value: string(body)},
+		}
+	} else {
+		mediaType = yaml.MapSlice{
+			{Key: "schema", Value: yaml.MapSlice{{Key: "type", Value: "string"}, {Key: "format", Value: "binary"}}},
+		}
+	}
+	return append(result, yaml.MapItem{Key: "content", Value: yaml.MapSlice{{Key: baseType, Value: mediaType}}})
+}
+
+func contentTypeForFile(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "application/xml"
+	case ".html", ".htm":
+		return "text/html"
+	case ".txt":
+		return "text/plain"
+	case ".csv":
+		return "text/csv"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// parseJsonExample parses the JSON body, keeping the order of the
+// properties of objects.
+func parseJsonExample(body []byte) (interface{}, bool) {
+	if !json.Valid(body) {
+		return nil, false
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
+		var object yaml.MapSlice
+		if err := yaml.Unmarshal(body, &object); err == nil {
+			return object, true
+		}
+	}
+	var value interface{}
+	if err := yaml.Unmarshal(body, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// inferSchema returns a JSON schema describing the structure of the value.
+// The items of arrays are described by their first item.
+func inferSchema(value interface{}) yaml.MapSlice {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		schema := yaml.MapSlice{{Key: "type", Value: "object"}}
+		var properties yaml.MapSlice
+		for _, item := range v {
+			properties = append(properties, yaml.MapItem{Key: item.Key, Value: inferSchema(item.Value)})
+		}
+		if len(properties) > 0 {
+			schema = append(schema, yaml.MapItem{Key: "properties", Value: properties})
+		}
+		return schema
+	case map[interface{}]interface{}:
+		var keys []string
+		for key := range v {
+			keys = append(keys, fmt.Sprintf("%v", key))
+		}
+		sort.Strings(keys)
+		object := yaml.MapSlice{}
+		for _, key := range keys {
+			object = append(object, yaml.MapItem{Key: key, Value: v[key]})
+		}
+		return inferSchema(object)
+	case []interface{}:
+		items := yaml.MapSlice{}
+		if len(v) > 0 {
+			items = inferSchema(v[0])
+		}
+		return yaml.MapSlice{{Key: "type", Value: "array"}, {Key: "items", Value: items}}
+	case string:
+		return yaml.MapSlice{{Key: "type", Value: "string"}}
+	case int, int64, uint64:
+		return yaml.MapSlice{{Key: "type", Value: "integer"}}
+	case float64:
+		return yaml.MapSlice{{Key: "type", Value: "number"}}
+	case bool:
+		return yaml.MapSlice{{Key: "type", Value: "boolean"}}
+	default:
+		return yaml.MapSlice{{Key: "nullable", Value: true}}
+	}
+}
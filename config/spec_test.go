@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateOpenApiSpec(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pets-config.yaml": `plugin: rest
+basePath: /v1
+resources:
+- path: /pets
+  method: GET
+  queryParams:
+    page: "1"
+  response:
+    file: pets.json
+- path: /pets
+  method: GET
+  response:
+    statusCode: 400
+    content: bad request
+- path: /pets/{petId}
+  method: DELETE
+  requestHeaders:
+    Authorization:
+      operator: Exists
+  response:
+    statusCode: 204
+    headers:
+      X-Request-Id: abc
+- path: /files/*
+`,
+		"pets.json":          `[{"id": 1, "name": "Fluffy", "tags": {"indoor": true}, "weight": 1.5}]`,
+		"soap-config.yaml":   "plugin: soap\nwsdlFile: service.wsdl\n",
+		"nested/not-scanned": "",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := GenerateOpenApiSpec(dir, false, "Pets")
+	if err != nil {
+		t.Fatalf("GenerateOpenApiSpec() error = %v", err)
+	}
+	want := `openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /v1/pets:
+    get:
+      summary: GET /v1/pets
+      parameters:
+      - name: page
+        in: query
+        required: false
+        schema:
+          type: string
+        example: "1"
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    id:
+                      type: integer
+                    name:
+                      type: string
+                    tags:
+                      type: object
+                      properties:
+                        indoor:
+                          type: boolean
+                    weight:
+                      type: number
+              example:
+              - id: 1
+                name: Fluffy
+                tags:
+                  indoor: true
+                weight: 1.5
+        "400":
+          description: Bad Request
+          content:
+            text/plain:
+              schema:
+                type: string
+              example: bad request
+  /v1/pets/{petId}:
+    delete:
+      summary: DELETE /v1/pets/{petId}
+      parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+      - name: Authorization
+        in: header
+        required: true
+        schema:
+          type: string
+      responses:
+        "204":
+          description: No Content
+          headers:
+            X-Request-Id:
+              schema:
+                type: string
+              example: abc
+`
+	if string(got) != want {
+		t.Errorf("GenerateOpenApiSpec() got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerateOpenApiSpec_noResources(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "soap-config.yaml"), []byte("plugin: soap\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateOpenApiSpec(dir, false, "Test"); err == nil {
+		t.Error("expected error when there are no rest resources")
+	}
+}
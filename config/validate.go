@@ -0,0 +1,387 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The schema types below hold the properties of Imposter configuration
+// files. They are decoded strictly, so properties that are not part of
+// the schema are reported. Values whose form varies, such as matchers,
+// which are either a string or a matcher object, are checked separately.
+
+type configSchema struct {
+	resourceSchema `yaml:",inline"`
+
+	Plugin            string
+	BasePath          string `yaml:"basePath"`
+	SpecFile          string `yaml:"specFile"`
+	WsdlFile          string `yaml:"wsdlFile"`
+	SchemaFile        string `yaml:"schemaFile"`
+	ProtoFile         string `yaml:"protoFile"`
+	DescriptorSetFile string `yaml:"descriptorSetFile"`
+	StripServerPath   bool   `yaml:"stripServerPath"`
+	ContentType       string `yaml:"contentType"`
+	Resources         []resourceSchema
+	Interceptors      []resourceSchema
+	System            interface{}
+	Validation        interface{}
+	Cors              interface{}
+}
+
+type resourceSchema struct {
+	Path           string
+	Method         string
+	Binding        string
+	Operation      string
+	OperationType  string `yaml:"operationType"`
+	SoapAction     string `yaml:"soapAction"`
+	Channel        string
+	Continue       bool
+	PathParams     map[string]interface{} `yaml:"pathParams"`
+	QueryParams    map[string]interface{} `yaml:"queryParams"`
+	RequestHeaders map[string]interface{} `yaml:"requestHeaders"`
+	FormParams     map[string]interface{} `yaml:"formParams"`
+	RequestBody    *requestBodySchema     `yaml:"requestBody"`
+	AllOf          []expressionSchema     `yaml:"allOf"`
+	AnyOf          []expressionSchema     `yaml:"anyOf"`
+	Capture        map[string]captureSchema
+	Security       *securitySchema
+	Steps          []interface{}
+	Log            string
+	Response       *responseSchema
+}
+
+type requestBodySchema struct {
+	JsonPath      string      `yaml:"jsonPath"`
+	XPath         string      `yaml:"xPath"`
+	XmlNamespaces interface{} `yaml:"xmlNamespaces"`
+	Value         string
+	Operator      string
+	AllOf         []requestBodySchema `yaml:"allOf"`
+	AnyOf         []requestBodySchema `yaml:"anyOf"`
+}
+
+type expressionSchema struct {
+	Expression string
+	Value      string
+	Operator   string
+}
+
+type captureSchema struct {
+	Store         string
+	Key           *captureSchema
+	Enabled       *bool
+	Phase         string
+	PathParam     string             `yaml:"pathParam"`
+	QueryParam    string             `yaml:"queryParam"`
+	FormParam     string             `yaml:"formParam"`
+	RequestHeader string             `yaml:"requestHeader"`
+	RequestBody   *requestBodySchema `yaml:"requestBody"`
+	Expression    string
+	Const         string
+}
+
+type securitySchema struct {
+	Default    string
+	Conditions []securityConditionSchema
+}
+
+type securityConditionSchema struct {
+	Effect         string
+	QueryParams    map[string]interface{} `yaml:"queryParams"`
+	RequestHeaders map[string]interface{} `yaml:"requestHeaders"`
+	FormParams     map[string]interface{} `yaml:"formParams"`
+}
+
+type responseSchema struct {
+	StatusCode  int `yaml:"statusCode"`
+	File        string
+	StaticFile  string `yaml:"staticFile"`
+	Content     string
+	StaticData  string `yaml:"staticData"`
+	ExampleName string `yaml:"exampleName"`
+	ScriptFile  string `yaml:"scriptFile"`
+	Template    bool
+	Headers     map[string]string
+	Delay       *struct {
+		Exact int
+		Min   int
+		Max   int
+	}
+	Fail string
+}
+
+var validMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "TRACE", "CONNECT"}
+
+var validOperators = []string{"EqualTo", "NotEqualTo", "Exists", "NotExists", "Contains", "NotContains", "Matches", "NotMatches"}
+
+var validEffects = []string{"Permit", "Deny"}
+
+// strictErrorPattern matches the errors reported by strict decoding,
+// which are prefixed by the line number.
+var strictErrorPattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// LintConfigFiles validates each config file in the specified configDir
+// against the schema of Imposter configuration. In addition to the checks
+// made by ValidateConfigFiles, unknown properties, invalid values, invalid
+// matchers and references to files that do not exist are reported, with
+// the line at which they occur, where known.
+func LintConfigFiles(configDir string, recursive bool) []ConfigFileError {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		return []ConfigFileError{{File: configDir, Message: fmt.Sprintf("unable to list directory contents: %v", err)}}
+	}
+	var configErrors []ConfigFileError
+	for _, file := range files {
+		filePath := filepath.Join(configDir, file.Name())
+		if file.IsDir() {
+			if recursive {
+				configErrors = append(configErrors, LintConfigFiles(filePath, recursive)...)
+			}
+		} else if matchesConfigFileFmt(file) {
+			configErrors = append(configErrors, lintConfigFile(filePath)...)
+		}
+	}
+	return configErrors
+}
+
+func lintConfigFile(filePath string) []ConfigFileError {
+	if err := validateConfigFile(filePath); err != nil {
+		return []ConfigFileError{{File: filePath, Message: err.Error()}}
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return []ConfigFileError{{File: filePath, Message: fmt.Sprintf("unable to read file: %v", err)}}
+	}
+	l := &linter{filePath: filePath, lines: strings.Split(string(content), "\n")}
+
+	var schema configSchema
+	if err := yaml.UnmarshalStrict(content, &schema); err != nil {
+		l.addDecodeErrors(err)
+		return l.errors
+	}
+	l.lintResource("", schema.resourceSchema)
+	for i, resource := range schema.Resources {
+		l.lintResource(fmt.Sprintf("resources[%d]", i), resource)
+	}
+	for i, interceptor := range schema.Interceptors {
+		l.lintResource(fmt.Sprintf("interceptors[%d]", i), interceptor)
+	}
+	for _, specFile := range []string{schema.SpecFile, schema.WsdlFile, schema.SchemaFile, schema.ProtoFile, schema.DescriptorSetFile} {
+		l.checkFileExists("", specFile)
+	}
+	sort.SliceStable(l.errors, func(i, j int) bool {
+		return l.errors[i].Line < l.errors[j].Line
+	})
+	return l.errors
+}
+
+type linter struct {
+	filePath string
+	lines    []string
+	errors   []ConfigFileError
+}
+
+// addDecodeErrors adds an error for each problem reported by strict
+// decoding, such as an unknown property or a value of the wrong type.
+func (l *linter) addDecodeErrors(err error) {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		l.errors = append(l.errors, ConfigFileError{File: l.filePath, Message: err.Error()})
+		return
+	}
+	for _, message := range typeErr.Errors {
+		configErr := ConfigFileError{File: l.filePath, Message: message}
+		if match := strictErrorPattern.FindStringSubmatch(message); match != nil {
+			configErr.Line, _ = strconv.Atoi(match[1])
+			configErr.Message = match[2]
+		}
+		l.errors = append(l.errors, configErr)
+	}
+}
+
+func (l *linter) addError(context string, needle string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if context != "" {
+		message = context + ": " + message
+	}
+	l.errors = append(l.errors, ConfigFileError{File: l.filePath, Line: l.findLine(needle), Message: message})
+}
+
+// findLine returns the first line containing the needle, or 0 if it
+// is not found. Values are not decoded with their position, so this
+// locates the line on which a value is most likely declared.
+func (l *linter) findLine(needle string) int {
+	if needle == "" {
+		return 0
+	}
+	for i, line := range l.lines {
+		if strings.Contains(line, needle) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (l *linter) lintResource(context string, resource resourceSchema) {
+	if resource.Method != "" && !stringutil.Contains(validMethods, strings.ToUpper(resource.Method)) {
+		l.addError(context, resource.Method, "invalid method: %s", resource.Method)
+	}
+	if resource.Path != "" && !strings.HasPrefix(resource.Path, "/") {
+		l.addError(context, resource.Path, "invalid path: %s - must start with '/'", resource.Path)
+	}
+	for _, matchers := range []map[string]interface{}{resource.PathParams, resource.QueryParams, resource.RequestHeaders, resource.FormParams} {
+		l.lintMatchers(context, matchers)
+	}
+	if resource.RequestBody != nil {
+		l.lintRequestBody(context, *resource.RequestBody)
+	}
+	for _, expression := range append(resource.AllOf, resource.AnyOf...) {
+		if expression.Expression == "" {
+			l.addError(context, "expression", "missing expression")
+		}
+		l.lintOperator(context, expression.Operator, expression.Value)
+	}
+	for name, capture := range resource.Capture {
+		l.lintCapture(context, name, capture)
+	}
+	if resource.Security != nil {
+		l.lintSecurity(context, *resource.Security)
+	}
+	if resource.Response != nil {
+		l.lintResponse(context, *resource.Response)
+	}
+}
+
+// lintMatchers checks matchers, which are either a value to which the
+// request value must be equal, or a matcher object with an operator.
+func (l *linter) lintMatchers(context string, matchers map[string]interface{}) {
+	for name, matcher := range matchers {
+		switch m := matcher.(type) {
+		case string, int, float64, bool:
+			continue
+		case map[interface{}]interface{}:
+			for key := range m {
+				if k := fmt.Sprintf("%v", key); k != "value" && k != "operator" {
+					l.addError(context, k, "unknown property in matcher for %s: %s", name, k)
+				}
+			}
+			operator, _ := m["operator"].(string)
+			value, _ := m["value"].(string)
+			l.lintOperator(context, operator, value)
+		default:
+			l.addError(context, name, "invalid matcher for %s - expected a value or an object with 'value' and 'operator'", name)
+		}
+	}
+}
+
+func (l *linter) lintOperator(context string, operator string, value string) {
+	if operator == "" {
+		return
+	}
+	if !stringutil.Contains(validOperators, operator) {
+		l.addError(context, operator, "invalid operator: %s (valid: %s)", operator, strings.Join(validOperators, ","))
+		return
+	}
+	if operator == "Matches" || operator == "NotMatches" {
+		if _, err := regexp.Compile(value); err != nil {
+			l.addError(context, value, "invalid regular expression: %s: %v", value, err)
+		}
+	}
+}
+
+func (l *linter) lintRequestBody(context string, body requestBodySchema) {
+	if body.JsonPath != "" && body.XPath != "" {
+		l.addError(context, body.XPath, "request body matcher must not specify both jsonPath and xPath")
+	}
+	if body.JsonPath != "" && !strings.HasPrefix(body.JsonPath, "$") {
+		l.addError(context, body.JsonPath, "invalid JSON path: %s - must start with '$'", body.JsonPath)
+	}
+	l.lintOperator(context, body.Operator, body.Value)
+	for _, nested := range append(body.AllOf, body.AnyOf...) {
+		l.lintRequestBody(context, nested)
+	}
+}
+
+func (l *linter) lintCapture(context string, name string, capture captureSchema) {
+	if capture.Store == "" {
+		l.addError(context, name+":", "missing store for capture: %s", name)
+	}
+	if capture.Phase != "" && capture.Phase != "REQUEST_RECEIVED" && capture.Phase != "RESPONSE_SENT" {
+		l.addError(context, capture.Phase, "invalid phase for capture %s: %s (valid: REQUEST_RECEIVED,RESPONSE_SENT)", name, capture.Phase)
+	}
+	if capture.RequestBody != nil {
+		l.lintRequestBody(context, *capture.RequestBody)
+	}
+}
+
+func (l *linter) lintSecurity(context string, security securitySchema) {
+	if !stringutil.Contains(validEffects, security.Default) {
+		l.addError(context, "default:", "invalid default security effect: '%s' (valid: %s)", security.Default, strings.Join(validEffects, ","))
+	}
+	for _, condition := range security.Conditions {
+		if !stringutil.Contains(validEffects, condition.Effect) {
+			l.addError(context, "effect:", "invalid security condition effect: '%s' (valid: %s)", condition.Effect, strings.Join(validEffects, ","))
+		}
+		for _, matchers := range []map[string]interface{}{condition.QueryParams, condition.RequestHeaders, condition.FormParams} {
+			l.lintMatchers(context, matchers)
+		}
+	}
+}
+
+func (l *linter) lintResponse(context string, response responseSchema) {
+	if response.StatusCode != 0 && (response.StatusCode < 100 || response.StatusCode > 599) {
+		l.addError(context, strconv.Itoa(response.StatusCode), "invalid status code: %d", response.StatusCode)
+	}
+	if response.File != "" && response.StaticFile != "" {
+		l.addError(context, response.StaticFile, "response must not specify both file and staticFile")
+	}
+	if (response.File != "" || response.StaticFile != "") && (response.Content != "" || response.StaticData != "") {
+		l.addError(context, "content", "response must not specify both a file and content")
+	}
+	l.checkFileExists(context, response.File)
+	l.checkFileExists(context, response.StaticFile)
+	l.checkFileExists(context, response.ScriptFile)
+	if response.Delay != nil && response.Delay.Max < response.Delay.Min {
+		l.addError(context, "max:", "invalid delay: max %d is less than min %d", response.Delay.Max, response.Delay.Min)
+	}
+}
+
+// checkFileExists reports a file that does not exist. Paths are
+// relative to the directory containing the config file.
+func (l *linter) checkFileExists(context string, file string) {
+	if file == "" {
+		return
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(l.filePath), file)
+	}
+	if _, err := os.Stat(path); err != nil {
+		l.addError(context, file, "file not found: %s", file)
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintConfigFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		files  []string
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: `plugin: rest
+resources:
+  - method: GET
+    path: /pets
+    queryParams:
+      page:
+        value: "[0-9]+"
+        operator: Matches
+    capture:
+      petId:
+        store: pets
+        pathParam: id
+    response:
+      statusCode: 200
+      file: pets.json
+      scriptFile: pets.js
+`,
+			files: []string{"pets.json", "pets.js"},
+		},
+		{
+			name: "unknown property",
+			config: `plugin: rest
+resources:
+  - method: GET
+    path: /pets
+    response:
+      statusCode: 200
+      contents: hello
+`,
+			want: []string{"test-config.yaml:7: field contents not found"},
+		},
+		{
+			name: "invalid values",
+			config: `plugin: rest
+resources:
+  - method: FETCH
+    path: pets
+    requestHeaders:
+      X-Version:
+        value: "("
+        operator: Matches
+    response:
+      statusCode: 999
+      file: missing.json
+`,
+			want: []string{
+				"test-config.yaml:3: resources[0]: invalid method: FETCH",
+				"test-config.yaml:4: resources[0]: invalid path: pets",
+				`test-config.yaml:7: resources[0]: invalid regular expression: (`,
+				"test-config.yaml:10: resources[0]: invalid status code: 999",
+				"test-config.yaml:11: resources[0]: file not found: missing.json",
+			},
+		},
+		{
+			name: "invalid security",
+			config: `plugin: rest
+security:
+  default: Allow
+  conditions:
+    - effect: Permit
+      requestHeaders:
+        Authorization:
+          operator: Present
+`,
+			want: []string{
+				"test-config.yaml:3: invalid default security effect: 'Allow'",
+				"test-config.yaml:8: invalid operator: Present",
+			},
+		},
+		{
+			name:   "missing plugin",
+			config: "resources: []\n",
+			want:   []string{"test-config.yaml: missing 'plugin' property"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "test-config.yaml"), []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, file), []byte{}, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			configErrors := LintConfigFiles(dir, false)
+			if len(configErrors) != len(tt.want) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.want), len(configErrors), configErrors)
+			}
+			for i, want := range tt.want {
+				if got := strings.TrimPrefix(configErrors[i].String(), dir+string(filepath.Separator)); !strings.HasPrefix(got, want) {
+					t.Errorf("error %d = %q, want prefix %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestConfigDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"mocks/petstore", "mocks/orders", "nested/deep/api", "empty", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"mocks/petstore/petstore-config.yaml", "mocks/orders/orders-config.json", "nested/deep/api/api-config.yaml", ".hidden/hidden-config.yaml"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("plugin: rest\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		name       string
+		toComplete string
+		want       []string
+	}{
+		{name: "top level", toComplete: root + sep, want: []string{root + sep + "mocks" + sep, root + sep + "nested" + sep}},
+		{name: "config dirs", toComplete: filepath.Join(root, "mocks") + sep, want: []string{filepath.Join(root, "mocks", "orders"), filepath.Join(root, "mocks", "petstore")}},
+		{name: "prefix", toComplete: filepath.Join(root, "mocks", "pet"), want: []string{filepath.Join(root, "mocks", "petstore")}},
+		{name: "hidden by name", toComplete: filepath.Join(root, ".h"), want: []string{filepath.Join(root, ".hidden")}},
+		{name: "no match", toComplete: filepath.Join(root, "missing", "x"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuggestConfigDirs(tt.toComplete)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("SuggestConfigDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/remote/client"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrNotRunning is returned by Connect if no daemon is running.
+var ErrNotRunning = fmt.Errorf("no daemon is running - start one with 'imposter daemon'")
+
+// Client calls the control API of a running daemon.
+type Client struct {
+	api *client.Client
+}
+
+func NewClient(baseUrl string, token string) *Client {
+	return &Client{api: client.New(baseUrl, token)}
+}
+
+// Connect returns a client for the daemon described by the state file,
+// checking that it responds. If no daemon is running, ErrNotRunning
+// is returned.
+func Connect() (*Client, error) {
+	state, err := ReadState()
+	if err != nil {
+		return nil, err
+	} else if state == nil {
+		return nil, ErrNotRunning
+	}
+	c := NewClient("http://"+net.JoinHostPort(DefaultHost, strconv.Itoa(state.Port)), state.Token)
+	if err := c.api.Request(http.MethodGet, "/v1/status", nil); err != nil {
+		// the state file outlives a daemon that was killed
+		logger.Debugf("daemon state file exists but daemon did not respond: %v", err)
+		return nil, ErrNotRunning
+	}
+	return c, nil
+}
+
+func (c *Client) ListMocks() ([]MockStatus, error) {
+	var mocks []MockStatus
+	if err := c.api.Request(http.MethodGet, "/v1/mocks", &mocks); err != nil {
+		return nil, err
+	}
+	return mocks, nil
+}
+
+func (c *Client) GetMock(id string) (*MockStatus, error) {
+	var mock MockStatus
+	if err := c.api.Request(http.MethodGet, "/v1/mocks/"+url.PathEscape(id), &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// StartMock asks the daemon to start a mock, returning as soon as it
+// is starting. See WaitForMock.
+func (c *Client) StartMock(req StartMockRequest) (*MockStatus, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall start mock request: %v", err)
+	}
+	var mock MockStatus
+	if err := c.api.Upload(http.MethodPost, "/v1/mocks", "application/json", body, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+func (c *Client) StopMock(id string) error {
+	return c.api.Request(http.MethodDelete, "/v1/mocks/"+url.PathEscape(id), nil)
+}
+
+// WaitForMock polls the mock until it is no longer starting, returning
+// an error if it failed to start, or the timeout elapses.
+func (c *Client) WaitForMock(id string, interval time.Duration, timeout time.Duration) (*MockStatus, error) {
+	var mock *MockStatus
+	err := client.Poll(interval, timeout, func() (bool, error) {
+		var err error
+		if mock, err = c.GetMock(id); err != nil {
+			return false, err
+		}
+		return mock.State != MockStateStarting, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for mock %s to start: %v", id, err)
+	}
+	if mock.State != MockStateRunning {
+		if mock.Error != "" {
+			return mock, fmt.Errorf("mock %s failed to start - state: %s: %s", id, mock.State, mock.Error)
+		}
+		return mock, fmt.Errorf("mock %s failed to start - state: %s", id, mock.State)
+	}
+	return mock, nil
+}
+
+// FollowLogs copies the logs of the mock to w, streaming new lines
+// until the daemon closes the connection.
+func (c *Client) FollowLogs(id string, w io.Writer) error {
+	return c.api.Stream(http.MethodGet, "/v1/mocks/"+url.PathEscape(id)+"/logs?follow=true", w)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/logging"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const DefaultPort = 8180
+const DefaultHost = "127.0.0.1"
+const stateFileName = "daemon.json"
+
+var logger = logging.GetLogger()
+
+// State describes a running daemon, so that clients can discover
+// its address and token.
+type State struct {
+	Pid   int    `json:"pid"`
+	Port  int    `json:"port"`
+	Token string `json:"token"`
+}
+
+type Options struct {
+	Host  string
+	Port  int
+	Token string
+
+	// WorkspacesDir is where deployed workspaces are extracted.
+	// If empty, a directory under the CLI configuration directory is used.
+	WorkspacesDir string
+}
+
+type Daemon struct {
+	host          string
+	port          int
+	token         string
+	stateFile     string
+	workspacesDir string
+	mu            sync.Mutex
+	mocks         map[string]*mockInstance
+	recordings    map[string]*recordingInstance
+	workspaces    map[string]*deployedWorkspace
+	events        *eventBroker
+
+	// workspaceLocks serialise deploying and undeploying each workspace,
+	// which are slow, so are not done while holding the daemon lock.
+	workspaceLocks map[string]*sync.Mutex
+}
+
+// New creates a daemon. If no token is provided, a random one is generated.
+func New(options Options) (*Daemon, error) {
+	token := options.Token
+	if token == "" {
+		generated, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		token = generated
+	}
+	host := options.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	workspacesDir := options.WorkspacesDir
+	if workspacesDir == "" {
+		configDir, err := config.GetGlobalConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		workspacesDir = filepath.Join(configDir, "daemon-workspaces")
+	}
+	return &Daemon{
+		host:          host,
+		port:          options.Port,
+		token:         token,
+		workspacesDir: workspacesDir,
+		mocks:         make(map[string]*mockInstance),
+		recordings:    make(map[string]*recordingInstance),
+		workspaces:    make(map[string]*deployedWorkspace),
+		events:        newEventBroker(),
+
+		workspaceLocks: make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// ListenAndServe runs the control API on the configured host, blocking
+// until the server exits. The daemon port and token are written to a state
+// file readable only by the current user, so that clients can discover them.
+func (d *Daemon) ListenAndServe() error {
+	stateFile, err := writeState(State{Pid: os.Getpid(), Port: d.port, Token: d.token})
+	if err != nil {
+		return err
+	}
+	d.stateFile = stateFile
+
+	logger.Infof("starting daemon control API on http://%s", net.JoinHostPort(d.host, strconv.Itoa(d.port)))
+	logger.Infof("daemon token written to: %s", stateFile)
+	if ip := net.ParseIP(d.host); ip == nil || !ip.IsLoopback() {
+		logger.Warnf("control API is reachable from other hosts - keep the token secret")
+	}
+
+	server := &http.Server{
+		Addr:    net.JoinHostPort(d.host, strconv.Itoa(d.port)),
+		Handler: d.buildMux(),
+	}
+	return server.ListenAndServe()
+}
+
+// Shutdown stops all mocks and recordings managed by the daemon,
+// and removes the state file.
+func (d *Daemon) Shutdown() {
+	d.mu.Lock()
+	var mockIds []string
+	for id := range d.mocks {
+		mockIds = append(mockIds, id)
+	}
+	d.mu.Unlock()
+	for _, id := range mockIds {
+		if _, err := d.stopMock(id); err != nil {
+			logger.Warn(err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id := range d.recordings {
+		d.stopRecording(id)
+	}
+	if d.stateFile != "" {
+		_ = os.Remove(d.stateFile)
+	}
+}
+
+func (d *Daemon) buildMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", d.handleStatus)
+	mux.HandleFunc("GET /v1/mocks", d.handleListMocks)
+	mux.HandleFunc("POST /v1/mocks", d.handleStartMock)
+	mux.HandleFunc("GET /v1/mocks/{id}", d.handleGetMock)
+	mux.HandleFunc("DELETE /v1/mocks/{id}", d.handleStopMock)
+	mux.HandleFunc("POST /v1/mocks/{id}/restart", d.handleRestartMock)
+	mux.HandleFunc("GET /v1/mocks/{id}/logs", d.handleMockLogs)
+	mux.HandleFunc("GET /v1/events", d.handleEvents)
+	mux.HandleFunc("GET /v1/recordings", d.handleListRecordings)
+	mux.HandleFunc("POST /v1/recordings", d.handleStartRecording)
+	mux.HandleFunc("DELETE /v1/recordings/{id}", d.handleStopRecording)
+	mux.HandleFunc("PUT /v1/workspaces/{name}", d.handleDeployWorkspace)
+	mux.HandleFunc("GET /v1/workspaces/{name}", d.handleGetWorkspace)
+	mux.HandleFunc("DELETE /v1/workspaces/{name}", d.handleUndeployWorkspace)
+	mux.HandleFunc("GET /v1/workspaces/{name}/logs", d.handleWorkspaceLogs)
+	return d.authenticate(mux)
+}
+
+// authenticate rejects requests that do not present the daemon token
+// as a bearer token.
+func (d *Daemon) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(d.token)) != 1 {
+			logger.Warnf("rejected unauthenticated request %s %v from %v", r.Method, r.URL, r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (d *Daemon) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"version":    config.Config.Version,
+		"mocks":      len(d.mocks),
+		"recordings": len(d.recordings),
+	})
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate daemon token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func getStateFilePath() (string, error) {
+	configDir, err := config.GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, stateFileName), nil
+}
+
+func writeState(state State) (string, error) {
+	stateFile, err := getStateFilePath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory for daemon state: %s: %v", stateFile, err)
+	}
+	j, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshall daemon state: %v", err)
+	}
+	if err := os.WriteFile(stateFile, j, 0600); err != nil {
+		return "", fmt.Errorf("failed to write daemon state: %s: %v", stateFile, err)
+	}
+	return stateFile, nil
+}
+
+// ReadState returns the state of the running daemon, if any.
+func ReadState() (*State, error) {
+	stateFile, err := getStateFilePath()
+	if err != nil {
+		return nil, err
+	}
+	j, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read daemon state: %s: %v", stateFile, err)
+	}
+	var state State
+	if err := json.Unmarshal(j, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon state: %s: %v", stateFile, err)
+	}
+	return &state, nil
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warnf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJson(w, statusCode, map[string]string{"error": err.Error()})
+}
+
+func readJson(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
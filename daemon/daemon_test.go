@@ -0,0 +1,568 @@
+package daemon
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDaemon_authenticate(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := d.buildMux()
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "missing token", authorization: "", want: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer secret", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status code = %v, want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_generatesToken(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.token) != 48 {
+		t.Errorf("generated token length = %v, want 48", len(d.token))
+	}
+}
+
+func TestDaemon_startMockWithInvalidConfig(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "test-config.yaml"), []byte("resources: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"configDir": %q}`, configDir)
+	req := httptest.NewRequest(http.MethodPost, "/v1/mocks", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	d.buildMux().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status code = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	select {
+	case event := <-events:
+		if event.Type != EventConfigInvalid {
+			t.Errorf("event type = %v, want %v", event.Type, EventConfigInvalid)
+		}
+		if len(event.Errors) != 1 {
+			t.Errorf("event errors = %v, want 1 error", event.Errors)
+		}
+	default:
+		t.Errorf("expected config-invalid event to be published")
+	}
+}
+
+func TestDaemon_deployWorkspace(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret", WorkspacesDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	f, _ := zw.Create("test-config.yaml")
+	_, _ = f.Write([]byte("resources: []\n"))
+	_ = zw.Close()
+
+	tests := []struct {
+		name          string
+		workspace     string
+		query         string
+		body          []byte
+		want          int
+		wantExtracted bool
+	}{
+		{name: "invalid name", workspace: "-test", body: buf.Bytes(), want: http.StatusBadRequest},
+		{name: "invalid port", workspace: "test", query: "?port=abc", body: buf.Bytes(), want: http.StatusBadRequest},
+		{name: "invalid bundle", workspace: "test", body: []byte("not a zip"), want: http.StatusBadRequest},
+		{name: "invalid config", workspace: "test", body: buf.Bytes(), want: http.StatusBadRequest, wantExtracted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/workspaces/"+tt.workspace+tt.query, bytes.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			d.buildMux().ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status code = %v, want %v: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			_, err := os.Stat(filepath.Join(d.workspacesDir, tt.workspace, "test-config.yaml"))
+			if extracted := err == nil; extracted != tt.wantExtracted {
+				t.Errorf("extracted = %v, want %v", extracted, tt.wantExtracted)
+			}
+			if len(d.workspaces) != 0 {
+				t.Errorf("deployed workspaces = %v, want none", len(d.workspaces))
+			}
+		})
+	}
+}
+
+func TestDaemon_getMissingWorkspace(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret", WorkspacesDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/v1/workspaces/missing", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		d.buildMux().ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s status code = %v, want %v", method, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestDaemon_mockLogs(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret", WorkspacesDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := newLogBuffer()
+	d.mocks["abc"] = &mockInstance{status: MockStatus{ID: "abc"}, logs: logs}
+	_, _ = logs.Write([]byte("first\nsecond\npartial"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/mocks/abc/logs", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	d.buildMux().ServeHTTP(rec, req)
+	if got := rec.Body.String(); got != "first\nsecond\n" {
+		t.Errorf("logs = %q, want %q", got, "first\nsecond\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req = httptest.NewRequest(http.MethodGet, "/v1/mocks/abc/logs?follow=true&since=1h", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	done := make(chan bool)
+	go func() {
+		d.buildMux().ServeHTTP(rec, req)
+		done <- true
+	}()
+	for {
+		logs.mu.Lock()
+		subscribed := len(logs.subscribers) > 0
+		logs.mu.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = logs.Write([]byte(" line\n"))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+	if got := rec.Body.String(); got != "first\nsecond\npartial line\n" {
+		t.Errorf("followed logs = %q, want %q", got, "first\nsecond\npartial line\n")
+	}
+}
+
+func Test_writeSseEvent(t *testing.T) {
+	event := Event{
+		Type:      EventReady,
+		MockID:    "abc",
+		ConfigDir: "/tmp/config",
+		Port:      8080,
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := writeSseEvent(&buf, event); err != nil {
+		t.Fatal(err)
+	}
+	want := `event: ready
+data: {"jsonrpc":"2.0","method":"mock/stateChanged","params":{"type":"ready","mockId":"abc","configDir":"/tmp/config","port":8080,"time":"2024-01-02T03:04:05Z"}}
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeSseEvent() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseEnvironmentHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", values: nil, want: nil},
+		{name: "encoded pairs", values: []string{"QVBJX1RPS0VOPWE9Yg==", "RU1QVFk9"}, want: []string{"API_TOKEN=a=b", "EMPTY="}},
+		{name: "not base64", values: []string{"API_TOKEN=abc"}, wantErr: true},
+		{name: "missing separator", values: []string{"QVBJX1RPS0VO"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvironmentHeaders(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnvironmentHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseEnvironmentHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	d, err := New(Options{Port: DefaultPort, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(d.buildMux())
+	defer server.Close()
+	c := NewClient(server.URL, "secret")
+
+	mocks, err := c.ListMocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mocks) != 0 {
+		t.Errorf("mocks = %v, want none", mocks)
+	}
+
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "test-config.yaml"), []byte("resources: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.StartMock(StartMockRequest{ConfigDir: configDir}); err == nil || !strings.Contains(err.Error(), "invalid configuration") {
+		t.Errorf("start mock error = %v, want invalid configuration", err)
+	}
+
+	d.mocks["running"] = &mockInstance{status: MockStatus{ID: "running", State: MockStateRunning}}
+	d.mocks["failed"] = &mockInstance{status: MockStatus{ID: "failed", State: MockStateFailed}}
+	if mock, err := c.WaitForMock("running", time.Millisecond, time.Second); err != nil || mock.ID != "running" {
+		t.Errorf("wait for running mock = %v, %v", mock, err)
+	}
+	if _, err := c.WaitForMock("failed", time.Millisecond, time.Second); err == nil {
+		t.Errorf("expected error waiting for failed mock")
+	}
+	if err := c.StopMock("missing"); err == nil || !strings.Contains(err.Error(), "no such mock") {
+		t.Errorf("stop mock error = %v, want no such mock", err)
+	}
+}
+
+func TestDaemon_mockLifecycle(t *testing.T) {
+	tests := []struct {
+		name          string
+		fake          *fakeEngine
+		wantState     MockState
+		wantError     string
+		wantStopCode  int
+		wantRemaining int
+	}{
+		{name: "start and stop", fake: &fakeEngine{}, wantState: MockStateRunning, wantStopCode: http.StatusOK},
+		{name: "start fails", fake: &fakeEngine{startErr: errors.New("port in use")}, wantState: MockStateFailed, wantError: "port in use", wantStopCode: http.StatusOK},
+		{name: "stop fails", fake: &fakeEngine{stopErr: errors.New("container busy")}, wantState: MockStateRunning, wantStopCode: http.StatusInternalServerError, wantRemaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeEngineDaemon(t, tt.fake)
+			server := httptest.NewServer(d.buildMux())
+			defer server.Close()
+			c := NewClient(server.URL, "secret")
+
+			started, err := c.StartMock(StartMockRequest{ConfigDir: writeValidConfig(t), EngineType: string(engine.EngineTypeJvmUnpacked)})
+			if err != nil {
+				t.Fatalf("start mock error = %v", err)
+			}
+			mock, err := c.WaitForMock(started.ID, time.Millisecond, time.Second)
+			if mock == nil || mock.State != tt.wantState || !strings.Contains(mock.Error, tt.wantError) {
+				t.Fatalf("wait for mock = %+v, %v, want state %v and error %q", mock, err, tt.wantState, tt.wantError)
+			}
+
+			req, _ := http.NewRequest(http.MethodDelete, server.URL+"/v1/mocks/"+started.ID, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStopCode {
+				t.Errorf("stop status code = %v, want %v", resp.StatusCode, tt.wantStopCode)
+			}
+			if len(d.mocks) != tt.wantRemaining {
+				t.Errorf("remaining mocks = %v, want %v", len(d.mocks), tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestDaemon_restartMock(t *testing.T) {
+	fake := &fakeEngine{}
+	d := newFakeEngineDaemon(t, fake)
+	server := httptest.NewServer(d.buildMux())
+	defer server.Close()
+	c := NewClient(server.URL, "secret")
+
+	started, err := c.StartMock(StartMockRequest{ConfigDir: writeValidConfig(t), EngineType: string(engine.EngineTypeJvmUnpacked)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WaitForMock(started.ID, time.Millisecond, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	fake.setStartErr(errors.New("image not found"))
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/mocks/"+started.ID+"/restart", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("restart status code = %v, want %v", resp.StatusCode, http.StatusAccepted)
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != EventCrashed || !strings.Contains(event.Message, "image not found") {
+			t.Errorf("event = %+v, want crashed with start error", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected crashed event after failed restart")
+	}
+	if mock, err := c.GetMock(started.ID); err != nil || mock.State != MockStateFailed {
+		t.Errorf("mock after failed restart = %+v, %v, want failed", mock, err)
+	}
+}
+
+func TestDaemon_stopMockReleasesLock(t *testing.T) {
+	fake := &fakeEngine{stopC: make(chan struct{})}
+	d := newFakeEngineDaemon(t, fake)
+	handler := d.buildMux()
+
+	mock, err := d.startMock(writeValidConfig(t), StartMockRequest{Port: 8080, EngineType: string(engine.EngineTypeJvmUnpacked)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan error)
+	go func() {
+		_, err := d.stopMock(mock.status.ID)
+		stopped <- err
+	}()
+	<-fake.stoppingC
+
+	// the daemon responds while the engine is stopping
+	req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	close(fake.stopC)
+	if err := <-stopped; err != nil {
+		t.Errorf("stopMock() error = %v", err)
+	}
+}
+
+// newFakeEngineDaemon returns a daemon, registering a library for the
+// unpacked engine type, which builds the fake engine.
+func newFakeEngineDaemon(t *testing.T, fake *fakeEngine) *Daemon {
+	engine.RegisterLibrary(engine.EngineTypeJvmUnpacked, func() engine.EngineLibrary {
+		return fakeLibrary{fake: fake}
+	})
+	fake.stoppingC = make(chan struct{}, 1)
+	d, err := New(Options{Port: DefaultPort, Token: "secret", WorkspacesDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func writeValidConfig(t *testing.T) string {
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "test-config.yaml"), []byte("plugin: rest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return configDir
+}
+
+type fakeLibrary struct {
+	fake *fakeEngine
+}
+
+func (fakeLibrary) CheckPrereqs() (bool, []string)         { return true, nil }
+func (fakeLibrary) List() ([]engine.EngineMetadata, error) { return nil, nil }
+func (l fakeLibrary) GetProvider(string) engine.Provider   { return fakeProvider(l) }
+func (fakeLibrary) IsSealedDistro() bool                   { return true }
+func (fakeLibrary) ShouldEnsurePlugins() bool              { return false }
+func (fakeLibrary) Remove(string) (int64, error)           { return 0, nil }
+
+type fakeProvider struct {
+	fake *fakeEngine
+}
+
+func (fakeProvider) Satisfied() bool                  { return true }
+func (fakeProvider) Provide(engine.PullPolicy) error  { return nil }
+func (fakeProvider) GetEngineType() engine.EngineType { return engine.EngineTypeJvmUnpacked }
+func (fakeProvider) Bundle(string, string) error      { return nil }
+
+func (p fakeProvider) Build(string, engine.StartOptions) engine.MockEngine {
+	return p.fake
+}
+
+// fakeEngine implements the embeddable start and stop of an engine. The
+// other methods of engine.MockEngine are not implemented.
+type fakeEngine struct {
+	engine.MockEngine
+	mu        sync.Mutex
+	startErr  error
+	stopErr   error
+	running   bool
+	stoppingC chan struct{}
+	stopC     chan struct{}
+}
+
+func (f *fakeEngine) setStartErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.startErr = err
+}
+
+func (f *fakeEngine) StartEmbedded(_ context.Context, wg *sync.WaitGroup) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.startErr != nil {
+		return f.startErr
+	}
+	wg.Add(1)
+	f.running = true
+	return nil
+}
+
+func (f *fakeEngine) StopEmbedded(wg *sync.WaitGroup) error {
+	select {
+	case f.stoppingC <- struct{}{}:
+	default:
+	}
+	if f.stopC != nil {
+		<-f.stopC
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopErr != nil {
+		return f.stopErr
+	}
+	if f.running {
+		f.running = false
+		wg.Done()
+	}
+	return nil
+}
+
+func TestDaemon_restartMockWithInvalidConfig(t *testing.T) {
+	d := newFakeEngineDaemon(t, &fakeEngine{})
+	configDir := t.TempDir()
+	d.mocks["abc"] = &mockInstance{status: MockStatus{ID: "abc", ConfigDir: configDir, Port: 8080, State: MockStateRunning}}
+	if err := os.WriteFile(filepath.Join(configDir, "test-config.yaml"), []byte("resources: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mocks/abc/restart", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	d.buildMux().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status code = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	select {
+	case event := <-events:
+		if event.Type != EventConfigInvalid || event.MockID != "abc" || event.Port != 8080 {
+			t.Errorf("event = %+v, want config-invalid for mock abc", event)
+		}
+	default:
+		t.Errorf("expected config-invalid event to be published")
+	}
+	if d.mocks["abc"].status.State != MockStateRunning {
+		t.Errorf("mock state = %v, want %v", d.mocks["abc"].status.State, MockStateRunning)
+	}
+}
+
+func TestDaemon_concurrentDeploys(t *testing.T) {
+	viper.Set("engine", string(engine.EngineTypeJvmUnpacked))
+	defer viper.Set("engine", "")
+	d := newFakeEngineDaemon(t, &fakeEngine{})
+	handler := d.buildMux()
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	f, _ := zw.Create("test-config.yaml")
+	_, _ = f.Write([]byte("plugin: rest\n"))
+	_ = zw.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPut, "/v1/workspaces/test", bytes.NewReader(buf.Bytes()))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("status code = %v, want %v: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.workspaces) != 1 || len(d.mocks) != 1 {
+		t.Fatalf("deployed workspaces = %v, mocks = %v, want 1 of each", len(d.workspaces), len(d.mocks))
+	}
+	if _, ok := d.mocks[d.workspaces["test"].mockId]; !ok {
+		t.Errorf("expected the running mock to belong to the deployed workspace")
+	}
+}
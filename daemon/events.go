@@ -0,0 +1,149 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventStarted       EventType = "started"
+	EventReady         EventType = "ready"
+	EventRestarted     EventType = "restarted"
+	EventCrashed       EventType = "crashed"
+	EventStopped       EventType = "stopped"
+	EventConfigInvalid EventType = "config-invalid"
+)
+
+// stateChangedMethod is the JSON-RPC method used for mock state notifications.
+const stateChangedMethod = "mock/stateChanged"
+
+// subscriberBufferSize is the number of events buffered for each
+// subscriber, after which events are dropped for that subscriber.
+const subscriberBufferSize = 32
+
+// Event describes a change in the state of a mock managed by the daemon.
+type Event struct {
+	Type      EventType                `json:"type"`
+	MockID    string                   `json:"mockId,omitempty"`
+	ConfigDir string                   `json:"configDir"`
+	Port      int                      `json:"port,omitempty"`
+	Time      time.Time                `json:"time"`
+	Message   string                   `json:"message,omitempty"`
+	Errors    []config.ConfigFileError `json:"errors,omitempty"`
+}
+
+// jsonRpcNotification is a JSON-RPC 2.0 request without an ID,
+// to which no response is expected.
+type jsonRpcNotification struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[chan Event]struct{})}
+}
+
+func (b *eventBroker) subscribe() chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan Event, subscriberBufferSize)
+	b.subscribers[c] = struct{}{}
+	return c
+}
+
+func (b *eventBroker) unsubscribe(c chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, c)
+}
+
+// publish sends the event to all subscribers, without blocking
+// on subscribers that are not keeping up.
+func (b *eventBroker) publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	logger.Debugf("mock event: %s %s", event.Type, event.MockID)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.subscribers {
+		select {
+		case c <- event:
+		default:
+			logger.Warnf("dropped %s event for slow subscriber", event.Type)
+		}
+	}
+}
+
+// handleEvents streams mock state events to the client as server-sent
+// events, each containing a JSON-RPC notification.
+func (d *Daemon) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := writeSseEvent(w, event); err != nil {
+				logger.Debugf("failed to write event: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSseEvent(w io.Writer, event Event) error {
+	j, err := json.Marshal(jsonRpcNotification{
+		JsonRpc: "2.0",
+		Method:  stateChangedMethod,
+		Params:  event,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshall event: %v", err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, j)
+	return err
+}
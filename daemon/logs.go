@@ -0,0 +1,176 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLogLines is the number of lines of output retained for each mock.
+const maxLogLines = 5000
+
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// logBuffer retains the most recent lines of the output of a mock engine,
+// and notifies subscribers of new lines.
+type logBuffer struct {
+	mu          sync.Mutex
+	lines       []logLine
+	partial     string
+	subscribers map[chan string]bool
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{subscribers: make(map[chan string]bool)}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text := b.partial + string(p)
+	lines := strings.Split(text, "\n")
+	b.partial = lines[len(lines)-1]
+	now := time.Now()
+	for _, line := range lines[:len(lines)-1] {
+		b.lines = append(b.lines, logLine{time: now, text: line})
+		for c := range b.subscribers {
+			select {
+			case c <- line:
+			default:
+				logger.Warnf("dropped log line for slow subscriber")
+			}
+		}
+	}
+	if len(b.lines) > maxLogLines {
+		b.lines = b.lines[len(b.lines)-maxLogLines:]
+	}
+	return len(p), nil
+}
+
+// since returns the retained lines written at or after the given time.
+func (b *logBuffer) since(t time.Time) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []string
+	for _, line := range b.lines {
+		if !line.time.Before(t) {
+			lines = append(lines, line.text)
+		}
+	}
+	return lines
+}
+
+func (b *logBuffer) subscribe() chan string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan string, 256)
+	b.subscribers[c] = true
+	return c
+}
+
+func (b *logBuffer) unsubscribe(c chan string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, c)
+}
+
+func (d *Daemon) handleMockLogs(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	mock := d.mocks[r.PathValue("id")]
+	d.mu.Unlock()
+	if mock == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such mock: %s", r.PathValue("id")))
+		return
+	}
+	writeLogs(w, r, mock.logs)
+}
+
+func (d *Daemon) handleWorkspaceLogs(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	var mock *mockInstance
+	if deployed := d.workspaces[r.PathValue("name")]; deployed != nil {
+		mock = d.mocks[deployed.mockId]
+	}
+	d.mu.Unlock()
+	if mock == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no running mock for workspace: %s", r.PathValue("name")))
+		return
+	}
+	writeLogs(w, r, mock.logs)
+}
+
+// writeLogs writes the retained log lines as plain text. The optional
+// 'since' query parameter is a duration limiting the lines to those
+// written within it. If the 'follow' query parameter is true, new lines
+// are streamed until the client disconnects.
+func writeLogs(w http.ResponseWriter, r *http.Request, logs *logBuffer) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since duration: %s", s))
+			return
+		}
+		since = time.Now().Add(-duration)
+	}
+	follow := r.URL.Query().Get("follow") == "true"
+	flusher, ok := w.(http.Flusher)
+	if follow && !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	// subscribe before reading the retained lines, so none are missed
+	var lines chan string
+	if follow {
+		lines = logs.subscribe()
+		defer logs.unsubscribe(lines)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	out := bufio.NewWriter(w)
+	for _, line := range logs.since(since) {
+		_, _ = fmt.Fprintln(out, line)
+	}
+	_ = out.Flush()
+	if !follow {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-lines:
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				logger.Debugf("failed to write log line: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,380 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/engine"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+type MockState string
+
+const (
+	MockStateStarting MockState = "starting"
+	MockStateRunning  MockState = "running"
+	MockStateFailed   MockState = "failed"
+	MockStateStopped  MockState = "stopped"
+)
+
+type StartMockRequest struct {
+	ConfigDir   string   `json:"configDir"`
+	Port        int      `json:"port"`
+	EngineType  string   `json:"engineType,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Environment []string `json:"environment,omitempty"`
+}
+
+type MockStatus struct {
+	ID         string    `json:"id"`
+	ConfigDir  string    `json:"configDir"`
+	Port       int       `json:"port"`
+	EngineType string    `json:"engineType"`
+	Version    string    `json:"version"`
+	State      MockState `json:"state"`
+
+	// Error describes why the mock failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+type mockInstance struct {
+	status MockStatus
+	engine engine.EmbeddableEngine
+	wg     *sync.WaitGroup
+	logs   *logBuffer
+
+	// ctx is cancelled when the mock is stopped, aborting any start.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// engineMu serialises starting, restarting and stopping the engine,
+	// which are slow, so are not done while holding the daemon lock.
+	engineMu sync.Mutex
+}
+
+func (d *Daemon) handleListMocks(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statuses := []MockStatus{}
+	for _, mock := range d.mocks {
+		statuses = append(statuses, mock.status)
+	}
+	writeJson(w, http.StatusOK, statuses)
+}
+
+func (d *Daemon) handleGetMock(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mock := d.mocks[r.PathValue("id")]
+	if mock == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such mock: %s", r.PathValue("id")))
+		return
+	}
+	writeJson(w, http.StatusOK, mock.status)
+}
+
+func (d *Daemon) handleStartMock(w http.ResponseWriter, r *http.Request) {
+	var req StartMockRequest
+	if err := readJson(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.ConfigDir == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("configDir must be specified"))
+		return
+	}
+	configDir, err := filepath.Abs(req.ConfigDir)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid configDir: %v", err))
+		return
+	}
+	if err := config.ValidateConfigExists(configDir, false); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Port == 0 {
+		req.Port = 8080
+	}
+	if !d.validateConfigFiles(w, "", configDir, req.Port) {
+		return
+	}
+	mock, err := d.startMock(configDir, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	writeJson(w, http.StatusAccepted, mock.status)
+}
+
+// validateConfigFiles checks the configuration files in configDir, for
+// the mock with the given ID, if it has been started. If any are invalid,
+// a config-invalid event is published, an error response is written, and
+// false is returned.
+func (d *Daemon) validateConfigFiles(w http.ResponseWriter, mockId string, configDir string, port int) bool {
+	configErrors := config.ValidateConfigFiles(configDir, viper.GetBool("config.scan.recursive"))
+	if len(configErrors) == 0 {
+		return true
+	}
+	d.events.publish(Event{
+		Type:      EventConfigInvalid,
+		MockID:    mockId,
+		ConfigDir: configDir,
+		Port:      port,
+		Message:   fmt.Sprintf("%d invalid config file(s)", len(configErrors)),
+		Errors:    configErrors,
+	})
+	writeJson(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  "invalid configuration",
+		"errors": configErrors,
+	})
+	return false
+}
+
+func (d *Daemon) handleStopMock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	mock, err := d.stopMock(id)
+	if mock == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such mock: %s", id))
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, http.StatusOK, mock.status)
+}
+
+func (d *Daemon) handleRestartMock(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := r.PathValue("id")
+	mock := d.mocks[id]
+	if mock == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such mock: %s", id))
+		return
+	}
+	if mock.status.State != MockStateRunning {
+		writeError(w, http.StatusConflict, fmt.Errorf("mock %s is not running", id))
+		return
+	}
+	if !d.validateConfigFiles(w, id, mock.status.ConfigDir, mock.status.Port) {
+		return
+	}
+	mock.status.State = MockStateStarting
+	go d.restartMock(mock)
+	writeJson(w, http.StatusAccepted, mock.status)
+}
+
+// startMock builds the engine for the request and starts it in the
+// background, returning immediately with the mock in the starting state,
+// or an error if the engine cannot be built.
+func (d *Daemon) startMock(configDir string, req StartMockRequest) (*mockInstance, error) {
+	engineType := engine.GetConfiguredType(req.EngineType)
+	if err := engine.ValidateEngineType(engineType); err != nil {
+		return nil, err
+	} else if !slices.Contains(engine.EnumerateLibraries(), engineType) {
+		return nil, fmt.Errorf("engine type %s is not available", engineType)
+	}
+	lib := engine.GetLibrary(engineType)
+
+	var version string
+	if !lib.IsSealedDistro() {
+		version = engine.GetConfiguredVersion(req.Version, true)
+	}
+	logs := newLogBuffer()
+	startOptions := engine.StartOptions{
+		Port:            req.Port,
+		Version:         version,
+		PullPolicy:      engine.PullIfNotPresent,
+		LogLevel:        config.Config.LogLevel,
+		ReplaceRunning:  true,
+		EnablePlugins:   true,
+		EnableFileCache: true,
+		Environment:     req.Environment,
+		LogWriter:       logs,
+
+		// a mock that is slow to start must not exit the daemon
+		Embedded: true,
+	}
+	provider := lib.GetProvider(version)
+	mockEngine, ok := provider.Build(configDir, startOptions).(engine.EmbeddableEngine)
+	if !ok {
+		return nil, fmt.Errorf("engine type %s cannot be started by the daemon", engineType)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mock := &mockInstance{
+		status: MockStatus{
+			ID:         uuid.New().String(),
+			ConfigDir:  configDir,
+			Port:       req.Port,
+			EngineType: string(engineType),
+			Version:    version,
+			State:      MockStateStarting,
+		},
+		engine: mockEngine,
+		wg:     &sync.WaitGroup{},
+		logs:   logs,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	d.mu.Lock()
+	d.mocks[mock.status.ID] = mock
+	d.mu.Unlock()
+
+	go func() {
+		logger.Infof("starting mock %s for %s on port %d", mock.status.ID, configDir, req.Port)
+		d.publishMockEvent(mock, EventStarted, "", nil)
+
+		mock.engineMu.Lock()
+		err := mock.engine.StartEmbedded(mock.ctx, mock.wg)
+		if err != nil && mock.ctx.Err() == nil {
+			d.stopFailedEngine(mock)
+		}
+		mock.engineMu.Unlock()
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if mock.status.State != MockStateStarting {
+			return
+		}
+		if err != nil {
+			logger.Warnf("mock %s failed to start: %v", mock.status.ID, err)
+			mock.status.State = MockStateFailed
+			mock.status.Error = err.Error()
+			d.publishMockEvent(mock, EventCrashed, fmt.Sprintf("mock failed to start: %v", err), nil)
+			return
+		}
+		mock.status.State = MockStateRunning
+		d.publishMockEvent(mock, EventReady, "", nil)
+		go d.watchForCrash(mock)
+	}()
+	return mock, nil
+}
+
+// restartMock stops the engine, then starts it again, marking the mock
+// as running, or as failed if it does not start.
+func (d *Daemon) restartMock(mock *mockInstance) {
+	logger.Infof("restarting mock %s", mock.status.ID)
+	mock.engineMu.Lock()
+
+	// keep the wait group from reaching zero, so the restart is not
+	// reported as a crash
+	mock.wg.Add(1)
+	err := mock.engine.StopEmbedded(mock.wg)
+	if err == nil {
+		if err = mock.engine.StartEmbedded(mock.ctx, mock.wg); err != nil && mock.ctx.Err() == nil {
+			d.stopFailedEngine(mock)
+		}
+	}
+	mock.wg.Done()
+	mock.engineMu.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if mock.status.State != MockStateStarting {
+		return
+	}
+	if err != nil {
+		logger.Warnf("mock %s failed to restart: %v", mock.status.ID, err)
+		mock.status.State = MockStateFailed
+		mock.status.Error = err.Error()
+		d.publishMockEvent(mock, EventCrashed, fmt.Sprintf("mock failed to restart: %v", err), nil)
+		return
+	}
+	mock.status.State = MockStateRunning
+	d.publishMockEvent(mock, EventRestarted, "", nil)
+}
+
+// stopFailedEngine stops an engine that failed to start, such as a
+// container that did not become ready. The caller must hold the engine lock.
+func (d *Daemon) stopFailedEngine(mock *mockInstance) {
+	if err := mock.engine.StopEmbedded(mock.wg); err != nil {
+		logger.Warnf("failed to stop mock %s after it failed to start: %v", mock.status.ID, err)
+	}
+}
+
+// watchForCrash blocks until the engine exits. If the mock was running,
+// and was not stopped by the daemon, it is marked as failed.
+func (d *Daemon) watchForCrash(mock *mockInstance) {
+	mock.wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if mock.status.State != MockStateRunning {
+		return
+	}
+	logger.Warnf("mock %s exited unexpectedly", mock.status.ID)
+	mock.status.State = MockStateFailed
+	mock.status.Error = "mock engine exited unexpectedly"
+	d.publishMockEvent(mock, EventCrashed, mock.status.Error, nil)
+}
+
+func (d *Daemon) publishMockEvent(mock *mockInstance, eventType EventType, message string, configErrors []config.ConfigFileError) {
+	d.events.publish(Event{
+		Type:      eventType,
+		MockID:    mock.status.ID,
+		ConfigDir: mock.status.ConfigDir,
+		Port:      mock.status.Port,
+		Message:   message,
+		Errors:    configErrors,
+	})
+}
+
+// stopMock stops and removes the mock with the given ID, returning it,
+// or nil if no such mock exists. The error is set if the engine could
+// not be stopped. The caller must not hold the lock, as stopping the
+// engine may be slow.
+func (d *Daemon) stopMock(id string) (*mockInstance, error) {
+	d.mu.Lock()
+	mock := d.mocks[id]
+	if mock == nil {
+		d.mu.Unlock()
+		return nil, nil
+	}
+	mock.status.State = MockStateStopped
+	delete(d.mocks, id)
+	d.mu.Unlock()
+
+	logger.Infof("stopping mock %s", id)
+	mock.cancel()
+	mock.engineMu.Lock()
+	defer mock.engineMu.Unlock()
+	if err := mock.engine.StopEmbedded(mock.wg); err != nil {
+		err = fmt.Errorf("failed to stop mock %s: %v", id, err)
+
+		// keep the mock, so stopping it can be retried
+		d.mu.Lock()
+		mock.status.State = MockStateFailed
+		mock.status.Error = err.Error()
+		d.mocks[id] = mock
+		d.mu.Unlock()
+		return mock, err
+	}
+	mock.wg.Wait()
+	d.publishMockEvent(mock, EventStopped, "", nil)
+	return mock, nil
+}
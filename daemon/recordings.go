@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"gatehill.io/imposter/proxy"
+	"github.com/google/uuid"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+)
+
+type StartRecordingRequest struct {
+	Upstream                string `json:"upstream"`
+	Port                    int    `json:"port"`
+	OutputDir               string `json:"outputDir"`
+	Rewrite                 bool   `json:"rewrite,omitempty"`
+	IgnoreDuplicateRequests bool   `json:"ignoreDuplicateRequests,omitempty"`
+}
+
+type RecordingStatus struct {
+	ID        string `json:"id"`
+	Upstream  string `json:"upstream"`
+	Port      int    `json:"port"`
+	OutputDir string `json:"outputDir"`
+}
+
+type recordingInstance struct {
+	status RecordingStatus
+	server *http.Server
+}
+
+func (d *Daemon) handleListRecordings(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statuses := []RecordingStatus{}
+	for _, recording := range d.recordings {
+		statuses = append(statuses, recording.status)
+	}
+	writeJson(w, http.StatusOK, statuses)
+}
+
+func (d *Daemon) handleStartRecording(w http.ResponseWriter, r *http.Request) {
+	var req StartRecordingRequest
+	if err := readJson(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Upstream == "" || req.OutputDir == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("upstream and outputDir must be specified"))
+		return
+	}
+	if req.Port == 0 {
+		req.Port = 8080
+	}
+	recording, err := d.startRecording(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, http.StatusCreated, recording.status)
+}
+
+func (d *Daemon) handleStopRecording(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := r.PathValue("id")
+	recording := d.stopRecording(id)
+	if recording == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such recording: %s", id))
+		return
+	}
+	writeJson(w, http.StatusOK, recording.status)
+}
+
+// startRecording starts a recording proxy for the upstream in the background.
+func (d *Daemon) startRecording(req StartRecordingRequest) (*recordingInstance, error) {
+	outputDir, err := filepath.Abs(req.OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outputDir: %v", err)
+	}
+	recorderC, err := proxy.StartRecorder(req.Upstream, outputDir, proxy.RecorderOptions{
+		IgnoreDuplicateRequests: req.IgnoreDuplicateRequests,
+		MaxBodySize:             proxy.DefaultMaxRecordBodySize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recording := &recordingInstance{
+		status: RecordingStatus{
+			ID:        uuid.New().String(),
+			Upstream:  req.Upstream,
+			Port:      req.Port,
+			OutputDir: outputDir,
+		},
+		server: &http.Server{
+			// reachable from the same hosts as the control API
+			Addr:    net.JoinHostPort(d.host, strconv.Itoa(req.Port)),
+			Handler: proxy.BuildRecordingMux(req.Upstream, proxy.BuildProxyBaseUrl(req.Port, false), req.Rewrite, proxy.DefaultMaxRecordBodySize, recorderC),
+		},
+	}
+
+	d.mu.Lock()
+	d.recordings[recording.status.ID] = recording
+	d.mu.Unlock()
+
+	go func() {
+		logger.Infof("starting recording %s of %s on port %d", recording.status.ID, req.Upstream, req.Port)
+		if err := recording.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("recording %s failed: %v", recording.status.ID, err)
+			d.mu.Lock()
+			delete(d.recordings, recording.status.ID)
+			d.mu.Unlock()
+		}
+	}()
+	return recording, nil
+}
+
+// stopRecording stops and removes the recording with the given ID, returning
+// it, or nil if no such recording exists. The caller must hold the lock.
+func (d *Daemon) stopRecording(id string) *recordingInstance {
+	recording := d.recordings[id]
+	if recording == nil {
+		return nil
+	}
+	logger.Infof("stopping recording %s", id)
+	if err := recording.server.Close(); err != nil {
+		logger.Warnf("error stopping recording %s: %v", id, err)
+	}
+	delete(d.recordings, id)
+	return recording
+}
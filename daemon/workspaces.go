@@ -0,0 +1,232 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"encoding/base64"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/fileutil"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWorkspaceBundleSize limits the size of an uploaded workspace bundle.
+const maxWorkspaceBundleSize = 64 * 1024 * 1024
+
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// EnvironmentHeader carries an environment variable for the mock, as a
+// base64 encoded NAME=value pair, in a deploy request. It may be repeated.
+const EnvironmentHeader = "Imposter-Environment"
+
+// WorkspaceStatus describes a workspace deployed to the daemon, and
+// the mock serving it.
+type WorkspaceStatus struct {
+	Name       string     `json:"name"`
+	DeployedAt int64      `json:"deployedAt"`
+	Mock       MockStatus `json:"mock"`
+}
+
+type deployedWorkspace struct {
+	name       string
+	mockId     string
+	configDir  string
+	deployedAt int64
+}
+
+// handleDeployWorkspace extracts the ZIP bundle in the request body as the
+// configuration of the workspace, replacing any previous deployment, and
+// starts a mock from it.
+func (d *Daemon) handleDeployWorkspace(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !workspaceNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid workspace name: %s", name))
+		return
+	}
+	port := 8080
+	if p := r.URL.Query().Get("port"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid port: %s", p))
+			return
+		}
+		port = parsed
+	}
+	environment, err := parseEnvironmentHeaders(r.Header.Values(EnvironmentHeader))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	bundle, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWorkspaceBundleSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read workspace bundle: %v", err))
+		return
+	}
+
+	unlock := d.lockWorkspace(name)
+	defer unlock()
+
+	if _, err := d.undeployWorkspace(name); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	configDir := filepath.Join(d.workspacesDir, name)
+	if err := os.RemoveAll(configDir); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to clear workspace directory: %s: %v", configDir, err))
+		return
+	}
+	if err := fileutil.ExtractZip(bundle, configDir); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := config.ValidateConfigExists(configDir, false); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !d.validateConfigFiles(w, "", configDir, port) {
+		return
+	}
+	logger.Infof("deployed workspace %s to %s", name, configDir)
+
+	mock, err := d.startMock(configDir, StartMockRequest{ConfigDir: configDir, Port: port, Environment: environment})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	deployed := &deployedWorkspace{
+		name:       name,
+		mockId:     mock.status.ID,
+		configDir:  configDir,
+		deployedAt: time.Now().UnixMilli(),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.workspaces[name] = deployed
+	writeJson(w, http.StatusAccepted, d.getWorkspaceStatus(deployed))
+}
+
+func (d *Daemon) handleGetWorkspace(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deployed := d.workspaces[r.PathValue("name")]
+	if deployed == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such workspace: %s", r.PathValue("name")))
+		return
+	}
+	writeJson(w, http.StatusOK, d.getWorkspaceStatus(deployed))
+}
+
+func (d *Daemon) handleUndeployWorkspace(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	unlock := d.lockWorkspace(name)
+	defer unlock()
+
+	deployed, err := d.undeployWorkspace(name)
+	if deployed == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such workspace: %s", name))
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"name": name})
+}
+
+// lockWorkspace waits until no other deployment of the workspace with the
+// given name is in progress, returning a function that releases the lock.
+// The caller must not hold the daemon lock.
+func (d *Daemon) lockWorkspace(name string) (unlock func()) {
+	d.mu.Lock()
+	lock := d.workspaceLocks[name]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		d.workspaceLocks[name] = lock
+	}
+	d.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// getWorkspaceStatus returns the status of the workspace. If its mock has
+// been stopped, the mock is reported as stopped. The caller must hold the lock.
+func (d *Daemon) getWorkspaceStatus(deployed *deployedWorkspace) WorkspaceStatus {
+	status := WorkspaceStatus{
+		Name:       deployed.name,
+		DeployedAt: deployed.deployedAt,
+	}
+	if mock := d.mocks[deployed.mockId]; mock != nil {
+		status.Mock = mock.status
+	} else {
+		status.Mock = MockStatus{ID: deployed.mockId, ConfigDir: deployed.configDir, State: MockStateStopped}
+	}
+	return status
+}
+
+// undeployWorkspace stops the mock for the workspace with the given name
+// and removes its configuration, returning it, or nil if no such workspace
+// is deployed. If the mock cannot be stopped, the workspace remains deployed
+// and an error is returned. The caller must not hold the lock.
+func (d *Daemon) undeployWorkspace(name string) (*deployedWorkspace, error) {
+	d.mu.Lock()
+	deployed := d.workspaces[name]
+	delete(d.workspaces, name)
+	d.mu.Unlock()
+	if deployed == nil {
+		return nil, nil
+	}
+
+	logger.Infof("undeploying workspace %s", name)
+	if _, err := d.stopMock(deployed.mockId); err != nil {
+		d.mu.Lock()
+		d.workspaces[name] = deployed
+		d.mu.Unlock()
+		return deployed, err
+	}
+	if err := os.RemoveAll(deployed.configDir); err != nil {
+		logger.Warnf("failed to remove workspace directory: %s: %v", deployed.configDir, err)
+	}
+	return deployed, nil
+}
+
+// parseEnvironmentHeaders decodes the environment variables sent with a
+// deploy request. Their values are never logged, as they may be secrets.
+func parseEnvironmentHeaders(values []string) ([]string, error) {
+	var environment []string
+	for _, value := range values {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %v", EnvironmentHeader, err)
+		}
+		env := string(decoded)
+		if !strings.Contains(env, "=") {
+			return nil, fmt.Errorf("invalid %s header: expected NAME=value", EnvironmentHeader)
+		}
+		environment = append(environment, env)
+	}
+	return environment, nil
+}
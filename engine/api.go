@@ -16,7 +16,15 @@ limitations under the License.
 
 package engine
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
 
 type StartOptions struct {
 	Port            int
@@ -30,6 +38,69 @@ type StartOptions struct {
 	Environment     []string
 	DirMounts       []string
 	DebugMode       bool
+	ContainerName   string
+	Network         string
+
+	// Host is the host on which the engine port is reachable, such as
+	// that of a remote Docker daemon. Empty means localhost.
+	Host string
+
+	// Image replaces the default engine image, if set.
+	Image string
+
+	// ConfigMountMode sets how the config dir, and other host paths, are
+	// provided to the engine container. Empty means the configured mode
+	// is used, or the paths are copied if the Docker daemon is remote.
+	ConfigMountMode ConfigMountMode
+
+	// ContainerUser runs the engine container as the user (username or
+	// uid), if set.
+	ContainerUser string
+
+	// ReadOnly makes the root filesystem of the engine container read-only.
+	ReadOnly bool
+
+	// CapDrop lists the Linux capabilities dropped from the engine
+	// container, such as 'ALL'.
+	CapDrop []string
+
+	// Memory is the memory limit in bytes. Zero means no limit.
+	Memory int64
+
+	// Cpus is the number of CPUs available to the engine. Zero means no limit.
+	Cpus float64
+
+	// JvmArgs are passed to the JVM running the engine, before the engine
+	// arguments, such as GC settings or debugging agents.
+	JvmArgs []string
+
+	// Heap is the maximum heap size of the JVM running the engine, such
+	// as '512m'. Empty means the JVM default is used.
+	Heap string
+
+	// WaitReady reports when the mock is ready, and exits the CLI with a
+	// non-zero exit code if the engine fails to become ready.
+	WaitReady bool
+
+	// Embedded is set if the engine is started by a program embedding
+	// the mock, rather than the CLI, so a failure to become ready is
+	// returned from Start, instead of exiting the process.
+	Embedded bool
+
+	// ReadyTimeout is the maximum time to wait for the engine to become
+	// ready. Zero means the configured start timeout is used.
+	ReadyTimeout time.Duration
+
+	// Tls configures HTTPS for the engine.
+	Tls TlsOptions
+
+	// DrainTimeout is the maximum time to wait for in-flight requests
+	// to complete before stopping the engine. Zero disables draining.
+	DrainTimeout time.Duration
+
+	// LogWriter receives the output of the engine, in addition to
+	// the console, if set.
+	LogWriter io.Writer
 }
 
 type PullPolicy int
@@ -40,6 +111,25 @@ const (
 	PullIfNotPresent PullPolicy = iota
 )
 
+// ConfigMountMode is how host paths are provided to the engine container.
+type ConfigMountMode string
+
+const (
+	ConfigMountModeBind ConfigMountMode = "bind"
+	ConfigMountModeCopy ConfigMountMode = "copy"
+)
+
+// ParseConfigMountMode validates the config mount mode. An empty mode
+// is valid, and means the default mode is used.
+func ParseConfigMountMode(mode string) (ConfigMountMode, error) {
+	switch ConfigMountMode(mode) {
+	case "", ConfigMountModeBind, ConfigMountModeCopy:
+		return ConfigMountMode(mode), nil
+	default:
+		return "", fmt.Errorf("invalid config mount mode: %s - valid values are: %s, %s", mode, ConfigMountModeBind, ConfigMountModeCopy)
+	}
+}
+
 type MockEngine interface {
 	Start(wg *sync.WaitGroup) (success bool)
 	Stop(wg *sync.WaitGroup)
@@ -47,12 +137,35 @@ type MockEngine interface {
 	Restart(wg *sync.WaitGroup)
 	ListAllManaged() ([]ManagedMock, error)
 	StopAllManaged() int
+
+	// StopManaged stops the managed mocks with the given IDs, returning
+	// the number stopped. See MatchManaged.
+	StopManaged(ids []string) int
 	GetVersionString() (string, error)
 }
 
+// EmbeddableEngine is implemented by engines that can be started by a
+// program embedding the mock, such as a test suite using the SDK. Unlike
+// Start and Stop, which exit the process if the engine cannot be started
+// or stopped, these return an error.
+type EmbeddableEngine interface {
+	// StartEmbedded starts the engine, returning once it is ready, or an
+	// error if it fails to start or become ready. If ctx is done first,
+	// waiting for the engine to become ready is aborted, and the context's
+	// error is returned.
+	StartEmbedded(ctx context.Context, wg *sync.WaitGroup) error
+
+	// StopEmbedded stops the engine, if it was started.
+	StopEmbedded(wg *sync.WaitGroup) error
+}
+
 type EngineMetadata struct {
 	EngineType EngineType
 	Version    string
+
+	// Size is the size in bytes of the cached engine binary/image,
+	// or 0 if it is not known.
+	Size int64
 }
 
 type Provider interface {
@@ -80,6 +193,10 @@ type EngineLibrary interface {
 	// ShouldEnsurePlugins indicates whether missing default plugins should be
 	// installed before starting the engine.
 	ShouldEnsurePlugins() bool
+
+	// Remove deletes the cached engine binary/image for the version,
+	// returning the number of bytes reclaimed.
+	Remove(version string) (int64, error)
 }
 
 type MockHealth string
@@ -97,4 +214,34 @@ type ManagedMock struct {
 	Health MockHealth
 }
 
+// MatchManaged returns the IDs of the mocks matching the given IDs, each
+// of which may be a unique prefix of the ID of a mock, such as the short
+// form of a container ID. An error is returned if an ID matches no mock,
+// or more than one.
+func MatchManaged(mocks []ManagedMock, ids []string) ([]string, error) {
+	var matched []string
+	for _, id := range ids {
+		var matches []string
+		for _, mock := range mocks {
+			if mock.ID == id {
+				matches = []string{mock.ID}
+				break
+			} else if strings.HasPrefix(mock.ID, id) {
+				matches = append(matches, mock.ID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no managed mock found with ID: %s", id)
+		case 1:
+			if !stringutil.Contains(matched, matches[0]) {
+				matched = append(matched, matches[0])
+			}
+		default:
+			return nil, fmt.Errorf("ID %s matches %d managed mocks - use a longer ID", id, len(matches))
+		}
+	}
+	return matched, nil
+}
+
 const DefaultDebugPort = 8000
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseConfigMountMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		want    ConfigMountMode
+		wantErr bool
+	}{
+		{name: "default", mode: "", want: ""},
+		{name: "bind", mode: "bind", want: ConfigMountModeBind},
+		{name: "copy", mode: "copy", want: ConfigMountModeCopy},
+		{name: "invalid", mode: "volume", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfigMountMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfigMountMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseConfigMountMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchManaged(t *testing.T) {
+	mocks := []ManagedMock{{ID: "abc123"}, {ID: "abd456"}, {ID: "abc"}}
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "full ID", ids: []string{"abd456"}, want: []string{"abd456"}},
+		{name: "unique prefix", ids: []string{"abd"}, want: []string{"abd456"}},
+		{name: "exact match preferred over prefix", ids: []string{"abc"}, want: []string{"abc"}},
+		{name: "duplicates removed", ids: []string{"abc1", "abc123"}, want: []string{"abc123"}},
+		{name: "ambiguous prefix", ids: []string{"ab"}, wantErr: true},
+		{name: "no match", ids: []string{"xyz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchManaged(mocks, tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchManaged() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) && !tt.wantErr {
+				t.Errorf("MatchManaged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package awslambda
 
 import (
+	"fmt"
 	"gatehill.io/imposter/engine"
 	"gatehill.io/imposter/logging"
 )
@@ -65,6 +66,10 @@ func (LambdaLibrary) List() ([]engine.EngineMetadata, error) {
 	return []engine.EngineMetadata{}, nil
 }
 
+func (LambdaLibrary) Remove(version string) (int64, error) {
+	return 0, fmt.Errorf("the Lambda engine does not cache engine versions")
+}
+
 func (p *LambdaProvider) GetEngineType() engine.EngineType {
 	return p.EngineType
 }
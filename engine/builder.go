@@ -18,6 +18,7 @@ package engine
 
 import (
 	"fmt"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/stringutil"
 	"github.com/spf13/viper"
@@ -62,12 +63,12 @@ func EnumerateLibraries() []EngineType {
 }
 
 func GetLibrary(engineType EngineType) EngineLibrary {
-	if err := validateEngineType(engineType); err != nil {
-		logger.Fatal(err)
+	if err := ValidateEngineType(engineType); err != nil {
+		failure.Fatal(err)
 	}
 	library := libraries[engineType]
 	if library == nil {
-		logger.Fatalf("unregistered engine type: %v", engineType)
+		failure.Fatal(failure.New(failure.CodeEngineUnsupported, "unregistered engine type: %v", engineType))
 	}
 	logger.Tracef("using %s library", engineType)
 	return library()
@@ -88,23 +89,24 @@ func BuildEngine(engineType EngineType, configDir string, startOptions StartOpti
 // build validates the engine type against those supported, then invokes the
 // associated engine builder function.
 func build(engineType EngineType, configDir string, startOptions StartOptions) MockEngine {
-	if err := validateEngineType(engineType); err != nil {
-		logger.Fatal(err)
+	if err := ValidateEngineType(engineType); err != nil {
+		failure.Fatal(err)
 	}
 	eng := engines[engineType]
 	if eng == nil {
-		logger.Fatalf("unregistered engine type: %v", engineType)
+		failure.Fatal(failure.New(failure.CodeEngineUnsupported, "unregistered engine type: %v", engineType))
 	}
 	logger.Tracef("using %s engine", engineType)
 	return eng(configDir, startOptions)
 }
 
-func validateEngineType(engineType EngineType) error {
+// ValidateEngineType returns an error if the engine type is not supported.
+func ValidateEngineType(engineType EngineType) error {
 	switch engineType {
 	case EngineTypeAwsLambda, EngineTypeDockerCore, EngineTypeDockerAll, EngineTypeDockerDistroless, EngineTypeJvmSingleJar, EngineTypeJvmUnpacked:
 		return nil
 	}
-	return fmt.Errorf("unsupported engine type: %v", engineType)
+	return failure.New(failure.CodeEngineUnsupported, "unsupported engine type: %v", engineType)
 }
 
 func GetConfiguredType(override string) EngineType {
@@ -135,6 +137,12 @@ func GetConfiguredVersionOrResolve(override string, allowCached bool, resolveIfL
 			panic(err)
 		}
 		version = latest
+	} else if IsFuzzyVersion(version) && resolveIfLatest {
+		resolved, err := ResolveFuzzyVersion(version, allowCached)
+		if err != nil {
+			panic(err)
+		}
+		version = resolved
 	}
 	return version
 }
@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"gatehill.io/imposter/debounce"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/plugin"
 	"gatehill.io/imposter/stringutil"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
@@ -43,6 +45,7 @@ import (
 const containerConfigDir = "/opt/imposter/config"
 const containerPluginDir = "/opt/imposter/plugins"
 const containerFileCacheDir = "/tmp/imposter-cache"
+const containerTlsDir = "/opt/imposter/tls"
 const removalTimeoutSec = 5
 
 var logger = logging.GetLogger()
@@ -51,67 +54,155 @@ func (d *DockerMockEngine) Start(wg *sync.WaitGroup) bool {
 	return d.startWithOptions(wg, d.options)
 }
 
+func (d *DockerMockEngine) StartEmbedded(ctx context.Context, wg *sync.WaitGroup) error {
+	options := d.options
+	options.Embedded = true
+	return engine.StartWithContext(ctx, d.shutDownC, func() (bool, error) {
+		return d.startContainer(wg, options)
+	})
+}
+
 func (d *DockerMockEngine) startWithOptions(wg *sync.WaitGroup, options engine.StartOptions) (success bool) {
+	success, err := d.startContainer(wg, options)
+	if err != nil {
+		failure.Fatal(err)
+	}
+	return success
+}
+
+// startContainer starts the mock engine container, returning whether it
+// became ready, or an error if it could not be started.
+func (d *DockerMockEngine) startContainer(wg *sync.WaitGroup, options engine.StartOptions) (success bool, err error) {
 	logger.Infof("starting mock engine on port %d - press ctrl+c to stop", options.Port)
+	if len(options.JvmArgs) > 0 || options.Heap != "" {
+		logger.Warnf("Docker engine does not support JVM arguments or heap size - these will be ignored")
+	}
 	ctx, cli, err := buildCliClient()
 	if err != nil {
-		logger.Fatal(err)
+		return false, failure.Wrap(failure.CodeEngineDockerUnavailable, err)
 	}
 
 	if !d.provider.Satisfied() {
 		if err := d.provider.Provide(engine.PullIfNotPresent); err != nil {
-			logger.Fatal(err)
+			return false, failure.Wrap(failure.CodeEngineDownloadFailed, err)
 		}
 	}
 
 	mockHash, containerLabels := generateMetadata(d, options)
 
 	if options.ReplaceRunning {
-		stopDuplicateContainers(d, cli, ctx, mockHash)
+		if err := stopDuplicateContainers(d, cli, ctx, mockHash); err != nil {
+			return false, failure.Wrap(failure.CodeEngineStartFailed, err)
+		}
 	}
 
 	// if not specified, falls back to default in container image
-	containerUser := viper.GetString("docker.containerUser")
+	containerUser := stringutil.GetFirstNonEmpty(options.ContainerUser, viper.GetString("docker.containerUser"))
 	logger.Tracef("container user: %s", containerUser)
 
+	options.Host = getRemoteDaemonHost(cli.DaemonHost())
+	copyFiles := resolveConfigMountMode(options.ConfigMountMode, options.Host) == engine.ConfigMountModeCopy
+	readOnly, err := resolveReadOnly(options, copyFiles)
+	if err != nil {
+		return false, err
+	}
+	var binds []string
+	if copyFiles {
+		logger.Debugf("files will be copied to the container")
+		if options.EnableFileCache {
+			logger.Debugf("file cache is not shared with the container when files are copied")
+		}
+	} else if binds, err = buildBinds(d, options); err != nil {
+		return false, failure.Wrap(failure.CodeEngineStartFailed, err)
+	}
+
 	exposedPorts, portBindings := buildPorts(options)
 	resp, err := cli.ContainerCreate(ctx, &container.Config{
-		Image: d.provider.imageAndTag,
-		Cmd: []string{
-			"--configDir=" + containerConfigDir,
-			fmt.Sprintf("--listenPort=%d", options.Port),
-		},
+		Image:        d.provider.imageAndTag,
+		Cmd:          buildCmd(options),
 		Env:          buildEnv(options),
 		ExposedPorts: exposedPorts,
 		Labels:       containerLabels,
 		User:         containerUser,
 	}, &container.HostConfig{
-		Binds:        buildBinds(d, options),
-		PortBindings: portBindings,
-	}, nil, nil, "")
+		Binds:          binds,
+		PortBindings:   portBindings,
+		NetworkMode:    container.NetworkMode(options.Network),
+		Resources:      buildResources(options),
+		ReadonlyRootfs: readOnly,
+		Tmpfs:          buildTmpfs(readOnly),
+		CapDrop:        getCapDrop(options),
+	}, buildNetworkingConfig(options), nil, options.ContainerName)
 	if err != nil {
-		logger.Fatal(err)
+		return false, failure.Wrap(failure.CodeEngineStartFailed, err)
 	}
 
+	// the container is removed when the engine is stopped, even if it
+	// fails to start
 	containerId := resp.ID
 	d.debouncer.Register(wg, containerId)
+	d.containerId = containerId
+	if copyFiles {
+		copyPaths, err := buildCopyPaths(d, options)
+		if err != nil {
+			return false, failure.Wrap(failure.CodeEngineStartFailed, err)
+		}
+		if err := copyToContainer(cli, ctx, containerId, copyPaths); err != nil {
+			return false, failure.Wrap(failure.CodeEngineStartFailed, err)
+		}
+	}
 	if err := cli.ContainerStart(ctx, containerId, types.ContainerStartOptions{}); err != nil {
-		logger.Fatalf("error starting mock engine container: %v", err)
+		return false, failure.New(failure.CodeEngineStartFailed, "error starting mock engine container: %v", err)
 	}
 	logger.Trace("starting Docker mock engine")
 
-	d.containerId = containerId
-	if err = streamLogsToStdIo(cli, ctx, containerId); err != nil {
+	logTail := engine.NewLogTail()
+	if err = streamLogsToStdIo(cli, ctx, containerId, options, logTail); err != nil {
 		logger.Warn(err)
 	}
-	up := engine.WaitUntilUp(options.Port, d.shutDownC)
 
 	// watch in case container stops
+	exitedC := watchForExit(cli, ctx, containerId)
 	go func() {
 		notifyOnStopBlocking(d, wg, containerId, cli, ctx)
 	}()
 
-	return up
+	return engine.WaitUntilReady(options, exitedC, logTail, d.shutDownC), nil
+}
+
+// watchForExit returns a channel that receives a description
+// of the container exit, once it stops running.
+func watchForExit(cli *client.Client, ctx context.Context, containerId string) <-chan string {
+	exitedC := make(chan string, 1)
+	statusCh, errCh := cli.ContainerWait(ctx, containerId, container.WaitConditionNotRunning)
+	go func() {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				exitedC <- err.Error()
+			}
+		case status := <-statusCh:
+			exitedC <- fmt.Sprintf("container exited with status %d", status.StatusCode)
+		}
+	}()
+	return exitedC
+}
+
+func buildCmd(options engine.StartOptions) []string {
+	cmd := []string{
+		"--configDir=" + containerConfigDir,
+		fmt.Sprintf("--listenPort=%d", options.Port),
+	}
+	return append(cmd, engine.BuildTlsArgs(options.Tls, getContainerKeystorePath(options))...)
+}
+
+// getContainerKeystorePath returns the path to the keystore within
+// the container, if one is configured.
+func getContainerKeystorePath(options engine.StartOptions) string {
+	if options.Tls.KeystorePath == "" {
+		return ""
+	}
+	return containerTlsDir + "/" + filepath.Base(options.Tls.KeystorePath)
 }
 
 func buildPorts(options engine.StartOptions) (nat.PortSet, nat.PortMap) {
@@ -139,6 +230,68 @@ func buildPorts(options engine.StartOptions) (nat.PortSet, nat.PortMap) {
 	return exposedPorts, portBindings
 }
 
+// buildResources sets the container resource limits, if any.
+func buildResources(options engine.StartOptions) container.Resources {
+	resources := container.Resources{
+		Memory:   options.Memory,
+		NanoCPUs: int64(options.Cpus * 1e9),
+	}
+	logger.Tracef("container resources: memory=%d nanoCpus=%d", resources.Memory, resources.NanoCPUs)
+	return resources
+}
+
+// resolveReadOnly returns whether the container should have a read-only
+// root filesystem, falling back to the 'docker.readOnly' configuration
+// key. Files cannot be copied to a read-only container, so this is an
+// error if copyFiles is true.
+func resolveReadOnly(options engine.StartOptions, copyFiles bool) (bool, error) {
+	readOnly := options.ReadOnly || viper.GetBool("docker.readOnly")
+	if readOnly && copyFiles {
+		return false, failure.New(failure.CodeEngineStartFailed, "files cannot be copied to a container with a read-only root filesystem - use bind mounts, or disable the read-only root filesystem")
+	}
+	return readOnly, nil
+}
+
+// buildTmpfs returns the writable in-memory mounts for a container with
+// a read-only root filesystem, as the engine writes temporary files.
+func buildTmpfs(readOnly bool) map[string]string {
+	if !readOnly {
+		return nil
+	}
+	return map[string]string{
+		"/tmp": "",
+	}
+}
+
+// getCapDrop returns the capabilities to drop from the container,
+// falling back to the 'docker.capDrop' configuration key.
+func getCapDrop(options engine.StartOptions) []string {
+	capDrop := options.CapDrop
+	if len(capDrop) == 0 {
+		capDrop = viper.GetStringSlice("docker.capDrop")
+	}
+	logger.Tracef("dropping container capabilities: %v", capDrop)
+	return capDrop
+}
+
+// buildNetworkingConfig attaches the container to the user-defined network,
+// if set, using the container name as a network alias.
+func buildNetworkingConfig(options engine.StartOptions) *network.NetworkingConfig {
+	if options.Network == "" {
+		return nil
+	}
+	logger.Tracef("attaching container to network: %s", options.Network)
+	endpoint := &network.EndpointSettings{}
+	if options.ContainerName != "" {
+		endpoint.Aliases = []string{options.ContainerName}
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			options.Network: endpoint,
+		},
+	}
+}
+
 func buildEnv(options engine.StartOptions) []string {
 	env := engine.BuildEnv(options, false)
 	if options.EnableFileCache {
@@ -148,7 +301,7 @@ func buildEnv(options engine.StartOptions) []string {
 	return env
 }
 
-func buildBinds(d *DockerMockEngine, options engine.StartOptions) []string {
+func buildBinds(d *DockerMockEngine, options engine.StartOptions) ([]string, error) {
 	binds := []string{
 		d.configDir + ":" + containerConfigDir + viper.GetString("docker.bindFlags"),
 	}
@@ -156,7 +309,7 @@ func buildBinds(d *DockerMockEngine, options engine.StartOptions) []string {
 		logger.Tracef("plugins are enabled")
 		pluginDir, err := plugin.EnsurePluginDir(options.Version)
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		binds = append(binds, pluginDir+":"+containerPluginDir)
 	} else {
@@ -166,20 +319,28 @@ func buildBinds(d *DockerMockEngine, options engine.StartOptions) []string {
 		logger.Tracef("file cache enabled")
 		fileCacheDir, err := engine.EnsureFileCacheDir()
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		binds = append(binds, fileCacheDir+":"+containerFileCacheDir)
 	} else {
 		logger.Tracef("file cache disabled")
 	}
-	binds = append(binds, parseDirMounts(options.DirMounts)...)
+	if options.Tls.KeystorePath != "" {
+		logger.Tracef("mounting TLS keystore: %s", options.Tls.KeystorePath)
+		binds = append(binds, options.Tls.KeystorePath+":"+getContainerKeystorePath(options)+":ro")
+	}
+	dirMounts, err := parseDirMounts(options.DirMounts)
+	if err != nil {
+		return nil, err
+	}
+	binds = append(binds, dirMounts...)
 	logger.Tracef("using binds: %v", binds)
-	return binds
+	return binds, nil
 }
 
 // parseDirMounts validates the directory mounts, generating
 // the container path if not provided
-func parseDirMounts(dirMounts []string) []string {
+func parseDirMounts(dirMounts []string) ([]string, error) {
 	var binds []string
 	for _, mountSpec := range dirMounts {
 		var hostDir string
@@ -197,14 +358,14 @@ func parseDirMounts(dirMounts []string) []string {
 
 		hostDirInfo, err := os.Stat(hostDir)
 		if err != nil {
-			logger.Fatalf("failed to stat host dir: %s", hostDir)
+			return nil, fmt.Errorf("failed to stat host dir: %s", hostDir)
 		}
 		if !hostDirInfo.IsDir() {
-			logger.Fatalf("host path: %s is not a directory", hostDir)
+			return nil, fmt.Errorf("host path: %s is not a directory", hostDir)
 		}
 		binds = append(binds, mountSpec)
 	}
-	return binds
+	return binds, nil
 }
 
 func generateMetadata(d *DockerMockEngine, options engine.StartOptions) (string, map[string]string) {
@@ -226,8 +387,9 @@ func generateMetadata(d *DockerMockEngine, options engine.StartOptions) (string,
 	return mockHash, containerLabels
 }
 
-func streamLogsToStdIo(cli *client.Client, ctx context.Context, containerId string) error {
-	return streamLogs(cli, ctx, containerId, os.Stdout, os.Stderr)
+func streamLogsToStdIo(cli *client.Client, ctx context.Context, containerId string, options engine.StartOptions, logTail *engine.LogTail) error {
+	stdout, stderr := engine.BuildOutputWriters(options, logTail)
+	return streamLogs(cli, ctx, containerId, stdout, stderr)
 }
 
 func streamLogs(cli *client.Client, ctx context.Context, containerId string, outStream io.Writer, errStream io.Writer) error {
@@ -249,7 +411,8 @@ func streamLogs(cli *client.Client, ctx context.Context, containerId string, out
 
 func buildCliClient() (context.Context, *client.Client, error) {
 	ctx := context.Background()
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	opts := append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, buildTlsOpts()...)
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -267,12 +430,29 @@ func (d *DockerMockEngine) Stop(wg *sync.WaitGroup) {
 		wg.Done()
 		return
 	}
+	if err := d.stopContainer(wg); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func (d *DockerMockEngine) StopEmbedded(wg *sync.WaitGroup) error {
+	if len(d.containerId) == 0 {
+		logger.Tracef("no container ID to remove")
+		return nil
+	}
+	return d.stopContainer(wg)
+}
+
+// stopContainer drains in-flight requests, then removes the container.
+func (d *DockerMockEngine) stopContainer(wg *sync.WaitGroup) error {
 	if logger.IsLevelEnabled(logrus.TraceLevel) {
 		logger.Tracef("stopping mock engine container %v", d.containerId)
 	} else {
 		logger.Info("stopping mock engine")
 	}
 
+	engine.DrainRequests(d.options.Port, d.options.Tls.Enabled, d.options.DrainTimeout)
+
 	oldContainerId := d.containerId
 
 	// supervisor to work-around removal race
@@ -282,7 +462,7 @@ func (d *DockerMockEngine) Stop(wg *sync.WaitGroup) {
 		d.debouncer.Notify(wg, debounce.AtMostOnceEvent{Id: oldContainerId})
 	}()
 
-	removeContainer(d, wg, oldContainerId)
+	return removeContainer(d, wg, oldContainerId)
 }
 
 func (d *DockerMockEngine) Restart(wg *sync.WaitGroup) {
@@ -322,7 +502,18 @@ func (d *DockerMockEngine) StopAllManaged() int {
 	labels := map[string]string{
 		labelKeyManaged: "true",
 	}
-	return stopContainersWithLabels(d, ctx, cli, labels)
+	stopped, err := stopContainersWithLabels(d, ctx, cli, labels)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return stopped
+}
+
+func (d *DockerMockEngine) StopManaged(ids []string) int {
+	if err := removeContainers(d, ids); err != nil {
+		logger.Fatal(err)
+	}
+	return len(ids)
 }
 
 func (d *DockerMockEngine) GetVersionString() (string, error) {
@@ -17,11 +17,16 @@ limitations under the License.
 package docker
 
 import (
+	"errors"
 	"gatehill.io/imposter/engine"
 	"gatehill.io/imposter/engine/enginetests"
+	"gatehill.io/imposter/failure"
+	"github.com/docker/docker/api/types/network"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -107,3 +112,120 @@ func TestEngine_List(t *testing.T) {
 	}
 	enginetests.List(t, tests, engineBuilder)
 }
+
+func Test_buildNetworkingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		options engine.StartOptions
+		want    *network.NetworkingConfig
+	}{
+		{
+			name:    "no network",
+			options: engine.StartOptions{ContainerName: "petstore"},
+			want:    nil,
+		},
+		{
+			name:    "network without name",
+			options: engine.StartOptions{Network: "test-net"},
+			want: &network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					"test-net": {},
+				},
+			},
+		},
+		{
+			name:    "network with name as alias",
+			options: engine.StartOptions{Network: "test-net", ContainerName: "petstore"},
+			want: &network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					"test-net": {Aliases: []string{"petstore"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildNetworkingConfig(tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildNetworkingConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveReadOnly(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        engine.StartOptions
+		configReadOnly bool
+		copyFiles      bool
+		want           bool
+		wantErr        bool
+	}{
+		{name: "writable by default", want: false},
+		{name: "read-only option", options: engine.StartOptions{ReadOnly: true}, want: true},
+		{name: "read-only configuration", configReadOnly: true, want: true},
+		{name: "copy files to writable container", copyFiles: true, want: false},
+		{name: "copy files to read-only container", options: engine.StartOptions{ReadOnly: true}, copyFiles: true, wantErr: true},
+		{name: "copy files to container read-only by configuration", configReadOnly: true, copyFiles: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.readOnly", tt.configReadOnly)
+			t.Cleanup(func() {
+				viper.Set("docker.readOnly", nil)
+			})
+			got, err := resolveReadOnly(tt.options, tt.copyFiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveReadOnly() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var typed *failure.Error
+				if !errors.As(err, &typed) || typed.Code != failure.CodeEngineStartFailed {
+					t.Errorf("resolveReadOnly() error = %v, want code %v", err, failure.CodeEngineStartFailed)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolveReadOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildTmpfs(t *testing.T) {
+	if got := buildTmpfs(false); got != nil {
+		t.Errorf("buildTmpfs(false) = %v, want nil", got)
+	}
+	if got, want := buildTmpfs(true), map[string]string{"/tmp": ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("buildTmpfs(true) = %v, want %v", got, want)
+	}
+}
+
+func Test_getCapDrop(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       engine.StartOptions
+		configCapDrop []string
+		want          []string
+	}{
+		{name: "none", want: nil},
+		{name: "from options", options: engine.StartOptions{CapDrop: []string{"ALL"}}, want: []string{"ALL"}},
+		{name: "from configuration", configCapDrop: []string{"NET_RAW", "SYS_ADMIN"}, want: []string{"NET_RAW", "SYS_ADMIN"}},
+		{name: "options take precedence", options: engine.StartOptions{CapDrop: []string{"ALL"}}, configCapDrop: []string{"NET_RAW"}, want: []string{"ALL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.capDrop", tt.configCapDrop)
+			t.Cleanup(func() {
+				viper.Set("docker.capDrop", nil)
+			})
+			got := getCapDrop(tt.options)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getCapDrop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,18 +18,24 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/lifecycle"
+	"gatehill.io/imposter/stringutil"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"io"
 	"io/ioutil"
-	"os"
 )
 
 type EngineImageProvider struct {
 	engine.EngineMetadata
 	imageAndTag string
+
+	// imageOverride replaces the default image for the engine type, if set
+	imageOverride string
 }
 
 func getProvider(engineType engine.EngineType, version string) *EngineImageProvider {
@@ -41,15 +47,30 @@ func getProvider(engineType engine.EngineType, version string) *EngineImageProvi
 	}
 }
 
+func getProviderWithImage(engineType engine.EngineType, version string, image string) *EngineImageProvider {
+	provider := getProvider(engineType, version)
+	provider.imageOverride = image
+	return provider
+}
+
 func (d *EngineImageProvider) Provide(policy engine.PullPolicy) error {
 	ctx, cli, err := buildCliClient()
 	if err != nil {
 		return err
 	}
-	imageAndTag, err := ensureContainerImage(cli, ctx, d.EngineType, d.Version, policy)
+	imageAndTag, err := getImageAndTag(d.EngineType, d.imageOverride, d.Version)
+	if err != nil {
+		return err
+	}
+	imageAndTag, err = ensureContainerImage(cli, ctx, imageAndTag, policy)
 	if err != nil {
 		return err
 	}
+	if policy != engine.PullSkip {
+		if err = verifyImage(cli, ctx, imageAndTag); err != nil {
+			return err
+		}
+	}
 	d.imageAndTag = imageAndTag
 	return nil
 }
@@ -65,12 +86,9 @@ func (d *EngineImageProvider) GetEngineType() engine.EngineType {
 func ensureContainerImage(
 	cli *client.Client,
 	ctx context.Context,
-	engineType engine.EngineType,
-	imageTag string,
+	imageAndTag string,
 	imagePullPolicy engine.PullPolicy,
-) (imageAndTag string, e error) {
-	imageAndTag = getImageRepo(engineType) + ":" + imageTag
-
+) (string, error) {
 	if imagePullPolicy == engine.PullSkip {
 		return imageAndTag, nil
 	}
@@ -86,28 +104,35 @@ func ensureContainerImage(
 			}
 		}
 		if hasImage {
-			logger.Debugf("engine image '%v' already present", imageTag)
+			logger.Debugf("engine image '%v' already present", imageAndTag)
 			return imageAndTag, nil
 		}
 	}
 
-	err := pullImage(cli, ctx, imageTag, imageAndTag)
+	err := pullImage(cli, ctx, imageAndTag)
 	if err != nil {
 		return "", err
 	}
 	return imageAndTag, nil
 }
 
-func pullImage(cli *client.Client, ctx context.Context, imageTag string, imageAndTag string) error {
-	logger.Infof("pulling '%v' engine image", imageTag)
-	reader, err := cli.ImagePull(ctx, "docker.io/"+imageAndTag, types.ImagePullOptions{})
+func pullImage(cli *client.Client, ctx context.Context, imageAndTag string) error {
+	logger.Infof("pulling '%v' engine image", imageAndTag)
+	lifecycle.Emit(lifecycle.Event{Type: lifecycle.EventPulling, Message: "pulling engine image " + imageAndTag})
+	registryAuth, err := getRegistryAuth(imageAndTag)
+	if err != nil {
+		logger.Warnf("failed to get registry credentials - pulling without credentials: %v", err)
+	}
+	reader, err := cli.ImagePull(ctx, qualifyImage(imageAndTag), types.ImagePullOptions{
+		RegistryAuth: registryAuth,
+	})
 	if err != nil {
 		return err
 	}
 
 	var pullLogDestination io.Writer
 	if logger.IsLevelEnabled(logrus.TraceLevel) {
-		pullLogDestination = os.Stdout
+		pullLogDestination = lifecycle.ConsoleOutput()
 	} else {
 		pullLogDestination = ioutil.Discard
 	}
@@ -118,7 +143,44 @@ func pullImage(cli *client.Client, ctx context.Context, imageTag string, imageAn
 	return nil
 }
 
-func getImageRepo(engineType engine.EngineType) string {
+// getImageAndTag returns the image reference for the engine type and version.
+// If an image override is set, either explicitly or in the CLI configuration,
+// it replaces the default image. If the override includes a tag or digest,
+// the version is ignored. If the version is a digest, the image is pinned
+// to it, so an override with a different tag or digest is an error.
+func getImageAndTag(engineType engine.EngineType, imageOverride string, version string) (string, error) {
+	separator := ":"
+	if engine.IsImageDigest(version) {
+		separator = "@"
+	}
+	image := getConfiguredImage(imageOverride)
+	if _, tag := splitImageRepoAndTag(image); tag != "" {
+		if separator == "@" && tag != separator+version {
+			return "", fmt.Errorf("image %s includes a tag or digest, so it cannot be pinned to digest %s - remove the tag from the image, or set the digest in the image instead of the version", image, version)
+		}
+		return image, nil
+	}
+	return getImageRepo(engineType, imageOverride) + separator + version, nil
+}
+
+// getImageRepo returns the image repository for the engine type. The
+// image override, if set, either explicitly or in the CLI configuration,
+// replaces the default repository.
+func getImageRepo(engineType engine.EngineType, imageOverride string) string {
+	if image := getConfiguredImage(imageOverride); image != "" {
+		repo, _ := splitImageRepoAndTag(image)
+		return repo
+	}
+	return getDefaultImageRepo(engineType)
+}
+
+// getConfiguredImage returns the image override, if set, otherwise
+// the image in the CLI configuration, which may be empty.
+func getConfiguredImage(imageOverride string) string {
+	return stringutil.GetFirstNonEmpty(imageOverride, viper.GetString("docker.image"))
+}
+
+func getDefaultImageRepo(engineType engine.EngineType) string {
 	var imageRepo string
 	switch engineType {
 	case engine.EngineTypeDockerCore:
@@ -133,5 +195,5 @@ func getImageRepo(engineType engine.EngineType) string {
 	default:
 		panic("Unsupported engine type: " + engineType)
 	}
-	return imageRepo
+	return applyRegistryMirror(imageRepo)
 }
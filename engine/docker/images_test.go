@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"gatehill.io/imposter/engine"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func Test_getImageAndTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		imageOverride string
+		configImage   string
+		version       string
+		want          string
+		wantErr       bool
+	}{
+		{name: "default image", version: "4.2.0", want: "outofcoffee/imposter:4.2.0"},
+		{name: "override without tag", imageOverride: "ghcr.io/acme/imposter", version: "4.2.0", want: "ghcr.io/acme/imposter:4.2.0"},
+		{name: "override with tag ignores version", imageOverride: "ghcr.io/acme/imposter:1.0", version: "4.2.0", want: "ghcr.io/acme/imposter:1.0"},
+		{name: "configured image", configImage: "ghcr.io/acme/imposter", version: "4.2.0", want: "ghcr.io/acme/imposter:4.2.0"},
+		{name: "override without tag pinned to digest", imageOverride: "ghcr.io/acme/imposter", version: testDigest, want: "ghcr.io/acme/imposter@" + testDigest},
+		{name: "override with same digest", imageOverride: "ghcr.io/acme/imposter@" + testDigest, version: testDigest, want: "ghcr.io/acme/imposter@" + testDigest},
+		{name: "override with tag and digest version", imageOverride: "ghcr.io/acme/imposter:1.0", version: testDigest, wantErr: true},
+		{name: "configured image with tag and digest version", configImage: "ghcr.io/acme/imposter:1.0", version: testDigest, wantErr: true},
+		{name: "override with different digest", imageOverride: "ghcr.io/acme/imposter@sha256:abc", version: testDigest, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.image", tt.configImage)
+			t.Cleanup(func() {
+				viper.Set("docker.image", nil)
+			})
+			got, err := getImageAndTag(engine.EngineTypeDockerCore, tt.imageOverride, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getImageAndTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getImageAndTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getImageRepo(t *testing.T) {
+	tests := []struct {
+		name          string
+		imageOverride string
+		configImage   string
+		want          string
+	}{
+		{name: "default image", want: "outofcoffee/imposter"},
+		{name: "override", imageOverride: "ghcr.io/acme/imposter:1.0", want: "ghcr.io/acme/imposter"},
+		{name: "override with digest", imageOverride: "ghcr.io/acme/imposter@" + testDigest, want: "ghcr.io/acme/imposter"},
+		{name: "configured image", configImage: "registry.example.com:5000/imposter:1.0", want: "registry.example.com:5000/imposter"},
+		{name: "override takes precedence over configured image", imageOverride: "ghcr.io/acme/imposter", configImage: "registry.example.com/imposter", want: "ghcr.io/acme/imposter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.image", tt.configImage)
+			t.Cleanup(func() {
+				viper.Set("docker.image", nil)
+			})
+			if got := getImageRepo(engine.EngineTypeDockerCore, tt.imageOverride); got != tt.want {
+				t.Errorf("getImageRepo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -45,7 +45,7 @@ func (l DockerEngineLibrary) List() ([]engine.EngineMetadata, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error building CLI client: %s", err)
 	}
-	imageRepo := getImageRepo(l.engineType)
+	imageRepo := getImageRepo(l.engineType, "")
 	var available []engine.EngineMetadata
 	imageSummaries, err := cli.ImageList(ctx, types.ImageListOptions{
 		Filters: filters.NewArgs(filters.Arg("reference", imageRepo+":*")),
@@ -54,16 +54,46 @@ func (l DockerEngineLibrary) List() ([]engine.EngineMetadata, error) {
 		return nil, fmt.Errorf("error listing images: %s", err)
 	}
 	for _, imageSummary := range imageSummaries {
-		for _, tag := range imageSummary.RepoTags {
+		for _, repoTag := range imageSummary.RepoTags {
+			_, tag := splitImageRepoAndTag(repoTag)
 			available = append(available, engine.EngineMetadata{
-				EngineType: engine.EngineTypeDockerCore,
-				Version:    strings.Split(tag, ":")[1],
+				EngineType: l.engineType,
+				Version:    strings.TrimPrefix(tag, ":"),
+				Size:       imageSummary.Size,
 			})
 		}
 	}
 	return available, nil
 }
 
+// Remove deletes the image for the version, unless it is used by a
+// container. Space is only reclaimed if no other tag refers to the image.
+func (l DockerEngineLibrary) Remove(version string) (int64, error) {
+	ctx, cli, err := buildCliClient()
+	if err != nil {
+		return 0, fmt.Errorf("error building CLI client: %s", err)
+	}
+	imageAndTag, err := getImageAndTag(l.engineType, "", version)
+	if err != nil {
+		return 0, err
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, imageAndTag)
+	if err != nil {
+		return 0, fmt.Errorf("error inspecting image: %s: %s", imageAndTag, err)
+	}
+	deleted, err := cli.ImageRemove(ctx, imageAndTag, types.ImageRemoveOptions{PruneChildren: true})
+	if err != nil {
+		return 0, fmt.Errorf("error removing image: %s: %s", imageAndTag, err)
+	}
+	logger.Debugf("removed engine image: %s", imageAndTag)
+	for _, item := range deleted {
+		if item.Deleted != "" {
+			return inspect.Size, nil
+		}
+	}
+	return 0, nil
+}
+
 func (l DockerEngineLibrary) GetProvider(version string) engine.Provider {
 	return getProvider(l.engineType, version)
 }
@@ -55,7 +55,7 @@ func buildEngine(engineType engine.EngineType, configDir string, options engine.
 	return &DockerMockEngine{
 		configDir: configDir,
 		options:   options,
-		provider:  getProvider(engineType, options.Version),
+		provider:  getProviderWithImage(engineType, options.Version, options.Image),
 		debouncer: debounce.Build(),
 		shutDownC: make(chan bool),
 	}
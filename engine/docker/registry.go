@@ -0,0 +1,232 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/spf13/viper"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const defaultRegistry = "docker.io"
+
+// dockerHubAuthKey is the key used for Docker Hub credentials in the
+// local Docker config file.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+type credentialHelperOutput struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+// splitImageRepoAndTag splits an image reference into its repository and
+// tag or digest, including the separator. The tag is empty if not set.
+func splitImageRepoAndTag(image string) (repo string, tag string) {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		return image[:idx], image[idx:]
+	}
+	lastSlash := strings.LastIndex(image, "/")
+	if idx := strings.LastIndex(image, ":"); idx > lastSlash {
+		return image[:idx], image[idx:]
+	}
+	return image, ""
+}
+
+// getRegistryHost returns the registry host for the given image reference,
+// defaulting to Docker Hub if the reference does not include a registry.
+func getRegistryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return defaultRegistry
+}
+
+// qualifyImage prefixes the image reference with the default registry,
+// if it does not already specify a registry.
+func qualifyImage(image string) string {
+	if getRegistryHost(image) == defaultRegistry && !strings.HasPrefix(image, defaultRegistry+"/") {
+		return defaultRegistry + "/" + image
+	}
+	return image
+}
+
+// applyRegistryMirror prefixes an image hosted on Docker Hub with the
+// registry mirror set by the 'docker.registryMirror' configuration key,
+// such as 'artifactory.example.com/dockerhub', if set.
+func applyRegistryMirror(image string) string {
+	mirror := strings.TrimSuffix(viper.GetString("docker.registryMirror"), "/")
+	if mirror == "" || getRegistryHost(image) != defaultRegistry {
+		return image
+	}
+	return mirror + "/" + strings.TrimPrefix(image, defaultRegistry+"/")
+}
+
+// getRegistryAuth returns the encoded credentials for the registry hosting
+// the given image, from the local Docker config file. If no credentials are
+// configured, an empty string is returned.
+func getRegistryAuth(image string) (string, error) {
+	host := getRegistryHost(image)
+	cfg, err := loadDockerConfig()
+	if err != nil || cfg == nil {
+		return "", err
+	}
+
+	authKey := host
+	if host == defaultRegistry {
+		authKey = dockerHubAuthKey
+	}
+
+	var authConfig *registry.AuthConfig
+	if helper := getCredentialHelper(cfg, authKey); helper != "" {
+		authConfig, err = getHelperCredentials(helper, authKey)
+		if err != nil {
+			return "", err
+		}
+	} else if auth, found := findAuth(cfg, authKey); found {
+		authConfig, err = decodeAuth(auth)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode credentials for registry %s: %v", host, err)
+		}
+	}
+	if authConfig == nil {
+		logger.Tracef("no credentials found for registry %s", host)
+		return "", nil
+	}
+	authConfig.ServerAddress = authKey
+	logger.Tracef("using credentials for registry %s", host)
+	return registry.EncodeAuthConfig(*authConfig)
+}
+
+// getDockerConfigDir returns the directory holding the local Docker
+// configuration, set by DOCKER_CONFIG, or '$HOME/.docker' by default.
+func getDockerConfigDir() (string, error) {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return configDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".docker"), nil
+}
+
+func loadDockerConfig() (*dockerConfigFile, error) {
+	configDir, err := getDockerConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	configFile := filepath.Join(configDir, "config.json")
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Tracef("no Docker config file found at %s", configFile)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read Docker config file: %s: %v", configFile, err)
+	}
+	var cfg dockerConfigFile
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse Docker config file: %s: %v", configFile, err)
+	}
+	return &cfg, nil
+}
+
+func getCredentialHelper(cfg *dockerConfigFile, authKey string) string {
+	if helper := cfg.CredHelpers[authKey]; helper != "" {
+		return helper
+	}
+	return cfg.CredsStore
+}
+
+// findAuth looks up the credentials for the registry, tolerating
+// keys with or without a scheme.
+func findAuth(cfg *dockerConfigFile, authKey string) (dockerConfigAuth, bool) {
+	for key, auth := range cfg.Auths {
+		normalised := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/")
+		if key == authKey || normalised == authKey {
+			return auth, true
+		}
+	}
+	return dockerConfigAuth{}, false
+}
+
+func decodeAuth(auth dockerConfigAuth) (*registry.AuthConfig, error) {
+	authConfig := &registry.AuthConfig{
+		IdentityToken: auth.IdentityToken,
+	}
+	if auth.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, err
+		}
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid auth value")
+		}
+		authConfig.Username = username
+		authConfig.Password = password
+	}
+	return authConfig, nil
+}
+
+// getHelperCredentials invokes the Docker credential helper to obtain
+// the credentials for the registry.
+func getHelperCredentials(helper string, authKey string) (*registry.AuthConfig, error) {
+	helperCmd := "docker-credential-" + helper
+	logger.Tracef("getting credentials for %s from %s", authKey, helperCmd)
+
+	command := exec.Command(helperCmd, "get")
+	command.Stdin = strings.NewReader(authKey)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, "credentials not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get credentials from %s: %v: %s", helperCmd, err, output)
+	}
+
+	var creds credentialHelperOutput
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials from %s: %v", helperCmd, err)
+	}
+	if creds.Username == "<token>" {
+		return &registry.AuthConfig{IdentityToken: creds.Secret}, nil
+	}
+	return &registry.AuthConfig{Username: creds.Username, Password: creds.Secret}, nil
+}
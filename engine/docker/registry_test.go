@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"encoding/base64"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func Test_splitImageRepoAndTag(t *testing.T) {
+	tests := []struct {
+		image    string
+		wantRepo string
+		wantTag  string
+	}{
+		{image: "outofcoffee/imposter", wantRepo: "outofcoffee/imposter", wantTag: ""},
+		{image: "outofcoffee/imposter:3.0.0", wantRepo: "outofcoffee/imposter", wantTag: ":3.0.0"},
+		{image: "localhost:5000/imposter", wantRepo: "localhost:5000/imposter", wantTag: ""},
+		{image: "localhost:5000/imposter:latest", wantRepo: "localhost:5000/imposter", wantTag: ":latest"},
+		{image: "ghcr.io/acme/imposter@sha256:abc", wantRepo: "ghcr.io/acme/imposter", wantTag: "@sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			gotRepo, gotTag := splitImageRepoAndTag(tt.image)
+			if gotRepo != tt.wantRepo || gotTag != tt.wantTag {
+				t.Errorf("splitImageRepoAndTag() = %v, %v, want %v, %v", gotRepo, gotTag, tt.wantRepo, tt.wantTag)
+			}
+		})
+	}
+}
+
+func Test_qualifyImage(t *testing.T) {
+	tests := []struct {
+		image        string
+		wantRegistry string
+		want         string
+	}{
+		{image: "outofcoffee/imposter:3.0.0", wantRegistry: "docker.io", want: "docker.io/outofcoffee/imposter:3.0.0"},
+		{image: "docker.io/outofcoffee/imposter", wantRegistry: "docker.io", want: "docker.io/outofcoffee/imposter"},
+		{image: "ghcr.io/acme/imposter:1.0", wantRegistry: "ghcr.io", want: "ghcr.io/acme/imposter:1.0"},
+		{image: "localhost:5000/imposter", wantRegistry: "localhost:5000", want: "localhost:5000/imposter"},
+		{image: "localhost/imposter", wantRegistry: "localhost", want: "localhost/imposter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := getRegistryHost(tt.image); got != tt.wantRegistry {
+				t.Errorf("getRegistryHost() = %v, want %v", got, tt.wantRegistry)
+			}
+			if got := qualifyImage(tt.image); got != tt.want {
+				t.Errorf("qualifyImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyRegistryMirror(t *testing.T) {
+	tests := []struct {
+		name   string
+		mirror string
+		image  string
+		want   string
+	}{
+		{name: "no mirror", image: "outofcoffee/imposter", want: "outofcoffee/imposter"},
+		{name: "docker hub image", mirror: "artifactory.example.com/dockerhub/", image: "outofcoffee/imposter", want: "artifactory.example.com/dockerhub/outofcoffee/imposter"},
+		{name: "qualified docker hub image", mirror: "mirror.example.com", image: "docker.io/outofcoffee/imposter", want: "mirror.example.com/outofcoffee/imposter"},
+		{name: "other registry", mirror: "mirror.example.com", image: "ghcr.io/acme/imposter", want: "ghcr.io/acme/imposter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.registryMirror", tt.mirror)
+			t.Cleanup(func() {
+				viper.Set("docker.registryMirror", nil)
+			})
+			if got := applyRegistryMirror(tt.image); got != tt.want {
+				t.Errorf("applyRegistryMirror() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findAuth(t *testing.T) {
+	cfg := &dockerConfigFile{
+		Auths: map[string]dockerConfigAuth{
+			"https://index.docker.io/v1/": {Auth: base64.StdEncoding.EncodeToString([]byte("hub:secret"))},
+			"https://ghcr.io":             {Auth: base64.StdEncoding.EncodeToString([]byte("gh:token"))},
+		},
+	}
+	tests := []struct {
+		authKey      string
+		wantFound    bool
+		wantUsername string
+		wantPassword string
+	}{
+		{authKey: dockerHubAuthKey, wantFound: true, wantUsername: "hub", wantPassword: "secret"},
+		{authKey: "ghcr.io", wantFound: true, wantUsername: "gh", wantPassword: "token"},
+		{authKey: "quay.io", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.authKey, func(t *testing.T) {
+			auth, found := findAuth(cfg, tt.authKey)
+			if found != tt.wantFound {
+				t.Fatalf("findAuth() found = %v, want %v", found, tt.wantFound)
+			}
+			if !found {
+				return
+			}
+			authConfig, err := decodeAuth(auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authConfig.Username != tt.wantUsername || authConfig.Password != tt.wantPassword {
+				t.Errorf("decodeAuth() = %v:%v, want %v:%v", authConfig.Username, authConfig.Password, tt.wantUsername, tt.wantPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/plugin"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/spf13/viper"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// buildTlsOpts returns the client options for connecting to a daemon
+// requiring TLS. The Docker client only reads certificates from
+// DOCKER_CERT_PATH, so, like the Docker CLI, the certificates in the
+// Docker config directory are used if only DOCKER_TLS_VERIFY is set.
+func buildTlsOpts() []client.Opt {
+	if os.Getenv(client.EnvTLSVerify) == "" || os.Getenv(client.EnvOverrideCertPath) != "" {
+		return nil
+	}
+	certDir, err := getDockerConfigDir()
+	if err != nil {
+		logger.Warnf("failed to determine Docker certificate directory: %v", err)
+		return nil
+	}
+	caFile := filepath.Join(certDir, "ca.pem")
+	if _, err := os.Stat(caFile); err != nil {
+		logger.Tracef("no Docker CA certificate found at %s", caFile)
+		return nil
+	}
+	logger.Tracef("using Docker TLS certificates in %s", certDir)
+	return []client.Opt{
+		client.WithTLSClientConfig(caFile, filepath.Join(certDir, "cert.pem"), filepath.Join(certDir, "key.pem")),
+	}
+}
+
+// getRemoteDaemonHost returns the hostname of the Docker daemon, if it
+// runs on another machine, such as one set by DOCKER_HOST=tcp://host:2376.
+// If the daemon is local, the empty string is returned.
+func getRemoteDaemonHost(daemonHost string) string {
+	u, err := url.Parse(daemonHost)
+	if err != nil {
+		logger.Tracef("failed to parse Docker daemon host: %s: %v", daemonHost, err)
+		return ""
+	}
+	switch u.Scheme {
+	case "unix", "npipe", "fd":
+		return ""
+	}
+	hostname := u.Hostname()
+	if hostname == "" || hostname == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(hostname); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return ""
+	}
+	return hostname
+}
+
+// resolveConfigMountMode returns the mode requested, falling back to
+// the 'docker.configMountMode' configuration key. If neither is set,
+// files are copied if the daemon is remote, as it cannot bind-mount
+// paths on this machine.
+func resolveConfigMountMode(mode engine.ConfigMountMode, remoteHost string) engine.ConfigMountMode {
+	if mode == "" {
+		configured, err := engine.ParseConfigMountMode(viper.GetString("docker.configMountMode"))
+		if err != nil {
+			logger.Warn(err)
+		}
+		mode = configured
+	}
+	if mode == "" {
+		if remoteHost != "" {
+			logger.Debugf("Docker daemon at %s is remote - files will be copied to the container", remoteHost)
+			return engine.ConfigMountModeCopy
+		}
+		return engine.ConfigMountModeBind
+	}
+	return mode
+}
+
+// buildCopyPaths returns the host paths that would otherwise be
+// bind-mounted into the container, keyed by their container paths.
+func buildCopyPaths(d *DockerMockEngine, options engine.StartOptions) (map[string]string, error) {
+	paths := map[string]string{
+		containerConfigDir: d.configDir,
+	}
+	if options.EnablePlugins {
+		pluginDir, err := plugin.EnsurePluginDir(options.Version)
+		if err != nil {
+			return nil, err
+		}
+		paths[containerPluginDir] = pluginDir
+	}
+	if options.Tls.KeystorePath != "" {
+		paths[getContainerKeystorePath(options)] = options.Tls.KeystorePath
+	}
+	dirMounts, err := parseDirMounts(options.DirMounts)
+	if err != nil {
+		return nil, err
+	}
+	for _, mountSpec := range dirMounts {
+		splitSpec := strings.SplitN(mountSpec, ":", 2)
+		paths[splitSpec[1]] = splitSpec[0]
+	}
+	return paths, nil
+}
+
+// copyToContainer copies the host paths into the container, instead of
+// bind-mounting them, such as for daemons on another machine.
+func copyToContainer(cli *client.Client, ctx context.Context, containerId string, paths map[string]string) error {
+	archive, err := buildCopyArchive(paths)
+	if err != nil {
+		return err
+	}
+	if err := cli.CopyToContainer(ctx, containerId, "/", archive, types.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("error copying files to container with ID: %v: %v", containerId, err)
+	}
+	logger.Tracef("copied %d path(s) to container with ID: %v", len(paths), containerId)
+	return nil
+}
+
+// buildCopyArchive returns a tar archive of the host paths, keyed by
+// their container paths, to be extracted at the root of the container.
+func buildCopyArchive(paths map[string]string) (io.Reader, error) {
+	var containerPaths []string
+	for containerPath := range paths {
+		containerPaths = append(containerPaths, containerPath)
+	}
+	sort.Strings(containerPaths)
+
+	buf := new(bytes.Buffer)
+	writer := tar.NewWriter(buf)
+	for _, containerPath := range containerPaths {
+		hostPath := paths[containerPath]
+		err := filepath.Walk(hostPath, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				logger.Tracef("skipping copy of non-regular file: %s", filePath)
+				return nil
+			}
+			relPath, err := filepath.Rel(hostPath, filePath)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = strings.TrimPrefix(path.Join(containerPath, filepath.ToSlash(relPath)), "/")
+
+			// the container user is unlikely to match the owner on this machine
+			header.Uid, header.Gid = 0, 0
+			header.Uname, header.Gname = "", ""
+			if info.IsDir() {
+				header.Name += "/"
+				header.Mode |= 0555
+			} else {
+				header.Mode |= 0444
+			}
+			if err := writer.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			file, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(writer, file)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error archiving %s for copy to container: %v", hostPath, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
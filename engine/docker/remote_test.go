@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"archive/tar"
+	"gatehill.io/imposter/engine"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_getRemoteDaemonHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		daemonHost string
+		want       string
+	}{
+		{name: "unix socket", daemonHost: "unix:///var/run/docker.sock", want: ""},
+		{name: "named pipe", daemonHost: "npipe:////./pipe/docker_engine", want: ""},
+		{name: "tcp localhost", daemonHost: "tcp://localhost:2375", want: ""},
+		{name: "tcp loopback", daemonHost: "tcp://127.0.0.1:2375", want: ""},
+		{name: "tcp remote", daemonHost: "tcp://docker.example.com:2376", want: "docker.example.com"},
+		{name: "tcp remote ip", daemonHost: "tcp://10.0.0.5:2376", want: "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getRemoteDaemonHost(tt.daemonHost); got != tt.want {
+				t.Errorf("getRemoteDaemonHost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveConfigMountMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       engine.ConfigMountMode
+		configured string
+		remoteHost string
+		want       engine.ConfigMountMode
+	}{
+		{name: "default local", want: engine.ConfigMountModeBind},
+		{name: "default remote", remoteHost: "docker.example.com", want: engine.ConfigMountModeCopy},
+		{name: "explicit copy", mode: engine.ConfigMountModeCopy, want: engine.ConfigMountModeCopy},
+		{name: "explicit bind remote", mode: engine.ConfigMountModeBind, remoteHost: "docker.example.com", want: engine.ConfigMountModeBind},
+		{name: "configured copy", configured: "copy", want: engine.ConfigMountModeCopy},
+		{name: "explicit overrides configured", mode: engine.ConfigMountModeBind, configured: "copy", want: engine.ConfigMountModeBind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("docker.configMountMode", tt.configured)
+			t.Cleanup(func() {
+				viper.Set("docker.configMountMode", nil)
+			})
+			if got := resolveConfigMountMode(tt.mode, tt.remoteHost); got != tt.want {
+				t.Errorf("resolveConfigMountMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildCopyArchive(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "responses"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "test-config.yaml"), []byte("plugin: rest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "responses", "pet.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	keystore := filepath.Join(t.TempDir(), "keystore.p12")
+	if err := os.WriteFile(keystore, []byte("keystore"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := buildCopyArchive(map[string]string{
+		containerConfigDir:                configDir,
+		containerTlsDir + "/keystore.p12": keystore,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if header.Uid != 0 || header.Mode&0444 != 0444 {
+			t.Errorf("buildCopyArchive() entry %s should be readable and owned by root, got uid %d mode %o", header.Name, header.Uid, header.Mode)
+		}
+	}
+	want := []string{
+		"opt/imposter/config/",
+		"opt/imposter/config/responses/",
+		"opt/imposter/config/responses/pet.json",
+		"opt/imposter/config/test-config.yaml",
+		"opt/imposter/tls/keystore.p12",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("buildCopyArchive() entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("buildCopyArchive() entry %d = %v, want %v", i, names[i], want[i])
+		}
+	}
+}
@@ -18,6 +18,7 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"gatehill.io/imposter/debounce"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -25,21 +26,28 @@ import (
 	"sync"
 )
 
-func removeContainers(d *DockerMockEngine, containerIds []string) {
+func removeContainers(d *DockerMockEngine, containerIds []string) error {
 	logger.Tracef("removing containers: %v", containerIds)
 	wg := &sync.WaitGroup{}
 
 	for _, containerId := range containerIds {
 		d.debouncer.Register(wg, containerId)
-		removeContainer(d, wg, containerId)
+		if err := removeContainer(d, wg, containerId); err != nil {
+			return err
+		}
 	}
 	wg.Wait()
+	return nil
 }
 
-func removeContainer(d *DockerMockEngine, wg *sync.WaitGroup, containerId string) {
+// removeContainer removes the container, notifying the debouncer once
+// it has stopped. An error is returned if the Docker daemon cannot be
+// reached.
+func removeContainer(d *DockerMockEngine, wg *sync.WaitGroup, containerId string) error {
 	ctx, cli, err := buildCliClient()
 	if err != nil {
-		logger.Fatal(err)
+		d.debouncer.Notify(wg, debounce.AtMostOnceEvent{Id: containerId, Err: err})
+		return err
 	}
 
 	// check it exists
@@ -51,7 +59,7 @@ func removeContainer(d *DockerMockEngine, wg *sync.WaitGroup, containerId string
 		} else {
 			d.debouncer.Notify(wg, debounce.AtMostOnceEvent{Id: containerId})
 		}
-		return
+		return nil
 	}
 
 	err = cli.ContainerRemove(ctx, containerId, types.ContainerRemoveOptions{Force: true})
@@ -62,10 +70,11 @@ func removeContainer(d *DockerMockEngine, wg *sync.WaitGroup, containerId string
 		} else {
 			d.debouncer.Notify(wg, debounce.AtMostOnceEvent{Id: containerId})
 		}
-		return
+		return nil
 	}
 
 	notifyOnStopBlocking(d, wg, containerId, cli, ctx)
+	return nil
 }
 
 func notifyOnStopBlocking(d *DockerMockEngine, wg *sync.WaitGroup, containerId string, cli *client.Client, ctx context.Context) {
@@ -86,18 +95,19 @@ func notifyOnStopBlocking(d *DockerMockEngine, wg *sync.WaitGroup, containerId s
 	}
 }
 
-func stopDuplicateContainers(d *DockerMockEngine, cli *client.Client, ctx context.Context, mockHash string) {
-	stopContainersWithLabels(d, cli, ctx, map[string]string{labelKeyHash: mockHash})
+func stopDuplicateContainers(d *DockerMockEngine, cli *client.Client, ctx context.Context, mockHash string) error {
+	_, err := stopContainersWithLabels(d, cli, ctx, map[string]string{labelKeyHash: mockHash})
+	return err
 }
 
-func stopContainersWithLabels(d *DockerMockEngine, cli *client.Client, ctx context.Context, containerLabels map[string]string) int {
+func stopContainersWithLabels(d *DockerMockEngine, cli *client.Client, ctx context.Context, containerLabels map[string]string) (int, error) {
 	containers, err := findContainersWithLabels(cli, ctx, containerLabels)
 	if err != nil {
-		logger.Fatalf("error searching for existing containers: %v", err)
+		return 0, fmt.Errorf("error searching for existing containers: %v", err)
 	}
 	if len(containers) == 0 {
 		logger.Tracef("no existing containers found matching labels: %v", containerLabels)
-		return 0
+		return 0, nil
 	}
 
 	logger.Debugf("stopping %d existing container(s)", len(containers))
@@ -105,6 +115,8 @@ func stopContainersWithLabels(d *DockerMockEngine, cli *client.Client, ctx conte
 	for _, mock := range containers {
 		containerIds = append(containerIds, mock.ID)
 	}
-	removeContainers(d, containerIds)
-	return len(containers)
+	if err := removeContainers(d, containerIds); err != nil {
+		return 0, err
+	}
+	return len(containers), nil
 }
@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/spf13/viper"
+	"os/exec"
+	"strings"
+)
+
+// getImageDigest returns the digest in the image reference, such as
+// 'sha256:<digest>', or the empty string if it is not pinned by digest.
+func getImageDigest(imageRef string) string {
+	if _, tag := splitImageRepoAndTag(imageRef); strings.HasPrefix(tag, "@") {
+		return strings.TrimPrefix(tag, "@")
+	}
+	return ""
+}
+
+// matchesDigest returns true if any of the repository digests of an
+// image, in the form 'repo@sha256:<digest>', has the given digest.
+func matchesDigest(repoDigests []string, digest string) bool {
+	for _, repoDigest := range repoDigests {
+		if _, d := splitImageRepoAndTag(repoDigest); d == "@"+digest {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyImage checks that an image pinned by digest matches the digest,
+// and verifies its signature, if signature verification is configured.
+func verifyImage(cli *client.Client, ctx context.Context, imageRef string) error {
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, imageRef)
+	if err != nil {
+		return fmt.Errorf("failed to inspect image: %s: %v", imageRef, err)
+	}
+	if digest := getImageDigest(imageRef); digest != "" {
+		if !matchesDigest(inspect.RepoDigests, digest) {
+			return fmt.Errorf("image %s does not match pinned digest %s - found: %s", imageRef, digest, strings.Join(inspect.RepoDigests, ", "))
+		}
+		logger.Debugf("verified image %s matches digest", imageRef)
+	}
+
+	args := buildCosignArgs(imageRef, inspect.RepoDigests)
+	if args == nil {
+		return nil
+	}
+	logger.Debugf("verifying signature of image: %s", imageRef)
+	output, err := exec.Command("cosign", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to verify signature of image: %s: %v: %s", imageRef, err, strings.TrimSpace(string(output)))
+	}
+	logger.Infof("verified signature of image: %s", imageRef)
+	return nil
+}
+
+// buildCosignArgs returns the arguments for cosign to verify the signature
+// of the image, or nil if signature verification is not configured. The
+// signature is verified for the digest of the image that was pulled, so a
+// tag cannot be moved between verification and use.
+func buildCosignArgs(imageRef string, repoDigests []string) []string {
+	key := viper.GetString("docker.cosign.key")
+	identity := viper.GetString("docker.cosign.identity")
+	if key == "" && identity == "" {
+		return nil
+	}
+	args := []string{"verify"}
+	if key != "" {
+		args = append(args, "--key", key)
+	} else {
+		args = append(args,
+			"--certificate-identity", identity,
+			"--certificate-oidc-issuer", viper.GetString("docker.cosign.issuer"),
+		)
+	}
+	verifyRef := imageRef
+	repo, _ := splitImageRepoAndTag(imageRef)
+	for _, repoDigest := range repoDigests {
+		if digestRepo, d := splitImageRepoAndTag(repoDigest); strings.TrimPrefix(digestRepo, defaultRegistry+"/") == strings.TrimPrefix(repo, defaultRegistry+"/") {
+			verifyRef = repo + d
+			break
+		}
+	}
+	return append(args, verifyRef)
+}
@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"gatehill.io/imposter/engine"
+	"github.com/spf13/viper"
+	"reflect"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func Test_getImageAndTag_digest(t *testing.T) {
+	got, err := getImageAndTag(engine.EngineTypeDockerCore, "", testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "outofcoffee/imposter@" + testDigest; got != want {
+		t.Errorf("getImageAndTag() = %v, want %v", got, want)
+	}
+	if digest := getImageDigest(got); digest != testDigest {
+		t.Errorf("getImageDigest() = %v, want %v", digest, testDigest)
+	}
+	if digest := getImageDigest("outofcoffee/imposter:4.2.0"); digest != "" {
+		t.Errorf("getImageDigest() = %v, want empty", digest)
+	}
+}
+
+func Test_matchesDigest(t *testing.T) {
+	repoDigests := []string{"outofcoffee/imposter@" + testDigest}
+	if !matchesDigest(repoDigests, testDigest) {
+		t.Errorf("expected digest to match")
+	}
+	if matchesDigest(repoDigests, "sha256:abc") {
+		t.Errorf("expected different digest not to match")
+	}
+	if matchesDigest(nil, testDigest) {
+		t.Errorf("expected no repo digests not to match")
+	}
+}
+
+func Test_buildCosignArgs(t *testing.T) {
+	repoDigests := []string{"outofcoffee/imposter@" + testDigest}
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []string
+	}{
+		{name: "not configured", want: nil},
+		{name: "public key", config: map[string]string{"docker.cosign.key": "cosign.pub"}, want: []string{
+			"verify", "--key", "cosign.pub", "outofcoffee/imposter@" + testDigest,
+		}},
+		{name: "keyless", config: map[string]string{
+			"docker.cosign.identity": "release@example.com",
+			"docker.cosign.issuer":   "https://accounts.example.com",
+		}, want: []string{
+			"verify", "--certificate-identity", "release@example.com",
+			"--certificate-oidc-issuer", "https://accounts.example.com", "outofcoffee/imposter@" + testDigest,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.config {
+				viper.Set(k, v)
+			}
+			t.Cleanup(func() {
+				for k := range tt.config {
+					viper.Set(k, nil)
+				}
+			})
+			if got := buildCosignArgs("outofcoffee/imposter:4.2.0", repoDigests); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildCosignArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// activeRequestsMetric is the engine metric reporting the number of
+// HTTP requests currently being processed.
+const activeRequestsMetric = "vertx_http_server_active_requests"
+
+const drainPollInterval = 250 * time.Millisecond
+
+// DrainRequests waits for in-flight requests to the mock on the specified port
+// to complete, by polling the engine's metrics endpoint. It returns once no
+// requests are active, the metrics are unavailable, or the timeout elapses.
+// A timeout of zero disables draining.
+func DrainRequests(port int, tls bool, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	logger.Debugf("draining in-flight requests to mock on port %d (timeout: %v)", port, timeout)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		active, err := getActiveRequests(port, tls)
+		if err != nil {
+			logger.Debugf("unable to determine in-flight requests - skipping drain: %v", err)
+			return
+		}
+		if active == 0 {
+			logger.Tracef("no in-flight requests to mock on port %d", port)
+			return
+		}
+		if time.Now().After(deadline) {
+			logger.Warnf("timed out draining mock on port %d with %d request(s) still in flight", port, active)
+			return
+		}
+		logger.Tracef("waiting for %d in-flight request(s) to mock on port %d", active, port)
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// getActiveRequests fetches the engine metrics and returns the number
+// of requests currently in flight.
+func getActiveRequests(port int, tls bool) (int, error) {
+	samples, err := FetchMetrics(port, tls)
+	if err != nil {
+		return 0, err
+	}
+	active, found := SumMetric(samples, activeRequestsMetric)
+	if !found {
+		return 0, fmt.Errorf("metric %s not found", activeRequestsMetric)
+	}
+	return int(active), nil
+}
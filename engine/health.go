@@ -17,19 +17,31 @@ limitations under the License.
 package engine
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/spf13/viper"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 )
 
-const defaultStartTimeout = 30 * time.Second
+const DefaultStartTimeout = 30 * time.Second
+
+// mockClient is used to call the mock engine's system endpoints. Certificate
+// verification is skipped, as the mock commonly serves a self-signed certificate.
+var mockClient = &http.Client{
+	Timeout: 2 * time.Second,
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
 
 func getStartTimeout() time.Duration {
 	startTimeout := viper.GetInt("startTimeout")
 	if startTimeout == 0 {
-		return defaultStartTimeout
+		return DefaultStartTimeout
 	}
 	return time.Duration(startTimeout) * time.Second
 }
@@ -49,10 +61,7 @@ func IsMockUp(port int) (success bool) {
 func CheckMockStatus(port int) error {
 	url := getStatusUrl(port)
 	logger.Tracef("checking mock engine at %v", url)
-	client := http.Client{
-		Timeout: 2 * time.Second,
-	}
-	resp, err := client.Get(url)
+	resp, err := mockClient.Get(url)
 	if err != nil {
 		return fmt.Errorf("healthcheck request failed for mock at %s: %s", url, err)
 	}
@@ -67,18 +76,36 @@ func CheckMockStatus(port int) error {
 	return fmt.Errorf("healthcheck status was %d for mock at %s: %s", resp.StatusCode, url, err)
 }
 
-func WaitUntilUp(port int, shutDownC chan bool) (success bool) {
-	url := getStatusUrl(port)
+func WaitUntilUp(port int, tls bool, shutDownC chan bool) (success bool) {
+	url := getMockBaseUrl(port, tls) + "/system/status"
 	return WaitForUrl(fmt.Sprintf("status endpoint to return HTTP 200 at %v", url), url, shutDownC)
 }
 
 func getStatusUrl(port int) string {
-	return fmt.Sprintf("http://localhost:%d/system/status", port)
+	return getMockBaseUrl(port, false) + "/system/status"
+}
+
+// getMockBaseUrl returns the base URL of the mock on the specified port.
+func getMockBaseUrl(port int, tls bool) string {
+	return getMockBaseUrlOnHost("", port, tls)
+}
+
+// getMockBaseUrlOnHost returns the base URL of the mock on the specified
+// host and port. If host is empty, localhost is used.
+func getMockBaseUrlOnHost(host string, port int, tls bool) string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
 }
 
 func WaitForUrl(desc string, url string, abortC chan bool) (success bool) {
 	return WaitForOp(desc, getStartTimeout(), abortC, func() bool {
-		resp, err := http.Get(url)
+		resp, err := mockClient.Get(url)
 		if err != nil {
 			return false
 		}
@@ -17,9 +17,12 @@ limitations under the License.
 package jvm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"gatehill.io/imposter/debounce"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/plugin"
 	"github.com/sirupsen/logrus"
@@ -35,44 +38,88 @@ func (j *JvmMockEngine) Start(wg *sync.WaitGroup) bool {
 	return j.startWithOptions(wg, j.options)
 }
 
+func (j *JvmMockEngine) StartEmbedded(ctx context.Context, wg *sync.WaitGroup) error {
+	options := j.options
+	options.Embedded = true
+	return engine.StartWithContext(ctx, j.shutDownC, func() (bool, error) {
+		return j.startProcess(wg, options)
+	})
+}
+
 func (j *JvmMockEngine) startWithOptions(wg *sync.WaitGroup, options engine.StartOptions) (success bool) {
+	success, err := j.startProcess(wg, options)
+	if err != nil {
+		failure.Fatal(err)
+	}
+	return success
+}
+
+// startProcess starts the mock engine process, returning whether it
+// became ready, or an error if it could not be started.
+func (j *JvmMockEngine) startProcess(wg *sync.WaitGroup, options engine.StartOptions) (success bool, err error) {
 	if len(options.DirMounts) > 0 {
 		logger.Warnf("JVM engine does not support directory mounts - these will be ignored")
 	}
+	if options.Network != "" || options.ContainerName != "" {
+		logger.Warnf("JVM engine does not support container name or network - these will be ignored")
+	}
+	if options.ContainerUser != "" || options.ReadOnly || len(options.CapDrop) > 0 {
+		logger.Warnf("JVM engine does not support container user, read-only filesystem or capabilities - these will be ignored")
+	}
+	if options.Memory != 0 || options.Cpus != 0 {
+		logger.Warnf("JVM engine does not support memory or CPU limits - these will be ignored")
+	}
 
 	args := []string{
 		"--configDir=" + j.configDir,
 		fmt.Sprintf("--listenPort=%d", options.Port),
 	}
-	env := buildEnv(options)
-	command := (*j.provider).GetStartCommand(args, env)
-	command.Stdout = os.Stdout
-	command.Stderr = os.Stderr
-	err := command.Start()
+	args = append(args, engine.BuildTlsArgs(options.Tls, options.Tls.KeystorePath)...)
+	env, err := buildEnv(options)
+	if err != nil {
+		return false, failure.Wrap(failure.CodeEngineStartFailed, err)
+	}
+	command, err := (*j.provider).GetStartCommand(buildJvmArgs(options), args, env)
 	if err != nil {
-		logger.Fatalf("failed to exec: %v %v: %v", command.Path, command.Args, err)
+		return false, err
+	}
+	logTail := engine.NewLogTail()
+	command.Stdout, command.Stderr = engine.BuildOutputWriters(options, logTail)
+	if err := command.Start(); err != nil {
+		return false, failure.New(failure.CodeEngineStartFailed, "failed to exec: %v %v: %v", command.Path, command.Args, err)
 	}
 	j.debouncer.Register(wg, strconv.Itoa(command.Process.Pid))
 	logger.Trace("starting JVM mock engine")
 	j.command = command
 
-	up := engine.WaitUntilUp(options.Port, j.shutDownC)
-
-	// watch in case container stops
+	// watch in case process stops
+	exitedC := make(chan string, 1)
 	go func() {
-		j.notifyOnStopBlocking(wg)
+		j.notifyOnStopBlocking(wg, exitedC)
 	}()
 
-	return up
+	return engine.WaitUntilReady(options, exitedC, logTail, j.shutDownC), nil
+}
+
+// buildJvmArgs returns the arguments passed to the JVM, such as
+// the maximum heap size, before the engine arguments.
+func buildJvmArgs(options engine.StartOptions) []string {
+	var jvmArgs []string
+	if options.Heap != "" {
+		jvmArgs = append(jvmArgs, "-Xmx"+options.Heap)
+	}
+	jvmArgs = append(jvmArgs, options.JvmArgs...)
+	logger.Tracef("JVM arguments: %v", jvmArgs)
+	return jvmArgs
 }
 
-func buildEnv(options engine.StartOptions) []string {
+func buildEnv(options engine.StartOptions) ([]string, error) {
 	env := engine.BuildEnv(options, true)
 	if options.EnablePlugins {
 		logger.Tracef("plugins are enabled")
 		pluginDir, err := plugin.EnsurePluginDir(options.Version)
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		env = append(env, "IMPOSTER_PLUGIN_DIR="+pluginDir)
 	} else {
@@ -82,14 +129,14 @@ func buildEnv(options engine.StartOptions) []string {
 		logger.Tracef("file cache enabled")
 		fileCacheDir, err := engine.EnsureFileCacheDir()
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		env = append(env, "IMPOSTER_CACHE_DIR="+fileCacheDir, "IMPOSTER_OPENAPI_REMOTE_FILE_CACHE=true")
 	} else {
 		logger.Tracef("file cache disabled")
 	}
 	logger.Tracef("engine environment: %v", env)
-	return env
+	return env, nil
 }
 
 func (j *JvmMockEngine) StopImmediately(wg *sync.WaitGroup) {
@@ -103,17 +150,36 @@ func (j *JvmMockEngine) Stop(wg *sync.WaitGroup) {
 		wg.Done()
 		return
 	}
+	if err := j.stopProcess(wg); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func (j *JvmMockEngine) StopEmbedded(wg *sync.WaitGroup) error {
+	if j.command == nil {
+		logger.Tracef("no process to remove")
+		return nil
+	}
+	return j.stopProcess(wg)
+}
+
+// stopProcess drains in-flight requests, then kills the process.
+func (j *JvmMockEngine) stopProcess(wg *sync.WaitGroup) error {
 	if logger.IsLevelEnabled(logrus.TraceLevel) {
 		logger.Tracef("stopping mock engine with PID: %v", j.command.Process.Pid)
 	} else {
 		logger.Info("stopping mock engine")
 	}
 
+	engine.DrainRequests(j.options.Port, j.options.Tls.Enabled, j.options.DrainTimeout)
+
+	// the process may already have exited, such as if it failed to start
 	err := j.command.Process.Kill()
-	if err != nil {
-		logger.Fatalf("error stopping engine with PID: %d: %v", j.command.Process.Pid, err)
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("error stopping engine with PID: %d: %v", j.command.Process.Pid, err)
 	}
-	j.notifyOnStopBlocking(wg)
+	j.notifyOnStopBlocking(wg, nil)
+	return nil
 }
 
 func (j *JvmMockEngine) Restart(wg *sync.WaitGroup) {
@@ -128,7 +194,9 @@ func (j *JvmMockEngine) Restart(wg *sync.WaitGroup) {
 	wg.Done()
 }
 
-func (j *JvmMockEngine) notifyOnStopBlocking(wg *sync.WaitGroup) {
+// notifyOnStopBlocking waits for the process to exit, then notifies the
+// debouncer. If exitedC is not nil, it receives a description of the exit.
+func (j *JvmMockEngine) notifyOnStopBlocking(wg *sync.WaitGroup, exitedC chan<- string) {
 	if j.command == nil || j.command.Process == nil {
 		logger.Trace("no subprocess - notifying immediately")
 		j.debouncer.Notify(wg, debounce.AtMostOnceEvent{})
@@ -138,7 +206,14 @@ func (j *JvmMockEngine) notifyOnStopBlocking(wg *sync.WaitGroup) {
 		logger.Tracef("process with PID: %v already exited - notifying immediately", pid)
 		j.debouncer.Notify(wg, debounce.AtMostOnceEvent{Id: pid})
 	}
-	_, err := j.command.Process.Wait()
+	state, err := j.command.Process.Wait()
+	if exitedC != nil {
+		if err != nil {
+			exitedC <- err.Error()
+		} else {
+			exitedC <- fmt.Sprintf("process %s", state)
+		}
+	}
 	if err != nil {
 		j.debouncer.Notify(wg, debounce.AtMostOnceEvent{
 			Id:  pid,
@@ -162,11 +237,22 @@ func (j *JvmMockEngine) StopAllManaged() int {
 	if err != nil {
 		logger.Fatal(err)
 	}
-	if len(processes) == 0 {
-		return 0
-	}
+	var pids []string
 	for _, proc := range processes {
-		pid, err := strconv.Atoi(proc.ID)
+		pids = append(pids, proc.ID)
+	}
+	return killProcesses(pids)
+}
+
+func (j *JvmMockEngine) StopManaged(ids []string) int {
+	return killProcesses(ids)
+}
+
+// killProcesses kills the JVM processes with the given PIDs, returning
+// the number of processes.
+func killProcesses(pids []string) int {
+	for _, id := range pids {
+		pid, err := strconv.Atoi(id)
 		if err != nil {
 			logger.Fatal(err)
 		}
@@ -181,7 +267,7 @@ func (j *JvmMockEngine) StopAllManaged() int {
 			logger.Warnf("error killing JVM process with PID: %d: %v", pid, err)
 		}
 	}
-	return len(processes)
+	return len(pids)
 }
 
 func (j *JvmMockEngine) GetVersionString() (string, error) {
@@ -197,10 +283,13 @@ func (j *JvmMockEngine) GetVersionString() (string, error) {
 	args := []string{
 		"--version",
 	}
-	command := (*j.provider).GetStartCommand(args, engine.BuildEnv(j.options, true))
+	command, err := (*j.provider).GetStartCommand(nil, args, engine.BuildEnv(j.options, true))
+	if err != nil {
+		return "", err
+	}
 	command.Stdout = output
 	command.Stderr = errOutput
-	err := command.Run()
+	err = command.Run()
 
 	if err != nil {
 		return "", fmt.Errorf("error starting mock engine process: %v\n%v\n%v", err, output, errOutput)
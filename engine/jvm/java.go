@@ -1,7 +1,10 @@
 package jvm
 
 import (
+	"errors"
 	"fmt"
+	"gatehill.io/imposter/failure"
+	"github.com/spf13/viper"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,14 +13,9 @@ import (
 )
 
 // GetJavaCmdPath finds the best candidate for the 'java' command, searching
-// the environment as well as using well-known OS-specific mechanisms.
+// the environment as well as using well-known OS-specific mechanisms. If no
+// Java installation is found, a JRE managed by the CLI is used.
 func GetJavaCmdPath() (string, error) {
-	// search for 'java' in the PATH
-	javaPath, err := exec.LookPath("java")
-	if err != nil {
-		logger.Tracef("could not find 'java' in PATH: %s", err)
-	}
-
 	var binaryPathSuffix string
 	if runtime.GOOS == "Windows" {
 		binaryPathSuffix = ".exe"
@@ -25,6 +23,22 @@ func GetJavaCmdPath() (string, error) {
 		binaryPathSuffix = ""
 	}
 
+	// an explicit Java home takes precedence over the environment
+	if javaHome := viper.GetString("jvm.javaHome"); javaHome != "" {
+		javaPath := filepath.Join(javaHome, "/bin/java"+binaryPathSuffix)
+		if _, err := os.Stat(javaPath); err != nil {
+			return "", failure.New(failure.CodeEngineJavaNotFound, "failed to find java in configured Java home: %s: %s", javaHome, err)
+		}
+		logger.Tracef("using java from configured Java home: %v", javaPath)
+		return javaPath, nil
+	}
+
+	// search for 'java' in the PATH
+	javaPath, err := exec.LookPath("java")
+	if err != nil {
+		logger.Tracef("could not find 'java' in PATH: %s", err)
+	}
+
 	if javaPath == "" {
 		// check JAVA_HOME environment variable
 		if javaHomeEnv, found := os.LookupEnv("JAVA_HOME"); found {
@@ -32,22 +46,34 @@ func GetJavaCmdPath() (string, error) {
 		}
 	}
 
+	var lookupErr error
 	if javaPath == "" && runtime.GOOS == "darwin" {
 		command, stdout := exec.Command("/usr/libexec/java_home"), new(strings.Builder)
 		command.Stdout = stdout
 		err := command.Run()
 		if err != nil {
-			return "", fmt.Errorf("error determining JAVA_HOME: %s", err)
-		}
-		if command.ProcessState.Success() {
+			lookupErr = fmt.Errorf("error determining JAVA_HOME: %s", err)
+		} else if command.ProcessState.Success() {
 			javaPath = filepath.Join(strings.TrimSpace(stdout.String()), "/bin/java"+binaryPathSuffix)
 		} else {
-			return "", fmt.Errorf("failed to determine JAVA_HOME using libexec")
+			lookupErr = fmt.Errorf("failed to determine JAVA_HOME using libexec")
 		}
 	}
 
 	if javaPath == "" {
-		return "", fmt.Errorf("failed to determine Java path - consider setting JAVA_HOME or updating PATH")
+		// fall back to a JRE managed by the CLI
+		managedJavaPath, err := findManagedJava()
+		if err != nil {
+			if !errors.Is(err, errNoManagedJre) {
+				return "", failure.Wrap(failure.CodeEngineJavaNotFound, err)
+			}
+			logger.Tracef("no managed JRE available: %s", err)
+			if lookupErr != nil {
+				return "", failure.Wrap(failure.CodeEngineJavaNotFound, lookupErr)
+			}
+			return "", failure.New(failure.CodeEngineJavaNotFound, "failed to determine Java path - consider setting JAVA_HOME, updating PATH, or setting jvm.downloadJre to download a JRE")
+		}
+		javaPath = managedJavaPath
 	}
 
 	logger.Tracef("using java: %v", javaPath)
@@ -0,0 +1,399 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jvm
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatehill.io/imposter/library"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const jreCacheDir = ".imposter/jre/"
+const defaultJreApiUrl = "https://api.adoptium.net"
+
+// defaultJreRelease is the Temurin release downloaded, unless overridden.
+// It is pinned, rather than using the latest release, so the same JRE is
+// downloaded each time.
+const defaultJreRelease = "jdk-17.0.13+11"
+
+// jreReleaseUrlTemplate follows the Adoptium assets API, in the form:
+// {base}/v3/assets/release_name/eclipse/{release}?{filters}
+const jreReleaseUrlTemplate = "%s/v3/assets/release_name/eclipse/%s?%s"
+
+// jreRelease is the subset of the Adoptium assets API response used to
+// download a JRE.
+type jreRelease struct {
+	Binaries []struct {
+		Os           string `json:"os"`
+		Architecture string `json:"architecture"`
+		ImageType    string `json:"image_type"`
+		Package      struct {
+			Link     string `json:"link"`
+			Checksum string `json:"checksum"`
+		} `json:"package"`
+	} `json:"binaries"`
+}
+
+// errNoManagedJre indicates that no JRE is cached, and one was not
+// downloaded, as downloads are disabled or the user declined.
+var errNoManagedJre = errors.New("no cached JRE found")
+
+// findManagedJava returns the path to the 'java' command of the JRE in the
+// CLI cache, downloading it if it is not present and downloads are enabled,
+// or the user accepts the download when prompted.
+// Once downloaded, the JRE is reused without network access.
+func findManagedJava() (string, error) {
+	jreDir, err := getJreDir()
+	if err != nil {
+		return "", err
+	}
+	if javaPath, found := findJavaBinary(jreDir); found {
+		logger.Tracef("found cached JRE: %s", jreDir)
+		return javaPath, nil
+	}
+	if !viper.GetBool("jvm.downloadJre") && !(isInteractive() && confirmJreDownload(os.Stdin, os.Stderr)) {
+		return "", fmt.Errorf("%w at: %s - set jvm.downloadJre to download one", errNoManagedJre, jreDir)
+	}
+	if err := downloadJre(jreDir); err != nil {
+		return "", err
+	}
+	if javaPath, found := findJavaBinary(jreDir); found {
+		return javaPath, nil
+	}
+	return "", fmt.Errorf("no java binary found in downloaded JRE: %s", jreDir)
+}
+
+// isInteractive returns true if stdin is a terminal, so the user
+// can be prompted.
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmJreDownload asks the user whether to download a JRE,
+// returning true if they accept.
+func confirmJreDownload(in io.Reader, out io.Writer) bool {
+	_, _ = fmt.Fprintf(out, "No Java installation was found. Download the Temurin JRE %s to the Imposter cache? [y/N] ", getJreRelease())
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// getJreDir returns the directory holding the JRE for the configured
+// release and the current platform.
+func getJreDir() (string, error) {
+	cacheDir, err := library.EnsureDirUsingConfig("jvm.jreCache", jreCacheDir)
+	if err != nil {
+		return "", err
+	}
+	jreOs, jreArch, err := getJrePlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, fmt.Sprintf("%s-%s-%s", getJreRelease(), jreOs, jreArch)), nil
+}
+
+func getJreRelease() string {
+	if release := viper.GetString("jvm.jreRelease"); release != "" {
+		return release
+	}
+	return defaultJreRelease
+}
+
+// getJrePlatform maps the Go OS and architecture to the names used
+// by the JRE download API.
+func getJrePlatform(goos string, goarch string) (jreOs string, jreArch string, err error) {
+	switch goos {
+	case "linux", "windows":
+		jreOs = goos
+	case "darwin":
+		jreOs = "mac"
+	default:
+		return "", "", fmt.Errorf("no JRE available for OS: %s", goos)
+	}
+	switch goarch {
+	case "amd64":
+		jreArch = "x64"
+	case "arm64":
+		jreArch = "aarch64"
+	default:
+		return "", "", fmt.Errorf("no JRE available for architecture: %s", goarch)
+	}
+	return jreOs, jreArch, nil
+}
+
+func buildJreReleaseUrl(apiUrl string, release string, jreOs string, jreArch string) string {
+	filters := url.Values{
+		"architecture": {jreArch},
+		"heap_size":    {"normal"},
+		"image_type":   {"jre"},
+		"jvm_impl":     {"hotspot"},
+		"os":           {jreOs},
+		"project":      {"jdk"},
+	}
+	return fmt.Sprintf(jreReleaseUrlTemplate, strings.TrimSuffix(apiUrl, "/"), url.PathEscape(release), filters.Encode())
+}
+
+// fetchJrePackage returns the download link and published SHA-256
+// checksum of the JRE package for the release and platform.
+func fetchJrePackage(apiUrl string, release string, jreOs string, jreArch string) (link string, checksum string, err error) {
+	releaseUrl := buildJreReleaseUrl(apiUrl, release, jreOs, jreArch)
+	logger.Debugf("fetching JRE release details from %v", releaseUrl)
+	resp, err := http.Get(releaseUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("error fetching JRE release details from: %v: %v", releaseUrl, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", "", fmt.Errorf("error fetching JRE release details from: %v: status code: %d", releaseUrl, resp.StatusCode)
+	}
+	var details jreRelease
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&details); err != nil {
+		return "", "", fmt.Errorf("error parsing JRE release details from: %v: %v", releaseUrl, err)
+	}
+	for _, binary := range details.Binaries {
+		if binary.Os != jreOs || binary.Architecture != jreArch || binary.ImageType != "jre" {
+			continue
+		}
+		checksum = strings.ToLower(binary.Package.Checksum)
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return "", "", fmt.Errorf("invalid checksum for JRE release %s: %s", release, binary.Package.Checksum)
+		}
+		if binary.Package.Link == "" {
+			return "", "", fmt.Errorf("no download link for JRE release %s", release)
+		}
+		return binary.Package.Link, checksum, nil
+	}
+	return "", "", fmt.Errorf("no JRE found in release %s for %s/%s", release, jreOs, jreArch)
+}
+
+// findJavaBinary searches the JRE directory for the 'java' command. The
+// archive usually contains a single top-level directory, and on macOS the
+// JRE is nested within a bundle.
+func findJavaBinary(jreDir string) (string, bool) {
+	binaryName := "java"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	candidates := []string{
+		filepath.Join(jreDir, "bin", binaryName),
+		filepath.Join(jreDir, "*", "bin", binaryName),
+		filepath.Join(jreDir, "*", "Contents", "Home", "bin", binaryName),
+	}
+	for _, candidate := range candidates {
+		matches, _ := filepath.Glob(candidate)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				return match, true
+			}
+		}
+	}
+	return "", false
+}
+
+// downloadJre downloads the JRE archive and extracts it to the JRE
+// directory. The archive is verified against the SHA-256 checksum
+// published by the API, then extracted to a temporary directory first,
+// so an interrupted download does not leave a partial JRE in the cache.
+func downloadJre(jreDir string) error {
+	jreOs, jreArch, err := getJrePlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	apiUrl := viper.GetString("jvm.jreApiUrl")
+	if apiUrl == "" {
+		apiUrl = defaultJreApiUrl
+	}
+	release := getJreRelease()
+	link, checksum, err := fetchJrePackage(apiUrl, release, jreOs, jreArch)
+	if err != nil {
+		return err
+	}
+	logger.Infof("downloading JRE %s for %s/%s", release, jreOs, jreArch)
+	logger.Debugf("downloading %v", link)
+
+	resp, err := http.Get(link)
+	if err != nil {
+		return fmt.Errorf("error downloading JRE from: %v: %v", link, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error downloading JRE from: %v: status code: %d", link, resp.StatusCode)
+	}
+
+	archive, err := os.CreateTemp(filepath.Dir(jreDir), "jre-*.download")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %v", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, hash), resp.Body); err != nil {
+		return fmt.Errorf("error downloading JRE from: %v: %v", link, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != checksum {
+		return fmt.Errorf("checksum mismatch for JRE from: %v: expected %s but was %s", link, checksum, actual)
+	}
+	logger.Debugf("verified checksum of: %v", link)
+
+	tempDir, err := os.MkdirTemp(filepath.Dir(jreDir), "jre-*.extract")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if jreOs == "windows" {
+		err = extractZip(archive.Name(), tempDir)
+	} else {
+		err = extractTarGz(archive.Name(), tempDir)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tempDir, jreDir); err != nil {
+		return fmt.Errorf("error moving JRE to: %s: %v", jreDir, err)
+	}
+	logger.Infof("installed JRE to: %s", jreDir)
+	return nil
+}
+
+// resolveArchivePath returns the path within the destination directory
+// for the archive entry, rejecting entries that would escape it.
+func resolveArchivePath(destDir string, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	if target != filepath.Clean(destDir) && !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
+
+func extractTarGz(archivePath string, destDir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening archive: %s: %v", archivePath, err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+		}
+		target, err := resolveArchivePath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, reader, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if _, err := resolveArchivePath(destDir, filepath.Join(filepath.Dir(header.Name), header.Linkname)); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			logger.Tracef("skipping archive entry: %s", header.Name)
+		}
+	}
+	return nil
+}
+
+func extractZip(archivePath string, destDir string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		target, err := resolveArchivePath(destDir, entry.Name)
+		if err != nil {
+			return err
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		src, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("error reading archive entry: %s: %v", entry.Name, err)
+		}
+		err = writeArchiveFile(target, src, entry.Mode())
+		_ = src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeArchiveFile(target string, src io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	dest, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("error creating file: %s: %v", target, err)
+	}
+	defer dest.Close()
+	if _, err := io.Copy(dest, src); err != nil {
+		return fmt.Errorf("error writing file: %s: %v", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package jvm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_getJrePlatform(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		goarch   string
+		wantOs   string
+		wantArch string
+		wantErr  bool
+	}{
+		{name: "linux amd64", goos: "linux", goarch: "amd64", wantOs: "linux", wantArch: "x64"},
+		{name: "darwin arm64", goos: "darwin", goarch: "arm64", wantOs: "mac", wantArch: "aarch64"},
+		{name: "windows amd64", goos: "windows", goarch: "amd64", wantOs: "windows", wantArch: "x64"},
+		{name: "unsupported os", goos: "plan9", goarch: "amd64", wantErr: true},
+		{name: "unsupported arch", goos: "linux", goarch: "mips", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOs, gotArch, err := getJrePlatform(tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getJrePlatform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotOs != tt.wantOs || gotArch != tt.wantArch {
+				t.Errorf("getJrePlatform() = %v/%v, want %v/%v", gotOs, gotArch, tt.wantOs, tt.wantArch)
+			}
+		})
+	}
+}
+
+func Test_buildJreReleaseUrl(t *testing.T) {
+	got := buildJreReleaseUrl("https://api.example.com/", "jdk-17.0.13+11", "linux", "x64")
+	want := "https://api.example.com/v3/assets/release_name/eclipse/jdk-17.0.13+11?architecture=x64&heap_size=normal&image_type=jre&jvm_impl=hotspot&os=linux&project=jdk"
+	if got != want {
+		t.Errorf("buildJreReleaseUrl() = %v, want %v", got, want)
+	}
+}
+
+func Test_downloadJre(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test archive is a tarball")
+	}
+	jreOs, jreArch, err := getJrePlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		t.Skip(err)
+	}
+	archive, err := os.ReadFile(writeTestTarGz(t, map[string]string{"jdk-17-jre/bin/java": "#!/bin/sh"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive)
+	validChecksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		checksum  string
+		wantErr   bool
+		wantFound bool
+	}{
+		{name: "valid checksum", checksum: validChecksum, wantFound: true},
+		{name: "checksum mismatch", checksum: strings.Repeat("0", sha256.Size*2), wantErr: true},
+		{name: "invalid checksum", checksum: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v3/assets/release_name/eclipse/" + defaultJreRelease:
+					_, _ = fmt.Fprintf(w, `{"binaries":[{"os":%q,"architecture":%q,"image_type":"jre","package":{"link":%q,"checksum":%q}}]}`,
+						jreOs, jreArch, server.URL+"/jre.tar.gz", tt.checksum)
+				case "/jre.tar.gz":
+					_, _ = w.Write(archive)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			viper.Set("jvm.jreApiUrl", server.URL)
+			defer viper.Set("jvm.jreApiUrl", "")
+
+			jreDir := filepath.Join(t.TempDir(), "jre")
+			err := downloadJre(jreDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadJre() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, found := findJavaBinary(jreDir); found != tt.wantFound {
+				t.Errorf("findJavaBinary() found = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_confirmJreDownload(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "accept", input: "y\n", want: true},
+		{name: "accept long form", input: "Yes\n", want: true},
+		{name: "decline", input: "n\n", want: false},
+		{name: "default", input: "\n", want: false},
+		{name: "no input", input: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := confirmJreDownload(strings.NewReader(tt.input), io.Discard); got != tt.want {
+				t.Errorf("confirmJreDownload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_extractTarGz(t *testing.T) {
+	binaryName := "java"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	tests := []struct {
+		name      string
+		entries   map[string]string
+		wantErr   bool
+		wantFound bool
+	}{
+		{
+			name:      "nested JRE",
+			entries:   map[string]string{"jdk-17-jre/bin/" + binaryName: "#!/bin/sh"},
+			wantFound: true,
+		},
+		{
+			name:      "macOS bundle",
+			entries:   map[string]string{"jdk-17-jre/Contents/Home/bin/" + binaryName: "#!/bin/sh"},
+			wantFound: true,
+		},
+		{
+			name:    "path traversal",
+			entries: map[string]string{"../evil": "x"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := writeTestTarGz(t, tt.entries)
+			destDir := t.TempDir()
+			err := extractTarGz(archivePath, destDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, found := findJavaBinary(destDir); found != tt.wantFound {
+				t.Errorf("findJavaBinary() found = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func writeTestTarGz(t *testing.T, entries map[string]string) string {
+	archivePath := filepath.Join(t.TempDir(), "jre.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	writer := tar.NewWriter(gz)
+	for name, content := range entries {
+		header := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
@@ -5,6 +5,7 @@ import (
 	"gatehill.io/imposter/engine"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -47,14 +48,36 @@ func (JvmEngineLibrary) List() ([]engine.EngineMetadata, error) {
 			continue
 		}
 		fileVersion := strings.Split(strings.TrimSuffix(file.Name(), ".jar"), "-")[1]
+		var size int64
+		if info, err := file.Info(); err == nil {
+			size = info.Size()
+		}
 		available = append(available, engine.EngineMetadata{
 			EngineType: engine.EngineTypeJvmSingleJar,
 			Version:    fileVersion,
+			Size:       size,
 		})
 	}
 	return available, nil
 }
 
+func (JvmEngineLibrary) Remove(version string) (int64, error) {
+	binCachePath, err := ensureBinCache()
+	if err != nil {
+		return 0, err
+	}
+	binFilePath := filepath.Join(binCachePath, fmt.Sprintf("imposter-%v.jar", version))
+	info, err := os.Stat(binFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat: %v: %v", binFilePath, err)
+	}
+	if err = os.Remove(binFilePath); err != nil {
+		return 0, fmt.Errorf("failed to remove: %v: %v", binFilePath, err)
+	}
+	logger.Debugf("removed engine binary: %v", binFilePath)
+	return info.Size(), nil
+}
+
 func (j JvmEngineLibrary) GetProvider(version string) engine.Provider {
 	switch j.engineType {
 	case engine.EngineTypeJvmSingleJar:
@@ -0,0 +1,40 @@
+package jvm
+
+import (
+	"gatehill.io/imposter/engine"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJvmEngineLibrary_List(t *testing.T) {
+	binCache := t.TempDir()
+	viper.Set("jvm.binCache", binCache)
+	t.Cleanup(func() {
+		viper.Set("jvm.binCache", nil)
+	})
+	files := map[string]string{
+		"imposter-4.2.0.jar": "jar contents",
+		"imposter-4.1.0.jar": "jar",
+		"checksums.txt":      "not an engine",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(binCache, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := JvmEngineLibrary{}.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []engine.EngineMetadata{
+		{EngineType: engine.EngineTypeJvmSingleJar, Version: "4.1.0", Size: 3},
+		{EngineType: engine.EngineTypeJvmSingleJar, Version: "4.2.0", Size: 12},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+}
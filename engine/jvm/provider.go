@@ -34,7 +34,7 @@ type JvmMockEngine struct {
 
 type JvmProvider interface {
 	engine.Provider
-	GetStartCommand(args []string, env []string) *exec.Cmd
+	GetStartCommand(jvmArgs []string, args []string, env []string) (*exec.Cmd, error)
 }
 
 type JvmProviderOptions struct {
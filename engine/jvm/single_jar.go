@@ -3,7 +3,9 @@ package jvm
 import (
 	"fmt"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/library"
+	"gatehill.io/imposter/lifecycle"
 	"github.com/spf13/viper"
 	"os"
 	"os/exec"
@@ -53,26 +55,24 @@ func newSingleJarProvider(version string) JvmProvider {
 	}
 }
 
-func (p *SingleJarProvider) GetStartCommand(args []string, env []string) *exec.Cmd {
+func (p *SingleJarProvider) GetStartCommand(jvmArgs []string, args []string, env []string) (*exec.Cmd, error) {
 	if p.javaCmd == "" {
 		javaCmd, err := GetJavaCmdPath()
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		p.javaCmd = javaCmd
 	}
 	if !p.Satisfied() {
 		if err := p.Provide(engine.PullIfNotPresent); err != nil {
-			logger.Fatal(err)
+			return nil, failure.Wrap(failure.CodeEngineDownloadFailed, err)
 		}
 	}
-	allArgs := append(
-		[]string{"-jar", p.jarPath},
-		args...,
-	)
+	allArgs := append(jvmArgs, "-jar", p.jarPath)
+	allArgs = append(allArgs, args...)
 	command := exec.Command(p.javaCmd, allArgs...)
 	command.Env = env
-	return command
+	return command, nil
 }
 
 func (p *SingleJarProvider) Provide(policy engine.PullPolicy) error {
@@ -102,7 +102,7 @@ func ensureBinary(version string, policy engine.PullPolicy) (string, error) {
 func checkOrDownloadBinary(version string, policy engine.PullPolicy) (string, error) {
 	binCachePath, err := ensureBinCache()
 	if err != nil {
-		logger.Fatal(err)
+		return "", err
 	}
 
 	binFilePath := filepath.Join(binCachePath, fmt.Sprintf("imposter-%v.jar", version))
@@ -133,7 +133,14 @@ func ensureBinCache() (string, error) {
 	return library.EnsureDirUsingConfig("jvm.binCache", binCacheDir)
 }
 
+// downloadBinary downloads the engine JAR file, verifying its published
+// checksum, unless checksum verification is disabled.
 func downloadBinary(localPath string, version string) error {
+	lifecycle.Emit(lifecycle.Event{Type: lifecycle.EventPulling, Version: version, Message: "downloading engine version " + version})
 	fallbackRemoteFileName := fmt.Sprintf("imposter-%v.jar", version)
-	return library.DownloadBinaryWithFallback(localPath, "imposter.jar", version, fallbackRemoteFileName)
+	if viper.GetBool("jvm.skipChecksum") {
+		logger.Warnf("skipping checksum verification of engine version %v", version)
+		return library.DownloadBinaryWithFallback(localPath, "imposter.jar", version, fallbackRemoteFileName)
+	}
+	return library.DownloadVerifiedBinaryWithFallback(localPath, "imposter.jar", version, fallbackRemoteFileName)
 }
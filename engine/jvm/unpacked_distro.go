@@ -3,6 +3,7 @@ package jvm
 import (
 	"fmt"
 	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/failure"
 	"github.com/spf13/viper"
 	"os"
 	"os/exec"
@@ -47,26 +48,24 @@ func newUnpackedDistroProvider(version string) JvmProvider {
 	}
 }
 
-func (p *UnpackedDistroProvider) GetStartCommand(args []string, env []string) *exec.Cmd {
+func (p *UnpackedDistroProvider) GetStartCommand(jvmArgs []string, args []string, env []string) (*exec.Cmd, error) {
 	if p.javaCmd == "" {
 		javaCmd, err := GetJavaCmdPath()
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 		p.javaCmd = javaCmd
 	}
 	if !p.Satisfied() {
 		if err := p.Provide(engine.PullIfNotPresent); err != nil {
-			logger.Fatal(err)
+			return nil, failure.Wrap(failure.CodeEngineDownloadFailed, err)
 		}
 	}
-	allArgs := append(
-		[]string{"-classpath", filepath.Join(p.distroDir, "lib") + "/*", mainClass},
-		args...,
-	)
+	allArgs := append(jvmArgs, "-classpath", filepath.Join(p.distroDir, "lib")+"/*", mainClass)
+	allArgs = append(allArgs, args...)
 	command := exec.Command(p.javaCmd, allArgs...)
 	command.Env = env
-	return command
+	return command, nil
 }
 
 func (p *UnpackedDistroProvider) Provide(engine.PullPolicy) error {
@@ -0,0 +1,274 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"gatehill.io/imposter/stats"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	metricRequestsTotal      = "vertx_http_server_requests_total"
+	metricResponseTimeSum    = "vertx_http_server_response_time_seconds_sum"
+	metricResponseTimeCount  = "vertx_http_server_response_time_seconds_count"
+	metricResponseTimeBucket = "vertx_http_server_response_time_seconds_bucket"
+)
+
+// MetricSample is a single sample from the engine metrics endpoint.
+type MetricSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// ResourceStats holds the request count and latency for a single
+// method and path combination served by the mock.
+type ResourceStats struct {
+	Method      string
+	Path        string
+	Requests    int
+	MeanLatency time.Duration
+}
+
+// FetchMetrics retrieves the metrics from the mock on the specified port.
+func FetchMetrics(port int, tls bool) ([]MetricSample, error) {
+	url := getMetricsUrl(port, tls)
+	resp, err := mockClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("metrics request failed for mock at %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("metrics status was %d for mock at %s", resp.StatusCode, url)
+	}
+	return parseMetrics(resp.Body)
+}
+
+func getMetricsUrl(port int, tls bool) string {
+	return getMockBaseUrl(port, tls) + "/system/metrics"
+}
+
+// parseMetrics parses metrics in Prometheus text exposition format.
+func parseMetrics(metrics io.Reader) ([]MetricSample, error) {
+	var samples []MetricSample
+
+	scanner := bufio.NewScanner(metrics)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample := MetricSample{Labels: make(map[string]string)}
+
+		rest := line
+		if idx := strings.IndexAny(line, "{ "); idx >= 0 {
+			sample.Name = line[:idx]
+			rest = line[idx:]
+		} else {
+			continue
+		}
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid labels for metric %s", sample.Name)
+			}
+			parseLabels(rest[1:end], sample.Labels)
+			rest = rest[end+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for metric %s: %v", sample.Name, err)
+		}
+		sample.Value = value
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %v", err)
+	}
+	return samples, nil
+}
+
+// parseLabels parses a label set of the form `a="b",c="d",`.
+func parseLabels(s string, labels map[string]string) {
+	for len(s) > 0 {
+		eq := strings.Index(s, "=\"")
+		if eq < 0 {
+			return
+		}
+		key := strings.TrimSpace(strings.TrimPrefix(s[:eq], ","))
+		s = s[eq+2:]
+
+		var value strings.Builder
+		i := 0
+		for ; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			}
+			if s[i] == '"' {
+				break
+			}
+			value.WriteByte(s[i])
+		}
+		labels[key] = value.String()
+		if i >= len(s) {
+			return
+		}
+		s = strings.TrimPrefix(s[i+1:], ",")
+	}
+}
+
+// SumMetric returns the total of all samples with the given name, and
+// whether any such samples were found.
+func SumMetric(samples []MetricSample, name string) (float64, bool) {
+	found := false
+	total := 0.0
+	for _, sample := range samples {
+		if sample.Name == name {
+			found = true
+			total += sample.Value
+		}
+	}
+	return total, found
+}
+
+// GetResourceStats aggregates the request metrics by method and path.
+func GetResourceStats(samples []MetricSample) []ResourceStats {
+	type accumulator struct {
+		requests     float64
+		latencySum   float64
+		latencyCount float64
+	}
+	stats := make(map[[2]string]*accumulator)
+	get := func(sample MetricSample) *accumulator {
+		path := sample.Labels["route"]
+		if path == "" {
+			path = sample.Labels["path"]
+		}
+		key := [2]string{sample.Labels["method"], path}
+		acc := stats[key]
+		if acc == nil {
+			acc = &accumulator{}
+			stats[key] = acc
+		}
+		return acc
+	}
+	for _, sample := range samples {
+		switch sample.Name {
+		case metricRequestsTotal:
+			get(sample).requests += sample.Value
+		case metricResponseTimeSum:
+			get(sample).latencySum += sample.Value
+		case metricResponseTimeCount:
+			get(sample).latencyCount += sample.Value
+		}
+	}
+
+	var resources []ResourceStats
+	for key, acc := range stats {
+		resource := ResourceStats{
+			Method:   key[0],
+			Path:     key[1],
+			Requests: int(acc.requests),
+		}
+		if acc.latencyCount > 0 {
+			resource.MeanLatency = time.Duration(acc.latencySum / acc.latencyCount * float64(time.Second))
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Path != resources[j].Path {
+			return resources[i].Path < resources[j].Path
+		}
+		return resources[i].Method < resources[j].Method
+	})
+	return resources
+}
+
+// GetSessionSummary summarises the requests served by the mock, from its
+// request count and response time metrics. Requests receiving a 404
+// without a matched route are counted as unmatched. Latency percentiles
+// are estimated from the response time histogram, if the engine
+// publishes one. Requests to the engine's system endpoints, such as
+// those made by the CLI, are excluded.
+func GetSessionSummary(samples []MetricSample) stats.Summary {
+	summary := stats.Summary{
+		Paths:    make(map[string]int),
+		Statuses: make(map[int]int),
+		P50:      -1,
+		P95:      -1,
+	}
+	buckets := make(map[float64]float64)
+	for _, sample := range samples {
+		route := sample.Labels["route"]
+		path := route
+		if path == "" {
+			path = sample.Labels["path"]
+		}
+		if strings.HasPrefix(path, "/system/") {
+			continue
+		}
+		switch sample.Name {
+		case metricRequestsTotal:
+			count := int(sample.Value)
+			if path == "" {
+				path = "-"
+			}
+			status, _ := strconv.Atoi(sample.Labels["code"])
+			summary.Requests += count
+			summary.Paths[sample.Labels["method"]+" "+path] += count
+			summary.Statuses[status] += count
+			if status == 404 && route == "" {
+				summary.Unmatched += count
+			}
+		case metricResponseTimeBucket:
+			le, err := strconv.ParseFloat(sample.Labels["le"], 64)
+			if err != nil {
+				continue
+			}
+			buckets[le] += sample.Value
+		}
+	}
+
+	var histogram []stats.HistogramBucket
+	for le, count := range buckets {
+		histogram = append(histogram, stats.HistogramBucket{UpperBound: le, Count: count})
+	}
+	if p50, ok := stats.HistogramQuantile(0.5, histogram); ok {
+		summary.P50 = secondsToDuration(p50)
+	}
+	if p95, ok := stats.HistogramQuantile(0.95, histogram); ok {
+		summary.P95 = secondsToDuration(p95)
+	}
+	return summary
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics string
+		want    []MetricSample
+		wantErr bool
+	}{
+		{
+			name:    "no labels",
+			metrics: "# TYPE vertx_http_server_active_requests gauge\nvertx_http_server_active_requests 3.0\n",
+			want: []MetricSample{
+				{Name: "vertx_http_server_active_requests", Labels: map[string]string{}, Value: 3},
+			},
+		},
+		{
+			name:    "with labels",
+			metrics: `vertx_http_server_requests_total{code="200",method="GET",route="/pets/{id}",} 2.0` + "\n",
+			want: []MetricSample{
+				{
+					Name:   "vertx_http_server_requests_total",
+					Labels: map[string]string{"code": "200", "method": "GET", "route": "/pets/{id}"},
+					Value:  2,
+				},
+			},
+		},
+		{
+			name:    "invalid value",
+			metrics: "vertx_http_server_active_requests foo\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMetrics(strings.NewReader(tt.metrics))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMetrics() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMetrics() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumMetric(t *testing.T) {
+	samples := []MetricSample{
+		{Name: "vertx_http_server_active_requests", Value: 2},
+		{Name: "vertx_http_server_active_requests", Value: 1},
+		{Name: "vertx_http_server_requests_total", Value: 99},
+	}
+	got, found := SumMetric(samples, "vertx_http_server_active_requests")
+	if !found || got != 3 {
+		t.Errorf("SumMetric() got = %v, %v, want 3, true", got, found)
+	}
+	if _, found := SumMetric(samples, "missing"); found {
+		t.Errorf("SumMetric() found missing metric")
+	}
+}
+
+func TestGetResourceStats(t *testing.T) {
+	get := map[string]string{"method": "GET", "route": "/pets", "code": "200"}
+	getNotFound := map[string]string{"method": "GET", "route": "/pets", "code": "404"}
+	post := map[string]string{"method": "POST", "path": "/pets", "code": "201"}
+
+	samples := []MetricSample{
+		{Name: metricRequestsTotal, Labels: get, Value: 3},
+		{Name: metricRequestsTotal, Labels: getNotFound, Value: 1},
+		{Name: metricResponseTimeSum, Labels: get, Value: 0.4},
+		{Name: metricResponseTimeCount, Labels: get, Value: 4},
+		{Name: metricRequestsTotal, Labels: post, Value: 1},
+	}
+	want := []ResourceStats{
+		{Method: "GET", Path: "/pets", Requests: 4, MeanLatency: 100 * time.Millisecond},
+		{Method: "POST", Path: "/pets", Requests: 1},
+	}
+	if got := GetResourceStats(samples); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResourceStats() = %v, want %v", got, want)
+	}
+}
+
+func TestGetSessionSummary(t *testing.T) {
+	samples := []MetricSample{
+		{Name: metricRequestsTotal, Labels: map[string]string{"method": "GET", "route": "/pets", "code": "200"}, Value: 8},
+		{Name: metricRequestsTotal, Labels: map[string]string{"method": "GET", "route": "/pets/:id", "code": "404"}, Value: 1},
+		{Name: metricRequestsTotal, Labels: map[string]string{"method": "GET", "path": "/missing", "code": "404"}, Value: 1},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "route": "/pets", "le": "0.01"}, Value: 4},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "route": "/pets", "le": "0.02"}, Value: 8},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "route": "/pets", "le": "+Inf"}, Value: 8},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "path": "/missing", "le": "0.01"}, Value: 2},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "path": "/missing", "le": "0.02"}, Value: 2},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "path": "/missing", "le": "+Inf"}, Value: 2},
+	}
+	got := GetSessionSummary(samples)
+	if got.Requests != 10 {
+		t.Errorf("Requests = %d, want 10", got.Requests)
+	}
+	if got.Unmatched != 1 {
+		t.Errorf("Unmatched = %d, want 1", got.Unmatched)
+	}
+	wantPaths := map[string]int{"GET /pets": 8, "GET /pets/:id": 1, "GET /missing": 1}
+	if !reflect.DeepEqual(got.Paths, wantPaths) {
+		t.Errorf("Paths = %v, want %v", got.Paths, wantPaths)
+	}
+	wantStatuses := map[int]int{200: 8, 404: 2}
+	if !reflect.DeepEqual(got.Statuses, wantStatuses) {
+		t.Errorf("Statuses = %v, want %v", got.Statuses, wantStatuses)
+	}
+	if got.P50 != 8333333*time.Nanosecond {
+		t.Errorf("P50 = %v, want 8.333333ms", got.P50)
+	}
+	if got.P95 != 18750*time.Microsecond {
+		t.Errorf("P95 = %v, want 18.75ms", got.P95)
+	}
+}
+
+func TestGetSessionSummary_noHistogram(t *testing.T) {
+	samples := []MetricSample{
+		{Name: metricRequestsTotal, Labels: map[string]string{"method": "GET", "route": "/pets", "code": "200"}, Value: 1},
+	}
+	got := GetSessionSummary(samples)
+	if got.P50 != -1 || got.P95 != -1 {
+		t.Errorf("P50, P95 = %v, %v, want -1", got.P50, got.P95)
+	}
+}
+
+func TestGetSessionSummary_excludesSystemEndpoints(t *testing.T) {
+	samples := []MetricSample{
+		{Name: metricRequestsTotal, Labels: map[string]string{"method": "GET", "route": "/system/status", "code": "200"}, Value: 5},
+		{Name: metricResponseTimeBucket, Labels: map[string]string{"method": "GET", "route": "/system/status", "le": "+Inf"}, Value: 5},
+	}
+	got := GetSessionSummary(samples)
+	if got.Requests != 0 || len(got.Paths) != 0 || got.P50 != -1 {
+		t.Errorf("GetSessionSummary() = %+v, want no requests", got)
+	}
+}
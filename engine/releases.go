@@ -0,0 +1,196 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/coreos/go-semver/semver"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const releasesApi = "https://api.github.com/repos/outofcoffee/imposter/releases"
+
+// maxReleasePages limits the number of pages of releases fetched.
+const maxReleasePages = 5
+
+// fuzzyVersionPattern matches versions such as '4.x' or '3.44.x'.
+var fuzzyVersionPattern = regexp.MustCompile(`^(\d+)(\.\d+)?\.[x*]$`)
+
+// Release is a published version of the engine.
+type Release struct {
+	Version    string
+	Prerelease bool
+	Published  time.Time
+}
+
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ListReleases returns the published versions of the engine, highest first.
+// Prereleases are only included if includePrerelease is true.
+func ListReleases(includePrerelease bool) ([]Release, error) {
+	var releases []Release
+	for page := 1; page <= maxReleasePages; page++ {
+		pageReleases, err := fetchReleases(page)
+		if err != nil {
+			return nil, err
+		}
+		if len(pageReleases) == 0 {
+			break
+		}
+		for _, r := range pageReleases {
+			if r.Draft || (r.Prerelease && !includePrerelease) {
+				continue
+			}
+			version := strings.TrimPrefix(r.TagName, "v")
+			if _, err := semver.NewVersion(version); err != nil {
+				continue
+			}
+			releases = append(releases, Release{
+				Version:    version,
+				Prerelease: r.Prerelease,
+				Published:  r.PublishedAt,
+			})
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return semver.New(releases[j].Version).LessThan(*semver.New(releases[i].Version))
+	})
+	return releases, nil
+}
+
+func fetchReleases(page int) ([]githubRelease, error) {
+	url := fmt.Sprintf("%s?per_page=100&page=%d", releasesApi, page)
+	logger.Tracef("fetching releases from: %s", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases from %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to list releases from %s - status code: %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases from %s - cannot read response body: %s", url, err)
+	}
+	var releases []githubRelease
+	if err = json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to list releases from %s - cannot unmarshall response body: %s", url, err)
+	}
+	return releases, nil
+}
+
+// IsFuzzyVersion returns true if the version is a pin such as '4.x'
+// or '3.44.x', rather than an exact version.
+func IsFuzzyVersion(version string) bool {
+	return fuzzyVersionPattern.MatchString(version)
+}
+
+// MatchFuzzyVersion returns the highest of the versions matching the
+// fuzzy pin, or the empty string if none match. Prereleases never match.
+func MatchFuzzyVersion(pin string, versions []string) string {
+	groups := fuzzyVersionPattern.FindStringSubmatch(pin)
+	if groups == nil {
+		return ""
+	}
+	prefix := groups[1] + groups[2] + "."
+
+	var highest *semver.Version
+	for _, version := range versions {
+		v, err := semver.NewVersion(version)
+		if err != nil || v.PreRelease != "" || !strings.HasPrefix(v.String(), prefix) {
+			continue
+		}
+		if highest == nil || highest.LessThan(*v) {
+			highest = v
+		}
+	}
+	if highest != nil {
+		return highest.String()
+	}
+	return ""
+}
+
+// ResolveFuzzyVersion resolves the fuzzy pin to the highest matching
+// release. If allowCached is true, the releases found by a previous
+// check within the threshold are used, and are also used if the
+// releases cannot be fetched.
+func ResolveFuzzyVersion(pin string, allowCached bool) (string, error) {
+	logger.Tracef("resolving version pin: %s (cache allowed: %v)", pin, allowCached)
+
+	now := time.Now().Unix()
+	var versions []string
+	if allowCached {
+		versions = loadCachedReleases(now, false)
+	}
+	if len(versions) == 0 {
+		releases, err := ListReleases(false)
+		if err != nil {
+			if !allowCached {
+				return "", err
+			}
+			logger.Warnf("failed to list releases (%s) - checking cache", err)
+			versions = loadCachedReleases(now, true)
+		} else {
+			for _, r := range releases {
+				versions = append(versions, r.Version)
+			}
+			saveCachedReleases(now, versions)
+		}
+	}
+
+	version := MatchFuzzyVersion(pin, versions)
+	if version == "" {
+		return "", fmt.Errorf("no release matches version %s", pin)
+	}
+	logger.Debugf("resolved version %s to: %s", pin, version)
+	return version, nil
+}
+
+func loadCachedReleases(now int64, ignoreThreshold bool) []string {
+	p := getVersionPrefs()
+	lastCheck, _ := p.ReadPropertyInt("last_releases_check")
+	if !ignoreThreshold && now-int64(lastCheck) >= checkThresholdSeconds {
+		return nil
+	}
+	cached, _ := p.ReadPropertyString("releases")
+	if cached == "" {
+		return nil
+	}
+	return strings.Split(cached, ",")
+}
+
+func saveCachedReleases(now int64, versions []string) {
+	p := getVersionPrefs()
+	if err := p.WriteProperty("releases", strings.Join(versions, ",")); err != nil {
+		logger.Warnf("failed to record releases: %s", err)
+	}
+	if err := p.WriteProperty("last_releases_check", now); err != nil {
+		logger.Warnf("failed to record last releases check time: %s", err)
+	}
+}
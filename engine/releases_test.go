@@ -0,0 +1,31 @@
+package engine
+
+import "testing"
+
+func TestMatchFuzzyVersion(t *testing.T) {
+	versions := []string{"3.44.1", "3.9.0", "4.0.0", "4.2.1", "4.10.0", "5.0.0-rc1", "40.0.0"}
+	tests := []struct {
+		name  string
+		pin   string
+		fuzzy bool
+		want  string
+	}{
+		{name: "major", pin: "4.x", fuzzy: true, want: "4.10.0"},
+		{name: "major with star", pin: "3.*", fuzzy: true, want: "3.44.1"},
+		{name: "major and minor", pin: "4.2.x", fuzzy: true, want: "4.2.1"},
+		{name: "prerelease never matches", pin: "5.x", fuzzy: true, want: ""},
+		{name: "no match", pin: "2.x", fuzzy: true, want: ""},
+		{name: "exact version", pin: "4.2.1", fuzzy: false, want: ""},
+		{name: "latest", pin: "latest", fuzzy: false, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFuzzyVersion(tt.pin); got != tt.fuzzy {
+				t.Errorf("IsFuzzyVersion() = %v, want %v", got, tt.fuzzy)
+			}
+			if got := MatchFuzzyVersion(tt.pin, versions); got != tt.want {
+				t.Errorf("MatchFuzzyVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"gatehill.io/imposter/stats"
+	"sync"
+	"time"
+)
+
+// SessionMetrics holds the metrics of a mock over a session, which may
+// span several restarts of the engine. As the engine's metrics are reset
+// when it restarts, the metrics are fetched periodically, so the summary
+// is available once the engine has stopped.
+type SessionMetrics struct {
+	port int
+	tls  bool
+
+	mutex sync.Mutex
+	// previous holds the final metrics of earlier runs of the engine
+	previous []MetricSample
+	latest   []MetricSample
+}
+
+func NewSessionMetrics(port int, tls bool) *SessionMetrics {
+	return &SessionMetrics{port: port, tls: tls}
+}
+
+// Poll refreshes the metrics at the interval, until the process exits.
+func (s *SessionMetrics) Poll(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			_ = s.Refresh()
+		}
+	}()
+}
+
+// Refresh fetches the current metrics from the engine.
+func (s *SessionMetrics) Refresh() error {
+	samples, err := FetchMetrics(s.port, s.tls)
+	if err != nil {
+		logger.Tracef("failed to refresh session metrics: %v", err)
+		return err
+	}
+	s.update(samples)
+	return nil
+}
+
+// update records the samples as the latest. If the request count has
+// decreased, the engine has restarted, so the previous latest samples
+// are retained as those of the earlier run.
+func (s *SessionMetrics) update(samples []MetricSample) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	latestTotal, _ := SumMetric(s.latest, metricRequestsTotal)
+	total, _ := SumMetric(samples, metricRequestsTotal)
+	if total < latestTotal {
+		s.previous = append(s.previous, s.latest...)
+	}
+	s.latest = samples
+}
+
+// Summary returns the summary of the requests served during the session.
+func (s *SessionMetrics) Summary() stats.Summary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	samples := append(append([]MetricSample(nil), s.previous...), s.latest...)
+	return GetSessionSummary(samples)
+}
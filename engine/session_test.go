@@ -0,0 +1,24 @@
+package engine
+
+import "testing"
+
+func TestSessionMetrics_update(t *testing.T) {
+	requests := func(count float64) []MetricSample {
+		return []MetricSample{{
+			Name:   metricRequestsTotal,
+			Labels: map[string]string{"method": "GET", "route": "/pets", "code": "200"},
+			Value:  count,
+		}}
+	}
+	s := NewSessionMetrics(8080, false)
+	s.update(requests(2))
+	s.update(requests(5))
+
+	// the engine restarted, resetting its metrics
+	s.update(requests(1))
+	s.update(requests(3))
+
+	if got := s.Summary().Requests; got != 8 {
+		t.Errorf("Summary().Requests = %d, want 8", got)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/lifecycle"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ExitCodeEngineExited indicates the engine exited before becoming ready.
+	ExitCodeEngineExited = 2
+
+	// ExitCodeNotReady indicates the engine did not become ready in time.
+	ExitCodeNotReady = 3
+)
+
+const logTailLines = 30
+const readyPollInterval = 100 * time.Millisecond
+
+// LogTail retains the last lines of engine output written to it,
+// so they can be shown if the engine fails to start.
+type LogTail struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+func NewLogTail() *LogTail {
+	return &LogTail{}
+}
+
+func (t *LogTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	text := t.partial + string(p)
+	lines := strings.Split(text, "\n")
+	t.partial = lines[len(lines)-1]
+	t.lines = append(t.lines, lines[:len(lines)-1]...)
+	if len(t.lines) > logTailLines {
+		t.lines = t.lines[len(t.lines)-logTailLines:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the retained lines, oldest first, including
+// any incomplete final line.
+func (t *LogTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string{}, t.lines...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > logTailLines {
+		lines = lines[len(lines)-logTailLines:]
+	}
+	return lines
+}
+
+// BuildOutputWriters returns the writers for the standard output and error
+// of the engine, which write to the console, the log tail, and the log
+// writer in the options, if set. Standard output is written to stderr
+// if lifecycle events are written to stdout.
+func BuildOutputWriters(options StartOptions, logTail *LogTail) (stdout io.Writer, stderr io.Writer) {
+	stdouts := []io.Writer{lifecycle.ConsoleOutput(), logTail}
+	stderrs := []io.Writer{os.Stderr, logTail}
+	if options.LogWriter != nil {
+		stdouts = append(stdouts, options.LogWriter)
+		stderrs = append(stderrs, options.LogWriter)
+	}
+	return io.MultiWriter(stdouts...), io.MultiWriter(stderrs...)
+}
+
+// StartWithContext calls start, which starts an engine for a program
+// embedding the mock, returning whether it became ready. If ctx is done
+// before start returns, abortC receives a value, to abort waiting for the
+// engine to become ready, and the context's error is returned.
+func StartWithContext(ctx context.Context, abortC chan bool, start func() (bool, error)) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case abortC <- true:
+			case <-done:
+			}
+		case <-done:
+		}
+	}()
+
+	success, err := start()
+	if err != nil {
+		return err
+	} else if !success {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return failure.New(failure.CodeEngineNotReady, "mock engine did not become ready")
+	}
+	return nil
+}
+
+// WaitUntilReady waits for the mock to pass its healthcheck. If the engine
+// exits, a description of the exit is sent on exitedC.
+//
+// If options.WaitReady is set, failure to become ready, either because the
+// engine exited or the timeout elapsed, causes the CLI to exit with a non-zero
+// exit code, after printing the tail of the engine log. Otherwise, it returns
+// false if the engine exits, and a timeout is fatal, unless options.Embedded
+// is set, in which case it also returns false.
+func WaitUntilReady(options StartOptions, exitedC <-chan string, logTail *LogTail, abortC chan bool) (success bool) {
+	baseUrl := getMockBaseUrlOnHost(options.Host, options.Port, options.Tls.Enabled)
+	url := baseUrl + "/system/status"
+	timeout := options.ReadyTimeout
+	if timeout <= 0 {
+		timeout = getStartTimeout()
+	}
+	logger.Tracef("waiting up to %v for status endpoint to return HTTP 200 at %v", timeout, url)
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if isStatusOk(url) {
+				if options.WaitReady {
+					logger.Infof("mock is ready at %s", baseUrl)
+				} else {
+					logger.Tracef("successfully waited for status endpoint at %v", url)
+				}
+				lifecycle.Emit(lifecycle.Event{Type: lifecycle.EventReady, Port: options.Port, Url: baseUrl})
+				return true
+			}
+		case reason := <-exitedC:
+			return failStartup(options, ExitCodeEngineExited, failure.CodeEngineExited, logTail, fmt.Sprintf("mock engine exited before becoming ready: %s", reason))
+		case <-deadline.C:
+			if !options.WaitReady && !options.Embedded {
+				failure.Fatal(failure.New(failure.CodeEngineNotReady, "timed out waiting for status endpoint to return HTTP 200 at %v", url))
+			}
+			return failStartup(options, ExitCodeNotReady, failure.CodeEngineNotReady, logTail, fmt.Sprintf("timed out after %v waiting for mock engine to become ready at %s", timeout, url))
+		case <-abortC:
+			logger.Debugf("aborted waiting for status endpoint at %v", url)
+			return false
+		}
+	}
+}
+
+func isStatusOk(url string) bool {
+	resp, err := mockClient.Get(url)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == 200
+}
+
+// failStartup reports the startup failure. If options.WaitReady is set, the
+// tail of the engine log is printed and the CLI exits with exitCode, writing
+// an error report with the failure code, if enabled.
+func failStartup(options StartOptions, exitCode int, code failure.Code, logTail *LogTail, message string) bool {
+	lifecycle.EmitError(failure.New(code, "%s", message))
+	if !options.WaitReady {
+		logger.Error(message)
+		return false
+	}
+	logger.Error(message)
+	if logTail != nil {
+		if lines := logTail.Lines(); len(lines) > 0 {
+			logger.Errorf("last %d line(s) of engine log:\n%s", len(lines), strings.Join(lines, "\n"))
+		}
+	}
+	failure.WriteReport(failure.New(code, "%s", message))
+	os.Exit(exitCode)
+	return false
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLogTail_Lines(t *testing.T) {
+	var manyLines string
+	for i := 1; i <= logTailLines+5; i++ {
+		manyLines += fmt.Sprintf("line %d\n", i)
+	}
+	var lastLines []string
+	for i := 6; i <= logTailLines+5; i++ {
+		lastLines = append(lastLines, fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{name: "empty", writes: nil, want: []string{}},
+		{name: "complete lines", writes: []string{"first\nsecond\n"}, want: []string{"first", "second"}},
+		{name: "line split across writes", writes: []string{"fir", "st\nsec", "ond\n"}, want: []string{"first", "second"}},
+		{name: "incomplete final line", writes: []string{"first\nsecond"}, want: []string{"first", "second"}},
+		{name: "retains last lines", writes: []string{manyLines}, want: lastLines},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logTail := NewLogTail()
+			for _, w := range tt.writes {
+				if _, err := logTail.Write([]byte(w)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := logTail.Lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitUntilReady_embedded(t *testing.T) {
+	port, err := FindFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := StartOptions{Port: port, ReadyTimeout: 300 * time.Millisecond, Embedded: true}
+
+	t.Run("timeout", func(t *testing.T) {
+		if WaitUntilReady(options, make(chan string), nil, make(chan bool)) {
+			t.Errorf("WaitUntilReady() = true, want false when the mock is not ready")
+		}
+	})
+	t.Run("engine exited", func(t *testing.T) {
+		exitedC := make(chan string, 1)
+		exitedC <- "exit status 1"
+		if WaitUntilReady(options, exitedC, NewLogTail(), make(chan bool)) {
+			t.Errorf("WaitUntilReady() = true, want false when the engine exits")
+		}
+	})
+}
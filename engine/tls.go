@@ -0,0 +1,185 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/library"
+	"gatehill.io/imposter/tlsutil"
+	"os"
+	"path/filepath"
+)
+
+const tlsDir = ".imposter/tls/"
+const selfSignedCertFile = "self-signed.crt"
+const selfSignedKeyFile = "self-signed.key"
+const keystoreAlias = "imposter"
+
+// selfSignedHosts are the names for which the self-signed certificate is valid.
+var selfSignedHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// TlsOptions configures HTTPS for the mock engine.
+type TlsOptions struct {
+	Enabled bool
+
+	// KeystorePath is the path on the host to a PKCS#12 keystore containing
+	// the certificate and key. If empty, the engine's built-in certificate is used.
+	KeystorePath string
+
+	KeystorePassword string
+}
+
+// BuildTlsOptions returns the TLS options for the engine. If a certificate
+// and key are provided, or a self-signed certificate is requested, they are
+// converted to a keystore that can be used by the engine.
+func BuildTlsOptions(enabled bool, certFile string, keyFile string, selfSigned bool) (TlsOptions, error) {
+	if !enabled {
+		if certFile != "" || keyFile != "" || selfSigned {
+			return TlsOptions{}, fmt.Errorf("TLS must be enabled to use a certificate")
+		}
+		return TlsOptions{}, nil
+	}
+	if selfSigned && (certFile != "" || keyFile != "") {
+		return TlsOptions{}, fmt.Errorf("a self-signed certificate cannot be used with a certificate or key file")
+	}
+	if (certFile == "") != (keyFile == "") {
+		return TlsOptions{}, fmt.Errorf("both a certificate and key file must be provided")
+	}
+
+	certPem, keyPem, err := ResolveCertificate(certFile, keyFile, selfSigned)
+	if err != nil {
+		return TlsOptions{}, err
+	} else if certPem == nil {
+		logger.Debugf("using engine built-in certificate")
+		return TlsOptions{Enabled: true}, nil
+	}
+
+	keystorePath, keystorePassword, err := writeKeystore(certPem, keyPem)
+	if err != nil {
+		return TlsOptions{}, err
+	}
+	return TlsOptions{
+		Enabled:          true,
+		KeystorePath:     keystorePath,
+		KeystorePassword: keystorePassword,
+	}, nil
+}
+
+// ResolveCertificate returns the PEM encoded certificate and key from the
+// files, or the self-signed certificate if requested. If neither is
+// specified, the certificate and key are nil.
+func ResolveCertificate(certFile string, keyFile string, selfSigned bool) (certPem []byte, keyPem []byte, err error) {
+	if selfSigned {
+		return ensureSelfSignedCert()
+	} else if certFile != "" {
+		return readCertAndKey(certFile, keyFile)
+	}
+	return nil, nil, nil
+}
+
+// ensureSelfSignedCert returns the self-signed certificate and key,
+// generating them if they do not exist. The certificate is reused
+// between runs, so clients only need to trust it once.
+func ensureSelfSignedCert() (certPem []byte, keyPem []byte, err error) {
+	dir, err := library.EnsureDirUsingConfig("tls.dir", tlsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPath := filepath.Join(dir, selfSignedCertFile)
+	keyPath := filepath.Join(dir, selfSignedKeyFile)
+	if _, err := os.Stat(certPath); err == nil {
+		certPem, keyPem, err = readCertAndKey(certPath, keyPath)
+		if err == nil {
+			logger.Infof("using self-signed certificate: %s", certPath)
+			return certPem, keyPem, nil
+		}
+		logger.Warnf("regenerating invalid self-signed certificate: %v", err)
+	}
+
+	certPem, keyPem, err = tlsutil.GenerateSelfSigned(selfSignedHosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := fileutil.WriteFileAtomic(keyPath, keyPem, 0600); err != nil {
+		return nil, nil, err
+	}
+	if err := fileutil.WriteFileAtomic(certPath, certPem, 0644); err != nil {
+		return nil, nil, err
+	}
+	logger.Infof("generated self-signed certificate: %s", certPath)
+	return certPem, keyPem, nil
+}
+
+func readCertAndKey(certFile string, keyFile string) (certPem []byte, keyPem []byte, err error) {
+	certPem, err = os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read certificate file: %s: %v", certFile, err)
+	}
+	keyPem, err = os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read key file: %s: %v", keyFile, err)
+	}
+	return certPem, keyPem, nil
+}
+
+// writeKeystore converts the PEM certificate and key to a PKCS#12 keystore,
+// named by the hash of its contents, in the TLS directory.
+//
+// The keystore must be readable by the engine, which may run as a different
+// user within a container, so its password is derived from the private key
+// rather than relying on file permissions.
+func writeKeystore(certPem []byte, keyPem []byte) (path string, password string, err error) {
+	key, certs, err := tlsutil.ParseKeyPair(certPem, keyPem)
+	if err != nil {
+		return "", "", err
+	}
+	passwordHash := sha256.Sum256(append([]byte(keystoreAlias), keyPem...))
+	password = hex.EncodeToString(passwordHash[:16])
+	keystore, err := tlsutil.EncodePkcs12(key, certs, keystoreAlias, password)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create keystore: %v", err)
+	}
+
+	dir, err := library.EnsureDirUsingConfig("tls.dir", tlsDir)
+	if err != nil {
+		return "", "", err
+	}
+	nameHash := sha1.Sum(append(certPem, keyPem...))
+	path = filepath.Join(dir, "keystore-"+hex.EncodeToString(nameHash[:8])+".p12")
+	if err := fileutil.WriteFileAtomic(path, keystore, 0644); err != nil {
+		return "", "", err
+	}
+	logger.Tracef("wrote keystore: %s", path)
+	return path, password, nil
+}
+
+// BuildTlsArgs returns the engine arguments to enable TLS, using the
+// given path to the keystore, as seen by the engine.
+func BuildTlsArgs(options TlsOptions, keystorePath string) []string {
+	if !options.Enabled {
+		return nil
+	}
+	args := []string{"--tlsEnabled=true"}
+	if keystorePath != "" {
+		args = append(args, "--keystorePath="+keystorePath, "--keystorePassword="+options.KeystorePassword)
+	}
+	return args
+}
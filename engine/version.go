@@ -7,6 +7,8 @@ import (
 	"github.com/coreos/go-semver/semver"
 	"io"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -14,6 +16,8 @@ import (
 const latestReleaseApi = "https://api.github.com/repos/outofcoffee/imposter/releases/latest"
 const checkThresholdSeconds = 86_400
 
+var imageDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
 func ResolveLatestToVersion(allowCached bool) (string, error) {
 	logger.Tracef("resolving latest version (cache allowed: %v)", allowCached)
 
@@ -53,6 +57,47 @@ func GetHighestVersion(engines []EngineMetadata) string {
 	return ""
 }
 
+// IsImageDigest returns true if the version is an image digest, such as
+// 'sha256:<digest>', pinning the exact Docker engine image.
+func IsImageDigest(version string) bool {
+	return imageDigestPattern.MatchString(version)
+}
+
+// SelectForPrune returns the engines that should be removed to keep only
+// the highest keepLatest versions of each engine type. Engines whose
+// version is not a semantic version, such as 'latest', are never selected.
+func SelectForPrune(engines []EngineMetadata, keepLatest int) []EngineMetadata {
+	byType := make(map[EngineType][]EngineMetadata)
+	var engineTypes []EngineType
+	seen := make(map[string]bool)
+	for _, engine := range engines {
+		key := string(engine.EngineType) + ":" + engine.Version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, err := semver.NewVersion(engine.Version); err != nil {
+			continue
+		}
+		if _, ok := byType[engine.EngineType]; !ok {
+			engineTypes = append(engineTypes, engine.EngineType)
+		}
+		byType[engine.EngineType] = append(byType[engine.EngineType], engine)
+	}
+
+	var prune []EngineMetadata
+	for _, engineType := range engineTypes {
+		candidates := byType[engineType]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return semver.New(candidates[j].Version).LessThan(*semver.New(candidates[i].Version))
+		})
+		if len(candidates) > keepLatest {
+			prune = append(prune, candidates[keepLatest:]...)
+		}
+	}
+	return prune
+}
+
 func loadCached(now int64) string {
 	var latest string
 
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectForPrune(t *testing.T) {
+	engines := []EngineMetadata{
+		{EngineType: EngineTypeDockerCore, Version: "4.0.0"},
+		{EngineType: EngineTypeDockerCore, Version: "latest"},
+		{EngineType: EngineTypeDockerCore, Version: "4.2.0"},
+		{EngineType: EngineTypeDockerCore, Version: "3.44.1"},
+		{EngineType: EngineTypeJvmSingleJar, Version: "4.1.0"},
+		{EngineType: EngineTypeJvmSingleJar, Version: "4.2.0"},
+		{EngineType: EngineTypeJvmSingleJar, Version: "4.1.0"},
+	}
+	tests := []struct {
+		name       string
+		keepLatest int
+		want       []EngineMetadata
+	}{
+		{name: "keep latest", keepLatest: 1, want: []EngineMetadata{
+			{EngineType: EngineTypeDockerCore, Version: "4.0.0"},
+			{EngineType: EngineTypeDockerCore, Version: "3.44.1"},
+			{EngineType: EngineTypeJvmSingleJar, Version: "4.1.0"},
+		}},
+		{name: "keep two", keepLatest: 2, want: []EngineMetadata{
+			{EngineType: EngineTypeDockerCore, Version: "3.44.1"},
+		}},
+		{name: "keep all", keepLatest: 3, want: nil},
+		{name: "keep none", keepLatest: 0, want: []EngineMetadata{
+			{EngineType: EngineTypeDockerCore, Version: "4.2.0"},
+			{EngineType: EngineTypeDockerCore, Version: "4.0.0"},
+			{EngineType: EngineTypeDockerCore, Version: "3.44.1"},
+			{EngineType: EngineTypeJvmSingleJar, Version: "4.2.0"},
+			{EngineType: EngineTypeJvmSingleJar, Version: "4.1.0"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectForPrune(engines, tt.keepLatest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectForPrune() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Phase is the stage of CLI operation in which a failure occurred.
+type Phase string
+
+const (
+	PhaseUnknown  Phase = "unknown"
+	PhaseCli      Phase = "cli"
+	PhaseConfig   Phase = "config"
+	PhaseEngine   Phase = "engine"
+	PhaseProxy    Phase = "proxy"
+	PhaseGenerate Phase = "generate"
+	PhaseVerify   Phase = "verify"
+)
+
+// Code identifies a class of failure. Codes are stable, so they can be
+// relied upon by CI systems and support tooling.
+type Code string
+
+const (
+	CodeUnknown  Code = "UNKNOWN"
+	CodeCliUsage Code = "CLI_USAGE"
+
+	CodeConfigNotFound Code = "CONFIG_NOT_FOUND"
+	CodeConfigInvalid  Code = "CONFIG_INVALID"
+
+	CodeEngineUnsupported       Code = "ENGINE_UNSUPPORTED"
+	CodeEngineDockerUnavailable Code = "ENGINE_DOCKER_UNAVAILABLE"
+	CodeEngineJavaNotFound      Code = "ENGINE_JAVA_NOT_FOUND"
+	CodeEngineDownloadFailed    Code = "ENGINE_DOWNLOAD_FAILED"
+	CodeEngineStartFailed       Code = "ENGINE_START_FAILED"
+	CodeEngineExited            Code = "ENGINE_EXITED"
+	CodeEngineNotReady          Code = "ENGINE_NOT_READY"
+
+	CodeProxyOutputExists Code = "PROXY_OUTPUT_EXISTS"
+	CodeProxyTlsInvalid   Code = "PROXY_TLS_INVALID"
+	CodeProxyListenFailed Code = "PROXY_LISTEN_FAILED"
+
+	CodeGenerateSpecInvalid Code = "GENERATE_SPEC_INVALID"
+	CodeGenerateNoSpecs     Code = "GENERATE_NO_SPECS"
+	CodeGenerateFileExists  Code = "GENERATE_FILE_EXISTS"
+	CodeGenerateWriteFailed Code = "GENERATE_WRITE_FAILED"
+
+	CodeVerifyTestsInvalid Code = "VERIFY_TESTS_INVALID"
+	CodeVerifyFailed       Code = "VERIFY_FAILED"
+	CodeSmokeTestFailed    Code = "SMOKE_TEST_FAILED"
+)
+
+type catalogueEntry struct {
+	phase      Phase
+	suggestion string
+}
+
+var catalogue = map[Code]catalogueEntry{
+	CodeUnknown:                 {PhaseUnknown, "Re-run with '--log-level trace' for more detail"},
+	CodeCliUsage:                {PhaseCli, "Check the command usage with '--help'"},
+	CodeConfigNotFound:          {PhaseConfig, "Check the path to the configuration directory, or create configuration with 'imposter scaffold'"},
+	CodeConfigInvalid:           {PhaseConfig, "Correct the reported problems in the configuration files"},
+	CodeEngineUnsupported:       {PhaseEngine, "Use a supported engine type, such as 'docker' or 'jvm'"},
+	CodeEngineDockerUnavailable: {PhaseEngine, "Check that Docker is installed and running, or use the JVM engine with '--engine-type jvm'"},
+	CodeEngineJavaNotFound:      {PhaseEngine, "Install Java 11+, set JAVA_HOME, or set jvm.downloadJre to download a JRE"},
+	CodeEngineDownloadFailed:    {PhaseEngine, "Check network access, or use an engine version that has already been downloaded"},
+	CodeEngineStartFailed:       {PhaseEngine, "Check the engine is not already running on the same port, and that the configuration directory is accessible"},
+	CodeEngineExited:            {PhaseEngine, "Check the engine log for errors in the mock configuration"},
+	CodeEngineNotReady:          {PhaseEngine, "Check the engine log, or allow more time to start with '--wait-ready'"},
+	CodeProxyOutputExists:       {PhaseProxy, "Remove the existing recording, or choose a different '--output-dir'"},
+	CodeProxyTlsInvalid:         {PhaseProxy, "Check the certificate and key files are PEM encoded and belong together"},
+	CodeProxyListenFailed:       {PhaseProxy, "Check the port is not in use, or choose a different '--port'"},
+	CodeGenerateSpecInvalid:     {PhaseGenerate, "Check the OpenAPI spec is valid YAML or JSON"},
+	CodeGenerateNoSpecs:         {PhaseGenerate, "Add an OpenAPI spec to the configuration directory"},
+	CodeGenerateFileExists:      {PhaseGenerate, "Pass '--force-overwrite' to replace existing files"},
+	CodeGenerateWriteFailed:     {PhaseGenerate, "Check the configuration directory is writable"},
+	CodeVerifyTestsInvalid:      {PhaseVerify, "Check the test file is valid YAML or JSON, in the format described in the README"},
+	CodeVerifyFailed:            {PhaseVerify, "Check the reported failures against the mock configuration"},
+	CodeSmokeTestFailed:         {PhaseVerify, "Check the engine log for errors in the script and response files of the failing resources"},
+}
+
+// Error is a failure with a stable code from the catalogue.
+type Error struct {
+	Code    Code
+	Message string
+	Cause   error
+}
+
+// New returns an error with the given code.
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns an error with the given code, caused by err. If err
+// already has a code, it is retained.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return err
+	}
+	return &Error{Code: code, Message: err.Error(), Cause: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Phase returns the stage of CLI operation in which the failure occurred.
+func (e *Error) Phase() Phase {
+	return lookup(e.Code).phase
+}
+
+// Suggestion returns a suggested remedy for the failure.
+func (e *Error) Suggestion() string {
+	return lookup(e.Code).suggestion
+}
+
+func lookup(code Code) catalogueEntry {
+	if entry, found := catalogue[code]; found {
+		return entry
+	}
+	return catalogue[CodeUnknown]
+}
+
+// CodeOf returns the code of the error, or CodeUnknown if it has none.
+func CodeOf(err error) Code {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+	return CodeUnknown
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logging.GetLogger()
+
+// Report is the machine-readable diagnosis of a failure.
+type Report struct {
+	Code       Code      `json:"code"`
+	Phase      Phase     `json:"phase"`
+	Message    string    `json:"message"`
+	Suggestion string    `json:"suggestion"`
+	CliVersion string    `json:"cliVersion"`
+	Time       time.Time `json:"time"`
+
+	// Command is the path of the command that failed, such as
+	// 'imposter proxy'. Arguments are omitted, as they may hold secrets.
+	Command []string `json:"command"`
+
+	// Flags are the names of the flags passed to the command, without
+	// their values.
+	Flags []string `json:"flags,omitempty"`
+}
+
+var reporter = struct {
+	sync.Mutex
+	reportFile string
+	cliVersion string
+	command    string
+	pending    error
+	written    bool
+}{}
+
+// EnableReport writes a report to reportFile when the CLI fails, either
+// through Fatal, or any fatal log entry.
+func EnableReport(reportFile string, cliVersion string) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	if reportFile == "" || reporter.reportFile != "" {
+		return
+	}
+	reporter.reportFile = reportFile
+	reporter.cliVersion = cliVersion
+	logger.AddHook(&reportHook{})
+}
+
+// SetCommand records the path of the command being run, such as
+// 'imposter proxy', for the report.
+func SetCommand(commandPath string) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	reporter.command = commandPath
+}
+
+// Fatal logs the error and exits. If a report is enabled, it describes
+// the error, including its code.
+func Fatal(err error) {
+	reporter.Lock()
+	reporter.pending = err
+	reporter.Unlock()
+	logger.Fatal(err)
+}
+
+// PendingError returns the error passed to Fatal, while it is being
+// logged, or nil.
+func PendingError() error {
+	reporter.Lock()
+	defer reporter.Unlock()
+	return reporter.pending
+}
+
+// WriteReport writes the report for the error, if a report is enabled.
+// This is for failures that exit the CLI without logging a fatal entry.
+func WriteReport(err error) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	writeReport(err)
+}
+
+// writeReport must be called with the reporter lock held. Only the first
+// failure is reported.
+func writeReport(err error) {
+	if reporter.reportFile == "" || reporter.written {
+		return
+	}
+	reporter.written = true
+	report := buildReport(err, reporter.cliVersion, reporter.command)
+	data, e := json.MarshalIndent(report, "", "  ")
+	if e == nil {
+		e = os.WriteFile(reporter.reportFile, data, 0600)
+	}
+	if e != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to write error report: %s: %v\n", reporter.reportFile, e)
+	}
+}
+
+// BuildReport returns the report for the error. Errors without a code
+// are reported as CodeUnknown.
+func BuildReport(err error, cliVersion string) Report {
+	reporter.Lock()
+	command := reporter.command
+	reporter.Unlock()
+	return buildReport(err, cliVersion, command)
+}
+
+func buildReport(err error, cliVersion string, command string) Report {
+	typed := &Error{Code: CodeUnknown, Message: err.Error()}
+	errors.As(err, &typed)
+	if command == "" {
+		command = filepath.Base(os.Args[0])
+	}
+	return Report{
+		Code:       typed.Code,
+		Phase:      typed.Phase(),
+		Message:    err.Error(),
+		Suggestion: typed.Suggestion(),
+		Command:    strings.Fields(command),
+		Flags:      flagNames(os.Args[1:]),
+		CliVersion: cliVersion,
+		Time:       time.Now().UTC(),
+	}
+}
+
+// flagNames returns the names of the flags in the arguments, omitting
+// their values and any positional arguments.
+func flagNames(args []string) []string {
+	var names []string
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		} else if strings.HasPrefix(arg, "--") {
+			name, _, _ := strings.Cut(arg, "=")
+			names = append(names, name)
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			// a shorthand flag may be followed by its value, such as '-p8080'
+			names = append(names, arg[:2])
+		}
+	}
+	return names
+}
+
+// reportHook writes the report when a fatal entry is logged, before
+// the logger exits.
+type reportHook struct{}
+
+func (h *reportHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.FatalLevel, logrus.PanicLevel}
+}
+
+func (h *reportHook) Fire(entry *logrus.Entry) error {
+	reporter.Lock()
+	defer reporter.Unlock()
+	err := reporter.pending
+	if err == nil {
+		if entryErr, ok := entry.Data[logrus.ErrorKey].(error); ok {
+			err = entryErr
+		} else {
+			err = errors.New(entry.Message)
+		}
+	}
+	writeReport(err)
+	return nil
+}
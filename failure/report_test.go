@@ -0,0 +1,127 @@
+package failure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestBuildReport(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       Code
+		wantPhase      Phase
+		wantMessage    string
+		wantSuggestion bool
+	}{
+		{
+			name:           "typed error",
+			err:            New(CodeProxyListenFailed, "listen tcp :8080: address already in use"),
+			wantCode:       CodeProxyListenFailed,
+			wantPhase:      PhaseProxy,
+			wantMessage:    "listen tcp :8080: address already in use",
+			wantSuggestion: true,
+		},
+		{
+			name:           "wrapped typed error",
+			err:            fmt.Errorf("failed to start: %w", Wrap(CodeEngineJavaNotFound, errors.New("no java"))),
+			wantCode:       CodeEngineJavaNotFound,
+			wantPhase:      PhaseEngine,
+			wantMessage:    "failed to start: no java",
+			wantSuggestion: true,
+		},
+		{
+			name:           "untyped error",
+			err:            errors.New("something went wrong"),
+			wantCode:       CodeUnknown,
+			wantPhase:      PhaseUnknown,
+			wantMessage:    "something went wrong",
+			wantSuggestion: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildReport(tt.err, "1.0.0")
+			if got.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", got.Code, tt.wantCode)
+			}
+			if got.Phase != tt.wantPhase {
+				t.Errorf("Phase = %v, want %v", got.Phase, tt.wantPhase)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("Message = %v, want %v", got.Message, tt.wantMessage)
+			}
+			if (got.Suggestion != "") != tt.wantSuggestion {
+				t.Errorf("Suggestion = %v, want suggestion %v", got.Suggestion, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
+func TestBuildReport_omitsArguments(t *testing.T) {
+	previousArgs := os.Args
+	defer func() { os.Args = previousArgs }()
+	os.Args = []string{"/usr/local/bin/imposter", "proxy", "https://api.example.com?key=secret", "--upstream-bearer-token", "secret", "--upstream-basic-auth=user:secret", "-p8080", "--", "--not-a-flag"}
+
+	tests := []struct {
+		name        string
+		command     string
+		wantCommand []string
+	}{
+		{name: "command path", command: "imposter proxy", wantCommand: []string{"imposter", "proxy"}},
+		{name: "unknown command path", command: "", wantCommand: []string{"imposter"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildReport(errors.New("failed"), "1.0.0", tt.command)
+			if !reflect.DeepEqual(got.Command, tt.wantCommand) {
+				t.Errorf("Command = %v, want %v", got.Command, tt.wantCommand)
+			}
+			wantFlags := []string{"--upstream-bearer-token", "--upstream-basic-auth", "-p"}
+			if !reflect.DeepEqual(got.Flags, wantFlags) {
+				t.Errorf("Flags = %v, want %v", got.Flags, wantFlags)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	original := New(CodeGenerateSpecInvalid, "bad spec")
+	if got := CodeOf(Wrap(CodeUnknown, original)); got != CodeGenerateSpecInvalid {
+		t.Errorf("Wrap() should retain existing code, got %v", got)
+	}
+	if Wrap(CodeUnknown, nil) != nil {
+		t.Errorf("Wrap() of nil should be nil")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	EnableReport(reportFile, "1.0.0")
+
+	WriteReport(New(CodeConfigNotFound, "cannot find config dir"))
+	WriteReport(New(CodeUnknown, "only the first failure is reported"))
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Code != CodeConfigNotFound || report.Phase != PhaseConfig || report.CliVersion != "1.0.0" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if info, err := os.Stat(reportFile); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("report file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ZipFiles creates a ZIP archive of the files, named by their path
+// relative to baseDir.
+func ZipFiles(baseDir string, files []string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, file := range files {
+		name, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine archive path for: %s: %v", file, err)
+		}
+		w, err := zw.Create(filepath.ToSlash(name))
+		if err != nil {
+			return nil, err
+		}
+		contents, err := ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(*contents); err != nil {
+			return nil, fmt.Errorf("failed to add file to archive: %s: %v", file, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ListZip returns the names of the files in the ZIP archive.
+func ListZip(data []byte) ([]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %v", err)
+	}
+	var names []string
+	for _, entry := range zr.File {
+		if !entry.FileInfo().IsDir() {
+			names = append(names, entry.Name)
+		}
+	}
+	return names, nil
+}
+
+// maxExtractSize and maxExtractEntries limit the total size of the files,
+// and the number of entries, extracted from a ZIP archive, so an archive
+// that expands to a very large size cannot fill the disk.
+var (
+	maxExtractSize    int64 = 512 * 1024 * 1024
+	maxExtractEntries       = 10_000
+)
+
+// ExtractZip extracts the ZIP archive into destDir, rejecting entries
+// that would be written outside it, and archives that exceed the limits
+// on extracted size or number of entries.
+func ExtractZip(data []byte, destDir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %v", err)
+	}
+	if len(zr.File) > maxExtractEntries {
+		return fmt.Errorf("archive contains %d entries, exceeding the limit of %d", len(zr.File), maxExtractEntries)
+	}
+	cleanDest := filepath.Clean(destDir)
+	remaining := maxExtractSize
+	for _, entry := range zr.File {
+		target := filepath.Join(cleanDest, entry.Name)
+		if !strings.HasPrefix(target, cleanDest+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal path in archive: %s", entry.Name)
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		written, err := extractZipEntry(entry, target, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= written
+	}
+	return nil
+}
+
+// extractZipEntry writes the entry to target, returning the number of
+// bytes written, or an error if it would exceed limit bytes. The size in
+// the entry header is not trusted, as it may not match the contents.
+func extractZipEntry(entry *zip.File, target string, limit int64) (int64, error) {
+	if entry.UncompressedSize64 > uint64(limit) {
+		return 0, fmt.Errorf("archive exceeds the extracted size limit of %d bytes", maxExtractSize)
+	}
+	src, err := entry.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive entry: %s: %v", entry.Name, err)
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	dest, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %s: %v", target, err)
+	}
+	defer dest.Close()
+	written, err := io.Copy(dest, io.LimitReader(src, limit+1))
+	if err != nil {
+		return written, fmt.Errorf("failed to write file: %s: %v", target, err)
+	} else if written > limit {
+		return written, fmt.Errorf("archive exceeds the extracted size limit of %d bytes", maxExtractSize)
+	}
+	return written, nil
+}
@@ -0,0 +1,63 @@
+package fileutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractZip(t *testing.T) {
+	tests := []struct {
+		name       string
+		entries    map[string]string
+		maxSize    int64
+		maxEntries int
+		wantErr    string
+	}{
+		{name: "within limits", entries: map[string]string{"a.txt": "abc", "dir/b.txt": "defg"}, maxSize: 7, maxEntries: 2},
+		{name: "illegal path", entries: map[string]string{"../a.txt": "abc"}, maxSize: 100, maxEntries: 10, wantErr: "illegal path"},
+		{name: "too large", entries: map[string]string{"a.txt": "abc", "b.txt": "defg"}, maxSize: 6, maxEntries: 10, wantErr: "size limit"},
+		{name: "too many entries", entries: map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"}, maxSize: 100, maxEntries: 2, wantErr: "3 entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origSize, origEntries := maxExtractSize, maxExtractEntries
+			maxExtractSize, maxExtractEntries = tt.maxSize, tt.maxEntries
+			defer func() { maxExtractSize, maxExtractEntries = origSize, origEntries }()
+
+			dest := t.TempDir()
+			err := ExtractZip(buildTestZip(t, tt.entries), dest)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExtractZip() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("ExtractZip() error = %v", err)
+			}
+			for name, content := range tt.entries {
+				assertFileContent(t, filepath.Join(dest, name), content)
+			}
+		})
+	}
+}
+
+func buildTestZip(t *testing.T, entries map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
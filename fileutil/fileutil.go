@@ -18,6 +18,7 @@ package fileutil
 
 import (
 	"fmt"
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/stringutil"
 	"io"
@@ -57,7 +58,7 @@ func MustNotExist(destFilePath string, forceOverwrite bool) {
 			logger.Fatal(err)
 		}
 	} else if !forceOverwrite {
-		logger.Fatalf("file already exists: %v - aborting", destFilePath)
+		failure.Fatal(failure.New(failure.CodeGenerateFileExists, "file already exists: %v - aborting", destFilePath))
 	}
 }
 
@@ -88,6 +89,31 @@ func CopyDirShallow(src string, dest string) error {
 	return nil
 }
 
+// CopyDir recursively copies the contents of the src directory into dest,
+// creating dest and any subdirectories as required.
+func CopyDir(src string, dest string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error reading directory: %v: %v", src, err)
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if d.IsDir() {
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return fmt.Errorf("error creating directory: %v: %v", target, err)
+			}
+			return nil
+		}
+		if err := CopyFile(path, target); err != nil {
+			return fmt.Errorf("error copying file: %v: %v", rel, err)
+		}
+		return nil
+	})
+}
+
 func CopyFile(src string, dest string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -0,0 +1,246 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transaction stages file writes in memory, so nothing is written to disk
+// until Commit is called. On commit, the files are first written to a
+// temporary directory alongside them, then swapped into place, so a
+// failure part way through leaves the previous files intact.
+type Transaction struct {
+	staged   []stagedFile
+	onCommit []func()
+}
+
+type stagedFile struct {
+	path string
+	data []byte
+	perm os.FileMode
+}
+
+// swappedFile records a file moved into place, and the backup of the
+// file it replaced, if any, to allow rollback.
+type swappedFile struct {
+	path   string
+	backup string
+}
+
+// stageFile and renameFile are replaced in tests to simulate failures.
+var (
+	stageFile  = writeFileSynced
+	renameFile = os.Rename
+)
+
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// WriteFile stages a write of data to the file at path. If the same path
+// is staged more than once, the last write wins.
+func (t *Transaction) WriteFile(path string, data []byte, perm os.FileMode) {
+	for i, s := range t.staged {
+		if s.path == path {
+			t.staged[i].data = data
+			t.staged[i].perm = perm
+			return
+		}
+	}
+	t.staged = append(t.staged, stagedFile{path: path, data: data, perm: perm})
+}
+
+// OnCommit registers fn to be called once the staged files have been
+// committed, such as to log that they were written.
+func (t *Transaction) OnCommit(fn func()) {
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// Commit writes each of the staged files, and a backup of any file it
+// replaces, to a staging directory next to the files, then renames them
+// into place. As the files are only renamed once all of them have been
+// written, a watcher sees the changes together, rather than spread over
+// the time taken to write them. If a rename fails, the files already
+// replaced are restored to their previous state.
+func (t *Transaction) Commit() error {
+	if len(t.staged) == 0 {
+		t.committed()
+		return nil
+	}
+	paths := make([]string, len(t.staged))
+	for i, s := range t.staged {
+		absPath, err := filepath.Abs(s.path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %s: %v", s.path, err)
+		}
+		paths[i] = absPath
+	}
+	stagingDir, err := os.MkdirTemp(commonDir(paths), ".imposter-tx-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	keepStagingDir := false
+	defer func() {
+		if keepStagingDir {
+			logger.Warnf("kept backups of files that could not be rolled back in: %s", stagingDir)
+		} else if err := os.RemoveAll(stagingDir); err != nil {
+			logger.Warnf("failed to remove staging directory: %s: %v", stagingDir, err)
+		}
+	}()
+
+	swaps := make([]swappedFile, len(t.staged))
+	for i, s := range t.staged {
+		if err := stageFile(filepath.Join(stagingDir, fmt.Sprintf("%d.new", i)), s.data, s.perm); err != nil {
+			return fmt.Errorf("failed to stage file: %s: %v", s.path, err)
+		}
+		swaps[i].path = paths[i]
+		if info, err := os.Stat(paths[i]); err == nil {
+			previous, err := os.ReadFile(paths[i])
+			if err != nil {
+				return fmt.Errorf("failed to back up file: %s: %v", s.path, err)
+			}
+			backup := filepath.Join(stagingDir, fmt.Sprintf("%d.old", i))
+			if err := stageFile(backup, previous, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to back up file: %s: %v", s.path, err)
+			}
+			swaps[i].backup = backup
+		}
+	}
+
+	for i, s := range t.staged {
+		if err := renameFile(filepath.Join(stagingDir, fmt.Sprintf("%d.new", i)), paths[i]); err != nil {
+			keepStagingDir = !t.rollback(swaps[:i])
+			return fmt.Errorf("failed to move file into place: %s: %v", s.path, err)
+		}
+	}
+	logger.Tracef("committed %d file(s)", len(t.staged))
+	t.committed()
+	return nil
+}
+
+func (t *Transaction) committed() {
+	onCommit := t.onCommit
+	t.staged = nil
+	t.onCommit = nil
+	for _, fn := range onCommit {
+		fn()
+	}
+}
+
+// rollback restores the swapped files to their previous state, returning
+// false if any could not be restored.
+func (t *Transaction) rollback(swapped []swappedFile) bool {
+	ok := true
+	for i := len(swapped) - 1; i >= 0; i-- {
+		s := swapped[i]
+		var err error
+		if s.backup != "" {
+			err = renameFile(s.backup, s.path)
+		} else {
+			err = os.Remove(s.path)
+		}
+		if err != nil {
+			logger.Warnf("failed to roll back file: %s: %v", s.path, err)
+			ok = false
+		}
+	}
+	logger.Debugf("rolled back %d file(s)", len(swapped))
+	return ok
+}
+
+// commonDir returns the deepest directory containing all the given
+// absolute file paths.
+func commonDir(paths []string) string {
+	dir := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for {
+			if rel, err := filepath.Rel(dir, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
+
+// writeFileSynced writes data to a new file at path, and syncs it to disk
+// before returning.
+func writeFileSynced(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// WriteFileAtomic writes data to a temporary file in the same directory
+// as path, then renames it into place, so readers never see a partially
+// written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmpFile, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for: %s: %v", path, err)
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temporary file for: %s: %v", path, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temporary file for: %s: %v", path, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temporary file for: %s: %v", path, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on temporary file for: %s: %v", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to move temporary file into place: %s: %v", path, err)
+	}
+	return nil
+}
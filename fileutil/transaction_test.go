@@ -0,0 +1,161 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransaction_Commit(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, existing, "old")
+	if err := os.Mkdir(filepath.Join(dir, "responses"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(dir, "responses", "response.json")
+
+	tx := NewTransaction()
+	tx.WriteFile(existing, []byte("first"), 0644)
+	tx.WriteFile(created, []byte("{}"), 0600)
+	tx.WriteFile(existing, []byte("new"), 0644)
+	committed := false
+	tx.OnCommit(func() {
+		committed = true
+		assertFileContent(t, created, "{}")
+	})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if !committed {
+		t.Errorf("expected OnCommit function to be called")
+	}
+
+	assertFileContent(t, existing, "new")
+	assertFileContent(t, created, "{}")
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected permissions 0600 on: %s, got: %v, stat error: %v", created, info.Mode().Perm(), err)
+	}
+	assertNoStagingDir(t, dir)
+}
+
+func TestTransaction_Commit_failedWrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, existing, "old")
+	created := filepath.Join(dir, "response.json")
+
+	staged := 0
+	stageFile = func(path string, data []byte, perm os.FileMode) error {
+		if staged++; staged > 2 {
+			return errors.New("disk full")
+		}
+		return writeFileSynced(path, data, perm)
+	}
+	defer func() { stageFile = writeFileSynced }()
+
+	tx := NewTransaction()
+	tx.WriteFile(existing, []byte("new"), 0644)
+	tx.WriteFile(created, []byte("{}"), 0644)
+	tx.OnCommit(func() {
+		t.Errorf("expected OnCommit function not to be called")
+	})
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Commit() error = %v, want disk full", err)
+	}
+
+	assertFileContent(t, existing, "old")
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("expected file not to be created: %s, stat error: %v", created, err)
+	}
+	assertNoStagingDir(t, dir)
+}
+
+func TestTransaction_Commit_failedRename(t *testing.T) {
+	dir := t.TempDir()
+	created := filepath.Join(dir, "response.json")
+	existing := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, existing, "old")
+	failing := filepath.Join(dir, "other.yaml")
+
+	renameFile = func(oldPath string, newPath string) error {
+		if newPath == failing {
+			return errors.New("device busy")
+		}
+		return os.Rename(oldPath, newPath)
+	}
+	defer func() { renameFile = os.Rename }()
+
+	tx := NewTransaction()
+	tx.WriteFile(created, []byte("{}"), 0644)
+	tx.WriteFile(existing, []byte("new"), 0644)
+	tx.WriteFile(failing, []byte("new"), 0644)
+	tx.OnCommit(func() {
+		t.Errorf("expected OnCommit function not to be called")
+	})
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "device busy") {
+		t.Fatalf("Commit() error = %v, want device busy", err)
+	}
+
+	assertFileContent(t, existing, "old")
+	for _, path := range []string{created, failing} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected file to be rolled back: %s, stat error: %v", path, err)
+		}
+	}
+	assertNoStagingDir(t, dir)
+}
+
+func Test_commonDir(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{name: "single file", paths: []string{"/a/b/c.yaml"}, want: "/a/b"},
+		{name: "same directory", paths: []string{"/a/b/c.yaml", "/a/b/d.yaml"}, want: "/a/b"},
+		{name: "subdirectory", paths: []string{"/a/b/c.yaml", "/a/b/d/e.json"}, want: "/a/b"},
+		{name: "parent directory", paths: []string{"/a/b/d/e.json", "/a/b/c.yaml"}, want: "/a/b"},
+		{name: "sibling directories", paths: []string{"/a/b/c.yaml", "/a/d/e.json"}, want: "/a"},
+		{name: "similar prefix", paths: []string{"/a/b/c.yaml", "/a/bc/d.yaml"}, want: "/a"},
+		{name: "root", paths: []string{"/a/b.yaml", "/c/d.yaml"}, want: "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, p := range tt.paths {
+				paths = append(paths, filepath.FromSlash(p))
+			}
+			if got := commonDir(paths); got != filepath.FromSlash(tt.want) {
+				t.Errorf("commonDir() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFileContent(t *testing.T, path string, want string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("failed to read file: %s: %v", path, err)
+	} else if string(content) != want {
+		t.Errorf("file %s content = %q, want %q", path, content, want)
+	}
+}
+
+func assertNoStagingDir(t *testing.T, dir string) {
+	matches, err := filepath.Glob(filepath.Join(dir, ".imposter-tx-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("expected staging directory to be removed, found: %v", matches)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphql
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/logging"
+	"path/filepath"
+)
+
+var logger = logging.GetLogger()
+
+// DiscoverSchemas finds GraphQL schema files within the given directory.
+// Files containing operations, rather than type definitions, are ignored.
+// It returns fully qualified paths to the files discovered.
+func DiscoverSchemas(configDir string) []string {
+	var schemas []string
+
+	candidates := fileutil.FindFilesWithExtension(configDir, ".graphql", ".graphqls", ".gql")
+	for _, candidate := range candidates {
+		fullyQualifiedPath := filepath.Join(configDir, candidate)
+		schema, err := Parse(fullyQualifiedPath)
+		if err != nil {
+			logger.Debugf("skipping file that is not a GraphQL schema: %v", err)
+			continue
+		}
+		if len(schema.Operations()) > 0 {
+			schemas = append(schemas, fullyQualifiedPath)
+		}
+	}
+
+	return schemas
+}
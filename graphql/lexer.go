@@ -0,0 +1,114 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenString
+	tokenNumber
+	tokenPunctuator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+}
+
+// tokenise splits a GraphQL document into tokens. Comments, commas
+// and whitespace are ignored, as they are insignificant in GraphQL.
+func tokenise(source string) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "..."):
+			tokens = append(tokens, token{kind: tokenPunctuator, value: "...", line: line})
+			i += 3
+		case strings.ContainsRune("!$&()[]{}:=@|", rune(c)):
+			tokens = append(tokens, token{kind: tokenPunctuator, value: string(c), line: line})
+			i++
+		case strings.HasPrefix(source[i:], `"""`):
+			end := strings.Index(source[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string at line %d", line)
+			}
+			value := source[i+3 : i+3+end]
+			tokens = append(tokens, token{kind: tokenString, value: value, line: line})
+			line += strings.Count(value, "\n")
+			i += end + 6
+		case c == '"':
+			j := i + 1
+			for j < len(source) && source[j] != '"' {
+				if source[j] == '\\' {
+					j++
+				} else if source[j] == '\n' {
+					return nil, fmt.Errorf("unterminated string at line %d", line)
+				}
+				j++
+			}
+			if j >= len(source) {
+				return nil, fmt.Errorf("unterminated string at line %d", line)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: source[i+1 : j], line: line})
+			i = j + 1
+		case c == '-' || isDigit(c):
+			j := i + 1
+			for j < len(source) && (isDigit(source[j]) || strings.ContainsRune(".eE+-", rune(source[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: source[i:j], line: line})
+			i = j
+		case isNameStart(c):
+			j := i + 1
+			for j < len(source) && (isNameStart(source[j]) || isDigit(source[j])) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: source[i:j], line: line})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at line %d", c, line)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, line: line}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
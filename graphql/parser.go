@@ -0,0 +1,522 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphql
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"os"
+	"strings"
+)
+
+type OperationType string
+
+const (
+	OperationQuery    OperationType = "query"
+	OperationMutation OperationType = "mutation"
+)
+
+// Operation is a field of the query or mutation root type.
+type Operation struct {
+	Type  OperationType
+	Field string
+}
+
+// Schema is the subset of a GraphQL schema needed to generate
+// configuration for the graphql plugin.
+type Schema struct {
+	QueryType    string
+	MutationType string
+	types        map[string]*typeDefinition
+	typeOrder    []string
+}
+
+type typeDefinition struct {
+	kind       string
+	name       string
+	fields     []fieldDefinition
+	interfaces []string
+	members    []string
+	enumValues []string
+}
+
+type fieldDefinition struct {
+	name string
+	typ  *typeRef
+}
+
+// typeRef is either a named type or, if elem is set, a list.
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+// definitionKeywords start a definition, so they end an
+// implements list written in the legacy, space separated, form.
+var definitionKeywords = []string{"schema", "scalar", "type", "interface", "union", "enum", "input", "directive", "extend"}
+
+// Parse reads the types of a GraphQL schema written in the schema
+// definition language (SDL). Extensions of a type are merged into it.
+func Parse(schemaFilePath string) (*Schema, error) {
+	content, err := os.ReadFile(schemaFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading GraphQL schema: %v: %v", schemaFilePath, err)
+	}
+	schema, err := parseSchema(strings.TrimPrefix(string(content), "\ufeff"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GraphQL schema: %v: %v", schemaFilePath, err)
+	}
+	return schema, nil
+}
+
+func parseSchema(source string) (*Schema, error) {
+	tokens, err := tokenise(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	schema := &Schema{types: make(map[string]*typeDefinition)}
+	for p.peek().kind != tokenEOF {
+		if err := p.parseDefinition(schema); err != nil {
+			return nil, err
+		}
+	}
+	if schema.QueryType == "" && schema.types["Query"] != nil {
+		schema.QueryType = "Query"
+	}
+	if schema.MutationType == "" && schema.types["Mutation"] != nil {
+		schema.MutationType = "Mutation"
+	}
+	return schema, nil
+}
+
+// Operations returns the fields of the query and mutation root types,
+// in the order they are declared.
+func (s *Schema) Operations() []Operation {
+	var operations []Operation
+	for _, root := range []struct {
+		operationType OperationType
+		typeName      string
+	}{{OperationQuery, s.QueryType}, {OperationMutation, s.MutationType}} {
+		if definition := s.types[root.typeName]; definition != nil {
+			for _, field := range definition.fields {
+				operations = append(operations, Operation{Type: root.operationType, Field: field.name})
+			}
+		}
+	}
+	return operations
+}
+
+func (s *Schema) define(definition *typeDefinition) {
+	existing := s.types[definition.name]
+	if existing == nil {
+		s.types[definition.name] = definition
+		s.typeOrder = append(s.typeOrder, definition.name)
+		return
+	}
+	existing.fields = append(existing.fields, definition.fields...)
+	existing.interfaces = append(existing.interfaces, definition.interfaces...)
+	existing.members = append(existing.members, definition.members...)
+	existing.enumValues = append(existing.enumValues, definition.enumValues...)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekIs(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunctuator && t.value == value
+}
+
+func (p *parser) expect(value string) error {
+	if t := p.next(); t.kind != tokenPunctuator || t.value != value {
+		return fmt.Errorf("expected %q but found %q at line %d", value, t.value, t.line)
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", fmt.Errorf("expected name but found %q at line %d", t.value, t.line)
+	}
+	return t.value, nil
+}
+
+func (p *parser) skipDescription() {
+	if p.peek().kind == tokenString {
+		p.next()
+	}
+}
+
+func (p *parser) parseDefinition(schema *Schema) error {
+	p.skipDescription()
+	keyword, err := p.expectName()
+	if err != nil {
+		return err
+	}
+	if keyword == "extend" {
+		if keyword, err = p.expectName(); err != nil {
+			return err
+		}
+	}
+
+	switch keyword {
+	case "schema":
+		return p.parseSchemaDefinition(schema)
+	case "directive":
+		return p.parseDirectiveDefinition()
+	case "scalar", "type", "interface", "union", "enum", "input":
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		definition := &typeDefinition{kind: keyword, name: name}
+		switch keyword {
+		case "type", "interface":
+			if definition.interfaces, err = p.parseImplements(); err != nil {
+				return err
+			}
+			if err = p.skipDirectives(); err != nil {
+				return err
+			}
+			definition.fields, err = p.parseFields()
+		case "union":
+			if err = p.skipDirectives(); err != nil {
+				return err
+			}
+			definition.members, err = p.parseUnionMembers()
+		case "enum":
+			if err = p.skipDirectives(); err != nil {
+				return err
+			}
+			definition.enumValues, err = p.parseEnumValues()
+		case "input":
+			if err = p.skipDirectives(); err != nil {
+				return err
+			}
+			_, err = p.parseFields()
+		default:
+			err = p.skipDirectives()
+		}
+		if err != nil {
+			return err
+		}
+		schema.define(definition)
+		return nil
+	default:
+		return fmt.Errorf("unexpected %q at line %d - not a schema definition", keyword, p.tokens[p.pos-1].line)
+	}
+}
+
+func (p *parser) parseSchemaDefinition(schema *Schema) error {
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if !p.peekIs("{") {
+		return nil
+	}
+	p.next()
+	for !p.peekIs("}") {
+		operationType, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err = p.expect(":"); err != nil {
+			return err
+		}
+		typeName, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		switch OperationType(operationType) {
+		case OperationQuery:
+			schema.QueryType = typeName
+		case OperationMutation:
+			schema.MutationType = typeName
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseDirectiveDefinition() error {
+	if err := p.expect("@"); err != nil {
+		return err
+	}
+	if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if err := p.skipArgumentDefinitions(); err != nil {
+		return err
+	}
+	if p.peek().kind == tokenName && p.peek().value == "repeatable" {
+		p.next()
+	}
+	if on, err := p.expectName(); err != nil {
+		return err
+	} else if on != "on" {
+		return fmt.Errorf("expected \"on\" but found %q at line %d", on, p.tokens[p.pos-1].line)
+	}
+	if p.peekIs("|") {
+		p.next()
+	}
+	if _, err := p.expectName(); err != nil {
+		return err
+	}
+	for p.peekIs("|") {
+		p.next()
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseImplements() ([]string, error) {
+	if p.peek().kind != tokenName || p.peek().value != "implements" {
+		return nil, nil
+	}
+	p.next()
+	if p.peekIs("&") {
+		p.next()
+	}
+	var interfaces []string
+	for {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		interfaces = append(interfaces, name)
+		if p.peekIs("&") {
+			p.next()
+		} else if t := p.peek(); t.kind != tokenName || stringutil.Contains(definitionKeywords, t.value) {
+			return interfaces, nil
+		}
+	}
+}
+
+// parseFields parses the fields of an object, interface or input type.
+// Argument and default values are not needed, so are skipped.
+func (p *parser) parseFields() ([]fieldDefinition, error) {
+	if !p.peekIs("{") {
+		return nil, nil
+	}
+	p.next()
+	var fields []fieldDefinition
+	for !p.peekIs("}") {
+		p.skipDescription()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipArgumentDefinitions(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekIs("=") {
+			p.next()
+			if err = p.skipValue(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.skipDirectives(); err != nil {
+			return nil, err
+		}
+		fields = append(fields, fieldDefinition{name: name, typ: typ})
+	}
+	p.next()
+	return fields, nil
+}
+
+func (p *parser) skipArgumentDefinitions() error {
+	if !p.peekIs("(") {
+		return nil
+	}
+	p.next()
+	for !p.peekIs(")") {
+		p.skipDescription()
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if _, err := p.parseType(); err != nil {
+			return err
+		}
+		if p.peekIs("=") {
+			p.next()
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		if err := p.skipDirectives(); err != nil {
+			return err
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseType() (*typeRef, error) {
+	var ref *typeRef
+	if p.peekIs("[") {
+		p.next()
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		ref = &typeRef{elem: elem}
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		ref = &typeRef{name: name}
+	}
+	if p.peekIs("!") {
+		p.next()
+		ref.nonNull = true
+	}
+	return ref, nil
+}
+
+func (p *parser) parseUnionMembers() ([]string, error) {
+	if !p.peekIs("=") {
+		return nil, nil
+	}
+	p.next()
+	if p.peekIs("|") {
+		p.next()
+	}
+	var members []string
+	for {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, name)
+		if !p.peekIs("|") {
+			return members, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseEnumValues() ([]string, error) {
+	if !p.peekIs("{") {
+		return nil, nil
+	}
+	p.next()
+	var values []string
+	for !p.peekIs("}") {
+		p.skipDescription()
+		value, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipDirectives(); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	p.next()
+	return values, nil
+}
+
+func (p *parser) skipDirectives() error {
+	for p.peekIs("@") {
+		p.next()
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if !p.peekIs("(") {
+			continue
+		}
+		p.next()
+		for !p.peekIs(")") {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if err := p.expect(":"); err != nil {
+				return err
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		p.next()
+	}
+	return nil
+}
+
+func (p *parser) skipValue() error {
+	t := p.next()
+	switch {
+	case t.kind == tokenPunctuator && t.value == "$":
+		_, err := p.expectName()
+		return err
+	case t.kind == tokenPunctuator && t.value == "[":
+		for !p.peekIs("]") {
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		p.next()
+	case t.kind == tokenPunctuator && t.value == "{":
+		for !p.peekIs("}") {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if err := p.expect(":"); err != nil {
+				return err
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		p.next()
+	case t.kind == tokenPunctuator || t.kind == tokenEOF:
+		return fmt.Errorf("expected value but found %q at line %d", t.value, t.line)
+	}
+	return nil
+}
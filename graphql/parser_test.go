@@ -0,0 +1,171 @@
+package graphql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const petstoreSchema = `
+"""
+The pet store.
+"""
+schema {
+  query: RootQuery
+  mutation: RootMutation
+}
+
+interface Node {
+  id: ID!
+}
+
+type Pet implements Node & Named @key(fields: "id") {
+  id: ID!
+  "The name of the pet"
+  name: String!
+  status: Status
+  tags: [String!]!
+  owner: Owner
+  weight: Float
+}
+
+type Owner implements Node {
+  id: ID!
+  pets(first: Int = 10, filter: PetFilter = {status: AVAILABLE}): [Pet]
+}
+
+enum Status {
+  AVAILABLE
+  SOLD @deprecated(reason: "no longer used")
+}
+
+input PetFilter {
+  status: Status = AVAILABLE
+}
+
+union SearchResult = | Pet | Owner
+
+scalar DateTime
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+
+type RootQuery {
+  pet(id: ID!): Pet
+  search(term: String): [SearchResult!]!
+}
+
+extend type RootQuery {
+  node(id: ID!): Node
+}
+
+type RootMutation {
+  addPet(name: String!): Pet!
+}
+`
+
+func writeTestSchema(t *testing.T, dir string, name string, content string) string {
+	filePath := filepath.Join(dir, name)
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestDiscoverSchemas(t *testing.T) {
+	dir := t.TempDir()
+	writeTestSchema(t, dir, "petstore.graphql", petstoreSchema)
+	writeTestSchema(t, dir, "query.graphql", `query { pet(id: "1") { name } }`)
+
+	got := DiscoverSchemas(dir)
+	if len(got) != 1 || filepath.Base(got[0]) != "petstore.graphql" {
+		t.Errorf("DiscoverSchemas() = %v, want [petstore.graphql]", got)
+	}
+}
+
+func TestSchema_Operations(t *testing.T) {
+	schema, err := parseSchema(petstoreSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Operation{
+		{Type: OperationQuery, Field: "pet"},
+		{Type: OperationQuery, Field: "search"},
+		{Type: OperationQuery, Field: "node"},
+		{Type: OperationMutation, Field: "addPet"},
+	}
+	if got := schema.Operations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Operations() = %v, want %v", got, want)
+	}
+}
+
+func TestSchema_SampleResponse(t *testing.T) {
+	schema, err := parseSchema(petstoreSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		operation Operation
+		want      string
+	}{
+		{
+			name:      "object with recursive type",
+			operation: Operation{Type: OperationQuery, Field: "pet"},
+			want: `{
+  "data": {
+    "pet": {
+      "id": "1",
+      "name": "string",
+      "status": "AVAILABLE",
+      "tags": [
+        "string"
+      ],
+      "owner": {
+        "id": "1",
+        "pets": []
+      },
+      "weight": 0.0
+    }
+  }
+}
+`,
+		},
+		{
+			name:      "interface",
+			operation: Operation{Type: OperationQuery, Field: "node"},
+			want: `{
+  "data": {
+    "node": {
+      "__typename": "Pet",
+      "id": "1",
+      "name": "string",
+      "status": "AVAILABLE",
+      "tags": [
+        "string"
+      ],
+      "owner": {
+        "id": "1",
+        "pets": []
+      },
+      "weight": 0.0
+    }
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(schema.SampleResponse(tt.operation)); got != tt.want {
+				t.Errorf("SampleResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	if _, err := parseSchema(`type Pet { name: }`); err == nil {
+		t.Error("expected error for invalid schema")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// SampleResponse generates a sample response for the operation, in the
+// form returned by a GraphQL server, populated from the schema types.
+func (s *Schema) SampleResponse(operation Operation) []byte {
+	rootType := s.QueryType
+	if operation.Type == OperationMutation {
+		rootType = s.MutationType
+	}
+
+	var value interface{}
+	if definition := s.types[rootType]; definition != nil {
+		for _, field := range definition.fields {
+			if field.name == operation.Field {
+				value = s.sampleValue(field.typ, make(map[string]bool))
+				break
+			}
+		}
+	}
+	response := jsonObject{{"data", jsonObject{{operation.Field, value}}}}
+	sample, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		logger.Fatalf("unable to marshal sample response: %v", err)
+	}
+	return append(sample, '\n')
+}
+
+// sampleValue returns a sample of the given type. Lists contain a single
+// item. Object types already being sampled are null, so recursive types
+// do not repeat indefinitely.
+func (s *Schema) sampleValue(ref *typeRef, visiting map[string]bool) interface{} {
+	if ref.elem != nil {
+		item := s.sampleValue(ref.elem, visiting)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	}
+
+	switch ref.name {
+	case "String":
+		return "string"
+	case "ID":
+		return "1"
+	case "Int":
+		return 0
+	case "Float":
+		return json.Number("0.0")
+	case "Boolean":
+		return false
+	}
+
+	definition := s.types[ref.name]
+	if definition == nil {
+		logger.Debugf("type not found in GraphQL schema: %s", ref.name)
+		return nil
+	}
+	switch definition.kind {
+	case "enum":
+		if len(definition.enumValues) > 0 {
+			return definition.enumValues[0]
+		}
+		return nil
+	case "type", "interface", "union":
+		return s.sampleObject(definition, visiting)
+	default:
+		return "string"
+	}
+}
+
+// sampleObject samples an object type. Interfaces and unions are sampled
+// using their first implementation or member, with its __typename.
+func (s *Schema) sampleObject(definition *typeDefinition, visiting map[string]bool) interface{} {
+	concrete := definition
+	switch definition.kind {
+	case "interface":
+		concrete = s.firstImplementation(definition.name)
+	case "union":
+		concrete = nil
+		if len(definition.members) > 0 {
+			concrete = s.types[definition.members[0]]
+		}
+	}
+	if concrete == nil || visiting[concrete.name] {
+		return nil
+	}
+	visiting[concrete.name] = true
+	defer delete(visiting, concrete.name)
+
+	object := jsonObject{}
+	if concrete != definition {
+		object = append(object, jsonField{"__typename", concrete.name})
+	}
+	for _, field := range concrete.fields {
+		object = append(object, jsonField{field.name, s.sampleValue(field.typ, visiting)})
+	}
+	return object
+}
+
+func (s *Schema) firstImplementation(interfaceName string) *typeDefinition {
+	for _, typeName := range s.typeOrder {
+		definition := s.types[typeName]
+		if definition.kind != "type" {
+			continue
+		}
+		for _, implemented := range definition.interfaces {
+			if implemented == interfaceName {
+				return definition
+			}
+		}
+	}
+	return nil
+}
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// jsonObject preserves the order in which fields are declared
+// in the schema when marshalled.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"encoding/json"
+	"gatehill.io/imposter/asyncapi"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"path/filepath"
+	"strings"
+)
+
+// messagingPlugins maps AsyncAPI server protocols to the
+// Imposter plugins used to mock them.
+var messagingPlugins = map[string]string{
+	"kafka":        "kafka",
+	"kafka-secure": "kafka",
+	"sqs":          "sqs",
+	"sns":          "sns",
+	"amqp":         "amqp",
+	"amqps":        "amqp",
+	"mqtt":         "mqtt",
+	"secure-mqtt":  "mqtt",
+}
+
+const defaultMessagingPlugin = "kafka"
+
+func writeAsyncApiMockConfig(tx *fileutil.Transaction, specFilePath string, generateResources bool, forceOverwrite bool, resourceOptions ResourceGenerationOptions) {
+	spec, err := asyncapi.Parse(specFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse AsyncAPI document: %v: %v", specFilePath, err))
+	}
+
+	var resources []Resource
+	if generateResources {
+		resources = buildAsyncApiResources(tx, spec, specFilePath, forceOverwrite, resourceOptions)
+	} else {
+		logger.Debug("skipping resource generation")
+	}
+	options := ConfigGenerationOptions{
+		PluginName:     getMessagingPlugin(spec.Protocol),
+		ScriptEngine:   resourceOptions.ScriptEngine,
+		ScriptFileName: resourceOptions.ScriptFileName,
+		SpecFilePath:   specFilePath,
+	}
+	writeMockConfigAdjacent(tx, resourceOptions.anchorFor(specFilePath), resources, forceOverwrite, options)
+}
+
+// getMessagingPlugin returns the plugin for the protocol of the servers
+// in the AsyncAPI document, falling back to the Kafka plugin.
+func getMessagingPlugin(protocol string) string {
+	if plugin, found := messagingPlugins[protocol]; found {
+		return plugin
+	}
+	if protocol == "" {
+		logger.Infof("no servers declared in AsyncAPI document - using %s plugin", defaultMessagingPlugin)
+	} else {
+		logger.Warnf("no messaging plugin for protocol: %s - using %s plugin", protocol, defaultMessagingPlugin)
+	}
+	return defaultMessagingPlugin
+}
+
+// buildAsyncApiResources generates a resource for each channel in the
+// AsyncAPI document. Each resource returns an example payload of the first
+// message on the channel, which is written adjacent to the document.
+func buildAsyncApiResources(tx *fileutil.Transaction, spec *asyncapi.Spec, specFilePath string, forceOverwrite bool, options ResourceGenerationOptions) []Resource {
+	var resources []Resource
+	for _, channel := range spec.Channels {
+		if len(channel.Messages) == 0 {
+			logger.Debugf("skipping channel without messages: %s", channel.Name)
+			continue
+		}
+		if len(channel.Messages) > 1 {
+			logger.Debugf("channel %s has %d messages - using the first", channel.Name, len(channel.Messages))
+		}
+		message := channel.Messages[0]
+
+		payload, extension, err := marshalPayload(message)
+		if err != nil {
+			failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to generate payload for channel: %s: %v", channel.Name, err))
+		}
+		payloadFilePath := fileutil.GenerateFilePathAdjacentToFile(options.anchorFor(specFilePath), "-"+sanitiseFileName(channel.Name)+"-message"+extension, forceOverwrite)
+		tx.WriteFile(payloadFilePath, payload, 0644)
+		tx.OnCommit(func() {
+			logger.Debugf("wrote payload file: %v", payloadFilePath)
+		})
+
+		resource := Resource{
+			Channel: channel.Name,
+			Response: &ResponseConfig{
+				StaticFile: filepath.Base(payloadFilePath),
+			},
+		}
+		if IsScriptEngineEnabled(options.ScriptEngine) {
+			resource.Response.ScriptFile = options.ScriptFileName
+		}
+		resource.Response.Delay = options.DelayProfile.BuildDelay(channel.Name, nil)
+		resources = append(resources, resource)
+	}
+	logger.Debugf("generated %d resources from AsyncAPI document", len(resources))
+	return resources
+}
+
+// marshalPayload returns the example payload of the message, and the
+// file extension for it. Payloads not sent as JSON are written as text.
+func marshalPayload(message asyncapi.Message) ([]byte, string, error) {
+	if !message.IsJson() {
+		if text, ok := message.Payload.(string); ok {
+			return []byte(text), ".txt", nil
+		}
+	}
+	payload, err := json.MarshalIndent(message.Payload, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	return append(payload, '\n'), ".json", nil
+}
+
+// sanitiseFileName replaces the characters of a channel name that
+// are not safe to use in a file name, such as '/'.
+func sanitiseFileName(name string) string {
+	sanitised := strings.Map(func(c rune) rune {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' {
+			return c
+		}
+		return '-'
+	}, name)
+	if sanitised = strings.Trim(sanitised, "-."); sanitised == "" {
+		return "channel"
+	}
+	return sanitised
+}
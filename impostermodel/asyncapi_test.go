@@ -0,0 +1,81 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_writeAsyncApiMockConfig(t *testing.T) {
+	dir := t.TempDir()
+	specFilePath := filepath.Join(dir, "events.yaml")
+	spec := `asyncapi: 2.6.0
+servers:
+  production:
+    url: sqs.eu-west-1.amazonaws.com
+    protocol: sqs
+channels:
+  user/signedup:
+    subscribe:
+      message:
+        payload:
+          type: object
+          properties:
+            email:
+              type: string
+              format: email
+`
+	if err := os.WriteFile(specFilePath, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeAsyncApiMockConfig(tx, specFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "events-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"plugin: sqs",
+		"specFile: events.yaml",
+		"channel: user/signedup",
+		"staticFile: events-user-signedup-message.json",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+
+	payload, err := os.ReadFile(filepath.Join(dir, "events-user-signedup-message.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), `"email": "user@example.com"`) {
+		t.Errorf("unexpected payload:\n%s", payload)
+	}
+}
+
+func Test_getMessagingPlugin(t *testing.T) {
+	tests := []struct {
+		protocol string
+		want     string
+	}{
+		{protocol: "kafka-secure", want: "kafka"},
+		{protocol: "sns", want: "sns"},
+		{protocol: "", want: "kafka"},
+		{protocol: "ws", want: "kafka"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			if got := getMessagingPlugin(tt.protocol); got != tt.want {
+				t.Errorf("getMessagingPlugin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
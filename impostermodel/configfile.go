@@ -17,10 +17,11 @@ limitations under the License.
 package impostermodel
 
 import (
+	"gatehill.io/imposter/failure"
 	"gatehill.io/imposter/fileutil"
 	"gatehill.io/imposter/logging"
-	"gatehill.io/imposter/openapi"
-	"os"
+	"gatehill.io/imposter/protobuf"
+	"gatehill.io/imposter/stringutil"
 	"path"
 	"path/filepath"
 	"sigs.k8s.io/yaml"
@@ -31,28 +32,121 @@ type ConfigGenerationOptions struct {
 	ScriptEngine   ScriptEngine
 	ScriptFileName string
 	SpecFilePath   string
+	WsdlFilePath   string
+	SchemaFilePath string
+	ProtoFilePath  string
+
+	// Security is applied to all resources, unless overridden.
+	Security *SecurityConfig
+
+	// Cors allows browser-based clients on other origins to call the mock.
+	Cors *CorsConfig
 }
 
 var logger = logging.GetLogger()
 
-func Create(configDir string, generateResources bool, forceOverwrite bool, scriptEngine ScriptEngine, requireOpenApi bool) {
-	openApiSpecs := openapi.DiscoverOpenApiSpecs(configDir)
-	logger.Infof("found %d OpenAPI spec(s)", len(openApiSpecs))
+// Create generates Imposter configuration in the configDir. The generated
+// files are only written once generation has completed, so an interrupted
+// or failed run does not leave partial configuration behind.
+//
+// OpenAPI specs are mocked with the openapi plugin, WSDL files with the
+// soap plugin, GraphQL schemas with the graphql plugin, .proto files or
+// descriptor sets with the grpc plugin and AsyncAPI documents with the
+// messaging plugin for their protocol. If none are found, a rest mock is
+// created, unless requireSpecs is set.
+func Create(configDir string, generateResources bool, forceOverwrite bool, options ResourceGenerationOptions, requireSpecs bool) {
+	scriptEngine := options.ScriptEngine
+	tx := fileutil.NewTransaction()
+	specs := discoverSpecFiles(configDir, options.ProtoImportPaths)
 
-	if len(openApiSpecs) > 0 {
-		logger.Tracef("using openapi plugin")
-		for _, openApiSpec := range openApiSpecs {
-			scriptFileName := getScriptFileName(openApiSpec, scriptEngine, forceOverwrite)
-			writeOpenapiMockConfig(openApiSpec, generateResources, forceOverwrite, scriptEngine, scriptFileName)
-		}
-	} else if !requireOpenApi {
+	if len(specs.all()) > 0 {
+		writeSpecMockConfigs(tx, specs, generateResources, forceOverwrite, options)
+	} else if !requireSpecs {
 		logger.Infof("falling back to rest plugin")
 		syntheticMockPath := path.Join(configDir, "mock.txt")
-		_, responseFilePath := generateRestMockFiles(configDir)
-		scriptFileName := getScriptFileName(syntheticMockPath, scriptEngine, forceOverwrite)
-		writeRestMockConfig(syntheticMockPath, responseFilePath, generateResources, forceOverwrite, scriptEngine, scriptFileName)
+		_, responseFilePath := generateRestMockFiles(tx, configDir)
+		scriptFileName := getScriptFileName(tx, syntheticMockPath, scriptEngine, options.TypeScript, forceOverwrite)
+		writeRestMockConfig(tx, syntheticMockPath, responseFilePath, generateResources, forceOverwrite, scriptEngine, scriptFileName, options.Cors)
 	} else {
-		logger.Fatalf("no OpenAPI specs found in: %s", configDir)
+		failure.Fatal(failure.New(failure.CodeGenerateNoSpecs, "no OpenAPI specs, WSDL files, GraphQL schemas, protobuf services or AsyncAPI specs found in: %s", configDir))
+	}
+
+	if options.TypeScript && scriptEngine == ScriptEngineJavaScript {
+		writeTypeScriptProject(tx, configDir, forceOverwrite)
+	}
+
+	if err := tx.Commit(); err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateWriteFailed, "failed to write Imposter configuration to: %s: %v", configDir, err))
+	}
+}
+
+// CreateFromSpecs generates Imposter configuration for the spec files at
+// the given paths, which may be in different directories, in the same way
+// as Create. Each path is a spec file, or a directory, in which case all
+// the specs in it are used. The configuration for each spec is written
+// alongside it.
+func CreateFromSpecs(specPaths []string, generateResources bool, forceOverwrite bool, options ResourceGenerationOptions) {
+	specs := selectSpecFiles(specPaths, options.ProtoImportPaths)
+	if len(specs.all()) == 0 {
+		failure.Fatal(failure.New(failure.CodeGenerateNoSpecs, "no OpenAPI specs, WSDL files, GraphQL schemas, protobuf services or AsyncAPI specs found in: %v", specPaths))
+	}
+
+	tx := fileutil.NewTransaction()
+	writeSpecMockConfigs(tx, specs, generateResources, forceOverwrite, options)
+
+	if options.TypeScript && options.ScriptEngine == ScriptEngineJavaScript {
+		var dirs []string
+		for _, specFile := range specs.all() {
+			dirs = append(dirs, filepath.Dir(specFile))
+		}
+		for _, dir := range stringutil.Unique(dirs) {
+			writeTypeScriptProject(tx, dir, forceOverwrite)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateWriteFailed, "failed to write Imposter configuration: %v", err))
+	}
+}
+
+// writeSpecMockConfigs stages the configuration for each of the specs.
+// The names of the files generated for each spec are based on the name
+// of the spec, so are distinct from those of other specs.
+func writeSpecMockConfigs(tx *fileutil.Transaction, specs specFiles, generateResources bool, forceOverwrite bool, options ResourceGenerationOptions) {
+	anchors := buildAnchorFilePaths(specs.all())
+	specOptionsFor := func(specFile string) ResourceGenerationOptions {
+		specOptions := options
+		specOptions.AnchorFilePath = anchors[specFile]
+		specOptions.ScriptFileName = getScriptFileName(tx, anchors[specFile], options.ScriptEngine, options.TypeScript, forceOverwrite)
+		return specOptions
+	}
+
+	if len(specs.openApi) > 0 {
+		logger.Tracef("using openapi plugin")
+	}
+	for _, openApiSpec := range specs.openApi {
+		writeOpenapiMockConfig(tx, openApiSpec, generateResources, forceOverwrite, specOptionsFor(openApiSpec))
+	}
+	if len(specs.wsdl) > 0 {
+		logger.Tracef("using soap plugin")
+	}
+	for _, wsdlFile := range specs.wsdl {
+		writeSoapMockConfig(tx, wsdlFile, generateResources, forceOverwrite, specOptionsFor(wsdlFile))
+	}
+	if len(specs.graphql) > 0 {
+		logger.Tracef("using graphql plugin")
+	}
+	for _, graphqlSchema := range specs.graphql {
+		writeGraphqlMockConfig(tx, graphqlSchema, generateResources, forceOverwrite, specOptionsFor(graphqlSchema))
+	}
+	if len(specs.proto) > 0 {
+		logger.Tracef("using grpc plugin")
+	}
+	for _, protoFile := range specs.proto {
+		writeGrpcMockConfig(tx, protoFile, generateResources, forceOverwrite, specOptionsFor(protoFile))
+	}
+	for _, asyncApiSpec := range specs.asyncApi {
+		writeAsyncApiMockConfig(tx, asyncApiSpec, generateResources, forceOverwrite, specOptionsFor(asyncApiSpec))
 	}
 }
 
@@ -63,6 +157,21 @@ func GenerateConfig(options ConfigGenerationOptions, resources []Resource) []byt
 	if options.SpecFilePath != "" {
 		pluginConfig.SpecFile = filepath.Base(options.SpecFilePath)
 	}
+	if options.WsdlFilePath != "" {
+		pluginConfig.WsdlFile = filepath.Base(options.WsdlFilePath)
+	}
+	if options.SchemaFilePath != "" {
+		pluginConfig.SchemaFile = filepath.Base(options.SchemaFilePath)
+	}
+	if options.ProtoFilePath != "" {
+		if protobuf.IsDescriptorSet(options.ProtoFilePath) {
+			pluginConfig.DescriptorSetFile = filepath.Base(options.ProtoFilePath)
+		} else {
+			pluginConfig.ProtoFile = filepath.Base(options.ProtoFilePath)
+		}
+	}
+	pluginConfig.Security = options.Security
+	pluginConfig.Cors = options.Cors
 	if len(resources) > 0 {
 		pluginConfig.Resources = resources
 	} else {
@@ -80,23 +189,15 @@ func GenerateConfig(options ConfigGenerationOptions, resources []Resource) []byt
 	return config
 }
 
-func writeMockConfigAdjacent(anchorFilePath string, resources []Resource, forceOverwrite bool, options ConfigGenerationOptions) {
+func writeMockConfigAdjacent(tx *fileutil.Transaction, anchorFilePath string, resources []Resource, forceOverwrite bool, options ConfigGenerationOptions) {
 	configFilePath := fileutil.GenerateFilePathAdjacentToFile(anchorFilePath, "-config.yaml", forceOverwrite)
-	writeMockConfig(configFilePath, resources, forceOverwrite, options)
+	writeMockConfig(tx, configFilePath, resources, options)
 }
 
-func writeMockConfig(configFilePath string, resources []Resource, forceOverwrite bool, options ConfigGenerationOptions) {
-	configFile, err := os.Create(configFilePath)
-	if err != nil {
-		logger.Fatal(err)
-	}
-	defer configFile.Close()
-
+func writeMockConfig(tx *fileutil.Transaction, configFilePath string, resources []Resource, options ConfigGenerationOptions) {
 	config := GenerateConfig(options, resources)
-	_, err = configFile.Write(config)
-	if err != nil {
-		logger.Fatal(err)
-	}
-
-	logger.Infof("wrote Imposter config: %v", configFilePath)
+	tx.WriteFile(configFilePath, config, 0644)
+	tx.OnCommit(func() {
+		logger.Infof("wrote Imposter config: %v", configFilePath)
+	})
 }
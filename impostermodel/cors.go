@@ -0,0 +1,45 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+// CorsAllOrigins allows any origin, by echoing the origin of the request.
+const CorsAllOrigins = "all"
+
+// corsMaxAge is the number of seconds for which browsers can cache the
+// result of a preflight request.
+const corsMaxAge = 300
+
+var corsAllowHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"}
+
+var corsAllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// BuildCorsConfig returns CORS settings allowing the origins, such as
+// 'http://localhost:3000', to call the mock with the common methods and
+// headers. If no origins are given, any origin is allowed.
+func BuildCorsConfig(origins []string) *CorsConfig {
+	cors := &CorsConfig{
+		AllowHeaders: corsAllowHeaders,
+		AllowMethods: corsAllowMethods,
+		MaxAge:       corsMaxAge,
+	}
+	if len(origins) == 0 {
+		cors.AllowOrigins = CorsAllOrigins
+	} else {
+		cors.AllowOrigins = origins
+	}
+	return cors
+}
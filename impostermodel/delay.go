@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DelayProfileType string
+
+const (
+	// DelayProfileFixed delays every response by the same duration.
+	DelayProfileFixed DelayProfileType = "fixed"
+
+	// DelayProfileUniform delays each response by a random duration
+	// between a minimum and maximum.
+	DelayProfileUniform DelayProfileType = "uniform"
+
+	// DelayProfileNormal gives each resource a fixed delay, sampled from
+	// a normal distribution, so that resources vary as they would in a
+	// real system.
+	DelayProfileNormal DelayProfileType = "normal"
+
+	// DelayProfileEmpirical delays the responses for each resource by
+	// a random duration within the range observed when it was recorded.
+	DelayProfileEmpirical DelayProfileType = "empirical"
+)
+
+// DelayProfile generates response delays for resources. The zero
+// value does not generate delays.
+type DelayProfile struct {
+	Type DelayProfileType
+
+	// durations in milliseconds
+	Exact  int
+	Min    int
+	Max    int
+	Mean   float64
+	StdDev float64
+}
+
+// ParseDelayProfile parses a profile in one of the forms 'fixed:<ms>',
+// 'uniform:<min ms>-<max ms>', 'normal:<mean ms>,<std dev ms>' or 'empirical'.
+func ParseDelayProfile(profile string) (DelayProfile, error) {
+	if profile == "" {
+		return DelayProfile{}, nil
+	}
+	profileType, args, _ := strings.Cut(profile, ":")
+	switch DelayProfileType(profileType) {
+	case DelayProfileFixed:
+		exact, err := strconv.Atoi(args)
+		if err != nil || exact < 0 {
+			return DelayProfile{}, fmt.Errorf("invalid fixed delay profile: %s (expected fixed:<ms>)", profile)
+		}
+		return DelayProfile{Type: DelayProfileFixed, Exact: exact}, nil
+
+	case DelayProfileUniform:
+		minArg, maxArg, found := strings.Cut(args, "-")
+		min, minErr := strconv.Atoi(minArg)
+		max, maxErr := strconv.Atoi(maxArg)
+		if !found || minErr != nil || maxErr != nil || min < 0 || max < min {
+			return DelayProfile{}, fmt.Errorf("invalid uniform delay profile: %s (expected uniform:<min ms>-<max ms>)", profile)
+		}
+		return DelayProfile{Type: DelayProfileUniform, Min: min, Max: max}, nil
+
+	case DelayProfileNormal:
+		meanArg, stdDevArg, found := strings.Cut(args, ",")
+		mean, meanErr := strconv.ParseFloat(meanArg, 64)
+		stdDev, stdDevErr := strconv.ParseFloat(stdDevArg, 64)
+		if !found || meanErr != nil || stdDevErr != nil || mean < 0 || stdDev < 0 {
+			return DelayProfile{}, fmt.Errorf("invalid normal delay profile: %s (expected normal:<mean ms>,<std dev ms>)", profile)
+		}
+		return DelayProfile{Type: DelayProfileNormal, Mean: mean, StdDev: stdDev}, nil
+
+	case DelayProfileEmpirical:
+		if args != "" {
+			return DelayProfile{}, fmt.Errorf("invalid empirical delay profile: %s (expected empirical)", profile)
+		}
+		return DelayProfile{Type: DelayProfileEmpirical}, nil
+	}
+	return DelayProfile{}, fmt.Errorf("unsupported delay profile: %s (valid: fixed,uniform,normal,empirical)", profile)
+}
+
+// BuildDelay returns the delay for the resource with the given key, or nil
+// if no delay applies. Observed durations are only used by the empirical
+// profile.
+func (p DelayProfile) BuildDelay(resourceKey string, observed []time.Duration) *ResponseDelay {
+	var delay *ResponseDelay
+	switch p.Type {
+	case DelayProfileFixed:
+		delay = &ResponseDelay{Exact: p.Exact}
+	case DelayProfileUniform:
+		delay = &ResponseDelay{Min: p.Min, Max: p.Max}
+	case DelayProfileNormal:
+		delay = &ResponseDelay{Exact: sampleNormal(resourceKey, p.Mean, p.StdDev)}
+	case DelayProfileEmpirical:
+		delay = buildEmpiricalDelay(observed)
+	}
+	if delay == nil || (delay.Exact == 0 && delay.Max == 0) {
+		return nil
+	}
+	return delay
+}
+
+// sampleNormal samples from the distribution, seeded by the key, so that
+// a resource has the same delay each time configuration is generated.
+func sampleNormal(key string, mean float64, stdDev float64) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	return int(math.Max(0, math.Round(r.NormFloat64()*stdDev+mean)))
+}
+
+func buildEmpiricalDelay(observed []time.Duration) *ResponseDelay {
+	if len(observed) == 0 {
+		return nil
+	}
+	min, max := observed[0], observed[0]
+	for _, d := range observed[1:] {
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	minMs, maxMs := int(min.Milliseconds()), int(max.Milliseconds())
+	if minMs == maxMs {
+		return &ResponseDelay{Exact: minMs}
+	}
+	return &ResponseDelay{Min: minMs, Max: maxMs}
+}
@@ -0,0 +1,80 @@
+package impostermodel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDelayProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    DelayProfile
+		wantErr bool
+	}{
+		{name: "none", profile: "", want: DelayProfile{}},
+		{name: "fixed", profile: "fixed:250", want: DelayProfile{Type: DelayProfileFixed, Exact: 250}},
+		{name: "uniform", profile: "uniform:100-500", want: DelayProfile{Type: DelayProfileUniform, Min: 100, Max: 500}},
+		{name: "normal", profile: "normal:200,50", want: DelayProfile{Type: DelayProfileNormal, Mean: 200, StdDev: 50}},
+		{name: "empirical", profile: "empirical", want: DelayProfile{Type: DelayProfileEmpirical}},
+		{name: "uniform max less than min", profile: "uniform:500-100", wantErr: true},
+		{name: "fixed missing value", profile: "fixed", wantErr: true},
+		{name: "unsupported", profile: "pareto:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDelayProfile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDelayProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDelayProfile() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelayProfile_BuildDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  DelayProfile
+		observed []time.Duration
+		want     *ResponseDelay
+	}{
+		{name: "no profile", profile: DelayProfile{}, want: nil},
+		{name: "fixed", profile: DelayProfile{Type: DelayProfileFixed, Exact: 250}, want: &ResponseDelay{Exact: 250}},
+		{name: "uniform", profile: DelayProfile{Type: DelayProfileUniform, Min: 100, Max: 500}, want: &ResponseDelay{Min: 100, Max: 500}},
+		{name: "normal without deviation", profile: DelayProfile{Type: DelayProfileNormal, Mean: 200}, want: &ResponseDelay{Exact: 200}},
+		{
+			name:     "empirical range",
+			profile:  DelayProfile{Type: DelayProfileEmpirical},
+			observed: []time.Duration{120 * time.Millisecond, 80 * time.Millisecond, 300 * time.Millisecond},
+			want:     &ResponseDelay{Min: 80, Max: 300},
+		},
+		{
+			name:     "empirical single observation",
+			profile:  DelayProfile{Type: DelayProfileEmpirical},
+			observed: []time.Duration{150 * time.Millisecond},
+			want:     &ResponseDelay{Exact: 150},
+		},
+		{name: "empirical without observations", profile: DelayProfile{Type: DelayProfileEmpirical}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.BuildDelay("GET /pets", tt.observed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sampleNormal(t *testing.T) {
+	first := sampleNormal("GET /pets", 200, 50)
+	if second := sampleNormal("GET /pets", 200, 50); first != second {
+		t.Errorf("expected the same delay for the same key, got %d and %d", first, second)
+	}
+	if negative := sampleNormal("GET /pets", 0, 1000); negative < 0 {
+		t.Errorf("expected delay to be non-negative, got %d", negative)
+	}
+}
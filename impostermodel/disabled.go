@@ -0,0 +1,171 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// DisabledResourcesFileName is the overlay config file holding interceptors
+// for disabled resources. It is picked up by the engine like any other config
+// file, so a running mock with auto-restart enabled reloads when it changes.
+const DisabledResourcesFileName = "disabled-resources-config.yaml"
+
+const disabledResponseMessage = "Resource disabled by imposter"
+
+// DisabledResource is a resource that responds with a fixed status code.
+type DisabledResource struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// ParseResourceSpec parses a resource in the form 'METHOD PATH' or 'PATH',
+// such as 'GET /orders/**'. If no method is given, all methods match. A
+// trailing '**' wildcard is converted to the engine's '*' wildcard.
+func ParseResourceSpec(spec string) (method string, path string, err error) {
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+		path = fields[0]
+	case 2:
+		method = strings.ToUpper(fields[0])
+		path = fields[1]
+	default:
+		return "", "", fmt.Errorf("invalid resource: %s - expected 'METHOD PATH' or 'PATH'", spec)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("invalid resource path: %s - must start with '/'", path)
+	}
+	if strings.HasSuffix(path, "/**") {
+		path = strings.TrimSuffix(path, "*")
+	}
+	return method, path, nil
+}
+
+// DisableResource adds an interceptor to the overlay config in configDir,
+// so that requests matching the method and path receive the status code.
+// If the resource is already disabled, its status code is updated.
+func DisableResource(configDir string, method string, path string, statusCode int) error {
+	overlay, err := loadOverlay(configDir)
+	if err != nil {
+		return err
+	}
+	interceptor := Interceptor{
+		Path:     path,
+		Method:   method,
+		Continue: false,
+		Response: &ResponseConfig{
+			StatusCode: statusCode,
+			StaticData: disabledResponseMessage,
+		},
+	}
+	if i := findInterceptor(overlay, method, path); i >= 0 {
+		overlay.Interceptors[i] = interceptor
+	} else {
+		overlay.Interceptors = append(overlay.Interceptors, interceptor)
+	}
+	return saveOverlay(configDir, overlay)
+}
+
+// EnableResource removes the interceptor for the method and path from the
+// overlay config, returning false if the resource was not disabled. The
+// overlay file is removed once no resources are disabled.
+func EnableResource(configDir string, method string, path string) (bool, error) {
+	overlay, err := loadOverlay(configDir)
+	if err != nil {
+		return false, err
+	}
+	i := findInterceptor(overlay, method, path)
+	if i < 0 {
+		return false, nil
+	}
+	overlay.Interceptors = append(overlay.Interceptors[:i], overlay.Interceptors[i+1:]...)
+	return true, saveOverlay(configDir, overlay)
+}
+
+// ListDisabledResources returns the resources disabled in configDir.
+func ListDisabledResources(configDir string) ([]DisabledResource, error) {
+	overlay, err := loadOverlay(configDir)
+	if err != nil {
+		return nil, err
+	}
+	var disabled []DisabledResource
+	for _, interceptor := range overlay.Interceptors {
+		statusCode := http.StatusNotImplemented
+		if interceptor.Response != nil {
+			statusCode = interceptor.Response.StatusCode
+		}
+		disabled = append(disabled, DisabledResource{
+			Method:     interceptor.Method,
+			Path:       interceptor.Path,
+			StatusCode: statusCode,
+		})
+	}
+	return disabled, nil
+}
+
+func findInterceptor(overlay *PluginConfig, method string, path string) int {
+	for i, interceptor := range overlay.Interceptors {
+		if interceptor.Method == method && interceptor.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func loadOverlay(configDir string) (*PluginConfig, error) {
+	overlayPath := filepath.Join(configDir, DisabledResourcesFileName)
+	content, err := os.ReadFile(overlayPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &PluginConfig{Plugin: "rest"}, nil
+		}
+		return nil, fmt.Errorf("failed to read disabled resources: %s: %v", overlayPath, err)
+	}
+	var overlay PluginConfig
+	if err := yaml.Unmarshal(content, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse disabled resources: %s: %v", overlayPath, err)
+	}
+	return &overlay, nil
+}
+
+func saveOverlay(configDir string, overlay *PluginConfig) error {
+	overlayPath := filepath.Join(configDir, DisabledResourcesFileName)
+	if len(overlay.Interceptors) == 0 {
+		if err := os.Remove(overlayPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove disabled resources: %s: %v", overlayPath, err)
+		}
+		logger.Tracef("removed disabled resources file: %s", overlayPath)
+		return nil
+	}
+	content, err := yaml.Marshal(overlay)
+	if err != nil {
+		return fmt.Errorf("failed to marshal disabled resources: %v", err)
+	}
+	if err := fileutil.WriteFileAtomic(overlayPath, content, 0644); err != nil {
+		return err
+	}
+	logger.Tracef("wrote disabled resources file: %s", overlayPath)
+	return nil
+}
@@ -0,0 +1,138 @@
+package impostermodel
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseResourceSpec(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		wantMethod string
+		wantPath   string
+		wantErr    bool
+	}{
+		{name: "method and path", spec: "GET /orders", wantMethod: "GET", wantPath: "/orders"},
+		{name: "lower case method", spec: "post /orders", wantMethod: "POST", wantPath: "/orders"},
+		{name: "path only", spec: "/payments", wantPath: "/payments"},
+		{name: "extra whitespace", spec: "  DELETE   /orders/{id} ", wantMethod: "DELETE", wantPath: "/orders/{id}"},
+		{name: "trailing wildcard", spec: "GET /orders/**", wantMethod: "GET", wantPath: "/orders/*"},
+		{name: "single wildcard", spec: "/orders/*", wantPath: "/orders/*"},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "relative path", spec: "GET orders", wantErr: true},
+		{name: "too many fields", spec: "GET /orders extra", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, path, err := ParseResourceSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResourceSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if method != tt.wantMethod || path != tt.wantPath {
+				t.Errorf("ParseResourceSpec() = %v %v, want %v %v", method, path, tt.wantMethod, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestDisableResource(t *testing.T) {
+	configDir := t.TempDir()
+	overlayPath := filepath.Join(configDir, DisabledResourcesFileName)
+
+	if err := DisableResource(configDir, "GET", "/orders/*", 501); err != nil {
+		t.Fatalf("DisableResource() error = %v", err)
+	}
+	if err := DisableResource(configDir, "", "/payments", 503); err != nil {
+		t.Fatalf("DisableResource() error = %v", err)
+	}
+	content, err := os.ReadFile(overlayPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `interceptors:
+- continue: false
+  method: GET
+  path: /orders/*
+  response:
+    staticData: Resource disabled by imposter
+    statusCode: 501
+- continue: false
+  path: /payments
+  response:
+    staticData: Resource disabled by imposter
+    statusCode: 503
+plugin: rest
+`
+	if string(content) != want {
+		t.Errorf("overlay =\n%s\nwant\n%s", content, want)
+	}
+
+	// disabling again updates the status code
+	if err := DisableResource(configDir, "GET", "/orders/*", 500); err != nil {
+		t.Fatalf("DisableResource() error = %v", err)
+	}
+	assertDisabled(t, configDir, []DisabledResource{
+		{Method: "GET", Path: "/orders/*", StatusCode: 500},
+		{Path: "/payments", StatusCode: 503},
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantFound bool
+		want      []DisabledResource
+	}{
+		{name: "different method", method: "POST", path: "/orders/*", wantFound: false, want: []DisabledResource{
+			{Method: "GET", Path: "/orders/*", StatusCode: 500},
+			{Path: "/payments", StatusCode: 503},
+		}},
+		{name: "disabled resource", method: "GET", path: "/orders/*", wantFound: true, want: []DisabledResource{
+			{Path: "/payments", StatusCode: 503},
+		}},
+		{name: "already enabled", method: "GET", path: "/orders/*", wantFound: false, want: []DisabledResource{
+			{Path: "/payments", StatusCode: 503},
+		}},
+		{name: "last resource", method: "", path: "/payments", wantFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := EnableResource(configDir, tt.method, tt.path)
+			if err != nil {
+				t.Fatalf("EnableResource() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Errorf("EnableResource() found = %v, want %v", found, tt.wantFound)
+			}
+			assertDisabled(t, configDir, tt.want)
+		})
+	}
+	if _, err := os.Stat(overlayPath); !os.IsNotExist(err) {
+		t.Errorf("expected overlay to be removed once all resources are enabled, stat error: %v", err)
+	}
+}
+
+func TestListDisabledResources_invalidOverlay(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, DisabledResourcesFileName), []byte("interceptors: {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListDisabledResources(configDir); err == nil || !strings.Contains(err.Error(), "failed to parse disabled resources") {
+		t.Errorf("ListDisabledResources() error = %v, want parse error", err)
+	}
+}
+
+func assertDisabled(t *testing.T, configDir string, want []DisabledResource) {
+	t.Helper()
+	got, err := ListDisabledResources(configDir)
+	if err != nil {
+		t.Fatalf("ListDisabledResources() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDisabledResources() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ExampleStrategyType string
+
+const (
+	// ExampleStrategyFirst uses the first named example as the default response.
+	ExampleStrategyFirst ExampleStrategyType = "first"
+
+	// ExampleStrategyNamed uses the example with a given name as the default
+	// response, falling back to the first example if it does not exist.
+	ExampleStrategyNamed ExampleStrategyType = "named"
+
+	// ExampleStrategyAllAsVariants uses the first named example as the default
+	// response, and generates a variant for each example, selected using
+	// the ExampleSelectionHeader request header.
+	ExampleStrategyAllAsVariants ExampleStrategyType = "all-as-variants"
+)
+
+// ExampleSelectionHeader is the request header used to select a variant
+// generated by ExampleStrategyAllAsVariants.
+const ExampleSelectionHeader = "X-Imposter-Example"
+
+type ExampleStrategy struct {
+	Type ExampleStrategyType
+
+	// Name is the example name, used by ExampleStrategyNamed
+	Name string
+}
+
+// ParseExampleStrategy parses a strategy in the form 'first',
+// 'named:<name>' or 'all-as-variants'.
+func ParseExampleStrategy(strategy string) (ExampleStrategy, error) {
+	switch ExampleStrategyType(strategy) {
+	case "", ExampleStrategyFirst:
+		return ExampleStrategy{Type: ExampleStrategyFirst}, nil
+	case ExampleStrategyAllAsVariants:
+		return ExampleStrategy{Type: ExampleStrategyAllAsVariants}, nil
+	}
+	if name, found := strings.CutPrefix(strategy, string(ExampleStrategyNamed)+":"); found {
+		if name == "" {
+			return ExampleStrategy{}, fmt.Errorf("example name is required for strategy: %s", strategy)
+		}
+		return ExampleStrategy{Type: ExampleStrategyNamed, Name: name}, nil
+	}
+	return ExampleStrategy{}, fmt.Errorf("unsupported example strategy: %s (valid: first,named:<name>,all-as-variants)", strategy)
+}
+
+// chooseDefaultExample returns the name of the example to use as the
+// default response, or an empty string if there are no named examples.
+func chooseDefaultExample(strategy ExampleStrategy, exampleNames []string) string {
+	if len(exampleNames) == 0 {
+		return ""
+	}
+	if strategy.Type == ExampleStrategyNamed {
+		for _, name := range exampleNames {
+			if name == strategy.Name {
+				return name
+			}
+		}
+		logger.Debugf("example '%s' not found - using first example '%s'", strategy.Name, exampleNames[0])
+	}
+	return exampleNames[0]
+}
+
+// buildExampleVariants generates a resource for each example, selected
+// using the ExampleSelectionHeader request header.
+func buildExampleVariants(resource Resource, exampleNames []string) []Resource {
+	var variants []Resource
+	for _, name := range exampleNames {
+		response := *resource.Response
+		response.ExampleName = name
+		variant := Resource{
+			Path:           resource.Path,
+			Method:         resource.Method,
+			RequestHeaders: &map[string]string{ExampleSelectionHeader: name},
+			Security:       resource.Security,
+			Response:       &response,
+		}
+		variants = append(variants, variant)
+	}
+	return variants
+}
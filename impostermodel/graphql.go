@@ -0,0 +1,76 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/graphql"
+	"path/filepath"
+)
+
+func writeGraphqlMockConfig(tx *fileutil.Transaction, schemaFilePath string, generateResources bool, forceOverwrite bool, resourceOptions ResourceGenerationOptions) {
+	var resources []Resource
+	if generateResources {
+		resources = buildGraphqlResources(tx, schemaFilePath, forceOverwrite, resourceOptions)
+	} else {
+		logger.Debug("skipping resource generation")
+	}
+	options := ConfigGenerationOptions{
+		PluginName:     "graphql",
+		ScriptEngine:   resourceOptions.ScriptEngine,
+		ScriptFileName: resourceOptions.ScriptFileName,
+		SchemaFilePath: schemaFilePath,
+		Cors:           resourceOptions.Cors,
+	}
+	writeMockConfigAdjacent(tx, resourceOptions.anchorFor(schemaFilePath), resources, forceOverwrite, options)
+}
+
+// buildGraphqlResources generates a resource for each query and mutation
+// in the schema. Each resource returns a sample response, generated from
+// the schema types, which is written adjacent to the schema file.
+func buildGraphqlResources(tx *fileutil.Transaction, schemaFilePath string, forceOverwrite bool, options ResourceGenerationOptions) []Resource {
+	schema, err := graphql.Parse(schemaFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse GraphQL schema: %v: %v", schemaFilePath, err))
+	}
+
+	var resources []Resource
+	for _, operation := range schema.Operations() {
+		responseFilePath := fileutil.GenerateFilePathAdjacentToFile(options.anchorFor(schemaFilePath), "-"+string(operation.Type)+"-"+operation.Field+"-response.json", forceOverwrite)
+		tx.WriteFile(responseFilePath, schema.SampleResponse(operation), 0644)
+		tx.OnCommit(func() {
+			logger.Debugf("wrote response file: %v", responseFilePath)
+		})
+
+		resource := Resource{
+			OperationType: string(operation.Type),
+			Operation:     operation.Field,
+			Response: &ResponseConfig{
+				StatusCode: 200,
+				StaticFile: filepath.Base(responseFilePath),
+			},
+		}
+		if IsScriptEngineEnabled(options.ScriptEngine) {
+			resource.Response.ScriptFile = options.ScriptFileName
+		}
+		resource.Response.Delay = options.DelayProfile.BuildDelay(resource.OperationType+" "+resource.Operation, nil)
+		resources = append(resources, resource)
+	}
+	logger.Debugf("generated %d resources from GraphQL schema", len(resources))
+	return resources
+}
@@ -0,0 +1,52 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_writeGraphqlMockConfig(t *testing.T) {
+	dir := t.TempDir()
+	schemaFilePath := filepath.Join(dir, "petstore.graphql")
+	schema := `type Query { pets: [Pet!]! }
+type Mutation { addPet(name: String!): Pet }
+type Pet { name: String! }`
+	if err := os.WriteFile(schemaFilePath, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeGraphqlMockConfig(tx, schemaFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "petstore-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"plugin: graphql",
+		"schemaFile: petstore.graphql",
+		"operationType: query",
+		"operation: pets",
+		"staticFile: petstore-query-pets-response.json",
+		"operationType: mutation",
+		"staticFile: petstore-mutation-addPet-response.json",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+
+	response, err := os.ReadFile(filepath.Join(dir, "petstore-query-pets-response.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(response), `"name": "string"`) {
+		t.Errorf("unexpected sample response:\n%s", response)
+	}
+}
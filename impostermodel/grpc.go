@@ -0,0 +1,79 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/protobuf"
+	"net/http"
+	"path/filepath"
+)
+
+func writeGrpcMockConfig(tx *fileutil.Transaction, protoFilePath string, generateResources bool, forceOverwrite bool, resourceOptions ResourceGenerationOptions) {
+	var resources []Resource
+	if generateResources {
+		resources = buildGrpcResources(tx, protoFilePath, forceOverwrite, resourceOptions)
+	} else {
+		logger.Debug("skipping resource generation")
+	}
+	options := ConfigGenerationOptions{
+		PluginName:     "grpc",
+		ScriptEngine:   resourceOptions.ScriptEngine,
+		ScriptFileName: resourceOptions.ScriptFileName,
+		ProtoFilePath:  protoFilePath,
+	}
+	writeMockConfigAdjacent(tx, resourceOptions.anchorFor(protoFilePath), resources, forceOverwrite, options)
+}
+
+// buildGrpcResources generates a resource for each method of each service
+// in the .proto file or descriptor set, matching the path of the gRPC
+// request. Each resource returns a sample output message, generated from
+// the message schema, which is written adjacent to the proto file.
+func buildGrpcResources(tx *fileutil.Transaction, protoFilePath string, forceOverwrite bool, options ResourceGenerationOptions) []Resource {
+	schema, err := protobuf.Load(protoFilePath, options.ProtoImportPaths)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse protobuf definitions: %v: %v", protoFilePath, err))
+	}
+
+	var resources []Resource
+	for _, service := range schema.Services {
+		for _, method := range service.Methods {
+			responseFilePath := fileutil.GenerateFilePathAdjacentToFile(options.anchorFor(protoFilePath), "-"+service.ShortName()+"-"+method.Name+"-response.json", forceOverwrite)
+			tx.WriteFile(responseFilePath, schema.SampleResponse(method), 0644)
+			tx.OnCommit(func() {
+				logger.Debugf("wrote response file: %v", responseFilePath)
+			})
+
+			resource := Resource{
+				Path:   service.Path(method),
+				Method: http.MethodPost,
+				Response: &ResponseConfig{
+					StatusCode: 200,
+					StaticFile: filepath.Base(responseFilePath),
+				},
+			}
+			if IsScriptEngineEnabled(options.ScriptEngine) {
+				resource.Response.ScriptFile = options.ScriptFileName
+			}
+			resource.Response.Delay = options.DelayProfile.BuildDelay(resource.Method+" "+resource.Path, nil)
+			resources = append(resources, resource)
+		}
+	}
+	logger.Debugf("generated %d resources from protobuf definitions", len(resources))
+	return resources
+}
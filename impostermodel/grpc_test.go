@@ -0,0 +1,52 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_writeGrpcMockConfig(t *testing.T) {
+	dir := t.TempDir()
+	protoFilePath := filepath.Join(dir, "pets.proto")
+	proto := `syntax = "proto3";
+package example.pets;
+message Pet { string name = 1; }
+message GetPetRequest { int64 id = 1; }
+service PetService { rpc GetPet (GetPetRequest) returns (Pet); }`
+	if err := os.WriteFile(protoFilePath, []byte(proto), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeGrpcMockConfig(tx, protoFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "pets-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"plugin: grpc",
+		"protoFile: pets.proto",
+		"path: /example.pets.PetService/GetPet",
+		"method: POST",
+		"staticFile: pets-PetService-GetPet-response.json",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+
+	response, err := os.ReadFile(filepath.Join(dir, "pets-PetService-GetPet-response.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(response), `"name": "string"`) {
+		t.Errorf("unexpected sample response:\n%s", response)
+	}
+}
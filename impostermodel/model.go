@@ -16,25 +16,176 @@ limitations under the License.
 
 package impostermodel
 
+import "encoding/json"
+
 type ResponseConfig struct {
 	StatusCode  int                `json:"statusCode,omitempty"`
 	StaticFile  string             `json:"staticFile,omitempty"`
 	StaticData  string             `json:"staticData,omitempty"`
 	ExampleName string             `json:"exampleName,omitempty"`
 	ScriptFile  string             `json:"scriptFile,omitempty"`
+	Template    bool               `json:"template,omitempty"`
 	Headers     *map[string]string `json:"headers,omitempty"`
+	Delay       *ResponseDelay     `json:"delay,omitempty"`
+
+	// Fail simulates a network failure instead of sending the response,
+	// such as 'EmptyResponse' or 'CloseConnection'.
+	Fail string `json:"fail,omitempty"`
+}
+
+// ResponseDelay simulates response latency, either as an exact
+// number of milliseconds, or a random delay between min and max.
+type ResponseDelay struct {
+	Exact int `json:"exact,omitempty"`
+	Min   int `json:"min,omitempty"`
+	Max   int `json:"max,omitempty"`
+}
+
+// RequestBody matches the request body, either in full, or the value
+// at a JSON path or XPath expression within it. Operator is EqualTo if
+// not set. If AllOf is set, the body must match all of its matchers
+// instead.
+type RequestBody struct {
+	JsonPath string        `json:"jsonPath,omitempty"`
+	XPath    string        `json:"xPath,omitempty"`
+	Value    string        `json:"value"`
+	Operator string        `json:"operator,omitempty"`
+	AllOf    []RequestBody `json:"allOf,omitempty"`
+}
+
+// MarshalJSON omits the value of a body matcher combining others with
+// AllOf, as it is not used.
+func (b RequestBody) MarshalJSON() ([]byte, error) {
+	type requestBody RequestBody
+	if len(b.AllOf) > 0 {
+		return json.Marshal(struct {
+			AllOf []RequestBody `json:"allOf"`
+		}{b.AllOf})
+	}
+	return json.Marshal(requestBody(b))
+}
+
+// MatchExpression matches the value of an expression evaluated against
+// the request, such as '${context.request.headers.Accept}', using an
+// operator, such as EqualTo, Contains or Matches.
+type MatchExpression struct {
+	Expression string `json:"expression"`
+	Value      string `json:"value,omitempty"`
+	Operator   string `json:"operator,omitempty"`
 }
 
 type Resource struct {
-	Path        string             `json:"path"`
-	Method      string             `json:"method"`
-	QueryParams *map[string]string `json:"queryParams,omitempty"`
-	Response    *ResponseConfig    `json:"response,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Method string `json:"method,omitempty"`
+
+	// Binding and Operation match SOAP requests, for the soap plugin.
+	Binding   string `json:"binding,omitempty"`
+	Operation string `json:"operation,omitempty"`
+
+	// OperationType and Operation match the query or mutation
+	// field of GraphQL requests, for the graphql plugin.
+	OperationType string `json:"operationType,omitempty"`
+
+	// Channel matches the topic or queue of messages, for
+	// the messaging plugins, such as kafka or sqs.
+	Channel string `json:"channel,omitempty"`
+
+	QueryParams    *map[string]string `json:"queryParams,omitempty"`
+	RequestHeaders *map[string]string `json:"requestHeaders,omitempty"`
+	FormParams     *map[string]string `json:"formParams,omitempty"`
+	RequestBody    *RequestBody       `json:"requestBody,omitempty"`
+
+	// AllOf holds further conditions, all of which must match.
+	AllOf []MatchExpression `json:"allOf,omitempty"`
+
+	// AnyOf holds further conditions, at least one of which must match.
+	AnyOf []MatchExpression `json:"anyOf,omitempty"`
+
+	Security *SecurityConfig    `json:"security,omitempty"`
+	Capture  map[string]Capture `json:"capture,omitempty"`
+	Response *ResponseConfig    `json:"response,omitempty"`
+}
+
+// Capture stores a value from the request in a store, so it can be
+// used by later requests. The value is taken from a path parameter,
+// the request body or an expression, such as '${context.request.body}'.
+// The item is named by Key, if set, otherwise by the capture name.
+type Capture struct {
+	Store       string       `json:"store,omitempty"`
+	Key         *Capture     `json:"key,omitempty"`
+	PathParam   string       `json:"pathParam,omitempty"`
+	RequestBody *CaptureBody `json:"requestBody,omitempty"`
+	Expression  string       `json:"expression,omitempty"`
+}
+
+// CaptureBody captures the value at a JSON path within the request body.
+type CaptureBody struct {
+	JsonPath string `json:"jsonPath"`
+}
+
+// SecurityConfig permits or denies requests. Requests matching a
+// condition are subject to its effect, otherwise the Default effect.
+// Denied requests receive a 401 response.
+type SecurityConfig struct {
+	Default    SecurityEffect      `json:"default"`
+	Conditions []SecurityCondition `json:"conditions,omitempty"`
+}
+
+type SecurityEffect string
+
+const (
+	SecurityEffectPermit SecurityEffect = "Permit"
+	SecurityEffectDeny   SecurityEffect = "Deny"
+)
+
+// SecurityCondition is matched if all of its request headers and query
+// parameters match.
+type SecurityCondition struct {
+	Effect         SecurityEffect             `json:"effect"`
+	RequestHeaders map[string]SecurityMatcher `json:"requestHeaders,omitempty"`
+	QueryParams    map[string]SecurityMatcher `json:"queryParams,omitempty"`
+}
+
+// SecurityMatcher matches a value using an operator, such as EqualTo,
+// Exists or Matches, for which Value is a regular expression.
+type SecurityMatcher struct {
+	Value    string `json:"value,omitempty"`
+	Operator string `json:"operator"`
+}
+
+// Interceptor is evaluated before resources. If Continue is false, its
+// response is returned and no further processing takes place.
+type Interceptor struct {
+	Path     string          `json:"path"`
+	Method   string          `json:"method,omitempty"`
+	Continue bool            `json:"continue"`
+	Response *ResponseConfig `json:"response,omitempty"`
 }
 
 type PluginConfig struct {
-	Plugin    string          `json:"plugin"`
-	SpecFile  string          `json:"specFile,omitempty"`
-	Response  *ResponseConfig `json:"response,omitempty"`
-	Resources []Resource      `json:"resources,omitempty"`
+	Plugin     string `json:"plugin"`
+	SpecFile   string `json:"specFile,omitempty"`
+	WsdlFile   string `json:"wsdlFile,omitempty"`
+	SchemaFile string `json:"schemaFile,omitempty"`
+
+	// ProtoFile or DescriptorSetFile hold the message types and
+	// services, for the grpc plugin.
+	ProtoFile         string `json:"protoFile,omitempty"`
+	DescriptorSetFile string `json:"descriptorSetFile,omitempty"`
+
+	Cors         *CorsConfig     `json:"cors,omitempty"`
+	Security     *SecurityConfig `json:"security,omitempty"`
+	Response     *ResponseConfig `json:"response,omitempty"`
+	Resources    []Resource      `json:"resources,omitempty"`
+	Interceptors []Interceptor   `json:"interceptors,omitempty"`
+}
+
+// CorsConfig holds the CORS settings of a mock, allowing browser-based
+// clients on other origins to call it. AllowOrigins is either
+// CorsAllOrigins or a list of origins.
+type CorsConfig struct {
+	AllowOrigins interface{} `json:"allowOrigins"`
+	AllowHeaders []string    `json:"allowHeaders,omitempty"`
+	AllowMethods []string    `json:"allowMethods,omitempty"`
+	MaxAge       int         `json:"maxAge,omitempty"`
 }
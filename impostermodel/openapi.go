@@ -17,62 +17,142 @@ limitations under the License.
 package impostermodel
 
 import (
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
 	"gatehill.io/imposter/openapi"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+// StatusSelectionHeader is the request header used to select the
+// variant of a resource returning a given status code.
+const StatusSelectionHeader = "X-Imposter-Status"
+
 type ResourceGenerationOptions struct {
-	ScriptEngine   ScriptEngine
-	ScriptFileName string
+	ScriptEngine    ScriptEngine
+	ScriptFileName  string
+	ExampleStrategy ExampleStrategy
+	DelayProfile    DelayProfile
+
+	// SimulateRateLimits generates a script returning 429 responses once the
+	// rate limits declared in the spec are exceeded.
+	SimulateRateLimits bool
+
+	// RateLimitScriptFileName is set once the rate limit script is written.
+	RateLimitScriptFileName string
+
+	// ProtoImportPaths are searched for the files imported by .proto files.
+	ProtoImportPaths []string
+
+	// SimulateSecurity generates security configuration from the security
+	// schemes in the spec, denying requests without the credentials they
+	// declare.
+	SimulateSecurity bool
+
+	// TypeScript writes a TypeScript source for each JavaScript script,
+	// with type declarations and a tsconfig to compile them.
+	TypeScript bool
+
+	// ResponseFiles writes the response bodies of OpenAPI operations to
+	// files, rather than relying on the examples in the spec.
+	ResponseFiles bool
+
+	// Cors is added to the configuration of mocks served over HTTP, so
+	// browser-based clients on other origins can call them.
+	Cors *CorsConfig
+
+	// AnchorFilePath is the path on which the names of the files generated
+	// for a spec are based, if not the spec file itself.
+	AnchorFilePath string
+}
+
+// anchorFor returns the path on which the names of the files generated
+// for the spec are based.
+func (o ResourceGenerationOptions) anchorFor(specFilePath string) string {
+	if o.AnchorFilePath != "" {
+		return o.AnchorFilePath
+	}
+	return specFilePath
 }
 
-func writeOpenapiMockConfig(specFilePath string, generateResources bool, forceOverwrite bool, scriptEngine ScriptEngine, scriptFileName string) {
+func writeOpenapiMockConfig(tx *fileutil.Transaction, specFilePath string, generateResources bool, forceOverwrite bool, resourceOptions ResourceGenerationOptions) {
 	var resources []Resource
 	if generateResources {
-		resources = buildOpenapiResources(specFilePath, scriptEngine, scriptFileName)
+		if resourceOptions.SimulateRateLimits {
+			resourceOptions.RateLimitScriptFileName = writeRateLimitScript(tx, resourceOptions.anchorFor(specFilePath), forceOverwrite)
+		}
+		resources = buildOpenapiResources(specFilePath, resourceOptions)
+		if resourceOptions.ResponseFiles {
+			writeOpenapiResponseFiles(tx, specFilePath, resourceOptions.anchorFor(specFilePath), resources, forceOverwrite)
+		}
 	} else {
 		logger.Debug("skipping resource generation")
 	}
 	options := ConfigGenerationOptions{
 		PluginName:     "openapi",
-		ScriptEngine:   scriptEngine,
-		ScriptFileName: scriptFileName,
+		ScriptEngine:   resourceOptions.ScriptEngine,
+		ScriptFileName: resourceOptions.ScriptFileName,
 		SpecFilePath:   specFilePath,
+		Cors:           resourceOptions.Cors,
+	}
+	if resourceOptions.SimulateSecurity {
+		options.Security = buildOpenapiSecurity(specFilePath)
 	}
-	writeMockConfigAdjacent(specFilePath, resources, forceOverwrite, options)
+	writeMockConfigAdjacent(tx, resourceOptions.anchorFor(specFilePath), resources, forceOverwrite, options)
 }
 
-func buildOpenapiResources(specFilePath string, scriptEngine ScriptEngine, scriptFileName string) []Resource {
-	resources := GenerateResourcesFromSpec(specFilePath, ResourceGenerationOptions{
-		ScriptEngine:   scriptEngine,
-		ScriptFileName: scriptFileName,
-	})
+func buildOpenapiResources(specFilePath string, options ResourceGenerationOptions) []Resource {
+	resources := GenerateResourcesFromSpec(specFilePath, options)
 	logger.Debugf("generated %d resources from spec", len(resources))
 	return resources
 }
 
 func GenerateResourcesFromSpec(specFilePath string, options ResourceGenerationOptions) []Resource {
-	var resources []Resource
 	partialSpec, err := openapi.Parse(specFilePath)
 	if err != nil {
-		logger.Fatalf("unable to parse openapi spec: %v: %v", specFilePath, err)
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse openapi spec: %v: %v", specFilePath, err))
 	}
+	return GenerateResourcesFromModel(partialSpec, options)
+}
+
+// GenerateResourcesFromModel generates a resource for each operation in
+// the parsed spec, and a variant for each of its other status codes.
+func GenerateResourcesFromModel(partialSpec *openapi.PartialModel, options ResourceGenerationOptions) []Resource {
+	var resources []Resource
 	if partialSpec != nil {
 		for path, pathDetail := range partialSpec.Paths {
 			for verb, resp := range pathDetail {
+				statusCode := chooseOpStatusCode(resp)
+				exampleNames := resp.Responses[strconv.Itoa(statusCode)].ExampleNames()
 				resource := Resource{
 					Path:   path,
 					Method: strings.ToUpper(verb),
 					Response: &ResponseConfig{
-						StatusCode: chooseOpStatusCode(resp),
+						StatusCode:  statusCode,
+						ExampleName: chooseDefaultExample(options.ExampleStrategy, exampleNames),
 					},
 				}
 				if IsScriptEngineEnabled(options.ScriptEngine) {
 					resource.Response.ScriptFile = options.ScriptFileName
 				}
+				if options.SimulateSecurity && resp.Security != nil {
+					resource.Security = buildSecurityConfig(partialSpec.SecuritySchemes, resp.Security)
+				}
+				if options.RateLimitScriptFileName != "" {
+					if _, _, found := findRateLimit(resp); found {
+						if resource.Response.ScriptFile != "" {
+							logger.Warnf("using rate limit script instead of %s for %s %s", resource.Response.ScriptFile, resource.Method, resource.Path)
+						}
+						resource.Response.ScriptFile = options.RateLimitScriptFileName
+					}
+				}
+				resource.Response.Delay = options.DelayProfile.BuildDelay(resource.Method+" "+resource.Path, nil)
 				resources = append(resources, resource)
+				if options.ExampleStrategy.Type == ExampleStrategyAllAsVariants {
+					resources = append(resources, buildExampleVariants(resource, exampleNames)...)
+				}
+				resources = append(resources, buildStatusVariants(resource, resp, options)...)
 			}
 		}
 
@@ -80,18 +160,55 @@ func GenerateResourcesFromSpec(specFilePath string, options ResourceGenerationOp
 	return resources
 }
 
-func chooseOpStatusCode(resp openapi.Operation) int {
-	if len(resp.Responses) == 0 {
-		logger.Tracef("no responses found for openapi operation - guessing 200 status code")
-		return 200
+// buildStatusVariants generates a resource for each status code declared
+// by the operation, other than that of the default response, selected
+// using the StatusSelectionHeader request header.
+func buildStatusVariants(resource Resource, operation openapi.Operation, options ResourceGenerationOptions) []Resource {
+	var variants []Resource
+	for _, statusCode := range listStatusCodes(operation) {
+		if statusCode == resource.Response.StatusCode {
+			continue
+		}
+		response := ResponseConfig{
+			StatusCode:  statusCode,
+			ExampleName: chooseDefaultExample(options.ExampleStrategy, operation.Responses[strconv.Itoa(statusCode)].ExampleNames()),
+		}
+		if IsScriptEngineEnabled(options.ScriptEngine) {
+			response.ScriptFile = options.ScriptFileName
+		}
+		response.Delay = options.DelayProfile.BuildDelay(resource.Method+" "+resource.Path, nil)
+		variant := Resource{
+			Path:           resource.Path,
+			Method:         resource.Method,
+			RequestHeaders: &map[string]string{StatusSelectionHeader: strconv.Itoa(statusCode)},
+			Security:       resource.Security,
+			Response:       &response,
+		}
+		variants = append(variants, variant)
 	}
+	return variants
+}
+
+// listStatusCodes returns the status codes of the responses declared by
+// the operation, in ascending order. Informational status codes, and
+// those that are not numeric, such as 'default', are excluded.
+func listStatusCodes(operation openapi.Operation) []int {
 	var statusCodes []int
-	for statusCode := range resp.Responses {
+	for statusCode := range operation.Responses {
 		if sc, err := strconv.Atoi(statusCode); err == nil && sc >= 200 {
 			statusCodes = append(statusCodes, sc)
 		}
 	}
 	sort.Ints(statusCodes)
+	return statusCodes
+}
+
+func chooseOpStatusCode(resp openapi.Operation) int {
+	if len(resp.Responses) == 0 {
+		logger.Tracef("no responses found for openapi operation - guessing 200 status code")
+		return 200
+	}
+	statusCodes := listStatusCodes(resp)
 	if len(statusCodes) > 0 {
 		return statusCodes[0]
 	}
@@ -0,0 +1,40 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/openapi"
+	"strconv"
+	"testing"
+)
+
+func Test_GenerateResourcesFromModel_statusVariants(t *testing.T) {
+	model := &openapi.PartialModel{
+		Paths: map[string]map[string]openapi.Operation{
+			"/pets": {
+				"get": openapi.Operation{
+					Responses: map[string]openapi.OperationResponse{
+						"200":     {Description: "OK"},
+						"404":     {Description: "Not found"},
+						"400":     {Description: "Bad request"},
+						"default": {Description: "Error"},
+					},
+				},
+			},
+		},
+	}
+	resources := GenerateResourcesFromModel(model, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone})
+	if len(resources) != 3 {
+		t.Fatalf("expected 3 resources, got %d", len(resources))
+	}
+	if resources[0].RequestHeaders != nil || resources[0].Response.StatusCode != 200 {
+		t.Errorf("expected default resource to return 200 without matching headers, got: %+v", resources[0])
+	}
+	for i, want := range []int{400, 404} {
+		variant := resources[i+1]
+		if variant.Response.StatusCode != want {
+			t.Errorf("expected variant %d to return %d, got %d", i, want, variant.Response.StatusCode)
+		}
+		if variant.RequestHeaders == nil || (*variant.RequestHeaders)[StatusSelectionHeader] != strconv.Itoa(want) {
+			t.Errorf("expected variant %d to match %s header, got: %v", i, StatusSelectionHeader, variant.RequestHeaders)
+		}
+	}
+}
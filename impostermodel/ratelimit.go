@@ -0,0 +1,191 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/openapi"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultRateLimitWindow is the window, in seconds, used when a spec
+// declares a limit without a window, such as in response headers.
+const defaultRateLimitWindow = 60
+
+// rateLimitHeaders are the response headers from which a limit is read.
+var rateLimitHeaders = []string{"X-RateLimit-Limit", "RateLimit-Limit"}
+
+var pathParamPattern = regexp.MustCompile(`\{[^}]+}`)
+
+type rateLimitRule struct {
+	Method   string `json:"method"`
+	Pattern  string `json:"pattern"`
+	Resource string `json:"resource"`
+	Limit    int    `json:"limit"`
+	Window   int    `json:"window"`
+}
+
+// findRateLimit returns the rate limit for the operation, declared either
+// by the 'x-rate-limit' extension, or the example value of a rate limit
+// header in one of its responses.
+func findRateLimit(op openapi.Operation) (limit int, window int, found bool) {
+	if op.RateLimit != nil && op.RateLimit.Limit > 0 {
+		window = op.RateLimit.Window
+		if window <= 0 {
+			window = defaultRateLimitWindow
+		}
+		return op.RateLimit.Limit, window, true
+	}
+
+	var statusCodes []string
+	for statusCode := range op.Responses {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Strings(statusCodes)
+	for _, statusCode := range statusCodes {
+		for headerName, header := range op.Responses[statusCode].Headers {
+			for _, rateLimitHeader := range rateLimitHeaders {
+				if !strings.EqualFold(headerName, rateLimitHeader) {
+					continue
+				}
+				for _, value := range []interface{}{header.Example, header.Schema.Example, header.Schema.Default} {
+					if limit, err := strconv.Atoi(fmt.Sprintf("%v", value)); err == nil && limit > 0 {
+						return limit, defaultRateLimitWindow, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// buildPathPattern converts an OpenAPI path template, such as /pets/{petId},
+// to a regular expression matching request paths.
+func buildPathPattern(path string) string {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range pathParamPattern.FindAllStringIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		pattern.WriteString("[^/]+")
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteString("$")
+	return pattern.String()
+}
+
+func buildRateLimitRules(partialSpec *openapi.PartialModel) []rateLimitRule {
+	var rules []rateLimitRule
+	if partialSpec == nil {
+		return rules
+	}
+	for path, pathDetail := range partialSpec.Paths {
+		for verb, op := range pathDetail {
+			if limit, window, found := findRateLimit(op); found {
+				method := strings.ToUpper(verb)
+				rules = append(rules, rateLimitRule{
+					Method:   method,
+					Pattern:  buildPathPattern(path),
+					Resource: method + " " + path,
+					Limit:    limit,
+					Window:   window,
+				})
+			}
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Resource < rules[j].Resource
+	})
+	return rules
+}
+
+// writeRateLimitScript writes a script simulating the rate limits declared
+// in the spec, returning its file name, or an empty string if the spec does
+// not declare any rate limits.
+func writeRateLimitScript(tx *fileutil.Transaction, specFilePath string, forceOverwrite bool) string {
+	partialSpec, err := openapi.Parse(specFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse openapi spec: %v: %v", specFilePath, err))
+	}
+	rules := buildRateLimitRules(partialSpec)
+	if len(rules) == 0 {
+		logger.Debugf("no rate limits declared in spec: %v", specFilePath)
+		return ""
+	}
+	rulesJson, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		logger.Fatalf("unable to marshal rate limits: %v", err)
+	}
+
+	scriptFilePath := fileutil.GenerateFilePathAdjacentToFile(specFilePath, "-rate-limit.js", forceOverwrite)
+	tx.WriteFile(scriptFilePath, []byte(fmt.Sprintf(rateLimitScript, rulesJson)), 0644)
+	tx.OnCommit(func() {
+		logger.Infof("wrote rate limit script for %d resource(s): %v", len(rules), scriptFilePath)
+	})
+	return filepath.Base(scriptFilePath)
+}
+
+// rateLimitScript counts requests per client, identified by its API key or
+// Authorization header, in fixed windows, responding with 429 once the
+// limit for the window is exceeded.
+const rateLimitScript = `// Simulates the rate limits declared in the OpenAPI spec.
+var rules = %s;
+
+var request = context.request;
+var headers = request.normalisedHeaders || {};
+var client = headers['x-api-key'] || headers['authorization'] || 'anonymous';
+
+for (var i = 0; i < rules.length; i++) {
+    var rule = rules[i];
+    if (rule.method !== request.method || !new RegExp(rule.pattern).test(request.path)) {
+        continue;
+    }
+    var now = Math.floor(Date.now() / 1000);
+    var windowStart = now - (now %% rule.window);
+    var reset = windowStart + rule.window;
+
+    var store = stores.open('rate-limits');
+    var key = rule.resource + ':' + client + ':' + windowStart;
+    var count = parseInt(store.load(key) || '0', 10) + 1;
+    store.save(key, String(count));
+
+    if (count > rule.limit) {
+        respond()
+            .withStatusCode(429)
+            .withHeader('X-RateLimit-Limit', String(rule.limit))
+            .withHeader('X-RateLimit-Remaining', '0')
+            .withHeader('X-RateLimit-Reset', String(reset))
+            .withHeader('Retry-After', String(reset - now))
+            .withEmpty();
+    } else {
+        respond()
+            .withHeader('X-RateLimit-Limit', String(rule.limit))
+            .withHeader('X-RateLimit-Remaining', String(rule.limit - count))
+            .withHeader('X-RateLimit-Reset', String(reset))
+            .usingDefaultBehaviour();
+    }
+    break;
+}
+`
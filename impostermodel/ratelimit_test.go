@@ -0,0 +1,104 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/openapi"
+	"regexp"
+	"testing"
+)
+
+func Test_findRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		op         openapi.Operation
+		wantLimit  int
+		wantWindow int
+		wantFound  bool
+	}{
+		{
+			name:       "extension",
+			op:         openapi.Operation{RateLimit: &openapi.RateLimit{Limit: 10, Window: 30}},
+			wantLimit:  10,
+			wantWindow: 30,
+			wantFound:  true,
+		},
+		{
+			name:       "extension without window",
+			op:         openapi.Operation{RateLimit: &openapi.RateLimit{Limit: 10}},
+			wantLimit:  10,
+			wantWindow: defaultRateLimitWindow,
+			wantFound:  true,
+		},
+		{
+			name: "response header schema example",
+			op: openapi.Operation{Responses: map[string]openapi.OperationResponse{
+				"200": {Headers: map[string]openapi.Header{
+					"x-ratelimit-limit": {Schema: openapi.HeaderSchema{Example: 5}},
+				}},
+			}},
+			wantLimit:  5,
+			wantWindow: defaultRateLimitWindow,
+			wantFound:  true,
+		},
+		{
+			name: "response header without value",
+			op: openapi.Operation{Responses: map[string]openapi.OperationResponse{
+				"200": {Headers: map[string]openapi.Header{"X-RateLimit-Limit": {}}},
+			}},
+		},
+		{
+			name: "no rate limit",
+			op:   openapi.Operation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, window, found := findRateLimit(tt.op)
+			if limit != tt.wantLimit || window != tt.wantWindow || found != tt.wantFound {
+				t.Errorf("findRateLimit() = %v, %v, %v, want %v, %v, %v", limit, window, found, tt.wantLimit, tt.wantWindow, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_buildPathPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		matches   []string
+		noMatches []string
+	}{
+		{
+			name:      "static path",
+			path:      "/pets",
+			matches:   []string{"/pets"},
+			noMatches: []string{"/pets/1", "/petsx"},
+		},
+		{
+			name:      "path parameter",
+			path:      "/pets/{petId}/photos",
+			matches:   []string{"/pets/1/photos"},
+			noMatches: []string{"/pets/photos", "/pets/1/2/photos"},
+		},
+		{
+			name:      "special characters",
+			path:      "/v1.0/pets",
+			matches:   []string{"/v1.0/pets"},
+			noMatches: []string{"/v1x0/pets"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := regexp.MustCompile(buildPathPattern(tt.path))
+			for _, path := range tt.matches {
+				if !pattern.MatchString(path) {
+					t.Errorf("buildPathPattern() = %v, should match %v", pattern, path)
+				}
+			}
+			for _, path := range tt.noMatches {
+				if pattern.MatchString(path) {
+					t.Errorf("buildPathPattern() = %v, should not match %v", pattern, path)
+				}
+			}
+		})
+	}
+}
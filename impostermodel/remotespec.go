@@ -0,0 +1,396 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/openapi"
+	"gatehill.io/imposter/stringutil"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RemoteSpecsFileName holds the remote specs tracked in a config dir.
+const RemoteSpecsFileName = ".imposter-remote-specs.yaml"
+
+const remoteSpecFetchTimeout = 30 * time.Second
+
+// RemoteSpec is an OpenAPI spec fetched from a URL into a config dir.
+type RemoteSpec struct {
+	Url  string `json:"url"`
+	File string `json:"file"`
+
+	// Hash is the SHA-1 hash of the spec content when last fetched
+	Hash      string    `json:"hash"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type remoteSpecs struct {
+	Specs []RemoteSpec `json:"specs"`
+}
+
+// AddRemoteSpec fetches the spec at the URL into the config dir, and tracks
+// it so it can be refreshed later. Configuration is not generated.
+func AddRemoteSpec(configDir string, specUrl string) (specFile string, err error) {
+	spec, _, err := syncRemoteSpec(configDir, specUrl, false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, spec.File), nil
+}
+
+// SyncRemoteSpec fetches the spec at the URL into the config dir. If the spec
+// has changed since it was last fetched, the resources in its configuration
+// are regenerated. Resources that are still in the spec keep any changes made
+// to them, and resources that were not generated from the spec are kept.
+func SyncRemoteSpec(configDir string, specUrl string) (changed bool, err error) {
+	_, changed, err = syncRemoteSpec(configDir, specUrl, true)
+	return changed, err
+}
+
+// RefreshRemoteSpecs syncs each remote spec tracked in the config dir,
+// returning the URLs of those that changed.
+func RefreshRemoteSpecs(configDir string) ([]string, error) {
+	tracked, err := loadRemoteSpecs(configDir)
+	if err != nil {
+		return nil, err
+	}
+	var changedUrls []string
+	for _, spec := range tracked.Specs {
+		changed, err := SyncRemoteSpec(configDir, spec.Url)
+		if err != nil {
+			return changedUrls, err
+		}
+		if changed {
+			changedUrls = append(changedUrls, spec.Url)
+		}
+	}
+	return changedUrls, nil
+}
+
+// ListRemoteSpecs returns the remote specs tracked in the config dir.
+func ListRemoteSpecs(configDir string) ([]RemoteSpec, error) {
+	tracked, err := loadRemoteSpecs(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return tracked.Specs, nil
+}
+
+func syncRemoteSpec(configDir string, specUrl string, updateConfig bool) (RemoteSpec, bool, error) {
+	tracked, err := loadRemoteSpecs(configDir)
+	if err != nil {
+		return RemoteSpec{}, false, err
+	}
+	content, err := fetchRemoteSpec(specUrl)
+	if err != nil {
+		return RemoteSpec{}, false, err
+	}
+	partialSpec, err := openapi.ParseContentFrom(content, specUrl)
+	if err != nil {
+		return RemoteSpec{}, false, fmt.Errorf("unable to parse openapi spec from %s: %v", specUrl, err)
+	}
+
+	index := -1
+	for i, s := range tracked.Specs {
+		if s.Url == specUrl {
+			index = i
+			break
+		}
+	}
+	var spec RemoteSpec
+	if index >= 0 {
+		spec = tracked.Specs[index]
+	} else {
+		spec = RemoteSpec{Url: specUrl, File: buildRemoteSpecFileName(specUrl, content)}
+		for _, s := range tracked.Specs {
+			if s.File == spec.File {
+				return RemoteSpec{}, false, fmt.Errorf("spec file %s is already used by %s", spec.File, s.Url)
+			}
+		}
+	}
+	specFilePath := filepath.Join(configDir, spec.File)
+
+	hash := stringutil.Sha1hash(content)
+	if hash == spec.Hash {
+		if _, err := os.Stat(specFilePath); err == nil {
+			logger.Debugf("remote spec %s is unchanged", specUrl)
+			return spec, false, nil
+		}
+	}
+	spec.Hash = hash
+	spec.FetchedAt = time.Now()
+	if index >= 0 {
+		tracked.Specs[index] = spec
+	} else {
+		tracked.Specs = append(tracked.Specs, spec)
+	}
+
+	tx := fileutil.NewTransaction()
+	if updateConfig {
+		if err := regenerateSpecConfig(tx, configDir, specFilePath, partialSpec); err != nil {
+			return RemoteSpec{}, false, err
+		}
+	}
+	tx.WriteFile(specFilePath, content, 0644)
+	if err := saveRemoteSpecs(tx, configDir, tracked); err != nil {
+		return RemoteSpec{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return RemoteSpec{}, false, fmt.Errorf("failed to write remote spec %s: %v", specUrl, err)
+	}
+	logger.Infof("fetched remote spec %s to %s", specUrl, specFilePath)
+	return spec, true, nil
+}
+
+func fetchRemoteSpec(specUrl string) ([]byte, error) {
+	client := http.Client{Timeout: remoteSpecFetchTimeout}
+	resp, err := client.Get(specUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote spec %s: %v", specUrl, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch remote spec %s: status code %d", specUrl, resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote spec %s: %v", specUrl, err)
+	}
+	return content, nil
+}
+
+// buildRemoteSpecFileName uses the last segment of the URL path if it has a
+// spec file extension, otherwise a name is derived from the URL host.
+func buildRemoteSpecFileName(specUrl string, content []byte) string {
+	u, err := url.Parse(specUrl)
+	if err == nil {
+		base := path.Base(u.Path)
+		if stringutil.Contains([]string{".yaml", ".yml", ".json"}, path.Ext(base)) {
+			return base
+		}
+	}
+	name := "remote-spec"
+	if u != nil && u.Hostname() != "" {
+		name = strings.ReplaceAll(u.Hostname(), ".", "-") + "-spec"
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+		return name + ".json"
+	}
+	return name + ".yaml"
+}
+
+// regenerateSpecConfig stages the configuration for the spec, merging the
+// resources generated from the new spec with those in the existing config.
+func regenerateSpecConfig(tx *fileutil.Transaction, configDir string, specFilePath string, partialSpec *openapi.PartialModel) error {
+	newResources := GenerateResourcesFromModel(partialSpec, ResourceGenerationOptions{})
+
+	configFilePath := findSpecConfigFile(configDir, filepath.Base(specFilePath))
+	if configFilePath == "" {
+		configFilePath = strings.TrimSuffix(specFilePath, filepath.Ext(specFilePath)) + "-config.yaml"
+		config := GenerateConfig(ConfigGenerationOptions{PluginName: "openapi", SpecFilePath: specFilePath}, newResources)
+		tx.WriteFile(configFilePath, config, 0644)
+		tx.OnCommit(func() {
+			logger.Infof("wrote Imposter config: %v", configFilePath)
+		})
+		return nil
+	}
+
+	var oldResources []Resource
+	if _, err := os.Stat(specFilePath); err == nil {
+		oldSpec, err := openapi.Parse(specFilePath)
+		if err != nil {
+			logger.Warnf("unable to parse previous spec %s - keeping all existing resources: %v", specFilePath, err)
+		} else {
+			oldResources = GenerateResourcesFromModel(oldSpec, ResourceGenerationOptions{})
+		}
+	}
+
+	existing, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", configFilePath, err)
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(existing, &config); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", configFilePath, err)
+	}
+	existingResources, _ := config["resources"].([]interface{})
+	merged, err := mergeResources(existingResources, oldResources, newResources)
+	if err != nil {
+		return err
+	}
+	if len(merged) > 0 {
+		config["resources"] = merged
+	} else {
+		delete(config, "resources")
+	}
+
+	updated, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config file %s: %v", configFilePath, err)
+	}
+	tx.WriteFile(configFilePath, updated, 0644)
+	logger.Infof("updated Imposter config: %v", configFilePath)
+	return nil
+}
+
+// findSpecConfigFile returns the path of the config file in the config dir
+// that refers to the spec file, or an empty string if there is none.
+func findSpecConfigFile(configDir string, specFileName string) string {
+	candidates := fileutil.FindFilesWithExtension(configDir, ".yaml", ".yml", ".json")
+	for _, candidate := range candidates {
+		if !strings.Contains(candidate, "-config.") {
+			continue
+		}
+		candidatePath := filepath.Join(configDir, candidate)
+		content, err := os.ReadFile(candidatePath)
+		if err != nil {
+			continue
+		}
+		var pluginConfig PluginConfig
+		if err := yaml.Unmarshal(content, &pluginConfig); err != nil {
+			continue
+		}
+		if pluginConfig.SpecFile == specFileName {
+			return candidatePath
+		}
+	}
+	return ""
+}
+
+// mergeResources combines the existing resources in a config file with those
+// generated from a spec. Existing resources are kept unless they were generated
+// from the old spec and are no longer present in the new spec. New resources
+// are appended.
+func mergeResources(existing []interface{}, oldResources []Resource, newResources []Resource) ([]interface{}, error) {
+	oldKeys := make(map[string]bool)
+	for _, r := range oldResources {
+		oldKeys[buildResourceKey(r.Path, r.Method, derefHeaders(r.RequestHeaders))] = true
+	}
+	newKeys := make(map[string]bool)
+	for _, r := range newResources {
+		newKeys[buildResourceKey(r.Path, r.Method, derefHeaders(r.RequestHeaders))] = true
+	}
+
+	var merged []interface{}
+	present := make(map[string]bool)
+	for _, e := range existing {
+		key := buildRawResourceKey(e)
+		if oldKeys[key] && !newKeys[key] {
+			logger.Debugf("removing resource no longer in spec: %s", key)
+			continue
+		}
+		present[key] = true
+		merged = append(merged, e)
+	}
+	for _, r := range newResources {
+		key := buildResourceKey(r.Path, r.Method, derefHeaders(r.RequestHeaders))
+		if present[key] {
+			continue
+		}
+		raw, err := toRawResource(r)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debugf("adding resource from spec: %s", key)
+		merged = append(merged, raw)
+	}
+	return merged, nil
+}
+
+func buildRawResourceKey(raw interface{}) string {
+	resource, ok := raw.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	resourcePath, _ := resource["path"].(string)
+	method, _ := resource["method"].(string)
+	headers := make(map[string]string)
+	if rawHeaders, ok := resource["requestHeaders"].(map[string]interface{}); ok {
+		for k, v := range rawHeaders {
+			headers[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return buildResourceKey(resourcePath, method, headers)
+}
+
+func buildResourceKey(resourcePath string, method string, requestHeaders map[string]string) string {
+	key := strings.ToUpper(method) + " " + resourcePath
+	var names []string
+	for name := range requestHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key += fmt.Sprintf(" [%s=%s]", name, requestHeaders[name])
+	}
+	return key
+}
+
+func derefHeaders(headers *map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	return *headers
+}
+
+func toRawResource(resource Resource) (interface{}, error) {
+	j, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(j, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource: %v", err)
+	}
+	return raw, nil
+}
+
+func loadRemoteSpecs(configDir string) (remoteSpecs, error) {
+	trackingFile := filepath.Join(configDir, RemoteSpecsFileName)
+	content, err := os.ReadFile(trackingFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return remoteSpecs{}, nil
+		}
+		return remoteSpecs{}, fmt.Errorf("failed to read remote specs file %s: %v", trackingFile, err)
+	}
+	var tracked remoteSpecs
+	if err := yaml.Unmarshal(content, &tracked); err != nil {
+		return remoteSpecs{}, fmt.Errorf("failed to parse remote specs file %s: %v", trackingFile, err)
+	}
+	return tracked, nil
+}
+
+func saveRemoteSpecs(tx *fileutil.Transaction, configDir string, tracked remoteSpecs) error {
+	content, err := yaml.Marshal(tracked)
+	if err != nil {
+		return fmt.Errorf("failed to marshal remote specs: %v", err)
+	}
+	tx.WriteFile(filepath.Join(configDir, RemoteSpecsFileName), content, 0644)
+	return nil
+}
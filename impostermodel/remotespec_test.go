@@ -0,0 +1,61 @@
+package impostermodel
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_mergeResources(t *testing.T) {
+	existing := []interface{}{
+		map[string]interface{}{"path": "/pets", "method": "GET", "response": map[string]interface{}{"statusCode": 200, "staticFile": "pets.json"}},
+		map[string]interface{}{"path": "/orders", "method": "GET"},
+		map[string]interface{}{"path": "/custom", "method": "POST"},
+	}
+	oldResources := []Resource{
+		{Path: "/pets", Method: "GET"},
+		{Path: "/orders", Method: "GET"},
+	}
+	newResources := []Resource{
+		{Path: "/pets", Method: "GET", Response: &ResponseConfig{StatusCode: 200}},
+		{Path: "/stores", Method: "GET", Response: &ResponseConfig{StatusCode: 200}},
+	}
+
+	merged, err := mergeResources(existing, oldResources, newResources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range merged {
+		keys = append(keys, buildRawResourceKey(r))
+	}
+	want := []string{"GET /pets", "POST /custom", "GET /stores"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("mergeResources() keys = %v, want %v", keys, want)
+	}
+
+	// customisations to resources still in the spec are kept
+	pets := merged[0].(map[string]interface{})
+	if pets["response"].(map[string]interface{})["staticFile"] != "pets.json" {
+		t.Errorf("expected existing resource to be kept: %v", pets)
+	}
+}
+
+func Test_buildRemoteSpecFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		specUrl string
+		content string
+		want    string
+	}{
+		{name: "file name from path", specUrl: "https://example.com/specs/petstore.yaml", content: "openapi: 3.0.0", want: "petstore.yaml"},
+		{name: "json derived from host", specUrl: "https://api.example.com/v3/api-docs", content: `{"openapi":"3.0.0"}`, want: "api-example-com-spec.json"},
+		{name: "yaml derived from host", specUrl: "https://api.example.com/spec", content: "openapi: 3.0.0", want: "api-example-com-spec.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildRemoteSpecFileName(tt.specUrl, []byte(tt.content)); got != tt.want {
+				t.Errorf("buildRemoteSpecFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
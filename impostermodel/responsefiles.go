@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"encoding/json"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/openapi"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// writeOpenapiResponseFiles writes the body of the response of each
+// resource to a file adjacent to the spec, and sets it as the static
+// file of the response. The body is taken from the examples in the spec,
+// or generated from the schema of the response. Resources whose
+// responses have no body are unchanged.
+func writeOpenapiResponseFiles(tx *fileutil.Transaction, specFilePath string, anchorFilePath string, resources []Resource, forceOverwrite bool) {
+	partialSpec, err := openapi.Parse(specFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse openapi spec: %v: %v", specFilePath, err))
+	}
+
+	// variants sharing an example share a response file
+	written := make(map[string]string)
+	for i := range resources {
+		response := resources[i].Response
+		operation, found := partialSpec.Paths[resources[i].Path][strings.ToLower(resources[i].Method)]
+		if response == nil || !found {
+			continue
+		}
+		statusCode := strconv.Itoa(response.StatusCode)
+		contentType, body, found := operation.Responses[statusCode].SampleBody(response.ExampleName)
+		if !found {
+			continue
+		}
+		content, extension, ok := encodeResponseBody(contentType, body)
+		if !ok {
+			logger.Debugf("unable to write %s response body for %s %s", contentType, resources[i].Method, resources[i].Path)
+			continue
+		}
+
+		suffix := "-" + strings.ToLower(resources[i].Method) + "-" + buildPathFileName(resources[i].Path) + "-" + statusCode
+		if response.ExampleName != "" {
+			suffix += "-" + sanitiseFileName(response.ExampleName)
+		}
+		suffix += "-response" + extension
+		responseFilePath, found := written[suffix]
+		if !found {
+			responseFilePath = fileutil.GenerateFilePathAdjacentToFile(anchorFilePath, suffix, forceOverwrite)
+			tx.WriteFile(responseFilePath, content, 0644)
+			tx.OnCommit(func() {
+				logger.Debugf("wrote response file: %v", responseFilePath)
+			})
+			written[suffix] = responseFilePath
+		}
+
+		response.StaticFile = filepath.Base(responseFilePath)
+		response.ExampleName = ""
+		if !openapi.IsJsonContentType(contentType) {
+			headers := map[string]string{"Content-Type": contentType}
+			response.Headers = &headers
+		}
+	}
+}
+
+// encodeResponseBody returns the content of the response file for the
+// body, and its extension. Bodies of content types other than JSON must
+// be strings.
+func encodeResponseBody(contentType string, body interface{}) ([]byte, string, bool) {
+	if openapi.IsJsonContentType(contentType) {
+		content, err := json.MarshalIndent(body, "", "  ")
+		if err != nil {
+			return nil, "", false
+		}
+		return content, ".json", true
+	}
+	text, ok := body.(string)
+	if !ok {
+		return nil, "", false
+	}
+	switch {
+	case strings.Contains(contentType, "xml"):
+		return []byte(text), ".xml", true
+	case strings.Contains(contentType, "html"):
+		return []byte(text), ".html", true
+	default:
+		return []byte(text), ".txt", true
+	}
+}
+
+// buildPathFileName returns the segments of the path, without any
+// template characters, joined by hyphens.
+func buildPathFileName(path string) string {
+	segments := strings.FieldsFunc(path, func(c rune) bool {
+		return !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_')
+	})
+	if len(segments) == 0 {
+		return "root"
+	}
+	return strings.Join(segments, "-")
+}
@@ -0,0 +1,113 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_writeOpenapiMockConfig_responseFiles(t *testing.T) {
+	dir := t.TempDir()
+	specFilePath := filepath.Join(dir, "petstore.yaml")
+	spec := `openapi: 3.0.0
+info:
+  title: Petstore
+  version: "1.0"
+paths:
+  /pets/{petId}:
+    get:
+      responses:
+        "200":
+          description: A pet
+          content:
+            application/json:
+              examples:
+                dog:
+                  value: {name: Fido}
+        "404":
+          description: Not found
+  /owners:
+    get:
+      responses:
+        "200":
+          description: Owners
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Owner"
+    delete:
+      responses:
+        "204":
+          description: Deleted
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        id: {type: integer}
+        email: {type: string}
+`
+	if err := os.WriteFile(specFilePath, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeOpenapiMockConfig(tx, specFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone, ResponseFiles: true})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "petstore-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"staticFile: petstore-get-pets-petId-200-dog-response.json",
+		"staticFile: petstore-get-owners-200-response.json",
+		"statusCode: 204",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+	if strings.Contains(string(config), "exampleName") {
+		t.Errorf("expected example names to be replaced by response files, got:\n%s", config)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "petstore-get-pets-petId-200-dog-response.json", want: `"name": "Fido"`},
+		{file: "petstore-get-owners-200-response.json", want: `"email": "jane.doe@example.com"`},
+	}
+	for _, tt := range tests {
+		response, err := os.ReadFile(filepath.Join(dir, tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(response), tt.want) {
+			t.Errorf("expected %s to contain %q, got:\n%s", tt.file, tt.want, response)
+		}
+	}
+}
+
+func Test_buildPathFileName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: "root"},
+		{path: "/pets", want: "pets"},
+		{path: "/pets/{petId}/toys", want: "pets-petId-toys"},
+	}
+	for _, tt := range tests {
+		if got := buildPathFileName(tt.path); got != tt.want {
+			t.Errorf("buildPathFileName(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -17,24 +17,19 @@ limitations under the License.
 package impostermodel
 
 import (
-	"os"
+	"gatehill.io/imposter/fileutil"
 	"path/filepath"
 )
 
 // generateRestMockFiles creates files for a rest mock, and returns
 // the full path to the response file.
-func generateRestMockFiles(configDir string) (readmeFilePath string, responseFilePath string) {
-	return generateReadmeFile(configDir), generateResponseFile(configDir)
+func generateRestMockFiles(tx *fileutil.Transaction, configDir string) (readmeFilePath string, responseFilePath string) {
+	return generateReadmeFile(tx, configDir), generateResponseFile(tx, configDir)
 }
 
-func generateReadmeFile(configDir string) string {
+func generateReadmeFile(tx *fileutil.Transaction, configDir string) string {
 	readmeFile := filepath.Join(configDir, "README.md")
-	configFile, err := os.Create(readmeFile)
-	if err != nil {
-		logger.Fatal(err)
-	}
-	defer configFile.Close()
-	_, err = configFile.WriteString(`Imposter REST mock
+	tx.WriteFile(readmeFile, []byte(`Imposter REST mock
 
 Start the mock with:
 
@@ -46,30 +41,23 @@ Example files:
 
 - response.json
 - mock-config.yaml
-`)
-	if err != nil {
-		logger.Fatalf("failed to write readme file: %s: %s", readmeFile, err)
-	}
-	logger.Debugf("wrote readme file: %v", readmeFile)
+`), 0644)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote readme file: %v", readmeFile)
+	})
 	return readmeFile
 }
 
-func generateResponseFile(configDir string) string {
+func generateResponseFile(tx *fileutil.Transaction, configDir string) string {
 	responseFile := filepath.Join(configDir, "response.json")
-	configFile, err := os.Create(responseFile)
-	if err != nil {
-		logger.Fatal(err)
-	}
-	defer configFile.Close()
-	_, err = configFile.WriteString("{ \"hello\": \"world\" }\n")
-	if err != nil {
-		logger.Fatalf("failed to write response file: %s: %s", responseFile, err)
-	}
-	logger.Debugf("wrote response file: %v", responseFile)
+	tx.WriteFile(responseFile, []byte("{ \"hello\": \"world\" }\n"), 0644)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote response file: %v", responseFile)
+	})
 	return responseFile
 }
 
-func writeRestMockConfig(mockConfigPath string, responseFilePath string, generateResources bool, forceOverwrite bool, scriptEngine ScriptEngine, scriptFileName string) {
+func writeRestMockConfig(tx *fileutil.Transaction, mockConfigPath string, responseFilePath string, generateResources bool, forceOverwrite bool, scriptEngine ScriptEngine, scriptFileName string, cors *CorsConfig) {
 	var resources []Resource
 	if generateResources {
 		resources = buildRestResources(responseFilePath, scriptEngine, scriptFileName)
@@ -80,8 +68,9 @@ func writeRestMockConfig(mockConfigPath string, responseFilePath string, generat
 		PluginName:     "rest",
 		ScriptEngine:   scriptEngine,
 		ScriptFileName: scriptFileName,
+		Cors:           cors,
 	}
-	writeMockConfigAdjacent(mockConfigPath, resources, forceOverwrite, options)
+	writeMockConfigAdjacent(tx, mockConfigPath, resources, forceOverwrite, options)
 }
 
 func buildRestResources(responseFilePath string, scriptEngine ScriptEngine, scriptFileName string) []Resource {
@@ -19,7 +19,6 @@ package impostermodel
 import (
 	"fmt"
 	"gatehill.io/imposter/fileutil"
-	"os"
 	"path/filepath"
 )
 
@@ -71,35 +70,34 @@ func IsScriptEngineEnabled(engine ScriptEngine) bool {
 	return len(engine) > 0 && engine != ScriptEngineNone
 }
 
-func getScriptFileName(anchorFilePath string, scriptEngine ScriptEngine, forceOverwrite bool) string {
+// getScriptFileName writes a script file for the anchor file, if the
+// script engine is enabled, and returns its name. If typeScript is set,
+// a TypeScript source is also written for JavaScript scripts.
+func getScriptFileName(tx *fileutil.Transaction, anchorFilePath string, scriptEngine ScriptEngine, typeScript bool, forceOverwrite bool) string {
 	var scriptFileName string
 	if IsScriptEngineEnabled(scriptEngine) {
-		scriptFilePath := writeScriptFile(anchorFilePath, scriptEngine, forceOverwrite)
+		scriptFilePath := writeScriptFile(tx, anchorFilePath, scriptEngine, forceOverwrite)
+		if typeScript && scriptEngine == ScriptEngineJavaScript {
+			writeTypeScriptStub(tx, scriptFilePath, forceOverwrite)
+		}
 		scriptFileName = filepath.Base(scriptFilePath)
 	}
 	return scriptFileName
 }
 
-func writeScriptFile(anchorFilePath string, engine ScriptEngine, forceOverwrite bool) string {
+func writeScriptFile(tx *fileutil.Transaction, anchorFilePath string, engine ScriptEngine, forceOverwrite bool) string {
 	scriptFilePath := BuildScriptFilePath(anchorFilePath, engine, forceOverwrite)
-	scriptFile, err := os.Create(scriptFilePath)
-	if err != nil {
-		logger.Fatalf("error writing script file: %v: %v", scriptFilePath, err)
-	}
-	defer scriptFile.Close()
-
-	_, err = scriptFile.WriteString(`
+	tx.WriteFile(scriptFilePath, []byte(`
 // TODO add your custom logic here
 logger.debug('method: ' + context.request.method);
 logger.debug('path: ' + context.request.path);
 logger.debug('pathParams: ' + context.request.pathParams);
 logger.debug('queryParams: ' + context.request.queryParams);
 logger.debug('headers: ' + context.request.headers);
-`)
-	if err != nil {
-		logger.Fatalf("error writing script file: %v: %v", scriptFilePath, err)
-	}
+`), 0644)
 
-	logger.Infof("wrote script file: %v", scriptFilePath)
+	tx.OnCommit(func() {
+		logger.Infof("wrote script file: %v", scriptFilePath)
+	})
 	return scriptFilePath
 }
@@ -0,0 +1,122 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/openapi"
+	"sort"
+	"strings"
+)
+
+const authorizationHeader = "Authorization"
+
+// buildOpenapiSecurity generates the security configuration for the
+// security requirements of the spec, or nil if it declares none.
+func buildOpenapiSecurity(specFilePath string) *SecurityConfig {
+	partialSpec, err := openapi.Parse(specFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse openapi spec: %v: %v", specFilePath, err))
+	}
+	requirements := partialSpec.Security
+	if requirements == nil {
+		// any declared scheme permits a request
+		for name := range partialSpec.SecuritySchemes {
+			requirements = append(requirements, openapi.SecurityRequirement{name: nil})
+		}
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+	return buildSecurityConfig(partialSpec.SecuritySchemes, requirements)
+}
+
+// buildSecurityConfig generates security configuration permitting
+// requests that satisfy any of the requirements, and denying others.
+// An empty list of requirements permits all requests. Requirements
+// using schemes that cannot be enforced, such as cookie API keys, are
+// omitted.
+func buildSecurityConfig(schemes map[string]openapi.SecurityScheme, requirements []openapi.SecurityRequirement) *SecurityConfig {
+	if len(requirements) == 0 {
+		return &SecurityConfig{Default: SecurityEffectPermit}
+	}
+	config := &SecurityConfig{Default: SecurityEffectDeny}
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			// an empty requirement makes security optional
+			return &SecurityConfig{Default: SecurityEffectPermit}
+		}
+		if condition, ok := buildSecurityCondition(schemes, requirement); ok {
+			config.Conditions = append(config.Conditions, condition)
+		}
+	}
+	sort.SliceStable(config.Conditions, func(i, j int) bool {
+		return describeCondition(config.Conditions[i]) < describeCondition(config.Conditions[j])
+	})
+	if len(config.Conditions) == 0 {
+		logger.Warnf("no security requirements can be enforced - permitting all requests")
+		return nil
+	}
+	return config
+}
+
+func buildSecurityCondition(schemes map[string]openapi.SecurityScheme, requirement openapi.SecurityRequirement) (SecurityCondition, bool) {
+	condition := SecurityCondition{Effect: SecurityEffectPermit}
+	for name := range requirement {
+		scheme, found := schemes[name]
+		if !found {
+			logger.Warnf("security scheme not found: %s", name)
+			return SecurityCondition{}, false
+		}
+		switch {
+		case scheme.Type == "apiKey" && strings.EqualFold(scheme.In, "header"):
+			condition.RequestHeaders = addMatcher(condition.RequestHeaders, scheme.Name, SecurityMatcher{Operator: "Exists"})
+		case scheme.Type == "apiKey" && strings.EqualFold(scheme.In, "query"):
+			condition.QueryParams = addMatcher(condition.QueryParams, scheme.Name, SecurityMatcher{Operator: "Exists"})
+		case scheme.Type == "basic", scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			condition.RequestHeaders = addMatcher(condition.RequestHeaders, authorizationHeader, SecurityMatcher{Value: "^[Bb]asic .+", Operator: "Matches"})
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"), scheme.Type == "oauth2", scheme.Type == "openIdConnect":
+			condition.RequestHeaders = addMatcher(condition.RequestHeaders, authorizationHeader, SecurityMatcher{Value: "^[Bb]earer .+", Operator: "Matches"})
+		default:
+			logger.Warnf("unsupported security scheme %s (type: %s, scheme: %s, in: %s) - omitting requirement", name, scheme.Type, scheme.Scheme, scheme.In)
+			return SecurityCondition{}, false
+		}
+	}
+	return condition, true
+}
+
+func addMatcher(matchers map[string]SecurityMatcher, name string, matcher SecurityMatcher) map[string]SecurityMatcher {
+	if matchers == nil {
+		matchers = make(map[string]SecurityMatcher)
+	}
+	matchers[name] = matcher
+	return matchers
+}
+
+// describeCondition returns a stable description of the condition,
+// used to order conditions consistently.
+func describeCondition(condition SecurityCondition) string {
+	var names []string
+	for name := range condition.RequestHeaders {
+		names = append(names, "header:"+name)
+	}
+	for name := range condition.QueryParams {
+		names = append(names, "query:"+name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,137 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/openapi"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_buildSecurityConfig(t *testing.T) {
+	schemes := map[string]openapi.SecurityScheme{
+		"apiKey":  {Type: "apiKey", Name: "X-Api-Key", In: "header"},
+		"query":   {Type: "apiKey", Name: "api_key", In: "query"},
+		"cookie":  {Type: "apiKey", Name: "session", In: "cookie"},
+		"bearer":  {Type: "http", Scheme: "bearer"},
+		"basic":   {Type: "basic"},
+		"oauth":   {Type: "oauth2"},
+		"unknown": {Type: "mutualTLS"},
+	}
+	exists := SecurityMatcher{Operator: "Exists"}
+	bearer := SecurityMatcher{Value: "^[Bb]earer .+", Operator: "Matches"}
+	basic := SecurityMatcher{Value: "^[Bb]asic .+", Operator: "Matches"}
+
+	tests := []struct {
+		name         string
+		requirements []openapi.SecurityRequirement
+		want         *SecurityConfig
+	}{
+		{
+			name:         "no requirements",
+			requirements: []openapi.SecurityRequirement{},
+			want:         &SecurityConfig{Default: SecurityEffectPermit},
+		},
+		{
+			name:         "optional",
+			requirements: []openapi.SecurityRequirement{{"bearer": nil}, {}},
+			want:         &SecurityConfig{Default: SecurityEffectPermit},
+		},
+		{
+			name:         "alternatives",
+			requirements: []openapi.SecurityRequirement{{"bearer": nil}, {"apiKey": nil}, {"cookie": nil}},
+			want: &SecurityConfig{
+				Default: SecurityEffectDeny,
+				Conditions: []SecurityCondition{
+					{Effect: SecurityEffectPermit, RequestHeaders: map[string]SecurityMatcher{"Authorization": bearer}},
+					{Effect: SecurityEffectPermit, RequestHeaders: map[string]SecurityMatcher{"X-Api-Key": exists}},
+				},
+			},
+		},
+		{
+			name:         "combined",
+			requirements: []openapi.SecurityRequirement{{"basic": nil, "query": nil}},
+			want: &SecurityConfig{
+				Default: SecurityEffectDeny,
+				Conditions: []SecurityCondition{
+					{Effect: SecurityEffectPermit, RequestHeaders: map[string]SecurityMatcher{"Authorization": basic}, QueryParams: map[string]SecurityMatcher{"api_key": exists}},
+				},
+			},
+		},
+		{
+			name:         "oauth2",
+			requirements: []openapi.SecurityRequirement{{"oauth": []string{"read"}}},
+			want: &SecurityConfig{
+				Default:    SecurityEffectDeny,
+				Conditions: []SecurityCondition{{Effect: SecurityEffectPermit, RequestHeaders: map[string]SecurityMatcher{"Authorization": bearer}}},
+			},
+		},
+		{
+			name:         "unenforceable",
+			requirements: []openapi.SecurityRequirement{{"unknown": nil}, {"missing": nil}},
+			want:         nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildSecurityConfig(schemes, tt.requirements); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSecurityConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeOpenapiMockConfig_security(t *testing.T) {
+	dir := t.TempDir()
+	specFilePath := filepath.Join(dir, "petstore.yaml")
+	spec := `openapi: 3.0.0
+info:
+  title: Petstore
+  version: "1.0"
+security:
+  - bearerAuth: []
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: Pets
+  /health:
+    get:
+      security: []
+      responses:
+        "200":
+          description: Healthy
+components:
+  securitySchemes:
+    bearerAuth:
+      type: http
+      scheme: bearer
+`
+	if err := os.WriteFile(specFilePath, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeOpenapiMockConfig(tx, specFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone, SimulateSecurity: true})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "petstore-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"default: Deny",
+		"effect: Permit",
+		"operator: Matches",
+		"default: Permit",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+}
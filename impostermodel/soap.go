@@ -0,0 +1,78 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/wsdl"
+	"path/filepath"
+)
+
+func writeSoapMockConfig(tx *fileutil.Transaction, wsdlFilePath string, generateResources bool, forceOverwrite bool, resourceOptions ResourceGenerationOptions) {
+	var resources []Resource
+	if generateResources {
+		resources = buildSoapResources(tx, wsdlFilePath, forceOverwrite, resourceOptions)
+	} else {
+		logger.Debug("skipping resource generation")
+	}
+	options := ConfigGenerationOptions{
+		PluginName:     "soap",
+		ScriptEngine:   resourceOptions.ScriptEngine,
+		ScriptFileName: resourceOptions.ScriptFileName,
+		WsdlFilePath:   wsdlFilePath,
+		Cors:           resourceOptions.Cors,
+	}
+	writeMockConfigAdjacent(tx, resourceOptions.anchorFor(wsdlFilePath), resources, forceOverwrite, options)
+}
+
+// buildSoapResources generates a resource for each operation of each
+// SOAP binding in the WSDL file. Each resource returns a sample response,
+// generated from the schema types, which is written adjacent to the WSDL file.
+func buildSoapResources(tx *fileutil.Transaction, wsdlFilePath string, forceOverwrite bool, options ResourceGenerationOptions) []Resource {
+	document, err := wsdl.Parse(wsdlFilePath)
+	if err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to parse WSDL file: %v: %v", wsdlFilePath, err))
+	}
+
+	var resources []Resource
+	for _, binding := range document.Bindings {
+		for _, operation := range binding.Operations {
+			responseFilePath := fileutil.GenerateFilePathAdjacentToFile(options.anchorFor(wsdlFilePath), "-"+binding.Name+"-"+operation.Name+"-response.xml", forceOverwrite)
+			tx.WriteFile(responseFilePath, document.SampleResponse(binding, operation), 0644)
+			tx.OnCommit(func() {
+				logger.Debugf("wrote response file: %v", responseFilePath)
+			})
+
+			resource := Resource{
+				Binding:   binding.Name,
+				Operation: operation.Name,
+				Response: &ResponseConfig{
+					StatusCode: 200,
+					StaticFile: filepath.Base(responseFilePath),
+				},
+			}
+			if IsScriptEngineEnabled(options.ScriptEngine) {
+				resource.Response.ScriptFile = options.ScriptFileName
+			}
+			resource.Response.Delay = options.DelayProfile.BuildDelay(binding.Name+" "+operation.Name, nil)
+			resources = append(resources, resource)
+		}
+	}
+	logger.Debugf("generated %d resources from WSDL file", len(resources))
+	return resources
+}
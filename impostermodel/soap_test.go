@@ -0,0 +1,69 @@
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stockWsdl = `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+             xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+             xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+             xmlns:tns="urn:example:stock" targetNamespace="urn:example:stock">
+  <message name="GetPriceResponse">
+    <part name="price" type="xsd:decimal"/>
+  </message>
+  <portType name="StockPortType">
+    <operation name="GetPrice"><output message="tns:GetPriceResponse"/></operation>
+  </portType>
+  <binding name="StockBinding" type="tns:StockPortType">
+    <soap:binding style="rpc" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetPrice">
+      <soap:operation soapAction="urn:GetPrice"/>
+      <output><soap:body use="literal"/></output>
+    </operation>
+  </binding>
+</definitions>`
+
+func Test_writeSoapMockConfig(t *testing.T) {
+	dir := t.TempDir()
+	wsdlFilePath := filepath.Join(dir, "stock.wsdl")
+	if err := os.WriteFile(wsdlFilePath, []byte(stockWsdl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := fileutil.NewTransaction()
+	writeSoapMockConfig(tx, wsdlFilePath, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineNone})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "stock-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"plugin: soap",
+		"wsdlFile: stock.wsdl",
+		"binding: StockBinding",
+		"operation: GetPrice",
+		"staticFile: stock-StockBinding-GetPrice-response.xml",
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+	if strings.Contains(string(config), "path:") {
+		t.Errorf("expected no path in soap resources, got:\n%s", config)
+	}
+
+	response, err := os.ReadFile(filepath.Join(dir, "stock-StockBinding-GetPrice-response.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(response), "<price xmlns=\"\">0.0</price>") {
+		t.Errorf("unexpected sample response:\n%s", response)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"fmt"
+	"gatehill.io/imposter/asyncapi"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/graphql"
+	"gatehill.io/imposter/openapi"
+	"gatehill.io/imposter/protobuf"
+	"gatehill.io/imposter/stringutil"
+	"gatehill.io/imposter/wsdl"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// specFiles holds the spec files for which configuration is generated,
+// by the plugin used to mock them.
+type specFiles struct {
+	openApi  []string
+	wsdl     []string
+	graphql  []string
+	proto    []string
+	asyncApi []string
+}
+
+func (s specFiles) all() []string {
+	var all []string
+	all = append(all, s.openApi...)
+	all = append(all, s.wsdl...)
+	all = append(all, s.graphql...)
+	all = append(all, s.proto...)
+	return append(all, s.asyncApi...)
+}
+
+func discoverSpecFiles(configDir string, protoImportPaths []string) specFiles {
+	specs := specFiles{}
+	specs.openApi = openapi.DiscoverOpenApiSpecs(configDir)
+	logger.Infof("found %d OpenAPI spec(s)", len(specs.openApi))
+	specs.wsdl = wsdl.DiscoverWsdlFiles(configDir)
+	logger.Infof("found %d WSDL file(s)", len(specs.wsdl))
+	specs.graphql = graphql.DiscoverSchemas(configDir)
+	logger.Infof("found %d GraphQL schema(s)", len(specs.graphql))
+	specs.proto = protobuf.DiscoverProtoFiles(configDir, protoImportPaths)
+	logger.Infof("found %d protobuf file(s) with services", len(specs.proto))
+	specs.asyncApi = asyncapi.DiscoverAsyncApiSpecs(configDir)
+	logger.Infof("found %d AsyncAPI spec(s)", len(specs.asyncApi))
+	return specs
+}
+
+// selectSpecFiles discovers the specs in the directories of the given
+// paths, keeping those at the paths, or in them if they are directories.
+// A file that is not a spec is a failure.
+func selectSpecFiles(specPaths []string, protoImportPaths []string) specFiles {
+	var dirs, files []string
+	for _, specPath := range specPaths {
+		if info, err := os.Stat(specPath); err != nil {
+			failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "unable to read spec: %v", err))
+		} else if info.IsDir() {
+			dirs = append(dirs, specPath)
+		} else {
+			dirs = append(dirs, filepath.Dir(specPath))
+			files = append(files, specPath)
+		}
+	}
+
+	selected := specFiles{}
+	keep := func(discovered []string) []string {
+		var kept []string
+		for _, specFile := range discovered {
+			if stringutil.Contains(specPaths, filepath.Dir(specFile)) || stringutil.Contains(files, specFile) {
+				kept = append(kept, specFile)
+			}
+		}
+		return kept
+	}
+	for _, dir := range stringutil.Unique(dirs) {
+		discovered := discoverSpecFiles(dir, protoImportPaths)
+		selected.openApi = append(selected.openApi, keep(discovered.openApi)...)
+		selected.wsdl = append(selected.wsdl, keep(discovered.wsdl)...)
+		selected.graphql = append(selected.graphql, keep(discovered.graphql)...)
+		selected.proto = append(selected.proto, keep(discovered.proto)...)
+		selected.asyncApi = append(selected.asyncApi, keep(discovered.asyncApi)...)
+	}
+
+	all := selected.all()
+	for _, file := range files {
+		if !stringutil.Contains(all, file) {
+			failure.Fatal(failure.New(failure.CodeGenerateSpecInvalid, "not an OpenAPI spec, WSDL file, GraphQL schema, protobuf service or AsyncAPI spec: %s", file))
+		}
+	}
+	return selected
+}
+
+// ExpandSpecPaths expands the glob patterns, such as 'services/*/openapi.yaml',
+// into the absolute paths of the files and directories they match. A
+// pattern that matches nothing is an error.
+func ExpandSpecPaths(patterns []string) ([]string, error) {
+	var specPaths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec pattern: %s: %v", pattern, err)
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match: %s", pattern)
+		}
+		for _, match := range matches {
+			absPath, _ := filepath.Abs(match)
+			specPaths = append(specPaths, absPath)
+		}
+	}
+	return stringutil.Unique(specPaths), nil
+}
+
+// buildAnchorFilePaths returns the path on which the names of the files
+// generated for each spec are based. This is the spec file itself, unless
+// another spec in the same directory has the same name other than its
+// extension, such as 'petstore.yaml' and 'petstore.json', in which case
+// the extension is added to the name, such as 'petstore-json.json'.
+func buildAnchorFilePaths(specFiles []string) map[string]string {
+	stems := make(map[string]int)
+	for _, specFile := range specFiles {
+		stems[strings.TrimSuffix(specFile, filepath.Ext(specFile))]++
+	}
+	anchors := make(map[string]string)
+	for _, specFile := range specFiles {
+		ext := filepath.Ext(specFile)
+		stem := strings.TrimSuffix(specFile, ext)
+		if stems[stem] > 1 && ext != "" {
+			anchors[specFile] = stem + "-" + strings.TrimPrefix(ext, ".") + ext
+		} else {
+			anchors[specFile] = specFile
+		}
+	}
+	return anchors
+}
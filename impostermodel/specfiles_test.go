@@ -0,0 +1,83 @@
+package impostermodel
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const minimalOpenApiSpec = `openapi: 3.0.0
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: OK
+`
+
+func Test_buildAnchorFilePaths(t *testing.T) {
+	specFiles := []string{"/a/petstore.yaml", "/a/petstore.json", "/a/orders.yaml", "/b/petstore.yaml"}
+	want := map[string]string{
+		"/a/petstore.yaml": "/a/petstore-yaml.yaml",
+		"/a/petstore.json": "/a/petstore-json.json",
+		"/a/orders.yaml":   "/a/orders.yaml",
+		"/b/petstore.yaml": "/b/petstore.yaml",
+	}
+	if got := buildAnchorFilePaths(specFiles); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildAnchorFilePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateFromSpecs(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"orders/openapi.yaml", "users/openapi.yaml", "users/openapi.json", "users/notes.yaml"} {
+		content := minimalOpenApiSpec
+		if filepath.Ext(file) == ".json" {
+			content = `{"openapi": "3.0.0", "info": {"title": "Test", "version": "1.0.0"}, "paths": {}}`
+		} else if file == "users/notes.yaml" {
+			content = "notes: not a spec\n"
+		}
+		path := filepath.Join(dir, file)
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	specPaths, err := ExpandSpecPaths([]string{filepath.Join(dir, "orders", "*.yaml"), filepath.Join(dir, "users")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	CreateFromSpecs(specPaths, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineJavaScript})
+
+	for _, file := range []string{
+		"orders/openapi-config.yaml",
+		"orders/openapi.js",
+		"users/openapi-yaml-config.yaml",
+		"users/openapi-yaml.js",
+		"users/openapi-json-config.yaml",
+		"users/openapi-json.js",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("expected %s to be written: %v", file, err)
+		}
+	}
+	config, err := os.ReadFile(filepath.Join(dir, "users", "openapi-json-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "specFile: openapi.json") || !strings.Contains(string(config), "scriptFile: openapi-json.js") {
+		t.Errorf("expected config to reference spec and script, got:\n%s", config)
+	}
+}
+
+func TestExpandSpecPaths_noMatch(t *testing.T) {
+	if _, err := ExpandSpecPaths([]string{filepath.Join(t.TempDir(), "*.yaml")}); err == nil {
+		t.Error("expected error for pattern matching no files")
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
+	"path/filepath"
+	"strings"
+)
+
+// Template is a built-in mock, demonstrating features of Imposter
+// configuration, that is generated without a spec.
+type Template string
+
+const (
+	// TemplateCrud is a stateful mock, storing created and updated
+	// items using capture, and returning them using a script.
+	TemplateCrud Template = "crud"
+
+	// TemplateAuth is a mock issuing tokens, and requiring a bearer
+	// token for other requests.
+	TemplateAuth Template = "auth"
+
+	// TemplatePaginated is a mock returning pages of items, selected
+	// using a query parameter.
+	TemplatePaginated Template = "paginated"
+)
+
+// DefaultTemplateResource is the name of the resource generated by a
+// template, if none is given.
+const DefaultTemplateResource = "items"
+
+// ParseTemplate parses the name of a built-in template.
+func ParseTemplate(name string) (Template, error) {
+	switch template := Template(name); template {
+	case TemplateCrud, TemplateAuth, TemplatePaginated:
+		return template, nil
+	default:
+		return "", fmt.Errorf("unsupported template: %s (valid: crud,auth,paginated)", name)
+	}
+}
+
+// CreateFromTemplate generates the configuration and files for a built-in
+// template in configDir. The resource name is used for the path, store
+// and file names of the mock. If cors is set, it is added to the
+// configuration.
+func CreateFromTemplate(configDir string, template Template, resourceName string, forceOverwrite bool, cors *CorsConfig) {
+	resourceName = strings.Trim(resourceName, "/")
+	if resourceName == "" {
+		resourceName = DefaultTemplateResource
+	}
+	tx := fileutil.NewTransaction()
+	anchorFilePath := filepath.Join(configDir, sanitiseFileName(resourceName)+".txt")
+
+	var resources []Resource
+	var security *SecurityConfig
+	switch template {
+	case TemplateCrud:
+		resources = buildCrudResources(tx, anchorFilePath, resourceName, forceOverwrite)
+	case TemplateAuth:
+		resources, security = buildAuthResources(tx, anchorFilePath, resourceName, forceOverwrite)
+	case TemplatePaginated:
+		resources = buildPaginatedResources(tx, anchorFilePath, resourceName, forceOverwrite)
+	default:
+		logger.Fatalf("unsupported template: %s", template)
+	}
+	options := ConfigGenerationOptions{
+		PluginName: "rest",
+		Security:   security,
+		Cors:       cors,
+	}
+	writeMockConfigAdjacent(tx, anchorFilePath, resources, forceOverwrite, options)
+
+	if err := tx.Commit(); err != nil {
+		failure.Fatal(failure.New(failure.CodeGenerateWriteFailed, "failed to write Imposter configuration to: %s: %v", configDir, err))
+	}
+	logger.Infof("generated %s template for /%s in %s", template, resourceName, configDir)
+}
+
+// buildCrudResources generates resources that create, read, update and
+// delete items. Created and updated items are captured in a store, named
+// after the resource, keyed by their ID. Reads and deletes use a script,
+// which accesses the store.
+func buildCrudResources(tx *fileutil.Transaction, anchorFilePath string, resourceName string, forceOverwrite bool) []Resource {
+	scriptFilePath := fileutil.GenerateFilePathAdjacentToFile(anchorFilePath, ".js", forceOverwrite)
+	tx.WriteFile(scriptFilePath, []byte(fmt.Sprintf(`// Reads and deletes the items captured in the '%[1]s' store.
+var store = stores.open('%[1]s');
+var id = context.request.pathParams.id;
+
+if (!id) {
+    var items = store.loadAll();
+    var list = [];
+    for (var key in items) {
+        list.push(JSON.parse(items[key]));
+    }
+    respond().withStatusCode(200).withHeader('Content-Type', 'application/json').withContent(JSON.stringify(list));
+
+} else if (!store.hasItemWithKey(id)) {
+    respond().withStatusCode(404).withEmpty();
+
+} else if (context.request.method === 'DELETE') {
+    store.delete(id);
+    respond().withStatusCode(204).withEmpty();
+
+} else {
+    respond().withStatusCode(200).withHeader('Content-Type', 'application/json').withContent(store.load(id));
+}
+`, resourceName)), 0644)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote script file: %v", scriptFilePath)
+	})
+	scriptFileName := filepath.Base(scriptFilePath)
+
+	collectionPath := "/" + resourceName
+	itemPath := collectionPath + "/{id}"
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+	echoResponse := func(statusCode int) *ResponseConfig {
+		return &ResponseConfig{
+			StatusCode: statusCode,
+			StaticData: "${context.request.body}",
+			Template:   true,
+			Headers:    &jsonHeaders,
+		}
+	}
+	return []Resource{
+		{
+			Path:   collectionPath,
+			Method: "POST",
+			Capture: map[string]Capture{
+				"item": {
+					Store:      resourceName,
+					Key:        &Capture{RequestBody: &CaptureBody{JsonPath: "$.id"}},
+					Expression: "${context.request.body}",
+				},
+			},
+			Response: echoResponse(201),
+		},
+		{
+			Path:   itemPath,
+			Method: "PUT",
+			Capture: map[string]Capture{
+				"item": {
+					Store:      resourceName,
+					Key:        &Capture{PathParam: "id"},
+					Expression: "${context.request.body}",
+				},
+			},
+			Response: echoResponse(200),
+		},
+		{
+			Path:     collectionPath,
+			Method:   "GET",
+			Response: &ResponseConfig{ScriptFile: scriptFileName},
+		},
+		{
+			Path:     itemPath,
+			Method:   "GET",
+			Response: &ResponseConfig{ScriptFile: scriptFileName},
+		},
+		{
+			Path:     itemPath,
+			Method:   "DELETE",
+			Response: &ResponseConfig{ScriptFile: scriptFileName},
+		},
+	}
+}
+
+// buildAuthResources generates a resource issuing tokens, and a resource
+// that requires a bearer token, returning the security configuration
+// that requires it.
+func buildAuthResources(tx *fileutil.Transaction, anchorFilePath string, resourceName string, forceOverwrite bool) ([]Resource, *SecurityConfig) {
+	tokenFilePath := writeTemplateJson(tx, anchorFilePath, "-token.json", map[string]interface{}{
+		"access_token": "eyJhbGciOiJIUzI1NiJ9.bW9jaw.c2lnbmF0dXJl",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	}, forceOverwrite)
+	itemsFilePath := writeTemplateJson(tx, anchorFilePath, ".json", buildTemplateItems(resourceName, 1, 3), forceOverwrite)
+
+	security := &SecurityConfig{
+		Default: SecurityEffectDeny,
+		Conditions: []SecurityCondition{
+			{
+				Effect:         SecurityEffectPermit,
+				RequestHeaders: map[string]SecurityMatcher{authorizationHeader: {Value: "^[Bb]earer .+", Operator: "Matches"}},
+			},
+		},
+	}
+	resources := []Resource{
+		{
+			Path:     "/auth/token",
+			Method:   "POST",
+			Security: &SecurityConfig{Default: SecurityEffectPermit},
+			Response: &ResponseConfig{
+				StatusCode: 200,
+				StaticFile: filepath.Base(tokenFilePath),
+			},
+		},
+		{
+			Path:   "/" + resourceName,
+			Method: "GET",
+			Response: &ResponseConfig{
+				StatusCode: 200,
+				StaticFile: filepath.Base(itemsFilePath),
+			},
+		},
+	}
+	return resources, security
+}
+
+// buildPaginatedResources generates a resource for each page of items,
+// selected using the 'page' query parameter. The first page is returned
+// if the parameter is absent.
+func buildPaginatedResources(tx *fileutil.Transaction, anchorFilePath string, resourceName string, forceOverwrite bool) []Resource {
+	const pages = 3
+	const pageSize = 2
+
+	var resources []Resource
+	for page := 1; page <= pages; page++ {
+		body := map[string]interface{}{
+			"page":       page,
+			"pageSize":   pageSize,
+			"totalPages": pages,
+			"totalItems": pages * pageSize,
+			"items":      buildTemplateItems(resourceName, (page-1)*pageSize+1, pageSize),
+		}
+		if page < pages {
+			body["next"] = fmt.Sprintf("/%s?page=%d", resourceName, page+1)
+		}
+		pageFilePath := writeTemplateJson(tx, anchorFilePath, fmt.Sprintf("-page-%d.json", page), body, forceOverwrite)
+
+		resource := Resource{
+			Path:        "/" + resourceName,
+			Method:      "GET",
+			QueryParams: &map[string]string{"page": fmt.Sprintf("%d", page)},
+			Response: &ResponseConfig{
+				StatusCode: 200,
+				StaticFile: filepath.Base(pageFilePath),
+			},
+		}
+		if page == 1 {
+			// the first page is also the default
+			resources = append(resources, Resource{Path: resource.Path, Method: resource.Method, Response: resource.Response})
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func buildTemplateItems(resourceName string, first int, count int) []interface{} {
+	var items []interface{}
+	for id := first; id < first+count; id++ {
+		items = append(items, map[string]interface{}{
+			"id":   fmt.Sprintf("%d", id),
+			"name": fmt.Sprintf("%s %d", resourceName, id),
+		})
+	}
+	return items
+}
+
+func writeTemplateJson(tx *fileutil.Transaction, anchorFilePath string, suffix string, body interface{}, forceOverwrite bool) string {
+	content, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		logger.Fatalf("unable to marshal template response: %v", err)
+	}
+	responseFilePath := fileutil.GenerateFilePathAdjacentToFile(anchorFilePath, suffix, forceOverwrite)
+	tx.WriteFile(responseFilePath, content, 0644)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote response file: %v", responseFilePath)
+	})
+	return responseFilePath
+}
@@ -0,0 +1,77 @@
+package impostermodel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateFromTemplate(t *testing.T) {
+	tests := []struct {
+		template Template
+		files    []string
+		want     []string
+	}{
+		{
+			template: TemplateCrud,
+			files:    []string{"users.js"},
+			want: []string{
+				"method: POST",
+				"store: users",
+				"jsonPath: $.id",
+				"pathParam: id",
+				"template: true",
+				"scriptFile: users.js",
+			},
+		},
+		{
+			template: TemplateAuth,
+			files:    []string{"users-token.json", "users.json"},
+			want: []string{
+				"path: /auth/token",
+				"default: Deny",
+				"default: Permit",
+				"staticFile: users.json",
+			},
+		},
+		{
+			template: TemplatePaginated,
+			files:    []string{"users-page-1.json", "users-page-2.json", "users-page-3.json"},
+			want: []string{
+				"page: \"2\"",
+				"staticFile: users-page-3.json",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.template), func(t *testing.T) {
+			dir := t.TempDir()
+			CreateFromTemplate(dir, tt.template, "/users", false, nil)
+
+			for _, file := range tt.files {
+				if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+					t.Errorf("expected %s to be written: %v", file, err)
+				}
+			}
+			config, err := os.ReadFile(filepath.Join(dir, "users-config.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(config), want) {
+					t.Errorf("expected config to contain %q, got:\n%s", want, config)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	if got, err := ParseTemplate("crud"); err != nil || got != TemplateCrud {
+		t.Errorf("ParseTemplate(crud) = %v, %v", got, err)
+	}
+	if _, err := ParseTemplate("unknown"); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impostermodel
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"path/filepath"
+	"strings"
+)
+
+const typeDeclarationsFileName = "imposter.d.ts"
+const tsconfigFileName = "tsconfig.json"
+
+// typeDeclarations declare the globals available to Imposter scripts.
+const typeDeclarations = `// Type declarations for Imposter scripts.
+// See https://docs.imposter.sh/scripting/
+
+interface ImposterRequest {
+    method: string;
+    path: string;
+    uri: string;
+    body: string;
+    pathParams: { [name: string]: string };
+    queryParams: { [name: string]: string };
+    formParams: { [name: string]: string };
+    headers: { [name: string]: string };
+    normalisedHeaders: { [name: string]: string };
+}
+
+interface ImposterContext {
+    request: ImposterRequest;
+}
+
+interface ResponseBuilder {
+    withStatusCode(statusCode: number): ResponseBuilder;
+    withFile(responseFile: string): ResponseBuilder;
+    withContent(content: string): ResponseBuilder;
+    withData(content: string): ResponseBuilder;
+    withExampleName(exampleName: string): ResponseBuilder;
+    withHeader(name: string, value: string): ResponseBuilder;
+    withEmpty(): ResponseBuilder;
+    withDelay(exactDelayMs: number): ResponseBuilder;
+    withDelayRange(minDelayMs: number, maxDelayMs: number): ResponseBuilder;
+    template(): ResponseBuilder;
+    usingDefaultBehaviour(): ResponseBuilder;
+    skipDefaultBehaviour(): ResponseBuilder;
+    and(): ResponseBuilder;
+}
+
+interface Store {
+    save(key: string, value: any): void;
+    load(key: string): any;
+    loadAsJson(key: string): string;
+    loadAll(): { [key: string]: any };
+    loadByKeyPrefix(keyPrefix: string): { [key: string]: any };
+    hasItemWithKey(key: string): boolean;
+    delete(key: string): void;
+}
+
+interface Stores {
+    open(storeName: string): Store;
+}
+
+interface Logger {
+    trace(message: string): void;
+    debug(message: string): void;
+    info(message: string): void;
+    warn(message: string): void;
+    error(message: string): void;
+}
+
+declare const context: ImposterContext;
+declare const env: { [name: string]: string };
+declare const logger: Logger;
+declare const stores: Stores;
+declare function respond(): ResponseBuilder;
+`
+
+// tsconfig compiles each TypeScript script to the JavaScript file
+// alongside it, which is the file referenced by the configuration.
+const tsconfig = `{
+  "compilerOptions": {
+    "target": "ES5",
+    "lib": ["ES5"],
+    "strict": true,
+    "noEmitOnError": true
+  },
+  "include": ["**/*.ts"]
+}
+`
+
+// writeTypeScriptStub writes a TypeScript source for the JavaScript
+// script at scriptFilePath, which is the output of compiling it.
+func writeTypeScriptStub(tx *fileutil.Transaction, scriptFilePath string, forceOverwrite bool) {
+	stubFilePath := strings.TrimSuffix(scriptFilePath, filepath.Ext(scriptFilePath)) + ".ts"
+	fileutil.MustNotExist(stubFilePath, forceOverwrite)
+	tx.WriteFile(stubFilePath, []byte(`// Compile to `+filepath.Base(scriptFilePath)+` with 'npx tsc' - see `+tsconfigFileName+`
+
+// TODO add your custom logic here
+logger.debug('method: ' + context.request.method);
+logger.debug('path: ' + context.request.path);
+logger.debug('pathParams: ' + JSON.stringify(context.request.pathParams));
+logger.debug('queryParams: ' + JSON.stringify(context.request.queryParams));
+logger.debug('headers: ' + JSON.stringify(context.request.headers));
+`), 0644)
+	tx.OnCommit(func() {
+		logger.Infof("wrote TypeScript script file: %v", stubFilePath)
+	})
+}
+
+// writeTypeScriptProject writes the type declarations for Imposter
+// scripts and a tsconfig to compile TypeScript scripts in configDir.
+func writeTypeScriptProject(tx *fileutil.Transaction, configDir string, forceOverwrite bool) {
+	declarationsFilePath := filepath.Join(configDir, typeDeclarationsFileName)
+	fileutil.MustNotExist(declarationsFilePath, forceOverwrite)
+	tx.WriteFile(declarationsFilePath, []byte(typeDeclarations), 0644)
+
+	tsconfigFilePath := filepath.Join(configDir, tsconfigFileName)
+	fileutil.MustNotExist(tsconfigFilePath, forceOverwrite)
+	tx.WriteFile(tsconfigFilePath, []byte(tsconfig), 0644)
+
+	tx.OnCommit(func() {
+		logger.Infof("wrote TypeScript declarations and %s - compile scripts with 'npx tsc' in %v", tsconfigFileName, configDir)
+	})
+}
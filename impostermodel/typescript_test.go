@@ -0,0 +1,33 @@
+package impostermodel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreate_typeScript(t *testing.T) {
+	dir := t.TempDir()
+	Create(dir, true, false, ResourceGenerationOptions{ScriptEngine: ScriptEngineJavaScript, TypeScript: true}, false)
+
+	for _, file := range []string{"mock.js", "mock.ts", typeDeclarationsFileName, tsconfigFileName} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("expected %s to be written: %v", file, err)
+		}
+	}
+	stub, err := os.ReadFile(filepath.Join(dir, "mock.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(stub), "Compile to mock.js") {
+		t.Errorf("expected stub to reference compiled script, got:\n%s", stub)
+	}
+	config, err := os.ReadFile(filepath.Join(dir, "mock-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "scriptFile: mock.js") {
+		t.Errorf("expected config to reference compiled script, got:\n%s", config)
+	}
+}
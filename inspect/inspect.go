@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"gatehill.io/imposter/proxy"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// resizePollInterval is how often the screen is redrawn, so it fits
+// the terminal if it is resized.
+const resizePollInterval = time.Second
+
+// Run shows the inspector until the user quits. Exchanges are received
+// on events, and errors from the source of the events on errs. Requests
+// are re-sent to, and curl commands use, the mock or proxy at baseUrl.
+func Run(baseUrl string, events <-chan proxy.ExchangeEvent, errs <-chan error) error {
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.close()
+
+	out := bufio.NewWriter(os.Stdout)
+	// use the alternate screen and hide the cursor
+	_, _ = out.WriteString("\033[?1049h\033[?25l")
+	defer func() {
+		_, _ = out.WriteString("\033[?25h\033[?1049l")
+		_ = out.Flush()
+	}()
+
+	keys := make(chan key)
+	go readKeys(os.Stdin, keys)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	statuses := make(chan string)
+	ticker := time.NewTicker(resizePollInterval)
+	defer ticker.Stop()
+
+	m := newModel("Imposter inspector - " + baseUrl)
+	for {
+		width, height := term.size()
+		draw(out, m.render(width, height))
+
+		select {
+		case event := <-events:
+			m.add(event)
+		case err := <-errs:
+			m.status = err.Error()
+		case status := <-statuses:
+			m.status = status
+		case k := <-keys:
+			m.status = ""
+			switch m.handle(k, height-3) {
+			case actionQuit:
+				return nil
+			case actionResend:
+				event, _ := m.current()
+				m.status = fmt.Sprintf("re-sending %s %s...", event.Method, event.Path)
+				go func() {
+					statuses <- resend(client, baseUrl, event)
+				}()
+			case actionCopy:
+				event, _ := m.current()
+				copyToClipboard(out, CurlCommand(baseUrl, event))
+				m.status = "copied curl command to clipboard"
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func resend(client *http.Client, baseUrl string, event proxy.ExchangeEvent) string {
+	status, elapsed, err := Resend(client, baseUrl, event)
+	if err != nil {
+		return fmt.Sprintf("failed to re-send %s %s: %v", event.Method, event.Path, err)
+	}
+	return fmt.Sprintf("re-sent %s %s [status: %d] in %v", event.Method, event.Path, status, elapsed.Round(time.Millisecond))
+}
+
+// readKeys sends each key pressed to keys. An escape sequence, such as
+// for an arrow key, is received in a single read.
+func readKeys(in io.Reader, keys chan<- key) {
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		if k := parseKey(buf[:n]); k != keyNone {
+			keys <- k
+		}
+	}
+}
+
+// draw writes the lines to the screen, replacing its content.
+func draw(out *bufio.Writer, lines []string) {
+	_, _ = out.WriteString("\033[H")
+	for i, line := range lines {
+		_, _ = out.WriteString(line)
+		// clear the remainder of the line
+		_, _ = out.WriteString("\033[K")
+		if i < len(lines)-1 {
+			_, _ = out.WriteString("\r\n")
+		}
+	}
+	_ = out.Flush()
+}
+
+// copyToClipboard sets the clipboard using the OSC 52 escape sequence,
+// which is supported by most terminals, including over SSH.
+func copyToClipboard(out *bufio.Writer, text string) {
+	_, _ = out.WriteString("\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a")
+	_ = out.Flush()
+}
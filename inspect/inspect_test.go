@@ -0,0 +1,181 @@
+package inspect
+
+import (
+	"gatehill.io/imposter/proxy"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCurlCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		event proxy.ExchangeEvent
+		want  string
+	}{
+		{
+			name:  "get",
+			event: proxy.ExchangeEvent{Method: http.MethodGet, Path: "/orders", Query: "page=2&size=10"},
+			want:  "curl 'http://localhost:8080/orders?page=2&size=10'",
+		},
+		{
+			name: "post with headers and body",
+			event: proxy.ExchangeEvent{
+				Method: http.MethodPost,
+				Path:   "/orders",
+				RequestHeaders: http.Header{
+					"Content-Type":   {"application/json"},
+					"Content-Length": {"14"},
+					"X-Note":         {"it's"},
+				},
+				RequestBody: []byte(`{"id":"o'1"}`),
+			},
+			want: `curl -X POST -H 'Content-Type: application/json' -H 'X-Note: it'\''s' --data-binary '{"id":"o'\''1"}' http://localhost:8080/orders`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CurlCommand("http://localhost:8080/", tt.event); got != tt.want {
+				t.Errorf("CurlCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResend(t *testing.T) {
+	var received *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		b := new(strings.Builder)
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		b.Write(buf[:n])
+		body = b.String()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	event := proxy.ExchangeEvent{
+		Method:         http.MethodPut,
+		Path:           "/orders/1",
+		Query:          "dryRun=true",
+		RequestHeaders: http.Header{"X-Test": {"value"}, "Content-Length": {"999"}},
+		RequestBody:    []byte("payload"),
+	}
+	status, _, err := Resend(server.Client(), server.URL, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusCreated {
+		t.Errorf("status = %d, want %d", status, http.StatusCreated)
+	}
+	if received.Method != http.MethodPut || received.URL.RequestURI() != "/orders/1?dryRun=true" {
+		t.Errorf("received %s %s", received.Method, received.URL.RequestURI())
+	}
+	if received.Header.Get("X-Test") != "value" || body != "payload" {
+		t.Errorf("received header = %q, body = %q", received.Header.Get("X-Test"), body)
+	}
+}
+
+func TestEventStream_Read(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != proxy.ExchangeEventsPath {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(": comment\n\ndata: {\"method\":\"GET\",\"path\":\"/a\",\"statusCode\":200}\n\ndata: {\"method\":\"POST\",\"path\":\"/b\",\"statusCode\":201}\n\n"))
+	}))
+	defer server.Close()
+
+	stream, err := SubscribeProxy(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan proxy.ExchangeEvent, 10)
+	_ = stream.Read(events)
+	close(events)
+	var paths []string
+	for event := range events {
+		paths = append(paths, event.Method+" "+event.Path)
+	}
+	if strings.Join(paths, ",") != "GET /a,POST /b" {
+		t.Errorf("events = %v", paths)
+	}
+
+	if _, err := SubscribeProxy(server.URL+"/other", ""); err == nil {
+		t.Errorf("expected error subscribing to endpoint without events")
+	}
+}
+
+func Test_model_navigation(t *testing.T) {
+	m := newModel("test")
+	for _, path := range []string{"/a", "/b", "/c"} {
+		m.add(proxy.ExchangeEvent{Time: time.Now(), Method: http.MethodGet, Path: path, StatusCode: 200})
+	}
+	if event, _ := m.current(); event.Path != "/c" {
+		t.Fatalf("selection should follow the latest exchange, got %s", event.Path)
+	}
+
+	m.handle(parseKey([]byte("\033[A")), 10)
+	m.add(proxy.ExchangeEvent{Method: http.MethodGet, Path: "/d"})
+	if event, _ := m.current(); event.Path != "/b" {
+		t.Errorf("selection should stay on /b after moving up, got %s", event.Path)
+	}
+
+	m.handle(keyEnter, 10)
+	if m.view != detailView {
+		t.Fatalf("enter should show the detail view")
+	}
+	if got := m.handle(keyResend, 10); got != actionResend {
+		t.Errorf("handle(keyResend) = %v, want %v", got, actionResend)
+	}
+	m.handle(keyBack, 10)
+	if m.view != listView {
+		t.Errorf("back should show the list view")
+	}
+	if got := m.handle(parseKey([]byte("q")), 10); got != actionQuit {
+		t.Errorf("handle(q) = %v, want %v", got, actionQuit)
+	}
+}
+
+func Test_model_render(t *testing.T) {
+	m := newModel("Imposter inspector")
+	m.add(proxy.ExchangeEvent{
+		Time:            time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Method:          http.MethodPost,
+		Path:            "/orders",
+		StatusCode:      201,
+		DurationMillis:  12.34,
+		Url:             "https://api.example.com/orders",
+		RequestHeaders:  http.Header{"Content-Type": {"application/json"}},
+		RequestBody:     []byte(`{"id":1}`),
+		ResponseHeaders: http.Header{},
+	})
+
+	lines := m.render(60, 10)
+	if len(lines) != 10 {
+		t.Fatalf("rendered %d lines, want 10", len(lines))
+	}
+	screen := stripStyles(strings.Join(lines, "\n"))
+	if !strings.Contains(screen, "10:00:00  POST        201     12.3ms  /orders") {
+		t.Errorf("list view missing exchange:\n%s", screen)
+	}
+
+	m.handle(keyEnter, 10)
+	screen = stripStyles(strings.Join(m.render(60, 20), "\n"))
+	for _, want := range []string{"Upstream: https://api.example.com/orders", "Content-Type: application/json", `  "id": 1`, "(none)", "(empty)"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("detail view missing %q:\n%s", want, screen)
+		}
+	}
+}
+
+func stripStyles(line string) string {
+	for _, style := range []string{styleReset, styleInverse, styleBold} {
+		line = strings.ReplaceAll(line, style, "")
+	}
+	return line
+}
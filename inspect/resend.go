@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"gatehill.io/imposter/proxy"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// skipResendHeaders are set by the HTTP client, rather than copied
+// from the original request.
+var skipResendHeaders = []string{
+	"Accept-Encoding",
+	"Connection",
+	"Content-Length",
+	"Host",
+	"Keep-Alive",
+	"Te",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// requestUrl returns the URL of the request at the mock or proxy.
+func requestUrl(baseUrl string, event proxy.ExchangeEvent) string {
+	u := strings.TrimSuffix(baseUrl, "/") + event.Path
+	if event.Query != "" {
+		u += "?" + event.Query
+	}
+	return u
+}
+
+// CurlCommand returns a curl command that sends the request to the mock
+// or proxy at the base URL.
+func CurlCommand(baseUrl string, event proxy.ExchangeEvent) string {
+	args := []string{"curl"}
+	if event.Method != http.MethodGet || len(event.RequestBody) > 0 {
+		args = append(args, "-X", event.Method)
+	}
+	var names []string
+	for name := range event.RequestHeaders {
+		if !shouldSkipHeader(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range event.RequestHeaders[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if len(event.RequestBody) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(event.RequestBody)))
+	}
+	args = append(args, shellQuote(requestUrl(baseUrl, event)))
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes the value for a POSIX shell, if it contains
+// characters other than those that are always safe.
+func shellQuote(value string) string {
+	safe := value != ""
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./:=@%+,", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func shouldSkipHeader(name string) bool {
+	for _, skip := range skipResendHeaders {
+		if strings.EqualFold(name, skip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resend sends the request again to the mock or proxy at the base URL,
+// returning the status of the response and the time taken.
+func Resend(client *http.Client, baseUrl string, event proxy.ExchangeEvent) (int, time.Duration, error) {
+	req, err := http.NewRequest(event.Method, requestUrl(baseUrl, event), bytes.NewReader(event.RequestBody))
+	if err != nil {
+		return 0, 0, err
+	}
+	for name, values := range event.RequestHeaders {
+		if shouldSkipHeader(name) {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, time.Since(start), nil
+}
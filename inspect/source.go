@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/accesslog"
+	"gatehill.io/imposter/proxy"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventStream is a connection to the exchange events endpoint of a proxy.
+type EventStream struct {
+	body io.ReadCloser
+}
+
+// SubscribeProxy connects to the exchange events endpoint of the proxy
+// at the base URL, such as http://localhost:8080, presenting the control
+// token, if not empty.
+func SubscribeProxy(baseUrl string, controlToken string) (*EventStream, error) {
+	eventsUrl := strings.TrimSuffix(baseUrl, "/") + proxy.ExchangeEventsPath
+	req, err := http.NewRequest(http.MethodGet, eventsUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if controlToken != "" {
+		req.Header.Set("Authorization", "Bearer "+controlToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy at %s: %v", baseUrl, err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("proxy refused access to %s [status: %d] - provide the proxy's control token", eventsUrl, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%s does not stream exchanges [status: %d] - to inspect a mock, provide its log file", eventsUrl, resp.StatusCode)
+	}
+	return &EventStream{body: resp.Body}, nil
+}
+
+// Read sends the exchanges received from the proxy to events, until
+// the connection is closed.
+func (s *EventStream) Read(events chan<- proxy.ExchangeEvent) error {
+	defer s.body.Close()
+	reader := bufio.NewReader(s.body)
+	for {
+		line, err := reader.ReadString('\n')
+		if data, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "data: "); ok {
+			var event proxy.ExchangeEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return fmt.Errorf("failed to parse exchange event: %v", err)
+			}
+			events <- event
+		}
+		if err == io.EOF {
+			return fmt.Errorf("proxy closed the connection")
+		} else if err != nil {
+			return fmt.Errorf("failed to read exchange events: %v", err)
+		}
+	}
+}
+
+// FollowLog sends the requests in the engine log file to events, as they
+// are written. The engine must log a request summary for each request,
+// which includes the method, path and status, but not headers or bodies.
+func FollowLog(path string, events chan<- proxy.ExchangeEvent) error {
+	return accesslog.Follow(path, func(line string) {
+		if entry, ok := accesslog.Parse(line); ok {
+			events <- buildLogEvent(entry)
+		}
+	})
+}
+
+func buildLogEvent(entry *accesslog.Entry) proxy.ExchangeEvent {
+	path, query, _ := strings.Cut(entry.Path, "?")
+	return proxy.ExchangeEvent{
+		Time:           time.Now(),
+		Method:         entry.Method,
+		Path:           path,
+		Query:          query,
+		StatusCode:     entry.Status,
+		DurationMillis: entry.Duration,
+	}
+}
@@ -0,0 +1,349 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/proxy"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxEvents is the number of exchanges held, after which the
+// oldest are discarded.
+const maxEvents = 1000
+
+const (
+	styleReset   = "\033[0m"
+	styleInverse = "\033[7m"
+	styleBold    = "\033[1m"
+)
+
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEnter
+	keyBack
+	keyQuit
+	keyResend
+	keyCopy
+)
+
+// parseKey returns the key for the bytes read from the terminal.
+func parseKey(input []byte) key {
+	switch string(input) {
+	case "\033[A", "\033OA", "k":
+		return keyUp
+	case "\033[B", "\033OB", "j":
+		return keyDown
+	case "\033[5~":
+		return keyPageUp
+	case "\033[6~", " ":
+		return keyPageDown
+	case "\r", "\n":
+		return keyEnter
+	case "\033", "\x7f", "b":
+		return keyBack
+	case "q", "\x03":
+		return keyQuit
+	case "r":
+		return keyResend
+	case "c":
+		return keyCopy
+	}
+	return keyNone
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	actionQuit
+	actionResend
+	actionCopy
+)
+
+type view int
+
+const (
+	listView view = iota
+	detailView
+)
+
+// model is the state of the inspector.
+type model struct {
+	title    string
+	events   []proxy.ExchangeEvent
+	selected int
+	view     view
+	scroll   int
+	status   string
+
+	// following is true if the selection moves to each new exchange
+	following bool
+}
+
+func newModel(title string) *model {
+	return &model{title: title, selected: -1, following: true}
+}
+
+func (m *model) add(event proxy.ExchangeEvent) {
+	m.events = append(m.events, event)
+	if len(m.events) > maxEvents {
+		m.events = m.events[1:]
+		if m.selected > 0 && !m.following {
+			m.selected--
+		}
+	}
+	if m.following {
+		m.selected = len(m.events) - 1
+	}
+}
+
+// current returns the selected exchange, if any.
+func (m *model) current() (proxy.ExchangeEvent, bool) {
+	if m.selected < 0 || m.selected >= len(m.events) {
+		return proxy.ExchangeEvent{}, false
+	}
+	return m.events[m.selected], true
+}
+
+// handle updates the model for the key, returning the action for the
+// caller to perform. The page size is the number of rows visible.
+func (m *model) handle(k key, pageSize int) action {
+	switch k {
+	case keyQuit:
+		return actionQuit
+	case keyResend:
+		if _, ok := m.current(); ok {
+			return actionResend
+		}
+	case keyCopy:
+		if _, ok := m.current(); ok {
+			return actionCopy
+		}
+	case keyEnter:
+		if _, ok := m.current(); ok && m.view == listView {
+			m.view = detailView
+			m.scroll = 0
+		}
+	case keyBack:
+		m.view = listView
+	case keyUp:
+		m.move(-1)
+	case keyDown:
+		m.move(1)
+	case keyPageUp:
+		m.move(-pageSize)
+	case keyPageDown:
+		m.move(pageSize)
+	}
+	return actionNone
+}
+
+// move scrolls the detail view, or moves the selection in the list.
+func (m *model) move(delta int) {
+	if m.view == detailView {
+		m.scroll = max(0, m.scroll+delta)
+		return
+	}
+	if len(m.events) == 0 {
+		return
+	}
+	m.selected = min(max(0, m.selected+delta), len(m.events)-1)
+	m.following = m.selected == len(m.events)-1
+}
+
+// render returns the lines of the screen, for a terminal of the
+// given size.
+func (m *model) render(width int, height int) []string {
+	var header string
+	var body []string
+	var footer string
+	bodyHeight := max(1, height-2)
+	if m.view == detailView {
+		event, _ := m.current()
+		header = fmt.Sprintf("%s %s", event.Method, pathWithQuery(event))
+		for _, line := range m.renderDetail(event, bodyHeight) {
+			body = append(body, truncate(line, width))
+		}
+		footer = "↑/↓ scroll  esc back  r re-send  c copy as curl  q quit"
+	} else {
+		header = fmt.Sprintf("%s - %d request(s)", m.title, len(m.events))
+		body = m.renderList(width, bodyHeight)
+		footer = "↑/↓ select  enter details  r re-send  c copy as curl  q quit"
+	}
+	if m.status != "" {
+		footer = m.status
+	}
+
+	lines := []string{styleInverse + pad(header, width) + styleReset}
+	for i := 0; i < bodyHeight; i++ {
+		if i < len(body) {
+			lines = append(lines, body[i])
+		} else {
+			lines = append(lines, "")
+		}
+	}
+	return append(lines, styleBold+truncate(footer, width)+styleReset)
+}
+
+func (m *model) renderList(width int, height int) []string {
+	lines := []string{truncate(fmt.Sprintf("%-8s  %-7s  %6s  %9s  %s", "TIME", "METHOD", "STATUS", "LATENCY", "PATH"), width)}
+	rows := max(1, height-1)
+
+	// keep the selection visible, showing the latest exchanges by default
+	first := max(0, len(m.events)-rows)
+	if m.selected >= 0 && m.selected < first {
+		first = m.selected
+	}
+	for i := first; i < len(m.events) && i < first+rows; i++ {
+		event := m.events[i]
+		line := fmt.Sprintf("%-8s  %-7s  %6s  %9s  %s", event.Time.Format("15:04:05"), event.Method, formatStatus(event.StatusCode), formatLatency(event.DurationMillis), pathWithQuery(event))
+		if i == m.selected {
+			line = styleInverse + pad(line, width) + styleReset
+		} else {
+			line = truncate(line, width)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m *model) renderDetail(event proxy.ExchangeEvent, height int) []string {
+	lines := []string{
+		fmt.Sprintf("Status: %s   Latency: %s   Time: %s", formatStatus(event.StatusCode), formatLatency(event.DurationMillis), event.Time.Format("2006-01-02 15:04:05")),
+	}
+	if event.Url != "" {
+		lines = append(lines, "Upstream: "+event.Url)
+	}
+	if event.TraceId != "" {
+		lines = append(lines, "Trace ID: "+event.TraceId)
+	}
+	if event.Url == "" {
+		// read from the engine log, rather than the proxy
+		lines = append(lines, "", "Headers and bodies are not available for requests read from the engine log.")
+	} else {
+		lines = append(lines, "", "Request headers:")
+		lines = append(lines, formatHeaders(event.RequestHeaders)...)
+		lines = append(lines, "", "Request body:")
+		lines = append(lines, formatBody(event.RequestBody)...)
+		lines = append(lines, "", "Response headers:")
+		lines = append(lines, formatHeaders(event.ResponseHeaders)...)
+		lines = append(lines, "", "Response body:")
+		lines = append(lines, formatBody(event.ResponseBody)...)
+	}
+
+	// prevent scrolling beyond the end
+	m.scroll = min(m.scroll, max(0, len(lines)-height))
+	return lines[m.scroll:]
+}
+
+func formatHeaders(headers http.Header) []string {
+	if len(headers) == 0 {
+		return []string{"  (none)"}
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		for _, value := range headers[name] {
+			lines = append(lines, fmt.Sprintf("  %s: %s", name, value))
+		}
+	}
+	return lines
+}
+
+// formatBody returns the lines of the body, indenting JSON, or a
+// description of the body if it is binary.
+func formatBody(body []byte) []string {
+	if len(body) == 0 {
+		return []string{"  (empty)"}
+	}
+	if !utf8.Valid(body) {
+		return []string{fmt.Sprintf("  (%d bytes of binary data)", len(body))}
+	}
+	var indented bytes.Buffer
+	if json.Indent(&indented, body, "", "  ") == nil {
+		body = indented.Bytes()
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		lines = append(lines, "  "+strings.ReplaceAll(strings.TrimRight(line, "\r"), "\t", "  "))
+	}
+	return lines
+}
+
+func pathWithQuery(event proxy.ExchangeEvent) string {
+	if event.Query == "" {
+		return event.Path
+	}
+	return event.Path + "?" + event.Query
+}
+
+func formatStatus(status int) string {
+	if status <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(status)
+}
+
+func formatLatency(millis float64) string {
+	if millis < 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(millis, 'f', 1, 64) + "ms"
+}
+
+// truncate shortens the line to the width, in runes, replacing
+// control characters that would disturb the screen.
+func truncate(line string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		if n >= width {
+			break
+		}
+		if r < ' ' || r == 0x7f {
+			r = ' '
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
+
+func pad(line string, width int) string {
+	line = truncate(line, width)
+	if n := utf8.RuneCountInString(line); n < width {
+		line += strings.Repeat(" ", width-n)
+	}
+	return line
+}
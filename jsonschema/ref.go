@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// LocalResolver resolves refs to locations within the given document,
+// such as '#/components/schemas/Pet'. Refs to other documents are not
+// resolved.
+func LocalResolver(document interface{}) Resolver {
+	return func(ref string) interface{} {
+		pointer, found := strings.CutPrefix(ref, "#")
+		if !found {
+			logger.Debugf("unable to resolve external ref: %s", ref)
+			return nil
+		}
+		return ResolvePointer(document, pointer)
+	}
+}
+
+// ResolvePointer returns the value at the JSON pointer within the
+// document, or nil if it does not exist.
+func ResolvePointer(document interface{}, pointer string) interface{} {
+	if unescaped, err := url.PathUnescape(pointer); err == nil {
+		pointer = unescaped
+	}
+	node := document
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(n) {
+				return nil
+			}
+			node = n[index]
+		default:
+			return nil
+		}
+	}
+	return node
+}
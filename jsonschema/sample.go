@@ -0,0 +1,230 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"gatehill.io/imposter/logging"
+	"sort"
+	"strings"
+)
+
+var logger = logging.GetLogger()
+
+// Resolver returns the schema referenced by a $ref, or nil
+// if it cannot be resolved.
+type Resolver func(ref string) interface{}
+
+// Sample generates an example value for a JSON schema, as decoded by
+// encoding/json. An example, default, const or enum value declared
+// in the schema is preferred to a generated value. Generated values for
+// properties with common names, such as email, are realistic.
+func Sample(schema interface{}, resolver Resolver) interface{} {
+	s := &sampler{resolver: resolver, visiting: make(map[string]bool)}
+	return s.sample(schema, "")
+}
+
+type sampler struct {
+	resolver Resolver
+
+	// visiting holds the refs being sampled, so recursive
+	// schemas do not repeat indefinitely.
+	visiting map[string]bool
+}
+
+// sample generates a value for the schema. If the schema is that of
+// a property, name is the property name, which is used to generate
+// realistic values for common properties, such as names and emails.
+func (s *sampler) sample(schema interface{}, name string) interface{} {
+	node, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if ref, ok := node["$ref"].(string); ok {
+		if s.resolver == nil || s.visiting[ref] {
+			return nil
+		}
+		s.visiting[ref] = true
+		defer delete(s.visiting, ref)
+		return s.sample(s.resolver(ref), name)
+	}
+
+	if example, found := node["example"]; found {
+		return example
+	}
+	if examples, ok := node["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	for _, key := range []string{"default", "const"} {
+		if value, found := node[key]; found {
+			return value
+		}
+	}
+	if enum, ok := node["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if allOf, ok := node["allOf"].([]interface{}); ok {
+		merged := make(map[string]interface{})
+		for _, subschema := range allOf {
+			if object, ok := s.sample(subschema, name).(map[string]interface{}); ok {
+				for name, value := range object {
+					merged[name] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives, ok := node[key].([]interface{}); ok && len(alternatives) > 0 {
+			return s.sample(alternatives[0], name)
+		}
+	}
+
+	switch schemaType(node) {
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := node["properties"].(map[string]interface{})
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, property := range names {
+			if value := s.sample(properties[property], property); value != nil {
+				object[property] = value
+			}
+		}
+		return object
+	case "array":
+		if item := s.sample(node["items"], name); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "string":
+		return sampleString(node["format"], name)
+	case "integer":
+		if isIdentifier(name) {
+			return 1
+		}
+		return 0
+	case "number":
+		return json.Number("0.0")
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// schemaType returns the type of the schema, or the first non-null type
+// if it has several. If the type is not declared, it is inferred.
+func schemaType(node map[string]interface{}) string {
+	switch t := node["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if _, found := node["properties"]; found {
+		return "object"
+	}
+	if _, found := node["items"]; found {
+		return "array"
+	}
+	return ""
+}
+
+func sampleString(format interface{}, name string) string {
+	switch format {
+	case "date-time":
+		return "2000-01-01T00:00:00Z"
+	case "date":
+		return "2000-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	default:
+		return sampleNamedString(name)
+	}
+}
+
+// namedStrings are realistic values for properties whose names contain
+// the key, in order of precedence.
+var namedStrings = []struct {
+	key   string
+	value string
+}{
+	{"email", "jane.doe@example.com"},
+	{"firstname", "Jane"},
+	{"lastname", "Doe"},
+	{"surname", "Doe"},
+	{"username", "jdoe"},
+	{"fullname", "Jane Doe"},
+	{"phone", "+1-555-0100"},
+	{"street", "1 Main Street"},
+	{"address", "1 Main Street"},
+	{"city", "Springfield"},
+	{"country", "US"},
+	{"postcode", "12345"},
+	{"zip", "12345"},
+	{"currency", "USD"},
+	{"url", "https://example.com"},
+	{"uri", "https://example.com"},
+	{"description", "A description"},
+	{"title", "Title"},
+	{"status", "active"},
+	{"name", "Jane Doe"},
+}
+
+// sampleNamedString returns a realistic value for a string property,
+// based on its name, or a placeholder if the name is not recognised.
+func sampleNamedString(name string) string {
+	normalised := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	if normalised == "" {
+		return "string"
+	}
+	if isIdentifier(name) {
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	}
+	for _, candidate := range namedStrings {
+		if strings.Contains(normalised, candidate.key) {
+			return candidate.value
+		}
+	}
+	return "string"
+}
+
+// isIdentifier returns true if the property name denotes an identifier,
+// such as 'id' or 'customerId'.
+func isIdentifier(name string) bool {
+	return name == "id" || name == "ID" || strings.HasSuffix(name, "Id") || strings.HasSuffix(strings.ToLower(name), "_id")
+}
@@ -0,0 +1,53 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	document := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+  "definitions": {
+    "Node": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "children": {"type": "array", "items": {"$ref": "#/definitions/Node"}}
+      }
+    }
+  }
+}`), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "example preferred", schema: `{"type": "string", "example": "fido"}`, want: `"fido"`},
+		{name: "enum", schema: `{"type": "string", "enum": ["available", "sold"]}`, want: `"available"`},
+		{name: "format", schema: `{"type": "string", "format": "date-time"}`, want: `"2000-01-01T00:00:00Z"`},
+		{name: "nullable type", schema: `{"type": ["null", "integer"]}`, want: `0`},
+		{name: "allOf", schema: `{"allOf": [{"properties": {"a": {"type": "boolean"}}}, {"properties": {"b": {"type": "number"}}}]}`, want: `{"a":false,"b":0.0}`},
+		{name: "recursive ref", schema: `{"$ref": "#/definitions/Node"}`, want: `{"children":[],"name":"Jane Doe"}`},
+		{name: "named properties", schema: `{"properties": {"customerId": {"type": "integer"}, "contact_email": {"type": "string"}, "city": {"type": "string"}, "notes": {"type": "string"}}}`, want: `{"city":"Springfield","contact_email":"jane.doe@example.com","customerId":1,"notes":"string"}`},
+		{name: "unresolved ref", schema: `{"$ref": "other.json#/Pet"}`, want: `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema interface{}
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(Sample(schema, LocalResolver(document)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Sample() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Validate checks a value, as decoded by encoding/json, against a JSON
+// schema. It returns a description of each violation, prefixed by the
+// location of the offending value, such as '$.items[0].id'. OpenAPI
+// extensions, such as nullable, are supported. Unresolved refs and
+// unsupported keywords are ignored.
+func Validate(schema interface{}, value interface{}, resolver Resolver) []string {
+	v := &validator{resolver: resolver}
+	v.validate(schema, value, "$", map[string]bool{})
+	return v.violations
+}
+
+type validator struct {
+	resolver   Resolver
+	violations []string
+}
+
+func (v *validator) fail(path string, format string, args ...interface{}) {
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate checks the value at path against the schema. Refs being
+// resolved are held by resolving, so recursive schemas terminate.
+func (v *validator) validate(schema interface{}, value interface{}, path string, resolving map[string]bool) {
+	node, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if ref, ok := node["$ref"].(string); ok {
+		if v.resolver == nil || resolving[ref] {
+			return
+		}
+		resolving[ref] = true
+		defer delete(resolving, ref)
+		v.validate(v.resolver(ref), value, path, resolving)
+		return
+	}
+
+	if value == nil && node["nullable"] == true {
+		return
+	}
+	if !v.checkType(node, value, path) {
+		return
+	}
+	if enum, ok := node["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		v.fail(path, "%s is not one of %s", encode(value), encode(enum))
+	}
+	if constant, found := node["const"]; found && !equalValues(constant, value) {
+		v.fail(path, "%s is not %s", encode(value), encode(constant))
+	}
+
+	if allOf, ok := node["allOf"].([]interface{}); ok {
+		for _, subschema := range allOf {
+			v.validate(subschema, value, path, resolving)
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]interface{}); ok && v.countMatches(anyOf, value, path, resolving) == 0 {
+		v.fail(path, "does not match any of the anyOf schemas")
+	}
+	if oneOf, ok := node["oneOf"].([]interface{}); ok {
+		if matches := v.countMatches(oneOf, value, path, resolving); matches != 1 {
+			v.fail(path, "matches %d of the oneOf schemas, rather than exactly one", matches)
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(node, typed, path, resolving)
+	case []interface{}:
+		v.validateArray(node, typed, path, resolving)
+	case string:
+		v.validateString(node, typed, path)
+	default:
+		if number, ok := toFloat(value); ok {
+			v.validateNumber(node, number, path)
+		}
+	}
+}
+
+// checkType returns false, having recorded a violation, if the value
+// is not of the type declared by the schema.
+func (v *validator) checkType(node map[string]interface{}, value interface{}, path string) bool {
+	var types []string
+	switch t := node["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return true
+	}
+	actual := valueType(value)
+	for _, expected := range types {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	v.fail(path, "expected %s but was %s", strings.Join(types, " or "), actual)
+	return false
+}
+
+func (v *validator) countMatches(schemas []interface{}, value interface{}, path string, resolving map[string]bool) int {
+	matches := 0
+	for _, subschema := range schemas {
+		candidate := &validator{resolver: v.resolver}
+		candidate.validate(subschema, value, path, resolving)
+		if len(candidate.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func (v *validator) validateObject(node map[string]interface{}, object map[string]interface{}, path string, resolving map[string]bool) {
+	if required, ok := node["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, found := object[fmt.Sprintf("%v", name)]; !found {
+				v.fail(path, "missing required property '%v'", name)
+			}
+		}
+	}
+	properties, _ := node["properties"].(map[string]interface{})
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, found := properties[name]; found {
+			v.validate(property, object[name], path+"."+name, resolving)
+			continue
+		}
+		switch additional := node["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path, "unexpected property '%s'", name)
+			}
+		case map[string]interface{}:
+			v.validate(additional, object[name], path+"."+name, resolving)
+		}
+	}
+}
+
+func (v *validator) validateArray(node map[string]interface{}, array []interface{}, path string, resolving map[string]bool) {
+	if min, ok := toFloat(node["minItems"]); ok && float64(len(array)) < min {
+		v.fail(path, "expected at least %v items but was %d", min, len(array))
+	}
+	if max, ok := toFloat(node["maxItems"]); ok && float64(len(array)) > max {
+		v.fail(path, "expected at most %v items but was %d", max, len(array))
+	}
+	if items, ok := node["items"]; ok {
+		for i, item := range array {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), resolving)
+		}
+	}
+}
+
+func (v *validator) validateString(node map[string]interface{}, s string, path string) {
+	length := float64(len([]rune(s)))
+	if min, ok := toFloat(node["minLength"]); ok && length < min {
+		v.fail(path, "expected at least %v characters but was %v", min, length)
+	}
+	if max, ok := toFloat(node["maxLength"]); ok && length > max {
+		v.fail(path, "expected at most %v characters but was %v", max, length)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err != nil {
+			logger.Debugf("ignoring invalid pattern %s: %v", pattern, err)
+		} else if !re.MatchString(s) {
+			v.fail(path, "'%s' does not match pattern %s", s, pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(node map[string]interface{}, number float64, path string) {
+	if min, ok := toFloat(node["minimum"]); ok {
+		if exclusive, _ := node["exclusiveMinimum"].(bool); exclusive && number <= min {
+			v.fail(path, "expected greater than %v but was %v", min, number)
+		} else if number < min {
+			v.fail(path, "expected at least %v but was %v", min, number)
+		}
+	}
+	if max, ok := toFloat(node["maximum"]); ok {
+		if exclusive, _ := node["exclusiveMaximum"].(bool); exclusive && number >= max {
+			v.fail(path, "expected less than %v but was %v", max, number)
+		} else if number > max {
+			v.fail(path, "expected at most %v but was %v", max, number)
+		}
+	}
+}
+
+// valueType returns the JSON schema type of the value.
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if number, ok := toFloat(value); ok {
+		if number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func containsValue(candidates []interface{}, value interface{}) bool {
+	for _, candidate := range candidates {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares values, treating numbers of different
+// types as equal if they have the same value.
+func equalValues(a interface{}, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func encode(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,49 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{name: "valid object", schema: `{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}, "tags": {"type": "array", "items": {"type": "string"}}}}`, value: `{"id": 1, "tags": ["a"]}`},
+		{name: "wrong type", schema: `{"type": "object", "properties": {"id": {"type": "integer"}}}`, value: `{"id": "one"}`, want: []string{"$.id: expected integer but was string"}},
+		{name: "integer is a number", schema: `{"type": "number"}`, value: `3`},
+		{name: "missing required", schema: `{"type": "object", "required": ["id", "name"]}`, value: `{"id": 1}`, want: []string{"$: missing required property 'name'"}},
+		{name: "additional properties", schema: `{"type": "object", "properties": {"id": {}}, "additionalProperties": false}`, value: `{"id": 1, "extra": true}`, want: []string{"$: unexpected property 'extra'"}},
+		{name: "array items", schema: `{"type": "array", "items": {"type": "string"}, "maxItems": 1}`, value: `["a", 2]`, want: []string{"$: expected at most 1 items but was 2", "$[1]: expected string but was integer"}},
+		{name: "enum", schema: `{"enum": ["available", "sold"]}`, value: `"lost"`, want: []string{`$: "lost" is not one of ["available","sold"]`}},
+		{name: "nullable", schema: `{"type": "string", "nullable": true}`, value: `null`},
+		{name: "type list", schema: `{"type": ["string", "null"]}`, value: `null`},
+		{name: "string constraints", schema: `{"type": "string", "minLength": 3, "pattern": "^[a-z]+$"}`, value: `"A"`, want: []string{"$: expected at least 3 characters but was 1", "$: 'A' does not match pattern ^[a-z]+$"}},
+		{name: "number range", schema: `{"type": "integer", "minimum": 1, "maximum": 10}`, value: `11`, want: []string{"$: expected at most 10 but was 11"}},
+		{name: "oneOf", schema: `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`, value: `true`, want: []string{"$: matches 0 of the oneOf schemas, rather than exactly one"}},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, value: `1`},
+		{name: "ref", schema: `{"$ref": "#/definitions/Pet"}`, value: `{"name": 1}`, want: []string{"$.name: expected string but was integer"}},
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{"definitions": {"Pet": {"properties": {"name": {"type": "string"}}}}}`), &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema, value interface{}
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if got := Validate(schema, value, LocalResolver(document)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
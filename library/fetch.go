@@ -1,68 +1,137 @@
 package library
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"github.com/spf13/viper"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-const latestBaseUrl = "https://github.com/outofcoffee/imposter/releases/latest/download/"
-const versionedBaseUrlTemplate = "https://github.com/outofcoffee/imposter/releases/download/v%v/"
+// defaultReleasesBaseUrl is the base URL from which release binaries are
+// downloaded, unless replaced by a mirror.
+const defaultReleasesBaseUrl = "https://github.com/outofcoffee/imposter/releases"
+
+// checksumSuffix is appended to the URL of a binary to get the URL of
+// its published SHA-256 checksum.
+const checksumSuffix = ".sha256"
 
 func DownloadBinary(localPath string, remoteFileName string, version string) error {
 	return DownloadBinaryWithFallback(localPath, remoteFileName, version, "")
 }
 
 func DownloadBinaryWithFallback(localPath string, remoteFileName string, version string, fallbackRemoteFileName string) error {
+	return downloadBinary(localPath, remoteFileName, version, fallbackRemoteFileName, false)
+}
+
+// DownloadVerifiedBinaryWithFallback downloads the binary, as for
+// DownloadBinaryWithFallback, then verifies it against its published
+// SHA-256 checksum. The binary is only written to localPath if the
+// checksum matches.
+func DownloadVerifiedBinaryWithFallback(localPath string, remoteFileName string, version string, fallbackRemoteFileName string) error {
+	return downloadBinary(localPath, remoteFileName, version, fallbackRemoteFileName, true)
+}
+
+func downloadBinary(localPath string, remoteFileName string, version string, fallbackRemoteFileName string, verify bool) error {
 	logger.Tracef("attempting to download %s version %s to %s", remoteFileName, version, localPath)
-	file, err := os.Create(localPath)
+	tmpFile, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("error creating file: %v: %v", localPath, err)
 	}
+	tmpPath := tmpFile.Name()
 	defer func() {
-		_ = file.Close()
-		if stat, err := os.Stat(localPath); err == nil && stat.Size() == 0 {
-			logger.Tracef("removing empty file: %s", localPath)
-			_ = os.Remove(localPath)
-		}
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
 	}()
 
-	var url string
-	var resp *http.Response
-	if version == "latest" {
-		url = latestBaseUrl + remoteFileName
+	url := buildDownloadUrl(version, remoteFileName)
+	resp, err := makeHttpRequest(url, err)
+	if err != nil {
+		return err
+	}
+
+	// fallback to versioned binary filename
+	if version != "latest" && resp.StatusCode == 404 && fallbackRemoteFileName != "" {
+		logger.Tracef("binary not found at: %v - retrying with fallback filename", url)
+		_ = resp.Body.Close()
+		url = buildDownloadUrl(version, fallbackRemoteFileName)
 		resp, err = makeHttpRequest(url, err)
 		if err != nil {
 			return err
 		}
+	}
 
-	} else {
-		versionedBaseUrl := fmt.Sprintf(versionedBaseUrlTemplate, version)
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error downloading from: %v: status code: %d", url, resp.StatusCode)
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(tmpFile, hash), resp.Body); err != nil {
+		return fmt.Errorf("error downloading from: %v: %v", url, err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("error writing file: %v: %v", localPath, err)
+	}
 
-		url := versionedBaseUrl + remoteFileName
-		resp, err = makeHttpRequest(url, err)
+	if verify {
+		expected, err := fetchChecksum(url + checksumSuffix)
 		if err != nil {
 			return err
 		}
-
-		// fallback to versioned binary filename
-		if resp.StatusCode == 404 && fallbackRemoteFileName != "" {
-			logger.Tracef("binary not found at: %v - retrying with fallback filename", url)
-			url = versionedBaseUrl + fallbackRemoteFileName
-			resp, err = makeHttpRequest(url, err)
-			if err != nil {
-				return err
-			}
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+			return fmt.Errorf("checksum mismatch for: %v: expected %s but was %s", url, expected, actual)
 		}
+		logger.Debugf("verified checksum of: %v", url)
+	}
+	if err = os.Rename(tmpPath, localPath); err != nil {
+		return fmt.Errorf("error writing file: %v: %v", localPath, err)
 	}
+	return nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("error downloading from: %v: status code: %d", url, resp.StatusCode)
+// buildDownloadUrl returns the URL of the release binary for the version.
+// The GitHub releases base URL can be replaced by a mirror with the same
+// layout, such as a remote repository in Artifactory, using the
+// 'download.baseUrl' configuration key.
+func buildDownloadUrl(version string, fileName string) string {
+	baseUrl := strings.TrimSuffix(viper.GetString("download.baseUrl"), "/")
+	if baseUrl == "" {
+		baseUrl = defaultReleasesBaseUrl
+	}
+	if version == "latest" {
+		return fmt.Sprintf("%s/latest/download/%s", baseUrl, fileName)
+	}
+	return fmt.Sprintf("%s/download/v%v/%s", baseUrl, version, fileName)
+}
+
+// fetchChecksum returns the SHA-256 checksum published at the URL, in
+// the format written by sha256sum, or as a bare checksum.
+func fetchChecksum(url string) (string, error) {
+	resp, err := makeHttpRequest(url, nil)
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(file, resp.Body)
-	return err
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("error downloading checksum from: %v: status code: %d - to skip verification, use --skip-checksum", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("error downloading checksum from: %v: %v", url, err)
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum at: %v", url)
+	}
+	checksum := strings.ToLower(fields[0])
+	if _, err = hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum at: %v: %s", url, fields[0])
+	}
+	return checksum, nil
 }
 
 func makeHttpRequest(url string, err error) (*http.Response, error) {
@@ -0,0 +1,73 @@
+package library
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_fetchChecksum(t *testing.T) {
+	const checksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bare.sha256":
+			fmt.Fprint(w, checksum)
+		case "/sha256sum.sha256":
+			fmt.Fprintf(w, "%s  imposter.jar\n", checksum)
+		case "/invalid.sha256":
+			fmt.Fprint(w, "not-a-checksum")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare checksum", path: "/bare.sha256", want: checksum},
+		{name: "sha256sum format", path: "/sha256sum.sha256", want: checksum},
+		{name: "invalid checksum", path: "/invalid.sha256", wantErr: true},
+		{name: "missing checksum", path: "/missing.sha256", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchChecksum(server.URL + tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("fetchChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildDownloadUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseUrl string
+		version string
+		want    string
+	}{
+		{name: "latest", version: "latest", want: "https://github.com/outofcoffee/imposter/releases/latest/download/imposter.jar"},
+		{name: "versioned", version: "4.2.0", want: "https://github.com/outofcoffee/imposter/releases/download/v4.2.0/imposter.jar"},
+		{name: "mirror", baseUrl: "https://artifactory.example.com/github/outofcoffee/imposter/releases/", version: "4.2.0", want: "https://artifactory.example.com/github/outofcoffee/imposter/releases/download/v4.2.0/imposter.jar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("download.baseUrl", tt.baseUrl)
+			t.Cleanup(func() {
+				viper.Set("download.baseUrl", nil)
+			})
+			if got := buildDownloadUrl(tt.version, "imposter.jar"); got != tt.want {
+				t.Errorf("buildDownloadUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
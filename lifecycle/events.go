@@ -0,0 +1,160 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/logging"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var logger = logging.GetLogger()
+
+// FormatNdjson writes each event as a JSON object on its own line.
+const FormatNdjson = "ndjson"
+
+// EventType is a stage in the lifecycle of a mock.
+type EventType string
+
+const (
+	EventPulling    EventType = "pulling"
+	EventStarting   EventType = "starting"
+	EventReady      EventType = "ready"
+	EventRestarting EventType = "restarting"
+	EventStopped    EventType = "stopped"
+	EventError      EventType = "error"
+)
+
+// Event is a machine-readable notification of a lifecycle stage, for
+// tools orchestrating the CLI, such as test frameworks and IDE plugins.
+type Event struct {
+	Type       EventType `json:"event"`
+	Time       time.Time `json:"time"`
+	EngineType string    `json:"engineType,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	Port       int       `json:"port,omitempty"`
+	Url        string    `json:"url,omitempty"`
+	Message    string    `json:"message,omitempty"`
+
+	// Code is the failure code of an error event.
+	Code failure.Code `json:"code,omitempty"`
+}
+
+var emitter = struct {
+	sync.Mutex
+	writer io.Writer
+	stdout bool
+	hooked bool
+}{}
+
+// Enable writes events in the format to the file, such as a named pipe,
+// or to stdout if file is empty. Opening a named pipe blocks until it
+// has a reader. If events are written to stdout, console output that
+// would otherwise be written there is written to stderr instead, so
+// the stream contains only events.
+func Enable(format string, file string) error {
+	if format != FormatNdjson {
+		return fmt.Errorf("unsupported event format: %s - valid values are: %s", format, FormatNdjson)
+	}
+	var writer io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open event file: %s: %v", file, err)
+		}
+		writer = f
+	}
+	enable(writer, file == "")
+	return nil
+}
+
+func enable(writer io.Writer, stdout bool) {
+	emitter.Lock()
+	defer emitter.Unlock()
+	if !emitter.hooked {
+		logger.AddHook(&errorHook{})
+		emitter.hooked = true
+	}
+	emitter.writer = writer
+	emitter.stdout = stdout
+}
+
+// Emit writes the event, if events are enabled. The time of the event
+// is set, if it is zero.
+func Emit(event Event) {
+	emitter.Lock()
+	defer emitter.Unlock()
+	if emitter.writer == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Warnf("failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+	if _, err := emitter.writer.Write(append(data, '\n')); err != nil {
+		// the reader may have gone away, which must not stop the mock
+		logger.Debugf("failed to write %s event: %v", event.Type, err)
+	}
+}
+
+// EmitError writes an error event for the failure.
+func EmitError(err error) {
+	Emit(Event{Type: EventError, Message: err.Error(), Code: failure.CodeOf(err)})
+}
+
+// ConsoleOutput returns the writer for console output, such as that of
+// the engine, which is stdout, unless events are written there.
+func ConsoleOutput() io.Writer {
+	emitter.Lock()
+	defer emitter.Unlock()
+	if emitter.stdout {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// errorHook emits an error event when a fatal entry is logged, before
+// the logger exits.
+type errorHook struct{}
+
+func (h *errorHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.FatalLevel, logrus.PanicLevel}
+}
+
+func (h *errorHook) Fire(entry *logrus.Entry) error {
+	err := failure.PendingError()
+	if err == nil {
+		if entryErr, ok := entry.Data[logrus.ErrorKey].(error); ok {
+			err = entryErr
+		} else {
+			err = errors.New(entry.Message)
+		}
+	}
+	EmitError(err)
+	return nil
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"gatehill.io/imposter/failure"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnable_invalidFormat(t *testing.T) {
+	if err := Enable("xml", ""); err == nil {
+		t.Errorf("Enable() expected error for unsupported format")
+	}
+}
+
+func TestEmit(t *testing.T) {
+	var buf bytes.Buffer
+	enable(&buf, true)
+	defer enable(nil, false)
+
+	Emit(Event{Type: EventReady, Port: 8080, Url: "http://localhost:8080"})
+	EmitError(failure.New(failure.CodeEngineExited, "mock engine exited"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %s", len(lines), buf.String())
+	}
+	var ready map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &ready); err != nil {
+		t.Fatal(err)
+	}
+	if ready["event"] != "ready" || ready["port"] != 8080.0 || ready["url"] != "http://localhost:8080" || ready["time"] == nil {
+		t.Errorf("unexpected ready event: %s", lines[0])
+	}
+	if _, found := ready["code"]; found {
+		t.Errorf("ready event should not have a code: %s", lines[0])
+	}
+	var errorEvent Event
+	if err := json.Unmarshal([]byte(lines[1]), &errorEvent); err != nil {
+		t.Fatal(err)
+	}
+	if errorEvent.Type != EventError || errorEvent.Code != failure.CodeEngineExited || errorEvent.Message != "mock engine exited" {
+		t.Errorf("unexpected error event: %s", lines[1])
+	}
+	if ConsoleOutput() != os.Stderr {
+		t.Errorf("ConsoleOutput() should be stderr when events are written to stdout")
+	}
+}
+
+func TestEnable_file(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := Enable(FormatNdjson, file); err != nil {
+		t.Fatal(err)
+	}
+	defer enable(nil, false)
+
+	Emit(Event{Type: EventStopped})
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"event":"stopped"`) {
+		t.Errorf("unexpected event file content: %s", data)
+	}
+	if ConsoleOutput() != os.Stdout {
+		t.Errorf("ConsoleOutput() should be stdout when events are written to a file")
+	}
+}
@@ -1,6 +1,9 @@
 package logging
 
-import "github.com/sirupsen/logrus"
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+)
 
 var logger = logrus.New()
 
@@ -21,3 +24,31 @@ func SetLogLevel(lvl string) {
 func GetLogger() *logrus.Logger {
 	return logger
 }
+
+// AddOutput writes log entries to the writer, such as a log file, in
+// addition to the console. Entries are written without colours.
+func AddOutput(w io.Writer) {
+	logger.AddHook(&writerHook{
+		writer:    w,
+		formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true},
+	})
+}
+
+// writerHook writes log entries at all levels to a writer.
+type writerHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *writerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *writerHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(line)
+	return err
+}
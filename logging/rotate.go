@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a writer that appends to a log file, rotating it once
+// it reaches the maximum size. Rotated files are suffixed with a number,
+// such as 'imposter.log.1' for the most recent, and those beyond the
+// maximum number of backups, or older than the maximum age, are deleted.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens the log file for appending, creating it and
+// its parent directory if they do not exist. A maxSize of zero disables
+// rotation, and a maxAge of zero keeps backups regardless of age.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %s: %v", filepath.Dir(r.path), err)
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s: %v", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %s: %v", r.path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write appends to the log file, rotating it first if the write
+// would exceed the maximum size.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current log file to the first backup, shifting
+// existing backups along, then opens a new log file.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %s: %v", r.path, err)
+	}
+	if r.maxBackups > 0 {
+		_ = os.Remove(r.backupPath(r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(r.backupPath(i), r.backupPath(i+1))
+		}
+		if err := os.Rename(r.path, r.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %s: %v", r.path, err)
+		}
+		r.removeExpiredBackups()
+	} else if err := os.Remove(r.path); err != nil {
+		return fmt.Errorf("failed to remove log file: %s: %v", r.path, err)
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) removeExpiredBackups() {
+	if r.maxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-r.maxAge)
+	for i := 1; i <= r.maxBackups; i++ {
+		info, err := os.Stat(r.backupPath(i))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			_ = os.Remove(r.backupPath(i))
+		}
+	}
+}
+
+func (r *RotatingFile) backupPath(index int) string {
+	return fmt.Sprintf("%s.%d", r.path, index)
+}
+
+// Close closes the log file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
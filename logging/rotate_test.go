@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile_Write(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "logs", "imposter.log")
+	r, err := NewRotatingFile(logPath, 10, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		logPath:        "fourth\n",
+		logPath + ".1": "third\n",
+		logPath + ".2": "second\n",
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("content of %s = %q, want %q", path, got, content)
+		}
+	}
+	if _, err := os.Stat(logPath + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no more than 2 backups")
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "imposter.log")
+	r, err := NewRotatingFile(logPath, 10, time.Hour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := r.Write([]byte("old entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("new entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(logPath+".1", expired, expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("latest\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(logPath + ".2"); !os.IsNotExist(err) {
+		t.Errorf("expected expired backup to be removed")
+	}
+	if _, err := os.Stat(logPath + ".1"); err != nil {
+		t.Errorf("expected recent backup to be kept: %v", err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"gatehill.io/imposter/logging"
 	"gatehill.io/imposter/remote/awslambda"
 	"gatehill.io/imposter/remote/cloudmocks"
+	"gatehill.io/imposter/remote/server"
 	"gatehill.io/imposter/stringutil"
 	"os"
 )
@@ -54,6 +55,7 @@ func main() {
 	// remotes
 	awslambda.Register()
 	cloudmocks.Register()
+	server.Register()
 
 	cmd.Execute()
 }
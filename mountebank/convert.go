@@ -0,0 +1,293 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountebank
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mock is the Imposter configuration converted from an imposter.
+type Mock struct {
+	// Port is the port of the imposter, or zero if mountebank chose it.
+	Port int
+
+	Resources []impostermodel.Resource
+
+	// Files are the response files, keyed by path relative to the
+	// config dir.
+	Files map[string][]byte
+}
+
+// Conversion is the Imposter configuration converted from imposters.
+type Conversion struct {
+	Mocks []Mock
+
+	// Warnings describe the features of the imposters that could not be
+	// converted, so the mocks may not behave the same as mountebank.
+	Warnings []string
+}
+
+var faults = map[string]string{
+	"CONNECTION_RESET_BY_PEER": "CloseConnection",
+	"RANDOM_DATA_THEN_CLOSE":   "CloseConnection",
+}
+
+// Convert converts the http and https imposters to Imposter mocks, with a
+// resource for each stub. Imposters using other protocols, and stubs that
+// cannot be converted, such as those proxying to another server, are
+// skipped, and unsupported predicates are ignored, with a warning for each.
+func Convert(imposters []Imposter) *Conversion {
+	conversion := &Conversion{}
+	for i, imposter := range imposters {
+		c := &converter{
+			conversion:   conversion,
+			imposterName: describeImposter(i, imposter),
+		}
+		if mock, ok := c.convertImposter(imposter); ok {
+			conversion.Mocks = append(conversion.Mocks, *mock)
+		}
+	}
+	return conversion
+}
+
+type converter struct {
+	conversion   *Conversion
+	mock         *Mock
+	defaults     *IsResponse
+	imposterName string
+	stubName     string
+}
+
+func describeImposter(index int, imposter Imposter) string {
+	if imposter.Name != "" {
+		return fmt.Sprintf("imposter '%s'", imposter.Name)
+	} else if imposter.Port != 0 {
+		return fmt.Sprintf("imposter %d", imposter.Port)
+	}
+	return fmt.Sprintf("imposter %d", index+1)
+}
+
+func (c *converter) warnf(format string, args ...interface{}) {
+	prefix := c.imposterName
+	if c.stubName != "" {
+		prefix += ": " + c.stubName
+	}
+	c.conversion.Warnings = append(c.conversion.Warnings, prefix+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convertImposter(imposter Imposter) (*Mock, bool) {
+	switch imposter.Protocol {
+	case "http":
+	case "https":
+		c.warnf("the certificate and TLS settings are not imported - configure TLS for the mock to serve HTTPS")
+	default:
+		c.warnf("skipped - the %s protocol is not supported", imposter.Protocol)
+		return nil, false
+	}
+	c.mock = &Mock{Port: imposter.Port, Files: make(map[string][]byte)}
+	c.defaults = imposter.DefaultResponse
+
+	for i, stub := range imposter.Stubs {
+		c.stubName = fmt.Sprintf("stub %d", i+1)
+		if resource, ok := c.convertStub(stub); ok {
+			c.mock.Resources = append(c.mock.Resources, resource)
+		}
+	}
+	c.stubName = ""
+	if imposter.DefaultResponse != nil {
+		c.stubName = "default response"
+		c.mock.Resources = append(c.mock.Resources, impostermodel.Resource{
+			Path:     "/*",
+			Response: c.convertIsResponse(*imposter.DefaultResponse),
+		})
+		c.stubName = ""
+	}
+	if len(c.mock.Resources) == 0 {
+		c.warnf("skipped - no stubs could be converted")
+		return nil, false
+	}
+	return c.mock, true
+}
+
+func (c *converter) convertStub(stub Stub) (impostermodel.Resource, bool) {
+	var response Response
+	if len(stub.Responses) > 0 {
+		response = stub.Responses[0]
+	}
+	if len(stub.Responses) > 1 {
+		c.warnf("only the first of %d responses is imported - Imposter does not cycle through responses", len(stub.Responses))
+	}
+	if response.Proxy != nil {
+		c.warnf("skipped - proxying to %s is not supported", response.Proxy.To)
+		return impostermodel.Resource{}, false
+	} else if response.Inject != "" {
+		c.warnf("skipped - injected responses are not supported")
+		return impostermodel.Resource{}, false
+	}
+
+	resource := impostermodel.Resource{}
+	c.applyPredicates(stub.Predicates, &resource)
+	if resource.Path == "" {
+		resource.Path = "/*"
+	}
+	resource.Response = c.convertResponse(response)
+	return resource, true
+}
+
+func (c *converter) convertResponse(response Response) *impostermodel.ResponseConfig {
+	var is IsResponse
+	if response.Is != nil {
+		is = *response.Is
+	}
+	config := c.convertIsResponse(is)
+
+	if response.Fault != "" {
+		if fail, ok := faults[response.Fault]; ok {
+			config.Fail = fail
+			if response.Fault != "CONNECTION_RESET_BY_PEER" {
+				c.warnf("fault %s is not supported - closing the connection instead", response.Fault)
+			}
+		} else {
+			c.warnf("fault %s is not supported - ignoring", response.Fault)
+		}
+	}
+
+	behaviors := response.Behaviors
+	if response.LegacyBehaviors != nil {
+		behaviors = append(behaviors, response.LegacyBehaviors)
+	}
+	for _, behavior := range behaviors {
+		for _, name := range sortedKeys(behavior) {
+			if name != "wait" {
+				c.warnf("%s behavior is not supported - ignoring", name)
+				continue
+			}
+			if wait, ok := behavior[name].(float64); ok {
+				config.Delay = &impostermodel.ResponseDelay{Exact: int(wait)}
+			} else {
+				c.warnf("wait behavior with a JavaScript function is not supported - ignoring")
+			}
+		}
+	}
+	return config
+}
+
+// convertIsResponse converts a canned response. Fields it does not set are
+// taken from the default response of the imposter, as mountebank does.
+func (c *converter) convertIsResponse(is IsResponse) *impostermodel.ResponseConfig {
+	config := &impostermodel.ResponseConfig{StatusCode: http.StatusOK}
+	defaults := IsResponse{}
+	if c.defaults != nil {
+		defaults = *c.defaults
+	}
+
+	statusCode := is.StatusCode
+	if statusCode == nil {
+		statusCode = defaults.StatusCode
+	}
+	switch s := statusCode.(type) {
+	case float64:
+		config.StatusCode = int(s)
+	case string:
+		if parsed, err := strconv.Atoi(s); err == nil {
+			config.StatusCode = parsed
+		} else {
+			c.warnf("invalid status code %s - using %d", s, http.StatusOK)
+		}
+	}
+
+	headers := make(map[string]string)
+	for _, source := range []map[string]interface{}{defaults.Headers, is.Headers} {
+		for _, name := range sortedKeys(source) {
+			switch v := source[name].(type) {
+			case []interface{}:
+				var values []string
+				for _, value := range v {
+					values = append(values, fmt.Sprint(value))
+				}
+				headers[name] = strings.Join(values, ", ")
+			default:
+				headers[name] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	body, mode := is.Body, is.Mode
+	if body == nil {
+		body, mode = defaults.Body, defaults.Mode
+	}
+	switch b := body.(type) {
+	case nil:
+	case string:
+		if mode != "binary" {
+			config.StaticData = b
+			break
+		}
+		content, err := base64.StdEncoding.DecodeString(b)
+		if err != nil {
+			c.warnf("invalid binary body - the response is empty: %v", err)
+			break
+		}
+		config.StaticFile = c.addFile(content)
+	default:
+		content, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			c.warnf("invalid body - the response is empty: %v", err)
+			break
+		}
+		config.StaticData = string(content)
+		if !hasHeader(headers, "Content-Type") {
+			headers["Content-Type"] = "application/json"
+		}
+	}
+	if len(headers) > 0 {
+		config.Headers = &headers
+	}
+	return config
+}
+
+// addFile adds a binary response file to the mock, returning its name.
+func (c *converter) addFile(content []byte) string {
+	name := fmt.Sprintf("response-%d.bin", len(c.mock.Files)+1)
+	c.mock.Files[name] = content
+	return name
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
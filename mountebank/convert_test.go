@@ -0,0 +1,269 @@
+package mountebank
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"testing"
+)
+
+func parseImposter(t *testing.T, content string) Imposter {
+	var imposter Imposter
+	if err := json.Unmarshal([]byte(content), &imposter); err != nil {
+		t.Fatal(err)
+	}
+	return imposter
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name         string
+		imposter     string
+		want         string
+		wantFiles    map[string]string
+		wantWarnings []string
+	}{
+		{
+			name: "equals predicate and json body",
+			imposter: `{
+  "protocol": "http", "port": 4545,
+  "stubs": [{
+    "predicates": [{"equals": {"method": "get", "path": "/pets", "query": {"type": "dog"}, "headers": {"Accept": "application/json"}}}],
+    "responses": [{"is": {"statusCode": 200, "body": {"name": "Rex"}}}]
+  }]
+}`,
+			want: `- method: GET
+  path: /pets
+  queryParams:
+    type: dog
+  requestHeaders:
+    Accept: application/json
+  response:
+    headers:
+      Content-Type: application/json
+    staticData: |-
+      {
+        "name": "Rex"
+      }
+    statusCode: 200
+`,
+		},
+		{
+			name: "operators become expressions",
+			imposter: `{
+  "protocol": "http", "port": 4545,
+  "stubs": [{
+    "predicates": [
+      {"startsWith": {"path": "/api/"}},
+      {"contains": {"headers": {"User-Agent": "curl"}}},
+      {"not": {"exists": {"query": {"debug": true}}}},
+      {"or": [{"equals": {"method": "PUT"}}, {"equals": {"method": "PATCH"}}]}
+    ],
+    "responses": [{"is": {"statusCode": "204"}}]
+  }]
+}`,
+			want: `- allOf:
+  - expression: ${context.request.path}
+    operator: Matches
+    value: ^/api/
+  - expression: ${context.request.headers.User-Agent}
+    operator: Contains
+    value: curl
+  - expression: ${context.request.queryParams.debug}
+    operator: NotExists
+  anyOf:
+  - expression: ${context.request.method}
+    operator: EqualTo
+    value: PUT
+  - expression: ${context.request.method}
+    operator: EqualTo
+    value: PATCH
+  path: /*
+  response:
+    statusCode: 204
+`,
+		},
+		{
+			name: "body predicates",
+			imposter: `{
+  "protocol": "http", "port": 4545,
+  "stubs": [{
+    "predicates": [
+      {"equals": {"method": "POST", "body": {"item": "book", "qty": 2}}},
+      {"matches": {"body": "^[A-Z]+$"}, "jsonpath": {"selector": "$.code"}}
+    ],
+    "responses": [{"is": {"statusCode": 201}}]
+  }]
+}`,
+			want: `- method: POST
+  path: /*
+  requestBody:
+    allOf:
+    - jsonPath: $.item
+      value: book
+    - jsonPath: $.qty
+      value: "2"
+    - jsonPath: $.code
+      operator: Matches
+      value: ^[A-Z]+$
+  response:
+    statusCode: 201
+`,
+		},
+		{
+			name: "default response, behaviors and binary body",
+			imposter: `{
+  "protocol": "http", "port": 4545,
+  "defaultResponse": {"statusCode": 404, "headers": {"X-Mock": "mountebank"}},
+  "stubs": [{
+    "predicates": [{"equals": {"path": "/logo"}}],
+    "responses": [{"is": {"statusCode": 200, "_mode": "binary", "body": "aGVsbG8="}, "behaviors": [{"wait": 250}]}]
+  }]
+}`,
+			want: `- path: /logo
+  response:
+    delay:
+      exact: 250
+    headers:
+      X-Mock: mountebank
+    staticFile: response-1.bin
+    statusCode: 200
+- path: /*
+  response:
+    headers:
+      X-Mock: mountebank
+    statusCode: 404
+`,
+			wantFiles: map[string]string{"response-1.bin": "hello"},
+		},
+		{
+			name: "unsupported features",
+			imposter: `{
+  "protocol": "https", "port": 4545, "name": "orders",
+  "stubs": [
+    {
+      "predicates": [{"equals": {"path": "/orders"}}, {"matches": {"requestFrom": "^127"}}, {"inject": "function (config) { return true; }"}],
+      "responses": [{"is": {"body": "first"}, "_behaviors": {"decorate": "function () {}"}}, {"is": {"body": "second"}}]
+    },
+    {"responses": [{"proxy": {"to": "https://example.com"}}]},
+    {"responses": [{"fault": "RANDOM_DATA_THEN_CLOSE"}]}
+  ]
+}`,
+			want: `- path: /orders
+  response:
+    staticData: first
+    statusCode: 200
+- path: /*
+  response:
+    fail: CloseConnection
+    statusCode: 200
+`,
+			wantWarnings: []string{
+				"imposter 'orders': the certificate and TLS settings are not imported - configure TLS for the mock to serve HTTPS",
+				"imposter 'orders': stub 1: only the first of 2 responses is imported - Imposter does not cycle through responses",
+				"imposter 'orders': stub 1: unsupported predicate 'matches': matching on requestFrom is not supported - ignoring it, so the stub matches more requests",
+				"imposter 'orders': stub 1: unsupported predicate 'inject': JavaScript injection is not supported - ignoring it, so the stub matches more requests",
+				"imposter 'orders': stub 1: decorate behavior is not supported - ignoring",
+				"imposter 'orders': stub 2: skipped - proxying to https://example.com is not supported",
+				"imposter 'orders': stub 3: fault RANDOM_DATA_THEN_CLOSE is not supported - closing the connection instead",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion := Convert([]Imposter{parseImposter(t, tt.imposter)})
+			if len(conversion.Mocks) != 1 {
+				t.Fatalf("Convert() returned %d mocks, want 1", len(conversion.Mocks))
+			}
+			mock := conversion.Mocks[0]
+			if mock.Port != 4545 {
+				t.Errorf("Convert() port = %d, want 4545", mock.Port)
+			}
+			got, err := yaml.Marshal(mock.Resources)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Convert() resources =\n%s\nwant\n%s", got, tt.want)
+			}
+			files := make(map[string]string)
+			for name, content := range mock.Files {
+				files[name] = string(content)
+			}
+			if len(files) != 0 || tt.wantFiles != nil {
+				if !reflect.DeepEqual(files, tt.wantFiles) {
+					t.Errorf("Convert() files = %v, want %v", files, tt.wantFiles)
+				}
+			}
+			if !reflect.DeepEqual(conversion.Warnings, tt.wantWarnings) {
+				t.Errorf("Convert() warnings = %v, want %v", conversion.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestConvert_skipsUnsupportedProtocols(t *testing.T) {
+	imposters := []Imposter{
+		parseImposter(t, `{"protocol": "tcp", "port": 5555, "stubs": [{"responses": [{"is": {"data": "hello"}}]}]}`),
+		parseImposter(t, `{"protocol": "http", "port": 4545, "stubs": [{"responses": [{"is": {"body": "ok"}}]}]}`),
+	}
+	conversion := Convert(imposters)
+	if len(conversion.Mocks) != 1 || conversion.Mocks[0].Port != 4545 {
+		t.Errorf("Convert() mocks = %+v, want the imposter on port 4545", conversion.Mocks)
+	}
+	want := []string{"imposter 5555: skipped - the tcp protocol is not supported"}
+	if !reflect.DeepEqual(conversion.Warnings, want) {
+		t.Errorf("Convert() warnings = %v, want %v", conversion.Warnings, want)
+	}
+}
+
+func TestLoadImposters(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantPorts []int
+		wantErr   bool
+	}{
+		{
+			name:      "saved imposters",
+			content:   `{"imposters": [{"protocol": "http", "port": 4545}, {"protocol": "https", "port": 4546}]}`,
+			wantPorts: []int{4545, 4546},
+		},
+		{
+			name:      "single imposter",
+			content:   `{"protocol": "http", "port": 4545}`,
+			wantPorts: []int{4545},
+		},
+		{
+			name:    "EJS template",
+			content: `{"imposters": [<% include orders.ejs %>]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an imposter",
+			content: `{"foo": "bar"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "imposters.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			imposters, err := LoadImposters(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadImposters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ports []int
+			for _, imposter := range imposters {
+				ports = append(ports, imposter.Port)
+			}
+			if !reflect.DeepEqual(ports, tt.wantPorts) {
+				t.Errorf("LoadImposters() ports = %v, want %v", ports, tt.wantPorts)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountebank
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"os"
+)
+
+var logger = logging.GetLogger()
+
+// Imposter is a mountebank imposter, listening on a port.
+type Imposter struct {
+	Protocol        string      `json:"protocol"`
+	Port            int         `json:"port"`
+	Name            string      `json:"name"`
+	Stubs           []Stub      `json:"stubs"`
+	DefaultResponse *IsResponse `json:"defaultResponse"`
+
+	// these settings have no equivalent
+	Key        string `json:"key"`
+	Cert       string `json:"cert"`
+	MutualAuth bool   `json:"mutualAuth"`
+}
+
+// Stub holds the predicates a request must satisfy, and the responses
+// returned, in turn, to matching requests.
+type Stub struct {
+	Predicates []Predicate `json:"predicates"`
+	Responses  []Response  `json:"responses"`
+}
+
+// Predicate is a mountebank predicate, such as {"equals": {"path": "/"}},
+// with any parameters, such as {"caseSensitive": true}.
+type Predicate map[string]interface{}
+
+// Response is a stub response. One of Is, Proxy, Inject or Fault is set;
+// if none are, the default response is returned.
+type Response struct {
+	Is     *IsResponse `json:"is"`
+	Proxy  *Proxy      `json:"proxy"`
+	Inject string      `json:"inject"`
+	Fault  string      `json:"fault"`
+
+	// Behaviors is the list of behaviors used by mountebank 2.x, and
+	// LegacyBehaviors is the '_behaviors' object used by earlier versions.
+	Behaviors       []map[string]interface{} `json:"behaviors"`
+	LegacyBehaviors map[string]interface{}   `json:"_behaviors"`
+}
+
+// IsResponse is a canned response. The status code may be a number or a
+// string, and the body a string or a JSON value.
+type IsResponse struct {
+	StatusCode interface{}            `json:"statusCode"`
+	Headers    map[string]interface{} `json:"headers"`
+	Body       interface{}            `json:"body"`
+	Mode       string                 `json:"_mode"`
+}
+
+// Proxy is a response proxying the request to another server.
+type Proxy struct {
+	To string `json:"to"`
+}
+
+// LoadImposters reads the imposters from a JSON file holding an
+// 'imposters' array, such as the output of 'mb save', or a single
+// imposter.
+func LoadImposters(file string) ([]Imposter, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mountebank imposters: %v", err)
+	}
+	if bytes.Contains(content, []byte("<%")) {
+		return nil, fmt.Errorf("failed to parse mountebank imposters: %s: EJS templates are not supported - save the imposters from a running mountebank with 'mb save' and import the saved file", file)
+	}
+	var wrapper struct {
+		Imposters *[]Imposter `json:"imposters"`
+	}
+	if err := json.Unmarshal(content, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse mountebank imposters: %s: %v", file, err)
+	}
+	if wrapper.Imposters != nil {
+		logger.Tracef("read %d imposter(s) from: %s", len(*wrapper.Imposters), file)
+		return *wrapper.Imposters, nil
+	}
+	var imposter Imposter
+	if err := json.Unmarshal(content, &imposter); err != nil {
+		return nil, fmt.Errorf("failed to parse mountebank imposters: %s: %v", file, err)
+	}
+	if imposter.Protocol == "" {
+		return nil, fmt.Errorf("no mountebank imposters found in: %s", file)
+	}
+	return []Imposter{imposter}, nil
+}
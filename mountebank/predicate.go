@@ -0,0 +1,407 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountebank
+
+import (
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"regexp"
+	"strings"
+)
+
+// predicateOperators are the mountebank predicate operators, in the order
+// they are looked for in a predicate.
+var predicateOperators = []string{
+	"equals", "deepEquals", "contains", "startsWith", "endsWith", "matches", "exists", "not", "and", "or", "inject",
+}
+
+var negatedOperators = map[string]string{
+	"EqualTo":     "NotEqualTo",
+	"NotEqualTo":  "EqualTo",
+	"Contains":    "NotContains",
+	"NotContains": "Contains",
+	"Matches":     "NotMatches",
+	"NotMatches":  "Matches",
+	"Exists":      "NotExists",
+	"NotExists":   "Exists",
+}
+
+// condition is a test of a single field of the request, converted from
+// a predicate.
+type condition struct {
+	// field is one of method, path, query, headers, form or body
+	field string
+
+	// name is the name of the query parameter, header or form field
+	name string
+
+	jsonPath string
+	xPath    string
+
+	// operator is an Imposter operator, such as 'EqualTo'
+	operator string
+	value    string
+}
+
+// applyPredicates converts the predicates of a stub, all of which must
+// match, to the request matchers of the resource. Predicates without an
+// equivalent are ignored, with a warning, so the resource matches more
+// requests than the stub.
+func (c *converter) applyPredicates(predicates []Predicate, resource *impostermodel.Resource) {
+	for _, predicate := range predicates {
+		operator, _ := predicateOperator(predicate)
+		if operator == "or" {
+			if err := applyOr(predicate, resource); err != nil {
+				c.warnf("unsupported predicate 'or': %v - ignoring it, so the stub matches more requests", err)
+			}
+			continue
+		}
+		conditions, err := convertPredicate(predicate)
+		if err != nil {
+			c.warnf("unsupported predicate '%s': %v - ignoring it, so the stub matches more requests", operator, err)
+			continue
+		}
+		for _, cond := range conditions {
+			applyCondition(cond, resource)
+		}
+	}
+}
+
+func predicateOperator(predicate Predicate) (string, interface{}) {
+	for _, operator := range predicateOperators {
+		if operand, ok := predicate[operator]; ok {
+			return operator, operand
+		}
+	}
+	return "", nil
+}
+
+// convertPredicate converts a predicate to the conditions, all of which
+// must match, that are equivalent to it.
+func convertPredicate(predicate Predicate) ([]condition, error) {
+	operator, operand := predicateOperator(predicate)
+	if _, ok := predicate["except"]; ok {
+		return nil, fmt.Errorf("the 'except' parameter is not supported")
+	}
+	switch operator {
+	case "":
+		return nil, fmt.Errorf("no predicate operator found")
+	case "inject":
+		return nil, fmt.Errorf("JavaScript injection is not supported")
+	case "or":
+		return nil, fmt.Errorf("'or' is only supported as a top-level predicate")
+	case "and":
+		var conditions []condition
+		for _, child := range toPredicates(operand) {
+			childConditions, err := convertPredicate(child)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, childConditions...)
+		}
+		return conditions, nil
+	case "not":
+		child, ok := operand.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid 'not' predicate")
+		}
+		conditions, err := convertPredicate(child)
+		if err != nil {
+			return nil, err
+		}
+		if len(conditions) != 1 {
+			return nil, fmt.Errorf("'not' is only supported for a predicate testing a single field")
+		}
+		conditions[0].operator = negatedOperators[conditions[0].operator]
+		return conditions, nil
+	}
+	fields, ok := operand.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid '%s' predicate", operator)
+	}
+	return convertFields(operator, fields, predicate)
+}
+
+func toPredicates(operand interface{}) []Predicate {
+	items, _ := operand.([]interface{})
+	var predicates []Predicate
+	for _, item := range items {
+		if predicate, ok := item.(map[string]interface{}); ok {
+			predicates = append(predicates, predicate)
+		}
+	}
+	return predicates
+}
+
+// convertFields converts the fields tested by a predicate operator, such
+// as 'equals', to a condition for each field.
+func convertFields(operator string, fields map[string]interface{}, predicate Predicate) ([]condition, error) {
+	var conditions []condition
+	for _, field := range sortedKeys(fields) {
+		value := fields[field]
+		switch field {
+		case "method", "path":
+			if operator == "exists" {
+				continue
+			}
+			cond, err := newCondition(operator, field, value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, cond)
+		case "query", "headers", "form":
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: %v", field, value)
+			}
+			for _, name := range sortedKeys(values) {
+				cond, err := newCondition(operator, field, values[name])
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %v", field, name, err)
+				}
+				cond.name = name
+				conditions = append(conditions, cond)
+			}
+		case "body":
+			bodyConditions, err := convertBody(operator, value, predicate)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, bodyConditions...)
+		default:
+			return nil, fmt.Errorf("matching on %s is not supported", field)
+		}
+	}
+	return conditions, nil
+}
+
+// convertBody converts a test of the request body. If the predicate has
+// a 'jsonpath' or 'xpath' parameter, the value selected is tested, and
+// if the expected body is a JSON object, each of its fields is tested.
+func convertBody(operator string, value interface{}, predicate Predicate) ([]condition, error) {
+	if selector := selectorOf(predicate, "jsonpath"); selector != "" {
+		cond, err := newCondition(operator, "body", value)
+		cond.jsonPath = selector
+		return []condition{cond}, err
+	}
+	if selector := selectorOf(predicate, "xpath"); selector != "" {
+		if xpath, _ := predicate["xpath"].(map[string]interface{}); xpath["ns"] != nil {
+			return nil, fmt.Errorf("XPath namespaces are not supported")
+		}
+		cond, err := newCondition(operator, "body", value)
+		cond.xPath = selector
+		return []condition{cond}, err
+	}
+	if _, isObject := value.(map[string]interface{}); !isObject {
+		cond, err := newCondition(operator, "body", value)
+		return []condition{cond}, err
+	}
+
+	var conditions []condition
+	var flatten func(path string, value interface{}) error
+	flatten = func(path string, value interface{}) error {
+		if object, ok := value.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(object) {
+				if err := flatten(path+"."+key, object[key]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		cond, err := newCondition(operator, "body", value)
+		if err != nil {
+			return fmt.Errorf("body %s: %v", path, err)
+		}
+		cond.jsonPath = path
+		conditions = append(conditions, cond)
+		return nil
+	}
+	if err := flatten("$", value); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+func selectorOf(predicate Predicate, parameter string) string {
+	options, _ := predicate[parameter].(map[string]interface{})
+	selector, _ := options["selector"].(string)
+	return selector
+}
+
+// newCondition returns the condition for a predicate operator testing
+// a field against the expected value.
+func newCondition(operator string, field string, expected interface{}) (condition, error) {
+	cond := condition{field: field}
+	if operator == "exists" {
+		exists, ok := expected.(bool)
+		if !ok {
+			return cond, fmt.Errorf("invalid 'exists' value: %v", expected)
+		}
+		cond.operator = "NotExists"
+		if exists {
+			cond.operator = "Exists"
+		}
+		return cond, nil
+	}
+
+	var value string
+	switch v := expected.(type) {
+	case string:
+		value = v
+	case float64, bool:
+		value = fmt.Sprint(v)
+	case []interface{}:
+		return cond, fmt.Errorf("matching more than one value is not supported")
+	default:
+		return cond, fmt.Errorf("unsupported value: %v", v)
+	}
+	switch operator {
+	case "equals", "deepEquals":
+		cond.operator, cond.value = "EqualTo", value
+	case "contains":
+		cond.operator, cond.value = "Contains", value
+	case "startsWith":
+		cond.operator, cond.value = "Matches", "^"+regexp.QuoteMeta(value)
+	case "endsWith":
+		cond.operator, cond.value = "Matches", regexp.QuoteMeta(value)+"$"
+	case "matches":
+		cond.operator, cond.value = "Matches", value
+	}
+	return cond, nil
+}
+
+// applyCondition adds a condition to the request matchers of the resource.
+// Exact matches of the method, path, query parameters, headers and form
+// fields use the corresponding resource properties; other tests of these
+// fields become expressions.
+func applyCondition(cond condition, resource *impostermodel.Resource) {
+	if cond.field == "body" {
+		addBodyMatcher(cond, resource)
+		return
+	}
+	if cond.operator == "EqualTo" {
+		switch cond.field {
+		case "method":
+			if resource.Method == "" {
+				resource.Method = strings.ToUpper(cond.value)
+				return
+			}
+		case "path":
+			if resource.Path == "" {
+				resource.Path = cond.value
+				return
+			}
+		case "query":
+			setParam(&resource.QueryParams, cond.name, cond.value)
+			return
+		case "headers":
+			setParam(&resource.RequestHeaders, cond.name, cond.value)
+			return
+		case "form":
+			setParam(&resource.FormParams, cond.name, cond.value)
+			return
+		}
+	}
+	expression, _ := cond.expression()
+	resource.AllOf = append(resource.AllOf, impostermodel.MatchExpression{
+		Expression: expression,
+		Value:      cond.value,
+		Operator:   cond.operator,
+	})
+}
+
+func setParam(params **map[string]string, name string, value string) {
+	if *params == nil {
+		*params = &map[string]string{}
+	}
+	(**params)[name] = value
+}
+
+func addBodyMatcher(cond condition, resource *impostermodel.Resource) {
+	matcher := impostermodel.RequestBody{
+		JsonPath: cond.jsonPath,
+		XPath:    cond.xPath,
+		Value:    cond.value,
+		Operator: cond.operator,
+	}
+	if matcher.Operator == "EqualTo" {
+		matcher.Operator = ""
+	}
+	switch {
+	case resource.RequestBody == nil:
+		resource.RequestBody = &matcher
+	case resource.RequestBody.AllOf == nil:
+		resource.RequestBody = &impostermodel.RequestBody{AllOf: []impostermodel.RequestBody{*resource.RequestBody, matcher}}
+	default:
+		resource.RequestBody.AllOf = append(resource.RequestBody.AllOf, matcher)
+	}
+}
+
+// expression returns the Imposter expression for the field tested by
+// the condition, or false if there is none.
+func (cond condition) expression() (string, bool) {
+	switch cond.field {
+	case "method", "path":
+		return "${context.request." + cond.field + "}", true
+	case "query":
+		return "${context.request.queryParams." + cond.name + "}", true
+	case "headers":
+		return "${context.request.headers." + cond.name + "}", true
+	case "form":
+		return "${context.request.formParams." + cond.name + "}", true
+	case "body":
+		if cond.xPath != "" {
+			return "", false
+		} else if cond.jsonPath != "" {
+			return "${context.request.body:" + cond.jsonPath + "}", true
+		}
+		return "${context.request.body}", true
+	}
+	return "", false
+}
+
+// applyOr converts an 'or' predicate to expressions, one of which must
+// match. Each of its predicates must test a single field.
+func applyOr(predicate Predicate, resource *impostermodel.Resource) error {
+	if resource.AnyOf != nil {
+		return fmt.Errorf("only one 'or' predicate per stub is supported")
+	}
+	var expressions []impostermodel.MatchExpression
+	for _, child := range toPredicates(predicate["or"]) {
+		conditions, err := convertPredicate(child)
+		if err != nil {
+			return err
+		}
+		if len(conditions) != 1 {
+			return fmt.Errorf("each of its predicates must test a single field")
+		}
+		expression, ok := conditions[0].expression()
+		if !ok {
+			return fmt.Errorf("XPath cannot be used within 'or'")
+		}
+		expressions = append(expressions, impostermodel.MatchExpression{
+			Expression: expression,
+			Value:      conditions[0].value,
+			Operator:   conditions[0].operator,
+		})
+	}
+	if len(expressions) == 0 {
+		return fmt.Errorf("no predicates to match")
+	}
+	resource.AnyOf = expressions
+	return nil
+}
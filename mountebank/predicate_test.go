@@ -0,0 +1,78 @@
+package mountebank
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_convertPredicate(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate string
+		want      []condition
+		wantErr   bool
+	}{
+		{
+			name:      "equals",
+			predicate: `{"equals": {"path": "/pets", "query": {"page": 2}}}`,
+			want: []condition{
+				{field: "path", operator: "EqualTo", value: "/pets"},
+				{field: "query", name: "page", operator: "EqualTo", value: "2"},
+			},
+		},
+		{
+			name:      "starts and ends with",
+			predicate: `{"and": [{"startsWith": {"path": "/v1.0/"}}, {"endsWith": {"headers": {"Host": ".example.com"}}}]}`,
+			want: []condition{
+				{field: "path", operator: "Matches", value: `^/v1\.0/`},
+				{field: "headers", name: "Host", operator: "Matches", value: `\.example\.com$`},
+			},
+		},
+		{
+			name:      "not",
+			predicate: `{"not": {"contains": {"body": "error"}}}`,
+			want:      []condition{{field: "body", operator: "NotContains", value: "error"}},
+		},
+		{
+			name:      "xpath",
+			predicate: `{"equals": {"body": "Rex"}, "xpath": {"selector": "//pet/name"}}`,
+			want:      []condition{{field: "body", xPath: "//pet/name", operator: "EqualTo", value: "Rex"}},
+		},
+		{
+			name:      "not of several fields",
+			predicate: `{"not": {"equals": {"method": "GET", "path": "/"}}}`,
+			wantErr:   true,
+		},
+		{
+			name:      "except",
+			predicate: `{"equals": {"body": "hello"}, "except": "\\d+"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "multiple values",
+			predicate: `{"equals": {"query": {"tag": ["a", "b"]}}}`,
+			wantErr:   true,
+		},
+		{
+			name:      "nested or",
+			predicate: `{"and": [{"or": [{"equals": {"path": "/a"}}, {"equals": {"path": "/b"}}]}]}`,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var predicate Predicate
+			if err := json.Unmarshal([]byte(tt.predicate), &predicate); err != nil {
+				t.Fatal(err)
+			}
+			got, err := convertPredicate(predicate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertPredicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertPredicate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,26 +21,135 @@ import (
 	"gopkg.in/yaml.v2"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 )
 
 type OperationResponse struct {
 	Description string
 
 	// key is content type
-	Content map[string]interface{}
+	Content map[string]MediaType
+
+	// key is header name
+	Headers map[string]Header
+}
+
+type Header struct {
+	Example interface{}
+	Schema  HeaderSchema
+}
+
+type HeaderSchema struct {
+	Example interface{}
+	Default interface{}
+}
+
+// RateLimit is declared using the 'x-rate-limit' vendor extension on an
+// operation, permitting Limit requests per Window seconds.
+type RateLimit struct {
+	Limit  int
+	Window int
+}
+
+type MediaType struct {
+	// named examples, in the order in which they appear in the spec
+	Examples yaml.MapSlice
+
+	Example interface{}
+	Schema  interface{}
+}
+
+// Parameter is a path, query, header or cookie parameter of an operation.
+type Parameter struct {
+	Name     string
+	In       string
+	Required bool
+	Example  interface{}
+	Schema   interface{}
+}
+
+// RequestBody is the body of a request to an operation.
+type RequestBody struct {
+	Required bool
+
+	// key is content type
+	Content map[string]MediaType
 }
 
 type Operation struct {
 	// key is status code
 	Responses   map[string]OperationResponse
 	Description string
+	RateLimit   *RateLimit `yaml:"x-rate-limit"`
+
+	// Parameters include those declared on the path.
+	Parameters  []Parameter
+	RequestBody *RequestBody `yaml:"requestBody"`
+
+	// Security overrides the security requirements of the spec,
+	// if declared. An empty list means no security is required.
+	Security []SecurityRequirement
 }
 
+// SecurityScheme is an OpenAPI 3 security scheme, or a Swagger 2.0
+// security definition.
+type SecurityScheme struct {
+	// Type is apiKey, http, oauth2 or openIdConnect, or for
+	// Swagger 2.0, basic, apiKey or oauth2
+	Type string
+
+	// Scheme is the HTTP authentication scheme, such as basic
+	// or bearer, for the http type
+	Scheme string
+
+	// Name and In locate the API key, for the apiKey type
+	Name string
+	In   string
+}
+
+// SecurityRequirement maps the names of the security schemes that must
+// all be satisfied to the scopes they require.
+type SecurityRequirement map[string][]string
+
 type PartialModel struct {
 	// key is path
 	Paths map[string]map[string]Operation
+
+	// key is scheme name
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
+
+	// Security holds alternative requirements, any of which
+	// permits a request
+	Security []SecurityRequirement
 }
 
+// ExampleNames returns the names of the examples for the response, in
+// spec order. Content types are considered in alphabetical order.
+func (r OperationResponse) ExampleNames() []string {
+	var contentTypes []string
+	for contentType := range r.Content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, contentType := range contentTypes {
+		for _, example := range r.Content[contentType].Examples {
+			name := fmt.Sprintf("%v", example.Key)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Parse parses a JSON or YAML spec file. Swagger 2.0 and OpenAPI 3.x
+// specs are supported. Refs to other files are resolved relative to
+// the spec file.
 func Parse(specFile string) (*PartialModel, error) {
 	reader, err := os.Open(specFile)
 	if err != nil {
@@ -52,9 +161,38 @@ func Parse(specFile string) (*PartialModel, error) {
 	if err != nil {
 		return nil, err
 	}
+	location, err := filepath.Abs(specFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseContentFrom(raw, location)
+}
+
+// ParseContent parses the content of a JSON or YAML spec. Refs to other
+// files are resolved relative to the working directory.
+func ParseContent(raw []byte) (*PartialModel, error) {
+	return ParseContentFrom(raw, "")
+}
+
+// ParseContentFrom parses the content of a JSON or YAML spec, resolving
+// refs to other documents relative to location, which is the file path
+// or URL from which the content was loaded.
+func ParseContentFrom(raw []byte, location string) (*PartialModel, error) {
+	var spec yaml.MapSlice
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
+	normalised, err := newResolver().normalise(spec, location)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := yaml.Marshal(normalised)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
 
 	o := PartialModel{}
-	err = yaml.Unmarshal(raw, &o)
+	err = yaml.Unmarshal(resolved, &o)
 	if err != nil {
 		return nil, fmt.Errorf("error: %v\n", err)
 	}
@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParse_Swagger2(t *testing.T) {
+	dir := t.TempDir()
+	specFile := writeFile(t, dir, "petstore.yaml", `swagger: "2.0"
+info:
+  title: Petstore
+  version: "1.0"
+x-custom: true
+paths:
+  x-extension: ignored
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        type: string
+    get:
+      responses:
+        "200":
+          description: A pet
+          headers:
+            X-Rate:
+              type: integer
+              default: 10
+              x-example: 5
+          schema:
+            $ref: "#/definitions/Pet"
+          examples:
+            application/json:
+              name: Fido
+        "404":
+          $ref: "#/responses/NotFound"
+responses:
+  NotFound:
+    description: Not found
+securityDefinitions:
+  key:
+    type: apiKey
+    name: X-Api-Key
+    in: header
+security:
+  - key: []
+definitions:
+  Pet:
+    type: object
+`)
+	model, err := Parse(specFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(model.Paths) != 1 {
+		t.Fatalf("expected 1 path, got %d", len(model.Paths))
+	}
+	operations := model.Paths["/pets/{petId}"]
+	if len(operations) != 1 {
+		t.Fatalf("expected 1 operation, got %d", len(operations))
+	}
+	responses := operations["get"].Responses
+	if responses["404"].Description != "Not found" {
+		t.Errorf("expected referenced response to be resolved, got: %v", responses["404"])
+	}
+	header := responses["200"].Headers["X-Rate"]
+	if header.Schema.Default != 10 || header.Schema.Example != 5 {
+		t.Errorf("expected header default and example to be converted, got: %v", header.Schema)
+	}
+	if model.SecuritySchemes["key"].Name != "X-Api-Key" || len(model.Security) != 1 {
+		t.Errorf("expected security definitions to be parsed, got: %v %v", model.SecuritySchemes, model.Security)
+	}
+	contentType, body, found := responses["200"].SampleBody("")
+	if !found || contentType != "application/json" || body.(map[string]interface{})["name"] != "Fido" {
+		t.Errorf("expected example body to be converted, got: %v %v", contentType, body)
+	}
+}
+
+func TestParse_ExternalRefs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/common.yaml":
+			_, _ = w.Write([]byte(`responses:
+  Error:
+    $ref: "errors.yaml#/Error"
+`))
+		case "/errors.yaml":
+			_, _ = w.Write([]byte(`Error:
+  description: Server error
+`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	_ = os.Mkdir(filepath.Join(dir, "paths"), 0755)
+	writeFile(t, dir, "paths/orders.yaml", `get:
+  responses:
+    "200":
+      $ref: "../openapi.yaml#/components/responses/Order"
+    "500":
+      $ref: "`+server.URL+`/common.yaml#/responses/Error"
+`)
+	specFile := writeFile(t, dir, "openapi.yaml", `openapi: 3.1.0
+info:
+  title: Orders
+  version: "1.0"
+paths:
+  /orders:
+    $ref: "./paths/orders.yaml"
+    summary: Orders
+components:
+  responses:
+    Order:
+      description: An order
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Order"
+          examples:
+            first:
+              $ref: "#/components/examples/First"
+  examples:
+    First:
+      value:
+        id: 1
+  schemas:
+    Order:
+      type: object
+      properties:
+        parent:
+          $ref: "#/components/schemas/Order"
+`)
+
+	model, err := Parse(specFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	responses := model.Paths["/orders"]["get"].Responses
+	if responses["200"].Description != "An order" {
+		t.Errorf("expected response from spec file, got: %v", responses["200"])
+	}
+	names := responses["200"].ExampleNames()
+	if len(names) != 1 || names[0] != "first" {
+		t.Errorf("expected example names [first], got: %v", names)
+	}
+	if responses["500"].Description != "Server error" {
+		t.Errorf("expected response from remote document, got: %v", responses["500"])
+	}
+}
+
+func TestParse_UnresolvableRef(t *testing.T) {
+	_, err := ParseContent([]byte(`openapi: 3.0.0
+paths:
+  /missing:
+    get:
+      responses:
+        "200":
+          $ref: "#/components/responses/Missing"
+`))
+	if err == nil {
+		t.Fatal("expected error for unresolvable ref")
+	}
+}
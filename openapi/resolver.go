@@ -0,0 +1,423 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"gopkg.in/yaml.v2"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpMethods are the keys of a path item that hold operations.
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// skippedKeys hold reusable schemas, which are only part of the model
+// where they are referenced, so refs within them are not resolved.
+var skippedKeys = []string{"schemas", "definitions"}
+
+const fetchTimeout = 30 * time.Second
+
+// resolver resolves the refs in a spec, including those to other files
+// and URLs. Documents are cached by their location, which is a file path
+// or a URL.
+type resolver struct {
+	documents map[string]yaml.MapSlice
+}
+
+func newResolver() *resolver {
+	return &resolver{documents: make(map[string]yaml.MapSlice)}
+}
+
+// normalise resolves the refs in the spec, and converts it to the form
+// expected by the model. Entries in the paths object that are not paths,
+// such as extensions, and entries in path items that are not operations,
+// such as parameters, are removed. The security schemes of OpenAPI 3
+// components and Swagger 2.0 security definitions are both held by the
+// securitySchemes key.
+func (r *resolver) normalise(spec yaml.MapSlice, location string) (yaml.MapSlice, error) {
+	r.documents[location] = spec
+	produces := toStrings(getValue(spec, "produces"))
+	var normalised yaml.MapSlice
+	for _, item := range spec {
+		switch fmt.Sprintf("%v", item.Key) {
+		case "paths":
+			paths, _ := item.Value.(yaml.MapSlice)
+			var normalisedPaths yaml.MapSlice
+			for _, path := range paths {
+				if !strings.HasPrefix(fmt.Sprintf("%v", path.Key), "/") {
+					continue
+				}
+				pathItem, err := r.resolve(path.Value, location, map[string]bool{})
+				if err != nil {
+					return nil, fmt.Errorf("error resolving path %v: %v", path.Key, err)
+				}
+				normalisedPaths = append(normalisedPaths, yaml.MapItem{Key: path.Key, Value: normaliseOperations(pathItem, produces)})
+			}
+			normalised = append(normalised, yaml.MapItem{Key: "paths", Value: normalisedPaths})
+
+		case "security":
+			normalised = append(normalised, item)
+
+		case "components", "securityDefinitions":
+			schemes := item.Value
+			if fmt.Sprintf("%v", item.Key) == "components" {
+				components, _ := item.Value.(yaml.MapSlice)
+				schemes = getValue(components, "securitySchemes")
+			}
+			if schemes == nil {
+				continue
+			}
+			resolved, err := r.resolve(schemes, location, map[string]bool{})
+			if err != nil {
+				return nil, fmt.Errorf("error resolving security schemes: %v", err)
+			}
+			normalised = append(normalised, yaml.MapItem{Key: "securitySchemes", Value: resolved})
+		}
+	}
+	return normalised, nil
+}
+
+func normaliseOperations(pathItem interface{}, produces []string) yaml.MapSlice {
+	var operations yaml.MapSlice
+	items, _ := pathItem.(yaml.MapSlice)
+	pathParameters, _ := getValue(items, "parameters").([]interface{})
+	for _, item := range items {
+		method := strings.ToLower(fmt.Sprintf("%v", item.Key))
+		if !stringutil.Contains(httpMethods, method) {
+			continue
+		}
+		operation, _ := item.Value.(yaml.MapSlice)
+		operation = normaliseParameters(operation, pathParameters)
+		operations = append(operations, yaml.MapItem{Key: method, Value: normaliseResponses(operation, produces)})
+	}
+	return operations
+}
+
+// normaliseParameters adds the parameters of the path item to those of
+// the operation, unless the operation overrides them. Swagger 2.0
+// parameters declare their type directly, rather than with a schema,
+// and the request body is declared as a parameter, which is converted
+// to the OpenAPI 3 form.
+func normaliseParameters(operation yaml.MapSlice, pathParameters []interface{}) yaml.MapSlice {
+	operationParameters, _ := getValue(operation, "parameters").([]interface{})
+	declared := make(map[string]bool)
+	for _, parameter := range operationParameters {
+		if p, ok := parameter.(yaml.MapSlice); ok {
+			declared[fmt.Sprintf("%v/%v", getValue(p, "in"), getValue(p, "name"))] = true
+		}
+	}
+	parameters := operationParameters
+	for _, parameter := range pathParameters {
+		if p, ok := parameter.(yaml.MapSlice); ok && !declared[fmt.Sprintf("%v/%v", getValue(p, "in"), getValue(p, "name"))] {
+			parameters = append(parameters, p)
+		}
+	}
+	if len(parameters) == 0 {
+		return operation
+	}
+
+	var normalised []interface{}
+	for _, parameter := range parameters {
+		p, ok := parameter.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		if getValue(p, "in") == "body" {
+			if getValue(operation, "requestBody") == nil {
+				requestBody := yaml.MapSlice{
+					{Key: "required", Value: getValue(p, "required") == true},
+					{Key: "content", Value: yaml.MapSlice{
+						{Key: "application/json", Value: yaml.MapSlice{{Key: "schema", Value: getValue(p, "schema")}}},
+					}},
+				}
+				operation = append(operation, yaml.MapItem{Key: "requestBody", Value: requestBody})
+			}
+			continue
+		}
+		if getValue(p, "schema") == nil {
+			schema := yaml.MapSlice{}
+			for _, key := range []string{"type", "format", "enum", "default"} {
+				if value := getValue(p, key); value != nil {
+					schema = append(schema, yaml.MapItem{Key: key, Value: value})
+				}
+			}
+			if value := getValue(p, "x-example"); value != nil {
+				schema = append(schema, yaml.MapItem{Key: "example", Value: value})
+			}
+			p = append(append(yaml.MapSlice{}, p...), yaml.MapItem{Key: "schema", Value: schema})
+		}
+		normalised = append(normalised, p)
+	}
+	return setValue(operation, "parameters", normalised)
+}
+
+// normaliseResponses converts Swagger 2.0 responses to the OpenAPI 3
+// form. The headers of Swagger 2.0 responses declare their default and
+// example values directly, rather than with a schema, and the schema
+// and examples of the body are declared on the response, rather than
+// per content type.
+func normaliseResponses(operation yaml.MapSlice, produces []string) yaml.MapSlice {
+	if operationProduces := toStrings(getValue(operation, "produces")); len(operationProduces) > 0 {
+		produces = operationProduces
+	}
+	responses, _ := getValue(operation, "responses").(yaml.MapSlice)
+	for i, response := range responses {
+		responseDetail, _ := response.Value.(yaml.MapSlice)
+		headers, _ := getValue(responseDetail, "headers").(yaml.MapSlice)
+		for j, header := range headers {
+			headerDetail, _ := header.Value.(yaml.MapSlice)
+			if headerDetail == nil || getValue(headerDetail, "schema") != nil {
+				continue
+			}
+			schema := yaml.MapSlice{}
+			if value := getValue(headerDetail, "default"); value != nil {
+				schema = append(schema, yaml.MapItem{Key: "default", Value: value})
+			}
+			if value := getValue(headerDetail, "x-example"); value != nil {
+				schema = append(schema, yaml.MapItem{Key: "example", Value: value})
+			}
+			headers[j].Value = append(headerDetail, yaml.MapItem{Key: "schema", Value: schema})
+		}
+		if content := buildSwaggerContent(responseDetail, produces); content != nil {
+			responses[i].Value = append(responseDetail, yaml.MapItem{Key: "content", Value: content})
+		}
+	}
+	return operation
+}
+
+// buildSwaggerContent returns the content of a Swagger 2.0 response,
+// keyed by content type, or nil if it has no body or is already in
+// the OpenAPI 3 form.
+func buildSwaggerContent(response yaml.MapSlice, produces []string) yaml.MapSlice {
+	if getValue(response, "content") != nil {
+		return nil
+	}
+	schema := getValue(response, "schema")
+	examples, _ := getValue(response, "examples").(yaml.MapSlice)
+	if schema == nil && len(examples) == 0 {
+		return nil
+	}
+	var contentTypes []string
+	for _, example := range examples {
+		contentTypes = append(contentTypes, fmt.Sprintf("%v", example.Key))
+	}
+	if schema != nil {
+		for _, contentType := range produces {
+			if !stringutil.Contains(contentTypes, contentType) {
+				contentTypes = append(contentTypes, contentType)
+			}
+		}
+		if len(contentTypes) == 0 {
+			contentTypes = append(contentTypes, "application/json")
+		}
+	}
+	var content yaml.MapSlice
+	for _, contentType := range contentTypes {
+		var mediaType yaml.MapSlice
+		if schema != nil {
+			mediaType = append(mediaType, yaml.MapItem{Key: "schema", Value: schema})
+		}
+		if example := getValue(examples, contentType); example != nil {
+			mediaType = append(mediaType, yaml.MapItem{Key: "example", Value: example})
+		}
+		content = append(content, yaml.MapItem{Key: contentType, Value: mediaType})
+	}
+	return content
+}
+
+// resolve returns the node with its refs replaced by their targets.
+// A ref to a location already being resolved is left in place, so
+// recursive structures are not expanded indefinitely.
+func (r *resolver) resolve(node interface{}, base string, resolving map[string]bool) (interface{}, error) {
+	switch n := node.(type) {
+	case yaml.MapSlice:
+		if ref, ok := getValue(n, "$ref").(string); ok {
+			location, pointer := resolveLocation(base, ref)
+			key := location + "#" + pointer
+			if resolving[key] {
+				return n, nil
+			}
+			target, err := r.lookup(location, pointer)
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve ref %s: %v", ref, err)
+			}
+			resolving[key] = true
+			defer delete(resolving, key)
+			return r.resolve(target, location, resolving)
+		}
+		resolved := make(yaml.MapSlice, 0, len(n))
+		for _, item := range n {
+			value := item.Value
+			if !stringutil.Contains(skippedKeys, fmt.Sprintf("%v", item.Key)) {
+				var err error
+				if value, err = r.resolve(item.Value, base, resolving); err != nil {
+					return nil, err
+				}
+			}
+			resolved = append(resolved, yaml.MapItem{Key: item.Key, Value: value})
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, 0, len(n))
+		for _, item := range n {
+			value, err := r.resolve(item, base, resolving)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, value)
+		}
+		return resolved, nil
+	default:
+		return node, nil
+	}
+}
+
+func (r *resolver) lookup(location string, pointer string) (interface{}, error) {
+	document, err := r.load(location)
+	if err != nil {
+		return nil, err
+	}
+	var node interface{} = document
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case yaml.MapSlice:
+			node = getValue(n, token)
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(n) {
+				return nil, fmt.Errorf("invalid index %s in pointer %s", token, pointer)
+			}
+			node = n[index]
+		default:
+			node = nil
+		}
+		if node == nil {
+			return nil, fmt.Errorf("%s not found in %s", pointer, location)
+		}
+	}
+	return node, nil
+}
+
+func (r *resolver) load(location string) (yaml.MapSlice, error) {
+	if document, found := r.documents[location]; found {
+		return document, nil
+	}
+	var content []byte
+	var err error
+	if isUrl(location) {
+		content, err = fetch(location)
+	} else {
+		content, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", location, err)
+	}
+	r.documents[location] = document
+	logger.Tracef("loaded referenced document: %s", location)
+	return document, nil
+}
+
+func fetch(location string) ([]byte, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching %s: %d", location, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// resolveLocation returns the location of the document a ref points to,
+// relative to the location of the document containing it, and the JSON
+// pointer within it.
+func resolveLocation(base string, ref string) (string, string) {
+	documentRef, pointer, _ := strings.Cut(ref, "#")
+	if documentRef == "" {
+		return base, pointer
+	}
+	if isUrl(documentRef) {
+		return documentRef, pointer
+	}
+	if isUrl(base) {
+		baseUrl, err := url.Parse(base)
+		if err == nil {
+			if resolved, err := baseUrl.Parse(documentRef); err == nil {
+				return resolved.String(), pointer
+			}
+		}
+	}
+	if filepath.IsAbs(documentRef) {
+		return documentRef, pointer
+	}
+	return filepath.Join(filepath.Dir(base), documentRef), pointer
+}
+
+func isUrl(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// setValue returns a copy of the map with the value of the key set.
+func setValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	updated := append(yaml.MapSlice{}, m...)
+	for i, item := range updated {
+		if fmt.Sprintf("%v", item.Key) == key {
+			updated[i].Value = value
+			return updated
+		}
+	}
+	return append(updated, yaml.MapItem{Key: key, Value: value})
+}
+
+func getValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if fmt.Sprintf("%v", item.Key) == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func toStrings(value interface{}) []string {
+	values, _ := value.([]interface{})
+	var strs []string
+	for _, v := range values {
+		strs = append(strs, fmt.Sprintf("%v", v))
+	}
+	return strs
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"gatehill.io/imposter/jsonschema"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strings"
+)
+
+// SampleBody returns the content type and body of the response. The
+// named example is preferred, followed by the first named example, the
+// example of the content type and finally a value generated from its
+// schema. JSON content types are preferred to others. If the response
+// has no body, found is false.
+func (r OperationResponse) SampleBody(exampleName string) (contentType string, body interface{}, found bool) {
+	return sampleContent(r.Content, exampleName)
+}
+
+// SampleBody returns the content type and body of a request, chosen in
+// the same way as those of a response.
+func (r RequestBody) SampleBody() (contentType string, body interface{}, found bool) {
+	return sampleContent(r.Content, "")
+}
+
+// SampleValue returns the example of the parameter, or a value
+// generated from its schema.
+func (p Parameter) SampleValue() (interface{}, bool) {
+	if p.Example != nil {
+		return toJson(p.Example), true
+	}
+	if p.Schema != nil {
+		if value := jsonschema.Sample(toJson(p.Schema), nil); value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// JsonSchema returns the schema of the media type in the form produced
+// by encoding/json, or nil if it has none.
+func (m MediaType) JsonSchema() interface{} {
+	if m.Schema == nil {
+		return nil
+	}
+	return toJson(m.Schema)
+}
+
+func sampleContent(content map[string]MediaType, exampleName string) (contentType string, body interface{}, found bool) {
+	var contentTypes []string
+	for ct := range content {
+		contentTypes = append(contentTypes, ct)
+	}
+	sort.SliceStable(contentTypes, func(i, j int) bool {
+		if IsJsonContentType(contentTypes[i]) != IsJsonContentType(contentTypes[j]) {
+			return IsJsonContentType(contentTypes[i])
+		}
+		return contentTypes[i] < contentTypes[j]
+	})
+	for _, ct := range contentTypes {
+		if body, found := content[ct].sample(exampleName); found {
+			return ct, body, true
+		}
+	}
+	return "", nil, false
+}
+
+func (m MediaType) sample(exampleName string) (interface{}, bool) {
+	if len(m.Examples) > 0 {
+		example := m.Examples[0].Value
+		for _, item := range m.Examples {
+			if fmt.Sprintf("%v", item.Key) == exampleName {
+				example = item.Value
+				break
+			}
+		}
+		if detail, ok := example.(yaml.MapSlice); ok {
+			if value := getValue(detail, "value"); value != nil {
+				return toJson(value), true
+			}
+		}
+	}
+	if m.Example != nil {
+		return toJson(m.Example), true
+	}
+	if m.Schema != nil {
+		if body := jsonschema.Sample(toJson(m.Schema), nil); body != nil {
+			return body, true
+		}
+	}
+	return nil, false
+}
+
+// IsJsonContentType returns true if the content type is JSON, or a
+// JSON-based type, such as application/problem+json.
+func IsJsonContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// toJson converts a value decoded from YAML to the form produced
+// by encoding/json, in which objects have string keys.
+func toJson(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		object := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			object[fmt.Sprintf("%v", item.Key)] = toJson(item.Value)
+		}
+		return object
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[fmt.Sprintf("%v", key)] = toJson(item)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			array = append(array, toJson(item))
+		}
+		return array
+	default:
+		return v
+	}
+}
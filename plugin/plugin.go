@@ -91,6 +91,46 @@ func EnsurePlugin(pluginName string, version string) error {
 	return nil
 }
 
+// ParsePluginRef splits a plugin reference in the form NAME[:VERSION]
+// into the plugin name and engine version. The ':zip' suffix, denoting
+// an archive format plugin, is kept as part of the name. If no version
+// is given, it is returned empty.
+func ParsePluginRef(ref string) (pluginName string, version string) {
+	parts := strings.Split(ref, ":")
+	pluginName = parts[0]
+	for _, part := range parts[1:] {
+		if part == "zip" {
+			pluginName += ":zip"
+		} else {
+			version = part
+		}
+	}
+	return pluginName, version
+}
+
+// Remove deletes the plugin file for the given engine version, returning
+// false if the plugin is not installed.
+func Remove(pluginName string, version string) (bool, error) {
+	pluginDir, err := getFullPluginDir(version)
+	if err != nil {
+		return false, err
+	}
+	name := strings.TrimSuffix(pluginName, ":zip")
+	var removed bool
+	for _, extension := range supportedPluginExtensions {
+		pluginFilePath := filepath.Join(pluginDir, "imposter-plugin-"+name+extension)
+		if err := os.Remove(pluginFilePath); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, fmt.Errorf("error removing plugin file: %s: %s", pluginFilePath, err)
+		}
+		logger.Tracef("removed plugin file: %s", pluginFilePath)
+		removed = true
+	}
+	return removed, nil
+}
+
 func EnsurePluginDir(version string) (string, error) {
 	fullPluginDir, err := getFullPluginDir(version)
 	if err != nil {
@@ -171,6 +211,26 @@ func addDefaultPlugins(plugins []string) error {
 	return writeDefaultPlugins(combined)
 }
 
+// RemoveDefaultPlugins removes the provided plugins from the list of
+// default plugins, if present, and writes the configuration file.
+func RemoveDefaultPlugins(plugins []string) error {
+	existing, err := ListDefaultPlugins()
+	if err != nil {
+		return fmt.Errorf("failed to load default plugins: %s", err)
+	}
+	remaining := []string{}
+	for _, p := range existing {
+		if !stringutil.Contains(plugins, p) {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(existing) == len(remaining) {
+		// none removed
+		return nil
+	}
+	return writeDefaultPlugins(remaining)
+}
+
 func ListDefaultPlugins() ([]string, error) {
 	v, err := parseConfigFile()
 	if err != nil {
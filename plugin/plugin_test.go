@@ -145,3 +145,58 @@ func Test_getPluginFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePluginRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		ref         string
+		wantName    string
+		wantVersion string
+	}{
+		{name: "name only", ref: "store-redis", wantName: "store-redis"},
+		{name: "name and version", ref: "soap:4.2.0", wantName: "soap", wantVersion: "4.2.0"},
+		{name: "zip suffix", ref: "js-graal:zip", wantName: "js-graal:zip"},
+		{name: "version and zip suffix", ref: "js-graal:4.2.0:zip", wantName: "js-graal:zip", wantVersion: "4.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotVersion := ParsePluginRef(tt.ref)
+			if gotName != tt.wantName {
+				t.Errorf("ParsePluginRef() gotName = %v, want %v", gotName, tt.wantName)
+			}
+			if gotVersion != tt.wantVersion {
+				t.Errorf("ParsePluginRef() gotVersion = %v, want %v", gotVersion, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	pluginDir, err := EnsurePluginDir("0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pluginFilePath := filepath.Join(pluginDir, "imposter-plugin-soap.jar")
+	if err := os.WriteFile(pluginFilePath, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Remove("soap", "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Errorf("Remove() expected plugin to be removed")
+	}
+	if _, err := os.Stat(pluginFilePath); !os.IsNotExist(err) {
+		t.Errorf("Remove() expected plugin file to be deleted: %s", pluginFilePath)
+	}
+
+	removed, err = Remove("soap", "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed {
+		t.Errorf("Remove() expected no plugin to be removed")
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DecodeMessage decodes a protobuf encoded message of the given type to a
+// map of its fields, named using the JSON mapping. Fields not in the
+// schema are named by their field number.
+func (s *Schema) DecodeMessage(typeName string, buf []byte) (map[string]interface{}, error) {
+	message, found := s.messages[typeName]
+	if !found {
+		return nil, fmt.Errorf("no protobuf descriptor for message type %s", typeName)
+	}
+	decoded := make(map[string]interface{})
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		f, found := message.fieldByNumber(fieldNumber)
+		if !found {
+			decoded[strconv.Itoa(int(fieldNumber))] = decodeUnknownValue(value)
+			return nil
+		}
+		if f.kind == "message" {
+			if entryType := s.messages[f.typeName]; entryType != nil && entryType.mapEntry {
+				return s.addMapEntry(decoded, f, value)
+			}
+		}
+		var values []interface{}
+		if f.repeated && value.wireType == wireBytes && isPackable(f.kind) {
+			var err error
+			if values, err = s.decodePacked(f, value.bytes); err != nil {
+				return err
+			}
+		} else {
+			v, err := s.decodeValue(f, value)
+			if err != nil {
+				return err
+			}
+			values = []interface{}{v}
+		}
+		if f.repeated {
+			existing, _ := decoded[f.name].([]interface{})
+			decoded[f.name] = append(existing, values...)
+		} else {
+			decoded[f.name] = values[0]
+		}
+		return nil
+	})
+	return decoded, err
+}
+
+// addMapEntry adds an entry of a map field, which is encoded as a
+// repeated message with 'key' and 'value' fields, to a JSON object.
+func (s *Schema) addMapEntry(decoded map[string]interface{}, f field, value wireValue) error {
+	if value.wireType != wireBytes {
+		return fmt.Errorf("invalid wire type %d for map field %s", value.wireType, f.name)
+	}
+	entry, err := s.DecodeMessage(f.typeName, value.bytes)
+	if err != nil {
+		return err
+	}
+	entries, _ := decoded[f.name].(map[string]interface{})
+	if entries == nil {
+		entries = make(map[string]interface{})
+		decoded[f.name] = entries
+	}
+	entries[fmt.Sprint(entry["key"])] = entry["value"]
+	return nil
+}
+
+func isPackable(kind string) bool {
+	switch kind {
+	case "string", "bytes", "message", "group":
+		return false
+	default:
+		return true
+	}
+}
+
+// decodePacked decodes the values of a packed repeated scalar field.
+func (s *Schema) decodePacked(f field, buf []byte) ([]interface{}, error) {
+	var values []interface{}
+	for pos := 0; pos < len(buf); {
+		var value wireValue
+		switch f.kind {
+		case "double", "fixed64", "sfixed64":
+			if len(buf)-pos < 8 {
+				return nil, fmt.Errorf("truncated packed field %s", f.name)
+			}
+			value = wireValue{wireType: wireFixed64, number: binary.LittleEndian.Uint64(buf[pos:])}
+			pos += 8
+		case "float", "fixed32", "sfixed32":
+			if len(buf)-pos < 4 {
+				return nil, fmt.Errorf("truncated packed field %s", f.name)
+			}
+			value = wireValue{wireType: wireFixed32, number: uint64(binary.LittleEndian.Uint32(buf[pos:]))}
+			pos += 4
+		default:
+			number, n := binary.Uvarint(buf[pos:])
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint in packed field %s", f.name)
+			}
+			value = wireValue{wireType: wireVarint, number: number}
+			pos += n
+		}
+		v, err := s.decodeValue(f, value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// decodeValue converts a field value to its JSON representation. As in
+// the protobuf JSON mapping, 64-bit integers are strings, bytes are base64
+// encoded and enums are named.
+func (s *Schema) decodeValue(f field, value wireValue) (interface{}, error) {
+	expectedWireType := wireVarint
+	switch f.kind {
+	case "double", "fixed64", "sfixed64":
+		expectedWireType = wireFixed64
+	case "float", "fixed32", "sfixed32":
+		expectedWireType = wireFixed32
+	case "string", "bytes", "message":
+		expectedWireType = wireBytes
+	case "group":
+		return nil, fmt.Errorf("unsupported group field %s", f.name)
+	}
+	if value.wireType != expectedWireType {
+		return nil, fmt.Errorf("invalid wire type %d for field %s", value.wireType, f.name)
+	}
+
+	n := value.number
+	switch f.kind {
+	case "double":
+		return math.Float64frombits(n), nil
+	case "float":
+		return math.Float32frombits(uint32(n)), nil
+	case "int64", "sfixed64":
+		return strconv.FormatInt(int64(n), 10), nil
+	case "uint64", "fixed64":
+		return strconv.FormatUint(n, 10), nil
+	case "int32", "sfixed32":
+		return int32(n), nil
+	case "uint32", "fixed32":
+		return uint32(n), nil
+	case "sint32":
+		return int32(uint32(n)>>1) ^ -int32(n&1), nil
+	case "sint64":
+		return strconv.FormatInt(int64(n>>1)^-int64(n&1), 10), nil
+	case "bool":
+		return n != 0, nil
+	case "enum":
+		for _, v := range s.enums[f.typeName] {
+			if v.number == int32(n) {
+				return v.name, nil
+			}
+		}
+		return int32(n), nil
+	case "string":
+		return string(value.bytes), nil
+	case "bytes":
+		return base64.StdEncoding.EncodeToString(value.bytes), nil
+	case "message":
+		return s.DecodeMessage(f.typeName, value.bytes)
+	default:
+		return nil, fmt.Errorf("unsupported type %s for field %s", f.kind, f.name)
+	}
+}
+
+// decodeUnknownValue represents a field without a descriptor by its raw
+// value, with length-delimited values base64 encoded.
+func decodeUnknownValue(value wireValue) interface{} {
+	if value.wireType == wireBytes {
+		return base64.StdEncoding.EncodeToString(value.bytes)
+	}
+	return strconv.FormatUint(value.number, 10)
+}
@@ -0,0 +1,70 @@
+package protobuf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema_DecodeMessage(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "common.proto", []byte(commonProto))
+	schema, err := ParseProtoFiles([]string{writeTestFile(t, dir, "pets.proto", []byte(petsProto))}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, found := schema.FindMethod("/example.pets.PetService/GetPet")
+	if !found {
+		t.Fatalf("FindMethod() did not find GetPet")
+	}
+
+	pet := joinProto(
+		protoVarintField(1, 42),
+		protoStringField(2, "Rex"),
+		protoVarintField(3, 1),
+		protoStringField(4, "good"),
+		protoStringField(4, "boy"),
+		protoBytesField(5, joinProto(
+			protoStringField(1, "alice"),
+			protoBytesField(2, protoStringField(1, "Alice")),
+		)),
+		protoVarintField(8, 7),
+		protoVarintField(99, 1),
+	)
+	decoded, err := schema.DecodeMessage(method.OutputType, pet)
+	if err != nil {
+		t.Fatalf("DecodeMessage() error = %v", err)
+	}
+	got, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"99":"1","id":"42","owners":{"alice":{"displayName":"Alice"}},"petName":"Rex","status":"STATUS_SOLD","tags":["good","boy"],"tattoo":7}`
+	if string(got) != want {
+		t.Errorf("DecodeMessage() = %s, want %s", got, want)
+	}
+
+	if _, err := schema.DecodeMessage("example.pets.Unknown", pet); err == nil {
+		t.Errorf("DecodeMessage() expected error for unknown message type")
+	}
+	if _, err := schema.DecodeMessage(method.OutputType, protoStringField(1, "not a number")); err == nil {
+		t.Errorf("DecodeMessage() expected error for invalid wire type")
+	}
+}
+
+func Test_toLowerCamelCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "name", want: "name"},
+		{name: "user_id", want: "userId"},
+		{name: "created_at_utc", want: "createdAtUtc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toLowerCamelCase(tt.name); got != tt.want {
+				t.Errorf("toLowerCamelCase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// protobuf field types, as defined in descriptor.proto
+var descriptorKinds = map[uint64]string{
+	1:  "double",
+	2:  "float",
+	3:  "int64",
+	4:  "uint64",
+	5:  "int32",
+	6:  "fixed64",
+	7:  "fixed32",
+	8:  "bool",
+	9:  "string",
+	10: "group",
+	11: "message",
+	12: "bytes",
+	13: "uint32",
+	14: "enum",
+	15: "sfixed32",
+	16: "sfixed64",
+	17: "sint32",
+	18: "sint64",
+}
+
+const descriptorLabelRepeated = 3
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// wireValue is a field value read from the wire. Varint and fixed width
+// values are held in number, and length-delimited values in bytes.
+type wireValue struct {
+	wireType int
+	number   uint64
+	bytes    []byte
+}
+
+// LoadDescriptorSet reads the message types and services from a descriptor
+// set, such as one generated by 'protoc --include_imports --descriptor_set_out'.
+func LoadDescriptorSet(descriptorSetPath string) (*Schema, error) {
+	return LoadDescriptorSets([]string{descriptorSetPath})
+}
+
+// LoadDescriptorSets reads the message types and services from each of
+// the descriptor sets into a single schema.
+func LoadDescriptorSets(descriptorSetPaths []string) (*Schema, error) {
+	schema := newSchema()
+	for _, descriptorSetPath := range descriptorSetPaths {
+		buf, err := os.ReadFile(descriptorSetPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read protobuf descriptor set: %s: %v", descriptorSetPath, err)
+		}
+		err = walk(buf, func(fieldNumber int32, value wireValue) error {
+			if fieldNumber == 1 {
+				return schema.addFileDescriptor(value.bytes)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid protobuf descriptor set: %s: %v", descriptorSetPath, err)
+		}
+	}
+	return schema, nil
+}
+
+func (s *Schema) addFileDescriptor(buf []byte) error {
+	var pkg string
+	var messages, enums, services [][]byte
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		switch fieldNumber {
+		case 2:
+			pkg = string(value.bytes)
+		case 4:
+			messages = append(messages, value.bytes)
+		case 5:
+			enums = append(enums, value.bytes)
+		case 6:
+			services = append(services, value.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	scope := ""
+	if pkg != "" {
+		scope = pkg + "."
+	}
+	for _, message := range messages {
+		if err := s.addMessageDescriptor(scope, message); err != nil {
+			return err
+		}
+	}
+	for _, enum := range enums {
+		if err := s.addEnumDescriptor(scope, enum); err != nil {
+			return err
+		}
+	}
+	for _, service := range services {
+		if err := s.addServiceDescriptor(scope, service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) addMessageDescriptor(scope string, buf []byte) error {
+	var name string
+	var fields, nested, enums [][]byte
+	var oneofs []string
+	message := &messageType{}
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		switch fieldNumber {
+		case 1:
+			name = string(value.bytes)
+		case 2:
+			fields = append(fields, value.bytes)
+		case 3:
+			nested = append(nested, value.bytes)
+		case 4:
+			enums = append(enums, value.bytes)
+		case 7:
+			return walk(value.bytes, func(option int32, value wireValue) error {
+				if option == 7 {
+					message.mapEntry = value.number != 0
+				}
+				return nil
+			})
+		case 8:
+			return walk(value.bytes, func(fieldNumber int32, value wireValue) error {
+				if fieldNumber == 1 {
+					oneofs = append(oneofs, string(value.bytes))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, buf := range fields {
+		f, err := parseFieldDescriptor(buf, oneofs)
+		if err != nil {
+			return err
+		}
+		message.fields = append(message.fields, f)
+	}
+	fullName := scope + name
+	s.messages[fullName] = message
+	for _, buf := range nested {
+		if err := s.addMessageDescriptor(fullName+".", buf); err != nil {
+			return err
+		}
+	}
+	for _, buf := range enums {
+		if err := s.addEnumDescriptor(fullName+".", buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFieldDescriptor(buf []byte, oneofs []string) (field, error) {
+	var f field
+	var jsonName string
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		switch fieldNumber {
+		case 1:
+			f.name = string(value.bytes)
+		case 3:
+			f.number = int32(value.number)
+		case 4:
+			f.repeated = value.number == descriptorLabelRepeated
+		case 5:
+			f.kind = descriptorKinds[value.number]
+		case 6:
+			f.typeName = strings.TrimPrefix(string(value.bytes), ".")
+		case 9:
+			if int(value.number) < len(oneofs) {
+				f.oneof = oneofs[value.number]
+			}
+		case 10:
+			jsonName = string(value.bytes)
+		}
+		return nil
+	})
+	if jsonName != "" {
+		f.name = jsonName
+	} else {
+		f.name = toLowerCamelCase(f.name)
+	}
+	return f, err
+}
+
+func (s *Schema) addEnumDescriptor(scope string, buf []byte) error {
+	var name string
+	var values []enumValue
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		switch fieldNumber {
+		case 1:
+			name = string(value.bytes)
+		case 2:
+			var enumValue enumValue
+			err := walk(value.bytes, func(fieldNumber int32, value wireValue) error {
+				switch fieldNumber {
+				case 1:
+					enumValue.name = string(value.bytes)
+				case 2:
+					enumValue.number = int32(value.number)
+				}
+				return nil
+			})
+			values = append(values, enumValue)
+			return err
+		}
+		return nil
+	})
+	s.enums[scope+name] = values
+	return err
+}
+
+func (s *Schema) addServiceDescriptor(scope string, buf []byte) error {
+	service := Service{}
+	var methods [][]byte
+	err := walk(buf, func(fieldNumber int32, value wireValue) error {
+		switch fieldNumber {
+		case 1:
+			service.Name = scope + string(value.bytes)
+		case 2:
+			methods = append(methods, value.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, buf := range methods {
+		var method Method
+		err := walk(buf, func(fieldNumber int32, value wireValue) error {
+			switch fieldNumber {
+			case 1:
+				method.Name = string(value.bytes)
+			case 2:
+				method.InputType = strings.TrimPrefix(string(value.bytes), ".")
+			case 3:
+				method.OutputType = strings.TrimPrefix(string(value.bytes), ".")
+			case 5:
+				method.ClientStreaming = value.number != 0
+			case 6:
+				method.ServerStreaming = value.number != 0
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		service.Methods = append(service.Methods, method)
+	}
+	s.Services = append(s.Services, service)
+	return nil
+}
+
+// walk calls fn with the number and value of each field of a protobuf
+// encoded message, in the order they appear.
+func walk(buf []byte, fn func(fieldNumber int32, value wireValue) error) error {
+	for pos := 0; pos < len(buf); {
+		tag, n := binary.Uvarint(buf[pos:])
+		if n <= 0 {
+			return fmt.Errorf("invalid field tag at offset %d", pos)
+		}
+		pos += n
+		value := wireValue{wireType: int(tag & 7)}
+		switch value.wireType {
+		case wireVarint:
+			if value.number, n = binary.Uvarint(buf[pos:]); n <= 0 {
+				return fmt.Errorf("invalid varint at offset %d", pos)
+			}
+			pos += n
+		case wireFixed64:
+			if len(buf)-pos < 8 {
+				return fmt.Errorf("truncated fixed64 at offset %d", pos)
+			}
+			value.number = binary.LittleEndian.Uint64(buf[pos:])
+			pos += 8
+		case wireFixed32:
+			if len(buf)-pos < 4 {
+				return fmt.Errorf("truncated fixed32 at offset %d", pos)
+			}
+			value.number = uint64(binary.LittleEndian.Uint32(buf[pos:]))
+			pos += 4
+		case wireBytes:
+			length, n := binary.Uvarint(buf[pos:])
+			if n <= 0 || uint64(len(buf)-pos-n) < length {
+				return fmt.Errorf("truncated length-delimited field at offset %d", pos)
+			}
+			pos += n
+			value.bytes = buf[pos : pos+int(length)]
+			pos += int(length)
+		default:
+			return fmt.Errorf("unsupported wire type %d at offset %d", value.wireType, pos)
+		}
+		if err := fn(int32(tag>>3), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/logging"
+	"path/filepath"
+)
+
+var logger = logging.GetLogger()
+
+// descriptorSetExtensions are the extensions commonly used for
+// descriptor sets generated by protoc.
+var descriptorSetExtensions = []string{".protoset", ".desc", ".pb"}
+
+// DiscoverProtoFiles finds .proto files and descriptor sets declaring
+// at least one service within the given directory. It returns fully
+// qualified paths to the files discovered.
+func DiscoverProtoFiles(configDir string, importPaths []string) []string {
+	var protoFiles []string
+
+	candidates := fileutil.FindFilesWithExtension(configDir, append([]string{".proto"}, descriptorSetExtensions...)...)
+	for _, candidate := range candidates {
+		fullyQualifiedPath := filepath.Join(configDir, candidate)
+		schema, err := Load(fullyQualifiedPath, importPaths)
+		if err != nil {
+			logger.Warnf("skipping invalid protobuf file: %v", err)
+			continue
+		}
+		if len(schema.Services) > 0 {
+			protoFiles = append(protoFiles, fullyQualifiedPath)
+		}
+	}
+
+	return protoFiles
+}
+
+// IsDescriptorSet determines if the file is a descriptor set, rather than
+// a .proto file, based on its extension.
+func IsDescriptorSet(filePath string) bool {
+	return filepath.Ext(filePath) != ".proto"
+}
+
+// Load reads a .proto file, resolving its imports relative to it and the
+// import paths, or a descriptor set.
+func Load(filePath string, importPaths []string) (*Schema, error) {
+	if IsDescriptorSet(filePath) {
+		return LoadDescriptorSet(filePath)
+	}
+	return ParseProtoFiles([]string{filePath}, importPaths)
+}
@@ -0,0 +1,706 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var scalarKinds = []string{
+	"double", "float", "int32", "int64", "uint32", "uint64", "sint32", "sint64",
+	"fixed32", "fixed64", "sfixed32", "sfixed64", "bool", "string", "bytes",
+}
+
+// ParseProtoFiles reads the message types and services from .proto files.
+// Imported files are parsed for their message types, and are resolved
+// relative to the importing file, then each of the import paths. Only
+// the services in the given files are included.
+func ParseProtoFiles(protoFiles []string, importPaths []string) (*Schema, error) {
+	p := &protoLoader{
+		schema:      newSchema(),
+		importPaths: importPaths,
+		loaded:      make(map[string]bool),
+	}
+	for _, protoFile := range protoFiles {
+		if err := p.load(protoFile, true); err != nil {
+			return nil, err
+		}
+	}
+	p.resolveTypes()
+	return p.schema, nil
+}
+
+type protoLoader struct {
+	schema      *Schema
+	importPaths []string
+	loaded      map[string]bool
+
+	// fields are resolved once all files are loaded, as types
+	// may be declared after they are referenced
+	unresolved []unresolvedField
+}
+
+type unresolvedField struct {
+	message *messageType
+	index   int
+	scope   string
+}
+
+func (l *protoLoader) load(protoFile string, includeServices bool) error {
+	absPath, err := filepath.Abs(protoFile)
+	if err != nil {
+		return err
+	}
+	if l.loaded[absPath] {
+		return nil
+	}
+	l.loaded[absPath] = true
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("error reading proto file: %v: %v", protoFile, err)
+	}
+	tokens, err := tokeniseProto(string(content))
+	if err != nil {
+		return fmt.Errorf("error parsing proto file: %v: %v", protoFile, err)
+	}
+	p := &protoParser{loader: l, tokens: tokens, includeServices: includeServices}
+	if err := p.parseFile(); err != nil {
+		return fmt.Errorf("error parsing proto file: %v: %v", protoFile, err)
+	}
+
+	for _, imported := range p.imports {
+		importPath := l.findImport(filepath.Dir(absPath), imported)
+		if importPath == "" {
+			if !strings.HasPrefix(imported, "google/protobuf/") {
+				logger.Warnf("imported proto file not found: %s - types from it will be empty", imported)
+			}
+			continue
+		}
+		if err := l.load(importPath, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *protoLoader) findImport(dir string, imported string) string {
+	for _, importPath := range append([]string{dir}, l.importPaths...) {
+		candidate := filepath.Join(importPath, imported)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// resolveTypes sets the kind and fully qualified name of message and
+// enum fields, following the protobuf scoping rules, where the innermost
+// scope is searched first.
+func (l *protoLoader) resolveTypes() {
+	for _, u := range l.unresolved {
+		f := &u.message.fields[u.index]
+		typeName := f.typeName
+		f.typeName, f.kind = l.resolveType(typeName, u.scope)
+		if f.kind == "" {
+			logger.Debugf("type not found: %s", typeName)
+			f.kind, f.typeName = "message", typeName
+		}
+	}
+	for i := range l.schema.Services {
+		service := &l.schema.Services[i]
+		scope := service.Name[:strings.LastIndex(service.Name, ".")+1]
+		for j := range service.Methods {
+			method := &service.Methods[j]
+			method.InputType = l.resolveMessageName(method.InputType, scope)
+			method.OutputType = l.resolveMessageName(method.OutputType, scope)
+		}
+	}
+}
+
+func (l *protoLoader) resolveMessageName(typeName string, scope string) string {
+	if resolved, kind := l.resolveType(typeName, scope); kind != "" {
+		return resolved
+	}
+	return strings.TrimPrefix(typeName, ".")
+}
+
+// resolveType returns the fully qualified name of the type and its kind,
+// either 'message' or 'enum'. scope is the fully qualified name of the
+// enclosing message or package, followed by a dot, if not empty.
+func (l *protoLoader) resolveType(typeName string, scope string) (string, string) {
+	if strings.HasPrefix(typeName, ".") {
+		return l.lookup(typeName[1:])
+	}
+	for {
+		if resolved, kind := l.lookup(scope + typeName); kind != "" {
+			return resolved, kind
+		}
+		if scope == "" {
+			return "", ""
+		}
+		scope = strings.TrimSuffix(scope, ".")
+		scope = scope[:strings.LastIndex(scope, ".")+1]
+	}
+}
+
+func (l *protoLoader) lookup(fullName string) (string, string) {
+	if _, found := l.schema.messages[fullName]; found {
+		return fullName, "message"
+	}
+	if _, found := l.schema.enums[fullName]; found {
+		return fullName, "enum"
+	}
+	if _, found := wellKnownSample(fullName); found {
+		return fullName, "message"
+	}
+	return "", ""
+}
+
+type protoParser struct {
+	loader          *protoLoader
+	tokens          []string
+	pos             int
+	pkg             string
+	imports         []string
+	includeServices bool
+}
+
+func (p *protoParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *protoParser) next() string {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *protoParser) expect(value string) error {
+	if t := p.next(); t != value {
+		return fmt.Errorf("expected %q but found %q", value, t)
+	}
+	return nil
+}
+
+func (p *protoParser) expectIdentifier() (string, error) {
+	t := p.next()
+	if t == "" || !isIdentifierStart(t[0]) {
+		return "", fmt.Errorf("expected identifier but found %q", t)
+	}
+	return t, nil
+}
+
+func (p *protoParser) scope() string {
+	if p.pkg == "" {
+		return ""
+	}
+	return p.pkg + "."
+}
+
+func (p *protoParser) parseFile() error {
+	for p.peek() != "" {
+		var err error
+		switch keyword := p.next(); keyword {
+		case "syntax", "edition", "option":
+			err = p.skipStatement()
+		case "package":
+			if p.pkg, err = p.expectIdentifier(); err == nil {
+				err = p.expect(";")
+			}
+		case "import":
+			if p.peek() == "public" || p.peek() == "weak" {
+				p.next()
+			}
+			imported := p.next()
+			if !isStringLiteral(imported) {
+				return fmt.Errorf("expected import path but found %q", imported)
+			}
+			p.imports = append(p.imports, unquote(imported))
+			err = p.expect(";")
+		case "message":
+			err = p.parseMessage(p.scope())
+		case "enum":
+			err = p.parseEnum(p.scope())
+		case "service":
+			err = p.parseService()
+		case "extend":
+			if _, err = p.expectIdentifier(); err == nil {
+				err = p.skipBlock()
+			}
+		case ";":
+		default:
+			return fmt.Errorf("unexpected %q", keyword)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) parseMessage(scope string) error {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	fullName := scope + name
+	message := &messageType{}
+	p.loader.schema.messages[fullName] = message
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return fmt.Errorf("unterminated message %s", name)
+		}
+		switch p.peek() {
+		case "message":
+			p.next()
+			err = p.parseMessage(fullName + ".")
+		case "enum":
+			p.next()
+			err = p.parseEnum(fullName + ".")
+		case "oneof":
+			p.next()
+			err = p.parseOneof(message, fullName)
+		case "option", "reserved", "extensions":
+			err = p.skipStatement()
+		case "extend":
+			p.next()
+			if _, err = p.expectIdentifier(); err == nil {
+				err = p.skipBlock()
+			}
+		case ";":
+			p.next()
+		default:
+			err = p.parseField(message, fullName, "")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *protoParser) parseOneof(message *messageType, messageName string) error {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		switch p.peek() {
+		case "":
+			return fmt.Errorf("unterminated oneof %s", name)
+		case "option":
+			err = p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			err = p.parseField(message, messageName, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.next()
+	return nil
+}
+
+// parseField parses a field, including a map field, which is represented
+// as a repeated entry message, in the same way as in a descriptor set.
+func (p *protoParser) parseField(message *messageType, messageName string, oneof string) error {
+	f := field{oneof: oneof}
+	switch p.peek() {
+	case "repeated":
+		f.repeated = true
+		p.next()
+	case "optional", "required":
+		p.next()
+	}
+
+	typeName, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	var keyType, valueType string
+	if typeName == "map" && p.peek() == "<" {
+		p.next()
+		if keyType, err = p.expectIdentifier(); err != nil {
+			return err
+		}
+		if err = p.expect(","); err != nil {
+			return err
+		}
+		if valueType, err = p.expectIdentifier(); err != nil {
+			return err
+		}
+		if err = p.expect(">"); err != nil {
+			return err
+		}
+	} else if typeName == "group" {
+		// proto2 groups are deprecated, and not sampled
+		if _, err = p.expectIdentifier(); err == nil {
+			if err = p.expect("="); err == nil {
+				p.next()
+				err = p.skipBlock()
+			}
+		}
+		return err
+	}
+
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	number, err := strconv.ParseInt(p.next(), 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid field number for %s: %v", name, err)
+	}
+	jsonName, err := p.parseFieldOptions()
+	if err != nil {
+		return err
+	}
+	if err = p.expect(";"); err != nil {
+		return err
+	}
+
+	f.name = toLowerCamelCase(name)
+	f.number = int32(number)
+	if jsonName != "" {
+		f.name = jsonName
+	}
+	if keyType != "" {
+		entryName := messageName + "." + strings.ToUpper(name[:1]) + toLowerCamelCase(name)[1:] + "Entry"
+		entry := &messageType{mapEntry: true}
+		p.loader.schema.messages[entryName] = entry
+		p.addField(entry, field{name: "key", number: 1, typeName: keyType}, messageName+".")
+		p.addField(entry, field{name: "value", number: 2, typeName: valueType}, messageName+".")
+		f.repeated, f.kind, f.typeName = true, "message", entryName
+		message.fields = append(message.fields, f)
+		return nil
+	}
+	f.typeName = typeName
+	p.addField(message, f, messageName+".")
+	return nil
+}
+
+// addField appends the field to the message, deferring the resolution
+// of its type if it is not a scalar.
+func (p *protoParser) addField(message *messageType, f field, scope string) {
+	if f.kind == "" {
+		for _, kind := range scalarKinds {
+			if f.typeName == kind {
+				f.kind, f.typeName = kind, ""
+				break
+			}
+		}
+	}
+	message.fields = append(message.fields, f)
+	if f.kind == "" {
+		p.loader.unresolved = append(p.loader.unresolved, unresolvedField{message: message, index: len(message.fields) - 1, scope: scope})
+	}
+}
+
+// parseFieldOptions skips the options of a field, returning the value
+// of the json_name option, if set.
+func (p *protoParser) parseFieldOptions() (string, error) {
+	if p.peek() != "[" {
+		return "", nil
+	}
+	p.next()
+	var jsonName string
+	for p.peek() != "]" {
+		if p.peek() == "" {
+			return "", fmt.Errorf("unterminated field options")
+		}
+		var optionName string
+		for p.peek() != "=" && p.peek() != "" {
+			optionName += p.next()
+		}
+		p.next()
+		value := p.peek()
+		if err := p.skipValue(); err != nil {
+			return "", err
+		}
+		if optionName == "json_name" && isStringLiteral(value) {
+			jsonName = unquote(value)
+		}
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+	return jsonName, nil
+}
+
+func (p *protoParser) parseEnum(scope string) error {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	var values []enumValue
+	for p.peek() != "}" {
+		switch p.peek() {
+		case "":
+			return fmt.Errorf("unterminated enum %s", name)
+		case "option", "reserved":
+			err = p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			var value enumValue
+			if value, err = p.parseEnumValue(); err == nil {
+				values = append(values, value)
+				err = p.skipStatement()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.next()
+	p.loader.schema.enums[scope+name] = values
+	return nil
+}
+
+// parseEnumValue parses the name and number of an enum value, leaving
+// its options to be skipped.
+func (p *protoParser) parseEnumValue() (enumValue, error) {
+	value := enumValue{name: p.next()}
+	if err := p.expect("="); err != nil {
+		return value, err
+	}
+	token := p.next()
+	if token == "-" {
+		token += p.next()
+	}
+	number, err := strconv.ParseInt(token, 0, 32)
+	if err != nil {
+		return value, fmt.Errorf("invalid number for enum value %s: %v", value.name, err)
+	}
+	value.number = int32(number)
+	return value, nil
+}
+
+func (p *protoParser) parseService() error {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return err
+	}
+	service := Service{Name: p.scope() + name}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		switch p.peek() {
+		case "":
+			return fmt.Errorf("unterminated service %s", name)
+		case "option":
+			err = p.skipStatement()
+		case ";":
+			p.next()
+		case "rpc":
+			p.next()
+			var method Method
+			method, err = p.parseMethod()
+			service.Methods = append(service.Methods, method)
+		default:
+			return fmt.Errorf("unexpected %q in service %s", p.peek(), name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.next()
+	if p.includeServices {
+		p.loader.schema.Services = append(p.loader.schema.Services, service)
+	}
+	return nil
+}
+
+func (p *protoParser) parseMethod() (Method, error) {
+	var method Method
+	var err error
+	if method.Name, err = p.expectIdentifier(); err != nil {
+		return method, err
+	}
+	if method.InputType, method.ClientStreaming, err = p.parseMethodType(); err != nil {
+		return method, err
+	}
+	if err = p.expect("returns"); err != nil {
+		return method, err
+	}
+	if method.OutputType, method.ServerStreaming, err = p.parseMethodType(); err != nil {
+		return method, err
+	}
+	if p.peek() == "{" {
+		return method, p.skipBlock()
+	}
+	return method, p.expect(";")
+}
+
+func (p *protoParser) parseMethodType() (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	stream := false
+	if p.peek() == "stream" && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1] != ")" {
+		p.next()
+		stream = true
+	}
+	typeName, err := p.expectIdentifier()
+	if err != nil {
+		return "", false, err
+	}
+	return typeName, stream, p.expect(")")
+}
+
+// skipStatement skips to the end of the current statement,
+// including any aggregate values within it.
+func (p *protoParser) skipStatement() error {
+	for {
+		switch p.next() {
+		case ";":
+			return nil
+		case "{":
+			p.pos--
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "":
+			return fmt.Errorf("unterminated statement")
+		}
+	}
+}
+
+// skipBlock skips a block delimited by braces, including nested blocks.
+func (p *protoParser) skipBlock() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "":
+			return fmt.Errorf("unterminated block")
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) skipValue() error {
+	switch p.peek() {
+	case "{":
+		return p.skipBlock()
+	case "", "]", ",", ";":
+		return fmt.Errorf("expected value but found %q", p.peek())
+	}
+	if p.next() == "-" {
+		p.next()
+	}
+	return nil
+}
+
+// tokeniseProto splits a .proto file into tokens, ignoring comments
+// and whitespace. Identifiers include dots, so qualified names are a
+// single token.
+func tokeniseProto(source string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(source) && source[j] != c {
+				if source[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(source) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, source[i:j+1])
+			i = j + 1
+		case isIdentifierStart(c) || isDigit(c):
+			j := i + 1
+			for j < len(source) && (isIdentifierStart(source[j]) || isDigit(source[j]) || (isDigit(c) && (source[j] == '+' || source[j] == '-') && (source[j-1] == 'e' || source[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, source[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isStringLiteral(token string) bool {
+	return len(token) >= 2 && (token[0] == '"' || token[0] == '\'')
+}
+
+func unquote(token string) string {
+	if unquoted, err := strconv.Unquote("\"" + strings.ReplaceAll(token[1:len(token)-1], "\"", "\\\"") + "\""); err == nil {
+		return unquoted
+	}
+	return token[1 : len(token)-1]
+}
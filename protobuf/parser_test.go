@@ -0,0 +1,221 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const petsProto = `
+syntax = "proto3";
+
+package example.pets;
+
+import "google/protobuf/timestamp.proto";
+import "common.proto";
+
+option java_package = "com.example.pets";
+
+// A pet in the store.
+message Pet {
+  int64 id = 1;
+  string name = 2 [json_name = "petName"];
+  Status status = 3;
+  repeated string tags = 4;
+  map<string, Owner> owners = 5;
+  google.protobuf.Timestamp born = 6;
+  oneof identifier {
+    string chip_id = 7;
+    int32 tattoo = 8;
+  }
+  Pet parent = 9;
+  common.Money price = 10;
+
+  enum Status {
+    option allow_alias = true;
+    STATUS_AVAILABLE = 0;
+    STATUS_SOLD = 1 [deprecated = true];
+  }
+
+  message Owner {
+    string display_name = 1;
+  }
+
+  reserved 20 to 30;
+}
+
+message GetPetRequest {
+  int64 id = 1;
+}
+
+service PetService {
+  option (custom.option) = { name: "pets" };
+  rpc GetPet (GetPetRequest) returns (Pet);
+  rpc WatchPets (GetPetRequest) returns (stream .example.pets.Pet) {
+    option deprecated = true;
+  }
+}
+`
+
+const commonProto = `
+syntax = "proto3";
+package common;
+
+message Money {
+  string currency_code = 1;
+  double amount = 2;
+}
+
+service NotIncluded {
+  rpc Ignored (Money) returns (Money);
+}
+`
+
+func writeTestFile(t *testing.T, dir string, name string, content []byte) string {
+	filePath := filepath.Join(dir, name)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestParseProtoFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "common.proto", []byte(commonProto))
+	schema, err := ParseProtoFiles([]string{writeTestFile(t, dir, "pets.proto", []byte(petsProto))}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Service{{
+		Name: "example.pets.PetService",
+		Methods: []Method{
+			{Name: "GetPet", InputType: "example.pets.GetPetRequest", OutputType: "example.pets.Pet"},
+			{Name: "WatchPets", InputType: "example.pets.GetPetRequest", OutputType: "example.pets.Pet", ServerStreaming: true},
+		},
+	}}
+	if !reflect.DeepEqual(schema.Services, want) {
+		t.Errorf("Services = %+v, want %+v", schema.Services, want)
+	}
+	if got := schema.Services[0].Path(schema.Services[0].Methods[0]); got != "/example.pets.PetService/GetPet" {
+		t.Errorf("Path() = %v", got)
+	}
+
+	got := string(schema.SampleResponse(schema.Services[0].Methods[0]))
+	wantSample := `{
+  "id": "0",
+  "petName": "string",
+  "status": "STATUS_AVAILABLE",
+  "tags": [
+    "string"
+  ],
+  "owners": {
+    "key": {
+      "displayName": "string"
+    }
+  },
+  "born": "1970-01-01T00:00:00Z",
+  "chipId": "string",
+  "price": {
+    "currencyCode": "string",
+    "amount": 0.0
+  }
+}
+`
+	if got != wantSample {
+		t.Errorf("SampleResponse() = %v, want %v", got, wantSample)
+	}
+}
+
+func protoVarintField(field int, value uint64) []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(nil, uint64(field<<3)), value)
+}
+
+func protoBytesField(field int, value []byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(field<<3|2))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func protoStringField(field int, value string) []byte {
+	return protoBytesField(field, []byte(value))
+}
+
+func joinProto(parts ...[]byte) []byte {
+	var buf []byte
+	for _, part := range parts {
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	field := func(name string, number int, label int, kind int, typeName string) []byte {
+		return joinProto(
+			protoStringField(1, name),
+			protoVarintField(3, uint64(number)),
+			protoVarintField(4, uint64(label)),
+			protoVarintField(5, uint64(kind)),
+			protoStringField(6, typeName),
+		)
+	}
+	reply := joinProto(
+		protoStringField(1, "HelloReply"),
+		protoBytesField(2, field("message_text", 1, 1, 9, "")),
+		protoBytesField(2, field("counts", 2, 3, 5, "")),
+		protoBytesField(2, field("kind", 3, 1, 14, ".greet.Kind")),
+	)
+	kind := joinProto(
+		protoStringField(1, "Kind"),
+		protoBytesField(2, joinProto(protoStringField(1, "KIND_UNKNOWN"), protoVarintField(2, 0))),
+	)
+	service := joinProto(
+		protoStringField(1, "Greeter"),
+		protoBytesField(2, joinProto(
+			protoStringField(1, "SayHello"),
+			protoStringField(2, ".greet.HelloRequest"),
+			protoStringField(3, ".greet.HelloReply"),
+		)),
+	)
+	file := joinProto(
+		protoStringField(1, "greet.proto"),
+		protoStringField(2, "greet"),
+		protoBytesField(4, reply),
+		protoBytesField(5, kind),
+		protoBytesField(6, service),
+	)
+	descriptorSet := writeTestFile(t, t.TempDir(), "greet.protoset", protoBytesField(1, file))
+
+	schema, err := Load(descriptorSet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Services) != 1 || schema.Services[0].Name != "greet.Greeter" {
+		t.Fatalf("unexpected services: %+v", schema.Services)
+	}
+	got := string(schema.SampleResponse(schema.Services[0].Methods[0]))
+	want := `{
+  "messageText": "string",
+  "counts": [
+    0
+  ],
+  "kind": "KIND_UNKNOWN"
+}
+`
+	if got != want {
+		t.Errorf("SampleResponse() = %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverProtoFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "common.proto", []byte(`syntax = "proto3"; message Money { string currency_code = 1; }`))
+	writeTestFile(t, dir, "pets.proto", []byte(`syntax = "proto3"; import "common.proto"; service Pets { rpc Price (Money) returns (Money); }`))
+
+	got := DiscoverProtoFiles(dir, nil)
+	if len(got) != 1 || filepath.Base(got[0]) != "pets.proto" {
+		t.Errorf("DiscoverProtoFiles() = %v, want [pets.proto]", got)
+	}
+}
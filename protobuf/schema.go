@@ -0,0 +1,300 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protobuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Schema holds the message types and services read from .proto files
+// or a descriptor set. Types are keyed by their fully qualified name,
+// such as 'example.pets.Pet'.
+type Schema struct {
+	Services []Service
+	messages map[string]*messageType
+	enums    map[string][]enumValue
+}
+
+type Service struct {
+	// Name is the fully qualified name of the service.
+	Name    string
+	Methods []Method
+}
+
+type Method struct {
+	Name            string
+	InputType       string
+	OutputType      string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// Path returns the HTTP/2 request path of the gRPC method,
+// such as '/example.pets.PetService/GetPet'.
+func (s Service) Path(method Method) string {
+	return "/" + s.Name + "/" + method.Name
+}
+
+// ShortName returns the name of the service without its package.
+func (s Service) ShortName() string {
+	return s.Name[strings.LastIndex(s.Name, ".")+1:]
+}
+
+// FindMethod returns the method with the given gRPC request path,
+// such as '/example.pets.PetService/GetPet'.
+func (s *Schema) FindMethod(path string) (Method, bool) {
+	for _, service := range s.Services {
+		for _, method := range service.Methods {
+			if service.Path(method) == path {
+				return method, true
+			}
+		}
+	}
+	return Method{}, false
+}
+
+type messageType struct {
+	fields   []field
+	mapEntry bool
+}
+
+// fieldByNumber returns the field with the given field number.
+func (m *messageType) fieldByNumber(number int32) (field, bool) {
+	for _, f := range m.fields {
+		if f.number == number {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// field is a message field. kind is the protobuf scalar type, such as
+// 'int32', or 'message' or 'enum', in which case typeName is set.
+type field struct {
+	name     string
+	number   int32
+	kind     string
+	typeName string
+	repeated bool
+	oneof    string
+}
+
+type enumValue struct {
+	name   string
+	number int32
+}
+
+func newSchema() *Schema {
+	return &Schema{
+		messages: make(map[string]*messageType),
+		enums:    make(map[string][]enumValue),
+	}
+}
+
+// SampleResponse generates a sample output message for the method, in
+// the JSON mapping for protobuf, populated from the message schema.
+func (s *Schema) SampleResponse(method Method) []byte {
+	sample := s.sampleMessage(method.OutputType, make(map[string]bool))
+	if sample == nil {
+		sample = jsonObject{}
+	}
+	response, err := json.MarshalIndent(sample, "", "  ")
+	if err != nil {
+		logger.Fatalf("unable to marshal sample response: %v", err)
+	}
+	return append(response, '\n')
+}
+
+// sampleMessage returns a sample of the message type. Only the first
+// field of each oneof is set. Message types already being sampled are
+// omitted, so recursive types do not repeat indefinitely.
+func (s *Schema) sampleMessage(typeName string, visiting map[string]bool) interface{} {
+	if sample, found := wellKnownSample(typeName); found {
+		return sample
+	}
+	message := s.messages[typeName]
+	if message == nil {
+		logger.Debugf("message type not found: %s", typeName)
+		return jsonObject{}
+	}
+	if visiting[typeName] {
+		return nil
+	}
+	visiting[typeName] = true
+	defer delete(visiting, typeName)
+
+	object := jsonObject{}
+	oneofs := make(map[string]bool)
+	for _, f := range message.fields {
+		if f.oneof != "" {
+			if oneofs[f.oneof] {
+				continue
+			}
+			oneofs[f.oneof] = true
+		}
+		if value := s.sampleField(f, visiting); value != nil {
+			object = append(object, jsonField{f.name, value})
+		}
+	}
+	return object
+}
+
+func (s *Schema) sampleField(f field, visiting map[string]bool) interface{} {
+	if !f.repeated {
+		return s.sampleValue(f, visiting)
+	}
+	if entry := s.messages[f.typeName]; f.kind == "message" && entry != nil && entry.mapEntry && len(entry.fields) == 2 {
+		key := entry.fields[0]
+		var keySample string
+		if key.kind == "string" {
+			keySample = "key"
+		} else {
+			keySample = strings.Trim(string(mustMarshal(s.sampleValue(key, visiting))), "\"")
+		}
+		value := s.sampleValue(entry.fields[1], visiting)
+		if value == nil {
+			return jsonObject{}
+		}
+		return jsonObject{{keySample, value}}
+	}
+	item := s.sampleValue(f, visiting)
+	if item == nil {
+		return []interface{}{}
+	}
+	return []interface{}{item}
+}
+
+func (s *Schema) sampleValue(f field, visiting map[string]bool) interface{} {
+	switch f.kind {
+	case "double", "float":
+		return json.Number("0.0")
+	case "int32", "sint32", "sfixed32", "uint32", "fixed32":
+		return 0
+	case "int64", "sint64", "sfixed64", "uint64", "fixed64":
+		// 64-bit integers are strings in the JSON mapping
+		return "0"
+	case "bool":
+		return false
+	case "string":
+		return "string"
+	case "bytes":
+		return ""
+	case "enum":
+		if values := s.enums[f.typeName]; len(values) > 0 {
+			return values[0].name
+		}
+		return 0
+	case "message":
+		return s.sampleMessage(f.typeName, visiting)
+	default:
+		return nil
+	}
+}
+
+// wellKnownSample returns a sample of the well-known types that
+// have a special representation in the JSON mapping.
+func wellKnownSample(typeName string) (interface{}, bool) {
+	switch typeName {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z", true
+	case "google.protobuf.Duration":
+		return "0s", true
+	case "google.protobuf.FieldMask":
+		return "", true
+	case "google.protobuf.Empty", "google.protobuf.Struct", "google.protobuf.Any":
+		return jsonObject{}, true
+	case "google.protobuf.ListValue":
+		return []interface{}{}, true
+	case "google.protobuf.Value":
+		return "string", true
+	case "google.protobuf.StringValue":
+		return "string", true
+	case "google.protobuf.BytesValue":
+		return "", true
+	case "google.protobuf.BoolValue":
+		return false, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return 0, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return "0", true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return json.Number("0.0"), true
+	}
+	return nil, false
+}
+
+func mustMarshal(value interface{}) []byte {
+	b, err := json.Marshal(value)
+	if err != nil {
+		logger.Fatalf("unable to marshal sample value: %v", err)
+	}
+	return b
+}
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// jsonObject preserves the order in which fields are declared
+// in the message when marshalled.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// toLowerCamelCase converts a protobuf field name, such as 'user_id', to
+// its JSON name, such as 'userId'.
+func toLowerCamelCase(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			sb.WriteString(strings.ToUpper(string(c)))
+			upper = false
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ProfileEmails = "emails"
+	ProfileNames  = "names"
+	ProfilePhones = "phones"
+	ProfileCards  = "cards"
+
+	// ProfileGdpr enables all the predefined profiles.
+	ProfileGdpr = "gdpr"
+)
+
+const anonymisationReportFileName = "anonymisation-report.json"
+
+// profileOrder is the order in which profiles are applied. Card numbers
+// are replaced before phone numbers, so long digit sequences are not
+// mistaken for phone numbers.
+var profileOrder = []string{ProfileNames, ProfileEmails, ProfileCards, ProfilePhones}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	phonePattern = regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d{2,4}){2,4}\b|\(\d{3}\) ?\d{3}-\d{4}\b|\b\d{3}-\d{3}-\d{4}\b`)
+
+	// namePattern matches JSON string properties whose keys commonly hold
+	// the name of a person. Generic keys such as 'name' are not matched,
+	// as they frequently hold other values.
+	namePattern = regexp.MustCompile(`(?i)("(?:first_?name|last_?name|middle_?name|full_?name|given_?name|family_?name|sur_?name|display_?name|customer_?name|account_?holder|card_?holder)"\s*:\s*")((?:[^"\\]|\\.)*)(")`)
+)
+
+// Anonymiser replaces personal data in recorded bodies, according to
+// its profiles, and keeps a report of the replacements made.
+type Anonymiser struct {
+	profiles []string
+	mu       sync.Mutex
+	report   AnonymisationReport
+}
+
+// AnonymisationReport records the number of values anonymised, in total
+// and for each recorded exchange.
+type AnonymisationReport struct {
+	Profiles  []string                 `json:"profiles"`
+	Totals    map[string]int           `json:"totals"`
+	Exchanges []AnonymisedExchangeInfo `json:"exchanges"`
+}
+
+// AnonymisedExchangeInfo identifies an exchange by its method and path.
+// The query is omitted, as its values may hold the personal data that
+// was anonymised.
+type AnonymisedExchangeInfo struct {
+	Method string         `json:"method"`
+	Path   string         `json:"path"`
+	File   string         `json:"file,omitempty"`
+	Counts map[string]int `json:"counts"`
+}
+
+// NewAnonymiser validates the profile names, expanding the 'gdpr' profile,
+// and returns an Anonymiser. If no profiles are given, it returns nil.
+func NewAnonymiser(profiles []string) (*Anonymiser, error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	var enabled []string
+	for _, profile := range profiles {
+		profile = strings.ToLower(strings.TrimSpace(profile))
+		switch profile {
+		case ProfileGdpr:
+			enabled = append(enabled, profileOrder...)
+		case ProfileEmails, ProfileNames, ProfilePhones, ProfileCards:
+			enabled = append(enabled, profile)
+		default:
+			return nil, fmt.Errorf("unsupported anonymisation profile: %s (valid: %s,%s)", profile, strings.Join(profileOrder, ","), ProfileGdpr)
+		}
+	}
+	var ordered []string
+	for _, profile := range profileOrder {
+		if stringutil.Contains(enabled, profile) {
+			ordered = append(ordered, profile)
+		}
+	}
+	return &Anonymiser{
+		profiles: ordered,
+		report: AnonymisationReport{
+			Profiles: ordered,
+			Totals:   make(map[string]int),
+		},
+	}, nil
+}
+
+// Anonymise returns a copy of the body with personal data replaced, and
+// the number of replacements made for each profile. Replacements are
+// derived from the original values, so the same value is always replaced
+// the same way, preserving relationships in the recorded data.
+func (a *Anonymiser) Anonymise(body []byte) ([]byte, map[string]int) {
+	counts := make(map[string]int)
+	text := string(body)
+	for _, profile := range a.profiles {
+		switch profile {
+		case ProfileNames:
+			text = namePattern.ReplaceAllStringFunc(text, func(match string) string {
+				parts := namePattern.FindStringSubmatch(match)
+				if parts[2] == "" {
+					return match
+				}
+				counts[profile]++
+				return parts[1] + "Person " + pseudonym(parts[2]) + parts[3]
+			})
+		case ProfileEmails:
+			text = emailPattern.ReplaceAllStringFunc(text, func(match string) string {
+				counts[profile]++
+				return "user-" + pseudonym(match) + "@example.com"
+			})
+		case ProfileCards:
+			text = cardPattern.ReplaceAllStringFunc(text, func(match string) string {
+				if !isLuhnValid(match) {
+					return match
+				}
+				counts[profile]++
+				return maskDigits(match, 4)
+			})
+		case ProfilePhones:
+			text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+				counts[profile]++
+				return maskDigits(match, 0)
+			})
+		}
+	}
+	return []byte(text), counts
+}
+
+// anonymiseExchange returns a copy of the exchange with personal data
+// replaced in the request query values, the request body and the response
+// body, and the number of replacements made for each profile. The
+// original request is not modified.
+func (a *Anonymiser) anonymiseExchange(exchange HttpExchange) (HttpExchange, map[string]int) {
+	counts := make(map[string]int)
+	addCounts := func(c map[string]int) {
+		for profile, count := range c {
+			counts[profile] += count
+		}
+	}
+	if exchange.Request.URL.RawQuery != "" {
+		rawQuery, c := a.anonymiseQuery(exchange.Request.URL.RawQuery)
+		if len(c) > 0 {
+			req := exchange.Request.Clone(exchange.Request.Context())
+			req.URL.RawQuery = rawQuery
+			req.RequestURI = req.URL.RequestURI()
+			exchange.Request = req
+			addCounts(c)
+		}
+	}
+	if exchange.RequestBody != nil {
+		body, c := a.Anonymise(*exchange.RequestBody)
+		exchange.RequestBody = &body
+		addCounts(c)
+	}
+	if exchange.ResponseBody != nil {
+		body, c := a.Anonymise(*exchange.ResponseBody)
+		exchange.ResponseBody = &body
+		addCounts(c)
+	}
+	return exchange, counts
+}
+
+// anonymiseQuery anonymises the value of each query parameter, keeping
+// the order and encoding of those that are unchanged.
+func (a *Anonymiser) anonymiseQuery(rawQuery string) (string, map[string]int) {
+	counts := make(map[string]int)
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		rawName, rawValue, hasValue := strings.Cut(param, "=")
+		if !hasValue {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue
+		}
+		anonymised, c := a.Anonymise([]byte(value))
+		if len(c) == 0 {
+			continue
+		}
+		params[i] = rawName + "=" + url.QueryEscape(string(anonymised))
+		for profile, count := range c {
+			counts[profile] += count
+		}
+	}
+	return strings.Join(params, "&"), counts
+}
+
+// Record adds the replacements made for an exchange to the report.
+func (a *Anonymiser) Record(exchange HttpExchange, file string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for profile, count := range counts {
+		a.report.Totals[profile] += count
+	}
+	a.report.Exchanges = append(a.report.Exchanges, AnonymisedExchangeInfo{
+		Method: exchange.Request.Method,
+		Path:   exchange.Request.URL.Path,
+		File:   file,
+		Counts: counts,
+	})
+}
+
+// Report returns the report in JSON format.
+func (a *Anonymiser) Report() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sort.SliceStable(a.report.Exchanges, func(i, j int) bool {
+		return a.report.Exchanges[i].Path < a.report.Exchanges[j].Path
+	})
+	return json.MarshalIndent(a.report, "", "  ")
+}
+
+// pseudonym returns a short, stable identifier for the value.
+func pseudonym(value string) string {
+	return stringutil.Sha1hashString(value)[:8]
+}
+
+// maskDigits replaces each digit with zero, except the last keep digits.
+func maskDigits(value string, keep int) string {
+	masked := []byte(value)
+	remaining := 0
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] >= '0' && masked[i] <= '9' {
+			if remaining >= keep {
+				masked[i] = '0'
+			}
+			remaining++
+		}
+	}
+	return string(masked)
+}
+
+// isLuhnValid checks the digits in the value pass the Luhn checksum
+// used by payment card numbers.
+func isLuhnValid(value string) bool {
+	sum := 0
+	double := false
+	digits := 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
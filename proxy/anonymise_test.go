@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnonymiser_Anonymise(t *testing.T) {
+	tests := []struct {
+		name       string
+		profiles   []string
+		body       string
+		want       string
+		wantCounts map[string]int
+	}{
+		{
+			name:       "email",
+			profiles:   []string{ProfileEmails},
+			body:       `{"contact": "jane.doe@example.org"}`,
+			want:       `{"contact": "user-` + pseudonym("jane.doe@example.org") + `@example.com"}`,
+			wantCounts: map[string]int{ProfileEmails: 1},
+		},
+		{
+			name:       "names",
+			profiles:   []string{ProfileNames},
+			body:       `{"firstName": "Jane", "last_name": "Doe", "name": "Widget"}`,
+			want:       `{"firstName": "Person ` + pseudonym("Jane") + `", "last_name": "Person ` + pseudonym("Doe") + `", "name": "Widget"}`,
+			wantCounts: map[string]int{ProfileNames: 2},
+		},
+		{
+			name:       "valid card number",
+			profiles:   []string{ProfileCards},
+			body:       `card 4111 1111 1111 1111 on file`,
+			want:       `card 0000 0000 0000 1111 on file`,
+			wantCounts: map[string]int{ProfileCards: 1},
+		},
+		{
+			name:       "digits failing checksum are not a card number",
+			profiles:   []string{ProfileCards},
+			body:       `order 4111111111111112`,
+			want:       `order 4111111111111112`,
+			wantCounts: map[string]int{},
+		},
+		{
+			name:       "phone number",
+			profiles:   []string{ProfilePhones},
+			body:       `call +44 20 7946 0958 or (555) 123-4567`,
+			want:       `call +00 00 0000 0000 or (000) 000-0000`,
+			wantCounts: map[string]int{ProfilePhones: 2},
+		},
+		{
+			name:       "gdpr enables all profiles",
+			profiles:   []string{ProfileGdpr},
+			body:       `{"fullName": "Jane Doe", "email": "jane@example.org", "card": "4111-1111-1111-1111"}`,
+			want:       `{"fullName": "Person ` + pseudonym("Jane Doe") + `", "email": "user-` + pseudonym("jane@example.org") + `@example.com", "card": "0000-0000-0000-1111"}`,
+			wantCounts: map[string]int{ProfileNames: 1, ProfileEmails: 1, ProfileCards: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnonymiser(tt.profiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, counts := a.Anonymise([]byte(tt.body))
+			if string(got) != tt.want {
+				t.Errorf("Anonymise() got = %v, want %v", string(got), tt.want)
+			}
+			if !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("Anonymise() counts = %v, want %v", counts, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestNewAnonymiser_invalidProfile(t *testing.T) {
+	if _, err := NewAnonymiser([]string{"passwords"}); err == nil {
+		t.Errorf("expected error for unsupported profile")
+	}
+}
+
+func TestAnonymiser_anonymiseExchange(t *testing.T) {
+	a, err := NewAnonymiser([]string{ProfileEmails})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/users?email=jane%40example.org&page=2", nil)
+	reqBody := []byte(`{"email": "jane@example.org"}`)
+	respBody := []byte(`{"id": 1, "email": "jane@example.org"}`)
+	exchange := HttpExchange{
+		Request:      req,
+		RequestBody:  &reqBody,
+		ResponseBody: &respBody,
+	}
+
+	got, counts := a.anonymiseExchange(exchange)
+
+	replacement := "user-" + pseudonym("jane@example.org") + "@example.com"
+	if want := "email=" + url.QueryEscape(replacement) + "&page=2"; got.Request.URL.RawQuery != want {
+		t.Errorf("query = %v, want %v", got.Request.URL.RawQuery, want)
+	}
+	if want := `{"email": "` + replacement + `"}`; string(*got.RequestBody) != want {
+		t.Errorf("request body = %v, want %v", string(*got.RequestBody), want)
+	}
+	if want := `{"id": 1, "email": "` + replacement + `"}`; string(*got.ResponseBody) != want {
+		t.Errorf("response body = %v, want %v", string(*got.ResponseBody), want)
+	}
+	if want := map[string]int{ProfileEmails: 3}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if !strings.Contains(req.URL.RawQuery, "jane") {
+		t.Errorf("original request was modified: %v", req.URL.RawQuery)
+	}
+
+	a.Record(got, "users.json", counts)
+	report, err := a.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(report), "email=") || !strings.Contains(string(report), `"path": "/users"`) {
+		t.Errorf("report should identify the exchange by path only: %s", report)
+	}
+}
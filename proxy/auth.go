@@ -0,0 +1,225 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before it expires an OAuth2 access token
+// is refreshed, so it does not expire while a request is in flight.
+const tokenExpiryMargin = 30 * time.Second
+
+// UpstreamAuth holds the credentials added to requests to the upstream,
+// so clients of the proxy do not need to supply them. At most one of
+// BearerToken, BasicAuth and OAuth2 may be set.
+type UpstreamAuth struct {
+	// Upstream is the URL of the upstream to which the credentials are
+	// sent. Requests to other upstreams, such as those of routes, are
+	// not authenticated.
+	Upstream string
+
+	BearerToken string
+
+	// BasicAuth is in the form 'username:password'
+	BasicAuth string
+
+	// Headers are set on every request to the upstream
+	Headers map[string]string
+
+	OAuth2 OAuth2ClientCredentials
+}
+
+// OAuth2ClientCredentials obtains an access token from the token URL
+// using the OAuth2 client credentials grant. The token is refreshed
+// before it expires, or if the upstream rejects it.
+type OAuth2ClientCredentials struct {
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type authenticator struct {
+	auth     UpstreamAuth
+	upstream *url.URL
+	mutex    sync.Mutex
+	token    string
+	expiry   time.Time
+}
+
+var upstreamAuth *authenticator
+
+// SetUpstreamAuth configures the credentials added to requests to the
+// upstream. It must be called before the proxy starts.
+func SetUpstreamAuth(auth UpstreamAuth) error {
+	a, err := newAuthenticator(auth)
+	if err != nil {
+		return err
+	}
+	upstreamAuth = a
+	return nil
+}
+
+func newAuthenticator(auth UpstreamAuth) (*authenticator, error) {
+	schemes := 0
+	for _, set := range []bool{auth.BearerToken != "", auth.BasicAuth != "", auth.OAuth2.TokenUrl != ""} {
+		if set {
+			schemes++
+		}
+	}
+	if schemes > 1 {
+		return nil, fmt.Errorf("only one of bearer token, basic auth or OAuth2 may be used to authenticate to the upstream")
+	}
+	if auth.BasicAuth != "" && !strings.Contains(auth.BasicAuth, ":") {
+		return nil, fmt.Errorf("upstream basic auth must be in the form 'username:password'")
+	}
+	if auth.OAuth2.TokenUrl != "" && auth.OAuth2.ClientId == "" {
+		return nil, fmt.Errorf("an OAuth2 client ID is required to authenticate to the upstream")
+	}
+	if schemes == 0 && len(auth.Headers) == 0 {
+		return nil, nil
+	}
+	upstream, err := url.Parse(auth.Upstream)
+	if err != nil || upstream.Host == "" {
+		return nil, fmt.Errorf("an upstream URL is required to authenticate to the upstream - credentials are not sent to the upstreams of routes")
+	}
+	return &authenticator{auth: auth, upstream: upstream}, nil
+}
+
+// ParseHeaders parses headers in the form 'Name: value'.
+func ParseHeaders(headers []string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header: %s - must be in the form 'Name: value'", header)
+		}
+		parsed[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return parsed, nil
+}
+
+// appliesTo returns true if the credentials are sent to the URL, which
+// is the case if it has the scheme and host of the configured upstream.
+func (a *authenticator) appliesTo(u *url.URL) bool {
+	return a != nil && strings.EqualFold(u.Scheme, a.upstream.Scheme) && strings.EqualFold(u.Host, a.upstream.Host)
+}
+
+// apply adds the credentials to the request to the upstream, replacing
+// any supplied by the client. It is a no-op if a is nil, or the request
+// is not to the configured upstream.
+func (a *authenticator) apply(req *http.Request) error {
+	if !a.appliesTo(req.URL) {
+		return nil
+	}
+	for name, value := range a.auth.Headers {
+		req.Header.Set(name, value)
+	}
+	if a.auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.auth.BearerToken)
+	} else if a.auth.BasicAuth != "" {
+		username, password, _ := strings.Cut(a.auth.BasicAuth, ":")
+		req.SetBasicAuth(username, password)
+	} else if a.auth.OAuth2.TokenUrl != "" {
+		token, err := a.getToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// invalidate discards the cached OAuth2 access token, such as when the
+// upstream rejects it, so a new one is obtained for the next request.
+func (a *authenticator) invalidate() {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = ""
+}
+
+func (a *authenticator) getToken() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && (a.expiry.IsZero() || time.Now().Before(a.expiry.Add(-tokenExpiryMargin))) {
+		return a.token, nil
+	}
+	token, expiresIn, err := fetchToken(a.auth.OAuth2)
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	if expiresIn > 0 {
+		a.expiry = time.Now().Add(expiresIn)
+	} else {
+		a.expiry = time.Time{}
+	}
+	logger.Debugf("obtained OAuth2 access token for upstream [expires in: %v]", expiresIn)
+	return token, nil
+}
+
+// fetchToken requests an access token using the client credentials grant,
+// authenticating the client with HTTP basic auth.
+func fetchToken(creds OAuth2ClientCredentials) (token string, expiresIn time.Duration, err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(creds.Scopes) > 0 {
+		form.Set("scope", strings.Join(creds.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, creds.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to build OAuth2 token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(creds.ClientId), url.QueryEscape(creds.ClientSecret))
+
+	resp, err := getClient().Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request OAuth2 token from %s: %v", creds.TokenUrl, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read OAuth2 token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("OAuth2 token request to %s failed with status %d: %s", creds.TokenUrl, resp.StatusCode, body)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to parse OAuth2 token response: %v", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("OAuth2 token response from %s did not contain an access token", creds.TokenUrl)
+	}
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
+}
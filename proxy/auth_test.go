@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_authenticator_apply(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       UpstreamAuth
+		url        string
+		wantHeader string
+		want       string
+	}{
+		{
+			name:       "bearer token",
+			auth:       UpstreamAuth{Upstream: "https://api.example.com", BearerToken: "abc"},
+			url:        "https://api.example.com/",
+			wantHeader: "Authorization",
+			want:       "Bearer abc",
+		},
+		{
+			name:       "basic auth",
+			auth:       UpstreamAuth{Upstream: "https://api.example.com", BasicAuth: "user:pass"},
+			url:        "https://api.example.com/",
+			wantHeader: "Authorization",
+			want:       "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:       "custom header",
+			auth:       UpstreamAuth{Upstream: "https://api.example.com", Headers: map[string]string{"X-Api-Key": "key"}},
+			url:        "https://api.example.com/",
+			wantHeader: "X-Api-Key",
+			want:       "key",
+		},
+		{
+			name:       "other upstream is not authenticated",
+			auth:       UpstreamAuth{Upstream: "https://api.example.com", BearerToken: "abc"},
+			url:        "https://other.example.com/",
+			wantHeader: "Authorization",
+			want:       "Bearer from-client",
+		},
+		{
+			name:       "other scheme is not authenticated",
+			auth:       UpstreamAuth{Upstream: "https://api.example.com", BearerToken: "abc"},
+			url:        "http://api.example.com/",
+			wantHeader: "Authorization",
+			want:       "Bearer from-client",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := newAuthenticator(tt.auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("Authorization", "Bearer from-client")
+			if err := a.apply(req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get(tt.wantHeader); got != tt.want {
+				t.Errorf("apply() %s = %v, want %v", tt.wantHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newAuthenticator_invalid(t *testing.T) {
+	invalid := []UpstreamAuth{
+		{BearerToken: "abc", BasicAuth: "user:pass"},
+		{BasicAuth: "user"},
+		{OAuth2: OAuth2ClientCredentials{TokenUrl: "http://localhost/token"}},
+		{BearerToken: "abc"},
+	}
+	for _, auth := range invalid {
+		if _, err := newAuthenticator(auth); err == nil {
+			t.Errorf("expected error for %+v", auth)
+		}
+	}
+}
+
+func Test_authenticator_oauth2(t *testing.T) {
+	tokensIssued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientId, clientSecret, _ := r.BasicAuth()
+		if clientId != "client" || clientSecret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		tokensIssued++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, tokensIssued)
+	}))
+	defer tokenServer.Close()
+
+	a, err := newAuthenticator(UpstreamAuth{Upstream: "https://api.example.com", OAuth2: OAuth2ClientCredentials{
+		TokenUrl:     tokenServer.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorise := func() string {
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+		if err := a.apply(req); err != nil {
+			t.Fatal(err)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	if got := authorise(); got != "Bearer token-1" {
+		t.Errorf("first request Authorization = %v, want Bearer token-1", got)
+	}
+	if got := authorise(); got != "Bearer token-1" {
+		t.Errorf("cached token Authorization = %v, want Bearer token-1", got)
+	}
+
+	a.invalidate()
+	if got := authorise(); got != "Bearer token-2" {
+		t.Errorf("invalidated token Authorization = %v, want Bearer token-2", got)
+	}
+
+	a.expiry = time.Now().Add(tokenExpiryMargin / 2)
+	if got := authorise(); got != "Bearer token-3" {
+		t.Errorf("expiring token Authorization = %v, want Bearer token-3", got)
+	}
+}
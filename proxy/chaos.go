@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChaosOptions injects faults into a percentage of proxied requests, so
+// client resilience can be tested against a real upstream. Each fault is
+// decided independently. Requests receiving an injected error, or whose
+// connection is dropped, are not forwarded to the upstream or recorded.
+type ChaosOptions struct {
+	// DelayMin and DelayMax bound the delay added before forwarding
+	// DelayPercent of requests.
+	DelayMin     time.Duration
+	DelayMax     time.Duration
+	DelayPercent float64
+
+	// ErrorStatus is returned for ErrorPercent of requests.
+	ErrorStatus  int
+	ErrorPercent float64
+
+	// DropPercent of connections are closed without a response.
+	DropPercent float64
+}
+
+// Enabled determines if any faults are injected.
+func (o ChaosOptions) Enabled() bool {
+	return o.DelayPercent > 0 || o.ErrorPercent > 0 || o.DropPercent > 0
+}
+
+// Validate checks the percentages and error status are in range.
+func (o ChaosOptions) Validate() error {
+	percentages := []struct {
+		name    string
+		percent float64
+	}{{"delay", o.DelayPercent}, {"error", o.ErrorPercent}, {"drop", o.DropPercent}}
+	for _, p := range percentages {
+		if p.percent < 0 || p.percent > 100 {
+			return fmt.Errorf("chaos %s percentage must be between 0 and 100: %v", p.name, p.percent)
+		}
+	}
+	if o.ErrorPercent > 0 && (o.ErrorStatus < 400 || o.ErrorStatus > 599) {
+		return fmt.Errorf("chaos error status must be between 400 and 599: %d", o.ErrorStatus)
+	}
+	if o.DelayPercent > 0 && o.DelayMax <= 0 {
+		return fmt.Errorf("a chaos delay must be provided to delay requests")
+	}
+	return nil
+}
+
+// ParseChaosDelay parses a delay, such as '500ms', or a range from which
+// the delay is chosen at random, such as '100ms-2s'.
+func ParseChaosDelay(spec string) (min time.Duration, max time.Duration, err error) {
+	if spec == "" {
+		return 0, 0, nil
+	}
+	minSpec, maxSpec, isRange := strings.Cut(spec, "-")
+	if min, err = time.ParseDuration(minSpec); err != nil {
+		return 0, 0, fmt.Errorf("invalid chaos delay: %s: %v", spec, err)
+	}
+	if !isRange {
+		return min, min, nil
+	}
+	if max, err = time.ParseDuration(maxSpec); err != nil {
+		return 0, 0, fmt.Errorf("invalid chaos delay: %s: %v", spec, err)
+	}
+	if max < min {
+		return 0, 0, fmt.Errorf("invalid chaos delay: %s - maximum is less than minimum", spec)
+	}
+	return min, max, nil
+}
+
+type chaosHandler struct {
+	options ChaosOptions
+	next    http.Handler
+
+	// random returns a number in [0, 1)
+	random func() float64
+	sleep  func(time.Duration)
+}
+
+// WithChaos returns a handler that injects faults, according to the
+// options, before passing requests to next. The status endpoint is not
+// affected.
+func WithChaos(next http.Handler, options ChaosOptions) http.Handler {
+	return &chaosHandler{
+		options: options,
+		next:    next,
+		random:  rand.Float64,
+		sleep:   time.Sleep,
+	}
+}
+
+func (c *chaosHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if isControlPath(req.URL.Path) {
+		c.next.ServeHTTP(w, req)
+		return
+	}
+	if c.inject(c.options.DropPercent) {
+		logger.Infof("chaos: dropping connection for %s %v", req.Method, req.URL)
+		panic(http.ErrAbortHandler)
+	}
+	if c.inject(c.options.ErrorPercent) {
+		logger.Infof("chaos: responding to %s %v with status %d", req.Method, req.URL, c.options.ErrorStatus)
+		http.Error(w, http.StatusText(c.options.ErrorStatus), c.options.ErrorStatus)
+		return
+	}
+	if c.inject(c.options.DelayPercent) {
+		delay := c.options.DelayMin
+		if spread := c.options.DelayMax - c.options.DelayMin; spread > 0 {
+			delay += time.Duration(c.random() * float64(spread))
+		}
+		logger.Infof("chaos: delaying %s %v by %v", req.Method, req.URL, delay)
+		c.sleep(delay)
+	}
+	c.next.ServeHTTP(w, req)
+}
+
+func (c *chaosHandler) inject(percent float64) bool {
+	return percent > 0 && c.random()*100 < percent
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseChaosDelay(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantMin time.Duration
+		wantMax time.Duration
+		wantErr bool
+	}{
+		{spec: "", wantMin: 0, wantMax: 0},
+		{spec: "500ms", wantMin: 500 * time.Millisecond, wantMax: 500 * time.Millisecond},
+		{spec: "100ms-2s", wantMin: 100 * time.Millisecond, wantMax: 2 * time.Second},
+		{spec: "2s-100ms", wantErr: true},
+		{spec: "slow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			gotMin, gotMax, err := ParseChaosDelay(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChaosDelay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("ParseChaosDelay() = %v, %v, want %v, %v", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestChaosOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ChaosOptions
+		wantErr bool
+	}{
+		{name: "disabled", options: ChaosOptions{}},
+		{name: "valid", options: ChaosOptions{ErrorStatus: 503, ErrorPercent: 10, DelayMax: time.Second, DelayPercent: 5}},
+		{name: "percentage out of range", options: ChaosOptions{DropPercent: 120}, wantErr: true},
+		{name: "non-error status", options: ChaosOptions{ErrorStatus: 200, ErrorPercent: 10}, wantErr: true},
+		{name: "delay percent without delay", options: ChaosOptions{DelayPercent: 10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_chaosHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     ChaosOptions
+		random      float64
+		wantStatus  int
+		wantDelay   time.Duration
+		wantForward bool
+		wantAbort   bool
+	}{
+		{
+			name:        "below threshold is forwarded",
+			options:     ChaosOptions{ErrorStatus: 503, ErrorPercent: 10},
+			random:      0.5,
+			wantStatus:  http.StatusOK,
+			wantForward: true,
+		},
+		{
+			name:       "error injected",
+			options:    ChaosOptions{ErrorStatus: 503, ErrorPercent: 10},
+			random:     0.05,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:        "delay injected",
+			options:     ChaosOptions{DelayMin: 100 * time.Millisecond, DelayMax: 300 * time.Millisecond, DelayPercent: 10},
+			random:      0.05,
+			wantStatus:  http.StatusOK,
+			wantDelay:   110 * time.Millisecond,
+			wantForward: true,
+		},
+		{
+			name:      "connection dropped",
+			options:   ChaosOptions{DropPercent: 10},
+			random:    0.05,
+			wantAbort: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded := false
+			var delay time.Duration
+			c := &chaosHandler{
+				options: tt.options,
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					forwarded = true
+				}),
+				random: func() float64 { return tt.random },
+				sleep:  func(d time.Duration) { delay = d },
+			}
+			w := httptest.NewRecorder()
+			aborted := func() (aborted bool) {
+				defer func() {
+					aborted = recover() == http.ErrAbortHandler
+				}()
+				c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				return false
+			}()
+			if aborted != tt.wantAbort {
+				t.Fatalf("aborted = %v, want %v", aborted, tt.wantAbort)
+			}
+			if tt.wantAbort {
+				return
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if forwarded != tt.wantForward {
+				t.Errorf("forwarded = %v, want %v", forwarded, tt.wantForward)
+			}
+			if delay != tt.wantDelay {
+				t.Errorf("delay = %v, want %v", delay, tt.wantDelay)
+			}
+		})
+	}
+}
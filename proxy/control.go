@@ -0,0 +1,221 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// statusPath is the path of the proxy status endpoint.
+const statusPath = "/system/status"
+
+// recordingControlPath is the path of the API controlling the recording.
+// A GET request returns the recording state, and a POST request to
+// '/pause', '/resume' or '/rotate' beneath it changes the state.
+const recordingControlPath = "/system/recording"
+
+// recordingControl holds whether recording is paused, and the number of
+// the current recording, which is incremented each time it is rotated.
+type recordingControl struct {
+	mutex    sync.Mutex
+	paused   bool
+	rotation int
+}
+
+// RecordingState is the response of the recording control API.
+type RecordingState struct {
+	Paused   bool `json:"paused"`
+	Rotation int  `json:"rotation"`
+}
+
+var control = &recordingControl{rotation: 1}
+
+// PauseRecording stops exchanges being recorded until recording is
+// resumed. Requests are still proxied to the upstream.
+func PauseRecording() {
+	control.setPaused(true)
+}
+
+func (c *recordingControl) setPaused(paused bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.paused = paused
+}
+
+// rotate starts a new recording, returning its number.
+func (c *recordingControl) rotate() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rotation++
+	return c.rotation
+}
+
+func (c *recordingControl) state() RecordingState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return RecordingState{Paused: c.paused, Rotation: c.rotation}
+}
+
+// isControlPath returns true if the path is served by the proxy itself,
+// rather than forwarded to the upstream.
+func isControlPath(path string) bool {
+	return path == statusPath || path == ExchangeEventsPath || path == recordingControlPath || strings.HasPrefix(path, recordingControlPath+"/")
+}
+
+// controlToken is the bearer token required by the endpoints wrapped with
+// requireControlAccess. If empty, they are only served to clients on the
+// local machine.
+var controlToken string
+
+// SetControlToken sets the bearer token clients must present to stream
+// exchanges from the proxy, or to control the recording. If empty, only
+// clients on the local machine may do so. It must be called before the
+// proxy starts.
+func SetControlToken(token string) {
+	controlToken = token
+}
+
+// requireControlAccess wraps the handler, so it is only served to clients
+// presenting the control token, or if none is set, to clients on the
+// local machine.
+func requireControlAccess(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if controlToken != "" {
+			token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(token), []byte(controlToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		} else if !isLoopbackClient(req) {
+			logger.Warnf("refused request to %s from %s - set a control token to allow access from other hosts", req.URL.Path, req.RemoteAddr)
+			http.Error(w, "only available to clients on the local machine", http.StatusForbidden)
+			return
+		}
+		handler(w, req)
+	}
+}
+
+func isLoopbackClient(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleRecordingControl serves the recording control API. It should be
+// wrapped with requireControlAccess, so other clients cannot change the
+// recording.
+func handleRecordingControl(w http.ResponseWriter, req *http.Request) {
+	action := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, recordingControlPath), "/")
+	if action == "" {
+		if req.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+	} else {
+		if req.Method != http.MethodPost {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		switch action {
+		case "pause":
+			control.setPaused(true)
+			logger.Infof("recording paused")
+		case "resume":
+			control.setPaused(false)
+			logger.Infof("recording resumed")
+		case "rotate":
+			rotation := control.rotate()
+			logger.Infof("rotated recording - subsequent exchanges are recorded to recording %d", rotation)
+		default:
+			http.NotFound(w, req)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(control.state())
+}
+
+// rotatingRecorder records exchanges unless recording is paused. When the
+// recording is rotated, it starts a new recorder writing to a directory
+// named after the original, with the recording number as a suffix, such
+// as 'recordings-2'.
+type rotatingRecorder struct {
+	upstream string
+	dir      string
+	options  RecorderOptions
+	control  *recordingControl
+	recorder *recorder
+	rotation int
+}
+
+func newRotatingRecorder(upstream string, dir string, options RecorderOptions, control *recordingControl) (*rotatingRecorder, error) {
+	r, err := newRecorder(upstream, dir, options)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatingRecorder{
+		upstream: upstream,
+		dir:      dir,
+		options:  options,
+		control:  control,
+		recorder: r,
+		rotation: control.state().Rotation,
+	}, nil
+}
+
+func (r *rotatingRecorder) record(exchange HttpExchange) error {
+	state := r.control.state()
+	if state.Paused {
+		logger.Debugf("skipping recording of %s %v - recording is paused", exchange.Request.Method, exchange.Request.URL)
+		return nil
+	}
+	if state.Rotation != r.rotation {
+		r.rotation = state.Rotation
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	return r.recorder.recordExchange(exchange)
+}
+
+func (r *rotatingRecorder) rotate() error {
+	rotatedDir := fmt.Sprintf("%s-%d", r.dir, r.rotation)
+	if err := os.MkdirAll(rotatedDir, 0700); err != nil {
+		return fmt.Errorf("failed to create recording directory: %s: %v", rotatedDir, err)
+	}
+	next, err := newRecorder(r.upstream, rotatedDir, r.options)
+	if err != nil {
+		return fmt.Errorf("failed to rotate recording to %s: %v", rotatedDir, err)
+	}
+	if err := r.recorder.writeDocuments(); err != nil {
+		logger.Warn(err)
+	}
+	r.recorder = next
+	logger.Infof("recording exchanges with %s to %s", r.upstream, rotatedDir)
+	return nil
+}
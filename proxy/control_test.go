@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_handleRecordingControl(t *testing.T) {
+	previous := control
+	defer func() { control = previous }()
+	control = &recordingControl{rotation: 1}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantState  RecordingState
+	}{
+		{name: "get state", method: http.MethodGet, path: "/system/recording", wantStatus: http.StatusOK, wantState: RecordingState{Rotation: 1}},
+		{name: "pause", method: http.MethodPost, path: "/system/recording/pause", wantStatus: http.StatusOK, wantState: RecordingState{Paused: true, Rotation: 1}},
+		{name: "rotate", method: http.MethodPost, path: "/system/recording/rotate", wantStatus: http.StatusOK, wantState: RecordingState{Paused: true, Rotation: 2}},
+		{name: "resume", method: http.MethodPost, path: "/system/recording/resume", wantStatus: http.StatusOK, wantState: RecordingState{Rotation: 2}},
+		{name: "unknown action", method: http.MethodPost, path: "/system/recording/stop", wantStatus: http.StatusNotFound},
+		{name: "action requires POST", method: http.MethodGet, path: "/system/recording/pause", wantStatus: http.StatusMethodNotAllowed},
+		{name: "state requires GET", method: http.MethodPost, path: "/system/recording", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleRecordingControl(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got RecordingState
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantState {
+				t.Errorf("state = %+v, want %+v", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func Test_requireControlAccess(t *testing.T) {
+	previous := controlToken
+	defer func() { controlToken = previous }()
+
+	tests := []struct {
+		name         string
+		controlToken string
+		remoteAddr   string
+		auth         string
+		wantStatus   int
+	}{
+		{name: "local client without token", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusOK},
+		{name: "local IPv6 client without token", remoteAddr: "[::1]:5000", wantStatus: http.StatusOK},
+		{name: "remote client without token", remoteAddr: "192.0.2.1:5000", wantStatus: http.StatusForbidden},
+		{name: "token presented", controlToken: "s3cret", remoteAddr: "192.0.2.1:5000", auth: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "wrong token", controlToken: "s3cret", remoteAddr: "192.0.2.1:5000", auth: "Bearer other", wantStatus: http.StatusUnauthorized},
+		{name: "token required from local client", controlToken: "s3cret", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlToken = tt.controlToken
+			handler := requireControlAccess(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, ExchangeEventsPath, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBuildRecordingMux_recordingControlAccess(t *testing.T) {
+	previous := control
+	defer func() { control = previous }()
+	control = &recordingControl{rotation: 1}
+
+	mux := BuildRecordingMux("https://example.com", "http://localhost:8080", false, DefaultMaxRecordBodySize, nil)
+	req := httptest.NewRequest(http.MethodPost, "/system/recording/pause", nil)
+	req.RemoteAddr = "192.0.2.1:5000"
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusForbidden)
+	}
+	if control.state().Paused {
+		t.Errorf("recording paused by remote client")
+	}
+}
+
+func Test_rotatingRecorder_record(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	c := &recordingControl{rotation: 1}
+	r, err := newRotatingRecorder("https://example.com", dir, RecorderOptions{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(path string) {
+		respBody := []byte("ok")
+		err := r.record(HttpExchange{
+			Request:         httptest.NewRequest(http.MethodGet, path, nil),
+			RequestBody:     &[]byte{},
+			StatusCode:      http.StatusOK,
+			ResponseBody:    &respBody,
+			ResponseHeaders: &http.Header{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.setPaused(true)
+	record("/paused")
+	c.setPaused(false)
+	record("/resumed")
+	c.rotate()
+	record("/rotated")
+
+	config := readRecordedConfig(t, filepath.Join(dir, "example.com-config.yaml"))
+	if strings.Contains(config, "/paused") {
+		t.Errorf("exchange recorded while paused")
+	}
+	if !strings.Contains(config, "/resumed") || strings.Contains(config, "/rotated") {
+		t.Errorf("unexpected first recording: %s", config)
+	}
+	rotated := readRecordedConfig(t, filepath.Join(dir+"-2", "example.com-config.yaml"))
+	if !strings.Contains(rotated, "/rotated") || strings.Contains(rotated, "/resumed") {
+		t.Errorf("unexpected rotated recording: %s", rotated)
+	}
+}
+
+func readRecordedConfig(t *testing.T, file string) string {
+	config, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read recorded config: %v", err)
+	}
+	return string(config)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ExchangeEventsPath is the path of the endpoint streaming each proxied
+// exchange as a server-sent event, such as for 'imposter inspect'.
+const ExchangeEventsPath = "/system/exchanges"
+
+// exchangeEventBuffer is the number of events held for each subscriber
+// before further events are dropped.
+const exchangeEventBuffer = 100
+
+// ExchangeEvent describes a proxied exchange, as sent to subscribers
+// of the exchange events endpoint.
+type ExchangeEvent struct {
+	Time           time.Time `json:"time"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Query          string    `json:"query,omitempty"`
+	StatusCode     int       `json:"statusCode"`
+	DurationMillis float64   `json:"durationMillis"`
+
+	// Url is the URL of the request at the upstream, whereas Path and
+	// Query are those of the request received by the proxy.
+	Url string `json:"url"`
+
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     []byte      `json:"requestBody,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    []byte      `json:"responseBody,omitempty"`
+	TraceId         string      `json:"traceId,omitempty"`
+}
+
+// exchangeHub sends exchanges to the subscribers of the events endpoint.
+type exchangeHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan ExchangeEvent]struct{}
+}
+
+var exchangeEvents = &exchangeHub{subscribers: make(map[chan ExchangeEvent]struct{})}
+
+func (h *exchangeHub) subscribe() chan ExchangeEvent {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	c := make(chan ExchangeEvent, exchangeEventBuffer)
+	h.subscribers[c] = struct{}{}
+	return c
+}
+
+func (h *exchangeHub) unsubscribe(c chan ExchangeEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.subscribers, c)
+}
+
+// publish sends the exchange to each subscriber, after applying the
+// redaction rules, so subscribers see the same values as the recording.
+// It does not block, so a slow subscriber misses events, rather than
+// delaying the proxy.
+func (h *exchangeHub) publish(upstream string, exchange HttpExchange, redactor *redactor) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.subscribers) == 0 {
+		return
+	}
+	event := buildExchangeEvent(upstream, redactor.redact(exchange))
+	for c := range h.subscribers {
+		select {
+		case c <- event:
+		default:
+			logger.Tracef("dropped exchange event for slow subscriber")
+		}
+	}
+}
+
+func buildExchangeEvent(upstream string, exchange HttpExchange) ExchangeEvent {
+	req := exchange.Request
+	event := ExchangeEvent{
+		Time:           exchange.StartedDateTime,
+		Method:         req.Method,
+		Url:            buildUpstreamUrl(upstream, req.URL),
+		Path:           req.URL.Path,
+		Query:          req.URL.RawQuery,
+		StatusCode:     exchange.StatusCode,
+		DurationMillis: float64(exchange.Duration) / float64(time.Millisecond),
+		RequestHeaders: req.Header,
+		TraceId:        exchange.TraceId,
+	}
+	if exchange.RequestBody != nil {
+		event.RequestBody = *exchange.RequestBody
+	}
+	if exchange.ResponseHeaders != nil {
+		event.ResponseHeaders = *exchange.ResponseHeaders
+	}
+	if exchange.ResponseBody != nil {
+		event.ResponseBody = *exchange.ResponseBody
+	}
+	return event
+}
+
+// handleExchangeEvents streams exchanges to the client as server-sent
+// events, until the client disconnects. It should be wrapped with
+// requireControlAccess, as the exchanges may hold sensitive data.
+func handleExchangeEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	c := exchangeEvents.subscribe()
+	defer exchangeEvents.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	logger.Debugf("client %v subscribed to exchange events", req.RemoteAddr)
+
+	for {
+		select {
+		case <-req.Context().Done():
+			logger.Debugf("client %v unsubscribed from exchange events", req.RemoteAddr)
+			return
+		case event := <-c:
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Warnf("failed to marshal exchange event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_handleExchangeEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(handleExchangeEvents))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("content type = %s, want text/event-stream", contentType)
+	}
+
+	redactor, err := newRedactor(RedactionRules{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/orders?page=2&token=secret", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// the subscription is registered before the headers are sent
+	respBody := []byte("ok")
+	exchangeEvents.publish("https://example.com/api", HttpExchange{
+		Request:         req,
+		RequestBody:     &[]byte{},
+		StatusCode:      http.StatusOK,
+		ResponseBody:    &respBody,
+		ResponseHeaders: &http.Header{"Content-Type": {"text/plain"}},
+		Duration:        5 * time.Millisecond,
+	}, redactor)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event ExchangeEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &event); err != nil {
+		t.Fatalf("failed to parse event %q: %v", line, err)
+	}
+	if event.Method != http.MethodGet || event.Path != "/orders" || event.Query != "page=2&token=REDACTED" || event.Url != "https://example.com/api/orders?page=2&token=REDACTED" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.StatusCode != http.StatusOK || string(event.ResponseBody) != "ok" || event.DurationMillis != 5 {
+		t.Errorf("unexpected event response: %+v", event)
+	}
+	if auth := event.RequestHeaders.Get("Authorization"); auth != RedactedValue {
+		t.Errorf("Authorization header = %q, want it redacted", auth)
+	}
+}
@@ -86,6 +86,20 @@ func generateRespFileName(
 	return respFile, nil
 }
 
+// contentAddressedDir is the directory, within the recording directory, in
+// which content addressed response files are stored.
+const contentAddressedDir = "responses"
+
+// generateContentAddressedFileName returns the filename for a response body
+// with the given hash. The extension is chosen from the response headers.
+func generateContentAddressedFileName(dir string, exchange HttpExchange, bodyHash string) (string, error) {
+	parentDir := path.Join(dir, contentAddressedDir)
+	if err := ensureDirExists(parentDir); err != nil {
+		return "", err
+	}
+	return path.Join(parentDir, bodyHash+getFileExtension(exchange.ResponseHeaders)), nil
+}
+
 func getFileExtension(respHeaders *http.Header) string {
 	if contentDisp := respHeaders.Get("Content-Disposition"); contentDisp != "" {
 		directives := strings.Split(contentDisp, ";")
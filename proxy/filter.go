@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"gatehill.io/imposter/stringutil"
+	"regexp"
+	"strings"
+)
+
+// recordFilter determines which exchanges are recorded, based on the
+// request path and method.
+type recordFilter struct {
+	recordPaths   []*regexp.Regexp
+	ignorePaths   []*regexp.Regexp
+	recordMethods []string
+	ignoreMethods []string
+}
+
+func newRecordFilter(options RecorderOptions) (*recordFilter, error) {
+	recordPaths, err := stringutil.CompilePathPatterns(options.RecordPaths)
+	if err != nil {
+		return nil, err
+	}
+	ignorePaths, err := stringutil.CompilePathPatterns(options.IgnorePaths)
+	if err != nil {
+		return nil, err
+	}
+	return &recordFilter{
+		recordPaths:   recordPaths,
+		ignorePaths:   ignorePaths,
+		recordMethods: options.RecordMethods,
+		ignoreMethods: options.IgnoreMethods,
+	}, nil
+}
+
+// matches determines if an exchange with the method and path should be
+// recorded. If any record patterns are set, the exchange must match one
+// of them, and it must not match any ignore pattern.
+func (f *recordFilter) matches(method string, path string) bool {
+	if len(f.recordMethods) > 0 && !containsFold(f.recordMethods, method) {
+		return false
+	}
+	if containsFold(f.ignoreMethods, method) {
+		return false
+	}
+	if len(f.recordPaths) > 0 && !stringutil.MatchesAny(f.recordPaths, path) {
+		return false
+	}
+	return !stringutil.MatchesAny(f.ignorePaths, path)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import "testing"
+
+func Test_recordFilter_matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		options RecorderOptions
+		method  string
+		path    string
+		want    bool
+	}{
+		{name: "no filters", method: "GET", path: "/anything", want: true},
+		{name: "record glob match", options: RecorderOptions{RecordPaths: []string{"/api/*"}}, method: "GET", path: "/api/pets", want: true},
+		{name: "record glob within segment", options: RecorderOptions{RecordPaths: []string{"/api/*"}}, method: "GET", path: "/api/pets/1", want: false},
+		{name: "record double star glob", options: RecorderOptions{RecordPaths: []string{"/api/**"}}, method: "GET", path: "/api/pets/1", want: true},
+		{name: "ignore glob", options: RecorderOptions{IgnorePaths: []string{"/static/**", "/health"}}, method: "GET", path: "/static/app.js", want: false},
+		{name: "ignore regex", options: RecorderOptions{IgnorePaths: []string{`regex:\.(css|png)$`}}, method: "GET", path: "/img/logo.png", want: false},
+		{name: "ignore takes precedence", options: RecorderOptions{RecordPaths: []string{"/api/**"}, IgnorePaths: []string{"/api/health"}}, method: "GET", path: "/api/health", want: false},
+		{name: "record method", options: RecorderOptions{RecordMethods: []string{"post"}}, method: "POST", path: "/pets", want: true},
+		{name: "record method mismatch", options: RecorderOptions{RecordMethods: []string{"POST"}}, method: "GET", path: "/pets", want: false},
+		{name: "ignore method", options: RecorderOptions{IgnoreMethods: []string{"OPTIONS"}}, method: "OPTIONS", path: "/pets", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newRecordFilter(tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.matches(tt.method, tt.path); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newRecordFilter_invalidRegex(t *testing.T) {
+	if _, err := newRecordFilter(RecorderOptions{RecordPaths: []string{"regex:("}}); err == nil {
+		t.Errorf("newRecordFilter() should reject invalid regex")
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/protobuf"
+	"io"
+	"strings"
+)
+
+// grpcContentType is the prefix of the content type of gRPC requests,
+// such as 'application/grpc' or 'application/grpc+proto'.
+const grpcContentType = "application/grpc"
+
+// grpcDecoder decodes the protobuf messages in gRPC exchanges to JSON, so
+// they are recorded in a readable form. Message types are read from
+// descriptor sets, such as those generated by
+// 'protoc --include_imports --descriptor_set_out'.
+type grpcDecoder struct {
+	schema *protobuf.Schema
+}
+
+// newGrpcDecoder loads the message types from the descriptor set files.
+// If there are no files, it returns nil.
+func newGrpcDecoder(descriptorFiles []string) (*grpcDecoder, error) {
+	if len(descriptorFiles) == 0 {
+		return nil, nil
+	}
+	schema, err := protobuf.LoadDescriptorSets(descriptorFiles)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("loaded %d gRPC services from protobuf descriptors", len(schema.Services))
+	return &grpcDecoder{schema: schema}, nil
+}
+
+// decode returns the exchange with the gRPC messages in its request and
+// response bodies decoded to JSON. The exchange is returned unchanged if
+// it is not a gRPC exchange, or its method is not in the descriptors. It
+// is safe to call if d is nil.
+func (d *grpcDecoder) decode(exchange HttpExchange) HttpExchange {
+	if d == nil || !isGrpc(exchange.Request.Header.Get("Content-Type")) {
+		return exchange
+	}
+	path := exchange.Request.URL.Path
+	method, found := d.schema.FindMethod(path)
+	if !found {
+		logger.Debugf("no protobuf descriptor for gRPC method %s - recording messages as received", path)
+		return exchange
+	}
+	var requestBody, responseBody []byte
+	var err error
+	if exchange.RequestBody != nil {
+		if requestBody, err = d.decodeMessages(method.InputType, *exchange.RequestBody, exchange.Request.Header.Get("Grpc-Encoding")); err != nil {
+			logger.Warnf("failed to decode gRPC request to %s: %v", path, err)
+			return exchange
+		}
+	}
+	if exchange.ResponseBody != nil && exchange.ResponseHeaders != nil {
+		if responseBody, err = d.decodeMessages(method.OutputType, *exchange.ResponseBody, exchange.ResponseHeaders.Get("Grpc-Encoding")); err != nil {
+			logger.Warnf("failed to decode gRPC response from %s: %v", path, err)
+			return exchange
+		}
+	}
+
+	req := exchange.Request.Clone(exchange.Request.Context())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Del("Grpc-Encoding")
+	exchange.Request = req
+	if requestBody != nil {
+		exchange.RequestBody = &requestBody
+	}
+	if exchange.ResponseHeaders != nil {
+		respHeaders := exchange.ResponseHeaders.Clone()
+		respHeaders.Set("Content-Type", "application/json")
+		respHeaders.Del("Grpc-Encoding")
+		exchange.ResponseHeaders = &respHeaders
+	}
+	if responseBody != nil {
+		exchange.ResponseBody = &responseBody
+	}
+	return exchange
+}
+
+func isGrpc(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(contentType), grpcContentType)
+}
+
+// decodeMessages decodes the length-prefixed messages in a gRPC body. A
+// single message is decoded to a JSON object, and a stream of messages
+// to an array.
+func (d *grpcDecoder) decodeMessages(typeName string, body []byte, encoding string) ([]byte, error) {
+	var messages []interface{}
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, fmt.Errorf("incomplete gRPC message prefix")
+		}
+		compressed := body[0] == 1
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint64(len(body)-5) < uint64(length) {
+			return nil, fmt.Errorf("incomplete gRPC message: expected %d bytes, got %d", length, len(body)-5)
+		}
+		payload := body[5 : 5+length]
+		body = body[5+length:]
+
+		if compressed {
+			if encoding != "gzip" {
+				return nil, fmt.Errorf("unsupported gRPC message encoding: %s", encoding)
+			}
+			reader, err := gzip.NewReader(bytes.NewReader(payload))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress gRPC message: %v", err)
+			}
+			if payload, err = io.ReadAll(reader); err != nil {
+				return nil, fmt.Errorf("failed to decompress gRPC message: %v", err)
+			}
+		}
+		message, err := d.schema.DecodeMessage(typeName, payload)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	switch len(messages) {
+	case 0:
+		return []byte{}, nil
+	case 1:
+		return json.MarshalIndent(messages[0], "", "  ")
+	default:
+		return json.MarshalIndent(messages, "", "  ")
+	}
+}
@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func protoVarintField(field int, value uint64) []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(nil, uint64(field<<3)), value)
+}
+
+func protoBytesField(field int, value []byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(field<<3|2))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func kindStringField(field int, value string) []byte {
+	return protoBytesField(field, []byte(value))
+}
+
+func joinProto(parts ...[]byte) []byte {
+	var buf []byte
+	for _, part := range parts {
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+// field types and labels, as defined in descriptor.proto
+const (
+	kindInt64     = 3
+	kindInt32     = 5
+	kindString    = 9
+	kindMessage   = 11
+	kindEnum      = 14
+	kindSint32    = 17
+	labelOptional = 1
+	labelRepeated = 3
+)
+
+// fieldDescriptor encodes a FieldDescriptorProto
+func fieldDescriptor(name string, number int, label int, kind int, typeName string) []byte {
+	buf := joinProto(
+		kindStringField(1, name),
+		protoVarintField(3, uint64(number)),
+		protoVarintField(4, uint64(label)),
+		protoVarintField(5, uint64(kind)),
+	)
+	if typeName != "" {
+		buf = append(buf, kindStringField(6, typeName)...)
+	}
+	return buf
+}
+
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// writeTestDescriptorSet writes a descriptor set equivalent to:
+//
+//	package greet;
+//	enum Kind { UNKNOWN = 0; FRIEND = 1; }
+//	message HelloRequest {
+//	  string name = 1;
+//	  int64 user_id = 2;
+//	  repeated int32 tags = 3;
+//	  Kind kind = 4;
+//	  map<string, string> labels = 5;
+//	}
+//	message HelloReply { string message = 1; sint32 score = 2; }
+//	service Greeter { rpc SayHello (HelloRequest) returns (stream HelloReply); }
+func writeTestDescriptorSet(t *testing.T) string {
+	labelsEntry := joinProto(
+		kindStringField(1, "LabelsEntry"),
+		protoBytesField(2, fieldDescriptor("key", 1, labelOptional, kindString, "")),
+		protoBytesField(2, fieldDescriptor("value", 2, labelOptional, kindString, "")),
+		protoBytesField(7, protoVarintField(7, 1)),
+	)
+	helloRequest := joinProto(
+		kindStringField(1, "HelloRequest"),
+		protoBytesField(2, fieldDescriptor("name", 1, labelOptional, kindString, "")),
+		protoBytesField(2, fieldDescriptor("user_id", 2, labelOptional, kindInt64, "")),
+		protoBytesField(2, fieldDescriptor("tags", 3, labelRepeated, kindInt32, "")),
+		protoBytesField(2, fieldDescriptor("kind", 4, labelOptional, kindEnum, ".greet.Kind")),
+		protoBytesField(2, fieldDescriptor("labels", 5, labelRepeated, kindMessage, ".greet.HelloRequest.LabelsEntry")),
+		protoBytesField(3, labelsEntry),
+	)
+	helloReply := joinProto(
+		kindStringField(1, "HelloReply"),
+		protoBytesField(2, fieldDescriptor("message", 1, labelOptional, kindString, "")),
+		protoBytesField(2, fieldDescriptor("score", 2, labelOptional, kindSint32, "")),
+	)
+	kind := joinProto(
+		kindStringField(1, "Kind"),
+		protoBytesField(2, joinProto(kindStringField(1, "UNKNOWN"), protoVarintField(2, 0))),
+		protoBytesField(2, joinProto(kindStringField(1, "FRIEND"), protoVarintField(2, 1))),
+	)
+	greeter := joinProto(
+		kindStringField(1, "Greeter"),
+		protoBytesField(2, joinProto(
+			kindStringField(1, "SayHello"),
+			kindStringField(2, ".greet.HelloRequest"),
+			kindStringField(3, ".greet.HelloReply"),
+		)),
+	)
+	file := joinProto(
+		kindStringField(1, "greet.proto"),
+		kindStringField(2, "greet"),
+		protoBytesField(4, helloRequest),
+		protoBytesField(4, helloReply),
+		protoBytesField(5, kind),
+		protoBytesField(6, greeter),
+	)
+	descriptorFile := filepath.Join(t.TempDir(), "greet.pb")
+	if err := os.WriteFile(descriptorFile, protoBytesField(1, file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return descriptorFile
+}
+
+func Test_grpcDecoder_decode(t *testing.T) {
+	decoder, err := newGrpcDecoder([]string{writeTestDescriptorSet(t)})
+	if err != nil {
+		t.Fatalf("newGrpcDecoder() error = %v", err)
+	}
+
+	request := joinProto(
+		kindStringField(1, "Ada"),
+		protoVarintField(2, 12345678901),
+		protoBytesField(3, []byte{1, 2, 150, 1}),
+		protoVarintField(4, 1),
+		protoBytesField(5, joinProto(kindStringField(1, "team"), kindStringField(2, "core"))),
+		protoVarintField(9, 7),
+	)
+	replies := joinProto(
+		grpcFrame(joinProto(kindStringField(1, "Hello"), protoVarintField(2, 3))),
+		grpcFrame(kindStringField(1, "Goodbye")),
+	)
+
+	tests := []struct {
+		name             string
+		decoder          *grpcDecoder
+		path             string
+		contentType      string
+		wantRequestBody  string
+		wantResponseBody string
+	}{
+		{
+			name:             "decodes request and streamed responses",
+			decoder:          decoder,
+			path:             "/greet.Greeter/SayHello",
+			contentType:      "application/grpc",
+			wantRequestBody:  `{"name":"Ada","userId":"12345678901","tags":[1,2,150],"kind":"FRIEND","labels":{"team":"core"},"9":"7"}`,
+			wantResponseBody: `[{"message":"Hello","score":-2},{"message":"Goodbye"}]`,
+		},
+		{
+			name:        "unknown method",
+			decoder:     decoder,
+			path:        "/greet.Greeter/Unknown",
+			contentType: "application/grpc",
+		},
+		{
+			name:        "not gRPC",
+			decoder:     decoder,
+			path:        "/greet.Greeter/SayHello",
+			contentType: "application/json",
+		},
+		{
+			name:        "no descriptors",
+			decoder:     nil,
+			path:        "/greet.Greeter/SayHello",
+			contentType: "application/grpc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("Content-Type", tt.contentType)
+			requestBody := grpcFrame(request)
+			respHeaders := http.Header{"Content-Type": []string{tt.contentType}}
+			exchange := HttpExchange{
+				Request:         req,
+				RequestBody:     &requestBody,
+				StatusCode:      http.StatusOK,
+				ResponseHeaders: &respHeaders,
+				ResponseBody:    &replies,
+			}
+
+			got := tt.decoder.decode(exchange)
+
+			if tt.wantRequestBody == "" {
+				if !reflect.DeepEqual(*got.RequestBody, requestBody) || !reflect.DeepEqual(*got.ResponseBody, replies) {
+					t.Errorf("decode() modified an exchange it should not decode")
+				}
+				return
+			}
+			assertJsonEqual(t, "request body", tt.wantRequestBody, *got.RequestBody)
+			assertJsonEqual(t, "response body", tt.wantResponseBody, *got.ResponseBody)
+			if contentType := got.ResponseHeaders.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("response Content-Type = %v, want application/json", contentType)
+			}
+			if contentType := req.Header.Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("original request Content-Type was modified: %v", contentType)
+			}
+		})
+	}
+}
+
+func Test_grpcDecoder_decodeMessages(t *testing.T) {
+	decoder, err := newGrpcDecoder([]string{writeTestDescriptorSet(t)})
+	if err != nil {
+		t.Fatalf("newGrpcDecoder() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "empty body", body: nil, want: ""},
+		{name: "incomplete prefix", body: []byte{0, 0, 0}, wantErr: true},
+		{name: "incomplete message", body: []byte{0, 0, 0, 0, 10, 1}, wantErr: true},
+		{name: "unsupported compression", body: []byte{1, 0, 0, 0, 0}, wantErr: true},
+		{name: "invalid wire type", body: grpcFrame(protoVarintField(1, 1)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decoder.decodeMessages("greet.HelloReply", tt.body, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeMessages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("decodeMessages() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func assertJsonEqual(t *testing.T, description string, want string, got []byte) {
+	var wantValue, gotValue interface{}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("%s is not valid JSON: %v: %s", description, err, got)
+	}
+	if !reflect.DeepEqual(wantValue, gotValue) {
+		t.Errorf("%s = %s, want %s", description, got, want)
+	}
+}
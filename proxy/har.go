@@ -0,0 +1,261 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/fileutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	OutputFormatImposter = "imposter"
+	OutputFormatHar      = "har"
+	OutputFormatOpenapi  = "openapi"
+	OutputFormatPact     = "pact"
+)
+
+// harLog accumulates recorded exchanges in HAR 1.2 format.
+// See http://www.softwareishard.com/blog/har-12-spec/
+type harLog struct {
+	mu       sync.Mutex
+	upstream string
+	entries  []harEntry
+}
+
+type harFile struct {
+	Log harLogContent `json:"log"`
+}
+
+type harLogContent struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	// TraceId is a custom field, so entries can be correlated
+	// with distributed traces
+	TraceId string `json:"_traceId,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectUrl string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// parseOutputFormats validates the output formats, defaulting
+// to Imposter configuration if none are specified.
+func parseOutputFormats(formats []string) ([]string, error) {
+	if len(formats) == 0 {
+		return []string{OutputFormatImposter}, nil
+	}
+	var parsed []string
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case OutputFormatImposter, OutputFormatHar, OutputFormatOpenapi, OutputFormatPact:
+			parsed = append(parsed, format)
+		default:
+			return nil, fmt.Errorf("unsupported output format: %s (valid: %s,%s,%s,%s)", format, OutputFormatImposter, OutputFormatHar, OutputFormatOpenapi, OutputFormatPact)
+		}
+	}
+	return parsed, nil
+}
+
+func newHarLog(upstream string) *harLog {
+	return &harLog{upstream: upstream}
+}
+
+func (h *harLog) add(exchange HttpExchange) {
+	entry := buildHarEntry(h.upstream, exchange)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+}
+
+// write stages the complete HAR file, including all entries so far.
+func (h *harLog) write(tx *fileutil.Transaction, harFilePath string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	har := harFile{
+		Log: harLogContent{
+			Version: "1.2",
+			Creator: harCreator{Name: "imposter-cli", Version: config.Config.Version},
+			Entries: h.entries,
+		},
+	}
+	content, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal HAR file: %v", err)
+	}
+	tx.WriteFile(harFilePath, content, 0644)
+	count := len(h.entries)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote HAR file %s with %d entries", harFilePath, count)
+	})
+	return nil
+}
+
+func buildHarEntry(upstream string, exchange HttpExchange) harEntry {
+	req := exchange.Request
+	elapsed := float64(exchange.Duration) / float64(time.Millisecond)
+
+	request := harRequest{
+		Method:      req.Method,
+		Url:         buildUpstreamUrl(upstream, req.URL),
+		HttpVersion: req.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     toHarNameValues(req.Header),
+		QueryString: toHarNameValues(req.URL.Query()),
+		HeadersSize: -1,
+	}
+	if exchange.RequestBody != nil && len(*exchange.RequestBody) > 0 {
+		request.BodySize = len(*exchange.RequestBody)
+		request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(*exchange.RequestBody),
+		}
+	}
+
+	var respHeaders http.Header
+	if exchange.ResponseHeaders != nil {
+		respHeaders = *exchange.ResponseHeaders
+	}
+	var respBody []byte
+	if exchange.ResponseBody != nil {
+		respBody = *exchange.ResponseBody
+	}
+	content := harContent{
+		Size:     len(respBody),
+		MimeType: respHeaders.Get("Content-Type"),
+	}
+	if utf8.Valid(respBody) {
+		content.Text = string(respBody)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(respBody)
+		content.Encoding = "base64"
+	}
+
+	return harEntry{
+		StartedDateTime: exchange.StartedDateTime.Format(time.RFC3339Nano),
+		Time:            elapsed,
+		Request:         request,
+		Response: harResponse{
+			Status:      exchange.StatusCode,
+			StatusText:  http.StatusText(exchange.StatusCode),
+			HttpVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     toHarNameValues(respHeaders),
+			Content:     content,
+			RedirectUrl: respHeaders.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(respBody),
+		},
+		Timings: harTimings{Wait: elapsed},
+		TraceId: exchange.TraceId,
+	}
+}
+
+// buildUpstreamUrl returns the URL of the request at the upstream.
+func buildUpstreamUrl(upstream string, requestUrl *url.URL) string {
+	upstreamUrl, err := url.JoinPath(upstream, requestUrl.Path)
+	if err != nil {
+		upstreamUrl = strings.TrimSuffix(upstream, "/") + requestUrl.Path
+	}
+	if requestUrl.RawQuery != "" {
+		upstreamUrl += "?" + requestUrl.RawQuery
+	}
+	return upstreamUrl
+}
+
+// toHarNameValues converts the values to name/value pairs, sorted by name.
+func toHarNameValues(values map[string][]string) []harNameValue {
+	pairs := []harNameValue{}
+	for name, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+	return pairs
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ImportHar converts the entries in a HAR file into Imposter configuration
+// and response files in dir, as if they had been recorded by the proxy.
+// A config file is written for each host in the HAR file. It returns the
+// number of entries imported.
+func ImportHar(harFilePath string, dir string, options RecorderOptions) (int, error) {
+	content, err := os.ReadFile(harFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read HAR file: %s: %v", harFilePath, err)
+	}
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		return 0, fmt.Errorf("failed to parse HAR file: %s: %v", harFilePath, err)
+	}
+	options.OutputFormats = []string{OutputFormatImposter}
+
+	recorders := make(map[string]*recorder)
+	imported := 0
+	for i, entry := range har.Log.Entries {
+		upstream, exchange, err := parseHarEntry(entry)
+		if err != nil {
+			logger.Warnf("skipping HAR entry %d: %v", i, err)
+			continue
+		}
+		r, ok := recorders[upstream]
+		if !ok {
+			r, err = newRecorder(upstream, dir, options)
+			if err != nil {
+				return imported, err
+			}
+			recorders[upstream] = r
+		}
+		if err := r.recordExchange(exchange); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	for _, r := range recorders {
+		if err := r.writeDocuments(); err != nil {
+			return imported, err
+		}
+	}
+	logger.Debugf("imported %d HAR entries for %d host(s)", imported, len(recorders))
+	return imported, nil
+}
+
+// parseHarEntry converts a HAR entry to an exchange, returning the
+// upstream base URL, such as https://example.com
+func parseHarEntry(entry harEntry) (string, HttpExchange, error) {
+	entryUrl, err := url.Parse(entry.Request.Url)
+	if err != nil {
+		return "", HttpExchange{}, fmt.Errorf("invalid request URL: %v", err)
+	}
+	if entryUrl.Scheme == "" || entryUrl.Host == "" {
+		return "", HttpExchange{}, fmt.Errorf("request URL must be absolute: %s", entry.Request.Url)
+	}
+	upstream := entryUrl.Scheme + "://" + entryUrl.Host
+
+	req := &http.Request{
+		Method: strings.ToUpper(entry.Request.Method),
+		URL:    &url.URL{Path: entryUrl.Path, RawPath: entryUrl.RawPath, RawQuery: entryUrl.RawQuery},
+		Proto:  entry.Request.HttpVersion,
+		Header: fromHarNameValues(entry.Request.Headers),
+	}
+	var requestBody []byte
+	if entry.Request.PostData != nil {
+		requestBody = []byte(entry.Request.PostData.Text)
+	}
+
+	var responseBody []byte
+	if entry.Response.Content.Encoding == "base64" {
+		responseBody, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+		if err != nil {
+			return "", HttpExchange{}, fmt.Errorf("invalid base64 response content: %v", err)
+		}
+	} else {
+		responseBody = []byte(entry.Response.Content.Text)
+	}
+	responseHeaders := fromHarNameValues(entry.Response.Headers)
+
+	// HAR content is decoded, so the original encoding no longer applies
+	responseHeaders.Del("Content-Encoding")
+
+	started, _ := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+	return upstream, HttpExchange{
+		Request:         req,
+		RequestBody:     &requestBody,
+		StatusCode:      entry.Response.Status,
+		ResponseBody:    &responseBody,
+		ResponseHeaders: &responseHeaders,
+		StartedDateTime: started,
+		Duration:        time.Duration(entry.Time * float64(time.Millisecond)),
+	}, nil
+}
+
+// fromHarNameValues converts name/value pairs to headers, ignoring
+// HTTP/2 pseudo-headers such as ':authority'.
+func fromHarNameValues(pairs []harNameValue) http.Header {
+	header := http.Header{}
+	for _, pair := range pairs {
+		if strings.HasPrefix(pair.Name, ":") {
+			continue
+		}
+		header.Add(pair.Name, pair.Value)
+	}
+	return header
+}
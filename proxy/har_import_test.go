@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"encoding/json"
+	"gatehill.io/imposter/impostermodel"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportHar(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "imposter-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(url string, body string) harEntry {
+		return harEntry{
+			StartedDateTime: "2024-01-02T03:04:05Z",
+			Request:         harRequest{Method: "GET", Url: url},
+			Response: harResponse{
+				Status:  200,
+				Headers: []harNameValue{{Name: "content-type", Value: "application/json"}, {Name: "content-encoding", Value: "gzip"}},
+				Content: harContent{MimeType: "application/json", Text: body},
+			},
+		}
+	}
+	har := harFile{Log: harLogContent{Version: "1.2", Entries: []harEntry{
+		entry("https://example.com/pets", `[{"id":1}]`),
+		entry("https://example.com/pets", `[{"id":1}]`),
+		entry("https://example.com/pets/1", `{"id":1}`),
+		entry("http://other.example.com:8080/status", `{"status":"ok"}`),
+		entry("/relative", ""),
+	}}}
+	content, _ := json.Marshal(har)
+	harFilePath := filepath.Join(dir, "capture.har")
+	if err := os.WriteFile(harFilePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ImportHar(harFilePath, dir, RecorderOptions{IgnoreDuplicateRequests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 4 {
+		t.Errorf("expected 4 entries to be imported, got %d", imported)
+	}
+	for _, file := range []string{
+		"example.com-config.yaml",
+		"other.example.com-8080-config.yaml",
+		"GET-pets.json",
+		"pets/GET-1.json",
+		"GET-status.json",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("expected file %s to exist: %v", file, err)
+		}
+	}
+}
+
+func TestImportHar_empiricalDelay(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "imposter-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := func(elapsed float64) harEntry {
+		return harEntry{
+			Time:     elapsed,
+			Request:  harRequest{Method: "GET", Url: "https://example.com/pets"},
+			Response: harResponse{Status: 200, Content: harContent{Text: "[]"}},
+		}
+	}
+	har := harFile{Log: harLogContent{Version: "1.2", Entries: []harEntry{entry(120), entry(80), entry(300)}}}
+	content, _ := json.Marshal(har)
+	harFilePath := filepath.Join(dir, "capture.har")
+	if err := os.WriteFile(harFilePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := RecorderOptions{
+		IgnoreDuplicateRequests: true,
+		DelayProfile:            impostermodel.DelayProfile{Type: impostermodel.DelayProfileEmpirical},
+	}
+	if _, err := ImportHar(harFilePath, dir, options); err != nil {
+		t.Fatal(err)
+	}
+	config, err := os.ReadFile(filepath.Join(dir, "example.com-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "min: 80") || !strings.Contains(string(config), "max: 300") {
+		t.Errorf("expected delay range from observed durations, got: %s", config)
+	}
+}
+
+func Test_parseHarEntry(t *testing.T) {
+	tests := []struct {
+		name         string
+		entry        harEntry
+		wantUpstream string
+		wantBody     string
+		wantErr      bool
+	}{
+		{
+			name: "text content",
+			entry: harEntry{
+				Request:  harRequest{Method: "get", Url: "https://example.com/pets?limit=1"},
+				Response: harResponse{Status: 200, Content: harContent{Text: "ok"}},
+			},
+			wantUpstream: "https://example.com",
+			wantBody:     "ok",
+		},
+		{
+			name: "base64 content",
+			entry: harEntry{
+				Request:  harRequest{Method: "GET", Url: "https://example.com/image"},
+				Response: harResponse{Status: 200, Content: harContent{Text: "b2s=", Encoding: "base64"}},
+			},
+			wantUpstream: "https://example.com",
+			wantBody:     "ok",
+		},
+		{
+			name:    "relative URL",
+			entry:   harEntry{Request: harRequest{Method: "GET", Url: "/pets"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, exchange, err := parseHarEntry(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHarEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if upstream != tt.wantUpstream {
+				t.Errorf("parseHarEntry() upstream = %v, want %v", upstream, tt.wantUpstream)
+			}
+			if string(*exchange.ResponseBody) != tt.wantBody {
+				t.Errorf("parseHarEntry() body = %s, want %s", *exchange.ResponseBody, tt.wantBody)
+			}
+			if exchange.Request.URL.Host != "" {
+				t.Errorf("parseHarEntry() request URL should be relative: %v", exchange.Request.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parseOutputFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "default to imposter", formats: nil, want: []string{"imposter"}},
+		{name: "har only", formats: []string{"har"}, want: []string{"har"}},
+		{name: "both formats", formats: []string{"imposter", " HAR"}, want: []string{"imposter", "har"}},
+		{name: "openapi", formats: []string{"openapi"}, want: []string{"openapi"}},
+		{name: "pact", formats: []string{"imposter", "pact"}, want: []string{"imposter", "pact"}},
+		{name: "unsupported format", formats: []string{"pcap"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOutputFormats(tt.formats)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOutputFormats() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOutputFormats() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildHarEntry(t *testing.T) {
+	requestUrl, _ := url.Parse("/pets?limit=10")
+	requestBody := []byte(`{"name":"Fluffy"}`)
+	textBody := []byte(`{"id":1}`)
+	binaryBody := []byte{0xff, 0xfe, 0x00}
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		responseBody []byte
+		wantText     string
+		wantEncoding string
+	}{
+		{name: "text response", responseBody: textBody, wantText: `{"id":1}`},
+		{name: "binary response", responseBody: binaryBody, wantText: "//4A", wantEncoding: "base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchange := HttpExchange{
+				Request: &http.Request{
+					Method: http.MethodPost,
+					URL:    requestUrl,
+					Proto:  "HTTP/1.1",
+					Header: http.Header{"Content-Type": []string{"application/json"}},
+				},
+				RequestBody:     &requestBody,
+				StatusCode:      http.StatusCreated,
+				ResponseBody:    &tt.responseBody,
+				ResponseHeaders: &http.Header{"Content-Type": []string{"application/json"}},
+				StartedDateTime: started,
+				Duration:        150 * time.Millisecond,
+			}
+			entry := buildHarEntry("https://example.com/api", exchange)
+
+			if entry.Request.Url != "https://example.com/api/pets?limit=10" {
+				t.Errorf("unexpected request URL: %s", entry.Request.Url)
+			}
+			if entry.Request.PostData == nil || entry.Request.PostData.Text != string(requestBody) {
+				t.Errorf("unexpected post data: %v", entry.Request.PostData)
+			}
+			if entry.Time != 150 || entry.StartedDateTime != "2024-01-02T03:04:05Z" {
+				t.Errorf("unexpected timing: %v %s", entry.Time, entry.StartedDateTime)
+			}
+			if entry.Response.Status != http.StatusCreated || entry.Response.StatusText != "Created" {
+				t.Errorf("unexpected status: %d %s", entry.Response.Status, entry.Response.StatusText)
+			}
+			if entry.Response.Content.Text != tt.wantText || entry.Response.Content.Encoding != tt.wantEncoding {
+				t.Errorf("unexpected content: %+v", entry.Response.Content)
+			}
+			if entry.Response.Content.Size != len(tt.responseBody) {
+				t.Errorf("unexpected content size: %d", entry.Response.Content.Size)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"golang.org/x/net/http2"
+	"net"
+	"net/http"
+)
+
+// h2cRoundTripper sends requests to plain HTTP upstreams using HTTP/2 with
+// prior knowledge (h2c), and all other requests using the base transport.
+type h2cRoundTripper struct {
+	base *http.Transport
+	h2c  *http2.Transport
+}
+
+// withH2c returns a round tripper that uses h2c for plain HTTP upstreams.
+// The response header timeout of the base transport is not applied to h2c
+// requests.
+func withH2c(base *http.Transport, dialer *net.Dialer) http.RoundTripper {
+	return &h2cRoundTripper{
+		base: base,
+		h2c: &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: true,
+			DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (t *h2cRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
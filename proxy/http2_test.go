@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandle_http2WithTrailers(t *testing.T) {
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		if te := r.Header.Get("TE"); te != "trailers" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = fmt.Fprint(w, "reply")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	previousOptions, previousTls := upstreamOptions, upstreamTls
+	previousClient := getClient()
+	defer func() {
+		upstreamOptions, upstreamTls, client = previousOptions, previousTls, previousClient
+	}()
+	upstreamOptions = UpstreamOptions{Http2: true}
+	var err error
+	if upstreamTls, err = newUpstreamTlsConfigs(UpstreamTls{InsecureSkipVerify: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: buildTransport()}
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Handle(upstream.URL, w, r, 0, nil, func(exchange HttpExchange) {})
+	}))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/greet.Greeter/SayHello", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	if string(body) != "reply" {
+		t.Errorf("body = %q, want %q", body, "reply")
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "0" {
+		t.Errorf("Grpc-Status trailer = %q, want %q", status, "0")
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/stringutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ignoredMatchHeaders vary between otherwise identical requests, so are
+// not used to distinguish them.
+var ignoredMatchHeaders = []string{
+	"Cache-Control",
+	"Content-Length",
+	"Date",
+	"If-Modified-Since",
+	"If-None-Match",
+	"Pragma",
+	"Postman-Token",
+	"Traceparent",
+	"Tracestate",
+	"User-Agent",
+	"X-Request-Id",
+}
+
+// recordedRequest holds the parts of a recorded exchange used to
+// distinguish it from later exchanges with the same method and URL.
+type recordedRequest struct {
+	requestHash  string
+	headers      http.Header
+	body         []byte
+	responseHash string
+}
+
+func newRecordedRequest(requestHash string, exchange HttpExchange) recordedRequest {
+	var body []byte
+	if exchange.RequestBody != nil {
+		body = *exchange.RequestBody
+	}
+	var respBody []byte
+	if exchange.ResponseBody != nil {
+		respBody = *exchange.ResponseBody
+	}
+	return recordedRequest{
+		requestHash:  requestHash,
+		headers:      exchange.Request.Header,
+		body:         body,
+		responseHash: stringutil.Sha1hashString(fmt.Sprintf("%d:%s", exchange.StatusCode, stringutil.Sha1hash(respBody))),
+	}
+}
+
+// requestMatchers are the request headers and body criteria that
+// distinguish resources with the same method and URL.
+type requestMatchers struct {
+	headers []string
+
+	// matchBody is set if the body is matched, using bodyJsonPath if
+	// set, otherwise in full
+	matchBody    bool
+	bodyJsonPath string
+}
+
+func (m requestMatchers) empty() bool {
+	return len(m.headers) == 0 && !m.matchBody
+}
+
+// findRequestMatchers returns the criteria that distinguish the request
+// from each of the others. If it cannot be distinguished from one of them,
+// the matchers are empty.
+func findRequestMatchers(req recordedRequest, others []recordedRequest) requestMatchers {
+	var matchers requestMatchers
+	for _, other := range others {
+		headers := findDistinctHeaders(req.headers, other.headers)
+		bodyDiffers := !bytes.Equal(req.body, other.body)
+		if len(headers) == 0 && !bodyDiffers {
+			return requestMatchers{}
+		}
+		for _, header := range headers {
+			if !stringutil.Contains(matchers.headers, header) {
+				matchers.headers = append(matchers.headers, header)
+			}
+		}
+		if bodyDiffers {
+			matchers.matchBody = true
+		}
+	}
+	sort.Strings(matchers.headers)
+	if matchers.matchBody {
+		matchers.bodyJsonPath = findDistinctJsonField(req.body, others)
+	}
+	return matchers
+}
+
+// findDistinctHeaders returns the names of headers present in the request
+// whose values differ in the other request.
+func findDistinctHeaders(headers http.Header, other http.Header) []string {
+	var distinct []string
+	for name := range headers {
+		name = http.CanonicalHeaderKey(name)
+		if stringutil.Contains(ignoredMatchHeaders, name) || stringutil.Contains(skipProxyHeaders, name) {
+			continue
+		}
+		if headers.Get(name) != other.Get(name) {
+			distinct = append(distinct, name)
+		}
+	}
+	return distinct
+}
+
+// findDistinctJsonField returns the JSON path of a top level field in
+// the body whose value differs in each of the other bodies, or an empty
+// string if there is none, in which case the body is matched in full.
+func findDistinctJsonField(body []byte, others []recordedRequest) string {
+	fields := parseJsonFields(body)
+	if fields == nil {
+		return ""
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		distinct := true
+		for _, other := range others {
+			if bytes.Equal(body, other.body) {
+				continue
+			}
+			if value, ok := parseJsonFields(other.body)[name]; ok && value == fields[name] {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			return "$." + name
+		}
+	}
+	return ""
+}
+
+// parseJsonFields returns the scalar top level fields of a JSON object,
+// formatted as strings, or nil if the body is not a JSON object.
+func parseJsonFields(body []byte) map[string]string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	for name, value := range obj {
+		switch value.(type) {
+		case string, json.Number, bool:
+			fields[name] = fmt.Sprint(value)
+		}
+	}
+	return fields
+}
+
+// apply adds the matchers to the resource, using the values from the
+// request. Matchers for values absent from the request are omitted.
+// The resource's existing header map is copied, not modified.
+func (m requestMatchers) apply(resource *impostermodel.Resource, req recordedRequest) {
+	headers := make(map[string]string)
+	if resource.RequestHeaders != nil {
+		for name, value := range *resource.RequestHeaders {
+			headers[name] = value
+		}
+	}
+	for _, name := range m.headers {
+		if value := req.headers.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	if len(headers) > 0 {
+		resource.RequestHeaders = &headers
+	}
+	if !m.matchBody || resource.RequestBody != nil {
+		return
+	}
+	if m.bodyJsonPath != "" {
+		if value, ok := parseJsonFields(req.body)[strings.TrimPrefix(m.bodyJsonPath, "$.")]; ok {
+			resource.RequestBody = &impostermodel.RequestBody{JsonPath: m.bodyJsonPath, Value: value}
+		}
+	} else if len(req.body) > 0 {
+		resource.RequestBody = &impostermodel.RequestBody{Value: string(req.body)}
+	}
+}
+
+// findVariant returns the hash identifying the resource for the request.
+// If earlier resources with the same method and URL returned different
+// responses, and the request can be distinguished from them, the request
+// is a variant, so the matchers and the indices of those resources are
+// also returned.
+func (r *recorder) findVariant(req recordedRequest) (resourceHash string, matchers requestMatchers, variantOf []int) {
+	var others []recordedRequest
+	for i, other := range r.resourceRequests {
+		if other.requestHash != req.requestHash {
+			continue
+		}
+		if other.responseHash == req.responseHash {
+			return r.resourceHashes[i], requestMatchers{}, nil
+		}
+		others = append(others, other)
+		variantOf = append(variantOf, i)
+	}
+	if len(others) == 0 {
+		return req.requestHash, requestMatchers{}, nil
+	}
+	matchers = findRequestMatchers(req, others)
+	if matchers.empty() {
+		logger.Debugf("cannot distinguish request with different response from earlier requests with the same method and URL")
+		return req.requestHash, requestMatchers{}, nil
+	}
+	var variant impostermodel.Resource
+	matchers.apply(&variant, req)
+	return buildMatcherHash(req.requestHash, variant), matchers, variantOf
+}
+
+// buildMatcherHash returns a hash identifying the resource by its method,
+// URL and request matchers.
+func buildMatcherHash(requestHash string, resource impostermodel.Resource) string {
+	matchers, _ := json.Marshal(struct {
+		Headers *map[string]string
+		Body    *impostermodel.RequestBody
+	}{resource.RequestHeaders, resource.RequestBody})
+	return stringutil.Sha1hashString(requestHash + string(matchers))
+}
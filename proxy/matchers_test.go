@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_findRequestMatchers(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    recordedRequest
+		others []recordedRequest
+		want   requestMatchers
+	}{
+		{
+			name:   "distinct header",
+			req:    recordedRequest{headers: http.Header{"Accept": {"application/xml"}, "User-Agent": {"a"}}},
+			others: []recordedRequest{{headers: http.Header{"Accept": {"application/json"}, "User-Agent": {"b"}}}},
+			want:   requestMatchers{headers: []string{"Accept"}},
+		},
+		{
+			name:   "distinct JSON field",
+			req:    recordedRequest{body: []byte(`{"type":"gold","qty":1}`)},
+			others: []recordedRequest{{body: []byte(`{"type":"silver","qty":1}`)}},
+			want:   requestMatchers{matchBody: true, bodyJsonPath: "$.type"},
+		},
+		{
+			name:   "distinct non-JSON body",
+			req:    recordedRequest{body: []byte(`type=gold`)},
+			others: []recordedRequest{{body: []byte(`type=silver`)}},
+			want:   requestMatchers{matchBody: true},
+		},
+		{
+			name:   "indistinguishable",
+			req:    recordedRequest{headers: http.Header{"User-Agent": {"a"}}, body: []byte(`x`)},
+			others: []recordedRequest{{headers: http.Header{"User-Agent": {"b"}}, body: []byte(`x`)}},
+			want:   requestMatchers{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findRequestMatchers(tt.req, tt.others); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findRequestMatchers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_recorder_recordVariants(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "imposter-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := newRecorder("https://example.com", dir, RecorderOptions{IgnoreDuplicateRequests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := func(accept string, body string) HttpExchange {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("Accept", accept)
+		respBody := []byte(body)
+		return HttpExchange{
+			Request:         req,
+			RequestBody:     &[]byte{},
+			StatusCode:      http.StatusOK,
+			ResponseBody:    &respBody,
+			ResponseHeaders: &http.Header{},
+		}
+	}
+	for _, e := range []HttpExchange{
+		exchange("application/json", `[]`),
+		exchange("application/xml", `<pets/>`),
+		exchange("application/xml", `<pets/>`),
+	} {
+		if err := r.recordExchange(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(r.resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(r.resources))
+	}
+	for i, want := range []string{"application/json", "application/xml"} {
+		headers := r.resources[i].RequestHeaders
+		if headers == nil || (*headers)["Accept"] != want {
+			t.Errorf("resource %d request headers = %v, want Accept: %s", i, headers, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/library"
+	"gatehill.io/imposter/tlsutil"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const mitmCaCertFile = "mitm-ca.crt"
+const mitmCaKeyFile = "mitm-ca.key"
+const tunnelDialTimeout = 10 * time.Second
+
+// mitmHandler acts as a forward proxy. CONNECT requests for the upstream
+// host are intercepted, using a certificate signed by a local CA, so the
+// exchanges can be recorded. CONNECT requests for other hosts are refused,
+// unless tunnelling is enabled, in which case they are relayed without
+// interception.
+type mitmHandler struct {
+	upstreamUrl *url.URL
+	ca          *tls.Certificate
+	tunnelOther bool
+	next        http.Handler
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+}
+
+// NewMitmHandler returns a forward proxy handler, which passes requests for
+// the upstream to the next handler, decrypting HTTPS using the CA. If
+// tunnelOther is set, connections to other hosts are relayed unmodified.
+func NewMitmHandler(upstream string, ca *tls.Certificate, tunnelOther bool, next http.Handler) (http.Handler, error) {
+	upstreamUrl, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse upstream URL: %v", err)
+	}
+	return &mitmHandler{
+		upstreamUrl: upstreamUrl,
+		ca:          ca,
+		tunnelOther: tunnelOther,
+		next:        next,
+		certs:       make(map[string]*tls.Certificate),
+	}, nil
+}
+
+func (m *mitmHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		if m.isUpstream(r.Host) {
+			m.intercept(w, r)
+		} else if m.tunnelOther {
+			m.tunnel(w, r)
+		} else {
+			logger.Debugf("refused connection to %s from %s", r.Host, r.RemoteAddr)
+			http.Error(w, fmt.Sprintf("only connections to %s are proxied", m.upstreamUrl.Host), http.StatusForbidden)
+		}
+		return
+	}
+	if r.URL.IsAbs() && !m.isUpstream(r.URL.Host) {
+		http.Error(w, fmt.Sprintf("only requests to %s are proxied", m.upstreamUrl.Host), http.StatusBadGateway)
+		return
+	}
+	m.next.ServeHTTP(w, r)
+}
+
+// isUpstream determines if the host and optional port are those of the upstream.
+func (m *mitmHandler) isUpstream(hostPort string) bool {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+	}
+	if host != m.upstreamUrl.Hostname() {
+		return false
+	}
+	return port == "" || port == getUpstreamPort(m.upstreamUrl)
+}
+
+func getUpstreamPort(upstreamUrl *url.URL) string {
+	if port := upstreamUrl.Port(); port != "" {
+		return port
+	} else if upstreamUrl.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// intercept terminates TLS on the client connection, and serves the
+// decrypted requests using the next handler.
+func (m *mitmHandler) intercept(w http.ResponseWriter, r *http.Request) {
+	conn, err := hijack(w)
+	if err != nil {
+		logger.Warnf("failed to intercept connection to %s: %v", r.Host, err)
+		return
+	}
+	logger.Debugf("intercepting connection to %s", r.Host)
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: m.getCertificate,
+		NextProtos:     []string{"http/1.1"},
+	})
+	server := &http.Server{Handler: m.next}
+	_ = server.Serve(&singleConnListener{conn: tlsConn})
+}
+
+// tunnel relays the connection to its destination without interception.
+func (m *mitmHandler) tunnel(w http.ResponseWriter, r *http.Request) {
+	destConn, err := net.DialTimeout("tcp", r.Host, tunnelDialTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, err := hijack(w)
+	if err != nil {
+		logger.Warnf("failed to tunnel connection to %s: %v", r.Host, err)
+		_ = destConn.Close()
+		return
+	}
+	logger.Tracef("tunnelling connection to %s", r.Host)
+	go relay(destConn, conn)
+	go relay(conn, destConn)
+}
+
+func relay(dest net.Conn, src net.Conn) {
+	defer dest.Close()
+	defer src.Close()
+	_, _ = io.Copy(dest, src)
+}
+
+// hijack takes over the client connection, confirming that it is established.
+func hijack(w http.ResponseWriter) (net.Conn, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection hijacking not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("connection hijacking not supported")
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// getCertificate returns a certificate for the upstream host, signed by the
+// CA. Certificates are never issued for other hosts, so a client cannot
+// obtain one for an arbitrary name by sending it in the SNI extension of a
+// connection to the upstream.
+func (m *mitmHandler) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := m.upstreamUrl.Hostname()
+	if hello.ServerName != "" && !strings.EqualFold(hello.ServerName, host) {
+		return nil, fmt.Errorf("refusing to issue certificate for %s - only %s is intercepted", hello.ServerName, host)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cert, ok := m.certs[host]; ok {
+		return cert, nil
+	}
+	cert, err := tlsutil.GenerateLeafCertificate(m.ca, host)
+	if err != nil {
+		return nil, err
+	}
+	m.certs[host] = cert
+	return cert, nil
+}
+
+// singleConnListener returns its connection once, so an http.Server
+// can serve an existing connection.
+type singleConnListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() {
+		conn = l.conn
+	})
+	if conn == nil {
+		return nil, io.EOF
+	}
+	return conn, nil
+}
+
+func (l *singleConnListener) Close() error {
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// EnsureMitmCa returns the CA used to intercept HTTPS connections,
+// generating it if it does not exist, along with the path to its
+// certificate, which clients must trust.
+func EnsureMitmCa() (ca *tls.Certificate, certPath string, err error) {
+	dir, err := library.EnsureDirUsingConfig("tls.dir", ".imposter/tls/")
+	if err != nil {
+		return nil, "", err
+	}
+	certPath = filepath.Join(dir, mitmCaCertFile)
+	keyPath := filepath.Join(dir, mitmCaKeyFile)
+
+	if _, err := os.Stat(certPath); err == nil {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err == nil {
+			cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		}
+		if err == nil {
+			return &cert, certPath, nil
+		}
+		logger.Warnf("regenerating invalid proxy CA: %v", err)
+	}
+
+	certPem, keyPem, err := tlsutil.GenerateCa("Imposter Proxy CA")
+	if err != nil {
+		return nil, "", err
+	}
+	if err := fileutil.WriteFileAtomic(keyPath, keyPem, 0600); err != nil {
+		return nil, "", err
+	}
+	if err := fileutil.WriteFileAtomic(certPath, certPem, 0644); err != nil {
+		return nil, "", err
+	}
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return nil, "", err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Infof("generated proxy CA: %s", certPath)
+	return &cert, certPath, nil
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"gatehill.io/imposter/tlsutil"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMitmHandler(t *testing.T) {
+	caCertPem, caKeyPem, err := tlsutil.GenerateCa("Test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := tls.X509KeyPair(caCertPem, caKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a server that is not the upstream, so its connections are tunnelled
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "tunnelled")
+	}))
+	defer other.Close()
+
+	upstream := "https://upstream.example.com"
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "intercepted %s", r.URL.Path)
+	})
+	handler, err := NewMitmHandler(upstream, &ca, true, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyServer := httptest.NewServer(handler)
+	defer proxyServer.Close()
+	proxyUrl, _ := url.Parse(proxyServer.URL)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPem)
+	roots.AddCert(other.Certificate())
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyUrl),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "intercept upstream", url: upstream + "/pets", want: "intercepted /pets"},
+		{name: "tunnel other host", url: other.URL, want: "tunnelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Get(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("got body %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestMitmHandler_isUpstream(t *testing.T) {
+	handler, _ := NewMitmHandler("https://example.com", nil, false, nil)
+	m := handler.(*mitmHandler)
+	tests := []struct {
+		hostPort string
+		want     bool
+	}{
+		{hostPort: "example.com:443", want: true},
+		{hostPort: "example.com", want: true},
+		{hostPort: "example.com:8443", want: false},
+		{hostPort: "other.com:443", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostPort, func(t *testing.T) {
+			if got := m.isUpstream(tt.hostPort); got != tt.want {
+				t.Errorf("isUpstream() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMitmHandler_refusesOtherHosts(t *testing.T) {
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "tunnelled")
+	}))
+	defer other.Close()
+
+	handler, err := NewMitmHandler("https://upstream.example.com", nil, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodConnect, other.Listener.Addr().String(), nil)
+	req.Host = other.Listener.Addr().String()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status code = %v, want %v", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestMitmHandler_getCertificate(t *testing.T) {
+	caCertPem, caKeyPem, err := tlsutil.GenerateCa("Test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := tls.X509KeyPair(caCertPem, caKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, _ := NewMitmHandler("https://upstream.example.com", &ca, false, nil)
+	m := handler.(*mitmHandler)
+
+	tests := []struct {
+		serverName string
+		wantErr    bool
+	}{
+		{serverName: "upstream.example.com"},
+		{serverName: "UPSTREAM.example.com"},
+		{serverName: ""},
+		{serverName: "bank.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverName, func(t *testing.T) {
+			cert, err := m.getCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			leaf, err := x509.ParseCertificate(cert.Certificate[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := leaf.VerifyHostname("upstream.example.com"); err != nil {
+				t.Errorf("certificate not valid for upstream: %v", err)
+			}
+		})
+	}
+}
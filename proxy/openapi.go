@@ -0,0 +1,354 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/stringutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pathParamPattern matches path segments that are likely to be
+// identifiers, such as numbers, UUIDs and long hexadecimal strings.
+var pathParamPattern = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// openapiBuilder synthesises an OpenAPI 3 document from recorded
+// exchanges, inferring path parameters and JSON schemas.
+type openapiBuilder struct {
+	mu       sync.Mutex
+	upstream string
+	paths    map[string]map[string]*openapiOperation
+}
+
+type openapiDocument struct {
+	Openapi string                                  `json:"openapi"`
+	Info    openapiInfo                             `json:"info"`
+	Servers []openapiServer                         `json:"servers"`
+	Paths   map[string]map[string]*openapiOperation `json:"paths"`
+}
+
+type openapiInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openapiServer struct {
+	Url string `json:"url"`
+}
+
+type openapiOperation struct {
+	Parameters  []*openapiParameter         `json:"parameters,omitempty"`
+	RequestBody *openapiRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openapiResponse `json:"responses"`
+}
+
+type openapiParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openapiRequestBody struct {
+	Content map[string]*openapiMediaType `json:"content"`
+}
+
+type openapiResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openapiMediaType `json:"content,omitempty"`
+}
+
+type openapiMediaType struct {
+	Schema  *jsonSchema `json:"schema"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+type jsonSchema struct {
+	Type       string                 `json:"type,omitempty"`
+	Format     string                 `json:"format,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Items      *jsonSchema            `json:"items,omitempty"`
+}
+
+func newOpenapiBuilder(upstream string) *openapiBuilder {
+	return &openapiBuilder{
+		upstream: upstream,
+		paths:    make(map[string]map[string]*openapiOperation),
+	}
+}
+
+// add merges the exchange into the operation for its path and method.
+func (b *openapiBuilder) add(exchange HttpExchange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	req := exchange.Request
+	pathTemplate, pathParams := buildPathTemplate(req.URL.Path)
+	operations := b.paths[pathTemplate]
+	if operations == nil {
+		operations = make(map[string]*openapiOperation)
+		b.paths[pathTemplate] = operations
+	}
+	method := strings.ToLower(req.Method)
+	op := operations[method]
+	if op == nil {
+		op = &openapiOperation{Responses: make(map[string]*openapiResponse)}
+		operations[method] = op
+	}
+
+	for _, name := range pathParams {
+		op.addParameter(name, "path", true, &jsonSchema{Type: "string"})
+	}
+	for name, values := range req.URL.Query() {
+		var schema *jsonSchema
+		if len(values) > 0 {
+			schema = inferScalarSchema(values[0])
+		}
+		op.addParameter(name, "query", false, schema)
+	}
+
+	if exchange.RequestBody != nil && len(*exchange.RequestBody) > 0 {
+		if op.RequestBody == nil {
+			op.RequestBody = &openapiRequestBody{Content: make(map[string]*openapiMediaType)}
+		}
+		mergeContent(op.RequestBody.Content, req.Header.Get("Content-Type"), *exchange.RequestBody, false)
+	}
+
+	status := strconv.Itoa(exchange.StatusCode)
+	resp := op.Responses[status]
+	if resp == nil {
+		resp = &openapiResponse{Description: http.StatusText(exchange.StatusCode)}
+		if resp.Description == "" {
+			resp.Description = "Status " + status
+		}
+		op.Responses[status] = resp
+	}
+	if exchange.ResponseBody != nil && len(*exchange.ResponseBody) > 0 {
+		if resp.Content == nil {
+			resp.Content = make(map[string]*openapiMediaType)
+		}
+		var contentType string
+		if exchange.ResponseHeaders != nil {
+			contentType = exchange.ResponseHeaders.Get("Content-Type")
+		}
+		mergeContent(resp.Content, contentType, *exchange.ResponseBody, true)
+	}
+}
+
+// write stages the complete OpenAPI document, including all exchanges so far.
+func (b *openapiBuilder) write(tx *fileutil.Transaction, specFilePath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	doc := openapiDocument{
+		Openapi: "3.0.3",
+		Info: openapiInfo{
+			Title:       "Recorded API",
+			Description: fmt.Sprintf("Generated from traffic recorded with %s", b.upstream),
+			Version:     "1.0.0",
+		},
+		Servers: []openapiServer{{Url: b.upstream}},
+		Paths:   b.paths,
+	}
+	content, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal OpenAPI spec: %v", err)
+	}
+	tx.WriteFile(specFilePath, content, 0644)
+	count := len(b.paths)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote OpenAPI spec %s with %d paths", specFilePath, count)
+	})
+	return nil
+}
+
+// buildPathTemplate replaces segments of the path that look like
+// identifiers with parameters, named for the preceding segment, such as
+// /users/123 becoming /users/{userId}.
+func buildPathTemplate(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if !pathParamPattern.MatchString(segment) {
+			continue
+		}
+		name := "id"
+		if i > 0 && segments[i-1] != "" && !strings.HasPrefix(segments[i-1], "{") {
+			name = strings.TrimSuffix(segments[i-1], "s") + "Id"
+		}
+		base := name
+		for n := 2; stringutil.Contains(params, name); n++ {
+			name = base + strconv.Itoa(n)
+		}
+		params = append(params, name)
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func (op *openapiOperation) addParameter(name string, in string, required bool, schema *jsonSchema) {
+	for _, param := range op.Parameters {
+		if param.Name == name && param.In == in {
+			param.Schema = mergeSchemas(param.Schema, schema)
+			return
+		}
+	}
+	if schema == nil {
+		schema = &jsonSchema{Type: "string"}
+	}
+	op.Parameters = append(op.Parameters, &openapiParameter{Name: name, In: in, Required: required, Schema: schema})
+}
+
+// mergeContent merges the body into the content for its media type. JSON
+// bodies have their schema inferred, and if withExample is set, the first
+// is kept as an example.
+func mergeContent(content map[string]*openapiMediaType, contentType string, body []byte, withExample bool) {
+	mediaType := "application/octet-stream"
+	if contentType != "" {
+		if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+			mediaType = parsed
+		}
+	}
+	existing := content[mediaType]
+
+	if isJsonMediaType(mediaType) {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err == nil {
+			if existing == nil {
+				existing = &openapiMediaType{}
+				if withExample {
+					existing.Example = value
+				}
+				content[mediaType] = existing
+			}
+			existing.Schema = mergeSchemas(existing.Schema, inferSchema(value))
+			return
+		}
+	}
+	if existing == nil {
+		schema := &jsonSchema{Type: "string"}
+		if !strings.HasPrefix(mediaType, "text/") && !isJsonMediaType(mediaType) {
+			schema.Format = "binary"
+		}
+		content[mediaType] = &openapiMediaType{Schema: schema}
+	}
+}
+
+func isJsonMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// inferSchema returns the schema of a value decoded from JSON.
+func inferSchema(value interface{}) *jsonSchema {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema)}
+		for name, property := range v {
+			schema.Properties[name] = inferSchema(property)
+		}
+		return schema
+	case []interface{}:
+		schema := &jsonSchema{Type: "array"}
+		for _, item := range v {
+			schema.Items = mergeSchemas(schema.Items, inferSchema(item))
+		}
+		if schema.Items == nil {
+			schema.Items = &jsonSchema{}
+		}
+		return schema
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &jsonSchema{Type: "integer"}
+		}
+		return &jsonSchema{Type: "number"}
+	case string:
+		schema := &jsonSchema{Type: "string"}
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			schema.Format = "date-time"
+		}
+		return schema
+	case bool:
+		return &jsonSchema{Type: "boolean"}
+	default:
+		// null values do not indicate a type
+		return &jsonSchema{}
+	}
+}
+
+// inferScalarSchema returns the schema of a query parameter value.
+func inferScalarSchema(value string) *jsonSchema {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return &jsonSchema{Type: "integer"}
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return &jsonSchema{Type: "number"}
+	}
+	if _, err := strconv.ParseBool(value); err == nil {
+		return &jsonSchema{Type: "boolean"}
+	}
+	return &jsonSchema{Type: "string"}
+}
+
+// mergeSchemas combines schemas inferred from different values, so object
+// properties seen in either are included. Where the types conflict, an
+// integer widens to a number, otherwise the type is left unspecified.
+func mergeSchemas(a *jsonSchema, b *jsonSchema) *jsonSchema {
+	if a == nil {
+		return b
+	} else if b == nil {
+		return a
+	}
+	merged := *a
+	switch {
+	case a.Type == "":
+		merged.Type, merged.Format = b.Type, b.Format
+	case b.Type == "":
+	case a.Type == b.Type:
+		if a.Format != b.Format {
+			merged.Format = ""
+		}
+	case (a.Type == "integer" && b.Type == "number") || (a.Type == "number" && b.Type == "integer"):
+		merged.Type, merged.Format = "number", ""
+	default:
+		return &jsonSchema{}
+	}
+	if a.Properties != nil || b.Properties != nil {
+		merged.Properties = make(map[string]*jsonSchema)
+		for name, property := range a.Properties {
+			merged.Properties[name] = property
+		}
+		for name, property := range b.Properties {
+			merged.Properties[name] = mergeSchemas(merged.Properties[name], property)
+		}
+	}
+	if a.Items != nil || b.Items != nil {
+		merged.Items = mergeSchemas(a.Items, b.Items)
+	}
+	return &merged
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_buildPathTemplate(t *testing.T) {
+	tests := []struct {
+		path       string
+		want       string
+		wantParams []string
+	}{
+		{path: "/users", want: "/users"},
+		{path: "/users/123", want: "/users/{userId}", wantParams: []string{"userId"}},
+		{path: "/users/123/orders/9f1c8c5e-2d3b-4b7a-9d0e-6f1a2b3c4d5e", want: "/users/{userId}/orders/{orderId}", wantParams: []string{"userId", "orderId"}},
+		{path: "/123/456", want: "/{id}/{id2}", wantParams: []string{"id", "id2"}},
+		{path: "/users/me", want: "/users/me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, params := buildPathTemplate(tt.path)
+			if got != tt.want {
+				t.Errorf("buildPathTemplate() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("buildPathTemplate() params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func Test_mergeSchemas(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want *jsonSchema
+	}{
+		{
+			name: "properties combined",
+			a:    `{"id":1}`,
+			b:    `{"id":2,"name":"Fluffy"}`,
+			want: &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{
+				"id":   {Type: "integer"},
+				"name": {Type: "string"},
+			}},
+		},
+		{
+			name: "integer widened to number",
+			a:    `[1]`,
+			b:    `[1.5]`,
+			want: &jsonSchema{Type: "array", Items: &jsonSchema{Type: "number"}},
+		},
+		{
+			name: "null takes other type",
+			a:    `{"born":null}`,
+			b:    `{"born":"2020-01-01T00:00:00Z"}`,
+			want: &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{
+				"born": {Type: "string", Format: "date-time"},
+			}},
+		},
+		{
+			name: "conflicting types",
+			a:    `"text"`,
+			b:    `true`,
+			want: &jsonSchema{},
+		},
+	}
+	decode := func(s string) interface{} {
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.UseNumber()
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeSchemas(inferSchema(decode(tt.a)), inferSchema(decode(tt.b)))
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJson, _ := json.Marshal(got)
+				wantJson, _ := json.Marshal(tt.want)
+				t.Errorf("mergeSchemas() = %s, want %s", gotJson, wantJson)
+			}
+		})
+	}
+}
+
+func Test_openapiBuilder_add(t *testing.T) {
+	b := newOpenapiBuilder("https://example.com")
+	add := func(method string, target string, status int, body string) {
+		respBody := []byte(body)
+		headers := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+		b.add(HttpExchange{
+			Request:         httptest.NewRequest(method, target, nil),
+			StatusCode:      status,
+			ResponseBody:    &respBody,
+			ResponseHeaders: &headers,
+		})
+	}
+	add(http.MethodGet, "/pets/1?verbose=true", http.StatusOK, `{"id":1,"name":"Fluffy"}`)
+	add(http.MethodGet, "/pets/2", http.StatusOK, `{"id":2,"tag":"cat"}`)
+	add(http.MethodGet, "/pets/3", http.StatusNotFound, ``)
+
+	op := b.paths["/pets/{petId}"]["get"]
+	if op == nil {
+		t.Fatalf("expected operation for GET /pets/{petId}, got paths: %v", b.paths)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Name != "petId" || op.Parameters[1].Name != "verbose" || op.Parameters[1].Schema.Type != "boolean" {
+		gotJson, _ := json.Marshal(op.Parameters)
+		t.Errorf("unexpected parameters: %s", gotJson)
+	}
+	ok := op.Responses["200"]
+	if ok == nil || ok.Content["application/json"] == nil {
+		t.Fatalf("expected JSON content for 200 response")
+	}
+	properties := ok.Content["application/json"].Schema.Properties
+	for _, name := range []string{"id", "name", "tag"} {
+		if properties[name] == nil {
+			t.Errorf("expected property %s in response schema", name)
+		}
+	}
+	if notFound := op.Responses["404"]; notFound == nil || notFound.Description != "Not Found" || notFound.Content != nil {
+		t.Errorf("unexpected 404 response: %+v", notFound)
+	}
+}
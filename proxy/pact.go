@@ -0,0 +1,239 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/config"
+	"gatehill.io/imposter/fileutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultPactConsumer is the consumer named in Pact files if
+// none is configured.
+const defaultPactConsumer = "imposter-recording"
+
+// pactRequestHeaders are the request headers recorded in interactions.
+// Others, such as User-Agent, vary between clients, so are omitted.
+var pactRequestHeaders = []string{"Accept", "Content-Type"}
+
+var pactNameSanitiser = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// pactBuilder accumulates recorded exchanges as the interactions
+// of a Pact v3 contract between a consumer and the upstream.
+// See https://github.com/pact-foundation/pact-specification/tree/version-3
+type pactBuilder struct {
+	mu           sync.Mutex
+	consumer     string
+	provider     string
+	interactions []pactInteraction
+}
+
+type pactFile struct {
+	Consumer     pactParticipant   `json:"consumer"`
+	Provider     pactParticipant   `json:"provider"`
+	Interactions []pactInteraction `json:"interactions"`
+	Metadata     pactMetadata      `json:"metadata"`
+}
+
+type pactParticipant struct {
+	Name string `json:"name"`
+}
+
+type pactInteraction struct {
+	Description    string              `json:"description"`
+	ProviderStates []pactProviderState `json:"providerStates,omitempty"`
+	Request        pactRequest         `json:"request"`
+	Response       pactResponse        `json:"response"`
+}
+
+type pactProviderState struct {
+	Name string `json:"name"`
+}
+
+type pactRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   map[string][]string `json:"query,omitempty"`
+	Headers map[string]string   `json:"headers,omitempty"`
+	Body    interface{}         `json:"body,omitempty"`
+}
+
+type pactResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+type pactMetadata struct {
+	PactSpecification pactVersion `json:"pactSpecification"`
+	Imposter          pactVersion `json:"imposter"`
+}
+
+type pactVersion struct {
+	Version string `json:"version"`
+}
+
+// newPactBuilder returns a builder for a contract between the consumer
+// and provider. If they are not set, the consumer defaults to
+// defaultPactConsumer and the provider to the upstream host.
+func newPactBuilder(consumer string, provider string, upstreamHost string) *pactBuilder {
+	if consumer == "" {
+		consumer = defaultPactConsumer
+	}
+	if provider == "" {
+		provider = upstreamHost
+	}
+	return &pactBuilder{consumer: consumer, provider: provider}
+}
+
+// fileName returns the conventional name of the Pact file,
+// in the form consumer-provider.json.
+func (p *pactBuilder) fileName() string {
+	return pactNameSanitiser.ReplaceAllString(p.consumer, "_") + "-" + pactNameSanitiser.ReplaceAllString(p.provider, "_") + ".json"
+}
+
+// add appends an interaction for the exchange. Exchanges identical to an
+// earlier interaction are skipped, and the descriptions of others with
+// the same method and path are numbered, as descriptions must be unique.
+func (p *pactBuilder) add(exchange HttpExchange, recordOnlyResponseHeaders []string) {
+	interaction := buildPactInteraction(exchange, recordOnlyResponseHeaders)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	occurrences := 0
+	for _, existing := range p.interactions {
+		if reflect.DeepEqual(existing.Request, interaction.Request) && reflect.DeepEqual(existing.Response, interaction.Response) {
+			logger.Debugf("skipping duplicate pact interaction for %s %s", interaction.Request.Method, interaction.Request.Path)
+			return
+		}
+		if existing.Request.Method == interaction.Request.Method && existing.Request.Path == interaction.Request.Path {
+			occurrences++
+		}
+	}
+	if occurrences > 0 {
+		interaction.Description += fmt.Sprintf(" (%d)", occurrences+1)
+	}
+	p.interactions = append(p.interactions, interaction)
+}
+
+// write stages the complete Pact file, including all interactions so far.
+func (p *pactBuilder) write(tx *fileutil.Transaction, pactFilePath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pact := pactFile{
+		Consumer:     pactParticipant{Name: p.consumer},
+		Provider:     pactParticipant{Name: p.provider},
+		Interactions: p.interactions,
+		Metadata: pactMetadata{
+			PactSpecification: pactVersion{Version: "3.0.0"},
+			Imposter:          pactVersion{Version: config.Config.Version},
+		},
+	}
+	content, err := json.MarshalIndent(pact, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal Pact file: %v", err)
+	}
+	tx.WriteFile(pactFilePath, content, 0644)
+	count := len(p.interactions)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote Pact file %s with %d interactions", pactFilePath, count)
+	})
+	return nil
+}
+
+func buildPactInteraction(exchange HttpExchange, recordOnlyResponseHeaders []string) pactInteraction {
+	req := exchange.Request
+	request := pactRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+	if query := req.URL.Query(); len(query) > 0 {
+		request.Query = query
+	}
+	for _, name := range pactRequestHeaders {
+		if value := req.Header.Get(name); value != "" {
+			if request.Headers == nil {
+				request.Headers = make(map[string]string)
+			}
+			request.Headers[name] = value
+		}
+	}
+	if exchange.RequestBody != nil {
+		request.Body = buildPactBody(req.Header.Get("Content-Type"), *exchange.RequestBody)
+	}
+
+	response := pactResponse{Status: exchange.StatusCode}
+	if exchange.ResponseHeaders != nil {
+		for name, values := range *exchange.ResponseHeaders {
+			if len(values) == 0 || !recordPactResponseHeader(name, recordOnlyResponseHeaders) {
+				continue
+			}
+			if response.Headers == nil {
+				response.Headers = make(map[string]string)
+			}
+			response.Headers[name] = values[0]
+		}
+		if exchange.ResponseBody != nil {
+			response.Body = buildPactBody(exchange.ResponseHeaders.Get("Content-Type"), *exchange.ResponseBody)
+		}
+	}
+
+	return pactInteraction{
+		Description: req.Method + " " + req.URL.Path,
+		Request:     request,
+		Response:    response,
+	}
+}
+
+// recordPactResponseHeader returns true if the response header should
+// be recorded. Only Content-Type is recorded by default, as providers
+// are verified against every recorded header, and others, such as Date,
+// vary between responses.
+func recordPactResponseHeader(name string, recordOnlyResponseHeaders []string) bool {
+	if len(recordOnlyResponseHeaders) > 0 {
+		for _, header := range recordOnlyResponseHeaders {
+			if strings.EqualFold(header, name) {
+				return true
+			}
+		}
+		return false
+	}
+	return http.CanonicalHeaderKey(name) == "Content-Type"
+}
+
+// buildPactBody returns the body as a JSON value if it has a JSON content
+// type, so it is compared structurally, or otherwise as a string.
+func buildPactBody(contentType string, body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		(mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			return value
+		}
+	}
+	return string(body)
+}
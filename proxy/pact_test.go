@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_buildPactInteraction(t *testing.T) {
+	requestBody := []byte(`{"name":"Fluffy"}`)
+	responseBody := []byte(`{"id":1,"name":"Fluffy"}`)
+	req := httptest.NewRequest(http.MethodPost, "/pets?tag=a&tag=b", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test")
+	exchange := HttpExchange{
+		Request:      req,
+		RequestBody:  &requestBody,
+		StatusCode:   http.StatusCreated,
+		ResponseBody: &responseBody,
+		ResponseHeaders: &http.Header{
+			"Content-Type": []string{"application/json"},
+			"Date":         []string{"Tue, 02 Jan 2024 03:04:05 GMT"},
+		},
+	}
+
+	got := buildPactInteraction(exchange, nil)
+	want := pactInteraction{
+		Description: "POST /pets",
+		Request: pactRequest{
+			Method:  "POST",
+			Path:    "/pets",
+			Query:   map[string][]string{"tag": {"a", "b"}},
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    map[string]interface{}{"name": "Fluffy"},
+		},
+		Response: pactResponse{
+			Status:  http.StatusCreated,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    map[string]interface{}{"id": float64(1), "name": "Fluffy"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildPactInteraction() = %+v, want %+v", got, want)
+	}
+
+	if got := buildPactInteraction(exchange, []string{"date"}); !reflect.DeepEqual(got.Response.Headers, map[string]string{"Date": "Tue, 02 Jan 2024 03:04:05 GMT"}) {
+		t.Errorf("expected only the Date header, got: %v", got.Response.Headers)
+	}
+}
+
+func Test_pactBuilder(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newRecorder("https://example.com:8443", dir, RecorderOptions{
+		OutputFormats: []string{OutputFormatPact},
+		PactConsumer:  "web app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := func(path string, body string) HttpExchange {
+		responseBody := []byte(body)
+		return HttpExchange{
+			Request:         httptest.NewRequest(http.MethodGet, path, nil),
+			StatusCode:      http.StatusOK,
+			ResponseBody:    &responseBody,
+			ResponseHeaders: &http.Header{"Content-Type": []string{"text/plain"}},
+		}
+	}
+	for _, e := range []HttpExchange{exchange("/pets", "a"), exchange("/pets", "a"), exchange("/pets", "b")} {
+		if err := r.recordExchange(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web_app-example.com-8443.json")); !os.IsNotExist(err) {
+		t.Errorf("expected Pact file not to be written until documents are written")
+	}
+	if err := r.writeDocuments(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "web_app-example.com-8443.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pact pactFile
+	if err := json.Unmarshal(content, &pact); err != nil {
+		t.Fatal(err)
+	}
+	if pact.Consumer.Name != "web app" || pact.Provider.Name != "example.com-8443" || pact.Metadata.PactSpecification.Version != "3.0.0" {
+		t.Errorf("unexpected pact header: %+v", pact)
+	}
+	var descriptions []string
+	for _, interaction := range pact.Interactions {
+		descriptions = append(descriptions, interaction.Description)
+	}
+	if want := []string{"GET /pets", "GET /pets (2)"}; !reflect.DeepEqual(descriptions, want) {
+		t.Errorf("descriptions = %v, want %v", descriptions, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "example.com-8443-config.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected no Imposter config to be recorded")
+	}
+}
@@ -0,0 +1,353 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultPostmanUpstream is used for requests whose host is a variable
+// without a value in the collection, such as '{{baseUrl}}'.
+const defaultPostmanUpstream = "http://localhost"
+
+var postmanVariablePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// postmanCollection is a Postman collection, in the v2.0 or v2.1 format.
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+// postmanItem is a request, or a folder if Item is set.
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	Header []postmanHeader `json:"header"`
+	Url    postmanUrl      `json:"url"`
+	Body   *postmanBody    `json:"body"`
+}
+
+type postmanUrl struct {
+	Raw   string          `json:"raw"`
+	Host  []string        `json:"host"`
+	Path  []string        `json:"path"`
+	Query []postmanHeader `json:"query"`
+}
+
+type postmanHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanBody struct {
+	Mode       string          `json:"mode"`
+	Raw        string          `json:"raw"`
+	UrlEncoded []postmanHeader `json:"urlencoded"`
+}
+
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	Code            int             `json:"code"`
+	Header          []postmanHeader `json:"header"`
+	Body            string          `json:"body"`
+	PreviewLanguage string          `json:"_postman_previewlanguage"`
+}
+
+type postmanVariable struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// UnmarshalJSON accepts a request given only as a URL string, as well as
+// a request object.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var rawUrl string
+	if err := json.Unmarshal(data, &rawUrl); err == nil {
+		*r = postmanRequest{Method: http.MethodGet, Url: postmanUrl{Raw: rawUrl}}
+		return nil
+	}
+	type request postmanRequest
+	return json.Unmarshal(data, (*request)(r))
+}
+
+// UnmarshalJSON accepts a URL given as a string, as well as a URL object.
+func (u *postmanUrl) UnmarshalJSON(data []byte) error {
+	var rawUrl string
+	if err := json.Unmarshal(data, &rawUrl); err == nil {
+		*u = postmanUrl{Raw: rawUrl}
+		return nil
+	}
+	type postmanUrlObject postmanUrl
+	return json.Unmarshal(data, (*postmanUrlObject)(u))
+}
+
+// ImportPostman converts the requests in a Postman collection, and their
+// saved example responses, into Imposter configuration and response files
+// in dir. Requests without examples respond with an empty 200 response.
+// Collection variables are substituted, and path variables, such as ':id',
+// become path parameters. If folderPrefixes is set, the path of each
+// request is prefixed with the names of the folders containing it. It
+// returns the number of responses imported.
+func ImportPostman(collectionPath string, dir string, options RecorderOptions, folderPrefixes bool) (int, error) {
+	content, err := os.ReadFile(collectionPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Postman collection: %s: %v", collectionPath, err)
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(content, &collection); err != nil {
+		return 0, fmt.Errorf("failed to parse Postman collection: %s: %v", collectionPath, err)
+	}
+	options.OutputFormats = []string{OutputFormatImposter}
+
+	variables := make(map[string]string)
+	for _, variable := range collection.Variable {
+		if variable.Value != nil {
+			variables[variable.Key] = fmt.Sprint(variable.Value)
+		}
+	}
+	importer := &postmanImporter{
+		dir:            dir,
+		options:        options,
+		variables:      variables,
+		folderPrefixes: folderPrefixes,
+		recorders:      make(map[string]*recorder),
+	}
+	if err := importer.importItems(collection.Item, nil); err != nil {
+		return importer.imported, err
+	}
+	for _, r := range importer.recorders {
+		if err := r.writeDocuments(); err != nil {
+			return importer.imported, err
+		}
+	}
+	logger.Debugf("imported %d responses from Postman collection %s for %d host(s)", importer.imported, collection.Info.Name, len(importer.recorders))
+	return importer.imported, nil
+}
+
+type postmanImporter struct {
+	dir            string
+	options        RecorderOptions
+	variables      map[string]string
+	folderPrefixes bool
+	recorders      map[string]*recorder
+	imported       int
+}
+
+func (p *postmanImporter) importItems(items []postmanItem, folders []string) error {
+	for _, item := range items {
+		if item.Request == nil {
+			if err := p.importItems(item.Item, append(folders, item.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+		upstream, exchanges, err := p.parseItem(item, folders)
+		if err != nil {
+			logger.Warnf("skipping Postman request %s: %v", item.Name, err)
+			continue
+		}
+		r, ok := p.recorders[upstream]
+		if !ok {
+			r, err = newRecorder(upstream, p.dir, p.options)
+			if err != nil {
+				return err
+			}
+			p.recorders[upstream] = r
+		}
+		for _, exchange := range exchanges {
+			if err := r.recordExchange(exchange); err != nil {
+				return err
+			}
+			p.imported++
+		}
+	}
+	return nil
+}
+
+// parseItem converts a request, and each of its example responses, to
+// exchanges, returning the upstream base URL, such as https://example.com
+func (p *postmanImporter) parseItem(item postmanItem, folders []string) (string, []HttpExchange, error) {
+	upstream, requestUrl, err := p.resolveUrl(item.Request.Url)
+	if err != nil {
+		return "", nil, err
+	}
+	if p.folderPrefixes && len(folders) > 0 {
+		var prefix []string
+		for _, folder := range folders {
+			prefix = append(prefix, slugify(folder))
+		}
+		requestUrl.Path = "/" + strings.Join(prefix, "/") + requestUrl.Path
+	}
+	method := strings.ToUpper(item.Request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var requestBody []byte
+	if body := item.Request.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			requestBody = []byte(p.substitute(body.Raw))
+		case "urlencoded":
+			form := url.Values{}
+			for _, param := range body.UrlEncoded {
+				if !param.Disabled {
+					form.Add(param.Key, p.substitute(param.Value))
+				}
+			}
+			requestBody = []byte(form.Encode())
+		}
+	}
+
+	responses := item.Response
+	if len(responses) == 0 {
+		responses = []postmanResponse{{Code: http.StatusOK}}
+	}
+	var exchanges []HttpExchange
+	for _, response := range responses {
+		req := &http.Request{
+			Method: method,
+			URL:    &url.URL{Path: requestUrl.Path, RawQuery: requestUrl.RawQuery},
+			Header: p.toHeaders(item.Request.Header),
+		}
+		statusCode := response.Code
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		responseHeaders := p.toHeaders(response.Header)
+		if responseHeaders.Get("Content-Type") == "" {
+			switch response.PreviewLanguage {
+			case "json":
+				responseHeaders.Set("Content-Type", "application/json")
+			case "xml":
+				responseHeaders.Set("Content-Type", "application/xml")
+			case "html":
+				responseHeaders.Set("Content-Type", "text/html")
+			}
+		}
+
+		// saved bodies are decoded, so the original encoding no longer applies
+		responseHeaders.Del("Content-Encoding")
+
+		responseBody := []byte(response.Body)
+		body := requestBody
+		exchanges = append(exchanges, HttpExchange{
+			Request:         req,
+			RequestBody:     &body,
+			StatusCode:      statusCode,
+			ResponseBody:    &responseBody,
+			ResponseHeaders: &responseHeaders,
+		})
+	}
+	return upstream, exchanges, nil
+}
+
+// resolveUrl substitutes the collection variables in the URL, returning
+// the upstream base URL and the request URL. Path variables, and
+// variables without a value, become path parameters.
+func (p *postmanImporter) resolveUrl(u postmanUrl) (string, *url.URL, error) {
+	rawUrl := u.Raw
+	if rawUrl == "" {
+		rawUrl = strings.Join(u.Host, ".") + "/" + strings.Join(u.Path, "/")
+	}
+	rawUrl = p.substitute(rawUrl)
+
+	upstream := ""
+	if strings.HasPrefix(rawUrl, "{{") {
+		// the host is a variable without a value
+		_, rawUrl, _ = strings.Cut(rawUrl, "}}")
+		upstream = defaultPostmanUpstream
+		rawUrl = upstream + "/" + strings.TrimPrefix(rawUrl, "/")
+	} else if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid request URL: %v", err)
+	}
+	if parsed.Host == "" {
+		return "", nil, fmt.Errorf("request URL has no host: %s", u.Raw)
+	}
+	if upstream == "" {
+		upstream = parsed.Scheme + "://" + parsed.Host
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = "{" + segment[1:] + "}"
+		} else if match := postmanVariablePattern.FindStringSubmatch(segment); match != nil && match[0] == segment {
+			segments[i] = "{" + match[1] + "}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return upstream, &url.URL{Path: path, RawQuery: parsed.RawQuery}, nil
+}
+
+// substitute replaces the collection variables in s with their values.
+// Variables without a value are left in place.
+func (p *postmanImporter) substitute(s string) string {
+	return postmanVariablePattern.ReplaceAllStringFunc(s, func(variable string) string {
+		if value, found := p.variables[strings.TrimSpace(variable[2:len(variable)-2])]; found {
+			return value
+		}
+		return variable
+	})
+}
+
+func (p *postmanImporter) toHeaders(pairs []postmanHeader) http.Header {
+	header := http.Header{}
+	for _, pair := range pairs {
+		if !pair.Disabled {
+			header.Add(pair.Key, p.substitute(pair.Value))
+		}
+	}
+	return header
+}
+
+// slugify converts a folder name, such as 'User Accounts', to a path
+// segment, such as 'user-accounts'.
+func slugify(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			sb.WriteRune(c)
+		case c == ' ' || c == '/':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
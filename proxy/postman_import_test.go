@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPostmanCollection = `{
+  "info": {"name": "Pet Store", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "baseUrl", "value": "https://example.com"}],
+  "item": [
+    {
+      "name": "Pets",
+      "item": [
+        {
+          "name": "List pets",
+          "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/pets?limit=10", "host": ["{{baseUrl}}"], "path": ["pets"]}},
+          "response": [
+            {"name": "OK", "code": 200, "_postman_previewlanguage": "json", "body": "[{\"id\":1}]"}
+          ]
+        },
+        {
+          "name": "Get pet",
+          "request": {"method": "GET", "url": "{{baseUrl}}/pets/:petId"},
+          "response": [
+            {"name": "OK", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{\"id\":1}"}
+          ]
+        }
+      ]
+    },
+    {
+      "name": "Create order",
+      "request": {"method": "POST", "url": "{{orderUrl}}/orders", "body": {"mode": "raw", "raw": "{\"petId\":1}"}}
+    }
+  ]
+}`
+
+func TestImportPostman(t *testing.T) {
+	tests := []struct {
+		name           string
+		folderPrefixes bool
+		wantFiles      []string
+		wantPaths      []string
+	}{
+		{
+			name:      "paths from URLs",
+			wantFiles: []string{"example.com-config.yaml", "localhost-config.yaml", "GET-pets.json"},
+			wantPaths: []string{"/pets", "/pets/{petId}"},
+		},
+		{
+			name:           "folders as path prefixes",
+			folderPrefixes: true,
+			wantFiles:      []string{"example.com-config.yaml", "localhost-config.yaml", "pets/GET-pets.json"},
+			wantPaths:      []string{"/pets/pets", "/pets/pets/{petId}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			collectionPath := filepath.Join(dir, "collection.json")
+			if err := os.WriteFile(collectionPath, []byte(testPostmanCollection), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			imported, err := ImportPostman(collectionPath, dir, RecorderOptions{IgnoreDuplicateRequests: true}, tt.folderPrefixes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported != 3 {
+				t.Errorf("expected 3 responses to be imported, got %d", imported)
+			}
+			for _, file := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+					t.Errorf("expected file %s to exist: %v", file, err)
+				}
+			}
+			config, err := os.ReadFile(filepath.Join(dir, "example.com-config.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range tt.wantPaths {
+				if !strings.Contains(string(config), "path: "+path) {
+					t.Errorf("expected config to contain path %s: %s", path, config)
+				}
+			}
+		})
+	}
+}
+
+func Test_postmanImporter_resolveUrl(t *testing.T) {
+	p := &postmanImporter{variables: map[string]string{"baseUrl": "https://api.example.com/v1"}}
+	tests := []struct {
+		name         string
+		url          postmanUrl
+		wantUpstream string
+		wantPath     string
+		wantQuery    string
+		wantErr      bool
+	}{
+		{name: "variable host", url: postmanUrl{Raw: "{{baseUrl}}/users"}, wantUpstream: "https://api.example.com", wantPath: "/v1/users"},
+		{name: "path variable", url: postmanUrl{Raw: "{{baseUrl}}/users/:id"}, wantUpstream: "https://api.example.com", wantPath: "/v1/users/{id}"},
+		{name: "unresolved path variable", url: postmanUrl{Raw: "{{baseUrl}}/users/{{userId}}"}, wantUpstream: "https://api.example.com", wantPath: "/v1/users/{userId}"},
+		{name: "unresolved host", url: postmanUrl{Raw: "{{host}}/status?verbose=true"}, wantUpstream: "http://localhost", wantPath: "/status", wantQuery: "verbose=true"},
+		{name: "no scheme", url: postmanUrl{Raw: "example.com/status"}, wantUpstream: "http://example.com", wantPath: "/status"},
+		{name: "from parts", url: postmanUrl{Host: []string{"example", "com"}, Path: []string{"a", "b"}}, wantUpstream: "http://example.com", wantPath: "/a/b"},
+		{name: "root", url: postmanUrl{Raw: "https://example.com"}, wantUpstream: "https://example.com", wantPath: "/"},
+		{name: "no host", url: postmanUrl{Raw: "/status"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, got, err := p.resolveUrl(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if upstream != tt.wantUpstream {
+				t.Errorf("resolveUrl() upstream = %v, want %v", upstream, tt.wantUpstream)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("resolveUrl() path = %v, want %v", got.Path, tt.wantPath)
+			}
+			if got.RawQuery != tt.wantQuery {
+				t.Errorf("resolveUrl() query = %v, want %v", got.RawQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func Test_slugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Pets", want: "pets"},
+		{name: "User Accounts", want: "user-accounts"},
+		{name: "Orders (v2)", want: "orders-v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slugify(tt.name); got != tt.want {
+				t.Errorf("slugify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,18 +23,48 @@ import (
 	"gatehill.io/imposter/stringutil"
 	"github.com/spf13/viper"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 type HttpExchange struct {
 	Request         *http.Request
+	RequestBody     *[]byte
 	StatusCode      int
 	ResponseBody    *[]byte
 	ResponseHeaders *http.Header
+
+	// StartedDateTime is when the request was received, and Duration is
+	// the time taken for the upstream to respond.
+	StartedDateTime time.Time
+	Duration        time.Duration
+
+	// BodyTruncated is set if the request or response body exceeded the
+	// capture limit, so the captured body is incomplete.
+	BodyTruncated bool
+
+	// TraceId is the W3C trace ID propagated to the upstream, if
+	// tracing is enabled.
+	TraceId string
+
+	// WebSocketMessages are the messages exchanged after a WebSocket
+	// handshake, in which case StatusCode is 101.
+	WebSocketMessages []WebSocketMessage
 }
 
+// DefaultMaxRecordBodySize is the default limit, in bytes, on the request
+// and response bodies captured for recording.
+const DefaultMaxRecordBodySize int64 = 10 * 1024 * 1024
+
+// DefaultHost is the address on which the proxy listens by default, so
+// that it is only reachable from the local machine.
+const DefaultHost = "127.0.0.1"
+
 var skipProxyHeaders = []string{
 	"Accept-Encoding",
 
@@ -65,115 +95,354 @@ var skipRecordHeaders = []string{
 
 var logger = logging.GetLogger()
 
-var transport *http.Transport
+const defaultMaxIdleConns = 100
+const defaultIdleConnTimeout = 90 * time.Second
+
+var client *http.Client
+var clientOnce sync.Once
 
-func init() {
-	transport = &http.Transport{
-		DisableCompression: true,
-		MaxIdleConns:       viper.GetInt("proxy.maxIdleConns"),
-		IdleConnTimeout:    viper.GetDuration("proxy.idleConnTimeout"),
+// getClient returns the HTTP client shared by all proxied requests, so
+// connections to the upstream are kept alive and reused. It is created on
+// first use, rather than at package initialisation, so the settings from
+// the CLI configuration file are applied.
+func getClient() *http.Client {
+	clientOnce.Do(func() {
+		transport := buildTransport()
+		logger.Tracef("initialised proxy transport: %+v", transport)
+		var roundTripper http.RoundTripper = transport
+		if upstreamOptions.Http2 {
+			roundTripper = withH2c(transport, newDialer())
+		}
+		client = &http.Client{Transport: roundTripper}
+	})
+	return client
+}
+
+// buildTransport returns a transport with a connection pool sized using the
+// 'proxy' configuration settings. As the proxy usually forwards to a single
+// upstream, the per-host idle connection limit defaults to the overall limit.
+// If upstream TLS settings are configured, they are used for HTTPS upstreams.
+func buildTransport() *http.Transport {
+	maxIdleConns := viper.GetInt("proxy.maxIdleConns")
+	if maxIdleConns <= 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+	maxIdleConnsPerHost := viper.GetInt("proxy.maxIdleConnsPerHost")
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = maxIdleConns
+	}
+	idleConnTimeout := viper.GetDuration("proxy.idleConnTimeout")
+	if idleConnTimeout <= 0 {
+		idleConnTimeout = defaultIdleConnTimeout
+	}
+	dialer := newDialer()
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		DisableCompression:    true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       viper.GetInt("proxy.maxConnsPerHost"),
+		IdleConnTimeout:       idleConnTimeout,
+		ResponseHeaderTimeout: upstreamOptions.Timeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     upstreamOptions.Http2,
+	}
+	if upstreamTls != nil {
+		transport.DialTLSContext = upstreamTls.dialTls(dialer)
+	}
+	return transport
+}
+
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
 	}
-	logger.Tracef("initialised proxy transport: %+v", transport)
 }
 
+// BodyRewriter returns a function to rewrite the response body, or nil if
+// the response body should be streamed to the client unmodified.
+type BodyRewriter func(respHeaders *http.Header) func(body *[]byte) *[]byte
+
+// Handle proxies the request to the upstream, streaming the request and
+// response bodies, then passes the exchange to the listener. WebSocket
+// connections are relayed until closed. Up to
+// maxRecordBodySize bytes of each body are captured for the exchange;
+// a limit of zero or less captures bodies in full.
+//
+// If the rewriter returns a function for the response, the response body
+// is read in full so it can be rewritten before it is sent to the client.
 func Handle(
 	upstream string,
 	w http.ResponseWriter,
 	req *http.Request,
-	listener func(statusCode int, respBody *[]byte, respHeaders *http.Header) (*[]byte, *http.Header),
+	maxRecordBodySize int64,
+	rewriter BodyRewriter,
+	listener func(exchange HttpExchange),
 ) {
+	if isWebSocketUpgrade(req) {
+		handleWebSocket(upstream, w, req, maxRecordBodySize, listener)
+		return
+	}
 	startTime := time.Now()
+	statusCode := http.StatusBadGateway
+	defer func() {
+		sessionStats.Record(req.Method, req.URL.Path, statusCode, time.Since(startTime))
+	}()
 
 	client := req.RemoteAddr
 	logger.Debugf("received request %v %v from client %v", req.Method, req.URL, client)
 
-	path, queryString, clientReqHeaders, requestBody, err := parseRequest(req)
-	if err != nil {
-		logger.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
+	defer req.Body.Close()
+	requestCapture := newCaptureBuffer(maxRecordBodySize)
+	requestBody := io.TeeReader(req.Body, requestCapture)
 
-	statusCode, responseBody, respHeaders, err := forward(upstream, req.Method, path, queryString, clientReqHeaders, requestBody)
+	span := startSpan(req)
+	upstreamHeaders := span.propagate(req.Header)
+	resp, err := forward(upstream, req.Method, req.URL.Path, req.URL.RawQuery, &upstreamHeaders, requestBody, req.ContentLength)
 	if err != nil {
 		logger.Error(err)
 		w.WriteHeader(http.StatusBadGateway)
+		span.end(upstream, http.StatusBadGateway)
 		return
 	}
+	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 
-	responseBody, respHeaders = listener(statusCode, responseBody, respHeaders)
+	activeRewriteRules.rewriteResponseHeaders(resp.Header)
+	var rewriteBody func(body *[]byte) *[]byte
+	if rewriter != nil {
+		rewriteBody = rewriter(&resp.Header)
+	}
+	rewriteBody = activeRewriteRules.wrapBodyRewrite(&resp.Header, rewriteBody)
 
-	err = sendResponse(w, respHeaders, statusCode, responseBody, client)
+	declareTrailers(w, resp)
+	responseCapture := newCaptureBuffer(maxRecordBodySize)
+	var bodySize int64
+	if rewriteBody != nil {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			logger.Errorf("error reading response body: %v", err)
+			w.WriteHeader(http.StatusBadGateway)
+			span.end(upstream, http.StatusBadGateway)
+			return
+		}
+		rewritten := rewriteBody(&body)
+		resp.Header.Set("Content-Length", strconv.Itoa(len(*rewritten)))
+		_, _ = responseCapture.Write(*rewritten)
+		bodySize, err = sendResponse(w, &resp.Header, resp.StatusCode, bytes.NewReader(*rewritten), false, client)
+	} else {
+		bodySize, err = sendResponse(w, &resp.Header, resp.StatusCode, io.TeeReader(resp.Body, responseCapture), resp.ContentLength < 0, client)
+	}
 	if err != nil {
+		// the status has already been sent to the client
 		logger.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		span.end(upstream, resp.StatusCode)
 		return
 	}
+	sendTrailers(w, resp.Trailer)
+	span.end(upstream, resp.StatusCode)
 
 	elapsed := time.Since(startTime)
-	logger.Infof("proxied %s %v to upstream [status: %v, body %v bytes] for client %v in %v", req.Method, req.URL, statusCode, len(*responseBody), client, elapsed)
-}
+	listener(HttpExchange{
+		Request:         req,
+		RequestBody:     requestCapture.bytes(),
+		StatusCode:      resp.StatusCode,
+		ResponseBody:    responseCapture.bytes(),
+		ResponseHeaders: &resp.Header,
+		StartedDateTime: startTime,
+		Duration:        elapsed,
+		BodyTruncated:   requestCapture.truncated || responseCapture.truncated,
+		TraceId:         span.traceId(),
+	})
 
-func parseRequest(req *http.Request) (path string, queryString string, headers *http.Header, body *[]byte, err error) {
-	defer req.Body.Close()
-	requestBody, err := io.ReadAll(req.Body)
-	if err != nil {
-		return "", "", nil, nil, fmt.Errorf("error parsing request body: %v", err)
-	}
-	return req.URL.Path, req.URL.RawQuery, &req.Header, &requestBody, nil
+	logger.Infof("proxied %s %v to upstream [status: %v, body %v bytes] for client %v in %v", req.Method, req.URL, resp.StatusCode, bodySize, client, elapsed)
 }
 
+// forward sends the request to the upstream, returning the response with
+// its body unread. The caller must close the response body. If the retry
+// policy permits the request to be retried, its body is read in full, so
+// it can be resent.
 func forward(
 	upstream string,
 	httpMethod string,
 	path string,
 	queryString string,
 	clientRequestHeaders *http.Header,
-	requestBody *[]byte,
-) (statusCode int, responseBody *[]byte, upstreamRespHeaders *http.Header, err error) {
-	logger.Debugf("invoking upstream %s with %s %s [body: %v bytes]", upstream, httpMethod, path, len(*requestBody))
+	requestBody io.Reader,
+	contentLength int64,
+) (*http.Response, error) {
+	logger.Debugf("invoking upstream %s with %s %s [body: %v bytes]", upstream, httpMethod, path, contentLength)
 
-	upstreamUrl, err := url.JoinPath(upstream, path)
+	upstreamUrl, err := url.JoinPath(upstream, activeRewriteRules.rewritePath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upstream URL: %v", err)
+	}
 	if queryString != "" {
 		upstreamUrl += "?" + queryString
 	}
-	if err != nil {
-		return 0, nil, nil, fmt.Errorf("failed to build upstream URL: %v", err)
-	}
 	logger.Tracef("upstream url: %s", upstreamUrl)
 
-	req, err := http.NewRequest(httpMethod, upstreamUrl, bytes.NewReader(*requestBody))
+	retry := upstreamOptions.Retry
+	canRetry := retry.canRetry(httpMethod)
+	var bufferedBody []byte
+	if canRetry && contentLength != 0 {
+		if bufferedBody, err = io.ReadAll(requestBody); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		contentLength = int64(len(bufferedBody))
+	}
+
+	for attempt := 0; ; attempt++ {
+		body := requestBody
+		if bufferedBody != nil {
+			body = bytes.NewReader(bufferedBody)
+		}
+		req, err := newUpstreamRequest(httpMethod, upstreamUrl, clientRequestHeaders, body, contentLength)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := getClient().Do(req)
+		if canRetry && attempt < retry.Retries && retry.shouldRetry(resp, err) {
+			delay := backoff(attempt)
+			if err != nil {
+				logger.Warnf("retrying %s %s in %v after error: %v", httpMethod, upstreamUrl, delay, err)
+			} else {
+				logger.Warnf("retrying %s %s in %v after status %d", httpMethod, upstreamUrl, delay, resp.StatusCode)
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+			}
+			time.Sleep(delay)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && upstreamAuth.appliesTo(req.URL) {
+			upstreamAuth.invalidate()
+		}
+		logger.Debugf("upstream responded to %s %s with status %d", httpMethod, upstreamUrl, resp.StatusCode)
+		return resp, nil
+	}
+}
+
+// newUpstreamRequest builds a request to the upstream, with the client
+// headers, rewrite rules and upstream credentials applied.
+func newUpstreamRequest(httpMethod string, upstreamUrl string, clientRequestHeaders *http.Header, requestBody io.Reader, contentLength int64) (*http.Request, error) {
+	if contentLength == 0 {
+		requestBody = http.NoBody
+	}
+	req, err := http.NewRequest(httpMethod, upstreamUrl, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upstream request: %v", err)
+	}
+	req.ContentLength = contentLength
 	upstreamReqHeaders := req.Header
 	copyHeaders(clientRequestHeaders, &upstreamReqHeaders)
 
-	client := &http.Client{Transport: transport}
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, nil, nil, err
+	// 'TE: trailers' is the only value permitted in HTTP/2 requests, and
+	// is required by some gRPC servers
+	if strings.Contains(strings.ToLower(clientRequestHeaders.Get("TE")), "trailers") {
+		upstreamReqHeaders.Set("TE", "trailers")
 	}
-	defer resp.Body.Close()
+	if err := activeRewriteRules.rewriteRequest(req); err != nil {
+		return nil, err
+	}
+	if err := upstreamAuth.apply(req); err != nil {
+		return nil, fmt.Errorf("failed to authenticate to upstream: %v", err)
+	}
+	return req, nil
+}
 
-	var respBody []byte
-	if resp.Body != nil {
-		respBody, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return 0, nil, nil, fmt.Errorf("error parsing request body: %v", err)
+// sendResponse writes the status and headers to the client, then copies
+// the body. If flush is set, each chunk of the body is flushed to the client
+// as it is read, such as for server-sent events.
+func sendResponse(w http.ResponseWriter, headers *http.Header, statusCode int, body io.Reader, flush bool, client string) (written int64, err error) {
+	clientRespHeaders := w.Header()
+	copyHeaders(headers, &clientRespHeaders)
+	w.WriteHeader(statusCode)
+
+	flusher, canFlush := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return written, fmt.Errorf("error writing response: %v", err)
+			}
+			written += int64(n)
+			if flush && canFlush {
+				flusher.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return written, fmt.Errorf("error reading upstream response body: %v", readErr)
 		}
 	}
-	logger.Debugf("upstream responded to %s %s with status %d [body %v bytes]", httpMethod, upstreamUrl, resp.StatusCode, len(respBody))
-	return resp.StatusCode, &respBody, &resp.Header, nil
+
+	logger.Debugf("wrote response [status: %v, body %v bytes] to client %v", statusCode, written, client)
+	return written, nil
+}
+
+// declareTrailers announces the trailers the upstream declared before its
+// response body. The content length is removed, so the response to an
+// HTTP/1.1 client is chunked, which permits trailers.
+func declareTrailers(w http.ResponseWriter, resp *http.Response) {
+	if len(resp.Trailer) == 0 {
+		return
+	}
+	resp.Header.Del("Content-Length")
+	for name := range resp.Trailer {
+		w.Header().Add("Trailer", name)
+	}
 }
 
-func sendResponse(w http.ResponseWriter, headers *http.Header, statusCode int, body *[]byte, client string) (err error) {
+// sendTrailers writes the trailers received from the upstream, such as the
+// gRPC status, to the client after the response body. Trailers the upstream
+// did not declare are only sent if the response was streamed to the client.
+func sendTrailers(w http.ResponseWriter, trailers http.Header) {
 	clientRespHeaders := w.Header()
-	copyHeaders(headers, &clientRespHeaders)
-	_, err = w.Write(*body)
-	if err != nil {
-		return fmt.Errorf("error writing response: %v", err)
+	for name, values := range trailers {
+		for _, value := range values {
+			clientRespHeaders.Add(http.TrailerPrefix+name, value)
+		}
+	}
+}
+
+// captureBuffer holds up to a limit of the bytes written to it, discarding
+// the remainder. Writes always succeed, so it can be used with io.TeeReader
+// without interrupting the stream.
+type captureBuffer struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func newCaptureBuffer(limit int64) *captureBuffer {
+	return &captureBuffer{limit: limit}
+}
+
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	if c.limit > 0 {
+		remaining := c.limit - int64(c.buf.Len())
+		if int64(len(p)) > remaining {
+			c.truncated = true
+			if remaining > 0 {
+				c.buf.Write(p[:remaining])
+			}
+			return len(p), nil
+		}
 	}
+	c.buf.Write(p)
+	return len(p), nil
+}
 
-	logger.Debugf("wrote response [status: %v, body %v bytes] to client %v", statusCode, len(*body), client)
-	return nil
+func (c *captureBuffer) bytes() *[]byte {
+	b := c.buf.Bytes()
+	return &b
 }
 
 // copyHeaders copies all headers from source to destination, unless the name
@@ -187,3 +456,50 @@ func copyHeaders(source *http.Header, destination *http.Header) {
 		}
 	}
 }
+
+// BuildProxyBaseUrl returns the URL at which the proxy is accessible,
+// such as http://localhost:8080
+func BuildProxyBaseUrl(port int, tls bool) string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, port)
+}
+
+// buildRewriter returns a BodyRewriter replacing the upstream URL with the
+// proxy URL in text responses, or nil if rewrite is not set.
+func buildRewriter(upstream string, proxyBaseUrl string, rewrite bool) BodyRewriter {
+	if !rewrite {
+		return nil
+	}
+	return func(respHeaders *http.Header) func(body *[]byte) *[]byte {
+		if !shouldRewrite(respHeaders) {
+			return nil
+		}
+		return func(body *[]byte) *[]byte {
+			return Rewrite(respHeaders, body, upstream, proxyBaseUrl)
+		}
+	}
+}
+
+// BuildRecordingMux returns a handler that proxies requests to the upstream,
+// sending each exchange to the recorder channel, as well as serving the
+// status, recording control and exchange events endpoints.
+func BuildRecordingMux(upstream string, proxyBaseUrl string, rewrite bool, maxRecordBodySize int64, recorderC chan HttpExchange) *http.ServeMux {
+	rewriter := buildRewriter(upstream, proxyBaseUrl, rewrite)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(statusPath, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprintf(writer, "ok\n")
+	})
+	mux.HandleFunc(recordingControlPath, requireControlAccess(handleRecordingControl))
+	mux.HandleFunc(recordingControlPath+"/", requireControlAccess(handleRecordingControl))
+	mux.HandleFunc(ExchangeEventsPath, requireControlAccess(handleExchangeEvents))
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		Handle(upstream, writer, request, maxRecordBodySize, rewriter, func(exchange HttpExchange) {
+			recorderC <- exchange
+		})
+	})
+	return mux
+}
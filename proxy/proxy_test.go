@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandle(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "%s from %s", body, "http://"+r.Host)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name              string
+		maxRecordBodySize int64
+		rewrite           bool
+		wantBody          string
+		wantRecordedBody  string
+		wantTruncated     bool
+	}{
+		{
+			name:             "stream full body",
+			wantBody:         "hello from " + upstream.URL,
+			wantRecordedBody: "hello from " + upstream.URL,
+		},
+		{
+			name:              "truncate recorded body",
+			maxRecordBodySize: 5,
+			wantBody:          "hello from " + upstream.URL,
+			wantRecordedBody:  "hello",
+			wantTruncated:     true,
+		},
+		{
+			name:             "rewrite body",
+			rewrite:          true,
+			wantBody:         "hello from http://proxy",
+			wantRecordedBody: "hello from http://proxy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rewriter BodyRewriter
+			if tt.rewrite {
+				rewriter = func(respHeaders *http.Header) func(body *[]byte) *[]byte {
+					return func(body *[]byte) *[]byte {
+						return Rewrite(respHeaders, body, upstream.URL, "http://proxy")
+					}
+				}
+			}
+			var exchange HttpExchange
+			req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello"))
+			w := httptest.NewRecorder()
+			Handle(upstream.URL, w, req, tt.maxRecordBodySize, rewriter, func(e HttpExchange) {
+				exchange = e
+			})
+
+			if w.Code != http.StatusCreated {
+				t.Errorf("status = %v, want %v", w.Code, http.StatusCreated)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %v, want %v", got, tt.wantBody)
+			}
+			if got := string(*exchange.ResponseBody); got != tt.wantRecordedBody {
+				t.Errorf("recorded body = %v, want %v", got, tt.wantRecordedBody)
+			}
+			if got := string(*exchange.RequestBody); got != "hello" {
+				t.Errorf("recorded request body = %v, want hello", got)
+			}
+			if exchange.BodyTruncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", exchange.BodyTruncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func Test_buildTransport(t *testing.T) {
+	tests := []struct {
+		name                    string
+		settings                map[string]interface{}
+		wantMaxIdleConns        int
+		wantMaxIdleConnsPerHost int
+		wantIdleConnTimeout     time.Duration
+	}{
+		{
+			name:                    "defaults",
+			wantMaxIdleConns:        defaultMaxIdleConns,
+			wantMaxIdleConnsPerHost: defaultMaxIdleConns,
+			wantIdleConnTimeout:     defaultIdleConnTimeout,
+		},
+		{
+			name: "configured",
+			settings: map[string]interface{}{
+				"proxy.maxIdleConns":        20,
+				"proxy.maxIdleConnsPerHost": 10,
+				"proxy.idleConnTimeout":     "30s",
+			},
+			wantMaxIdleConns:        20,
+			wantMaxIdleConnsPerHost: 10,
+			wantIdleConnTimeout:     30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+			defer func() {
+				for key := range tt.settings {
+					viper.Set(key, nil)
+				}
+			}()
+			transport := buildTransport()
+			if transport.MaxIdleConns != tt.wantMaxIdleConns {
+				t.Errorf("MaxIdleConns = %v, want %v", transport.MaxIdleConns, tt.wantMaxIdleConns)
+			}
+			if transport.MaxIdleConnsPerHost != tt.wantMaxIdleConnsPerHost {
+				t.Errorf("MaxIdleConnsPerHost = %v, want %v", transport.MaxIdleConnsPerHost, tt.wantMaxIdleConnsPerHost)
+			}
+			if transport.IdleConnTimeout != tt.wantIdleConnTimeout {
+				t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, tt.wantIdleConnTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit permits a number of requests per period, such as 10/s.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// PathRateLimit applies a rate limit to requests whose path matches the
+// pattern. All matching requests share the limit.
+type PathRateLimit struct {
+	Pattern string
+	Limit   RateLimit
+	matcher *regexp.Regexp
+}
+
+// RateLimitOptions limits the rate of proxied requests. Requests exceeding
+// either the global limit, or the first path limit matching the request,
+// receive a 429 response and are not forwarded to the upstream.
+type RateLimitOptions struct {
+	Global RateLimit
+	Paths  []PathRateLimit
+}
+
+// Enabled determines if any rate limit is set.
+func (o RateLimitOptions) Enabled() bool {
+	return o.Global.Requests > 0 || len(o.Paths) > 0
+}
+
+var rateLimitPeriods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+func (l RateLimit) String() string {
+	if l.Requests <= 0 {
+		return "none"
+	}
+	for unit, per := range rateLimitPeriods {
+		if per == l.Per {
+			return fmt.Sprintf("%d/%s", l.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%v", l.Requests, l.Per)
+}
+
+// ParseRateLimit parses a limit in the form N/s, N/m or N/h.
+func ParseRateLimit(spec string) (RateLimit, error) {
+	if spec == "" {
+		return RateLimit{}, nil
+	}
+	count, unit, found := strings.Cut(spec, "/")
+	per, validUnit := rateLimitPeriods[unit]
+	if !found || !validUnit {
+		return RateLimit{}, fmt.Errorf("invalid rate limit: %s - must be in the form N/s, N/m or N/h", spec)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit: %s - the number of requests must be a positive integer", spec)
+	}
+	return RateLimit{Requests: requests, Per: per}, nil
+}
+
+// ParsePathRateLimits parses limits in the form PATTERN=N/s, where the
+// pattern is a glob, or a regular expression prefixed with 'regex:'.
+func ParsePathRateLimits(specs []string) ([]PathRateLimit, error) {
+	var limits []PathRateLimit
+	for _, spec := range specs {
+		pattern, limitSpec, found := strings.Cut(spec, "=")
+		if !found || pattern == "" {
+			return nil, fmt.Errorf("invalid path rate limit: %s - must be in the form PATTERN=N/s", spec)
+		}
+		limit, err := ParseRateLimit(limitSpec)
+		if err != nil {
+			return nil, err
+		}
+		matchers, err := stringutil.CompilePathPatterns([]string{pattern})
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, PathRateLimit{Pattern: pattern, Limit: limit, matcher: matchers[0]})
+	}
+	return limits, nil
+}
+
+// tokenBucket holds up to the number of requests in the limit, refilled
+// continuously over the period, so short bursts are permitted.
+type tokenBucket struct {
+	limit    RateLimit
+	tokens   float64
+	lastFill time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit.Requests), lastFill: now}
+}
+
+// available returns true if a token is available, otherwise the time
+// until one will be.
+func (b *tokenBucket) available(now time.Time) (bool, time.Duration) {
+	rate := float64(b.limit.Requests) / float64(b.limit.Per)
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+float64(now.Sub(b.lastFill))*rate)
+	b.lastFill = now
+	if b.tokens >= 1 {
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate)
+}
+
+type rateLimitHandler struct {
+	next        http.Handler
+	mutex       sync.Mutex
+	global      *tokenBucket
+	paths       []PathRateLimit
+	pathBuckets []*tokenBucket
+	now         func() time.Time
+}
+
+// WithRateLimit returns a handler that limits the rate of requests passed
+// to next. The status endpoint is not limited.
+func WithRateLimit(next http.Handler, options RateLimitOptions) http.Handler {
+	h := &rateLimitHandler{next: next, paths: options.Paths, now: time.Now}
+	now := h.now()
+	if options.Global.Requests > 0 {
+		h.global = newTokenBucket(options.Global, now)
+	}
+	for _, path := range options.Paths {
+		h.pathBuckets = append(h.pathBuckets, newTokenBucket(path.Limit, now))
+	}
+	return h
+}
+
+func (h *rateLimitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if isControlPath(req.URL.Path) {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+	if allowed, retryAfter := h.allow(req.URL.Path); !allowed {
+		logger.Infof("rate limit exceeded for %s %v - retry after %v", req.Method, req.URL, retryAfter)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	h.next.ServeHTTP(w, req)
+}
+
+// allow takes a token from the global bucket and the bucket of the first
+// path limit matching the path, only if both have one available.
+func (h *rateLimitHandler) allow(path string) (bool, time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var buckets []*tokenBucket
+	if h.global != nil {
+		buckets = append(buckets, h.global)
+	}
+	for i, limit := range h.paths {
+		if limit.matcher.MatchString(path) {
+			buckets = append(buckets, h.pathBuckets[i])
+			break
+		}
+	}
+
+	now := h.now()
+	var retryAfter time.Duration
+	for _, bucket := range buckets {
+		if ok, wait := bucket.available(now); !ok && wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, 0
+}
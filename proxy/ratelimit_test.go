@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    RateLimit
+		wantErr bool
+	}{
+		{spec: "", want: RateLimit{}},
+		{spec: "10/s", want: RateLimit{Requests: 10, Per: time.Second}},
+		{spec: "100/m", want: RateLimit{Requests: 100, Per: time.Minute}},
+		{spec: "10", wantErr: true},
+		{spec: "10/d", wantErr: true},
+		{spec: "0/s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRateLimit(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRateLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rateLimitHandler(t *testing.T) {
+	paths, err := ParsePathRateLimits([]string{"/search/**=1/s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := WithRateLimit(next, RateLimitOptions{
+		Global: RateLimit{Requests: 3, Per: time.Second},
+		Paths:  paths,
+	}).(*rateLimitHandler)
+
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		path           string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "path limit permits first request", path: "/search/a", wantStatus: http.StatusOK},
+		{name: "path limit exceeded", path: "/search/b", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "other path permitted", path: "/users", wantStatus: http.StatusOK},
+		{name: "global limit permits third request", path: "/users", wantStatus: http.StatusOK},
+		{name: "global limit exceeded", path: "/users", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "status endpoint not limited", path: "/system/status", wantStatus: http.StatusOK},
+		{name: "tokens refilled", advance: time.Second, path: "/search/c", wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		w := serve(step.path)
+		if w.Code != step.wantStatus {
+			t.Errorf("%s: status = %d, want %d", step.name, w.Code, step.wantStatus)
+		}
+		if got := w.Header().Get("Retry-After"); got != step.wantRetryAfter {
+			t.Errorf("%s: Retry-After = %s, want %s", step.name, got, step.wantRetryAfter)
+		}
+	}
+}
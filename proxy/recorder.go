@@ -18,6 +18,8 @@ package proxy
 
 import (
 	"fmt"
+	"gatehill.io/imposter/failure"
+	"gatehill.io/imposter/fileutil"
 	"gatehill.io/imposter/impostermodel"
 	"gatehill.io/imposter/stringutil"
 	"github.com/google/uuid"
@@ -28,62 +30,419 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// documentWriteInterval is how often the HAR, OpenAPI and Pact documents
+// are written while exchanges are being recorded.
+const documentWriteInterval = time.Second
+
+// startedRecorders holds a channel for each started recorder, on which
+// requests to write its documents are sent.
+var startedRecorders = struct {
+	mu     sync.Mutex
+	flushC []chan chan struct{}
+}{}
+
 type RecorderOptions struct {
 	IgnoreDuplicateRequests   bool
 	RecordOnlyResponseHeaders []string
 	FlatResponseFileStructure bool
+
+	// ContentAddressed stores each response body in a file named by the
+	// hash of its content, so identical bodies are stored once, even
+	// across recordings to the same directory.
+	ContentAddressed bool
+
+	// OutputFormats are the formats in which exchanges are recorded. If
+	// empty, exchanges are recorded as Imposter configuration.
+	OutputFormats []string
+
+	// DelayProfile generates response delays for recorded resources.
+	DelayProfile impostermodel.DelayProfile
+
+	// RecordPaths and IgnorePaths are glob patterns, or regular expressions
+	// prefixed with 'regex:', matched against the request path. If any
+	// RecordPaths are set, only matching requests are recorded.
+	RecordPaths []string
+	IgnorePaths []string
+
+	// RecordMethods and IgnoreMethods filter recorded requests by method.
+	RecordMethods []string
+	IgnoreMethods []string
+
+	// Redaction removes sensitive values from exchanges before they are
+	// recorded. Credential headers, such as Authorization, are always
+	// redacted.
+	Redaction RedactionRules
+
+	// RecordWebSockets records the messages exchanged over WebSocket
+	// connections to a transcript file.
+	RecordWebSockets bool
+
+	// MaxBodySize is the limit, in bytes, on the request and response bodies
+	// captured for recording. Exchanges exceeding it are proxied but not
+	// recorded. If zero, bodies are captured in full.
+	MaxBodySize int64
+
+	// AnonymisationProfiles are applied to request query values, and
+	// request and response bodies, before they are recorded, such as
+	// 'emails' or 'gdpr'.
+	AnonymisationProfiles []string
+
+	// ProtoDescriptors are protobuf descriptor set files, used to record
+	// the messages in gRPC exchanges as JSON.
+	ProtoDescriptors []string
+
+	// PactConsumer and PactProvider name the participants in the Pact
+	// file. If empty, the provider is named after the upstream host.
+	PactConsumer string
+	PactProvider string
 }
 
+// recorder writes exchanges with a single upstream in the configured formats.
+type recorder struct {
+	dir            string
+	upstreamHost   string
+	options        RecorderOptions
+	recordImposter bool
+	configFile     string
+	harFile        string
+	har            *harLog
+	openapiFile    string
+	openapi        *openapiBuilder
+	pactFile       string
+	pact           *pactBuilder
+	anonymiser     *Anonymiser
+	filter         *recordFilter
+	redactor       *redactor
+	grpcDecoder    *grpcDecoder
+
+	// webSocketTranscript holds the messages of each recorded WebSocket
+	// connection, written to webSocketFile
+	webSocketFile        string
+	webSocketConnections int
+	webSocketTranscript  []webSocketTranscriptEntry
+
+	resources      []impostermodel.Resource
+	genOptions     impostermodel.ConfigGenerationOptions
+	requestHashes  []string
+	responseHashes map[string]string
+
+	// resourceHashes holds the request hash for each resource, and
+	// durations the time taken by the upstream for each request hash
+	resourceHashes []string
+	durations      map[string][]time.Duration
+
+	// resourceRequests holds the request for each resource, to
+	// distinguish later requests with the same method and URL
+	resourceRequests []recordedRequest
+
+	// documentsChanged is set when exchanges have been added to the HAR,
+	// OpenAPI or Pact documents since they were last written
+	documentsChanged bool
+}
+
+// StartRecorder records the exchanges sent to the returned channel to the
+// directory, and publishes them to subscribers of the exchange events
+// endpoint. Recording can be paused, resumed and rotated at runtime using
+// the recording control API.
 func StartRecorder(upstream string, dir string, options RecorderOptions) (chan HttpExchange, error) {
-	upstreamHost, err := formatUpstreamHostPort(upstream)
+	r, err := newRotatingRecorder(upstream, dir, options, control)
 	if err != nil {
 		return nil, err
 	}
-	configFile := path.Join(dir, upstreamHost+"-config.yaml")
-	if _, err := os.Stat(configFile); err == nil {
-		return nil, fmt.Errorf("config file %s already exists", configFile)
-	}
-
-	var resources []impostermodel.Resource
-	genOptions := impostermodel.ConfigGenerationOptions{PluginName: "rest"}
-
-	var requestHashes []string
-	responseHashes := make(map[string]string)
 
 	recordC := make(chan HttpExchange)
+	flushC := make(chan chan struct{})
+	startedRecorders.mu.Lock()
+	startedRecorders.flushC = append(startedRecorders.flushC, flushC)
+	startedRecorders.mu.Unlock()
+
 	go func() {
+		ticker := time.NewTicker(documentWriteInterval)
+		defer ticker.Stop()
 		for {
-			exchange := <-recordC
-
-			var responseFilePrefix string
-			requestHash := getRequestHash(exchange.Request)
-			if stringutil.Contains(requestHashes, requestHash) {
-				if options.IgnoreDuplicateRequests {
-					logger.Debugf("skipping recording of duplicate request %s %v", exchange.Request.Method, exchange.Request.URL)
-					continue
+			select {
+			case exchange := <-recordC:
+				exchangeEvents.publish(upstream, exchange, r.recorder.redactor)
+				if err := r.record(exchange); err != nil {
+					logger.Warn(err)
 				}
+			case <-ticker.C:
+				if err := r.recorder.writeDocuments(); err != nil {
+					logger.Warn(err)
+				}
+			case done := <-flushC:
+				if err := r.recorder.writeDocuments(); err != nil {
+					logger.Warn(err)
+				}
+				close(done)
+			}
+		}
+	}()
+
+	return recordC, nil
+}
+
+// FlushRecorders writes the documents of each started recorder that
+// have changed since they were last written. It should be called before
+// the process exits, so the last exchanges are not lost.
+func FlushRecorders() {
+	startedRecorders.mu.Lock()
+	flushCs := append([]chan chan struct{}{}, startedRecorders.flushC...)
+	startedRecorders.mu.Unlock()
+
+	for _, flushC := range flushCs {
+		done := make(chan struct{})
+		flushC <- done
+		<-done
+	}
+}
+
+func newRecorder(upstream string, dir string, options RecorderOptions) (*recorder, error) {
+	upstreamHost, err := formatUpstreamHostPort(upstream)
+	if err != nil {
+		return nil, err
+	}
+	formats, err := parseOutputFormats(options.OutputFormats)
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{
+		dir:            dir,
+		upstreamHost:   upstreamHost,
+		options:        options,
+		recordImposter: stringutil.Contains(formats, OutputFormatImposter),
+		configFile:     path.Join(dir, upstreamHost+"-config.yaml"),
+		harFile:        path.Join(dir, upstreamHost+".har"),
+		openapiFile:    path.Join(dir, upstreamHost+"-openapi.yaml"),
+		webSocketFile:  path.Join(dir, upstreamHost+"-websocket.ndjson"),
+		genOptions:     impostermodel.ConfigGenerationOptions{PluginName: "rest"},
+		responseHashes: make(map[string]string),
+		durations:      make(map[string][]time.Duration),
+	}
+	if r.recordImposter {
+		if _, err := os.Stat(r.configFile); err == nil {
+			return nil, failure.New(failure.CodeProxyOutputExists, "config file %s already exists", r.configFile)
+		}
+	}
+	if stringutil.Contains(formats, OutputFormatHar) {
+		if _, err := os.Stat(r.harFile); err == nil {
+			return nil, failure.New(failure.CodeProxyOutputExists, "HAR file %s already exists", r.harFile)
+		}
+		r.har = newHarLog(upstream)
+	}
+	if stringutil.Contains(formats, OutputFormatOpenapi) {
+		if _, err := os.Stat(r.openapiFile); err == nil {
+			return nil, failure.New(failure.CodeProxyOutputExists, "OpenAPI spec %s already exists", r.openapiFile)
+		}
+		r.openapi = newOpenapiBuilder(upstream)
+	}
+	if stringutil.Contains(formats, OutputFormatPact) {
+		r.pact = newPactBuilder(options.PactConsumer, options.PactProvider, upstreamHost)
+		r.pactFile = path.Join(dir, r.pact.fileName())
+		if _, err := os.Stat(r.pactFile); err == nil {
+			return nil, failure.New(failure.CodeProxyOutputExists, "Pact file %s already exists", r.pactFile)
+		}
+	}
+	r.anonymiser, err = NewAnonymiser(options.AnonymisationProfiles)
+	if err != nil {
+		return nil, err
+	}
+	r.filter, err = newRecordFilter(options)
+	if err != nil {
+		return nil, err
+	}
+	r.redactor, err = newRedactor(options.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	r.grpcDecoder, err = newGrpcDecoder(options.ProtoDescriptors)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// recordExchange writes the files for an exchange together, so a failure
+// part way through does not leave a partial recording.
+func (r *recorder) recordExchange(exchange HttpExchange) error {
+	if exchange.BodyTruncated {
+		logger.Warnf("skipping recording of %s %v - body exceeds the capture limit", exchange.Request.Method, exchange.Request.URL)
+		return nil
+	}
+	if !r.filter.matches(exchange.Request.Method, exchange.Request.URL.Path) {
+		logger.Debugf("skipping recording of %s %v - excluded by filters", exchange.Request.Method, exchange.Request.URL)
+		return nil
+	}
+	exchange = r.grpcDecoder.decode(exchange)
+	exchange = r.redactor.redact(exchange)
+	if exchange.StatusCode == http.StatusSwitchingProtocols {
+		if !r.options.RecordWebSockets {
+			logger.Debugf("skipping recording of WebSocket connection for %v", exchange.Request.URL)
+			return nil
+		}
+		return r.recordWebSocket(exchange)
+	}
+	var anonymised map[string]int
+	if r.anonymiser != nil {
+		exchange, anonymised = r.anonymiser.anonymiseExchange(exchange)
+	}
+
+	if r.har != nil {
+		r.har.add(exchange)
+		r.documentsChanged = true
+	}
+	if r.openapi != nil {
+		r.openapi.add(exchange)
+		r.documentsChanged = true
+	}
+	if r.pact != nil {
+		r.pact.add(exchange, r.options.RecordOnlyResponseHeaders)
+		r.documentsChanged = true
+	}
+
+	tx := fileutil.NewTransaction()
+
+	var resource *impostermodel.Resource
+	var updated []impostermodel.Resource
+	requestHash := getRequestHash(exchange.Request)
+	var request recordedRequest
+	if r.recordImposter {
+		request = newRecordedRequest(requestHash, exchange)
+		var matchers requestMatchers
+		var variantOf []int
+		requestHash, matchers, variantOf = r.findVariant(request)
+
+		var responseFilePrefix string
+		r.durations[requestHash] = append(r.durations[requestHash], exchange.Duration)
+		duplicate := stringutil.Contains(r.requestHashes, requestHash)
+		if duplicate && r.options.IgnoreDuplicateRequests {
+			logger.Debugf("skipping recording of duplicate request %s %v", exchange.Request.Method, exchange.Request.URL)
+			if r.options.DelayProfile.Type == impostermodel.DelayProfileEmpirical {
+				updated = r.updateEmpiricalDelays(requestHash)
+				updateConfigFile(tx, exchange, r.genOptions, updated, r.configFile)
+			}
+		} else {
+			if duplicate {
 				responseFilePrefix = uuid.New().String() + "-"
-			} else {
-				responseFilePrefix = ""
 			}
-			requestHashes = append(requestHashes, requestHash)
+			r.requestHashes = append(r.requestHashes, requestHash)
 
-			resource, err := record(upstreamHost, dir, &responseHashes, responseFilePrefix, exchange, options)
+			var err error
+			resource, err = record(tx, r.upstreamHost, r.dir, &r.responseHashes, responseFilePrefix, exchange, r.options)
 			if err != nil {
-				logger.Warn(err)
-				continue
+				return err
 			}
-			resources = append(resources, *resource)
+			resourceKey := resource.Method + " " + resource.Path
+			resource.Response.Delay = r.options.DelayProfile.BuildDelay(resourceKey, r.durations[requestHash])
+			updated = r.addRequestMatchers(resource, request, matchers, variantOf)
+			updateConfigFile(tx, exchange, r.genOptions, updated, r.configFile)
+		}
+	}
 
-			if err := updateConfigFile(exchange, genOptions, resources, configFile); err != nil {
-				logger.Warn(err)
-			}
+	if err := tx.Commit(); err != nil {
+		if resource != nil {
+			forgetResponseFile(r.dir, &r.responseHashes, resource)
 		}
-	}()
+		return fmt.Errorf("failed to record %s %v: %v", exchange.Request.Method, exchange.Request.URL, err)
+	}
 
-	return recordC, nil
+	var responseFile string
+	if resource != nil {
+		r.resourceHashes = append(r.resourceHashes, requestHash)
+		r.resourceRequests = append(r.resourceRequests, request)
+		responseFile = resource.Response.StaticFile
+	}
+	if updated != nil {
+		r.resources = updated
+	}
+	if len(anonymised) > 0 {
+		r.anonymiser.Record(exchange, responseFile, anonymised)
+		writeAnonymisationReport(r.dir, r.anonymiser)
+	}
+	return nil
+}
+
+// writeDocuments writes the HAR, OpenAPI and Pact documents, if exchanges
+// have been added to them since they were last written. Each document is
+// rewritten in full, so they are written periodically, rather than for
+// every exchange.
+func (r *recorder) writeDocuments() error {
+	if !r.documentsChanged {
+		return nil
+	}
+	tx := fileutil.NewTransaction()
+	if r.har != nil {
+		if err := r.har.write(tx, r.harFile); err != nil {
+			return err
+		}
+	}
+	if r.openapi != nil {
+		if err := r.openapi.write(tx, r.openapiFile); err != nil {
+			return err
+		}
+	}
+	if r.pact != nil {
+		if err := r.pact.write(tx, r.pactFile); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write recorded documents: %v", err)
+	}
+	r.documentsChanged = false
+	return nil
+}
+
+// addRequestMatchers returns a copy of the resources with the new resource
+// appended. If the new resource is a variant of earlier resources, the
+// matchers are added to it and to them, so each is matched by its own
+// request.
+func (r *recorder) addRequestMatchers(resource *impostermodel.Resource, request recordedRequest, matchers requestMatchers, variantOf []int) []impostermodel.Resource {
+	updated := make([]impostermodel.Resource, len(r.resources), len(r.resources)+1)
+	copy(updated, r.resources)
+	if !matchers.empty() {
+		matchers.apply(resource, request)
+		for _, i := range variantOf {
+			matchers.apply(&updated[i], r.resourceRequests[i])
+		}
+		logger.Debugf("recorded %s %v as a variant distinguished by request matchers", resource.Method, resource.Path)
+	}
+	return append(updated, *resource)
+}
+
+// updateEmpiricalDelays returns a copy of the resources, in which the delays
+// of those for the request hash reflect all of its observed durations.
+func (r *recorder) updateEmpiricalDelays(requestHash string) []impostermodel.Resource {
+	updated := make([]impostermodel.Resource, len(r.resources))
+	copy(updated, r.resources)
+	for i, hash := range r.resourceHashes {
+		if hash != requestHash {
+			continue
+		}
+		response := *updated[i].Response
+		resourceKey := updated[i].Method + " " + updated[i].Path
+		response.Delay = r.options.DelayProfile.BuildDelay(resourceKey, r.durations[requestHash])
+		updated[i].Response = &response
+	}
+	return updated
+}
+
+func writeAnonymisationReport(dir string, anonymiser *Anonymiser) {
+	report, err := anonymiser.Report()
+	if err != nil {
+		logger.Warnf("failed to generate anonymisation report: %v", err)
+		return
+	}
+	reportFile := filepath.Join(dir, anonymisationReportFileName)
+	if err := fileutil.WriteFileAtomic(reportFile, report, 0644); err != nil {
+		logger.Warnf("failed to write anonymisation report: %v", err)
+		return
+	}
+	logger.Debugf("wrote anonymisation report %s", reportFile)
 }
 
 func formatUpstreamHostPort(upstream string) (string, error) {
@@ -107,6 +466,7 @@ func formatUpstreamHostPort(upstream string) (string, error) {
 }
 
 func record(
+	tx *fileutil.Transaction,
 	upstreamHost string,
 	dir string,
 	responseHashes *map[string]string,
@@ -114,7 +474,7 @@ func record(
 	exchange HttpExchange,
 	options RecorderOptions,
 ) (resource *impostermodel.Resource, err error) {
-	respFile, err := getResponseFile(upstreamHost, dir, options, exchange, responseHashes, prefix)
+	respFile, err := getResponseFile(tx, upstreamHost, dir, options, exchange, responseHashes, prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +489,7 @@ func record(
 // If a body is not empty, the file hashes are checked for the hash of the response body to
 // see if it has already been written. If not, a new file is written and its hash stored in the map.
 func getResponseFile(
+	tx *fileutil.Transaction,
 	upstreamHost string,
 	dir string,
 	options RecorderOptions,
@@ -148,15 +509,31 @@ func getResponseFile(
 		logger.Debugf("reusing identical response file %s for %s %v", existing, req.Method, req.URL)
 		return existing, nil
 
+	} else if options.ContentAddressed {
+		respFile, err := generateContentAddressedFileName(dir, exchange, bodyHash)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(respFile); err == nil {
+			logger.Debugf("reusing existing response file %s for %s %v", respFile, req.Method, req.URL)
+		} else {
+			tx.WriteFile(respFile, respBody, 0644)
+			tx.OnCommit(func() {
+				logger.Debugf("wrote response file %s for %s %v [%d bytes]", respFile, req.Method, req.URL, len(respBody))
+			})
+		}
+		(*fileHashes)[bodyHash] = respFile
+		return respFile, nil
+
 	} else {
 		respFile, err := generateRespFileName(upstreamHost, dir, options, exchange, prefix)
 		if err != nil {
 			return "", err
 		}
-		if err = os.WriteFile(respFile, respBody, 0644); err != nil {
-			return "", fmt.Errorf("failed to write response file %s for %s %v: %v", respFile, req.Method, req.URL, err)
-		}
-		logger.Debugf("wrote response file %s for %s %v [%d bytes]", respFile, req.Method, req.URL, len(respBody))
+		tx.WriteFile(respFile, respBody, 0644)
+		tx.OnCommit(func() {
+			logger.Debugf("wrote response file %s for %s %v [%d bytes]", respFile, req.Method, req.URL, len(respBody))
+		})
 		(*fileHashes)[bodyHash] = respFile
 		return respFile, nil
 	}
@@ -211,13 +588,28 @@ func getRequestHash(req *http.Request) string {
 	return stringutil.Sha1hashString(req.Method + req.URL.String())
 }
 
-func updateConfigFile(exchange HttpExchange, options impostermodel.ConfigGenerationOptions, resources []impostermodel.Resource, configFile string) error {
+func updateConfigFile(tx *fileutil.Transaction, exchange HttpExchange, options impostermodel.ConfigGenerationOptions, resources []impostermodel.Resource, configFile string) {
 	req := exchange.Request
 	config := impostermodel.GenerateConfig(options, resources)
-	err := os.WriteFile(configFile, config, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write config file %s for %s %v: %v", configFile, req.Method, req.URL, err)
+	tx.WriteFile(configFile, config, 0644)
+	tx.OnCommit(func() {
+		logger.Debugf("wrote config file %s for %s %v", configFile, req.Method, req.URL)
+	})
+}
+
+// forgetResponseFile removes the hash of a response file that was not
+// written, so later identical responses are not pointed at a missing file.
+func forgetResponseFile(dir string, fileHashes *map[string]string, resource *impostermodel.Resource) {
+	if resource.Response == nil || resource.Response.StaticFile == "" {
+		return
+	}
+	respFile := filepath.Join(dir, resource.Response.StaticFile)
+	if _, err := os.Stat(respFile); !os.IsNotExist(err) {
+		return
+	}
+	for hash, file := range *fileHashes {
+		if filepath.Clean(file) == respFile {
+			delete(*fileHashes, hash)
+		}
 	}
-	logger.Debugf("wrote config file %s for %s %v", configFile, req.Method, req.URL)
-	return nil
 }
@@ -1,11 +1,15 @@
 package proxy
 
 import (
+	"encoding/json"
 	"fmt"
+	"gatehill.io/imposter/fileutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 )
 
@@ -64,6 +68,22 @@ func Test_getResponseFile(t *testing.T) {
 			want:    path.Join(outputDir, "existing-file-0.txt"),
 			wantErr: false,
 		},
+		{
+			name: "content addressed response body",
+			args: args{
+				upstreamHost: "example.com",
+				dir:          outputDir,
+				options:      RecorderOptions{ContentAddressed: true},
+				exchange: HttpExchange{
+					Request:         &http.Request{Method: "GET", URL: rootUrl},
+					ResponseBody:    &responseBody,
+					ResponseHeaders: &http.Header{},
+				},
+				fileHashes: buildMap(outputDir, []string{}),
+			},
+			want:    path.Join(outputDir, "responses", bodyHash+".txt"),
+			wantErr: false,
+		},
 		{
 			name: "new response body",
 			args: args{
@@ -83,7 +103,8 @@ func Test_getResponseFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getResponseFile(tt.args.upstreamHost, tt.args.dir, tt.args.options, tt.args.exchange, tt.args.fileHashes, tt.args.prefix)
+			tx := fileutil.NewTransaction()
+			got, err := getResponseFile(tx, tt.args.upstreamHost, tt.args.dir, tt.args.options, tt.args.exchange, tt.args.fileHashes, tt.args.prefix)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getResponseFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -91,10 +112,49 @@ func Test_getResponseFile(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("getResponseFile() got = %v, want %v", got, tt.want)
 			}
+			if err := tx.Commit(); err != nil {
+				t.Errorf("failed to commit response file: %v", err)
+			}
 		})
 	}
 }
 
+func TestStartRecorder_writesDocumentsOnFlush(t *testing.T) {
+	dir := t.TempDir()
+	recordC, err := StartRecorder("https://example.com", dir, RecorderOptions{
+		OutputFormats: []string{OutputFormatImposter, OutputFormatHar},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/a", "/b"} {
+		respBody := []byte("ok")
+		recordC <- HttpExchange{
+			Request:         httptest.NewRequest(http.MethodGet, p, nil),
+			RequestBody:     &[]byte{},
+			StatusCode:      http.StatusOK,
+			ResponseBody:    &respBody,
+			ResponseHeaders: &http.Header{},
+		}
+	}
+	FlushRecorders()
+
+	if _, err := os.Stat(filepath.Join(dir, "example.com-config.yaml")); err != nil {
+		t.Errorf("expected config file to be written: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "example.com.har"))
+	if err != nil {
+		t.Fatalf("expected HAR file to be written: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		t.Fatal(err)
+	}
+	if len(har.Log.Entries) != 2 {
+		t.Errorf("expected 2 HAR entries, got: %d", len(har.Log.Entries))
+	}
+}
+
 func buildMap(dir string, hashes []string) *map[string]string {
 	m := make(map[string]string)
 	for i, hash := range hashes {
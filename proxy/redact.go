@@ -0,0 +1,261 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RedactedValue replaces redacted values in recordings.
+const RedactedValue = "REDACTED"
+
+// defaultRedactedHeaders are always redacted, as they hold credentials.
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+// defaultRedactedQueryParams are always redacted, as they commonly hold
+// credentials.
+var defaultRedactedQueryParams = []string{"access_token", "api_key", "apikey", "client_secret", "password", "token"}
+
+// RedactionRules describe the values removed from exchanges before they
+// are recorded.
+type RedactionRules struct {
+	// Headers are the names of request and response headers whose values
+	// are redacted, in addition to the default headers.
+	Headers []string
+
+	// QueryParams are the names of request query parameters whose values
+	// are redacted, in addition to the default query parameters.
+	QueryParams []string
+
+	// JsonPaths are the paths of fields in JSON bodies whose values are
+	// redacted, such as 'user.ssn' or '$.items[*].card'.
+	JsonPaths []string
+
+	// Patterns are regular expressions whose matches are redacted from
+	// bodies, header values and query parameter values.
+	Patterns []string
+}
+
+type redactor struct {
+	headers     []string
+	queryParams []string
+	jsonPaths   [][]string
+	patterns    []*regexp.Regexp
+}
+
+func newRedactor(rules RedactionRules) (*redactor, error) {
+	r := &redactor{
+		headers:     append(append([]string{}, defaultRedactedHeaders...), rules.Headers...),
+		queryParams: append(append([]string{}, defaultRedactedQueryParams...), rules.QueryParams...),
+	}
+	for _, jsonPath := range rules.JsonPaths {
+		segments, err := parseJsonPath(jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		r.jsonPaths = append(r.jsonPaths, segments)
+	}
+	for _, pattern := range rules.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern: %s: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// parseJsonPath splits a path such as '$.items[*].card' into its segments,
+// where '*' matches any array element or object property.
+func parseJsonPath(jsonPath string) ([]string, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+	path = strings.ReplaceAll(path, "[*]", ".*")
+	if path == "" {
+		return nil, fmt.Errorf("invalid redaction JSON path: %s", jsonPath)
+	}
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid redaction JSON path: %s", jsonPath)
+		}
+	}
+	return segments, nil
+}
+
+// redact returns a copy of the exchange with the rules applied. The
+// original request and headers are not modified.
+func (r *redactor) redact(exchange HttpExchange) HttpExchange {
+	req := exchange.Request.Clone(exchange.Request.Context())
+	r.redactHeaders(req.Header)
+	if req.URL.RawQuery != "" {
+		req.URL.RawQuery = r.redactQuery(req.URL.RawQuery)
+		req.RequestURI = req.URL.RequestURI()
+	}
+	exchange.Request = req
+
+	if exchange.ResponseHeaders != nil {
+		respHeaders := exchange.ResponseHeaders.Clone()
+		r.redactHeaders(respHeaders)
+		exchange.ResponseHeaders = &respHeaders
+	}
+	if exchange.RequestBody != nil {
+		body := r.redactBody(*exchange.RequestBody)
+		exchange.RequestBody = &body
+	}
+	if exchange.ResponseBody != nil {
+		body := r.redactBody(*exchange.ResponseBody)
+		exchange.ResponseBody = &body
+	}
+	if len(exchange.WebSocketMessages) > 0 {
+		messages := make([]WebSocketMessage, len(exchange.WebSocketMessages))
+		for i, message := range exchange.WebSocketMessages {
+			if message.Encoding == "" {
+				message.Data = string(r.redactBody([]byte(message.Data)))
+			}
+			messages[i] = message
+		}
+		exchange.WebSocketMessages = messages
+	}
+	return exchange
+}
+
+func (r *redactor) redactHeaders(headers http.Header) {
+	for name, values := range headers {
+		for _, redactedHeader := range r.headers {
+			if strings.EqualFold(name, redactedHeader) {
+				for i := range values {
+					values[i] = RedactedValue
+				}
+			}
+		}
+		for i, value := range values {
+			values[i] = r.redactPatterns(value)
+		}
+	}
+}
+
+// redactQuery redacts the values of query parameters with redacted names,
+// and matches of the patterns in the values of the others. Parameters that
+// are not redacted keep their original encoding and order.
+func (r *redactor) redactQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		rawName, rawValue, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+		if slices.ContainsFunc(r.queryParams, func(p string) bool { return strings.EqualFold(p, name) }) {
+			params[i] = rawName + "=" + RedactedValue
+			continue
+		} else if !hasValue {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue
+		}
+		if redacted := r.redactPatterns(value); redacted != value {
+			params[i] = rawName + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func (r *redactor) redactBody(body []byte) []byte {
+	if len(r.jsonPaths) > 0 {
+		body = r.redactJson(body)
+	}
+	if len(r.patterns) > 0 {
+		body = []byte(r.redactPatterns(string(body)))
+	}
+	return body
+}
+
+// redactJson redacts the JSON paths from the body. The body is only
+// re-encoded if a value was redacted, so other bodies are unchanged.
+func (r *redactor) redactJson(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+	redacted := false
+	for _, segments := range r.jsonPaths {
+		if redactJsonPath(doc, segments) {
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		logger.Warnf("failed to encode redacted JSON body: %v", err)
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func redactJsonPath(node interface{}, segments []string) bool {
+	segment, last := segments[0], len(segments) == 1
+	redacted := false
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, child := range n {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if last {
+				n[key] = RedactedValue
+				redacted = true
+			} else if redactJsonPath(child, segments[1:]) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		if segment != "*" {
+			return false
+		}
+		for i, child := range n {
+			if last {
+				n[i] = RedactedValue
+				redacted = true
+			} else if redactJsonPath(child, segments[1:]) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func (r *redactor) redactPatterns(value string) string {
+	for _, pattern := range r.patterns {
+		value = pattern.ReplaceAllLiteralString(value, RedactedValue)
+	}
+	return value
+}
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_redactor_redactJson(t *testing.T) {
+	tests := []struct {
+		name      string
+		jsonPaths []string
+		body      string
+		want      string
+	}{
+		{
+			name:      "nested field",
+			jsonPaths: []string{"$.user.ssn"},
+			body:      `{"user":{"name":"Ada","ssn":"123-45-6789"}}`,
+			want:      `{"user":{"name":"Ada","ssn":"REDACTED"}}`,
+		},
+		{
+			name:      "array elements",
+			jsonPaths: []string{"items[*].card"},
+			body:      `{"items":[{"card":"4111","qty":1},{"card":"5500","qty":2}]}`,
+			want:      `{"items":[{"card":"REDACTED","qty":1},{"card":"REDACTED","qty":2}]}`,
+		},
+		{
+			name:      "missing path leaves body unchanged",
+			jsonPaths: []string{"$.password"},
+			body:      `{ "name": "Ada" }`,
+			want:      `{ "name": "Ada" }`,
+		},
+		{
+			name:      "non-JSON body unchanged",
+			jsonPaths: []string{"$.password"},
+			body:      `password=secret`,
+			want:      `password=secret`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRedactor(RedactionRules{JsonPaths: tt.jsonPaths})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(r.redactBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("redactBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_redactor_redact(t *testing.T) {
+	r, err := newRedactor(RedactionRules{
+		Headers:  []string{"X-Api-Key"},
+		Patterns: []string{`\d{3}-\d{2}-\d{4}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Api-Key", "key")
+	req.Header.Set("Accept", "application/json")
+	respHeaders := http.Header{}
+	respHeaders.Set("Set-Cookie", "session=abc")
+	requestBody := []byte(`ssn=123-45-6789`)
+	responseBody := []byte(`{"ssn":"123-45-6789"}`)
+
+	redacted := r.redact(HttpExchange{
+		Request:         req,
+		RequestBody:     &requestBody,
+		ResponseBody:    &responseBody,
+		ResponseHeaders: &respHeaders,
+	})
+
+	checks := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Authorization", redacted.Request.Header.Get("Authorization"), RedactedValue},
+		{"X-Api-Key", redacted.Request.Header.Get("X-Api-Key"), RedactedValue},
+		{"Accept", redacted.Request.Header.Get("Accept"), "application/json"},
+		{"Set-Cookie", redacted.ResponseHeaders.Get("Set-Cookie"), RedactedValue},
+		{"request body", string(*redacted.RequestBody), "ssn=REDACTED"},
+		{"response body", string(*redacted.ResponseBody), `{"ssn":"REDACTED"}`},
+
+		// the original exchange is not modified
+		{"original Authorization", req.Header.Get("Authorization"), "Bearer secret"},
+		{"original Set-Cookie", respHeaders.Get("Set-Cookie"), "session=abc"},
+		{"original request body", string(requestBody), "ssn=123-45-6789"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func Test_newRedactor_invalid(t *testing.T) {
+	if _, err := newRedactor(RedactionRules{Patterns: []string{"("}}); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+	if _, err := newRedactor(RedactionRules{JsonPaths: []string{"$."}}); err == nil {
+		t.Errorf("expected error for invalid JSON path")
+	}
+}
+
+func Test_redactor_redactQuery(t *testing.T) {
+	r, err := newRedactor(RedactionRules{
+		QueryParams: []string{"sig"},
+		Patterns:    []string{`\d{3}-\d{2}-\d{4}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		rawQuery string
+		want     string
+	}{
+		{name: "default parameter", rawQuery: "access_token=abc&page=2", want: "access_token=REDACTED&page=2"},
+		{name: "name ignoring case", rawQuery: "API_KEY=abc", want: "API_KEY=REDACTED"},
+		{name: "configured parameter", rawQuery: "sig=abc%2Fdef&sig=ghi", want: "sig=REDACTED&sig=REDACTED"},
+		{name: "pattern in value", rawQuery: "ssn=123-45-6789&q=a+b", want: "ssn=REDACTED&q=a+b"},
+		{name: "parameter without value", rawQuery: "token&debug", want: "token=REDACTED&debug"},
+		{name: "nothing redacted", rawQuery: "q=a%20b&page=2", want: "q=a%20b&page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.redactQuery(tt.rawQuery); got != tt.want {
+				t.Errorf("redactQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users?api_key=secret&page=2", nil)
+	redacted := r.redact(HttpExchange{Request: req})
+	if got := redacted.Request.URL.String(); got != "/users?api_key=REDACTED&page=2" {
+		t.Errorf("redacted URL = %v, want %v", got, "/users?api_key=REDACTED&page=2")
+	}
+	if got := req.URL.RawQuery; got != "api_key=secret&page=2" {
+		t.Errorf("original query = %v, want it unmodified", got)
+	}
+}
@@ -18,7 +18,6 @@ package proxy
 
 import (
 	"bytes"
-	"fmt"
 	"mime"
 	"net/http"
 	"regexp"
@@ -31,29 +30,33 @@ var rewriteMediaTypes = []string{
 	"application/xml",
 }
 
-func Rewrite(respHeaders *http.Header, respBody *[]byte, upstream string, port int) *[]byte {
+func Rewrite(respHeaders *http.Header, respBody *[]byte, upstream string, proxyBaseUrl string) *[]byte {
+	if !shouldRewrite(respHeaders) {
+		return respBody
+	}
+	rewritten := bytes.ReplaceAll(*respBody, []byte(upstream), []byte(proxyBaseUrl))
+	respBody = &rewritten
+	return respBody
+}
+
+// shouldRewrite determines whether the response body can be rewritten,
+// based on its content type.
+func shouldRewrite(respHeaders *http.Header) bool {
 	contentType := (*respHeaders).Get("Content-Type")
 	if contentType == "" {
 		logger.Warnf("no content type - skipping rewrite")
-		return respBody
+		return false
 	}
 	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
 		logger.Warnf("failed to parse content type - skipping rewrite: %v", err)
-		return respBody
+		return false
 	}
-	rewrite := false
 	for _, rewriteMediaType := range rewriteMediaTypes {
 		if matched, _ := regexp.MatchString(rewriteMediaType, mediaType); matched {
-			rewrite = true
-			break
+			return true
 		}
 	}
-	if !rewrite {
-		logger.Debugf("unsupported content type %s for rewrite - skipping rewrite", mediaType)
-		return respBody
-	}
-	rewritten := bytes.ReplaceAll(*respBody, []byte(upstream), []byte(fmt.Sprintf("http://localhost:%d", port)))
-	respBody = &rewritten
-	return respBody
+	logger.Debugf("unsupported content type %s for rewrite - skipping rewrite", mediaType)
+	return false
 }
@@ -0,0 +1,138 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Route sends requests whose path matches the pattern to the upstream.
+type Route struct {
+	Pattern  string
+	Upstream string
+	matcher  *regexp.Regexp
+}
+
+// defaultRoutePattern matches every request path.
+const defaultRoutePattern = "**"
+
+// ParseRoutes parses routes in the form 'PATTERN=URL', such as
+// '/users/**=https://users.example.com'. The pattern is a glob, or a
+// regular expression prefixed with 'regex:'. If defaultUpstream is set,
+// it is added as a final route matching all other requests.
+func ParseRoutes(routes []string, defaultUpstream string) ([]Route, error) {
+	var parsed []Route
+	for _, route := range routes {
+		pattern, upstream, found := strings.Cut(route, "=")
+		if !found || pattern == "" || upstream == "" {
+			return nil, fmt.Errorf("invalid route: %s - must be in the form PATTERN=URL", route)
+		}
+		if u, err := url.Parse(upstream); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream URL for route: %s", route)
+		}
+		parsed = append(parsed, Route{Pattern: pattern, Upstream: upstream})
+	}
+	if defaultUpstream != "" {
+		parsed = append(parsed, Route{Pattern: defaultRoutePattern, Upstream: defaultUpstream})
+	}
+	for i := range parsed {
+		matchers, err := stringutil.CompilePathPatterns([]string{parsed[i].Pattern})
+		if err != nil {
+			return nil, err
+		}
+		parsed[i].matcher = matchers[0]
+	}
+	return parsed, nil
+}
+
+// matchRoute returns the first route matching the path, or nil if none
+// match.
+func matchRoute(routes []Route, path string) *Route {
+	for i := range routes {
+		if routes[i].matcher.MatchString(path) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// StartRouteRecorders starts a recorder for each upstream, recording into
+// a subdirectory of dir named for the upstream host, so the resources
+// for each upstream are kept in separate configuration.
+func StartRouteRecorders(routes []Route, dir string, options RecorderOptions) (map[string]chan HttpExchange, error) {
+	recorders := make(map[string]chan HttpExchange)
+	for _, route := range routes {
+		if _, found := recorders[route.Upstream]; found {
+			continue
+		}
+		upstreamHost, err := formatUpstreamHostPort(route.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		routeDir := filepath.Join(dir, upstreamHost)
+		if err := os.MkdirAll(routeDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create recording directory: %s: %v", routeDir, err)
+		}
+		recorderC, err := StartRecorder(route.Upstream, routeDir, options)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("recording exchanges with %s to %s", route.Upstream, routeDir)
+		recorders[route.Upstream] = recorderC
+	}
+	return recorders, nil
+}
+
+// BuildRoutingMux returns a handler that proxies each request to the
+// upstream of the first route matching its path, sending each exchange to
+// the recorder channel for that upstream, as well as serving a status
+// endpoint. Requests matching no route receive a 404 response.
+func BuildRoutingMux(routes []Route, proxyBaseUrl string, rewrite bool, maxRecordBodySize int64, recorders map[string]chan HttpExchange) *http.ServeMux {
+	rewriters := make(map[string]BodyRewriter)
+	for _, route := range routes {
+		rewriters[route.Upstream] = buildRewriter(route.Upstream, proxyBaseUrl, rewrite)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(statusPath, func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprintf(writer, "ok\n")
+	})
+	mux.HandleFunc(recordingControlPath, requireControlAccess(handleRecordingControl))
+	mux.HandleFunc(recordingControlPath+"/", requireControlAccess(handleRecordingControl))
+	mux.HandleFunc(ExchangeEventsPath, requireControlAccess(handleExchangeEvents))
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		route := matchRoute(routes, request.URL.Path)
+		if route == nil {
+			logger.Warnf("no route matches %s %v", request.Method, request.URL)
+			sessionStats.RecordUnmatched(request.Method, request.URL.Path, http.StatusNotFound)
+			http.Error(writer, "no route matches the request path", http.StatusNotFound)
+			return
+		}
+		recorderC := recorders[route.Upstream]
+		Handle(route.Upstream, writer, request, maxRecordBodySize, rewriters[route.Upstream], func(exchange HttpExchange) {
+			recorderC <- exchange
+		})
+	})
+	return mux
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name            string
+		routes          []string
+		defaultUpstream string
+		path            string
+		wantUpstream    string
+		wantErr         bool
+	}{
+		{
+			name:         "glob route",
+			routes:       []string{"/users/**=https://users.example.com", "/orders/**=https://orders.example.com"},
+			path:         "/orders/1/items",
+			wantUpstream: "https://orders.example.com",
+		},
+		{
+			name:            "default upstream",
+			routes:          []string{"/users/**=https://users.example.com"},
+			defaultUpstream: "https://api.example.com",
+			path:            "/health",
+			wantUpstream:    "https://api.example.com",
+		},
+		{
+			name:         "regex route",
+			routes:       []string{`regex:^/v[0-9]+/users=https://users.example.com`},
+			path:         "/v2/users",
+			wantUpstream: "https://users.example.com",
+		},
+		{
+			name:   "no match",
+			routes: []string{"/users/*=https://users.example.com"},
+			path:   "/users/1/orders",
+		},
+		{
+			name:    "missing upstream",
+			routes:  []string{"/users/**"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid upstream",
+			routes:  []string{"/users/**=users"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := ParseRoutes(tt.routes, tt.defaultUpstream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRoutes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got string
+			if route := matchRoute(routes, tt.path); route != nil {
+				got = route.Upstream
+			}
+			if got != tt.wantUpstream {
+				t.Errorf("matchRoute() upstream = %v, want %v", got, tt.wantUpstream)
+			}
+		})
+	}
+}
+
+func TestBuildRoutingMux(t *testing.T) {
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+		}))
+	}
+	users := newUpstream("users")
+	defer users.Close()
+	orders := newUpstream("orders")
+	defer orders.Close()
+
+	routes, err := ParseRoutes([]string{"/users/**=" + users.URL, "/orders/**=" + orders.URL}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorders := map[string]chan HttpExchange{
+		users.URL:  make(chan HttpExchange, 1),
+		orders.URL: make(chan HttpExchange, 1),
+	}
+	mux := BuildRoutingMux(routes, "http://localhost:8080", false, 0, recorders)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+		recorder   chan HttpExchange
+	}{
+		{path: "/users/1", wantStatus: http.StatusOK, wantBody: "users /users/1", recorder: recorders[users.URL]},
+		{path: "/orders/2", wantStatus: http.StatusOK, wantBody: "orders /orders/2", recorder: recorders[orders.URL]},
+		{path: "/other", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.recorder == nil {
+				return
+			}
+			if body, _ := io.ReadAll(w.Body); string(body) != tt.wantBody {
+				t.Errorf("body = %s, want %s", body, tt.wantBody)
+			}
+			if exchange := <-tt.recorder; exchange.Request.URL.Path != tt.path {
+				t.Errorf("recorded path = %s, want %s", exchange.Request.URL.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestBuildRoutingMux_upstreamAuth(t *testing.T) {
+	newUpstream := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, r.Header.Get("Authorization"))
+		}))
+	}
+	api := newUpstream()
+	defer api.Close()
+	users := newUpstream()
+	defer users.Close()
+
+	previous := upstreamAuth
+	defer func() { upstreamAuth = previous }()
+	if err := SetUpstreamAuth(UpstreamAuth{Upstream: api.URL, BearerToken: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	routes, err := ParseRoutes([]string{"/users/**=" + users.URL}, api.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorders := map[string]chan HttpExchange{
+		api.URL:   make(chan HttpExchange, 1),
+		users.URL: make(chan HttpExchange, 1),
+	}
+	mux := BuildRoutingMux(routes, "http://localhost:8080", false, 0, recorders)
+
+	tests := []struct {
+		path     string
+		wantAuth string
+	}{
+		{path: "/users/1", wantAuth: ""},
+		{path: "/orders/2", wantAuth: "Bearer abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if body, _ := io.ReadAll(w.Body); string(body) != tt.wantAuth {
+				t.Errorf("upstream received Authorization = %q, want %q", body, tt.wantAuth)
+			}
+		})
+	}
+}
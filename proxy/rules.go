@@ -0,0 +1,191 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// RewriteRules modify requests before they are forwarded to the upstream,
+// and responses before they are sent to the client and recorded.
+type RewriteRules struct {
+	// Host replaces the Host header sent to the upstream.
+	Host string `json:"host,omitempty"`
+
+	// StripPathPrefix is removed from, then AddPathPrefix is added to,
+	// the path of requests to the upstream.
+	StripPathPrefix string `json:"stripPathPrefix,omitempty"`
+	AddPathPrefix   string `json:"addPathPrefix,omitempty"`
+
+	Request  MessageRewrite `json:"request,omitempty"`
+	Response MessageRewrite `json:"response,omitempty"`
+}
+
+// MessageRewrite modifies the headers and body of a request or response.
+// Headers are removed before they are added, and body replacements are
+// applied in order.
+type MessageRewrite struct {
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"`
+	AddHeaders    map[string]string `json:"addHeaders,omitempty"`
+	ReplaceBody   []Replacement     `json:"replaceBody,omitempty"`
+}
+
+// Replacement substitutes each occurrence of Find with Replace.
+type Replacement struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+}
+
+var activeRewriteRules *RewriteRules
+
+// SetRewriteRules configures the rules applied to proxied requests and
+// responses. It must be called before the proxy starts.
+func SetRewriteRules(rules RewriteRules) error {
+	for _, replacement := range append(rules.Request.ReplaceBody, rules.Response.ReplaceBody...) {
+		if replacement.Find == "" {
+			return fmt.Errorf("body replacement must have a non-empty search string")
+		}
+	}
+	if rules.empty() {
+		activeRewriteRules = nil
+	} else {
+		activeRewriteRules = &rules
+	}
+	return nil
+}
+
+// LoadRewriteRules reads rules from a YAML or JSON file.
+func LoadRewriteRules(file string) (RewriteRules, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return RewriteRules{}, fmt.Errorf("failed to read rewrite rules file: %s: %v", file, err)
+	}
+	var rules RewriteRules
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return RewriteRules{}, fmt.Errorf("failed to parse rewrite rules file: %s: %v", file, err)
+	}
+	return rules, nil
+}
+
+// ParseReplacements parses body replacements in the form 'FIND=REPLACE'.
+func ParseReplacements(replacements []string) ([]Replacement, error) {
+	var parsed []Replacement
+	for _, replacement := range replacements {
+		find, replace, found := strings.Cut(replacement, "=")
+		if !found || find == "" {
+			return nil, fmt.Errorf("invalid body replacement: %s - must be in the form FIND=REPLACE", replacement)
+		}
+		parsed = append(parsed, Replacement{Find: find, Replace: replace})
+	}
+	return parsed, nil
+}
+
+func (r RewriteRules) empty() bool {
+	return r.Host == "" && r.StripPathPrefix == "" && r.AddPathPrefix == "" &&
+		r.Request.empty() && r.Response.empty()
+}
+
+func (m MessageRewrite) empty() bool {
+	return len(m.RemoveHeaders) == 0 && len(m.AddHeaders) == 0 && len(m.ReplaceBody) == 0
+}
+
+// rewritePath returns the path of the request to the upstream.
+func (r *RewriteRules) rewritePath(path string) string {
+	if r == nil {
+		return path
+	}
+	if r.StripPathPrefix != "" && strings.HasPrefix(path, r.StripPathPrefix) {
+		path = strings.TrimPrefix(path, r.StripPathPrefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if r.AddPathPrefix != "" {
+		path = strings.TrimSuffix(r.AddPathPrefix, "/") + path
+	}
+	return path
+}
+
+// rewriteRequest applies the rules to the request to the upstream. If
+// the body is replaced, it is read in full.
+func (r *RewriteRules) rewriteRequest(req *http.Request) error {
+	if r == nil {
+		return nil
+	}
+	if r.Host != "" {
+		req.Host = r.Host
+	}
+	rewriteHeaders(req.Header, r.Request)
+	if len(r.Request.ReplaceBody) == 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %v", err)
+	}
+	_ = req.Body.Close()
+	body = replaceBody(body, r.Request.ReplaceBody)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return nil
+}
+
+// rewriteResponseHeaders applies the header rules to the response.
+func (r *RewriteRules) rewriteResponseHeaders(headers http.Header) {
+	if r == nil {
+		return
+	}
+	rewriteHeaders(headers, r.Response)
+}
+
+// wrapBodyRewrite returns a function applying the response body
+// replacements after rewriteBody, if set. Replacements are only applied to
+// text responses.
+func (r *RewriteRules) wrapBodyRewrite(respHeaders *http.Header, rewriteBody func(body *[]byte) *[]byte) func(body *[]byte) *[]byte {
+	if r == nil || len(r.Response.ReplaceBody) == 0 || !shouldRewrite(respHeaders) {
+		return rewriteBody
+	}
+	return func(body *[]byte) *[]byte {
+		if rewriteBody != nil {
+			body = rewriteBody(body)
+		}
+		replaced := replaceBody(*body, r.Response.ReplaceBody)
+		return &replaced
+	}
+}
+
+func rewriteHeaders(headers http.Header, rewrite MessageRewrite) {
+	for _, name := range rewrite.RemoveHeaders {
+		headers.Del(name)
+	}
+	for name, value := range rewrite.AddHeaders {
+		headers.Set(name, value)
+	}
+}
+
+func replaceBody(body []byte, replacements []Replacement) []byte {
+	for _, replacement := range replacements {
+		body = bytes.ReplaceAll(body, []byte(replacement.Find), []byte(replacement.Replace))
+	}
+	return body
+}
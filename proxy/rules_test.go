@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteRules_rewritePath(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules *RewriteRules
+		path  string
+		want  string
+	}{
+		{name: "no rules", rules: nil, path: "/api/users", want: "/api/users"},
+		{name: "strip prefix", rules: &RewriteRules{StripPathPrefix: "/api"}, path: "/api/users", want: "/users"},
+		{name: "strip whole path", rules: &RewriteRules{StripPathPrefix: "/api"}, path: "/api", want: "/"},
+		{name: "prefix not present", rules: &RewriteRules{StripPathPrefix: "/api"}, path: "/users", want: "/users"},
+		{name: "add prefix", rules: &RewriteRules{AddPathPrefix: "/v1/"}, path: "/users", want: "/v1/users"},
+		{name: "strip and add", rules: &RewriteRules{StripPathPrefix: "/api", AddPathPrefix: "/v2"}, path: "/api/users", want: "/v2/users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.rewritePath(tt.path); got != tt.want {
+				t.Errorf("rewritePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRewriteRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `{"host": "internal.example.com", "response": {"replaceBody": [{"find": "internal", "replace": "public"}]}}`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRewriteRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Host != "internal.example.com" {
+		t.Errorf("Host = %v, want internal.example.com", rules.Host)
+	}
+	if len(rules.Response.ReplaceBody) != 1 || rules.Response.ReplaceBody[0].Replace != "public" {
+		t.Errorf("Response.ReplaceBody = %+v", rules.Response.ReplaceBody)
+	}
+}
+
+func TestHandle_rewriteRules(t *testing.T) {
+	var upstreamReq *http.Request
+	var upstreamBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamReq = r
+		body, _ := io.ReadAll(r.Body)
+		upstreamBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Internal", "true")
+		_, _ = w.Write([]byte(`{"server":"internal-1"}`))
+	}))
+	defer upstream.Close()
+
+	err := SetRewriteRules(RewriteRules{
+		Host:            "api.example.com",
+		StripPathPrefix: "/api",
+		Request: MessageRewrite{
+			RemoveHeaders: []string{"X-Debug"},
+			AddHeaders:    map[string]string{"X-Tenant": "acme"},
+			ReplaceBody:   []Replacement{{Find: "staging", Replace: "production"}},
+		},
+		Response: MessageRewrite{
+			RemoveHeaders: []string{"X-Internal"},
+			ReplaceBody:   []Replacement{{Find: "internal-1", Replace: "server"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetRewriteRules(RewriteRules{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"env":"staging"}`))
+	req.Header.Set("X-Debug", "true")
+	w := httptest.NewRecorder()
+	var exchange HttpExchange
+	Handle(upstream.URL, w, req, 0, nil, func(e HttpExchange) {
+		exchange = e
+	})
+
+	checks := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"upstream path", upstreamReq.URL.Path, "/users"},
+		{"upstream host", upstreamReq.Host, "api.example.com"},
+		{"upstream X-Tenant", upstreamReq.Header.Get("X-Tenant"), "acme"},
+		{"upstream X-Debug", upstreamReq.Header.Get("X-Debug"), ""},
+		{"upstream body", upstreamBody, `{"env":"production"}`},
+		{"response X-Internal", w.Header().Get("X-Internal"), ""},
+		{"response body", w.Body.String(), `{"server":"server"}`},
+		{"recorded response body", string(*exchange.ResponseBody), `{"server":"server"}`},
+		{"recorded path", exchange.Request.URL.Path, "/api/users"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
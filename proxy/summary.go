@@ -0,0 +1,28 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import "gatehill.io/imposter/stats"
+
+// sessionStats accumulates the requests received by the proxy.
+var sessionStats = stats.NewCollector()
+
+// SessionSummary returns the summary of the requests received since
+// the proxy started, including those matching no route.
+func SessionSummary() stats.Summary {
+	return sessionStats.Summary()
+}
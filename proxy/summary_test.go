@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"gatehill.io/imposter/stats"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionSummary(t *testing.T) {
+	previous := sessionStats
+	sessionStats = stats.NewCollector()
+	defer func() { sessionStats = previous }()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	routes, err := ParseRoutes([]string{"/users/**=" + upstream.URL}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorders := map[string]chan HttpExchange{upstream.URL: make(chan HttpExchange, 2)}
+	mux := BuildRoutingMux(routes, "http://localhost:8080", false, 0, recorders)
+	for _, path := range []string{"/users/1", "/users/1", "/other"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	summary := SessionSummary()
+	if summary.Requests != 3 {
+		t.Errorf("Requests = %d, want 3", summary.Requests)
+	}
+	if summary.Unmatched != 1 {
+		t.Errorf("Unmatched = %d, want 1", summary.Unmatched)
+	}
+	if summary.Paths["POST /users/1"] != 2 || summary.Paths["POST /other"] != 1 {
+		t.Errorf("Paths = %v", summary.Paths)
+	}
+	if summary.Statuses[http.StatusCreated] != 2 || summary.Statuses[http.StatusNotFound] != 1 {
+		t.Errorf("Statuses = %v", summary.Statuses)
+	}
+	if summary.P50 < 0 {
+		t.Errorf("P50 = %v, want latency of proxied requests", summary.P50)
+	}
+}
@@ -0,0 +1,406 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTracingServiceName is the service name of spans exported by
+// the proxy, if none is configured.
+const DefaultTracingServiceName = "imposter-proxy"
+
+const traceparentHeader = "Traceparent"
+
+// otlpTracesPath is appended to an OTLP endpoint without a path, as
+// for the OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+const otlpTracesPath = "/v1/traces"
+
+const spanExportInterval = 5 * time.Second
+const spanExportBatchSize = 100
+
+// TracingOptions control the W3C trace context sent to the upstream,
+// and the export of spans for proxied requests.
+type TracingOptions struct {
+	// Enabled starts a new trace for requests received without a
+	// traceparent header, so the upstream receives one for every request.
+	Enabled bool
+
+	// OtlpEndpoint is the URL of an OpenTelemetry collector to which
+	// a span is exported for each proxied request, using OTLP over HTTP.
+	// Setting it enables tracing.
+	OtlpEndpoint string
+
+	// OtlpHeaders are sent with each export, such as for authentication.
+	OtlpHeaders map[string]string
+
+	ServiceName string
+}
+
+// tracer creates spans for proxied requests. It is nil if tracing
+// is disabled.
+type tracer struct {
+	exporter *spanExporter
+}
+
+var activeTracer *tracer
+
+// SetTracing configures trace propagation and span export. It must be
+// called before the proxy starts.
+func SetTracing(options TracingOptions) error {
+	if !options.Enabled && options.OtlpEndpoint == "" {
+		activeTracer = nil
+		return nil
+	}
+	t := &tracer{}
+	if options.OtlpEndpoint != "" {
+		endpoint, err := buildOtlpTracesUrl(options.OtlpEndpoint)
+		if err != nil {
+			return err
+		}
+		serviceName := options.ServiceName
+		if serviceName == "" {
+			serviceName = DefaultTracingServiceName
+		}
+		t.exporter = newSpanExporter(endpoint, options.OtlpHeaders, serviceName)
+		t.exporter.start()
+		logger.Infof("exporting spans for proxied requests to %s", endpoint)
+	}
+	activeTracer = t
+	return nil
+}
+
+// buildOtlpTracesUrl returns the URL to which spans are exported. If the
+// endpoint has no path, the standard OTLP traces path is used.
+func buildOtlpTracesUrl(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint: %s - must be an HTTP(S) URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return u.String(), nil
+}
+
+// traceContext is the W3C trace context of a request.
+type traceContext struct {
+	traceId string
+	spanId  string
+	flags   string
+}
+
+// parseTraceparent parses a W3C traceparent header, such as
+// '00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'.
+func parseTraceparent(header string) (traceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceContext{}, false
+	}
+	// later versions may append fields, but must keep the first four
+	if parts[0] == "00" && len(parts) != 4 {
+		return traceContext{}, false
+	}
+	tc := traceContext{traceId: parts[1], spanId: parts[2], flags: parts[3]}
+	if !isHexId(tc.traceId, 32) || !isHexId(tc.spanId, 16) || !isHex(tc.flags, 2) {
+		return traceContext{}, false
+	}
+	return tc, true
+}
+
+func (tc traceContext) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%s", tc.traceId, tc.spanId, tc.flags)
+}
+
+// isHexId returns true if the value is a lowercase hex string of the
+// given length, which is not all zeros, as required for trace and span IDs.
+func isHexId(value string, length int) bool {
+	return isHex(value, length) && strings.Trim(value, "0") != ""
+}
+
+func isHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, c := range value {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newId(bytes int) string {
+	b := make([]byte, bytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// span is the proxy's handling of a single request.
+type span struct {
+	tracer    *tracer
+	name      string
+	context   traceContext
+	parentId  string
+	startTime time.Time
+	method    string
+	path      string
+}
+
+// startSpan starts a span for the request, continuing the trace in its
+// traceparent header, if valid, or starting a new trace otherwise. It
+// returns nil if tracing is disabled.
+func startSpan(req *http.Request) *span {
+	if activeTracer == nil {
+		return nil
+	}
+	s := &span{
+		tracer:    activeTracer,
+		name:      req.Method + " " + req.URL.Path,
+		startTime: time.Now(),
+		method:    req.Method,
+		path:      req.URL.Path,
+	}
+	if incoming, ok := parseTraceparent(req.Header.Get(traceparentHeader)); ok {
+		s.context = incoming
+		s.parentId = incoming.spanId
+		if activeTracer.exporter != nil {
+			s.context.spanId = newId(8)
+		}
+	} else {
+		s.context = traceContext{traceId: newId(16), spanId: newId(8), flags: "01"}
+	}
+	return s
+}
+
+// propagate returns the headers to send to the upstream, with the trace
+// context of the span. If spans are not exported, a valid traceparent
+// from the client is sent unchanged, as the span would not exist.
+func (s *span) propagate(headers http.Header) http.Header {
+	if s == nil {
+		return headers
+	}
+	propagated := headers.Clone()
+	propagated.Set(traceparentHeader, s.context.traceparent())
+	return propagated
+}
+
+// traceId returns the ID of the trace, or the empty string if s is nil.
+func (s *span) traceId() string {
+	if s == nil {
+		return ""
+	}
+	return s.context.traceId
+}
+
+// end completes the span with the status sent to the client, and
+// queues it for export, if enabled. It is a no-op if s is nil.
+func (s *span) end(upstream string, statusCode int) {
+	if s == nil || s.tracer.exporter == nil {
+		return
+	}
+	attributes := []otlpAttribute{
+		stringAttribute("http.request.method", s.method),
+		stringAttribute("url.path", s.path),
+		intAttribute("http.response.status_code", statusCode),
+	}
+	if u, err := url.Parse(upstream); err == nil && u.Host != "" {
+		attributes = append(attributes, stringAttribute("server.address", u.Hostname()))
+	}
+	status := otlpStatus{}
+	if statusCode >= 500 {
+		status.Code = otlpStatusCodeError
+	}
+	s.tracer.exporter.add(otlpSpan{
+		TraceId:           s.context.traceId,
+		SpanId:            s.context.spanId,
+		ParentSpanId:      s.parentId,
+		Name:              s.name,
+		Kind:              otlpSpanKindServer,
+		StartTimeUnixNano: strconv.FormatInt(s.startTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	})
+}
+
+// OTLP JSON encoding of spans. Trace and span IDs are hex encoded,
+// and 64-bit integers are strings.
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+const otlpSpanKindServer = 2
+const otlpStatusCodeError = 2
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int) otlpAttribute {
+	v := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: &v}}
+}
+
+// spanExporter sends spans to an OTLP endpoint in batches, so exporting
+// does not delay responses to clients.
+type spanExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	mu      sync.Mutex
+	pending []otlpSpan
+}
+
+func newSpanExporter(endpoint string, headers map[string]string, serviceName string) *spanExporter {
+	return &spanExporter{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// start exports pending spans periodically.
+func (e *spanExporter) start() {
+	go func() {
+		for range time.Tick(spanExportInterval) {
+			e.flush()
+		}
+	}()
+}
+
+func (e *spanExporter) add(s otlpSpan) {
+	e.mu.Lock()
+	e.pending = append(e.pending, s)
+	full := len(e.pending) >= spanExportBatchSize
+	e.mu.Unlock()
+	if full {
+		go e.flush()
+	}
+}
+
+// flush exports the pending spans. Spans that fail to export are
+// dropped, rather than retried, so they do not accumulate if the
+// collector is unavailable.
+func (e *spanExporter) flush() {
+	e.mu.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	if err := e.export(spans); err != nil {
+		logger.Warnf("failed to export %d span(s): %v", len(spans), err)
+		return
+	}
+	logger.Tracef("exported %d span(s) to %s", len(spans), e.endpoint)
+}
+
+func (e *spanExporter) export(spans []otlpSpan) error {
+	traces := otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{stringAttribute("service.name", e.serviceName)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "gatehill.io/imposter/proxy"},
+				Spans: spans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(traces)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
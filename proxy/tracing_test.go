@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_parseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   traceContext
+		wantOk bool
+	}{
+		{
+			name:   "valid",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:   traceContext{traceId: "4bf92f3577b34da6a3ce929d0e0e4736", spanId: "00f067aa0ba902b7", flags: "01"},
+			wantOk: true,
+		},
+		{
+			name:   "future version with extra field",
+			header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			want:   traceContext{traceId: "4bf92f3577b34da6a3ce929d0e0e4736", spanId: "00f067aa0ba902b7", flags: "00"},
+			wantOk: true,
+		},
+		{name: "empty", header: "", wantOk: false},
+		{name: "all zero trace ID", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantOk: false},
+		{name: "uppercase", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantOk: false},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantOk: false},
+		{name: "extra field in version 00", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTraceparent(tt.header)
+			if ok != tt.wantOk {
+				t.Fatalf("parseTraceparent() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseTraceparent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildOtlpTracesUrl(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{endpoint: "http://localhost:4318", want: "http://localhost:4318/v1/traces"},
+		{endpoint: "https://collector.example.com/", want: "https://collector.example.com/v1/traces"},
+		{endpoint: "http://localhost:4318/custom/traces", want: "http://localhost:4318/custom/traces"},
+		{endpoint: "localhost:4318", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			got, err := buildOtlpTracesUrl(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildOtlpTracesUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("buildOtlpTracesUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandle_tracing(t *testing.T) {
+	const clientTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name             string
+		clientHeader     string
+		export           bool
+		wantSameHeader   bool
+		wantClientTrace  bool
+		wantParentSpanId string
+	}{
+		{name: "client trace propagated unchanged", clientHeader: clientTraceparent, wantSameHeader: true, wantClientTrace: true},
+		{name: "new trace started", wantClientTrace: false},
+		{name: "client trace continued by exported span", clientHeader: clientTraceparent, export: true, wantClientTrace: true, wantParentSpanId: "00f067aa0ba902b7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Get("traceparent")
+			}))
+			defer upstream.Close()
+
+			var exported []byte
+			collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				exported, _ = io.ReadAll(r.Body)
+			}))
+			defer collector.Close()
+
+			activeTracer = &tracer{}
+			if tt.export {
+				activeTracer.exporter = newSpanExporter(collector.URL, nil, DefaultTracingServiceName)
+			}
+			defer func() { activeTracer = nil }()
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.clientHeader != "" {
+				req.Header.Set("traceparent", tt.clientHeader)
+			}
+			var exchange HttpExchange
+			Handle(upstream.URL, httptest.NewRecorder(), req, 0, nil, func(e HttpExchange) {
+				exchange = e
+			})
+
+			upstreamContext, ok := parseTraceparent(received)
+			if !ok {
+				t.Fatalf("upstream received invalid traceparent: %q", received)
+			}
+			if tt.wantSameHeader && received != tt.clientHeader {
+				t.Errorf("traceparent = %s, want %s", received, tt.clientHeader)
+			}
+			if isClientTrace := upstreamContext.traceId == "4bf92f3577b34da6a3ce929d0e0e4736"; isClientTrace != tt.wantClientTrace {
+				t.Errorf("trace ID = %s, client trace wanted %v", upstreamContext.traceId, tt.wantClientTrace)
+			}
+			if exchange.TraceId != upstreamContext.traceId {
+				t.Errorf("exchange trace ID = %s, want %s", exchange.TraceId, upstreamContext.traceId)
+			}
+			if req.Header.Get("traceparent") != tt.clientHeader {
+				t.Errorf("client request headers were modified")
+			}
+
+			if !tt.export {
+				return
+			}
+			activeTracer.exporter.flush()
+			var traces otlpTraces
+			if err := json.Unmarshal(exported, &traces); err != nil {
+				t.Fatalf("failed to parse exported spans: %v", err)
+			}
+			spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+			if len(spans) != 1 {
+				t.Fatalf("exported %d spans, want 1", len(spans))
+			}
+			s := spans[0]
+			if s.SpanId != upstreamContext.spanId || s.ParentSpanId != tt.wantParentSpanId || s.Name != "GET /orders" {
+				t.Errorf("exported span = %+v, upstream context = %+v", s, upstreamContext)
+			}
+			if !strings.Contains(string(exported), `"service.name"`) {
+				t.Errorf("exported spans missing service name: %s", exported)
+			}
+		})
+	}
+}
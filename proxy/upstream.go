@@ -0,0 +1,126 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUpstreamTimeout is the default time to wait for the upstream to
+// start responding.
+const DefaultUpstreamTimeout = 60 * time.Second
+
+// retryBackoff is the delay before the first retry, doubled for each
+// subsequent retry.
+const retryBackoff = 100 * time.Millisecond
+
+// RetryOnConnectionError is the retry condition for failures to connect
+// to, or receive a response from, the upstream, including timeouts.
+const RetryOnConnectionError = "connection-error"
+
+// UpstreamOptions control how requests are sent to the upstream.
+type UpstreamOptions struct {
+	// Timeout is how long to wait for the upstream to start responding.
+	// It does not limit the time taken to stream the response body. If
+	// zero, there is no limit.
+	Timeout time.Duration
+
+	Retry RetryPolicy
+
+	// Http2 negotiates HTTP/2 with HTTPS upstreams, and uses HTTP/2 with
+	// prior knowledge (h2c) for plain HTTP upstreams, as required to proxy
+	// gRPC services.
+	Http2 bool
+}
+
+// RetryPolicy retries requests with idempotent methods, if they fail with a
+// connection error, or the upstream responds with one of the statuses.
+type RetryPolicy struct {
+	Retries                int
+	RetryOnConnectionError bool
+	RetryOnStatus          []int
+}
+
+var upstreamOptions = UpstreamOptions{Timeout: DefaultUpstreamTimeout}
+
+// SetUpstreamOptions configures how requests are sent to the upstream.
+// It must be called before the proxy starts.
+func SetUpstreamOptions(options UpstreamOptions) error {
+	if options.Timeout < 0 {
+		return fmt.Errorf("upstream timeout must not be negative: %v", options.Timeout)
+	}
+	if options.Retry.Retries < 0 {
+		return fmt.Errorf("number of retries must not be negative: %d", options.Retry.Retries)
+	}
+	upstreamOptions = options
+	return nil
+}
+
+// ParseRetryOn parses retry conditions, each of which is an HTTP status
+// code, or 'connection-error'.
+func ParseRetryOn(conditions []string) (RetryPolicy, error) {
+	var policy RetryPolicy
+	for _, condition := range conditions {
+		condition = strings.TrimSpace(condition)
+		if condition == RetryOnConnectionError {
+			policy.RetryOnConnectionError = true
+			continue
+		}
+		status, err := strconv.Atoi(condition)
+		if err != nil || status < 100 || status > 599 {
+			return RetryPolicy{}, fmt.Errorf("invalid retry condition: %s - must be an HTTP status code or '%s'", condition, RetryOnConnectionError)
+		}
+		policy.RetryOnStatus = append(policy.RetryOnStatus, status)
+	}
+	return policy, nil
+}
+
+// canRetry determines if a request with the method may be retried. Only
+// idempotent methods are retried, as the upstream may have acted on a
+// request whose response was not received.
+func (p RetryPolicy) canRetry(method string) bool {
+	if p.Retries <= 0 {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// shouldRetry determines if the outcome of an attempt is retryable.
+func (p RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.RetryOnConnectionError
+	}
+	for _, status := range p.RetryOnStatus {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the retry following the attempt.
+func backoff(attempt int) time.Duration {
+	return retryBackoff << attempt
+}
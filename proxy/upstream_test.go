@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryOn(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		want       RetryPolicy
+		wantErr    bool
+	}{
+		{
+			name:       "statuses and connection errors",
+			conditions: []string{"connection-error", "502", "503"},
+			want:       RetryPolicy{RetryOnConnectionError: true, RetryOnStatus: []int{502, 503}},
+		},
+		{
+			name:       "invalid status",
+			conditions: []string{"999"},
+			wantErr:    true,
+		},
+		{
+			name:       "unknown condition",
+			conditions: []string{"timeout"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRetryOn(tt.conditions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRetryOn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRetryOn() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_forward_retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		failures     int
+		retries      int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "succeeds after retries", method: http.MethodPut, failures: 2, retries: 2, wantStatus: http.StatusOK, wantAttempts: 3},
+		{name: "retries exhausted", method: http.MethodGet, failures: 3, retries: 1, wantStatus: http.StatusServiceUnavailable, wantAttempts: 2},
+		{name: "non-idempotent method not retried", method: http.MethodPost, failures: 1, retries: 2, wantStatus: http.StatusServiceUnavailable, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+					t.Errorf("attempt %d body = %s, want payload", attempts, body)
+				}
+				if attempts <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer upstream.Close()
+
+			err := SetUpstreamOptions(UpstreamOptions{
+				Timeout: time.Second,
+				Retry:   RetryPolicy{Retries: tt.retries, RetryOnStatus: []int{http.StatusServiceUnavailable}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer SetUpstreamOptions(UpstreamOptions{Timeout: DefaultUpstreamTimeout})
+
+			resp, err := forward(upstream.URL, tt.method, "/", "", &http.Header{}, strings.NewReader("payload"), int64(len("payload")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// UpstreamTls configures the TLS connection to an upstream, such as the
+// client certificate presented to upstreams that require mutual TLS.
+type UpstreamTls struct {
+	// ClientCertFile and ClientKeyFile are PEM files holding the client
+	// certificate and its private key. Both or neither must be set.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// CaFile is a PEM bundle of CA certificates trusted to sign the
+	// upstream certificate, instead of the system roots.
+	CaFile string
+
+	InsecureSkipVerify bool
+}
+
+// upstreamTlsConfigs holds the TLS configuration for each upstream host,
+// falling back to the default for other hosts.
+type upstreamTlsConfigs struct {
+	defaultConfig *tls.Config
+	hosts         map[string]*tls.Config
+}
+
+var upstreamTls *upstreamTlsConfigs
+
+// SetUpstreamTls configures the TLS connections to upstreams. The hosts
+// map is keyed by upstream host, optionally with a port, and overrides the
+// defaults for that host. It must be called before the proxy starts.
+func SetUpstreamTls(defaults UpstreamTls, hosts map[string]UpstreamTls) error {
+	configs, err := newUpstreamTlsConfigs(defaults, hosts)
+	if err != nil {
+		return err
+	}
+	upstreamTls = configs
+	return nil
+}
+
+func newUpstreamTlsConfigs(defaults UpstreamTls, hosts map[string]UpstreamTls) (*upstreamTlsConfigs, error) {
+	if defaults == (UpstreamTls{}) && len(hosts) == 0 {
+		return nil, nil
+	}
+	defaultConfig, err := buildTlsConfig(defaults)
+	if err != nil {
+		return nil, err
+	}
+	configs := &upstreamTlsConfigs{
+		defaultConfig: defaultConfig,
+		hosts:         make(map[string]*tls.Config),
+	}
+	for host, options := range hosts {
+		config, err := buildTlsConfig(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration for upstream %s: %v", host, err)
+		}
+		configs.hosts[strings.ToLower(host)] = config
+	}
+	return configs, nil
+}
+
+func buildTlsConfig(options UpstreamTls) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+	if (options.ClientCertFile == "") != (options.ClientKeyFile == "") {
+		return nil, fmt.Errorf("both a client certificate and key file must be provided")
+	}
+	if options.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if options.CaFile != "" {
+		caPem, err := os.ReadFile(options.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %s: %v", options.CaFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("no certificates found in CA file: %s", options.CaFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// clientConfig returns the TLS configuration for a connection to the
+// address, in the form 'host:port', offering the application protocols
+// using ALPN. It is safe to call if u is nil.
+func (u *upstreamTlsConfigs) clientConfig(addr string, nextProtos []string) *tls.Config {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	var config *tls.Config
+	if u == nil {
+		config = &tls.Config{}
+	} else if hostConfig, found := u.hosts[strings.ToLower(addr)]; found {
+		config = hostConfig.Clone()
+	} else if hostConfig, found := u.hosts[strings.ToLower(host)]; found {
+		config = hostConfig.Clone()
+	} else {
+		config = u.defaultConfig.Clone()
+	}
+	config.ServerName = host
+	config.NextProtos = nextProtos
+	return config
+}
+
+// dialTls returns a function that opens TLS connections to upstreams
+// using the configuration for each host. HTTP/2 is offered if enabled
+// in the upstream options.
+func (u *upstreamTlsConfigs) dialTls(dialer *net.Dialer) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	nextProtos := []string{"http/1.1"}
+	if upstreamOptions.Http2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: u.clientConfig(addr, nextProtos)}
+		return tlsDialer.DialContext(ctx, network, addr)
+	}
+}
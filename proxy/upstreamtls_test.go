@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"gatehill.io/imposter/tlsutil"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_upstreamTlsConfigs_clientConfig(t *testing.T) {
+	configs, err := newUpstreamTlsConfigs(UpstreamTls{}, map[string]UpstreamTls{
+		"secure.example.com":     {InsecureSkipVerify: true},
+		"other.example.com:8443": {InsecureSkipVerify: true},
+	})
+	if err != nil {
+		t.Fatalf("newUpstreamTlsConfigs() error = %v", err)
+	}
+	tests := []struct {
+		name         string
+		configs      *upstreamTlsConfigs
+		addr         string
+		wantInsecure bool
+	}{
+		{name: "host match", configs: configs, addr: "secure.example.com:443", wantInsecure: true},
+		{name: "host match is case insensitive", configs: configs, addr: "SECURE.example.com:443", wantInsecure: true},
+		{name: "host and port match", configs: configs, addr: "other.example.com:8443", wantInsecure: true},
+		{name: "different port", configs: configs, addr: "other.example.com:443", wantInsecure: false},
+		{name: "default", configs: configs, addr: "example.com:443", wantInsecure: false},
+		{name: "not configured", configs: nil, addr: "example.com:443", wantInsecure: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.configs.clientConfig(tt.addr, []string{"http/1.1"})
+			if got.InsecureSkipVerify != tt.wantInsecure {
+				t.Errorf("clientConfig() InsecureSkipVerify = %v, want %v", got.InsecureSkipVerify, tt.wantInsecure)
+			}
+			host, _, _ := net.SplitHostPort(tt.addr)
+			if got.ServerName != host {
+				t.Errorf("clientConfig() ServerName = %v, want %v", got.ServerName, host)
+			}
+		})
+	}
+}
+
+func Test_newUpstreamTlsConfigs(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults UpstreamTls
+		hosts    map[string]UpstreamTls
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "not configured", wantNil: true},
+		{name: "insecure", defaults: UpstreamTls{InsecureSkipVerify: true}},
+		{name: "certificate without key", defaults: UpstreamTls{ClientCertFile: "cert.pem"}, wantErr: true},
+		{name: "missing CA file", defaults: UpstreamTls{CaFile: "/does/not/exist.pem"}, wantErr: true},
+		{name: "invalid host", hosts: map[string]UpstreamTls{"example.com": {ClientKeyFile: "key.pem"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newUpstreamTlsConfigs(tt.defaults, tt.hosts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newUpstreamTlsConfigs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("newUpstreamTlsConfigs() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func Test_upstreamTlsConfigs_dialTls(t *testing.T) {
+	caCertPem, caKeyPem, err := tlsutil.GenerateCa("Test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := tls.X509KeyPair(caCertPem, caKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := tlsutil.GenerateLeafCertificate(&ca, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := tlsutil.GenerateLeafCertificate(&ca, "test-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	upstream.TLS = &tls.Config{
+		Certificates: []tls.Certificate{*serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	keyDer, err := x509.MarshalPKCS8PrivateKey(clientCert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string][]byte{
+		caFile:   caCertPem,
+		certFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]}),
+		keyFile:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}),
+	} {
+		if err := os.WriteFile(file, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs, err := newUpstreamTlsConfigs(UpstreamTls{
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+		CaFile:         caFile,
+	}, nil)
+	if err != nil {
+		t.Fatalf("newUpstreamTlsConfigs() error = %v", err)
+	}
+	c := &http.Client{Transport: &http.Transport{
+		DialTLSContext: configs.dialTls(&net.Dialer{Timeout: 5 * time.Second}),
+	}}
+	resp, err := c.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("request to upstream failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "test-client" {
+		t.Errorf("upstream received client certificate %q, want %q", body, "test-client")
+	}
+
+	// without the CA, the upstream certificate is not trusted
+	untrusted, err := newUpstreamTlsConfigs(UpstreamTls{ClientCertFile: certFile, ClientKeyFile: keyFile}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrusted.dialTls(&net.Dialer{})(context.Background(), "tcp", upstream.Listener.Addr().String()); err == nil {
+		t.Errorf("expected an untrusted upstream certificate to be rejected")
+	}
+}
@@ -0,0 +1,407 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+)
+
+const (
+	// WebSocketDirectionSend is a message from the client to the upstream.
+	WebSocketDirectionSend = "send"
+
+	// WebSocketDirectionReceive is a message from the upstream to the client.
+	WebSocketDirectionReceive = "receive"
+)
+
+// WebSocketMessage is a message exchanged over a proxied WebSocket
+// connection. Binary data is base64 encoded.
+type WebSocketMessage struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Opcode    int       `json:"opcode"`
+	Data      string    `json:"data"`
+	Encoding  string    `json:"encoding,omitempty"`
+}
+
+// isWebSocketUpgrade determines if the request is a WebSocket handshake.
+func isWebSocketUpgrade(req *http.Request) bool {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleWebSocket forwards the handshake to the upstream and, if it is
+// accepted, relays frames in both directions until either side closes the
+// connection. Messages are captured, up to maxRecordBodySize bytes in
+// total, then passed to the listener with the handshake.
+func handleWebSocket(
+	upstream string,
+	w http.ResponseWriter,
+	req *http.Request,
+	maxRecordBodySize int64,
+	listener func(exchange HttpExchange),
+) {
+	startTime := time.Now()
+	client := req.RemoteAddr
+
+	upstreamConn, outReq, err := dialWebSocketUpstream(upstream, req)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer upstreamConn.Close()
+
+	upstreamReader := bufio.NewReader(upstreamConn)
+	resp, err := http.ReadResponse(upstreamReader, outReq)
+	if err != nil {
+		logger.Errorf("error reading WebSocket handshake response from upstream: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		logger.Debugf("upstream rejected WebSocket handshake for %v with status %d", req.URL, resp.StatusCode)
+		defer resp.Body.Close()
+		_, _ = sendResponse(w, &resp.Header, resp.StatusCode, resp.Body, false, client)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Errorf("cannot proxy WebSocket connection for %v - connection does not support hijacking", req.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		logger.Errorf("error hijacking client connection: %v", err)
+		return
+	}
+	defer clientConn.Close()
+
+	if err := resp.Write(clientConn); err != nil {
+		logger.Errorf("error writing WebSocket handshake response to client %v: %v", client, err)
+		return
+	}
+	logger.Infof("proxying WebSocket connection for %v to upstream for client %v", req.URL, client)
+
+	sendCapture := newFrameCapture(WebSocketDirectionSend, maxRecordBodySize)
+	receiveCapture := newFrameCapture(WebSocketDirectionReceive, maxRecordBodySize)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	relay := func(dest net.Conn, src io.Reader, capture *frameCapture) {
+		defer wg.Done()
+		_, _ = io.Copy(dest, io.TeeReader(src, capture))
+		// unblock the other direction
+		_ = clientConn.Close()
+		_ = upstreamConn.Close()
+	}
+	go relay(upstreamConn, clientBuf, sendCapture)
+	go relay(clientConn, upstreamReader, receiveCapture)
+	wg.Wait()
+
+	messages := mergeWebSocketMessages(sendCapture.messages, receiveCapture.messages)
+	empty := []byte{}
+	listener(HttpExchange{
+		Request:           req,
+		RequestBody:       &empty,
+		StatusCode:        resp.StatusCode,
+		ResponseBody:      &empty,
+		ResponseHeaders:   &resp.Header,
+		StartedDateTime:   startTime,
+		Duration:          time.Since(startTime),
+		BodyTruncated:     sendCapture.truncated || receiveCapture.truncated,
+		WebSocketMessages: messages,
+	})
+	logger.Infof("closed WebSocket connection for %v [%d messages] for client %v after %v", req.URL, len(messages), client, time.Since(startTime))
+}
+
+// dialWebSocketUpstream connects to the upstream and writes the handshake
+// request, retaining the Connection and Upgrade headers that are otherwise
+// removed as hop-by-hop headers.
+func dialWebSocketUpstream(upstream string, req *http.Request) (net.Conn, *http.Request, error) {
+	upstreamUrl, err := url.Parse(upstream)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse upstream URL: %v", err)
+	}
+	target := *upstreamUrl
+	target.Path, err = url.JoinPath("/", upstreamUrl.Path, activeRewriteRules.rewritePath(req.URL.Path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build upstream URL: %v", err)
+	}
+	target.RawQuery = req.URL.RawQuery
+
+	host := upstreamUrl.Host
+	if upstreamUrl.Port() == "" {
+		if upstreamUrl.Scheme == "https" {
+			host = net.JoinHostPort(upstreamUrl.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(upstreamUrl.Hostname(), "80")
+		}
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	if upstreamUrl.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, upstreamTls.clientConfig(host, []string{"http/1.1"}))
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to upstream %s: %v", host, err)
+	}
+
+	outReq := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &target,
+		Host:       upstreamUrl.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+	}
+	copyHeaders(&req.Header, &outReq.Header)
+	outReq.Header.Set("Connection", "Upgrade")
+	outReq.Header.Set("Upgrade", req.Header.Get("Upgrade"))
+	if err := activeRewriteRules.rewriteRequest(outReq); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if err := upstreamAuth.apply(outReq); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("failed to authenticate to upstream: %v", err)
+	}
+	if err := outReq.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("error writing WebSocket handshake to upstream: %v", err)
+	}
+	logger.Tracef("sent WebSocket handshake to upstream: %s", target.String())
+	return conn, outReq, nil
+}
+
+// frameCapture parses the WebSocket frames written to it, retaining the
+// complete text and binary messages. Writes always succeed, so it can be
+// used with io.TeeReader without interrupting the connection.
+type frameCapture struct {
+	direction string
+	limit     int64
+	buf       []byte
+	opcode    byte
+	fragments []byte
+	size      int64
+	truncated bool
+	messages  []WebSocketMessage
+}
+
+func newFrameCapture(direction string, limit int64) *frameCapture {
+	return &frameCapture{direction: direction, limit: limit}
+}
+
+func (c *frameCapture) Write(p []byte) (int, error) {
+	if c.truncated {
+		return len(p), nil
+	}
+	c.buf = append(c.buf, p...)
+	for {
+		n := c.parseFrame()
+		if n == 0 {
+			break
+		}
+		c.buf = c.buf[n:]
+	}
+	if len(c.buf) == 0 {
+		c.buf = nil
+	}
+	return len(p), nil
+}
+
+// parseFrame parses the frame at the start of the buffer, returning the
+// number of bytes consumed, or 0 if the frame is incomplete.
+func (c *frameCapture) parseFrame() int {
+	if len(c.buf) < 2 {
+		return 0
+	}
+	fin := c.buf[0]&0x80 != 0
+	opcode := c.buf[0] & 0x0f
+	masked := c.buf[1]&0x80 != 0
+	length := uint64(c.buf[1] & 0x7f)
+	offset := 2
+	switch length {
+	case 126:
+		if len(c.buf) < 4 {
+			return 0
+		}
+		length = uint64(binary.BigEndian.Uint16(c.buf[2:4]))
+		offset = 4
+	case 127:
+		if len(c.buf) < 10 {
+			return 0
+		}
+		length = binary.BigEndian.Uint64(c.buf[2:10])
+		offset = 10
+	}
+	var mask []byte
+	if masked {
+		if len(c.buf) < offset+4 {
+			return 0
+		}
+		mask = c.buf[offset : offset+4]
+		offset += 4
+	}
+	if c.limit > 0 && c.size+int64(length) > c.limit {
+		c.truncated = true
+		c.buf = nil
+		return 0
+	}
+	if uint64(len(c.buf)-offset) < length {
+		return 0
+	}
+
+	payload := make([]byte, length)
+	copy(payload, c.buf[offset:offset+int(length)])
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	c.size += int64(length)
+	c.addFrame(fin, opcode, payload)
+	return offset + int(length)
+}
+
+func (c *frameCapture) addFrame(fin bool, opcode byte, payload []byte) {
+	switch opcode {
+	case wsOpContinuation:
+		c.fragments = append(c.fragments, payload...)
+	case wsOpText, wsOpBinary:
+		c.opcode = opcode
+		c.fragments = payload
+	case wsOpClose:
+		// the payload is a 2 byte status code followed by the reason
+		reason := ""
+		if len(payload) > 2 {
+			reason = string(payload[2:])
+		}
+		c.messages = append(c.messages, WebSocketMessage{Time: time.Now(), Direction: c.direction, Opcode: wsOpClose, Data: reason})
+		return
+	default:
+		// ping and pong frames are not recorded
+		return
+	}
+	if fin {
+		c.messages = append(c.messages, buildWebSocketMessage(c.direction, c.opcode, c.fragments))
+		c.fragments = nil
+	}
+}
+
+func buildWebSocketMessage(direction string, opcode byte, data []byte) WebSocketMessage {
+	message := WebSocketMessage{Time: time.Now(), Direction: direction, Opcode: int(opcode)}
+	if opcode == wsOpText && utf8.Valid(data) {
+		message.Data = string(data)
+	} else {
+		message.Data = base64.StdEncoding.EncodeToString(data)
+		message.Encoding = "base64"
+	}
+	return message
+}
+
+// mergeWebSocketMessages returns the messages from both directions in
+// the order in which they were captured.
+func mergeWebSocketMessages(send []WebSocketMessage, receive []WebSocketMessage) []WebSocketMessage {
+	merged := make([]WebSocketMessage, 0, len(send)+len(receive))
+	i, j := 0, 0
+	for i < len(send) || j < len(receive) {
+		if j >= len(receive) || (i < len(send) && !send[i].Time.After(receive[j].Time)) {
+			merged = append(merged, send[i])
+			i++
+		} else {
+			merged = append(merged, receive[j])
+			j++
+		}
+	}
+	return merged
+}
+
+// webSocketTranscriptEntry is a line in the WebSocket transcript file.
+type webSocketTranscriptEntry struct {
+	Connection int    `json:"connection"`
+	Path       string `json:"path"`
+	WebSocketMessage
+}
+
+// recordWebSocket appends the messages of the connection to the
+// transcript file, as newline delimited JSON.
+func (r *recorder) recordWebSocket(exchange HttpExchange) error {
+	r.webSocketConnections++
+	for _, message := range exchange.WebSocketMessages {
+		if r.anonymiser != nil && message.Encoding == "" {
+			data, _ := r.anonymiser.Anonymise([]byte(message.Data))
+			message.Data = string(data)
+		}
+		r.webSocketTranscript = append(r.webSocketTranscript, webSocketTranscriptEntry{
+			Connection:       r.webSocketConnections,
+			Path:             exchange.Request.URL.Path,
+			WebSocketMessage: message,
+		})
+	}
+
+	var content []byte
+	for _, entry := range r.webSocketTranscript {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal WebSocket message: %v", err)
+		}
+		content = append(append(content, line...), '\n')
+	}
+	tx := fileutil.NewTransaction()
+	tx.WriteFile(r.webSocketFile, content, 0644)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write WebSocket transcript: %s: %v", r.webSocketFile, err)
+	}
+	logger.Debugf("recorded %d WebSocket message(s) for %v to %s", len(exchange.WebSocketMessages), exchange.Request.URL, r.webSocketFile)
+	return nil
+}
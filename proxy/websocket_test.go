@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_frameCapture(t *testing.T) {
+	tests := []struct {
+		name          string
+		writes        [][]byte
+		limit         int64
+		wantData      []string
+		wantTruncated bool
+	}{
+		{
+			name:     "masked text frame",
+			writes:   [][]byte{buildFrame(true, wsOpText, []byte("hello"), true)},
+			wantData: []string{"hello"},
+		},
+		{
+			name: "frame split across writes",
+			writes: func() [][]byte {
+				frame := buildFrame(true, wsOpText, []byte("hello"), false)
+				return [][]byte{frame[:3], frame[3:]}
+			}(),
+			wantData: []string{"hello"},
+		},
+		{
+			name: "fragmented message and ping",
+			writes: [][]byte{
+				buildFrame(false, wsOpText, []byte("hel"), false),
+				buildFrame(true, 0x9, nil, false),
+				buildFrame(true, wsOpContinuation, []byte("lo"), false),
+			},
+			wantData: []string{"hello"},
+		},
+		{
+			name:          "exceeds limit",
+			writes:        [][]byte{buildFrame(true, wsOpText, []byte("hello"), false)},
+			limit:         4,
+			wantTruncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := newFrameCapture(WebSocketDirectionSend, tt.limit)
+			for _, write := range tt.writes {
+				_, _ = capture.Write(write)
+			}
+			var got []string
+			for _, message := range capture.messages {
+				got = append(got, message.Data)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantData, ",") {
+				t.Errorf("messages = %v, want %v", got, tt.wantData)
+			}
+			if capture.truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", capture.truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestHandle_webSocket(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isWebSocketUpgrade(r) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = fmt.Fprint(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+
+		// echo a single message, unmasked, then close
+		payload, err := readFrame(buf.Reader)
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write(buildFrame(true, wsOpText, payload, false))
+	}))
+	defer upstream.Close()
+
+	exchangeC := make(chan HttpExchange, 1)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Handle(upstream.URL, w, r, 0, nil, func(exchange HttpExchange) {
+			exchangeC <- exchange
+		})
+	}))
+	defer proxyServer.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyServer.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = fmt.Fprint(conn, "GET /chat HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %v, want 101", resp.StatusCode)
+	}
+	_, _ = conn.Write(buildFrame(true, wsOpText, []byte("hello"), true))
+	payload, err := readFrame(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "hello" {
+		t.Errorf("echoed payload = %v, want hello", string(payload))
+	}
+
+	select {
+	case exchange := <-exchangeC:
+		if len(exchange.WebSocketMessages) != 2 {
+			t.Fatalf("messages = %+v, want 2", exchange.WebSocketMessages)
+		}
+		if exchange.WebSocketMessages[0].Direction != WebSocketDirectionSend || exchange.WebSocketMessages[1].Direction != WebSocketDirectionReceive {
+			t.Errorf("unexpected message order: %+v", exchange.WebSocketMessages)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for exchange")
+	}
+}
+
+func buildFrame(fin bool, opcode byte, payload []byte, masked bool) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	if len(payload) < 126 {
+		frame = append(frame, maskBit|byte(len(payload)))
+	} else {
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+		return frame
+	}
+	return append(frame, payload...)
+}
+
+// readFrame reads a single unfragmented frame with a short payload.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	masked := header[1]&0x80 != 0
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(reader, mask); err != nil {
+			return nil, err
+		}
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	for i := range payload {
+		if masked {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return payload, nil
+}
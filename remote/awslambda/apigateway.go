@@ -0,0 +1,105 @@
+package awslambda
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"strings"
+)
+
+// ensureApiGateway ensures an HTTP API exists that routes all requests
+// to the function, returning its endpoint.
+func ensureApiGateway(sess *awssession.Session, svc *lambda.Lambda, region string, apiName string, funcArn string) (string, error) {
+	gw := apigatewayv2.New(sess)
+	api, err := findApi(gw, apiName)
+	if err != nil {
+		return "", err
+	}
+	if api == nil {
+		logger.Debugf("creating HTTP API: %s in region: %s", apiName, region)
+
+		// quick create configures a default route, integration and stage
+		created, err := gw.CreateApi(&apigatewayv2.CreateApiInput{
+			Name:         aws.String(apiName),
+			ProtocolType: aws.String(apigatewayv2.ProtocolTypeHttp),
+			Target:       aws.String(funcArn),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create HTTP API %s in region %s: %v", apiName, region, err)
+		}
+		logger.Infof("created HTTP API: %s with ID: %s", apiName, *created.ApiId)
+		api = &apigatewayv2.Api{ApiId: created.ApiId, ApiEndpoint: created.ApiEndpoint}
+	} else {
+		logger.Debugf("HTTP API already exists: %s", *api.ApiEndpoint)
+	}
+
+	if err = permitApiGatewayInvoke(svc, region, funcArn, *api.ApiId); err != nil {
+		return "", err
+	}
+	return *api.ApiEndpoint, nil
+}
+
+// permitApiGatewayInvoke allows the API to invoke the function.
+func permitApiGatewayInvoke(svc *lambda.Lambda, region string, funcArn string, apiId string) error {
+	// function ARN is of the form arn:aws:lambda:region:account:function:name
+	arnParts := strings.Split(funcArn, ":")
+	if len(arnParts) < 5 {
+		return fmt.Errorf("unexpected function ARN: %s", funcArn)
+	}
+	_, err := svc.AddPermission(&lambda.AddPermissionInput{
+		StatementId:  aws.String("PermitApiGatewayInvoke-" + apiId),
+		Action:       aws.String("lambda:InvokeFunction"),
+		FunctionName: aws.String(funcArn),
+		Principal:    aws.String("apigateway.amazonaws.com"),
+		SourceArn:    aws.String(fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*", region, arnParts[4], apiId)),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == lambda.ErrCodeResourceConflictException {
+			logger.Debugf("API Gateway invoke permission already exists")
+			return nil
+		}
+		return fmt.Errorf("failed to add API Gateway invoke permission: %v", err)
+	}
+	logger.Debugf("added API Gateway invoke permission")
+	return nil
+}
+
+// findApi returns the HTTP API with the given name, or nil if it does not exist.
+func findApi(gw *apigatewayv2.ApiGatewayV2, apiName string) (*apigatewayv2.Api, error) {
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		result, err := gw.GetApis(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list HTTP APIs: %v", err)
+		}
+		for _, api := range result.Items {
+			if aws.StringValue(api.Name) == apiName {
+				return api, nil
+			}
+		}
+		if result.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+func deleteApiGateway(sess *awssession.Session, apiName string) error {
+	gw := apigatewayv2.New(sess)
+	api, err := findApi(gw, apiName)
+	if err != nil {
+		return err
+	} else if api == nil {
+		logger.Tracef("HTTP API %s does not exist", apiName)
+		return nil
+	}
+	_, err = gw.DeleteApi(&apigatewayv2.DeleteApiInput{ApiId: api.ApiId})
+	if err != nil {
+		return fmt.Errorf("failed to delete HTTP API: %s: %v", apiName, err)
+	}
+	logger.Debugf("deleted HTTP API: %s", apiName)
+	return nil
+}
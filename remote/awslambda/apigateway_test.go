@@ -0,0 +1,200 @@
+package awslambda
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testFuncArn = "arn:aws:lambda:eu-west-1:123456789012:function:imposter-test"
+
+// fakeAws serves the subset of the API Gateway and Lambda APIs used to
+// manage the HTTP API of a function.
+type fakeAws struct {
+	mu sync.Mutex
+
+	// apis maps API ID to API name
+	apis map[string]string
+
+	// permissionExists causes AddPermission to fail with a conflict
+	permissionExists bool
+
+	created     []string
+	deleted     []string
+	permissions []map[string]interface{}
+}
+
+func (f *fakeAws) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/v2/apis":
+		var items []map[string]string
+		for id, name := range f.apis {
+			items = append(items, map[string]string{"apiId": id, "name": name, "apiEndpoint": "https://" + id + ".example.com"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+
+	case req.Method == http.MethodPost && req.URL.Path == "/v2/apis":
+		var body map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		f.created = append(f.created, body["name"])
+		f.apis["created"] = body["name"]
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"apiId": "created", "name": body["name"], "apiEndpoint": "https://created.example.com"})
+
+	case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/v2/apis/"):
+		id := strings.TrimPrefix(req.URL.Path, "/v2/apis/")
+		f.deleted = append(f.deleted, id)
+		delete(f.apis, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/policy"):
+		if f.permissionExists {
+			w.Header().Set("X-Amzn-Errortype", lambda.ErrCodeResourceConflictException)
+			w.WriteHeader(http.StatusConflict)
+			_, _ = io.WriteString(w, `{"Type":"User","message":"The statement id provided already exists"}`)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		f.permissions = append(f.permissions, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"Statement":"{}"}`)
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// newFakeAwsSession returns a session whose requests are served by f.
+func newFakeAwsSession(t *testing.T, f *fakeAws) *awssession.Session {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	return awssession.Must(awssession.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("eu-west-1"),
+		MaxRetries:  aws.Int(0),
+	}))
+}
+
+func Test_ensureApiGateway(t *testing.T) {
+	tests := []struct {
+		name             string
+		apis             map[string]string
+		permissionExists bool
+		wantEndpoint     string
+		wantCreated      []string
+		wantPermission   bool
+	}{
+		{
+			name:           "creates api when missing",
+			apis:           map[string]string{"other": "other-api"},
+			wantEndpoint:   "https://created.example.com",
+			wantCreated:    []string{"imposter-test"},
+			wantPermission: true,
+		},
+		{
+			name:           "reuses existing api",
+			apis:           map[string]string{"existing": "imposter-test"},
+			wantEndpoint:   "https://existing.example.com",
+			wantPermission: true,
+		},
+		{
+			name:             "tolerates existing permission",
+			apis:             map[string]string{"existing": "imposter-test"},
+			permissionExists: true,
+			wantEndpoint:     "https://existing.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAws{apis: tt.apis, permissionExists: tt.permissionExists}
+			sess := newFakeAwsSession(t, f)
+
+			endpoint, err := ensureApiGateway(sess, lambda.New(sess), "eu-west-1", "imposter-test", testFuncArn)
+			if err != nil {
+				t.Fatalf("ensureApiGateway() error = %v", err)
+			}
+			if endpoint != tt.wantEndpoint {
+				t.Errorf("ensureApiGateway() endpoint = %v, want %v", endpoint, tt.wantEndpoint)
+			}
+			if strings.Join(f.created, ",") != strings.Join(tt.wantCreated, ",") {
+				t.Errorf("ensureApiGateway() created = %v, want %v", f.created, tt.wantCreated)
+			}
+			if !tt.wantPermission {
+				if len(f.permissions) != 0 {
+					t.Errorf("ensureApiGateway() added permissions = %v, want none", f.permissions)
+				}
+				return
+			}
+			if len(f.permissions) != 1 {
+				t.Fatalf("ensureApiGateway() added %d permissions, want 1", len(f.permissions))
+			}
+			permission := f.permissions[0]
+			if permission["Principal"] != "apigateway.amazonaws.com" {
+				t.Errorf("permission principal = %v, want apigateway.amazonaws.com", permission["Principal"])
+			}
+			apiId := strings.TrimPrefix(strings.TrimSuffix(tt.wantEndpoint, ".example.com"), "https://")
+			wantSourceArn := "arn:aws:execute-api:eu-west-1:123456789012:" + apiId + "/*"
+			if permission["SourceArn"] != wantSourceArn {
+				t.Errorf("permission source ARN = %v, want %v", permission["SourceArn"], wantSourceArn)
+			}
+		})
+	}
+}
+
+func Test_ensureApiGateway_invalidArn(t *testing.T) {
+	f := &fakeAws{apis: map[string]string{"existing": "imposter-test"}}
+	sess := newFakeAwsSession(t, f)
+
+	if _, err := ensureApiGateway(sess, lambda.New(sess), "eu-west-1", "imposter-test", "not-an-arn"); err == nil {
+		t.Errorf("ensureApiGateway() expected error for invalid function ARN")
+	}
+}
+
+func Test_deleteApiGateway(t *testing.T) {
+	tests := []struct {
+		name        string
+		apis        map[string]string
+		wantDeleted []string
+	}{
+		{
+			name:        "deletes matching api",
+			apis:        map[string]string{"existing": "imposter-test", "other": "other-api"},
+			wantDeleted: []string{"existing"},
+		},
+		{
+			name: "ignores missing api",
+			apis: map[string]string{"other": "other-api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAws{apis: tt.apis}
+			sess := newFakeAwsSession(t, f)
+
+			if err := deleteApiGateway(sess, "imposter-test"); err != nil {
+				t.Fatalf("deleteApiGateway() error = %v", err)
+			}
+			if strings.Join(f.deleted, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("deleteApiGateway() deleted = %v, want %v", f.deleted, tt.wantDeleted)
+			}
+			if _, ok := f.apis["other"]; !ok {
+				t.Errorf("deleteApiGateway() deleted unrelated api")
+			}
+		})
+	}
+}
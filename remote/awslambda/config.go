@@ -19,6 +19,13 @@ const (
 	LambdaArchitectureArm64  LambdaArchitecture = "arm64"
 )
 
+type EndpointType string
+
+const (
+	EndpointTypeUrl        EndpointType = "url"
+	EndpointTypeApiGateway EndpointType = "apigateway"
+)
+
 const remoteType = "awslambda"
 const defaultArchitecture = LambdaArchitectureX86_64
 const defaultRegion = "us-east-1"
@@ -26,6 +33,7 @@ const defaultMemory = 768
 
 const configKeyAnonAccess = "anonAccess"
 const configKeyArchitecture = "architecture"
+const configKeyEndpointType = "endpointType"
 const configKeyEngineVersion = "engineVersion"
 const configKeyFuncName = "functionName"
 const configKeyIamRoleName = "iamRoleName"
@@ -35,6 +43,7 @@ const configKeyRegion = "region"
 var configKeys = []string{
 	configKeyAnonAccess,
 	configKeyArchitecture,
+	configKeyEndpointType,
 	configKeyEngineVersion,
 	configKeyFuncName,
 	configKeyIamRoleName,
@@ -100,6 +109,10 @@ func (m LambdaRemote) SetConfigValue(key string, value string) error {
 		if !regionFound {
 			return fmt.Errorf("invalid region: %s", value)
 		}
+	} else if key == configKeyEndpointType {
+		if value != string(EndpointTypeUrl) && value != string(EndpointTypeApiGateway) {
+			return fmt.Errorf("invalid endpoint type: %s - must be one of: %s, %s", value, EndpointTypeUrl, EndpointTypeApiGateway)
+		}
 	}
 	m.Config[key] = value
 	return m.SaveConfig()
@@ -161,3 +174,10 @@ func (m LambdaRemote) getArchitecture() LambdaArchitecture {
 	}
 	return defaultArchitecture
 }
+
+func (m LambdaRemote) getEndpointType() EndpointType {
+	if configuredType := m.Config[configKeyEndpointType]; configuredType != "" {
+		return EndpointType(configuredType)
+	}
+	return EndpointTypeUrl
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"os"
@@ -38,6 +39,11 @@ func (m LambdaRemote) Deploy() error {
 		logger.Fatal(err)
 	}
 
+	secrets, err := remote.LoadSecrets(m.Dir, m.Workspace)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %s", err)
+	}
+
 	funcArn, err := ensureFunctionExists(
 		svc,
 		region,
@@ -46,10 +52,16 @@ func (m LambdaRemote) Deploy() error {
 		m.getMemorySize(),
 		m.getArchitecture(),
 		zipContents,
+		buildEnv(secrets),
 	)
 	if err != nil {
 		return err
 	}
+	if m.getEndpointType() == EndpointTypeApiGateway {
+		_, err = ensureApiGateway(sess, svc, region, m.getFunctionName(), funcArn)
+		return err
+	}
+
 	_, err = ensureUrlConfigured(svc, funcArn)
 	if err != nil {
 		return err
@@ -64,12 +76,15 @@ func (m LambdaRemote) Deploy() error {
 }
 
 func (m LambdaRemote) Undeploy() error {
-	region, _, svc, err := m.initAws()
+	region, sess, svc, err := m.initAws()
 	if err != nil {
 		return err
 	}
 
 	funcName := m.getFunctionName()
+	if err = deleteApiGateway(sess, funcName); err != nil {
+		return err
+	}
 
 	var funcArn string
 	funcExists, err := checkFunctionExists(svc, funcName)
@@ -97,7 +112,7 @@ func (m LambdaRemote) Undeploy() error {
 }
 
 func (m LambdaRemote) GetEndpoint() (*remote.EndpointDetails, error) {
-	_, _, svc, err := m.initAws()
+	_, sess, svc, err := m.initAws()
 	if err != nil {
 		return nil, err
 	}
@@ -112,12 +127,24 @@ func (m LambdaRemote) GetEndpoint() (*remote.EndpointDetails, error) {
 	}
 
 	var functionUrl string
-	getUrlResult, err := checkFunctionUrlConfig(svc, funcArn)
-	if err != nil {
-		return nil, err
+	if m.getEndpointType() == EndpointTypeApiGateway {
+		api, err := findApi(apigatewayv2.New(sess), m.getFunctionName())
+		if err != nil {
+			return nil, err
+		} else if api == nil {
+			return nil, fmt.Errorf("no HTTP API found for function: %s", m.getFunctionName())
+		}
+		functionUrl = *api.ApiEndpoint
+		logger.Tracef("API endpoint: %s", functionUrl)
+
 	} else {
-		functionUrl = *getUrlResult.FunctionUrl
-		logger.Tracef("function URL: %s", functionUrl)
+		getUrlResult, err := checkFunctionUrlConfig(svc, funcArn)
+		if err != nil {
+			return nil, err
+		} else {
+			functionUrl = *getUrlResult.FunctionUrl
+			logger.Tracef("function URL: %s", functionUrl)
+		}
 	}
 
 	details := &remote.EndpointDetails{
@@ -226,6 +253,7 @@ func ensureFunctionExists(
 	memoryMb int64,
 	arch LambdaArchitecture,
 	zipContents *[]byte,
+	env *lambda.Environment,
 ) (string, error) {
 	var funcArn string
 	result, err := checkFunctionExists(svc, funcName)
@@ -240,6 +268,7 @@ func ensureFunctionExists(
 					memoryMb,
 					arch,
 					zipContents,
+					env,
 				)
 				if err != nil {
 					return "", err
@@ -257,6 +286,9 @@ func ensureFunctionExists(
 		if err = updateFunctionCode(svc, funcArn, zipContents); err != nil {
 			return "", err
 		}
+		if err = updateFunctionEnv(svc, funcArn, env); err != nil {
+			return "", err
+		}
 	}
 	return funcArn, nil
 }
@@ -276,6 +308,7 @@ func createFunction(
 	memoryMb int64,
 	arch LambdaArchitecture,
 	zipContents *[]byte,
+	env *lambda.Environment,
 ) (arn string, err error) {
 	logger.Debugf("creating function: %s in region: %s", funcName, region)
 
@@ -289,7 +322,7 @@ func createFunction(
 		Role:          aws.String(roleArn),
 		Runtime:       aws.String("java11"),
 		Architectures: []*string{aws.String(string(arch))},
-		Environment:   buildEnv(),
+		Environment:   env,
 	}
 
 	result, err := svc.CreateFunction(input)
@@ -319,6 +352,27 @@ func updateFunctionCode(svc *lambda.Lambda, funcArn string, zipContents *[]byte)
 	return nil
 }
 
+// updateFunctionEnv replaces the environment of the function, once the
+// preceding code update has completed.
+func updateFunctionEnv(svc *lambda.Lambda, funcArn string, env *lambda.Environment) error {
+	logger.Debugf("updating function environment for: %s", funcArn)
+	err := svc.WaitUntilFunctionUpdated(&lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(funcArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed waiting for function update: %s: %v", funcArn, err)
+	}
+	_, err = svc.UpdateFunctionConfiguration(&lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(funcArn),
+		Environment:  env,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update function environment: %s: %v", funcArn, err)
+	}
+	logger.Debugf("updated function environment for: %s", funcArn)
+	return nil
+}
+
 func ensureUrlConfigured(svc *lambda.Lambda, funcArn string) (string, error) {
 	logger.Debugf("configuring URL for function: %s", funcArn)
 
@@ -366,8 +420,13 @@ func (m LambdaRemote) getAwsRegion() string {
 	panic("no AWS default region set")
 }
 
-func buildEnv() *lambda.Environment {
+// buildEnv returns the environment of the function, including the
+// workspace secrets.
+func buildEnv(secrets map[string]string) *lambda.Environment {
 	env := make(map[string]*string)
+	for name, value := range secrets {
+		env[name] = aws.String(value)
+	}
 	env["IMPOSTER_CONFIG_DIR"] = aws.String("/var/task/config")
 	env["JAVA_TOOL_OPTIONS"] = aws.String("-XX:+TieredCompilation -XX:TieredStopAtLevel=1")
 	return &lambda.Environment{Variables: env}
@@ -0,0 +1,69 @@
+package awslambda
+
+import (
+	"fmt"
+	"gatehill.io/imposter/remote"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"io"
+	"strings"
+	"time"
+)
+
+const logPollInterval = 2 * time.Second
+const defaultLogsSince = time.Hour
+
+// StreamLogs writes the events in the CloudWatch log group of the function.
+// If following, the log group is polled for new events.
+func (m LambdaRemote) StreamLogs(w io.Writer, options remote.LogOptions) error {
+	if m.Config[configKeyRegion] == "" {
+		return fmt.Errorf("region cannot be null")
+	}
+	_, sess := m.startAwsSession()
+	svc := cloudwatchlogs.New(sess)
+	logGroupName := "/aws/lambda/" + m.getFunctionName()
+
+	since := options.Since
+	if since == 0 {
+		since = defaultLogsSince
+	}
+	startTime := time.Now().Add(-since).UnixMilli()
+	for {
+		lastTimestamp, err := writeLogEvents(svc, logGroupName, startTime, w)
+		if err != nil {
+			return err
+		}
+		if !options.Follow {
+			return nil
+		}
+		if lastTimestamp >= startTime {
+			startTime = lastTimestamp + 1
+		}
+		time.Sleep(logPollInterval)
+	}
+}
+
+// writeLogEvents writes the events in the log group from startTime,
+// returning the timestamp of the last event written.
+func writeLogEvents(svc *cloudwatchlogs.CloudWatchLogs, logGroupName string, startTime int64, w io.Writer) (lastTimestamp int64, err error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(logGroupName),
+		StartTime:    aws.Int64(startTime),
+	}
+	err = svc.FilterLogEventsPages(input, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		for _, event := range page.Events {
+			message := strings.TrimSuffix(aws.StringValue(event.Message), "\n")
+			if _, err := fmt.Fprintln(w, message); err != nil {
+				return false
+			}
+			if timestamp := aws.Int64Value(event.Timestamp); timestamp > lastTimestamp {
+				lastTimestamp = timestamp
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get log events for: %s: %v", logGroupName, err)
+	}
+	return lastTimestamp, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client makes authenticated requests to the API of a remote.
+type Client struct {
+	BaseUrl    string
+	Token      string
+	HttpClient *http.Client
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(baseUrl string, token string) *Client {
+	return &Client{
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		Token:      token,
+		HttpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Request sends a request without a body, unmarshalling any JSON response
+// into response, if it is not nil.
+func (c *Client) Request(method string, path string, response interface{}) error {
+	return c.do(method, path, "", nil, nil, response)
+}
+
+// Upload sends the content as the body of the request, unmarshalling any
+// JSON response into response, if it is not nil.
+func (c *Client) Upload(method string, path string, contentType string, content []byte, response interface{}) error {
+	return c.UploadWithHeaders(method, path, contentType, content, nil, response)
+}
+
+// UploadWithHeaders sends the content as the body of the request, along
+// with the given headers, unmarshalling any JSON response into response,
+// if it is not nil.
+func (c *Client) UploadWithHeaders(method string, path string, contentType string, content []byte, headers http.Header, response interface{}) error {
+	return c.do(method, path, contentType, bytes.NewReader(content), headers, response)
+}
+
+func (c *Client) do(method string, path string, contentType string, body io.Reader, headers http.Header, response interface{}) error {
+	url := c.BaseUrl + path
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %s", url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return buildStatusError(url, resp.StatusCode, respBody)
+	}
+	if response != nil && len(respBody) > 0 {
+		if err = json.Unmarshal(respBody, response); err != nil {
+			return fmt.Errorf("failed to unmarshall response from: %s: %s", url, err)
+		}
+	}
+	return nil
+}
+
+// Stream sends a request without a body, copying the response body to w
+// as it is received. Streams are not subject to the client timeout.
+func (c *Client) Stream(method string, path string, w io.Writer) error {
+	url := c.BaseUrl + path
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	streamClient := *c.HttpClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return buildStatusError(url, resp.StatusCode, respBody)
+	}
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read response from %s: %s", url, err)
+	}
+	return nil
+}
+
+func buildStatusError(url string, statusCode int, respBody []byte) error {
+	var errResp errorResponse
+	if json.Unmarshal(respBody, &errResp) == nil && errResp.Error != "" {
+		return fmt.Errorf("error requesting %s - HTTP status: %d: %s", url, statusCode, errResp.Error)
+	}
+	return fmt.Errorf("error requesting %s - HTTP status: %d", url, statusCode)
+}
+
+// Poll calls check at the given interval until it reports that it is done,
+// returns an error, or the timeout elapses.
+func Poll(interval time.Duration, timeout time.Duration, check func() (done bool, err error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		} else if done {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(interval)
+	}
+}
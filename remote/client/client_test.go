@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Request(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid or missing token"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/status":
+			_, _ = w.Write([]byte(`{"state":"running"}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{name: "json response", token: "secret", path: "/status", want: "running"},
+		{name: "empty response", token: "secret", path: "/empty", want: ""},
+		{name: "error with message", token: "wrong", path: "/status", wantErr: "HTTP status: 401: invalid or missing token"},
+		{name: "error without message", token: "secret", path: "/missing", wantErr: "HTTP status: 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				State string `json:"state"`
+			}
+			err := New(server.URL+"/", tt.token).Request("GET", tt.path, &resp)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Errorf("Request() error = %v, want suffix %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			if resp.State != tt.want {
+				t.Errorf("Request() state = %v, want %v", resp.State, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Upload(t *testing.T) {
+	var gotMethod, gotContentType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotContentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	err := New(server.URL, "secret").Upload("PUT", "/bundle", "application/zip", []byte("content"), nil)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if gotMethod != "PUT" || gotContentType != "application/zip" || gotBody != "content" {
+		t.Errorf("Upload() sent %v %v %v, want PUT application/zip content", gotMethod, gotContentType, gotBody)
+	}
+}
+
+func TestClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"no such mock"}`))
+			return
+		}
+		_, _ = w.Write([]byte("line 1\nline 2\n"))
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	if err := New(server.URL, "secret").Stream("GET", "/logs", &out); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if out.String() != "line 1\nline 2\n" {
+		t.Errorf("Stream() wrote %q", out.String())
+	}
+	err := New(server.URL, "secret").Stream("GET", "/missing", &out)
+	if err == nil || !strings.HasSuffix(err.Error(), "HTTP status: 404: no such mock") {
+		t.Errorf("Stream() error = %v, want 404", err)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	calls := 0
+	err := Poll(time.Millisecond, time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Poll() error = %v, calls = %v, want nil and 3", err, calls)
+	}
+
+	err = Poll(time.Millisecond, time.Second, func() (bool, error) {
+		return false, fmt.Errorf("failed")
+	})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Poll() error = %v, want failed", err)
+	}
+
+	err = Poll(5*time.Millisecond, 10*time.Millisecond, func() (bool, error) {
+		return false, nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "timed out") {
+		t.Errorf("Poll() error = %v, want timeout", err)
+	}
+}
@@ -33,6 +33,12 @@ func (m CloudMocksRemote) Deploy() error {
 		return fmt.Errorf("auth token cannot be null")
 	}
 
+	if secrets, err := remote.LoadSecrets(m.Dir, m.Workspace); err != nil {
+		return fmt.Errorf("failed to load secrets: %s", err)
+	} else if len(secrets) > 0 {
+		logger.Warnf("the %s remote does not support secrets - %d secret(s) will not be deployed", remoteType, len(secrets))
+	}
+
 	err := m.ensureMockExists()
 	if err != nil {
 		return err
@@ -0,0 +1,47 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Health describes the result of calling the status endpoint of a mock.
+type Health struct {
+	Healthy bool
+	Version string
+	Message string
+}
+
+type systemStatusResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+var healthClient = &http.Client{Timeout: 10 * time.Second}
+
+// CheckHealth calls the status endpoint of a deployed mock. The mock is
+// healthy if the endpoint returns HTTP 200. The engine version is
+// read from the response, if present.
+func CheckHealth(statusUrl string) Health {
+	resp, err := healthClient.Get(statusUrl)
+	if err != nil {
+		return Health{Message: fmt.Sprintf("request failed: %s", err)}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Health{Message: fmt.Sprintf("failed to read response: %s", err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Health{Message: fmt.Sprintf("HTTP status %d", resp.StatusCode)}
+	}
+	health := Health{Healthy: true}
+	var status systemStatusResponse
+	if json.Unmarshal(body, &status) == nil {
+		health.Version = status.Version
+	}
+	return health
+}
@@ -0,0 +1,41 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/system/status":
+			_, _ = w.Write([]byte(`{"status":"ok","version":"4.2.0"}`))
+		case "/plain/system/status":
+			_, _ = w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		wantHealthy bool
+		wantVersion string
+		wantMessage string
+	}{
+		{name: "healthy with version", path: "/system/status", wantHealthy: true, wantVersion: "4.2.0"},
+		{name: "healthy without version", path: "/plain/system/status", wantHealthy: true},
+		{name: "unhealthy", path: "/down", wantMessage: "HTTP status 503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckHealth(server.URL + tt.path)
+			if got.Healthy != tt.wantHealthy || got.Version != tt.wantVersion || got.Message != tt.wantMessage {
+				t.Errorf("CheckHealth() = %+v, want healthy %v, version %v, message %v", got, tt.wantHealthy, tt.wantVersion, tt.wantMessage)
+			}
+		})
+	}
+}
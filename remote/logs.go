@@ -0,0 +1,21 @@
+package remote
+
+import (
+	"io"
+	"time"
+)
+
+// LogStreamer is implemented by remotes that can retrieve the logs of
+// a deployed mock.
+type LogStreamer interface {
+	// StreamLogs writes the logs of the mock to w. If follow is set, new
+	// log lines are written until an error occurs or the process exits.
+	StreamLogs(w io.Writer, options LogOptions) error
+}
+
+type LogOptions struct {
+	Follow bool
+
+	// Since limits the logs to those written within the duration.
+	Since time.Duration
+}
@@ -58,6 +58,8 @@ func SaveActiveRemoteType(dir string, remoteType string) (*workspace.Workspace,
 	active, m, err := workspace.GetActiveWithMetadata(dir)
 	if err != nil {
 		return nil, err
+	} else if active == nil {
+		return nil, fmt.Errorf("no active workspace")
 	}
 	active.RemoteType = remoteType
 	err = workspace.SaveMetadata(dir, m)
@@ -79,12 +81,27 @@ func Load(dir string, workspace *workspace.Workspace) (*Remote, error) {
 }
 
 func LoadActive(dir string) (*workspace.Workspace, *Remote, error) {
+	return LoadActiveAs(dir, "")
+}
+
+// LoadActiveAs loads the remote of the active workspace, using the remote
+// type, if not empty, instead of the type set for the workspace. The
+// remote type of the workspace is not changed.
+func LoadActiveAs(dir string, remoteType string) (*workspace.Workspace, *Remote, error) {
 	active, err := workspace.GetActive(dir)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to load workspace: %s", err)
 	} else if active == nil {
 		return nil, nil, fmt.Errorf("no active workspace")
 	}
+	if remoteType != "" {
+		if providers[remoteType] == nil {
+			return nil, nil, fmt.Errorf("unsupported remote type: %s", remoteType)
+		}
+		override := *active
+		override.RemoteType = remoteType
+		active = &override
+	}
 
 	r, err := Load(dir, active)
 	if err != nil {
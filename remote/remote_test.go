@@ -0,0 +1,74 @@
+package remote
+
+import (
+	"gatehill.io/imposter/workspace"
+	"testing"
+)
+
+type fakeRemote struct {
+	Remote
+	remoteType string
+}
+
+func (r fakeRemote) GetType() string {
+	return r.remoteType
+}
+
+func registerFake(t *testing.T, remoteType string) {
+	Register(remoteType, func(dir string, w *workspace.Workspace) (Remote, error) {
+		return fakeRemote{remoteType: w.RemoteType}, nil
+	})
+	t.Cleanup(func() { delete(providers, remoteType) })
+}
+
+func TestLoadActiveAs(t *testing.T) {
+	registerFake(t, "fake-default")
+	registerFake(t, "fake-override")
+
+	dir := t.TempDir()
+	if _, err := workspace.New(dir, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspace.SetActive(dir, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveActiveRemoteType(dir, "fake-default"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteType string
+		want       string
+		wantErr    bool
+	}{
+		{name: "uses workspace remote type", remoteType: "", want: "fake-default"},
+		{name: "uses override remote type", remoteType: "fake-override", want: "fake-override"},
+		{name: "rejects unknown remote type", remoteType: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, r, err := LoadActiveAs(dir, tt.remoteType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadActiveAs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if active.RemoteType != tt.want {
+				t.Errorf("LoadActiveAs() workspace remote type = %v, want %v", active.RemoteType, tt.want)
+			}
+			if got := (*r).GetType(); got != tt.want {
+				t.Errorf("LoadActiveAs() remote type = %v, want %v", got, tt.want)
+			}
+
+			saved, err := workspace.GetActive(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.RemoteType != "fake-default" {
+				t.Errorf("LoadActiveAs() changed saved remote type to %v", saved.RemoteType)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/library"
+	"gatehill.io/imposter/stringutil"
+	"gatehill.io/imposter/workspace"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Secrets are stored in an encrypted file in the user's preferences
+// directory, rather than in the workspace, so they are never committed
+// alongside the workspace metadata. The key is not stored with the file;
+// it is held in the OS keychain, or derived from a passphrase.
+const secretsFileName = "secrets.enc"
+
+// secretsFileMagic begins a secrets file, followed by the source of its
+// key, the salt, the nonce and the ciphertext.
+var secretsFileMagic = []byte("IMPSEC2")
+
+// legacySecretsKeyFileName is the key file stored alongside the secrets
+// file by earlier versions. It is removed once the secrets are re-encrypted.
+const legacySecretsKeyFileName = "secrets.key"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// getSecretsDir returns the directory holding the secrets file.
+var getSecretsDir = func() (string, error) {
+	return library.EnsureDirUsingConfig("prefs.dir", ".imposter")
+}
+
+// secretStore holds the secrets of each workspace, keyed by scope and
+// then by environment variable name.
+type secretStore map[string]map[string]string
+
+// SetSecret stores the secret with the given name for the workspace. The
+// name is used as the environment variable name at deploy time.
+func SetSecret(dir string, w *workspace.Workspace, name string, value string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name: %s - must be a valid environment variable name", name)
+	}
+	store, err := loadSecretStore()
+	if err != nil {
+		return err
+	}
+	scope := getSecretsScope(dir, w)
+	if store[scope] == nil {
+		store[scope] = make(map[string]string)
+	}
+	store[scope][name] = value
+	return saveSecretStore(store)
+}
+
+// DeleteSecret removes the secret with the given name from the workspace.
+func DeleteSecret(dir string, w *workspace.Workspace, name string) error {
+	store, err := loadSecretStore()
+	if err != nil {
+		return err
+	}
+	scope := getSecretsScope(dir, w)
+	if _, ok := store[scope][name]; !ok {
+		return fmt.Errorf("no such secret: %s", name)
+	}
+	delete(store[scope], name)
+	if len(store[scope]) == 0 {
+		delete(store, scope)
+	}
+	return saveSecretStore(store)
+}
+
+// ListSecretNames returns the sorted names of the secrets of the workspace.
+func ListSecretNames(dir string, w *workspace.Workspace) ([]string, error) {
+	secrets, err := LoadSecrets(dir, w)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadSecrets returns the secrets of the workspace, keyed by environment
+// variable name. Remotes inject these into the environment of the mock
+// when the workspace is deployed.
+func LoadSecrets(dir string, w *workspace.Workspace) (map[string]string, error) {
+	store, err := loadSecretStore()
+	if err != nil {
+		return nil, err
+	}
+	secrets := store[getSecretsScope(dir, w)]
+	if secrets == nil {
+		secrets = make(map[string]string)
+	}
+	return secrets, nil
+}
+
+// getSecretsScope identifies the workspace by its absolute directory and
+// name, so workspaces with the same name in different directories do not
+// share secrets.
+func getSecretsScope(dir string, w *workspace.Workspace) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+	return stringutil.Sha1hashString(absDir) + "/" + w.Name
+}
+
+func loadSecretStore() (secretStore, error) {
+	secretsDir, err := getSecretsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets directory: %s", err)
+	}
+	secretsFile := filepath.Join(secretsDir, secretsFileName)
+	encrypted, err := os.ReadFile(secretsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(secretStore), nil
+		}
+		return nil, fmt.Errorf("failed to read secrets file: %s: %s", secretsFile, err)
+	}
+
+	var key []byte
+	if bytes.HasPrefix(encrypted, secretsFileMagic) {
+		headerSize := len(secretsFileMagic) + 1 + secretsSaltSize
+		if len(encrypted) < headerSize {
+			return nil, fmt.Errorf("secrets file is corrupt: %s", secretsFile)
+		}
+		source := keySource(encrypted[len(secretsFileMagic)])
+		salt := encrypted[len(secretsFileMagic)+1 : headerSize]
+		if key, err = getSecretsKey(source, salt, false); err != nil {
+			return nil, err
+		}
+		encrypted = encrypted[headerSize:]
+	} else {
+		keyFile := filepath.Join(secretsDir, legacySecretsKeyFileName)
+		if key, err = os.ReadFile(keyFile); err != nil {
+			return nil, fmt.Errorf("failed to read secrets key: %s: %s", keyFile, err)
+		}
+		logger.Debugf("read secrets encrypted with legacy key file: %s", keyFile)
+	}
+
+	gcm, err := newSecretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(encrypted) < nonceSize {
+		return nil, fmt.Errorf("secrets file is corrupt: %s", secretsFile)
+	}
+	plaintext, err := gcm.Open(nil, encrypted[:nonceSize], encrypted[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file: %s: %s", secretsFile, err)
+	}
+	var store secretStore
+	if err = json.Unmarshal(plaintext, &store); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall secrets file: %s: %s", secretsFile, err)
+	}
+	return store, nil
+}
+
+func saveSecretStore(store secretStore) error {
+	secretsDir, err := getSecretsDir()
+	if err != nil {
+		return fmt.Errorf("failed to get secrets directory: %s", err)
+	}
+	source, err := getPreferredKeySource()
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, secretsSaltSize)
+	if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %s", err)
+	}
+	key, err := getSecretsKey(source, salt, true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newSecretsCipher(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(store)
+	if err != nil {
+		return fmt.Errorf("failed to marshall secrets: %s", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %s", err)
+	}
+	header := append(append(bytes.Clone(secretsFileMagic), byte(source)), salt...)
+	encrypted := gcm.Seal(append(header, nonce...), nonce, plaintext, nil)
+
+	secretsFile := filepath.Join(secretsDir, secretsFileName)
+	if err = fileutil.WriteFileAtomic(secretsFile, encrypted, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %s: %s", secretsFile, err)
+	}
+	keyFile := filepath.Join(secretsDir, legacySecretsKeyFileName)
+	if err = os.Remove(keyFile); err == nil {
+		logger.Debugf("removed legacy secrets key file: %s", keyFile)
+	} else if !os.IsNotExist(err) {
+		logger.Warnf("failed to remove legacy secrets key file: %s: %s", keyFile, err)
+	}
+	return nil
+}
+
+func newSecretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %s", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// BuildSecretsEnv returns the secrets as a list of NAME=value pairs,
+// sorted by name.
+func BuildSecretsEnv(secrets map[string]string) []string {
+	var env []string
+	for name, value := range secrets {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,185 @@
+package remote
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"gatehill.io/imposter/workspace"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeKeychain struct {
+	key []byte
+}
+
+func (k *fakeKeychain) get() ([]byte, bool, error) {
+	return k.key, k.key != nil, nil
+}
+
+func (k *fakeKeychain) set(key []byte) error {
+	k.key = key
+	return nil
+}
+
+// useSecretsDir stores secrets in a temporary directory, with the given
+// keychain, and no passphrase.
+func useSecretsDir(t *testing.T, k keychain) string {
+	secretsDir := t.TempDir()
+	originalDir, originalKeychain := getSecretsDir, osKeychain
+	getSecretsDir = func() (string, error) { return secretsDir, nil }
+	osKeychain = k
+	t.Cleanup(func() {
+		getSecretsDir, osKeychain = originalDir, originalKeychain
+	})
+	t.Setenv(SecretsPassphraseEnvVar, "")
+	return secretsDir
+}
+
+func TestSecrets(t *testing.T) {
+	k := &fakeKeychain{}
+	secretsDir := useSecretsDir(t, k)
+
+	dir := t.TempDir()
+	w := &workspace.Workspace{Name: "test"}
+	other := &workspace.Workspace{Name: "other"}
+
+	if err := SetSecret(dir, w, "API_TOKEN", "s3cret"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if err := SetSecret(dir, w, "DB_PASSWORD", "hunter2"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if err := SetSecret(dir, w, "not-valid", "x"); err == nil {
+		t.Errorf("expected error for invalid secret name")
+	}
+
+	names, err := ListSecretNames(dir, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"API_TOKEN", "DB_PASSWORD"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListSecretNames() = %v, want %v", names, want)
+	}
+	if otherSecrets, _ := LoadSecrets(dir, other); len(otherSecrets) != 0 {
+		t.Errorf("expected no secrets for other workspace, got: %v", otherSecrets)
+	}
+
+	encrypted, err := os.ReadFile(filepath.Join(secretsDir, secretsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "s3cret") {
+		t.Errorf("expected secrets file to be encrypted")
+	}
+	if len(k.key) != 32 {
+		t.Errorf("expected key to be added to keychain")
+	}
+	if entries, _ := os.ReadDir(secretsDir); len(entries) != 1 {
+		t.Errorf("expected only the secrets file in the secrets directory, got: %v", entries)
+	}
+
+	if err := DeleteSecret(dir, w, "API_TOKEN"); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if err := DeleteSecret(dir, w, "API_TOKEN"); err == nil {
+		t.Errorf("expected error deleting missing secret")
+	}
+	secrets, err := LoadSecrets(dir, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := BuildSecretsEnv(secrets), []string{"DB_PASSWORD=hunter2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildSecretsEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestSecrets_passphrase(t *testing.T) {
+	useSecretsDir(t, nil)
+	dir := t.TempDir()
+	w := &workspace.Workspace{Name: "test"}
+
+	if err := SetSecret(dir, w, "API_TOKEN", "s3cret"); err == nil {
+		t.Errorf("expected error without keychain or passphrase")
+	}
+
+	t.Setenv(SecretsPassphraseEnvVar, "correct horse")
+	if err := SetSecret(dir, w, "API_TOKEN", "s3cret"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	secrets, err := LoadSecrets(dir, w)
+	if err != nil {
+		t.Fatalf("LoadSecrets() error = %v", err)
+	}
+	if secrets["API_TOKEN"] != "s3cret" {
+		t.Errorf("LoadSecrets() = %v, want API_TOKEN", secrets)
+	}
+
+	t.Setenv(SecretsPassphraseEnvVar, "wrong")
+	if _, err := LoadSecrets(dir, w); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("LoadSecrets() error = %v, want decryption error", err)
+	}
+	t.Setenv(SecretsPassphraseEnvVar, "")
+	if _, err := LoadSecrets(dir, w); err == nil || !strings.Contains(err.Error(), SecretsPassphraseEnvVar) {
+		t.Errorf("LoadSecrets() error = %v, want passphrase error", err)
+	}
+}
+
+func TestSecrets_migratesLegacyKeyFile(t *testing.T) {
+	secretsDir := useSecretsDir(t, &fakeKeychain{})
+	dir := t.TempDir()
+	w := &workspace.Workspace{Name: "test"}
+
+	// write a secrets file encrypted with a key file alongside it
+	key := make([]byte, 32)
+	keyFile := filepath.Join(secretsDir, legacySecretsKeyFileName)
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	plaintext, _ := json.Marshal(secretStore{getSecretsScope(dir, w): {"API_TOKEN": "s3cret"}})
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	if err := os.WriteFile(filepath.Join(secretsDir, secretsFileName), gcm.Seal(nonce, nonce, plaintext, nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetSecret(dir, w, "DB_PASSWORD", "hunter2"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+		t.Errorf("expected legacy key file to be removed, stat error: %v", err)
+	}
+	names, err := ListSecretNames(dir, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"API_TOKEN", "DB_PASSWORD"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListSecretNames() = %v, want %v", names, want)
+	}
+}
+
+func Test_pbkdf2Key(t *testing.T) {
+	// test vectors from RFC 7914, section 11
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{password: "passwd", salt: "salt", iterations: 1, want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{password: "Password", salt: "NaCl", iterations: 80000, want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got := hex.EncodeToString(pbkdf2Key([]byte(tt.password), []byte(tt.salt), tt.iterations, 64))
+			if got != tt.want {
+				t.Errorf("pbkdf2Key() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
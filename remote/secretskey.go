@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// SecretsPassphraseEnvVar holds a passphrase from which the secrets key is
+// derived, instead of storing the key in the OS keychain.
+const SecretsPassphraseEnvVar = "IMPOSTER_SECRETS_PASSPHRASE"
+
+const keychainService = "imposter-cli"
+const keychainAccount = "remote-secrets"
+
+const pbkdf2Iterations = 600_000
+const secretsSaltSize = 16
+
+// keySource identifies where the key of a secrets file comes from. It is
+// stored in the header of the file, so the file can be decrypted however
+// the key was obtained.
+type keySource byte
+
+const (
+	keySourceKeychain   keySource = 'k'
+	keySourcePassphrase keySource = 'p'
+)
+
+// keychain stores the secrets key in the OS credential store.
+type keychain interface {
+	get() (key []byte, found bool, err error)
+	set(key []byte) error
+}
+
+// osKeychain is the keychain of the platform, or nil if it has none that
+// the CLI can use.
+var osKeychain = newOSKeychain()
+
+func newOSKeychain() keychain {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}
+	case "windows":
+		return nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err == nil {
+		return secretServiceKeychain{}
+	}
+	return nil
+}
+
+// getPreferredKeySource returns the source of the key used to encrypt the
+// secrets file: the passphrase, if one is set, or the OS keychain.
+func getPreferredKeySource() (keySource, error) {
+	if os.Getenv(SecretsPassphraseEnvVar) != "" {
+		return keySourcePassphrase, nil
+	} else if osKeychain != nil {
+		return keySourceKeychain, nil
+	}
+	return 0, fmt.Errorf("no OS keychain is available to store the secrets key - set %s to encrypt secrets with a passphrase instead", SecretsPassphraseEnvVar)
+}
+
+// getSecretsKey returns the key from the given source. A key is generated
+// and added to the keychain if create is true and it holds none.
+func getSecretsKey(source keySource, salt []byte, create bool) ([]byte, error) {
+	switch source {
+	case keySourcePassphrase:
+		passphrase := os.Getenv(SecretsPassphraseEnvVar)
+		if passphrase == "" {
+			return nil, fmt.Errorf("secrets are encrypted with a passphrase - set %s", SecretsPassphraseEnvVar)
+		}
+		return pbkdf2Key([]byte(passphrase), salt, pbkdf2Iterations, 32), nil
+
+	case keySourceKeychain:
+		if osKeychain == nil {
+			return nil, fmt.Errorf("secrets are encrypted with a key in the OS keychain, which is not available")
+		}
+		key, found, err := osKeychain.get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets key from OS keychain: %s - set %s to use a passphrase instead", err, SecretsPassphraseEnvVar)
+		} else if found {
+			return key, nil
+		} else if !create {
+			return nil, fmt.Errorf("secrets key not found in OS keychain")
+		}
+		key = make([]byte, 32)
+		if _, err = io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("failed to generate secrets key: %s", err)
+		}
+		if err = osKeychain.set(key); err != nil {
+			return nil, fmt.Errorf("failed to add secrets key to OS keychain: %s - set %s to use a passphrase instead", err, SecretsPassphraseEnvVar)
+		}
+		logger.Tracef("added secrets key to OS keychain")
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown secrets key source: %c", source)
+}
+
+// pbkdf2Key derives a key from the password, using PBKDF2 with
+// HMAC-SHA256, as defined by RFC 8018.
+func pbkdf2Key(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		_ = binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := bytes.Clone(u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// macKeychain stores the key in the login keychain, using the 'security'
+// tool.
+type macKeychain struct{}
+
+func (macKeychain) get() ([]byte, bool, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(output)))
+	return key, err == nil, err
+}
+
+// set passes the key to 'security' on stdin, rather than as an argument,
+// where it would be visible to other processes. With '-w' as the last
+// argument, 'security' prompts for the password, then asks for it again
+// to confirm it.
+func (macKeychain) set(key []byte) error {
+	encoded := hex.EncodeToString(key)
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", keychainAccount, "-w")
+	cmd.Stdin = strings.NewReader(encoded + "\n" + encoded + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// secretServiceKeychain stores the key using the Secret Service API, such
+// as GNOME Keyring or KWallet, using the 'secret-tool' tool.
+type secretServiceKeychain struct{}
+
+func (secretServiceKeychain) get() ([]byte, bool, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 && len(output) == 0 {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(output)))
+	return key, err == nil, err
+}
+
+func (secretServiceKeychain) set(key []byte) error {
+	cmd := exec.Command("secret-tool", "store", "--label=Imposter CLI remote secrets key", "service", keychainService, "account", keychainAccount)
+	cmd.Stdin = strings.NewReader(hex.EncodeToString(key))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
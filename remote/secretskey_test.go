@@ -0,0 +1,48 @@
+package remote
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_macKeychain_set(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake 'security' tool is a shell script")
+	}
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	stdinFile := filepath.Join(binDir, "stdin")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat > " + stdinFile + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "security"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	if err := (macKeychain{}).set(key); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := hex.EncodeToString(key)
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(args), encoded) {
+		t.Errorf("key passed as an argument: %s", args)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(args)), " -w") {
+		t.Errorf("expected -w to be the last argument: %s", args)
+	}
+	stdin, err := os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := encoded + "\n" + encoded + "\n"; string(stdin) != want {
+		t.Errorf("stdin = %q, want the key and its confirmation", stdin)
+	}
+}
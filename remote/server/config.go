@@ -0,0 +1,144 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"gatehill.io/imposter/prefs"
+	"gatehill.io/imposter/remote"
+	"gatehill.io/imposter/remote/client"
+	"gatehill.io/imposter/workspace"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const remoteType = "server"
+const defaultPort = "8080"
+
+const configKeyPort = "port"
+const configKeyToken = "token"
+const configKeyUrl = "url"
+
+// ServerRemote deploys workspaces to a self-hosted Imposter server,
+// which is a daemon started with 'imposter daemon'.
+type ServerRemote struct {
+	remote.RemoteMetadata
+}
+
+var configKeys = []string{
+	configKeyPort,
+	configKeyToken,
+	configKeyUrl,
+}
+
+var logger = logging.GetLogger()
+
+func Register() {
+	remote.Register(remoteType, func(dir string, workspace *workspace.Workspace) (remote.Remote, error) {
+		return Load(dir, workspace)
+	})
+}
+
+func Load(dir string, w *workspace.Workspace) (ServerRemote, error) {
+	c, err := remote.LoadConfig(dir, w, func() *map[string]string {
+		return &map[string]string{
+			configKeyPort: defaultPort,
+		}
+	})
+	if err != nil {
+		return ServerRemote{}, err
+	}
+
+	r := ServerRemote{
+		remote.RemoteMetadata{
+			Workspace: w,
+			Dir:       dir,
+			Config:    *c,
+		},
+	}
+	return r, nil
+}
+
+func (ServerRemote) GetType() string {
+	return remoteType
+}
+
+func (ServerRemote) GetConfigKeys() []string {
+	return configKeys
+}
+
+func (m ServerRemote) SetConfigValue(key string, value string) error {
+	if err := m.CheckConfigKey(m.GetConfigKeys(), key); err != nil {
+		return err
+	}
+
+	switch key {
+	case configKeyUrl:
+		value = strings.TrimSuffix(value, "/")
+		if u, err := url.Parse(value); err != nil || u.Host == "" {
+			return fmt.Errorf("failed to parse URL: %s", value)
+		}
+		break
+
+	case configKeyPort:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid port: %s", value)
+		}
+		break
+
+	case configKeyToken:
+		// do not persist token to config
+		return getCredsPrefs().WriteProperty(m.Config[configKeyUrl], value)
+	}
+	m.Config[key] = value
+	return m.SaveConfig()
+}
+
+func (m ServerRemote) GetConfig() (*map[string]string, error) {
+	cfg := *remote.CloneMap(&m.Config)
+	token, err := m.getCleartextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		cfg[configKeyToken] = strings.Repeat("*", 8) + token[len(token)-min(4, len(token)):]
+	}
+	return &cfg, nil
+}
+
+func (m ServerRemote) getCleartextToken() (string, error) {
+	return getCredsPrefs().ReadPropertyString(m.Config[configKeyUrl])
+}
+
+func (m ServerRemote) getClient() (*client.Client, error) {
+	if m.Config[configKeyUrl] == "" {
+		return nil, fmt.Errorf("URL cannot be null")
+	}
+	token, err := m.getCleartextToken()
+	if err != nil {
+		return nil, err
+	} else if token == "" {
+		return nil, fmt.Errorf("auth token cannot be null")
+	}
+	return client.New(m.Config[configKeyUrl], token), nil
+}
+
+func getCredsPrefs() prefs.Prefs {
+	return prefs.Load("credentials.json")
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/remote"
+	"gatehill.io/imposter/remote/client"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const deployTimeout = 120 * time.Second
+
+// environmentHeader carries an environment variable for the mock, as a
+// base64 encoded NAME=value pair, in the deploy request.
+const environmentHeader = "Imposter-Environment"
+
+type workspaceStatus struct {
+	Name       string `json:"name"`
+	DeployedAt int64  `json:"deployedAt"`
+	Mock       struct {
+		State string `json:"state"`
+	} `json:"mock"`
+}
+
+func (m ServerRemote) Deploy() error {
+	c, err := m.getClient()
+	if err != nil {
+		return err
+	}
+	files, err := fileutil.ListFiles(m.Dir, false)
+	if err != nil {
+		return err
+	}
+	bundle, err := fileutil.ZipFiles(m.Dir, files)
+	if err != nil {
+		return fmt.Errorf("failed to bundle workspace: %s", err)
+	}
+
+	secrets, err := remote.LoadSecrets(m.Dir, m.Workspace)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %s", err)
+	}
+	headers := http.Header{}
+	for _, env := range remote.BuildSecretsEnv(secrets) {
+		headers.Add(environmentHeader, base64.StdEncoding.EncodeToString([]byte(env)))
+	}
+
+	logger.Infof("uploading %d files from workspace", len(files))
+	path := fmt.Sprintf("%s?port=%s", m.getWorkspacePath(), url.QueryEscape(m.getPort()))
+	if err = c.UploadWithHeaders("PUT", path, "application/zip", bundle, headers, nil); err != nil {
+		return fmt.Errorf("failed to upload workspace: %s", err)
+	}
+
+	logger.Infof("waiting for mock to start...")
+	return client.Poll(time.Second, deployTimeout, func() (bool, error) {
+		status, err := m.getStatus(c)
+		if err != nil {
+			return false, err
+		}
+		logger.Tracef("mock state: %s", status.Mock.State)
+		switch status.Mock.State {
+		case "running":
+			return true, nil
+		case "failed", "stopped":
+			return false, fmt.Errorf("mock failed to start - check the server logs")
+		}
+		return false, nil
+	})
+}
+
+func (m ServerRemote) Undeploy() error {
+	c, err := m.getClient()
+	if err != nil {
+		return err
+	}
+	if err = c.Request("DELETE", m.getWorkspacePath(), nil); err != nil {
+		return fmt.Errorf("failed to undeploy workspace: %s", err)
+	}
+	return nil
+}
+
+func (m ServerRemote) GetStatus() (*remote.Status, error) {
+	c, err := m.getClient()
+	if err != nil {
+		return nil, err
+	}
+	s, err := m.getStatus(c)
+	if err != nil {
+		return nil, err
+	}
+	state := strings.ToUpper(s.Mock.State)
+	if state == "RUNNING" {
+		state = "ACTIVE"
+	}
+	status := remote.Status{
+		Status:       state,
+		LastModified: s.DeployedAt,
+	}
+	return &status, nil
+}
+
+// GetEndpoint returns the URLs of the mock, which is served on the
+// configured port of the host running the server.
+func (m ServerRemote) GetEndpoint() (*remote.EndpointDetails, error) {
+	serverUrl, err := url.Parse(m.Config[configKeyUrl])
+	if err != nil || serverUrl.Host == "" {
+		return nil, fmt.Errorf("failed to parse URL: %s", m.Config[configKeyUrl])
+	}
+	baseUrl := fmt.Sprintf("http://%s", net.JoinHostPort(serverUrl.Hostname(), m.getPort()))
+	details := &remote.EndpointDetails{
+		BaseUrl:   baseUrl,
+		SpecUrl:   remote.MustJoinPath(baseUrl, "/_spec/"),
+		StatusUrl: remote.MustJoinPath(baseUrl, "/system/status"),
+	}
+	return details, nil
+}
+
+func (m ServerRemote) getStatus(c *client.Client) (*workspaceStatus, error) {
+	var resp workspaceStatus
+	if err := c.Request("GET", m.getWorkspacePath(), &resp); err != nil {
+		return nil, fmt.Errorf("error getting status: %s", err)
+	}
+	return &resp, nil
+}
+
+func (m ServerRemote) getPort() string {
+	if port := m.Config[configKeyPort]; port != "" {
+		return port
+	}
+	return defaultPort
+}
+
+func (m ServerRemote) getWorkspacePath() string {
+	return "/v1/workspaces/" + url.PathEscape(m.Workspace.Name)
+}
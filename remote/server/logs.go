@@ -0,0 +1,46 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"gatehill.io/imposter/remote"
+	"io"
+	"net/url"
+)
+
+func (m ServerRemote) StreamLogs(w io.Writer, options remote.LogOptions) error {
+	c, err := m.getClient()
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if options.Follow {
+		query.Set("follow", "true")
+	}
+	if options.Since > 0 {
+		query.Set("since", options.Since.String())
+	}
+	path := m.getWorkspacePath() + "/logs"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	if err = c.Stream("GET", path, w); err != nil {
+		return fmt.Errorf("failed to get logs: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sdk starts Imposter mocks from Go programs, such as test
+// suites, using the same engines as the CLI.
+//
+//	mock, err := sdk.StartMock(ctx, sdk.Options{ConfigDir: "testdata/mock"})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer mock.Stop()
+//	resp, err := http.Get(mock.BaseUrl + "/pets")
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/engine/docker"
+	"gatehill.io/imposter/engine/jvm"
+	"gatehill.io/imposter/stringutil"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLogLevel is the log level of the engine, if none is set.
+const DefaultLogLevel = "INFO"
+
+// Options configure a mock started by StartMock.
+type Options struct {
+	// ConfigDir is the directory containing the Imposter configuration
+	// of the mock. It is required.
+	ConfigDir string
+
+	// EngineType is the type of engine running the mock, such as
+	// 'docker' or 'jvm'. Empty means the configured type is used,
+	// or 'docker' if none is configured.
+	EngineType string
+
+	// Version is the engine version, such as '4.2.0', or a pin such
+	// as '4.x'. Empty means the latest version.
+	Version string
+
+	// Port is the port on which the mock listens. Zero selects a
+	// free port.
+	Port int
+
+	// Pull fetches the engine, even if it is already present.
+	Pull bool
+
+	// Environment holds environment variables set for the engine.
+	Environment map[string]string
+
+	// LogLevel is the log level of the engine. Empty means DefaultLogLevel.
+	LogLevel string
+
+	// LogWriter receives the output of the engine, in addition to
+	// the console, if set.
+	LogWriter io.Writer
+
+	// ReadyTimeout is the maximum time to wait for the mock to become
+	// ready. Zero means the configured start timeout is used.
+	ReadyTimeout time.Duration
+
+	// Tls configures HTTPS for the mock, such as the options returned by
+	// engine.BuildTlsOptions. If enabled, BaseUrl uses the https scheme.
+	Tls engine.TlsOptions
+}
+
+// Mock is a running mock.
+type Mock struct {
+	// BaseUrl is the URL at which the mock is served, such as
+	// 'http://localhost:8080', or 'https://localhost:8443' if TLS
+	// is enabled.
+	BaseUrl string
+
+	Port int
+
+	mockEngine engine.EmbeddableEngine
+	wg         *sync.WaitGroup
+	stopOnce   sync.Once
+	stopErr    error
+}
+
+var enableEngines sync.Once
+
+// StartMock starts a mock with the options, returning once it is ready
+// to serve requests. The context bounds startup only - if it is done
+// before the mock is ready, the mock is stopped and the context's error
+// is returned. The caller must stop the mock with Mock.Stop.
+func StartMock(ctx context.Context, options Options) (*Mock, error) {
+	enableEngines.Do(func() {
+		docker.EnableEngine()
+		jvm.EnableSingleJarEngine()
+		jvm.EnableUnpackedDistroEngine()
+	})
+
+	configDir, err := validateConfigDir(options.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	engineType := engine.GetConfiguredType(options.EngineType)
+	if err := validateEngineType(engineType); err != nil {
+		return nil, err
+	}
+	lib := engine.GetLibrary(engineType)
+	if ok, msgs := lib.CheckPrereqs(); !ok {
+		return nil, fmt.Errorf("prerequisites for %s engine not met:\n%s", engineType, strings.Join(msgs, "\n"))
+	}
+
+	var version string
+	if !lib.IsSealedDistro() {
+		version, err = resolveVersion(options.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pullPolicy := engine.PullIfNotPresent
+	if options.Pull {
+		pullPolicy = engine.PullAlways
+	}
+	provider := lib.GetProvider(version)
+	if err := provider.Provide(pullPolicy); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s engine version %s: %v", engineType, version, err)
+	}
+
+	port := options.Port
+	if port == 0 {
+		if port, err = engine.FindFreePort(); err != nil {
+			return nil, err
+		}
+	}
+	startOptions := engine.StartOptions{
+		Port:            port,
+		Version:         version,
+		PullPolicy:      engine.PullSkip,
+		LogLevel:        stringutil.GetFirstNonEmpty(options.LogLevel, DefaultLogLevel),
+		EnablePlugins:   true,
+		EnableFileCache: true,
+		Environment:     buildEnvironment(options.Environment),
+		ReadyTimeout:    options.ReadyTimeout,
+		Embedded:        true,
+		LogWriter:       options.LogWriter,
+		Tls:             options.Tls,
+	}
+	mockEngine, ok := provider.Build(configDir, startOptions).(engine.EmbeddableEngine)
+	if !ok {
+		return nil, fmt.Errorf("engine type %s cannot be embedded", engineType)
+	}
+	mock := &Mock{
+		BaseUrl:    buildBaseUrl(port, options.Tls),
+		Port:       port,
+		mockEngine: mockEngine,
+		wg:         &sync.WaitGroup{},
+	}
+
+	if err := mockEngine.StartEmbedded(ctx, mock.wg); err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("mock failed to start from: %s: %v", configDir, err)
+		}
+		if stopErr := mock.Stop(); stopErr != nil {
+			return nil, errors.Join(err, stopErr)
+		}
+		return nil, err
+	}
+	return mock, nil
+}
+
+// Stop stops the mock, waiting until it has stopped. It is safe to
+// call more than once, in which case the error, if any, of the first
+// call is returned.
+func (m *Mock) Stop() error {
+	m.stopOnce.Do(func() {
+		if m.stopErr = m.mockEngine.StopEmbedded(m.wg); m.stopErr == nil {
+			m.wg.Wait()
+		}
+	})
+	return m.stopErr
+}
+
+// buildBaseUrl returns the URL of the mock on localhost, using the https
+// scheme if TLS is enabled.
+func buildBaseUrl(port int, tls engine.TlsOptions) string {
+	scheme := "http"
+	if tls.Enabled {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, port)
+}
+
+func validateConfigDir(configDir string) (string, error) {
+	if configDir == "" {
+		return "", fmt.Errorf("config dir must be set")
+	}
+	abs, err := filepath.Abs(configDir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("config dir does not exist: %s", configDir)
+	}
+	return abs, nil
+}
+
+// validateEngineType returns an error if the engine type cannot run a
+// local mock, such as that of a remote.
+func validateEngineType(engineType engine.EngineType) error {
+	if err := engine.ValidateEngineType(engineType); err != nil {
+		return err
+	}
+	if engineType == engine.EngineTypeAwsLambda {
+		return fmt.Errorf("engine type %s cannot be started locally", engineType)
+	}
+	return nil
+}
+
+// resolveVersion returns the engine version for the version or pin,
+// resolving 'latest' and pins to a version.
+func resolveVersion(version string) (string, error) {
+	version = stringutil.GetFirstNonEmpty(version, "latest")
+	if version == "latest" {
+		return engine.ResolveLatestToVersion(true)
+	}
+	if engine.IsFuzzyVersion(version) {
+		return engine.ResolveFuzzyVersion(version, true)
+	}
+	return version, nil
+}
+
+// buildEnvironment returns the environment variables in the form
+// NAME=VALUE, sorted by name.
+func buildEnvironment(env map[string]string) []string {
+	var vars []string
+	for name, value := range env {
+		vars = append(vars, name+"="+value)
+	}
+	sort.Strings(vars)
+	return vars
+}
@@ -0,0 +1,212 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"gatehill.io/imposter/engine"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStartMock_invalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{name: "no config dir", options: Options{}},
+		{name: "missing config dir", options: Options{ConfigDir: "testdata/missing"}},
+		{name: "unsupported engine type", options: Options{ConfigDir: t.TempDir(), EngineType: "foo"}},
+		{name: "remote engine type", options: Options{ConfigDir: t.TempDir(), EngineType: string(engine.EngineTypeAwsLambda)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := StartMock(context.Background(), tt.options)
+			if err == nil {
+				_ = mock.Stop()
+				t.Fatalf("StartMock() expected error")
+			}
+		})
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	got, err := resolveVersion("4.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "4.2.0" {
+		t.Errorf("resolveVersion() = %v, want 4.2.0", got)
+	}
+}
+
+func Test_buildEnvironment(t *testing.T) {
+	got := buildEnvironment(map[string]string{"IMPOSTER_LOG_LEVEL": "DEBUG", "FOO": "bar"})
+	want := []string{"FOO=bar", "IMPOSTER_LOG_LEVEL=DEBUG"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildEnvironment() = %v, want %v", got, want)
+	}
+	if got := buildEnvironment(nil); len(got) != 0 {
+		t.Errorf("buildEnvironment(nil) = %v, want empty", got)
+	}
+}
+
+func TestStartMock_fakeEngine(t *testing.T) {
+	tests := []struct {
+		name        string
+		fake        *fakeEngine
+		options     Options
+		wantScheme  string
+		wantErr     string
+		wantStopErr string
+	}{
+		{
+			name:       "start and stop",
+			fake:       &fakeEngine{},
+			wantScheme: "http://",
+		},
+		{
+			name:       "tls",
+			fake:       &fakeEngine{},
+			options:    Options{Tls: engine.TlsOptions{Enabled: true}},
+			wantScheme: "https://",
+		},
+		{
+			name:    "start fails",
+			fake:    &fakeEngine{startErr: errors.New("container exited")},
+			wantErr: "container exited",
+		},
+		{
+			name:        "stop fails",
+			fake:        &fakeEngine{stopErr: errors.New("daemon unavailable")},
+			wantScheme:  "http://",
+			wantStopErr: "daemon unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeEngine(tt.fake)
+			tt.options.ConfigDir = t.TempDir()
+			tt.options.EngineType = string(engine.EngineTypeJvmUnpacked)
+
+			mock, err := StartMock(context.Background(), tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("StartMock() error = %v, want %v", err, tt.wantErr)
+				}
+				if !tt.fake.stopped {
+					t.Errorf("expected engine to be stopped after failing to start")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("StartMock() error = %v", err)
+			}
+
+			if !tt.fake.options.Embedded || tt.fake.options.Tls != tt.options.Tls {
+				t.Errorf("unexpected start options: %+v", tt.fake.options)
+			}
+			if !strings.HasPrefix(mock.BaseUrl, tt.wantScheme+"localhost:") || mock.Port == 0 {
+				t.Errorf("StartMock() BaseUrl = %v, Port = %v, want scheme %v", mock.BaseUrl, mock.Port, tt.wantScheme)
+			}
+			for i := 0; i < 2; i++ {
+				err := mock.Stop()
+				if tt.wantStopErr == "" && err != nil {
+					t.Errorf("Stop() error = %v", err)
+				} else if tt.wantStopErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantStopErr)) {
+					t.Errorf("Stop() error = %v, want %v", err, tt.wantStopErr)
+				}
+			}
+			if tt.fake.stopCalls != 1 {
+				t.Errorf("expected engine to be stopped once, got: %d", tt.fake.stopCalls)
+			}
+		})
+	}
+}
+
+func TestStartMock_contextDone(t *testing.T) {
+	fake := &fakeEngine{waitForContext: true}
+	useFakeEngine(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := StartMock(ctx, Options{ConfigDir: t.TempDir(), EngineType: string(engine.EngineTypeJvmUnpacked)})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("StartMock() error = %v, want %v", err, context.Canceled)
+	}
+	if !fake.stopped {
+		t.Errorf("expected engine to be stopped")
+	}
+}
+
+// useFakeEngine registers a library for the unpacked engine type, which
+// builds the fake engine.
+func useFakeEngine(fake *fakeEngine) {
+	enableEngines.Do(func() {})
+	engine.RegisterLibrary(engine.EngineTypeJvmUnpacked, func() engine.EngineLibrary {
+		return fakeLibrary{fake: fake}
+	})
+}
+
+type fakeLibrary struct {
+	fake *fakeEngine
+}
+
+func (fakeLibrary) CheckPrereqs() (bool, []string)         { return true, nil }
+func (fakeLibrary) List() ([]engine.EngineMetadata, error) { return nil, nil }
+func (l fakeLibrary) GetProvider(string) engine.Provider   { return fakeProvider(l) }
+func (fakeLibrary) IsSealedDistro() bool                   { return true }
+func (fakeLibrary) ShouldEnsurePlugins() bool              { return false }
+func (fakeLibrary) Remove(string) (int64, error)           { return 0, nil }
+
+type fakeProvider struct {
+	fake *fakeEngine
+}
+
+func (fakeProvider) Satisfied() bool                  { return true }
+func (fakeProvider) Provide(engine.PullPolicy) error  { return nil }
+func (fakeProvider) GetEngineType() engine.EngineType { return engine.EngineTypeJvmUnpacked }
+func (fakeProvider) Bundle(string, string) error      { return nil }
+
+func (p fakeProvider) Build(_ string, startOptions engine.StartOptions) engine.MockEngine {
+	p.fake.options = startOptions
+	return p.fake
+}
+
+// fakeEngine implements the embeddable start and stop of an engine. The
+// other methods of engine.MockEngine are not implemented.
+type fakeEngine struct {
+	engine.MockEngine
+	options        engine.StartOptions
+	startErr       error
+	stopErr        error
+	waitForContext bool
+	running        bool
+	stopped        bool
+	stopCalls      int
+}
+
+func (f *fakeEngine) StartEmbedded(ctx context.Context, wg *sync.WaitGroup) error {
+	if f.waitForContext {
+		<-ctx.Done()
+		return ctx.Err()
+	} else if f.startErr != nil {
+		return f.startErr
+	}
+	wg.Add(1)
+	f.running = true
+	return nil
+}
+
+func (f *fakeEngine) StopEmbedded(wg *sync.WaitGroup) error {
+	f.stopCalls++
+	if f.stopErr != nil {
+		return f.stopErr
+	}
+	if f.running {
+		f.running = false
+		wg.Done()
+	}
+	f.stopped = true
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/prefs"
+	"github.com/coreos/go-semver/semver"
+	"strings"
+)
+
+const notifyThresholdSeconds = 86_400
+
+// CheckForNewVersions returns a one-line hint if a newer release of the
+// CLI, or of the engine version pinned in the configuration, is available.
+// Releases are checked at most once a day; otherwise the empty string is
+// returned, so the hint is only shown once a day. The result of the check
+// is cached in the prefs store, in the CLI configuration directory.
+func CheckForNewVersions(now int64, cliVersion string, engineVersion string) string {
+	p := getNotifyPrefs()
+	lastCheck, _ := p.ReadPropertyInt("last_check")
+	if now-int64(lastCheck) < notifyThresholdSeconds {
+		logger.Tracef("skipping new version check - last checked at %d", lastCheck)
+		return ""
+	}
+
+	var latestCli string
+	if release, err := FindLatest(ChannelStable); err != nil {
+		logger.Debugf("failed to check for new CLI version: %v", err)
+	} else {
+		latestCli = release.Version
+	}
+	latestEngine, err := engine.ResolveLatestToVersion(true)
+	if err != nil {
+		logger.Debugf("failed to check for new engine version: %v", err)
+	}
+
+	for key, value := range map[string]interface{}{"last_check": now, "latest_cli": latestCli, "latest_engine": latestEngine} {
+		if err := p.WriteProperty(key, value); err != nil {
+			logger.Debugf("failed to record new version check: %v", err)
+		}
+	}
+	return buildHint(cliVersion, latestCli, engineVersion, latestEngine)
+}
+
+// buildHint describes the releases that are newer than the current
+// versions, or returns the empty string if there are none. Versions
+// that are not semantic versions, such as 'dev' or 'latest', are
+// never reported as outdated.
+func buildHint(cliVersion string, latestCli string, engineVersion string, latestEngine string) string {
+	var updates []string
+	if isOutdated(cliVersion, latestCli) {
+		updates = append(updates, fmt.Sprintf("CLI %s (current: %s) - run 'imposter self-update'", latestCli, cliVersion))
+	}
+	if isOutdated(engineVersion, latestEngine) {
+		updates = append(updates, fmt.Sprintf("engine %s (configured: %s)", latestEngine, engineVersion))
+	}
+	if len(updates) == 0 {
+		return ""
+	}
+	return "new version available: " + strings.Join(updates, "; ")
+}
+
+func isOutdated(current string, latest string) bool {
+	currentVersion, err := semver.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return false
+	}
+	latestVersion, err := semver.NewVersion(latest)
+	if err != nil {
+		return false
+	}
+	return currentVersion.LessThan(*latestVersion)
+}
+
+func getNotifyPrefs() prefs.Prefs {
+	return prefs.Load("update_check.json")
+}
@@ -0,0 +1,51 @@
+package selfupdate
+
+import "testing"
+
+func Test_buildHint(t *testing.T) {
+	tests := []struct {
+		name          string
+		cliVersion    string
+		latestCli     string
+		engineVersion string
+		latestEngine  string
+		want          string
+	}{
+		{
+			name:       "up to date",
+			cliVersion: "1.2.0", latestCli: "1.2.0",
+			engineVersion: "4.2.1", latestEngine: "4.2.1",
+			want: "",
+		},
+		{
+			name:       "new CLI",
+			cliVersion: "1.2.0", latestCli: "1.3.0",
+			engineVersion: "latest", latestEngine: "4.2.1",
+			want: "new version available: CLI 1.3.0 (current: 1.2.0) - run 'imposter self-update'",
+		},
+		{
+			name:       "new CLI and engine",
+			cliVersion: "1.2.0", latestCli: "1.3.0",
+			engineVersion: "4.0.0", latestEngine: "4.2.1",
+			want: "new version available: CLI 1.3.0 (current: 1.2.0) - run 'imposter self-update'; engine 4.2.1 (configured: 4.0.0)",
+		},
+		{
+			name:       "dev CLI",
+			cliVersion: "dev", latestCli: "1.3.0",
+			want: "",
+		},
+		{
+			name:       "check failed",
+			cliVersion: "1.2.0", latestCli: "",
+			engineVersion: "4.0.0", latestEngine: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildHint(tt.cliVersion, tt.latestCli, tt.engineVersion, tt.latestEngine); got != tt.want {
+				t.Errorf("buildHint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
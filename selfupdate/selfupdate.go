@@ -0,0 +1,371 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"github.com/coreos/go-semver/semver"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const checksumsFileName = "checksums.txt"
+
+var logger = logging.GetLogger()
+
+// releasesApi lists the releases of the CLI. It is a variable so that
+// it can be replaced in tests.
+var releasesApi = "https://api.github.com/repos/gatehill/imposter-cli/releases?per_page=100"
+
+// Channel selects the releases considered for an update.
+type Channel string
+
+const (
+	// ChannelStable only considers full releases.
+	ChannelStable Channel = "stable"
+
+	// ChannelBeta also considers prereleases.
+	ChannelBeta Channel = "beta"
+)
+
+// ParseChannel validates the release channel. An empty channel means
+// the stable channel.
+func ParseChannel(channel string) (Channel, error) {
+	switch Channel(channel) {
+	case "", ChannelStable:
+		return ChannelStable, nil
+	case ChannelBeta:
+		return ChannelBeta, nil
+	default:
+		return "", fmt.Errorf("invalid channel: %s - valid values are: %s, %s", channel, ChannelStable, ChannelBeta)
+	}
+}
+
+// Release is a published version of the CLI, and the download URLs of
+// its assets, keyed by file name.
+type Release struct {
+	Version    string
+	Prerelease bool
+	Assets     map[string]string
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadUrl string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// FindLatest returns the highest release of the CLI in the channel.
+func FindLatest(channel Channel) (*Release, error) {
+	releases, err := fetchReleases()
+	if err != nil {
+		return nil, err
+	}
+	var latest *Release
+	var latestVersion *semver.Version
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && channel != ChannelBeta) {
+			continue
+		}
+		version, err := semver.NewVersion(strings.TrimPrefix(r.TagName, "v"))
+		if err != nil {
+			continue
+		}
+		if latestVersion != nil && !latestVersion.LessThan(*version) {
+			continue
+		}
+		assets := make(map[string]string)
+		for _, asset := range r.Assets {
+			assets[asset.Name] = asset.BrowserDownloadUrl
+		}
+		latestVersion = version
+		latest = &Release{Version: version.String(), Prerelease: r.Prerelease, Assets: assets}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no %s release found at %s", channel, releasesApi)
+	}
+	return latest, nil
+}
+
+func fetchReleases() ([]githubRelease, error) {
+	logger.Tracef("fetching releases from: %s", releasesApi)
+	resp, err := http.Get(releasesApi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases from %s: %s", releasesApi, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to list releases from %s - status code: %d", releasesApi, resp.StatusCode)
+	}
+	var releases []githubRelease
+	if err = json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to list releases from %s - cannot unmarshall response body: %s", releasesApi, err)
+	}
+	return releases, nil
+}
+
+// IsNewer returns true if the release is newer than the current version.
+func IsNewer(current string, release *Release) (bool, error) {
+	currentVersion, err := semver.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return false, fmt.Errorf("cannot compare current version %s: %v", current, err)
+	}
+	return currentVersion.LessThan(*semver.New(release.Version)), nil
+}
+
+// AssetName returns the file name of the release archive for the
+// platform, in the layout of the release workflow and install script.
+// 32-bit arm binaries are built for ARMv6, and named accordingly.
+func AssetName(version string, goos string, goarch string) string {
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+	if goarch == "arm" {
+		goarch = "armv6"
+	}
+	return fmt.Sprintf("imposter_%s_%s_%s.%s", version, goos, goarch, ext)
+}
+
+// Apply downloads the release archive for the current platform, verifies
+// it against the published checksums, and replaces the executable at
+// exePath with the binary it contains.
+func Apply(release *Release, exePath string) error {
+	assetName := AssetName(release.Version, runtime.GOOS, runtime.GOARCH)
+	assetUrl, found := release.Assets[assetName]
+	if !found {
+		return fmt.Errorf("release %s has no binary for %s/%s (expected %s)", release.Version, runtime.GOOS, runtime.GOARCH, assetName)
+	}
+	checksumsUrl, found := release.Assets[checksumsFileName]
+	if !found {
+		return fmt.Errorf("release %s has no %s", release.Version, checksumsFileName)
+	}
+	expected, err := fetchChecksum(checksumsUrl, assetName)
+	if err != nil {
+		return err
+	}
+
+	exeDir := filepath.Dir(exePath)
+	archivePath, err := download(assetUrl, exeDir, expected)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	binaryName := "imposter"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	binaryPath, err := extractBinary(archivePath, binaryName, exeDir)
+	if err != nil {
+		return err
+	}
+	if err = replaceExecutable(exePath, binaryPath); err != nil {
+		_ = os.Remove(binaryPath)
+		return err
+	}
+	return nil
+}
+
+// fetchChecksum returns the SHA-256 checksum of the file from the
+// checksums file at the URL, in the format written by sha256sum.
+func fetchChecksum(url string, fileName string) (string, error) {
+	logger.Debugf("downloading %v", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error downloading checksums from: %v: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("error downloading checksums from: %v: status code: %d", url, resp.StatusCode)
+	}
+	return parseChecksum(resp.Body, fileName)
+}
+
+func parseChecksum(checksums io.Reader, fileName string) (string, error) {
+	scanner := bufio.NewScanner(checksums)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != fileName {
+			continue
+		}
+		checksum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return "", fmt.Errorf("invalid checksum for: %s: %s", fileName, fields[0])
+		}
+		return checksum, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading checksums: %v", err)
+	}
+	return "", fmt.Errorf("no checksum found for: %s", fileName)
+}
+
+// download writes the file at the URL to a temporary file in destDir,
+// returning its path if its SHA-256 checksum matches the expected value.
+func download(url string, destDir string, expected string) (string, error) {
+	logger.Debugf("downloading %v", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error downloading from: %v: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("error downloading from: %v: status code: %d", url, resp.StatusCode)
+	}
+
+	file, err := os.CreateTemp(destDir, ".imposter-download-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file in: %v: %v", destDir, err)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), resp.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("error downloading from: %v: %v", url, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("checksum mismatch for: %v: expected %s but was %s", url, expected, actual)
+	}
+	logger.Debugf("verified checksum of: %v", url)
+	return file.Name(), nil
+}
+
+// extractBinary writes the named binary from the .tar.gz or .zip archive
+// to an executable temporary file in destDir, returning its path.
+func extractBinary(archivePath string, binaryName string, destDir string) (string, error) {
+	var src io.ReadCloser
+	if isZip(archivePath) {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return "", fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+		}
+		defer reader.Close()
+		for _, entry := range reader.File {
+			if path.Base(entry.Name) == binaryName && !entry.FileInfo().IsDir() {
+				if src, err = entry.Open(); err != nil {
+					return "", fmt.Errorf("error reading archive entry: %s: %v", entry.Name, err)
+				}
+				break
+			}
+		}
+	} else {
+		file, err := os.Open(archivePath)
+		if err != nil {
+			return "", fmt.Errorf("error opening archive: %s: %v", archivePath, err)
+		}
+		defer file.Close()
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return "", fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+		}
+		defer gz.Close()
+		reader := tar.NewReader(gz)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return "", fmt.Errorf("error reading archive: %s: %v", archivePath, err)
+			}
+			if header.Typeflag == tar.TypeReg && path.Base(header.Name) == binaryName {
+				src = io.NopCloser(reader)
+				break
+			}
+		}
+	}
+	if src == nil {
+		return "", fmt.Errorf("archive does not contain: %s", binaryName)
+	}
+	defer src.Close()
+
+	dest, err := os.CreateTemp(destDir, ".imposter-update-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file in: %v: %v", destDir, err)
+	}
+	_, err = io.Copy(dest, src)
+	closeErr := dest.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(dest.Name(), 0755)
+	}
+	if err != nil {
+		_ = os.Remove(dest.Name())
+		return "", fmt.Errorf("error writing file: %s: %v", dest.Name(), err)
+	}
+	return dest.Name(), nil
+}
+
+// isZip returns true if the file starts with the ZIP local file header.
+func isZip(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+	return string(magic) == "PK\x03\x04"
+}
+
+// replaceExecutable atomically renames the new binary over the executable.
+// Windows does not allow a running executable to be replaced, but does
+// allow it to be renamed, so the old binary is first moved aside.
+func replaceExecutable(exePath string, newPath string) error {
+	if runtime.GOOS == "windows" {
+		oldPath := exePath + ".old"
+		_ = os.Remove(oldPath)
+		if err := os.Rename(exePath, oldPath); err != nil {
+			return fmt.Errorf("error moving aside executable: %s: %v", exePath, err)
+		}
+		if err := os.Rename(newPath, exePath); err != nil {
+			_ = os.Rename(oldPath, exePath)
+			return fmt.Errorf("error replacing executable: %s: %v", exePath, err)
+		}
+		return nil
+	}
+	if err := os.Rename(newPath, exePath); err != nil {
+		return fmt.Errorf("error replacing executable: %s: %v", exePath, err)
+	}
+	return nil
+}
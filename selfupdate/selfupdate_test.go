@@ -0,0 +1,197 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// buildArchive returns a release archive for the current platform,
+// containing the binary with the given content.
+func buildArchive(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	if runtime.GOOS == "windows" {
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("imposter.exe")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{"README.md": "readme", "imposter": content} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(body))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func startReleaseServer(t *testing.T, archive []byte, checksum string) *httptest.Server {
+	assetName := AssetName("1.2.0-rc1", runtime.GOOS, runtime.GOARCH)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases":
+			fmt.Fprintf(w, `[
+  {"tag_name": "v1.3.0", "draft": true},
+  {"tag_name": "v1.2.0-rc1", "prerelease": true, "assets": [
+    {"name": "%[1]s", "browser_download_url": "%[2]s/asset"},
+    {"name": "checksums.txt", "browser_download_url": "%[2]s/checksums.txt"}
+  ]},
+  {"tag_name": "v1.1.0"},
+  {"tag_name": "not-a-version"},
+  {"tag_name": "v1.0.0"}
+]`, assetName, server.URL)
+		case "/asset":
+			_, _ = w.Write(archive)
+		case "/checksums.txt":
+			fmt.Fprintf(w, "%s  imposter_1.2.0-rc1_other_arch.tar.gz\n%s  %s\n", strings.Repeat("0", 64), checksum, assetName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	releasesApi = server.URL + "/releases"
+	return server
+}
+
+func TestFindLatest(t *testing.T) {
+	server := startReleaseServer(t, nil, "")
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		channel Channel
+		want    string
+	}{
+		{name: "stable", channel: ChannelStable, want: "1.1.0"},
+		{name: "beta", channel: ChannelBeta, want: "1.2.0-rc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := FindLatest(tt.channel)
+			if err != nil {
+				t.Fatalf("FindLatest() error = %v", err)
+			}
+			if release.Version != tt.want {
+				t.Errorf("FindLatest() version = %v, want %v", release.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		want    bool
+		wantErr bool
+	}{
+		{name: "older", current: "1.0.0", want: true},
+		{name: "same", current: "1.1.0", want: false},
+		{name: "newer", current: "1.2.0", want: false},
+		{name: "prerelease of same version", current: "1.1.0-rc1", want: true},
+		{name: "dev build", current: "dev", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsNewer(tt.current, &Release{Version: "1.1.0"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsNewer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsNewer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := []struct {
+		goos   string
+		goarch string
+		want   string
+	}{
+		{goos: "linux", goarch: "amd64", want: "imposter_1.2.0_linux_amd64.tar.gz"},
+		{goos: "linux", goarch: "arm64", want: "imposter_1.2.0_linux_arm64.tar.gz"},
+		{goos: "linux", goarch: "arm", want: "imposter_1.2.0_linux_armv6.tar.gz"},
+		{goos: "darwin", goarch: "arm64", want: "imposter_1.2.0_darwin_arm64.tar.gz"},
+		{goos: "windows", goarch: "amd64", want: "imposter_1.2.0_windows_amd64.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			if got := AssetName("1.2.0", tt.goos, tt.goarch); got != tt.want {
+				t.Errorf("AssetName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	archive := buildArchive(t, "new binary")
+	sum := sha256.Sum256(archive)
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     string
+		wantErr  bool
+	}{
+		{name: "checksum matches", checksum: hex.EncodeToString(sum[:]), want: "new binary"},
+		{name: "checksum mismatch", checksum: strings.Repeat("a", 64), want: "old binary", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startReleaseServer(t, archive, tt.checksum)
+			defer server.Close()
+
+			exeDir := t.TempDir()
+			exePath := filepath.Join(exeDir, "imposter")
+			if err := os.WriteFile(exePath, []byte("old binary"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			release, err := FindLatest(ChannelBeta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Apply(release, exePath); (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := os.ReadFile(exePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("executable = %q, want %q", got, tt.want)
+			}
+			if runtime.GOOS != "windows" {
+				if entries, _ := os.ReadDir(exeDir); len(entries) != 1 {
+					t.Errorf("expected only the executable to remain, found %d files", len(entries))
+				}
+			}
+		})
+	}
+}
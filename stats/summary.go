@@ -0,0 +1,234 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// maxLatencySamples bounds the latencies held by a Collector. Beyond
+// it, a random sample of latencies is kept, from which percentiles
+// are estimated.
+const maxLatencySamples = 100_000
+
+// maxSummaryPaths is the number of paths listed by Summary.Write.
+const maxSummaryPaths = 20
+
+// Summary describes the requests received during a session.
+type Summary struct {
+	Requests int
+
+	// Unmatched is the number of requests that matched no resource,
+	// or no route of the proxy.
+	Unmatched int
+
+	// Paths holds the number of requests for each method and path,
+	// keyed in the form 'GET /orders'.
+	Paths map[string]int
+
+	// Statuses holds the number of responses with each status code.
+	Statuses map[int]int
+
+	// P50 and P95 are latency percentiles, or -1 if unknown.
+	P50 time.Duration
+	P95 time.Duration
+}
+
+// Collector accumulates the requests of a session. It is safe
+// for concurrent use.
+type Collector struct {
+	mutex     sync.Mutex
+	requests  int
+	unmatched int
+	paths     map[string]int
+	statuses  map[int]int
+	latencies []time.Duration
+	observed  int
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		paths:    make(map[string]int),
+		statuses: make(map[int]int),
+	}
+}
+
+// Record adds a request that was handled, with the status of its
+// response and the time taken.
+func (c *Collector) Record(method string, path string, status int, latency time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.requests++
+	c.paths[method+" "+path]++
+	c.statuses[status]++
+
+	// reservoir sampling keeps a uniform sample once the limit is reached
+	c.observed++
+	if len(c.latencies) < maxLatencySamples {
+		c.latencies = append(c.latencies, latency)
+	} else if i := rand.Intn(c.observed); i < maxLatencySamples {
+		c.latencies[i] = latency
+	}
+}
+
+// RecordUnmatched adds a request that matched nothing, so received
+// the status without being handled.
+func (c *Collector) RecordUnmatched(method string, path string, status int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.requests++
+	c.unmatched++
+	c.paths[method+" "+path]++
+	c.statuses[status]++
+}
+
+// Summary returns the summary of the requests recorded so far.
+func (c *Collector) Summary() Summary {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	summary := Summary{
+		Requests:  c.requests,
+		Unmatched: c.unmatched,
+		Paths:     make(map[string]int, len(c.paths)),
+		Statuses:  make(map[int]int, len(c.statuses)),
+		P50:       -1,
+		P95:       -1,
+	}
+	for path, count := range c.paths {
+		summary.Paths[path] = count
+	}
+	for status, count := range c.statuses {
+		summary.Statuses[status] = count
+	}
+	if len(c.latencies) > 0 {
+		sorted := append([]time.Duration(nil), c.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		summary.P50 = Percentile(sorted, 50)
+		summary.P95 = Percentile(sorted, 95)
+	}
+	return summary
+}
+
+// Percentile returns the pth percentile of the sorted latencies,
+// using the nearest-rank method.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return -1
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// HistogramBucket is a cumulative bucket of a histogram, counting the
+// observations less than or equal to its upper bound.
+type HistogramBucket struct {
+	UpperBound float64
+	Count      float64
+}
+
+// HistogramQuantile estimates the qth quantile (0 <= q <= 1) of the
+// histogram, interpolating linearly within the bucket in which it falls,
+// as Prometheus does. It returns false if the histogram is empty.
+func HistogramQuantile(q float64, buckets []HistogramBucket) (float64, bool) {
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].UpperBound < buckets[j].UpperBound })
+	if len(buckets) == 0 || buckets[len(buckets)-1].Count == 0 {
+		return 0, false
+	}
+	total := buckets[len(buckets)-1].Count
+	rank := q * total
+	lowerBound, lowerCount := 0.0, 0.0
+	for _, bucket := range buckets {
+		if bucket.Count >= rank {
+			if math.IsInf(bucket.UpperBound, 1) {
+				// the quantile is beyond the highest finite bound
+				return lowerBound, true
+			}
+			if bucket.Count == lowerCount {
+				return bucket.UpperBound, true
+			}
+			return lowerBound + (bucket.UpperBound-lowerBound)*(rank-lowerCount)/(bucket.Count-lowerCount), true
+		}
+		lowerBound, lowerCount = bucket.UpperBound, bucket.Count
+	}
+	return lowerBound, true
+}
+
+// Write prints the summary, listing the busiest paths first, up to
+// maxSummaryPaths.
+func (s Summary) Write(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Session summary")
+	if s.Requests == 0 {
+		_, _ = fmt.Fprintln(w, "  No requests received")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "  Requests:     %d (%d unmatched)\n", s.Requests, s.Unmatched)
+	_, _ = fmt.Fprintf(w, "  Latency:      p50 %s, p95 %s\n", formatLatency(s.P50), formatLatency(s.P95))
+	_, _ = fmt.Fprintf(w, "  Status codes: %s\n\n", formatStatuses(s.Statuses))
+
+	type pathCount struct {
+		path  string
+		count int
+	}
+	var paths []pathCount
+	for path, count := range s.Paths {
+		paths = append(paths, pathCount{path, count})
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].count != paths[j].count {
+			return paths[i].count > paths[j].count
+		}
+		return paths[i].path < paths[j].path
+	})
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "  METHOD\tPATH\tREQUESTS")
+	for _, p := range paths[:min(len(paths), maxSummaryPaths)] {
+		method, path, _ := strings.Cut(p.path, " ")
+		_, _ = fmt.Fprintf(table, "  %s\t%s\t%d\n", method, path, p.count)
+	}
+	_ = table.Flush()
+	if len(paths) > maxSummaryPaths {
+		_, _ = fmt.Fprintf(w, "  ...and %d more path(s)\n", len(paths)-maxSummaryPaths)
+	}
+}
+
+func formatLatency(latency time.Duration) string {
+	if latency < 0 {
+		return "n/a"
+	}
+	return latency.Round(10 * time.Microsecond).String()
+}
+
+func formatStatuses(statuses map[int]int) string {
+	var codes []int
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var parts []string
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%d: %d", code, statuses[code]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector_Summary(t *testing.T) {
+	c := NewCollector()
+	for i := 1; i <= 20; i++ {
+		c.Record("GET", "/orders", 200, time.Duration(i)*time.Millisecond)
+	}
+	c.Record("POST", "/orders", 500, 100*time.Millisecond)
+	c.RecordUnmatched("GET", "/missing", 404)
+
+	got := c.Summary()
+	if got.Requests != 22 {
+		t.Errorf("Requests = %d, want 22", got.Requests)
+	}
+	if got.Unmatched != 1 {
+		t.Errorf("Unmatched = %d, want 1", got.Unmatched)
+	}
+	if got.Paths["GET /orders"] != 20 || got.Paths["POST /orders"] != 1 || got.Paths["GET /missing"] != 1 {
+		t.Errorf("Paths = %v", got.Paths)
+	}
+	if got.Statuses[200] != 20 || got.Statuses[500] != 1 || got.Statuses[404] != 1 {
+		t.Errorf("Statuses = %v", got.Statuses)
+	}
+	if got.P50 != 11*time.Millisecond {
+		t.Errorf("P50 = %v, want 11ms", got.P50)
+	}
+	if got.P95 != 20*time.Millisecond {
+		t.Errorf("P95 = %v, want 20ms", got.P95)
+	}
+}
+
+func TestCollector_Summary_noLatencies(t *testing.T) {
+	c := NewCollector()
+	c.RecordUnmatched("GET", "/missing", 404)
+	got := c.Summary()
+	if got.P50 != -1 || got.P95 != -1 {
+		t.Errorf("P50, P95 = %v, %v, want -1", got.P50, got.P95)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 25, want: 1},
+		{p: 50, want: 2},
+		{p: 95, want: 4},
+		{p: 100, want: 4},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != -1 {
+		t.Errorf("Percentile(nil) = %v, want -1", got)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	inf := []HistogramBucket{{UpperBound: 0.1, Count: 50}, {UpperBound: 0.2, Count: 90}, {UpperBound: 0.4, Count: 100}}
+	tests := []struct {
+		name    string
+		q       float64
+		buckets []HistogramBucket
+		want    float64
+		wantOk  bool
+	}{
+		{name: "empty", q: 0.5, wantOk: false},
+		{name: "no observations", q: 0.5, buckets: []HistogramBucket{{UpperBound: 1}}, wantOk: false},
+		{name: "first bucket", q: 0.5, buckets: inf, want: 0.1, wantOk: true},
+		{name: "interpolated", q: 0.95, buckets: inf, want: 0.3, wantOk: true},
+		{
+			name:    "beyond highest bound",
+			q:       0.95,
+			buckets: []HistogramBucket{{UpperBound: 0.1, Count: 50}, {UpperBound: math.Inf(1), Count: 100}},
+			want:    0.1,
+			wantOk:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := HistogramQuantile(tt.q, tt.buckets)
+			if ok != tt.wantOk {
+				t.Fatalf("HistogramQuantile() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && (got < tt.want-1e-9 || got > tt.want+1e-9) {
+				t.Errorf("HistogramQuantile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary_Write(t *testing.T) {
+	summary := Summary{
+		Requests:  3,
+		Unmatched: 1,
+		Paths:     map[string]int{"GET /orders": 2, "GET /missing": 1},
+		Statuses:  map[int]int{200: 2, 404: 1},
+		P50:       12 * time.Millisecond,
+		P95:       -1,
+	}
+	var out bytes.Buffer
+	summary.Write(&out)
+	for _, want := range []string{"Requests:     3 (1 unmatched)", "p50 12ms, p95 n/a", "200: 2, 404: 1", "/orders", "/missing"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Index(out.String(), "/orders") > strings.Index(out.String(), "/missing") {
+		t.Errorf("Write() should list the busiest path first:\n%s", out.String())
+	}
+}
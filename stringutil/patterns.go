@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stringutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexPatternPrefix marks a path pattern as a regular expression,
+// rather than a glob.
+const regexPatternPrefix = "regex:"
+
+// CompilePathPatterns compiles path patterns, each of which is either a
+// glob, such as '/orders/**', or a regular expression prefixed with 'regex:'.
+func CompilePathPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		var expr string
+		if strings.HasPrefix(pattern, regexPatternPrefix) {
+			expr = strings.TrimPrefix(pattern, regexPatternPrefix)
+		} else {
+			expr = globToRegex(pattern)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern: %s: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// globToRegex converts a glob to an anchored regular expression. A '*'
+// matches within a path segment, and '**' matches across segments.
+func globToRegex(glob string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
+
+// MatchesAny returns true if any of the patterns matches the value.
+func MatchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsutil
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"unicode/utf16"
+)
+
+// PKCS#12 object identifiers, see RFC 7292.
+var (
+	oidDataContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidShroudedKeyBag    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertTypeX509      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyId        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPbeWithSha1And3DE = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidSha1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+const pkcs12Iterations = 2048
+
+const (
+	kdfIdKey = 1
+	kdfIdIv  = 2
+	kdfIdMac = 3
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set"`
+}
+
+type pkcs12Attribute struct {
+	Id    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	Id   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// EncodePkcs12 produces a PKCS#12 keystore containing the private key and
+// certificate chain, protected by the password. The key entry is given the
+// alias, and uses algorithms supported by the Java runtime.
+func EncodePkcs12(key crypto.PrivateKey, certs []*x509.Certificate, alias string, password string) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("at least one certificate is required")
+	}
+	encodedPassword := bmpStringZeroTerminated(password)
+
+	localKeyId := sha1.Sum(certs[0].Raw)
+	attributes, err := buildBagAttributes(localKeyId[:], alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []safeBag
+	for i, cert := range certs {
+		bagValue, err := asn1.Marshal(certBag{Id: oidCertTypeX509, Data: cert.Raw})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode certificate: %v", err)
+		}
+		bag := safeBag{Id: oidCertBag, Value: explicitValue(bagValue)}
+		if i == 0 {
+			bag.Attributes = attributes
+		}
+		certBags = append(certBags, bag)
+	}
+
+	keyBagValue, err := encryptPrivateKey(key, encodedPassword)
+	if err != nil {
+		return nil, err
+	}
+	keyBags := []safeBag{{Id: oidShroudedKeyBag, Value: explicitValue(keyBagValue), Attributes: attributes}}
+
+	var authenticatedSafe []contentInfo
+	for _, bags := range [][]safeBag{certBags, keyBags} {
+		ci, err := buildDataContentInfo(bags)
+		if err != nil {
+			return nil, err
+		}
+		authenticatedSafe = append(authenticatedSafe, ci)
+	}
+	authSafeBytes, err := asn1.Marshal(authenticatedSafe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode authenticated safe: %v", err)
+	}
+
+	macSalt := make([]byte, 8)
+	if _, err := rand.Read(macSalt); err != nil {
+		return nil, err
+	}
+	macKey := pkcs12Kdf(encodedPassword, macSalt, pkcs12Iterations, kdfIdMac, 20)
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(authSafeBytes)
+
+	authSafeContent, err := asn1.Marshal(authSafeBytes)
+	if err != nil {
+		return nil, err
+	}
+	pfx := pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidDataContentType,
+			Content:     explicitValue(authSafeContent),
+		},
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSha1, Parameters: asn1.NullRawValue},
+				Digest:    mac.Sum(nil),
+			},
+			MacSalt:    macSalt,
+			Iterations: pkcs12Iterations,
+		},
+	}
+	return asn1.Marshal(pfx)
+}
+
+func buildBagAttributes(localKeyId []byte, alias string) ([]pkcs12Attribute, error) {
+	keyIdValue, err := asn1.Marshal(localKeyId)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{
+		{Id: oidLocalKeyId, Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: keyIdValue}},
+	}
+	if alias != "" {
+		nameValue, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Class: asn1.ClassUniversal, Bytes: bmpString(alias)})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pkcs12Attribute{
+			Id:    oidFriendlyName,
+			Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: nameValue},
+		})
+	}
+	return attributes, nil
+}
+
+func buildDataContentInfo(bags []safeBag) (contentInfo, error) {
+	safeContents, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("failed to encode safe contents: %v", err)
+	}
+	octets, err := asn1.Marshal(safeContents)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidDataContentType, Content: explicitValue(octets)}, nil
+}
+
+// encryptPrivateKey encodes the key as PKCS#8 and encrypts it using
+// pbeWithSHAAnd3-KeyTripleDES-CBC.
+func encryptPrivateKey(key crypto.PrivateKey, encodedPassword []byte) ([]byte, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: pkcs12Iterations})
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey := pkcs12Kdf(encodedPassword, salt, pkcs12Iterations, kdfIdKey, 24)
+	iv := pkcs12Kdf(encodedPassword, salt, pkcs12Iterations, kdfIdIv, 8)
+	block, err := des.NewTripleDESCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	padding := block.BlockSize() - len(pkcs8)%block.BlockSize()
+	plaintext := append(pkcs8, make([]byte, padding)...)
+	for i := len(pkcs8); i < len(plaintext); i++ {
+		plaintext[i] = byte(padding)
+	}
+	encrypted := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plaintext)
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		AlgorithmIdentifier: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPbeWithSha1And3DE,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: encrypted,
+	})
+}
+
+// pkcs12Kdf derives key material from the password, as described
+// in RFC 7292, appendix B.2, using SHA-1.
+func pkcs12Kdf(password []byte, salt []byte, iterations int, id byte, size int) []byte {
+	const v = 64
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	s := fillToBlockSize(salt, v)
+	p := fillToBlockSize(password, v)
+	i := append(s, p...)
+
+	var result []byte
+	one := big.NewInt(1)
+	for len(result) < size {
+		h := sha1.New()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			sum := sha1.Sum(a)
+			a = sum[:]
+		}
+		result = append(result, a...)
+		if len(result) >= size {
+			break
+		}
+
+		b := fillToBlockSize(a, v)
+		bInt := new(big.Int).SetBytes(b)
+		bInt.Add(bInt, one)
+		for j := 0; j < len(i); j += v {
+			block := new(big.Int).SetBytes(i[j : j+v])
+			block.Add(block, bInt)
+			blockBytes := block.Bytes()
+			// keep the lowest v bytes, left-padding with zeros if shorter
+			if len(blockBytes) > v {
+				blockBytes = blockBytes[len(blockBytes)-v:]
+			}
+			copy(i[j:j+v], make([]byte, v-len(blockBytes)))
+			copy(i[j+v-len(blockBytes):j+v], blockBytes)
+		}
+	}
+	return result[:size]
+}
+
+// fillToBlockSize repeats the input to fill a whole number of blocks.
+func fillToBlockSize(input []byte, blockSize int) []byte {
+	if len(input) == 0 {
+		return nil
+	}
+	length := blockSize * ((len(input) + blockSize - 1) / blockSize)
+	output := make([]byte, length)
+	for i := range output {
+		output[i] = input[i%len(input)]
+	}
+	return output
+}
+
+// bmpString encodes the string as big-endian UTF-16.
+func bmpString(s string) []byte {
+	var encoded []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		encoded = append(encoded, byte(r>>8), byte(r))
+	}
+	return encoded
+}
+
+// bmpStringZeroTerminated encodes a password as required by the PKCS#12 KDF.
+func bmpStringZeroTerminated(s string) []byte {
+	return append(bmpString(s), 0, 0)
+}
+
+func explicitValue(content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+}
@@ -0,0 +1,54 @@
+package tlsutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/asn1"
+	"testing"
+)
+
+func TestEncodePkcs12(t *testing.T) {
+	certPem, keyPem, err := GenerateSelfSigned([]string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	key, certs, err := ParseKeyPair(certPem, keyPem)
+	if err != nil {
+		t.Fatalf("failed to parse key pair: %v", err)
+	}
+	if len(certs[0].IPAddresses) != 1 || len(certs[0].DNSNames) != 1 {
+		t.Errorf("expected one IP address and one DNS name, got %v and %v", certs[0].IPAddresses, certs[0].DNSNames)
+	}
+
+	keystore, err := EncodePkcs12(key, certs, "imposter", "secret")
+	if err != nil {
+		t.Fatalf("failed to encode keystore: %v", err)
+	}
+
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(keystore, &pfx); err != nil {
+		t.Fatalf("failed to decode keystore: %v", err)
+	}
+	if pfx.Version != 3 {
+		t.Errorf("expected version 3, got %d", pfx.Version)
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		t.Fatalf("failed to decode authenticated safe: %v", err)
+	}
+
+	for _, tt := range []struct {
+		password string
+		valid    bool
+	}{
+		{password: "secret", valid: true},
+		{password: "wrong", valid: false},
+	} {
+		macKey := pkcs12Kdf(bmpStringZeroTerminated(tt.password), pfx.MacData.MacSalt, pfx.MacData.Iterations, kdfIdMac, 20)
+		mac := hmac.New(sha1.New, macKey)
+		mac.Write(authSafe)
+		if got := hmac.Equal(mac.Sum(nil), pfx.MacData.Mac.Digest); got != tt.valid {
+			t.Errorf("MAC verification with password %q = %v, want %v", tt.password, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/jsonschema"
+	"gatehill.io/imposter/openapi"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunContract sends a request for each resource generated from the
+// OpenAPI spec, including its status and example variants, to the mock
+// at baseUrl, and checks that the responses conform to the spec. The
+// status code must be declared by the operation, the content type must
+// be one of those declared for the response and JSON bodies must be
+// valid against the response schema.
+func RunContract(specFile string, baseUrl string) (Report, error) {
+	spec, err := openapi.Parse(specFile)
+	if err != nil {
+		return Report{}, fmt.Errorf("unable to parse openapi spec: %v: %v", specFile, err)
+	}
+	resources := impostermodel.GenerateResourcesFromModel(spec, impostermodel.ResourceGenerationOptions{
+		ScriptEngine:     impostermodel.ScriptEngineNone,
+		SimulateSecurity: true,
+	})
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].Path != resources[j].Path {
+			return resources[i].Path < resources[j].Path
+		}
+		return resources[i].Method < resources[j].Method
+	})
+
+	report := Report{}
+	for _, resource := range resources {
+		operation := spec.Paths[resource.Path][strings.ToLower(resource.Method)]
+		started := time.Now()
+		failures := checkContract(resource, operation, strings.TrimSuffix(baseUrl, "/"))
+		result := Result{Name: describeResource(resource), Failures: failures, Duration: time.Since(started)}
+		logger.Debugf("contract check %s completed in %v with %d failure(s)", result.Name, result.Duration, len(result.Failures))
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// describeResource names the check for a resource, including the
+// request header that selects a variant, if any.
+func describeResource(resource impostermodel.Resource) string {
+	name := resource.Method + " " + resource.Path
+	if resource.RequestHeaders != nil {
+		if status, found := (*resource.RequestHeaders)[impostermodel.StatusSelectionHeader]; found {
+			return name + " (status " + status + ")"
+		}
+		if example, found := (*resource.RequestHeaders)[impostermodel.ExampleSelectionHeader]; found {
+			return name + " (example " + example + ")"
+		}
+	}
+	return name
+}
+
+func checkContract(resource impostermodel.Resource, operation openapi.Operation, baseUrl string) []string {
+	path, query, headers := buildContractRequest(resource, operation)
+	var body string
+	if operation.RequestBody != nil {
+		if contentType, sample, found := operation.RequestBody.SampleBody(); found {
+			headers["Content-Type"] = contentType
+			if s, ok := sample.(string); ok && !openapi.IsJsonContentType(contentType) {
+				body = s
+			} else {
+				body = toJson(sample)
+			}
+		}
+	}
+	target := baseUrl + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	resp, respBody, err := send(resource.Method, target, headers, body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var failures []string
+	if resource.Response != nil && resource.Response.StatusCode != 0 && resource.Response.StatusCode != resp.StatusCode {
+		failures = append(failures, fmt.Sprintf("expected status %d but was %d", resource.Response.StatusCode, resp.StatusCode))
+	}
+	response, declared := findDeclaredResponse(operation, resp.StatusCode)
+	if !declared {
+		return append(failures, fmt.Sprintf("status %d is not declared by the operation", resp.StatusCode))
+	}
+	return append(failures, checkContent(response, resp.Header.Get("Content-Type"), respBody)...)
+}
+
+// buildContractRequest returns the path, with its parameters replaced by
+// sample values, and the query parameters and headers required by the
+// operation. Credentials are added to satisfy the security of the
+// resource, and the headers that select a variant are included.
+func buildContractRequest(resource impostermodel.Resource, operation openapi.Operation) (string, url.Values, map[string]string) {
+	path := resource.Path
+	query := url.Values{}
+	headers := make(map[string]string)
+	for _, parameter := range operation.Parameters {
+		if parameter.In != "path" && !parameter.Required {
+			continue
+		}
+		value := sampleParameter(parameter)
+		switch parameter.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+parameter.Name+"}", url.PathEscape(value))
+		case "query":
+			query.Set(parameter.Name, value)
+		case "header":
+			headers[parameter.Name] = value
+		}
+	}
+	if resource.Security != nil && len(resource.Security.Conditions) > 0 {
+		condition := resource.Security.Conditions[0]
+		for name, matcher := range condition.RequestHeaders {
+			headers[name] = sampleCredential(matcher)
+		}
+		for name, matcher := range condition.QueryParams {
+			query.Set(name, sampleCredential(matcher))
+		}
+	}
+	if resource.RequestHeaders != nil {
+		for name, value := range *resource.RequestHeaders {
+			headers[name] = value
+		}
+	}
+	return path, query, headers
+}
+
+func sampleParameter(parameter openapi.Parameter) string {
+	value, found := parameter.SampleValue()
+	if !found {
+		return "1"
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// sampleCredential returns a value satisfying a matcher generated from
+// a security scheme.
+func sampleCredential(matcher impostermodel.SecurityMatcher) string {
+	switch {
+	case strings.Contains(matcher.Value, "asic"):
+		return "Basic dXNlcjpwYXNzd29yZA=="
+	case strings.Contains(matcher.Value, "earer"):
+		return "Bearer token"
+	default:
+		return "key"
+	}
+}
+
+// findDeclaredResponse returns the response declared for the status
+// code, a range such as 4XX, or the default response.
+func findDeclaredResponse(operation openapi.Operation, statusCode int) (openapi.OperationResponse, bool) {
+	status := strconv.Itoa(statusCode)
+	for _, key := range []string{status, status[:1] + "XX", status[:1] + "xx", "default"} {
+		if response, found := operation.Responses[key]; found {
+			return response, true
+		}
+	}
+	return openapi.OperationResponse{}, false
+}
+
+// checkContent checks the content type and body of the response against
+// those declared. Responses declared without content are not checked.
+func checkContent(response openapi.OperationResponse, contentType string, body []byte) []string {
+	if len(response.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid content type '%s'", contentType)}
+	}
+	declared, found := findMediaType(response.Content, mediaType)
+	if !found {
+		var declaredTypes []string
+		for ct := range response.Content {
+			declaredTypes = append(declaredTypes, ct)
+		}
+		sort.Strings(declaredTypes)
+		return []string{fmt.Sprintf("content type '%s' is not one of %s", mediaType, strings.Join(declaredTypes, ", "))}
+	}
+	schema := declared.JsonSchema()
+	if schema == nil || !openapi.IsJsonContentType(mediaType) {
+		return nil
+	}
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return []string{fmt.Sprintf("invalid JSON body: %v: '%s'", err, abbreviate(body))}
+	}
+	var failures []string
+	for _, violation := range jsonschema.Validate(schema, document, nil) {
+		failures = append(failures, "body does not match schema: "+violation)
+	}
+	return failures
+}
+
+// findMediaType returns the declared media type matching the content
+// type, allowing for wildcards such as application/* and */*.
+func findMediaType(content map[string]openapi.MediaType, mediaType string) (openapi.MediaType, bool) {
+	mediaType = strings.ToLower(mediaType)
+	for _, candidate := range []string{mediaType, strings.Split(mediaType, "/")[0] + "/*", "*/*"} {
+		for declared, media := range content {
+			if declaredType, _, err := mime.ParseMediaType(declared); err == nil && strings.ToLower(declaredType) == candidate {
+				return media, true
+			}
+		}
+	}
+	return openapi.MediaType{}, false
+}
@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"gatehill.io/imposter/openapi"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunContract(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "spec.yaml")
+	spec := `openapi: 3.0.1
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          example: 7
+    get:
+      security:
+        - token: []
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+            example: acme
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id:
+                    type: integer
+                  name:
+                    type: string
+        "404":
+          description: Not found
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]
+components:
+  securitySchemes:
+    token:
+      type: http
+      scheme: bearer
+`
+	if err := os.WriteFile(specFile, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("X-Tenant")+" "+r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Imposter-Status") == "404" {
+			// drifted from the contract: missing the required message
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 7, "name": "Fluffy"}`))
+	}))
+	defer server.Close()
+
+	report, err := RunContract(specFile, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("expected 2 results, got: %+v", report.Results)
+	}
+	if !report.Results[0].Passed() || report.Results[0].Name != "GET /pets/{petId}" {
+		t.Errorf("expected default response to pass: %+v", report.Results[0])
+	}
+	want := []string{"body does not match schema: $: missing required property 'message'"}
+	if report.Results[1].Name != "GET /pets/{petId} (status 404)" || !reflect.DeepEqual(report.Results[1].Failures, want) {
+		t.Errorf("expected status variant to fail with %v, got: %+v", want, report.Results[1])
+	}
+	if requests[0] != "/pets/7 acme Bearer token" {
+		t.Errorf("unexpected request: %s", requests[0])
+	}
+}
+
+func Test_checkContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		declared    string
+		contentType string
+		body        string
+		want        []string
+	}{
+		{name: "matching type", declared: "application/json", contentType: "application/json; charset=utf-8", body: `{}`},
+		{name: "wildcard", declared: "text/*", contentType: "text/plain", body: `hello`},
+		{name: "undeclared type", declared: "application/json", contentType: "text/html", body: `<p/>`, want: []string{"content type 'text/html' is not one of application/json"}},
+		{name: "invalid JSON", declared: "application/json", contentType: "application/json", body: `{`, want: []string{"invalid JSON body: unexpected end of JSON input: '{'"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := openapi.OperationResponse{Content: map[string]openapi.MediaType{
+				tt.declared: {Schema: map[interface{}]interface{}{"type": "object"}},
+			}}
+			if got := checkContent(response, tt.contentType, []byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"strings"
+	"time"
+)
+
+type jsonReport struct {
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Results []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	Name       string   `json:"name"`
+	Passed     bool     `json:"passed"`
+	Failures   []string `json:"failures,omitempty"`
+	DurationMs int64    `json:"durationMs"`
+}
+
+// MarshalJSON encodes the report with a summary of the number of tests
+// that passed and failed, and the duration of each in milliseconds.
+func (r Report) MarshalJSON() ([]byte, error) {
+	report := jsonReport{
+		Passed:  len(r.Results) - r.Failed(),
+		Failed:  r.Failed(),
+		Results: []jsonResult{},
+	}
+	for _, result := range r.Results {
+		report.Results = append(report.Results, jsonResult{
+			Name:       result.Name,
+			Passed:     result.Passed(),
+			Failures:   result.Failures,
+			DurationMs: result.Duration.Milliseconds(),
+		})
+	}
+	return json.Marshal(report)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// FormatJUnit renders the report as JUnit XML, in a single test suite
+// with the given name, as understood by CI test reporting tools.
+func FormatJUnit(report Report, suiteName string, timestamp time.Time) ([]byte, error) {
+	var total time.Duration
+	suite := junitTestSuite{
+		Name:      suiteName,
+		Tests:     len(report.Results),
+		Failures:  report.Failed(),
+		Timestamp: timestamp.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, result := range report.Results {
+		total += result.Duration
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: suiteName,
+			Time:      formatSeconds(result.Duration),
+		}
+		if !result.Passed() {
+			testCase.Failure = &junitFailure{
+				Message: result.Failures[0],
+				Type:    "AssertionError",
+				Text:    strings.Join(result.Failures, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = formatSeconds(total)
+
+	suites := junitTestSuites{
+		Name:     suiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	content, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JUnit report: %v", err)
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// WriteJUnit writes the report as JUnit XML to the file.
+func WriteJUnit(report Report, suiteName string, file string) error {
+	content, err := FormatJUnit(report, suiteName, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileAtomic(file, content, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %s: %v", file, err)
+	}
+	logger.Debugf("wrote JUnit report to %s", file)
+	return nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,45 @@
+package verify
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var testReport = Report{Results: []Result{
+	{Name: "GET /pets", Duration: 12 * time.Millisecond},
+	{Name: "POST /pets", Failures: []string{"expected status 201 but was 500", "expected body to contain 'Rex' but was ''"}, Duration: 1500 * time.Millisecond},
+}}
+
+func TestReport_MarshalJSON(t *testing.T) {
+	got, err := json.Marshal(testReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"passed":1,"failed":1,"results":[` +
+		`{"name":"GET /pets","passed":true,"durationMs":12},` +
+		`{"name":"POST /pets","passed":false,"failures":["expected status 201 but was 500","expected body to contain 'Rex' but was ''"],"durationMs":1500}]}`
+	if string(got) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", got, want)
+	}
+}
+
+func TestFormatJUnit(t *testing.T) {
+	got, err := FormatJUnit(testReport, "tests.yaml", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="tests.yaml" tests="2" failures="1" time="1.512">
+  <testsuite name="tests.yaml" tests="2" failures="1" errors="0" skipped="0" time="1.512" timestamp="2024-01-02T03:04:05">
+    <testcase name="GET /pets" classname="tests.yaml" time="0.012"></testcase>
+    <testcase name="POST /pets" classname="tests.yaml" time="1.500">
+      <failure message="expected status 201 but was 500" type="AssertionError">expected status 201 but was 500&#xA;expected body to contain &#39;Rex&#39; but was &#39;&#39;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if string(got) != want {
+		t.Errorf("FormatJUnit() =\n%s\nwant\n%s", got, want)
+	}
+}
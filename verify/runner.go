@@ -0,0 +1,235 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var logger = logging.GetLogger()
+
+// client sends test requests to the mock. Certificate verification is
+// skipped, as the mock commonly serves a self-signed certificate.
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
+// Result is the outcome of a test. The test passed if there are no
+// failures.
+type Result struct {
+	Name     string
+	Failures []string
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Report holds the results of the tests in a suite.
+type Report struct {
+	Results []Result
+}
+
+// Failed returns the number of tests that failed.
+func (r Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Run sends the request of each test to the mock at baseUrl, such as
+// 'http://localhost:8080', and checks the responses.
+func Run(suite *Suite, baseUrl string) Report {
+	report := Report{}
+	for _, test := range suite.Tests {
+		result := runTest(test, strings.TrimSuffix(baseUrl, "/"))
+		logger.Debugf("test %s completed in %v with %d failure(s)", result.Name, result.Duration, len(result.Failures))
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func runTest(test Test, baseUrl string) Result {
+	started := time.Now()
+	failures := sendAndCheck(test, baseUrl)
+	return Result{Name: test.DisplayName(), Failures: failures, Duration: time.Since(started)}
+}
+
+func sendAndCheck(test Test, baseUrl string) []string {
+	resp, body, err := send(test.Request.method(), baseUrl+test.Request.Path, test.Request.Headers, test.Request.Body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return checkResponse(test.Expect, resp.StatusCode, resp.Header, body)
+}
+
+// send makes a request to the mock, returning the response and its body.
+func send(method string, url string, headers map[string]string, body string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid request: %v", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read response body: %v", err)
+	}
+	return resp, respBody, nil
+}
+
+// checkResponse returns a description of each expectation the response
+// does not meet.
+func checkResponse(expect Expectation, status int, headers http.Header, body []byte) []string {
+	var failures []string
+	if expect.Status != 0 && expect.Status != status {
+		failures = append(failures, fmt.Sprintf("expected status %d but was %d", expect.Status, status))
+	}
+	for name, expected := range expect.Headers {
+		actual := headers.Get(name)
+		if !headerMatches(name, expected, actual) {
+			failures = append(failures, fmt.Sprintf("expected header %s to be '%s' but was '%s'", name, expected, actual))
+		}
+	}
+	if expect.Body != nil {
+		failures = append(failures, checkBody(*expect.Body, body)...)
+	}
+	return failures
+}
+
+func headerMatches(name string, expected string, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	if strings.EqualFold(name, "Content-Type") && !strings.Contains(expected, ";") {
+		if mediaType, _, err := mime.ParseMediaType(actual); err == nil {
+			return strings.EqualFold(mediaType, expected)
+		}
+	}
+	return false
+}
+
+func checkBody(expect BodyExpectation, body []byte) []string {
+	var failures []string
+	if expect.Equals != nil && *expect.Equals != string(body) {
+		failures = append(failures, fmt.Sprintf("expected body to equal '%s' but was '%s'", *expect.Equals, abbreviate(body)))
+	}
+	if expect.Contains != "" && !strings.Contains(string(body), expect.Contains) {
+		failures = append(failures, fmt.Sprintf("expected body to contain '%s' but was '%s'", expect.Contains, abbreviate(body)))
+	}
+	if expect.Matches != "" {
+		if re, err := regexp.Compile(expect.Matches); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid body pattern: %s: %v", expect.Matches, err))
+		} else if !re.Match(body) {
+			failures = append(failures, fmt.Sprintf("expected body to match '%s' but was '%s'", expect.Matches, abbreviate(body)))
+		}
+	}
+	if len(expect.JsonPath) > 0 {
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return append(failures, fmt.Sprintf("expected a JSON body but was '%s'", abbreviate(body)))
+		}
+		for path, expected := range expect.JsonPath {
+			actual, found, err := evaluateJsonPath(document, path)
+			if err != nil {
+				failures = append(failures, err.Error())
+			} else if !found {
+				failures = append(failures, fmt.Sprintf("expected %s to be %s but it was not found", path, toJson(expected)))
+			} else if !reflect.DeepEqual(expected, actual) {
+				failures = append(failures, fmt.Sprintf("expected %s to be %s but was %s", path, toJson(expected), toJson(actual)))
+			}
+		}
+	}
+	return failures
+}
+
+// evaluateJsonPath returns the value at a path such as '$.items[0].id'
+// within the document.
+func evaluateJsonPath(document interface{}, path string) (value interface{}, found bool, err error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false, fmt.Errorf("invalid JSON path: %s", path)
+	}
+	value = document
+	remaining := strings.ReplaceAll(strings.TrimPrefix(path, "$"), "[", ".[")
+	for _, segment := range strings.Split(remaining, ".") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "[") {
+			index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(segment, "["), "]"))
+			if err != nil || !strings.HasSuffix(segment, "]") {
+				return nil, false, fmt.Errorf("invalid JSON path: %s", path)
+			}
+			items, ok := value.([]interface{})
+			if !ok || index < 0 || index >= len(items) {
+				return nil, false, nil
+			}
+			value = items[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		if value, ok = object[segment]; !ok {
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+func toJson(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+// abbreviate returns the body, truncated if it is long, for messages.
+func abbreviate(body []byte) string {
+	const maxLength = 200
+	if len(body) > maxLength {
+		return string(body[:maxLength]) + "..."
+	}
+	return string(body)
+}
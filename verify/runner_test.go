@@ -0,0 +1,153 @@
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pets":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"items": [{"id": 1, "name": "Fluffy"}], "total": 1}`))
+		case "/echo":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(r.Method + " " + r.Header.Get("X-Test")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	equals := "POST abc"
+	suite := &Suite{Tests: []Test{
+		{
+			Request: Request{Path: "/pets"},
+			Expect: Expectation{
+				Status:  200,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body: &BodyExpectation{
+					Contains: "Fluffy",
+					JsonPath: map[string]interface{}{"$.items[0].name": "Fluffy", "$.total": float64(1)},
+				},
+			},
+		},
+		{
+			Name:    "echo",
+			Request: Request{Method: "post", Path: "/echo", Headers: map[string]string{"X-Test": "abc"}},
+			Expect:  Expectation{Status: 201, Body: &BodyExpectation{Equals: &equals, Matches: "^POST"}},
+		},
+		{
+			Request: Request{Path: "/pets"},
+			Expect: Expectation{
+				Status: 201,
+				Body:   &BodyExpectation{JsonPath: map[string]interface{}{"$.items[1].name": "Rex", "$.total": float64(2)}},
+			},
+		},
+	}}
+
+	report := Run(suite, server.URL+"/")
+	if got := report.Failed(); got != 1 {
+		t.Fatalf("Failed() = %d, want 1: %+v", got, report.Results)
+	}
+	if !report.Results[0].Passed() || report.Results[0].Name != "GET /pets" {
+		t.Errorf("expected first test to pass: %+v", report.Results[0])
+	}
+	if !report.Results[1].Passed() || report.Results[1].Name != "echo" {
+		t.Errorf("expected second test to pass: %+v", report.Results[1])
+	}
+	failures := report.Results[2].Failures
+	if len(failures) != 3 || failures[0] != "expected status 201 but was 200" {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+	for _, want := range []string{"expected $.items[1].name to be \"Rex\" but it was not found", "expected $.total to be 2 but was 1"} {
+		found := false
+		for _, failure := range failures {
+			found = found || failure == want
+		}
+		if !found {
+			t.Errorf("expected failure %q in %v", want, failures)
+		}
+	}
+}
+
+func Test_evaluateJsonPath(t *testing.T) {
+	document := map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"id": float64(1)}},
+	}
+	tests := []struct {
+		path      string
+		want      interface{}
+		wantFound bool
+		wantErr   bool
+	}{
+		{path: "$", want: document, wantFound: true},
+		{path: "$.items[0].id", want: float64(1), wantFound: true},
+		{path: "$.items[1].id"},
+		{path: "$.missing"},
+		{path: "items", wantErr: true},
+		{path: "$.items[x]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, found, err := evaluateJsonPath(document, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateJsonPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluateJsonPath() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestLoadSuite(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `tests:
+- request:
+    path: /pets
+  expect:
+    status: 200
+    body:
+      jsonPath:
+        $.total: 1
+`,
+		},
+		{name: "unknown property", content: "tests:\n- request:\n    path: /pets\n  expected:\n    status: 200\n", wantErr: "unknown field"},
+		{name: "relative path", content: "tests:\n- request:\n    path: pets\n", wantErr: "must start with '/'"},
+		{name: "no tests", content: "tests: []\n", wantErr: "no tests found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "tests.yaml")
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			suite, err := LoadSuite(testFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadSuite() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSuite() error = %v", err)
+			}
+			if got := suite.Tests[0].Expect.Body.JsonPath["$.total"]; got != float64(1) {
+				t.Errorf("expected JSON path value 1, got %v", got)
+			}
+		})
+	}
+}
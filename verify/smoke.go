@@ -0,0 +1,43 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SmokeTest sends the request of each test to the mock at baseUrl, such
+// as 'http://localhost:8080'. A test fails if its request fails, or
+// receives a server error (5xx) status other than the expected status,
+// which typically indicates a broken script or missing response file.
+func SmokeTest(tests []Test, baseUrl string) Report {
+	report := Report{}
+	for _, test := range tests {
+		started := time.Now()
+		var failures []string
+		resp, body, err := send(test.Request.method(), strings.TrimSuffix(baseUrl, "/")+test.Request.Path, test.Request.Headers, test.Request.Body)
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else if resp.StatusCode >= 500 && resp.StatusCode != test.Expect.Status {
+			failures = append(failures, fmt.Sprintf("unexpected status %d: '%s'", resp.StatusCode, abbreviate(body)))
+		}
+		report.Results = append(report.Results, Result{Name: test.DisplayName(), Failures: failures, Duration: time.Since(started)})
+	}
+	return report
+}
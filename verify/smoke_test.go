@@ -0,0 +1,36 @@
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSmokeTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Imposter-Status") == "503":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("script error"))
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	report := SmokeTest([]Test{
+		{Request: Request{Path: "/pets"}},
+		{Request: Request{Path: "/pets", Headers: map[string]string{"X-Imposter-Status": "503"}}, Expect: Expectation{Status: 503}},
+		{Request: Request{Method: "POST", Path: "/broken"}},
+		{Request: Request{Path: "/missing"}},
+	}, server.URL)
+
+	if got := report.Failed(); got != 1 {
+		t.Fatalf("Failed() = %d, want 1: %+v", got, report.Results)
+	}
+	if result := report.Results[2]; result.Name != "POST /broken" || result.Failures[0] != "unexpected status 500: 'script error'" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
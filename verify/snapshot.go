@@ -0,0 +1,233 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/stringutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotHeaders are the response headers recorded in snapshots. Others,
+// such as Date, vary between responses, so are omitted.
+var snapshotHeaders = []string{"Content-Type", "Location"}
+
+// snapshotFileSuffix distinguishes snapshot files from configuration
+// files, such as those ending in '-config.json'.
+const snapshotFileSuffix = ".snapshot.json"
+
+var snapshotNameSanitiser = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Snapshot is the canonical form of the response to a request. JSON
+// bodies are held as JSON, so they are written with sorted keys.
+type Snapshot struct {
+	Request  Request          `json:"request"`
+	Response SnapshotResponse `json:"response"`
+}
+
+type SnapshotResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// SnapshotFileName returns the name of the snapshot file for the request,
+// derived from its method and path. If the request has headers, such as
+// those selecting a variant of a resource, a hash of them is appended.
+func SnapshotFileName(request Request) string {
+	name := request.method() + "-" + strings.Trim(snapshotNameSanitiser.ReplaceAllString(request.Path, "_"), "_")
+	if len(request.Headers) > 0 {
+		var headers []string
+		for header, value := range request.Headers {
+			headers = append(headers, strings.ToLower(header)+"="+value)
+		}
+		sort.Strings(headers)
+		name += "-" + stringutil.Sha1hashString(strings.Join(headers, "&"))[:8]
+	}
+	return strings.TrimSuffix(name, "-") + snapshotFileSuffix
+}
+
+// UpdateSnapshots sends the request of each test to the mock at baseUrl,
+// and writes the canonical form of each response to a file in dir.
+// Snapshots in dir for requests that are no longer tested are removed.
+func UpdateSnapshots(tests []Test, baseUrl string, dir string) (Report, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Report{}, fmt.Errorf("failed to create snapshot directory: %s: %v", dir, err)
+	}
+	report := Report{}
+	current := make(map[string]bool)
+	for _, test := range tests {
+		started := time.Now()
+		fileName := SnapshotFileName(test.Request)
+		current[fileName] = true
+		var failures []string
+		if content, err := takeSnapshot(test.Request, baseUrl); err != nil {
+			failures = append(failures, err.Error())
+		} else if err := fileutil.WriteFileAtomic(filepath.Join(dir, fileName), content, 0644); err != nil {
+			return report, fmt.Errorf("failed to write snapshot: %s: %v", fileName, err)
+		}
+		report.Results = append(report.Results, Result{Name: test.DisplayName(), Failures: failures, Duration: time.Since(started)})
+	}
+	stale, err := listStaleSnapshots(dir, current)
+	if err != nil {
+		return report, err
+	}
+	for _, fileName := range stale {
+		if err := os.Remove(filepath.Join(dir, fileName)); err != nil {
+			return report, fmt.Errorf("failed to remove stale snapshot: %s: %v", fileName, err)
+		}
+		logger.Debugf("removed stale snapshot %s", fileName)
+	}
+	return report, nil
+}
+
+// CheckSnapshots sends the request of each test to the mock at baseUrl,
+// and compares the canonical form of each response with its snapshot in
+// dir. A test fails if its snapshot is missing or differs. Snapshots for
+// requests that are no longer tested are reported as failures, so they
+// are not silently left behind.
+func CheckSnapshots(tests []Test, baseUrl string, dir string) (Report, error) {
+	report := Report{}
+	current := make(map[string]bool)
+	for _, test := range tests {
+		started := time.Now()
+		fileName := SnapshotFileName(test.Request)
+		current[fileName] = true
+		report.Results = append(report.Results, Result{
+			Name:     test.DisplayName(),
+			Failures: checkSnapshot(test.Request, baseUrl, filepath.Join(dir, fileName)),
+			Duration: time.Since(started),
+		})
+	}
+	stale, err := listStaleSnapshots(dir, current)
+	if err != nil {
+		return report, err
+	}
+	for _, fileName := range stale {
+		report.Results = append(report.Results, Result{
+			Name:     fileName,
+			Failures: []string{"snapshot is not for any current resource - update the snapshots to remove it"},
+		})
+	}
+	return report, nil
+}
+
+func checkSnapshot(request Request, baseUrl string, snapshotFile string) []string {
+	expected, err := os.ReadFile(snapshotFile)
+	if os.IsNotExist(err) {
+		return []string{fmt.Sprintf("no snapshot recorded in %s", filepath.Base(snapshotFile))}
+	} else if err != nil {
+		return []string{fmt.Sprintf("unable to read snapshot: %v", err)}
+	}
+	actual, err := takeSnapshot(request, baseUrl)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		return nil
+	}
+	return []string{"response differs from snapshot " + filepath.Base(snapshotFile) + ":\n" + diffLines(string(expected), string(actual))}
+}
+
+// takeSnapshot sends the request and returns the canonical form of
+// the response.
+func takeSnapshot(request Request, baseUrl string) ([]byte, error) {
+	resp, body, err := send(request.method(), strings.TrimSuffix(baseUrl, "/")+request.Path, request.Headers, request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Method = request.method()
+	snapshot := Snapshot{
+		Request:  request,
+		Response: SnapshotResponse{Status: resp.StatusCode},
+	}
+	for _, header := range snapshotHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			if snapshot.Response.Headers == nil {
+				snapshot.Response.Headers = make(map[string]string)
+			}
+			snapshot.Response.Headers[header] = value
+		}
+	}
+	if len(body) > 0 {
+		snapshot.Response.Body = string(body)
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+			(mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+			var value interface{}
+			if err := json.Unmarshal(body, &value); err == nil {
+				snapshot.Response.Body = value
+			}
+		}
+	}
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// listStaleSnapshots returns the names of the snapshot files in dir
+// that are not current.
+func listStaleSnapshots(dir string, current map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to list snapshot directory: %s: %v", dir, err)
+	}
+	var stale []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), snapshotFileSuffix) && !current[entry.Name()] {
+			stale = append(stale, entry.Name())
+		}
+	}
+	return stale, nil
+}
+
+// diffLines describes the lines that differ between the expected and
+// actual content, prefixed with '-' and '+' respectively. Lines common
+// to the start and end of both are omitted.
+func diffLines(expected string, actual string) string {
+	expectedLines := strings.Split(strings.TrimSpace(expected), "\n")
+	actualLines := strings.Split(strings.TrimSpace(actual), "\n")
+	start := 0
+	for start < len(expectedLines) && start < len(actualLines) && expectedLines[start] == actualLines[start] {
+		start++
+	}
+	endExpected, endActual := len(expectedLines), len(actualLines)
+	for endExpected > start && endActual > start && expectedLines[endExpected-1] == actualLines[endActual-1] {
+		endExpected--
+		endActual--
+	}
+	var diff []string
+	for _, line := range expectedLines[start:endExpected] {
+		diff = append(diff, "- "+line)
+	}
+	for _, line := range actualLines[start:endActual] {
+		diff = append(diff, "+ "+line)
+	}
+	return strings.Join(diff, "\n")
+}
@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshots(t *testing.T) {
+	name := "Fluffy"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "varies")
+		if r.Header.Get("X-Imposter-Status") == "404" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "` + name + `", "id": 1}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "GET-orders.snapshot.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []Test{
+		{Request: Request{Path: "/pets/1"}},
+		{Request: Request{Path: "/pets/1", Headers: map[string]string{"X-Imposter-Status": "404"}}},
+	}
+
+	report, err := UpdateSnapshots(tests, server.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed() != 0 {
+		t.Fatalf("expected snapshots to be taken: %+v", report.Results)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "GET-pets_1.snapshot.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "request": {
+    "method": "GET",
+    "path": "/pets/1"
+  },
+  "response": {
+    "status": 200,
+    "headers": {
+      "Content-Type": "application/json"
+    },
+    "body": {
+      "id": 1,
+      "name": "Fluffy"
+    }
+  }
+}
+`
+	if string(content) != want {
+		t.Errorf("unexpected snapshot:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "GET-orders.snapshot.json")); !os.IsNotExist(err) {
+		t.Errorf("expected stale snapshot to be removed")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("expected 2 snapshots and README, got %d files", len(entries))
+	}
+
+	report, err = CheckSnapshots(tests, server.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed() != 0 {
+		t.Errorf("expected unchanged responses to match: %+v", report.Results)
+	}
+
+	name = "Rex"
+	report, err = CheckSnapshots(append(tests, Test{Request: Request{Path: "/stores"}}), server.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed() != 2 {
+		t.Fatalf("expected 2 failures, got: %+v", report.Results)
+	}
+	wantDiff := "response differs from snapshot GET-pets_1.snapshot.json:\n-       \"name\": \"Fluffy\"\n+       \"name\": \"Rex\""
+	if failures := report.Results[0].Failures; len(failures) != 1 || failures[0] != wantDiff {
+		t.Errorf("unexpected failures: %q", failures)
+	}
+	if failures := report.Results[2].Failures; len(failures) != 1 || !strings.HasPrefix(failures[0], "no snapshot recorded") {
+		t.Errorf("unexpected failures: %q", failures)
+	}
+}
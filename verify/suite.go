@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"fmt"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Suite is a set of tests, read from a YAML or JSON test file.
+type Suite struct {
+	Tests []Test `json:"tests"`
+}
+
+// Test sends a request to the mock and checks the response against
+// its expectations.
+type Test struct {
+	Name    string      `json:"name,omitempty"`
+	Request Request     `json:"request"`
+	Expect  Expectation `json:"expect"`
+}
+
+type Request struct {
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Expectation holds the checks made on a response. Headers are matched
+// exactly, other than the parameters of the Content-Type header, such as
+// the charset, which are ignored unless they are expected.
+type Expectation struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *BodyExpectation  `json:"body,omitempty"`
+}
+
+// BodyExpectation checks the response body. Equals, Contains and Matches,
+// a regular expression, check the whole body. JsonPath checks the values
+// at paths, such as '$.items[0].id', within a JSON body.
+type BodyExpectation struct {
+	Equals   *string                `json:"equals,omitempty"`
+	Contains string                 `json:"contains,omitempty"`
+	Matches  string                 `json:"matches,omitempty"`
+	JsonPath map[string]interface{} `json:"jsonPath,omitempty"`
+}
+
+// DisplayName returns the name of the test, or its method and path.
+func (t Test) DisplayName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Request.method() + " " + t.Request.Path
+}
+
+func (r Request) method() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// LoadSuite reads the tests in the file. Unknown properties are an error,
+// so mistakes in the test file are not silently ignored.
+func LoadSuite(testFile string) (*Suite, error) {
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read test file: %s: %v", testFile, err)
+	}
+	var suite Suite
+	if err := yaml.UnmarshalStrict(content, &suite); err != nil {
+		return nil, fmt.Errorf("invalid test file: %s: %v", testFile, err)
+	}
+	if len(suite.Tests) == 0 {
+		return nil, fmt.Errorf("no tests found in: %s", testFile)
+	}
+	for i, test := range suite.Tests {
+		if !strings.HasPrefix(test.Request.Path, "/") {
+			return nil, fmt.Errorf("invalid test file: %s: test %d: request path must start with '/'", testFile, i+1)
+		}
+	}
+	return &suite, nil
+}
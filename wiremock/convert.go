@@ -0,0 +1,570 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiremock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/impostermodel"
+	"gatehill.io/imposter/stringutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Options controls the conversion of stub mappings.
+type Options struct {
+	// FilesDir holds the files named by 'bodyFileName', such as
+	// WireMock's '__files' directory.
+	FilesDir string
+
+	// GlobalTemplating treats every response as a template, as WireMock
+	// does when started with '--global-response-templating'.
+	GlobalTemplating bool
+}
+
+// Conversion is the Imposter configuration converted from stub mappings.
+type Conversion struct {
+	Resources []impostermodel.Resource
+
+	// Files are the response files, keyed by path relative to the
+	// config dir.
+	Files map[string][]byte
+
+	// Warnings describe the features of the mappings that could not be
+	// converted, so the mock may not behave the same as WireMock.
+	Warnings []string
+}
+
+// regexMetaChars are the characters indicating a path segment of a URL
+// pattern is a regular expression, rather than a literal.
+const regexMetaChars = `[](){}*+?|^$\`
+
+var faults = map[string]string{
+	"EMPTY_RESPONSE":           "EmptyResponse",
+	"CONNECTION_RESET_BY_PEER": "CloseConnection",
+	"RANDOM_DATA_THEN_CLOSE":   "CloseConnection",
+	"MALFORMED_RESPONSE_CHUNK": "CloseConnection",
+}
+
+// Convert converts the stub mappings to Imposter resources. Mappings that
+// cannot be converted, such as those proxying to another server, are
+// skipped, and unsupported matchers are ignored, with a warning for each.
+func Convert(mappings []Mapping, options Options) *Conversion {
+	c := &converter{
+		options:    options,
+		conversion: &Conversion{Files: make(map[string][]byte)},
+	}
+	for i, mapping := range mappings {
+		c.mappingName = describeMapping(i, mapping)
+		resource, ok := c.convertMapping(mapping)
+		if !ok {
+			continue
+		}
+		c.conversion.Resources = append(c.conversion.Resources, resource)
+	}
+	return c.conversion
+}
+
+type converter struct {
+	options     Options
+	conversion  *Conversion
+	mappingName string
+}
+
+func describeMapping(index int, mapping Mapping) string {
+	if mapping.Name != "" {
+		return fmt.Sprintf("mapping '%s'", mapping.Name)
+	} else if mapping.Id != "" {
+		return fmt.Sprintf("mapping %s", mapping.Id)
+	}
+	return fmt.Sprintf("mapping %d", index+1)
+}
+
+func (c *converter) warnf(format string, args ...interface{}) {
+	c.conversion.Warnings = append(c.conversion.Warnings, c.mappingName+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convertMapping(mapping Mapping) (impostermodel.Resource, bool) {
+	if mapping.Response.ProxyBaseUrl != "" {
+		c.warnf("skipped - proxying to %s is not supported", mapping.Response.ProxyBaseUrl)
+		return impostermodel.Resource{}, false
+	}
+	resource := impostermodel.Resource{}
+	if method := strings.ToUpper(mapping.Request.Method); method != "" && method != "ANY" {
+		resource.Method = method
+	}
+	if err := c.convertUrl(mapping.Request, &resource); err != nil {
+		c.warnf("skipped - %v", err)
+		return impostermodel.Resource{}, false
+	}
+	c.convertParams(mapping.Request.QueryParameters, "query parameter", "queryParams", &resource)
+	c.convertParams(mapping.Request.Headers, "header", "headers", &resource)
+	c.convertBodyPatterns(mapping.Request.BodyPatterns, &resource)
+
+	if len(mapping.Request.Cookies) > 0 {
+		c.warnf("cookie matchers are not supported - ignoring")
+	}
+	if mapping.Request.BasicAuthCredentials != nil {
+		c.warnf("basic auth credentials matchers are not supported - ignoring")
+	}
+	if len(mapping.Request.MultipartPatterns) > 0 {
+		c.warnf("multipart matchers are not supported - ignoring")
+	}
+	if mapping.Request.CustomMatcher != nil {
+		c.warnf("custom matchers are not supported - ignoring")
+	}
+	if mapping.ScenarioName != "" {
+		c.warnf("scenario '%s' is not supported - the response is returned in every state", mapping.ScenarioName)
+	}
+
+	resource.Response = c.convertResponse(mapping.Response, resource.Path)
+	return resource, true
+}
+
+// convertUrl sets the path, and any query parameters, of the resource from
+// the URL matcher of the request.
+func (c *converter) convertUrl(request Request, resource *impostermodel.Resource) error {
+	switch {
+	case request.Url != "":
+		parsed, err := url.Parse(request.Url)
+		if err != nil {
+			return fmt.Errorf("invalid url: %s: %v", request.Url, err)
+		}
+		resource.Path = parsed.Path
+		if query := parsed.Query(); len(query) > 0 {
+			params := make(map[string]string)
+			for name, values := range query {
+				params[name] = values[0]
+			}
+			resource.QueryParams = &params
+		}
+	case request.UrlPath != "":
+		resource.Path = request.UrlPath
+	case request.UrlPathTemplate != "":
+		resource.Path = request.UrlPathTemplate
+	case request.UrlPathPattern != "":
+		return c.convertUrlPattern(request.UrlPathPattern, resource)
+	case request.UrlPattern != "":
+		pattern := request.UrlPattern
+		if i := strings.Index(pattern, `\?`); i >= 0 {
+			c.warnf("the query string of urlPattern %s is not matched - ignoring", pattern)
+			pattern = pattern[:i]
+		}
+		return c.convertUrlPattern(pattern, resource)
+	default:
+		resource.Path = "/*"
+	}
+	return nil
+}
+
+// convertUrlPattern converts a regular expression matching the path to an
+// Imposter path. Segments that are regular expressions become path
+// parameters, matched against the expression, and a trailing '.*' becomes
+// a wildcard.
+func (c *converter) convertUrlPattern(pattern string, resource *impostermodel.Resource) error {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	if !strings.HasPrefix(trimmed, "/") {
+		return fmt.Errorf("unsupported URL pattern: %s", pattern)
+	}
+	segments := strings.Split(strings.TrimPrefix(trimmed, "/"), "/")
+	var path []string
+	for i, segment := range segments {
+		if i == len(segments)-1 && (segment == ".*" || segment == ".+") {
+			path = append(path, "*")
+			break
+		}
+		if literal := unescapeLiteral(segment); literal != "" || segment == "" {
+			path = append(path, literal)
+			continue
+		}
+		if _, err := regexp.Compile(segment); err != nil || strings.Count(segment, "(") != strings.Count(segment, ")") {
+			return fmt.Errorf("unsupported URL pattern: %s", pattern)
+		}
+		param := fmt.Sprintf("param%d", i+1)
+		path = append(path, "{"+param+"}")
+		resource.AllOf = append(resource.AllOf, impostermodel.MatchExpression{
+			Expression: "${context.request.pathParams." + param + "}",
+			Value:      "^" + segment + "$",
+			Operator:   "Matches",
+		})
+	}
+	resource.Path = "/" + strings.Join(path, "/")
+	return nil
+}
+
+// unescapeLiteral returns the literal value of a path segment of a
+// regular expression, such as 'v1.0' for 'v1\.0', or the empty string
+// if the segment is not a literal.
+func unescapeLiteral(segment string) string {
+	var sb strings.Builder
+	for i := 0; i < len(segment); i++ {
+		ch := segment[i]
+		if ch == '\\' && i+1 < len(segment) && strings.IndexByte(`.-/_`, segment[i+1]) >= 0 {
+			sb.WriteByte(segment[i+1])
+			i++
+			continue
+		}
+		if ch == '.' || strings.IndexByte(regexMetaChars, ch) >= 0 {
+			return ""
+		}
+		sb.WriteByte(ch)
+	}
+	return sb.String()
+}
+
+// convertParams converts query parameter or header matchers. Exact matches
+// become query parameters or request headers of the resource, and other
+// matchers become expressions.
+func (c *converter) convertParams(params map[string]Pattern, kind string, contextKey string, resource *impostermodel.Resource) {
+	exact := make(map[string]string)
+	for _, name := range sortedKeys(params) {
+		operator, value, ok := convertPattern(params[name])
+		if !ok {
+			c.warnf("unsupported matcher for %s %s: %v - ignoring", kind, name, describePattern(params[name]))
+			continue
+		}
+		if operator == "EqualTo" {
+			exact[name] = value
+			continue
+		}
+		resource.AllOf = append(resource.AllOf, impostermodel.MatchExpression{
+			Expression: "${context.request." + contextKey + "." + name + "}",
+			Value:      value,
+			Operator:   operator,
+		})
+	}
+	if len(exact) == 0 {
+		return
+	}
+	switch contextKey {
+	case "queryParams":
+		if resource.QueryParams == nil {
+			resource.QueryParams = &map[string]string{}
+		}
+		for name, value := range exact {
+			(*resource.QueryParams)[name] = value
+		}
+	case "headers":
+		resource.RequestHeaders = &exact
+	}
+}
+
+// convertPattern returns the Imposter operator and value equivalent to
+// a WireMock string pattern, or false if there is none.
+func convertPattern(pattern Pattern) (operator string, value string, ok bool) {
+	caseInsensitive, _ := pattern["caseInsensitive"].(bool)
+	for key, v := range pattern {
+		s, isString := v.(string)
+		switch key {
+		case "equalTo":
+			if caseInsensitive && isString {
+				return "Matches", "(?i)^" + regexp.QuoteMeta(s) + "$", true
+			}
+			return "EqualTo", fmt.Sprint(v), true
+		case "contains":
+			return "Contains", s, isString
+		case "doesNotContain":
+			return "NotContains", s, isString
+		case "matches":
+			return "Matches", s, isString
+		case "doesNotMatch":
+			return "NotMatches", s, isString
+		case "absent":
+			if absent, _ := v.(bool); absent {
+				return "NotExists", "", true
+			}
+			return "Exists", "", true
+		}
+	}
+	return "", "", false
+}
+
+func describePattern(pattern Pattern) string {
+	var keys []string
+	for _, key := range sortedKeys(pattern) {
+		if key != "caseInsensitive" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// convertBodyPatterns converts the body patterns of the request, all of
+// which must match.
+func (c *converter) convertBodyPatterns(patterns []Pattern, resource *impostermodel.Resource) {
+	var matchers []impostermodel.RequestBody
+	for _, pattern := range patterns {
+		converted, err := convertBodyPattern(pattern)
+		if err != nil {
+			c.warnf("%v - ignoring", err)
+			continue
+		}
+		matchers = append(matchers, converted...)
+	}
+	switch len(matchers) {
+	case 0:
+		return
+	case 1:
+		resource.RequestBody = &matchers[0]
+	default:
+		resource.RequestBody = &impostermodel.RequestBody{AllOf: matchers}
+	}
+}
+
+func convertBodyPattern(pattern Pattern) ([]impostermodel.RequestBody, error) {
+	if expected, ok := pattern["equalToJson"]; ok {
+		return convertEqualToJson(expected)
+	}
+	if path, ok := pattern["matchesJsonPath"]; ok {
+		return convertPathPattern(path, false)
+	}
+	if path, ok := pattern["matchesXPath"]; ok {
+		return convertPathPattern(path, true)
+	}
+	operator, value, ok := convertPattern(pattern)
+	if !ok || operator == "Exists" || operator == "NotExists" {
+		return nil, fmt.Errorf("unsupported body pattern: %s", describePattern(pattern))
+	}
+	return []impostermodel.RequestBody{{Value: value, Operator: operator}}, nil
+}
+
+// convertPathPattern converts a 'matchesJsonPath' or 'matchesXPath' body
+// pattern, which is either an expression that must match some of the body,
+// or an object with the expression and a pattern the value must match.
+func convertPathPattern(path interface{}, xml bool) ([]impostermodel.RequestBody, error) {
+	matcher := impostermodel.RequestBody{Operator: "Exists"}
+	var expression string
+	switch p := path.(type) {
+	case string:
+		expression = p
+	case map[string]interface{}:
+		expression, _ = p["expression"].(string)
+		operator, value, ok := convertPattern(p)
+		if !ok {
+			return nil, fmt.Errorf("unsupported matcher for body path %s: %s", expression, describePattern(p))
+		}
+		matcher.Operator = operator
+		matcher.Value = value
+		if operator == "EqualTo" {
+			matcher.Operator = ""
+		}
+	}
+	if expression == "" {
+		return nil, fmt.Errorf("unsupported body path pattern: %v", path)
+	}
+	if xml {
+		matcher.XPath = expression
+	} else {
+		matcher.JsonPath = expression
+	}
+	return []impostermodel.RequestBody{matcher}, nil
+}
+
+// convertEqualToJson converts an 'equalToJson' body pattern to a JSON path
+// matcher for each field, so that the formatting of the body is ignored.
+func convertEqualToJson(expected interface{}) ([]impostermodel.RequestBody, error) {
+	if s, ok := expected.(string); ok {
+		if err := json.Unmarshal([]byte(s), &expected); err != nil {
+			return nil, fmt.Errorf("invalid equalToJson body pattern: %v", err)
+		}
+	}
+	var matchers []impostermodel.RequestBody
+	var flatten func(path string, value interface{}) error
+	flatten = func(path string, value interface{}) error {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(v) {
+				if err := flatten(path+"."+key, v[key]); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			return fmt.Errorf("unsupported equalToJson body pattern: arrays cannot be matched, at %s", path)
+		case nil:
+			matchers = append(matchers, impostermodel.RequestBody{JsonPath: path, Operator: "NotExists"})
+		default:
+			matchers = append(matchers, impostermodel.RequestBody{JsonPath: path, Value: fmt.Sprint(v)})
+		}
+		return nil
+	}
+	if err := flatten("$", expected); err != nil {
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("unsupported equalToJson body pattern: no fields to match")
+	}
+	return matchers, nil
+}
+
+func (c *converter) convertResponse(response Response, path string) *impostermodel.ResponseConfig {
+	config := &impostermodel.ResponseConfig{StatusCode: response.Status}
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusOK
+	}
+	template := c.options.GlobalTemplating || stringutil.Contains(response.Transformers, "response-template")
+	for _, transformer := range response.Transformers {
+		if transformer != "response-template" {
+			c.warnf("response transformer %s is not supported - ignoring", transformer)
+		}
+	}
+
+	headers := make(map[string]string)
+	for _, name := range sortedKeys(response.Headers) {
+		switch v := response.Headers[name].(type) {
+		case []interface{}:
+			var values []string
+			for _, value := range v {
+				values = append(values, fmt.Sprint(value))
+			}
+			headers[name] = strings.Join(values, ", ")
+		default:
+			headers[name] = fmt.Sprint(v)
+		}
+		if template {
+			headers[name] = c.convertTemplate(headers[name], path)
+		}
+	}
+
+	switch {
+	case response.BodyFileName != "":
+		body, err := os.ReadFile(filepath.Join(c.options.FilesDir, response.BodyFileName))
+		if err != nil {
+			c.warnf("failed to read body file - the response is empty: %v", err)
+			break
+		}
+		if template {
+			body = []byte(c.convertTemplate(string(body), path))
+		}
+		config.StaticFile = c.addFile(filepath.Base(response.BodyFileName), body)
+	case len(response.JsonBody) > 0:
+		var body bytes.Buffer
+		if err := json.Indent(&body, response.JsonBody, "", "  "); err != nil {
+			c.warnf("invalid jsonBody - the response is empty: %v", err)
+			break
+		}
+		config.StaticData = body.String()
+		if template {
+			config.StaticData = c.convertTemplate(config.StaticData, path)
+		}
+		if !hasHeader(headers, "Content-Type") {
+			headers["Content-Type"] = "application/json"
+		}
+	case response.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(response.Base64Body)
+		if err != nil {
+			c.warnf("invalid base64Body - the response is empty: %v", err)
+			break
+		}
+		config.StaticFile = c.addFile(slugify(c.mappingName)+"-response.bin", body)
+		template = false
+	case response.Body != "":
+		config.StaticData = response.Body
+		if template {
+			config.StaticData = c.convertTemplate(config.StaticData, path)
+		}
+	}
+	config.Template = template
+	if len(headers) > 0 {
+		config.Headers = &headers
+	}
+
+	if response.FixedDelayMilliseconds > 0 {
+		config.Delay = &impostermodel.ResponseDelay{Exact: response.FixedDelayMilliseconds}
+	} else if d := response.DelayDistribution; d != nil {
+		switch d.Type {
+		case "uniform":
+			config.Delay = &impostermodel.ResponseDelay{Min: d.Lower, Max: d.Upper}
+		case "lognormal":
+			c.warnf("lognormal delay distribution is not supported - using a fixed delay of the median")
+			config.Delay = &impostermodel.ResponseDelay{Exact: d.Median}
+		default:
+			c.warnf("delay distribution %s is not supported - ignoring", d.Type)
+		}
+	}
+	if response.ChunkedDribbleDelay != nil {
+		c.warnf("chunked dribble delay is not supported - ignoring")
+	}
+
+	if response.Fault != "" {
+		if fail, ok := faults[response.Fault]; ok {
+			config.Fail = fail
+			if response.Fault != "EMPTY_RESPONSE" && response.Fault != "CONNECTION_RESET_BY_PEER" {
+				c.warnf("fault %s is not supported - closing the connection instead", response.Fault)
+			}
+		} else {
+			c.warnf("fault %s is not supported - ignoring", response.Fault)
+		}
+	}
+	return config
+}
+
+// addFile adds a response file, returning its name. Files with the same
+// content share a file, and the name is made unique if it is in use.
+func (c *converter) addFile(name string, content []byte) string {
+	for existing, existingContent := range c.conversion.Files {
+		if bytes.Equal(existingContent, content) {
+			return existing
+		}
+	}
+	unique := name
+	ext := filepath.Ext(name)
+	for i := 2; c.conversion.Files[unique] != nil; i++ {
+		unique = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	c.conversion.Files[unique] = content
+	return unique
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// slugify converts a mapping description, such as "mapping 'Get user'",
+// to a file name prefix, such as 'mapping-get-user'.
+func slugify(name string) string {
+	var sb strings.Builder
+	for _, ch := range strings.ToLower(name) {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9', ch == '-', ch == '_':
+			sb.WriteRune(ch)
+		case ch == ' ' || ch == '/':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
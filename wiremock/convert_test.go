@@ -0,0 +1,254 @@
+package wiremock
+
+import (
+	"encoding/json"
+	"gatehill.io/imposter/impostermodel"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"testing"
+)
+
+func parseMapping(t *testing.T, content string) Mapping {
+	var mapping Mapping
+	if err := json.Unmarshal([]byte(content), &mapping); err != nil {
+		t.Fatal(err)
+	}
+	return mapping
+}
+
+func TestConvert(t *testing.T) {
+	filesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(filesDir, "user.json"), []byte(`{"id": "{{request.path.[1]}}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		mapping      string
+		want         string
+		wantFiles    map[string]string
+		wantWarnings []string
+	}{
+		{
+			name: "exact url and static body",
+			mapping: `{
+  "request": {"method": "GET", "url": "/pets?type=dog"},
+  "response": {"status": 200, "body": "woof", "headers": {"Content-Type": "text/plain"}}
+}`,
+			want: `method: GET
+path: /pets
+queryParams:
+  type: dog
+response:
+  headers:
+    Content-Type: text/plain
+  staticData: woof
+  statusCode: 200
+`,
+		},
+		{
+			name: "matchers become expressions",
+			mapping: `{
+  "request": {
+    "method": "ANY",
+    "urlPath": "/search",
+    "queryParameters": {"q": {"contains": "cat"}, "page": {"equalTo": "1"}},
+    "headers": {"Accept": {"equalTo": "application/json", "caseInsensitive": true}, "X-Debug": {"absent": true}}
+  },
+  "response": {"status": 204}
+}`,
+			want: `allOf:
+- expression: ${context.request.queryParams.q}
+  operator: Contains
+  value: cat
+- expression: ${context.request.headers.Accept}
+  operator: Matches
+  value: (?i)^application/json$
+- expression: ${context.request.headers.X-Debug}
+  operator: NotExists
+path: /search
+queryParams:
+  page: "1"
+response:
+  statusCode: 204
+`,
+		},
+		{
+			name: "url path pattern",
+			mapping: `{
+  "request": {"method": "DELETE", "urlPathPattern": "/api/v1\\.0/users/[0-9]+/.*"},
+  "response": {"status": 202}
+}`,
+			want: `allOf:
+- expression: ${context.request.pathParams.param4}
+  operator: Matches
+  value: ^[0-9]+$
+method: DELETE
+path: /api/v1.0/users/{param4}/*
+response:
+  statusCode: 202
+`,
+		},
+		{
+			name: "body patterns",
+			mapping: `{
+  "request": {
+    "method": "POST",
+    "urlPath": "/orders",
+    "bodyPatterns": [
+      {"equalToJson": "{\"item\": \"book\", \"qty\": 2}"},
+      {"matchesJsonPath": "$.customer"},
+      {"matchesJsonPath": {"expression": "$.note", "contains": "gift"}}
+    ]
+  },
+  "response": {"status": 201, "jsonBody": {"id": 1}}
+}`,
+			want: `method: POST
+path: /orders
+requestBody:
+  allOf:
+  - jsonPath: $.item
+    value: book
+  - jsonPath: $.qty
+    value: "2"
+  - jsonPath: $.customer
+    operator: Exists
+    value: ""
+  - jsonPath: $.note
+    operator: Contains
+    value: gift
+response:
+  headers:
+    Content-Type: application/json
+  staticData: |-
+    {
+      "id": 1
+    }
+  statusCode: 201
+`,
+		},
+		{
+			name: "templated body file with delay",
+			mapping: `{
+  "name": "Get user",
+  "request": {"method": "GET", "urlPathTemplate": "/users/{id}"},
+  "response": {
+    "bodyFileName": "user.json",
+    "transformers": ["response-template"],
+    "headers": {"X-Request-Id": "{{request.headers.X-Request-Id}}"},
+    "delayDistribution": {"type": "uniform", "lower": 10, "upper": 50}
+  }
+}`,
+			want: `method: GET
+path: /users/{id}
+response:
+  delay:
+    max: 50
+    min: 10
+  headers:
+    X-Request-Id: ${context.request.headers.X-Request-Id}
+  staticFile: user.json
+  statusCode: 200
+  template: true
+`,
+			wantFiles: map[string]string{"user.json": `{"id": "${context.request.pathParams.id}"}`},
+		},
+		{
+			name: "unsupported features",
+			mapping: `{
+  "name": "Flaky",
+  "scenarioName": "retry",
+  "request": {"method": "GET", "urlPath": "/flaky", "cookies": {"session": {"equalTo": "abc"}}, "headers": {"X-Date": {"before": "now"}}},
+  "response": {"fault": "RANDOM_DATA_THEN_CLOSE"}
+}`,
+			want: `method: GET
+path: /flaky
+response:
+  fail: CloseConnection
+  statusCode: 200
+`,
+			wantWarnings: []string{
+				"mapping 'Flaky': unsupported matcher for header X-Date: before - ignoring",
+				"mapping 'Flaky': cookie matchers are not supported - ignoring",
+				"mapping 'Flaky': scenario 'retry' is not supported - the response is returned in every state",
+				"mapping 'Flaky': fault RANDOM_DATA_THEN_CLOSE is not supported - closing the connection instead",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion := Convert([]Mapping{parseMapping(t, tt.mapping)}, Options{FilesDir: filesDir})
+			if len(conversion.Resources) != 1 {
+				t.Fatalf("Convert() returned %d resources, want 1", len(conversion.Resources))
+			}
+			got, err := yaml.Marshal(conversion.Resources[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Convert() resource =\n%s\nwant\n%s", got, tt.want)
+			}
+			files := make(map[string]string)
+			for name, content := range conversion.Files {
+				files[name] = string(content)
+			}
+			if len(files) != 0 || tt.wantFiles != nil {
+				if !reflect.DeepEqual(files, tt.wantFiles) {
+					t.Errorf("Convert() files = %v, want %v", files, tt.wantFiles)
+				}
+			}
+			if !reflect.DeepEqual(conversion.Warnings, tt.wantWarnings) {
+				t.Errorf("Convert() warnings = %v, want %v", conversion.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestConvert_skipsProxyMappings(t *testing.T) {
+	mappings := []Mapping{
+		parseMapping(t, `{"request": {"urlPath": "/proxied"}, "response": {"proxyBaseUrl": "https://example.com"}}`),
+		parseMapping(t, `{"request": {}, "response": {"body": "fallback"}}`),
+	}
+	conversion := Convert(mappings, Options{})
+	want := []impostermodel.Resource{{
+		Path:     "/*",
+		Response: &impostermodel.ResponseConfig{StatusCode: 200, StaticData: "fallback"},
+	}}
+	if !reflect.DeepEqual(conversion.Resources, want) {
+		t.Errorf("Convert() resources = %+v, want %+v", conversion.Resources, want)
+	}
+	if len(conversion.Warnings) != 1 || !strings.Contains(conversion.Warnings[0], "proxying to https://example.com is not supported") {
+		t.Errorf("Convert() warnings = %v", conversion.Warnings)
+	}
+}
+
+func TestLoadMappings(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.json":        `{"request": {"urlPath": "/a"}, "response": {}}`,
+		"nested/b.json": `{"mappings": [{"request": {"urlPath": "/b"}, "response": {}}, {"request": {"urlPath": "/c"}, "response": {}}]}`,
+		"readme.txt":    "not a mapping",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mappings, err := LoadMappings(dir)
+	if err != nil {
+		t.Fatalf("LoadMappings() error = %v", err)
+	}
+	var paths []string
+	for _, mapping := range mappings {
+		paths = append(paths, mapping.Request.UrlPath)
+	}
+	if want := []string{"/a", "/b", "/c"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("LoadMappings() paths = %v, want %v", paths, want)
+	}
+}
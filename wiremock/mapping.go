@@ -0,0 +1,150 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiremock
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/logging"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var logger = logging.GetLogger()
+
+// Mapping is a WireMock stub mapping.
+type Mapping struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+
+	ScenarioName string `json:"scenarioName"`
+}
+
+// Request holds the request matchers of a stub mapping. At most one of
+// the URL fields is set; if none are, any URL matches.
+type Request struct {
+	Method          string `json:"method"`
+	Url             string `json:"url"`
+	UrlPath         string `json:"urlPath"`
+	UrlPattern      string `json:"urlPattern"`
+	UrlPathPattern  string `json:"urlPathPattern"`
+	UrlPathTemplate string `json:"urlPathTemplate"`
+
+	QueryParameters map[string]Pattern `json:"queryParameters"`
+	Headers         map[string]Pattern `json:"headers"`
+	BodyPatterns    []Pattern          `json:"bodyPatterns"`
+
+	// these matchers have no equivalent
+	Cookies              map[string]Pattern `json:"cookies"`
+	BasicAuthCredentials interface{}        `json:"basicAuthCredentials"`
+	MultipartPatterns    []interface{}      `json:"multipartPatterns"`
+	CustomMatcher        interface{}        `json:"customMatcher"`
+}
+
+// Pattern is a WireMock content pattern, such as {"equalTo": "foo"},
+// with any options, such as {"caseInsensitive": true}.
+type Pattern map[string]interface{}
+
+// Response is the response definition of a stub mapping.
+type Response struct {
+	Status                 int                    `json:"status"`
+	Headers                map[string]interface{} `json:"headers"`
+	Body                   string                 `json:"body"`
+	JsonBody               json.RawMessage        `json:"jsonBody"`
+	Base64Body             string                 `json:"base64Body"`
+	BodyFileName           string                 `json:"bodyFileName"`
+	FixedDelayMilliseconds int                    `json:"fixedDelayMilliseconds"`
+	DelayDistribution      *DelayDistribution     `json:"delayDistribution"`
+	ChunkedDribbleDelay    interface{}            `json:"chunkedDribbleDelay"`
+	Fault                  string                 `json:"fault"`
+	ProxyBaseUrl           string                 `json:"proxyBaseUrl"`
+	Transformers           []string               `json:"transformers"`
+}
+
+// DelayDistribution is a random delay, in milliseconds.
+type DelayDistribution struct {
+	Type   string `json:"type"`
+	Lower  int    `json:"lower"`
+	Upper  int    `json:"upper"`
+	Median int    `json:"median"`
+}
+
+// LoadMappings reads the stub mappings from a JSON file, or from the
+// JSON files in a directory and its subdirectories, such as WireMock's
+// 'mappings' directory. Each file holds a single mapping, or a
+// 'mappings' array of them.
+func LoadMappings(path string) ([]Mapping, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WireMock mappings: %v", err)
+	}
+	var files []string
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".json") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list WireMock mappings in: %s: %v", path, err)
+		}
+		sort.Strings(files)
+	} else {
+		files = []string{path}
+	}
+
+	var mappings []Mapping
+	for _, file := range files {
+		fileMappings, err := loadMappingFile(file)
+		if err != nil {
+			return nil, err
+		}
+		logger.Tracef("read %d mapping(s) from: %s", len(fileMappings), file)
+		mappings = append(mappings, fileMappings...)
+	}
+	return mappings, nil
+}
+
+func loadMappingFile(file string) ([]Mapping, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WireMock mapping file: %s: %v", file, err)
+	}
+	var wrapper struct {
+		Mappings *[]Mapping `json:"mappings"`
+	}
+	if err := json.Unmarshal(content, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse WireMock mapping file: %s: %v", file, err)
+	}
+	if wrapper.Mappings != nil {
+		return *wrapper.Mappings, nil
+	}
+	var mapping Mapping
+	if err := json.Unmarshal(content, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse WireMock mapping file: %s: %v", file, err)
+	}
+	return []Mapping{mapping}, nil
+}
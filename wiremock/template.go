@@ -0,0 +1,202 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiremock
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var handlebarsPattern = regexp.MustCompile(`\{\{\{?\s*(.*?)\s*\}?\}\}`)
+
+var segmentIndexPattern = regexp.MustCompile(`^\[(\d+)]$`)
+
+// randomValueFunctions maps the types of the WireMock 'randomValue'
+// helper to Imposter random functions.
+var randomValueFunctions = map[string]string{
+	"ALPHABETIC":   "alphabetic",
+	"ALPHANUMERIC": "alphanumeric",
+	"NUMERIC":      "numeric",
+}
+
+// convertTemplate converts the Handlebars expressions in a WireMock
+// response template to Imposter placeholders. The path is the Imposter
+// path of the resource, used to convert references to path segments.
+// Expressions without an equivalent, such as block helpers, are left
+// in place, with a warning.
+func (c *converter) convertTemplate(template string, path string) string {
+	return handlebarsPattern.ReplaceAllStringFunc(template, func(match string) string {
+		expression := handlebarsPattern.FindStringSubmatch(match)[1]
+		if placeholder, ok := convertExpression(expression, path); ok {
+			return placeholder
+		}
+		c.warnf("template expression %s is not supported - leaving it in place", match)
+		return match
+	})
+}
+
+func convertExpression(expression string, path string) (string, bool) {
+	args := splitArgs(expression)
+	if len(args) == 0 {
+		return "", false
+	}
+	switch args[0] {
+	case "request.url":
+		return "${context.request.uri}", len(args) == 1
+	case "request.path":
+		return "${context.request.path}", len(args) == 1
+	case "request.method":
+		return "${context.request.method}", len(args) == 1
+	case "request.body":
+		return "${context.request.body}", len(args) == 1
+	case "jsonPath":
+		if len(args) == 3 && args[1] == "request.body" {
+			return "${context.request.body:" + unquote(args[2]) + "}", true
+		}
+		return "", false
+	case "now":
+		options := parseOptions(args[1:])
+		switch {
+		case len(options) == 0:
+			return "${datetime.now.iso8601_datetime}", true
+		case len(options) == 1 && options["format"] == "epoch":
+			return "${datetime.now.millis}", true
+		}
+		return "", false
+	case "randomValue":
+		options := parseOptions(args[1:])
+		if options["type"] == "UUID" {
+			return "${random.uuid()}", true
+		}
+		if function, ok := randomValueFunctions[options["type"]]; ok {
+			length := options["length"]
+			if length == "" {
+				length = "1"
+			}
+			return "${random." + function + "(length=" + length + ")}", true
+		}
+		return "", false
+	}
+	if len(args) != 1 {
+		return "", false
+	}
+	if name, ok := cutField(args[0], "request.query."); ok {
+		return "${context.request.queryParams." + name + "}", true
+	}
+	if name, ok := cutField(args[0], "request.headers."); ok {
+		return "${context.request.headers." + name + "}", true
+	}
+	for _, prefix := range []string{"request.pathSegments.", "request.path."} {
+		if segment, ok := strings.CutPrefix(args[0], prefix); ok {
+			return convertPathSegment(segment, path)
+		}
+	}
+	return "", false
+}
+
+// convertPathSegment converts a reference to a segment of the request
+// path, either by index, such as '[1]', or by the name of a parameter
+// of a path template.
+func convertPathSegment(segment string, path string) (string, bool) {
+	match := segmentIndexPattern.FindStringSubmatch(segment)
+	if match == nil {
+		if strings.ContainsAny(segment, ".[]") {
+			return "", false
+		}
+		return "${context.request.pathParams." + segment + "}", true
+	}
+	index, _ := strconv.Atoi(match[1])
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if index >= len(segments) || segments[index] == "*" {
+		return "", false
+	}
+	if param, ok := strings.CutPrefix(segments[index], "{"); ok {
+		return "${context.request.pathParams." + strings.TrimSuffix(param, "}") + "}", true
+	}
+	return segments[index], true
+}
+
+// cutField returns the name of a query parameter or header, such as
+// 'id' for 'request.query.id', 'request.query.id.[0]' or
+// 'request.query.[id]'. Only the first value is supported.
+func cutField(expression string, prefix string) (string, bool) {
+	name, ok := strings.CutPrefix(expression, prefix)
+	if !ok {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, ".[0]")
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		name = name[1 : len(name)-1]
+	}
+	if name == "" || strings.ContainsAny(name, "[]") {
+		return "", false
+	}
+	return name, true
+}
+
+// splitArgs splits a Handlebars expression into its helper, or path,
+// and arguments, keeping quoted arguments together.
+func splitArgs(expression string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	for _, ch := range expression {
+		switch {
+		case quote != 0:
+			current.WriteRune(ch)
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			current.WriteRune(ch)
+		case ch == ' ':
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// parseOptions returns the hash arguments of a helper, such as
+// type='UUID', or nil if any argument is not a hash argument.
+func parseOptions(args []string) map[string]string {
+	options := make(map[string]string)
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return map[string]string{"": arg}
+		}
+		options[key] = unquote(value)
+	}
+	return options
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
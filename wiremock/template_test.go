@@ -0,0 +1,62 @@
+package wiremock
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_convertTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		template     string
+		path         string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name:     "request fields",
+			template: "{{request.method}} {{request.path}} {{{request.body}}}",
+			path:     "/",
+			want:     "${context.request.method} ${context.request.path} ${context.request.body}",
+		},
+		{
+			name:     "query and headers",
+			template: "{{request.query.id}} {{request.query.[page].[0]}} {{request.headers.Accept}}",
+			path:     "/",
+			want:     "${context.request.queryParams.id} ${context.request.queryParams.page} ${context.request.headers.Accept}",
+		},
+		{
+			name:     "path segments",
+			template: "{{request.path.[0]}}/{{request.pathSegments.[1]}}/{{request.path.id}}",
+			path:     "/users/{param2}",
+			want:     "users/${context.request.pathParams.param2}/${context.request.pathParams.id}",
+		},
+		{
+			name:     "helpers",
+			template: `{{jsonPath request.body '$.name'}} {{now}} {{now format='epoch'}} {{randomValue type='UUID'}} {{randomValue length=8 type='ALPHANUMERIC'}}`,
+			path:     "/",
+			want:     "${context.request.body:$.name} ${datetime.now.iso8601_datetime} ${datetime.now.millis} ${random.uuid()} ${random.alphanumeric(length=8)}",
+		},
+		{
+			name:     "unsupported",
+			template: "{{#if request.body}}yes{{/if}}",
+			path:     "/",
+			want:     "{{#if request.body}}yes{{/if}}",
+			wantWarnings: []string{
+				"mapping 1: template expression {{#if request.body}} is not supported - leaving it in place",
+				"mapping 1: template expression {{/if}} is not supported - leaving it in place",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &converter{conversion: &Conversion{}, mappingName: "mapping 1"}
+			if got := c.convertTemplate(tt.template, tt.path); got != tt.want {
+				t.Errorf("convertTemplate() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(c.conversion.Warnings, tt.wantWarnings) {
+				t.Errorf("convertTemplate() warnings = %v, want %v", c.conversion.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
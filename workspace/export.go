@@ -0,0 +1,80 @@
+package workspace
+
+import (
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/stringutil"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export creates a ZIP archive of the workspace directory, containing the
+// configuration and response files, and the workspace metadata. Other hidden
+// files, the recording library, and any of the excluded paths, such as the
+// destination of the archive, are not included.
+func Export(dir string, excludePaths ...string) ([]byte, error) {
+	files, err := listExportFiles(dir, excludePaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export workspace: %s", err)
+	}
+	archive, err := fileutil.ZipFiles(dir, files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export workspace: %s", err)
+	}
+	logger.Tracef("exported %d files from: %s", len(files), dir)
+	return archive, nil
+}
+
+// Import extracts an archive created by Export into the workspace
+// directory, returning the names of the files imported. Unless force
+// is set, existing files are not overwritten.
+func Import(dir string, archive []byte, force bool) ([]string, error) {
+	names, err := fileutil.ListZip(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import workspace: %s", err)
+	}
+	if !force {
+		var existing []string
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+				existing = append(existing, name)
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("files already exist in %s: %s", dir, strings.Join(existing, ", "))
+		}
+	}
+	if err := fileutil.ExtractZip(archive, dir); err != nil {
+		return nil, fmt.Errorf("failed to import workspace: %s", err)
+	}
+	logger.Tracef("imported %d files into: %s", len(names), dir)
+	return names, nil
+}
+
+func listExportFiles(dir string, excludePaths []string) ([]string, error) {
+	recordingsDir := filepath.Join(dir, metaDirName, recordingsDirName)
+	gitSyncDir := filepath.Join(dir, metaDirName, gitSyncDirName)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		hidden := strings.HasPrefix(d.Name(), ".") && d.Name() != metaDirName
+		if d.IsDir() {
+			if hidden || path == recordingsDir || path == gitSyncDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hidden && d.Type().IsRegular() && !stringutil.Contains(excludePaths, path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
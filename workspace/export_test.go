@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"petstore-config.yaml":               "plugin: openapi\n",
+		"responses/pets.json":                "[]",
+		".imposter/workspaces.json":          `{"workspaces":[{"name":"test"}],"active":"test"}`,
+		".imposter/recordings/abc/file.json": "{}",
+		".git/config":                        "",
+		".DS_Store":                          "",
+		"test.zip":                           "",
+	})
+	if _, err := New(src, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := Export(src, filepath.Join(src, "test.zip"))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	imported, err := Import(dest, archive, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	sort.Strings(imported)
+	want := []string{".imposter/workspaces.json", "petstore-config.yaml", "responses/pets.json"}
+	if !reflect.DeepEqual(imported, want) {
+		t.Errorf("Import() = %v, want %v", imported, want)
+	}
+	if active, err := GetActive(dest); err != nil || active == nil || active.Name != "test" {
+		t.Errorf("GetActive() = %v, %v, want test", active, err)
+	}
+
+	_, err = Import(dest, archive, false)
+	if err == nil || !strings.Contains(err.Error(), "petstore-config.yaml") {
+		t.Errorf("Import() without force error = %v, want existing file error", err)
+	}
+	if _, err = Import(dest, archive, true); err != nil {
+		t.Errorf("Import() with force error = %v", err)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
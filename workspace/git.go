@@ -0,0 +1,110 @@
+package workspace
+
+import (
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitSyncDirName is the directory, within the metadata directory, holding
+// the synced copies of git-backed workspaces.
+const gitSyncDirName = "git"
+
+const defaultGitRef = "HEAD"
+
+// GetGitSyncDir returns the directory into which the git repository of
+// the workspace is synced. It returns an error if the workspace name
+// would place the directory outside the git sync directory.
+func GetGitSyncDir(dir string, w *Workspace) (string, error) {
+	gitRoot := filepath.Join(dir, metaDirName, gitSyncDirName)
+	syncDir := filepath.Join(gitRoot, w.Name)
+	if !strings.HasPrefix(syncDir, gitRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid workspace name: %s - sync directory must be within: %s", w.Name, gitRoot)
+	}
+	return syncDir, nil
+}
+
+// IsGitSynced returns true if the git repository of the workspace has
+// been pulled into its sync directory.
+func IsGitSynced(dir string, w *Workspace) bool {
+	syncDir, err := GetGitSyncDir(dir, w)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(syncDir, ".git"))
+	return err == nil
+}
+
+// Pull fetches the configured ref of the git repository of the workspace
+// and checks it out into the sync directory, discarding any local changes.
+// It returns the sync directory and the revision checked out.
+func Pull(dir string, w *Workspace) (syncDir string, revision string, err error) {
+	if w.GitUrl == "" {
+		return "", "", fmt.Errorf("workspace '%s' does not reference a git repository - set %s with 'imposter workspace config'", w.Name, SettingGitUrl)
+	}
+	ref := stringutil.GetFirstNonEmpty(w.GitRef, defaultGitRef)
+	if err = validateGitArg(SettingGitUrl, w.GitUrl); err != nil {
+		return "", "", err
+	} else if err = validateGitArg(SettingGitRef, ref); err != nil {
+		return "", "", err
+	}
+
+	if syncDir, err = GetGitSyncDir(dir, w); err != nil {
+		return "", "", err
+	}
+	if IsGitSynced(dir, w) {
+		if _, err = runGit(syncDir, "remote", "set-url", "--", "origin", w.GitUrl); err != nil {
+			return "", "", err
+		}
+	} else {
+		if err = os.MkdirAll(syncDir, 0700); err != nil {
+			return "", "", fmt.Errorf("failed to create sync directory: %s: %s", syncDir, err)
+		}
+		if _, err = runGit(syncDir, "init", "--quiet"); err != nil {
+			return "", "", err
+		}
+		if _, err = runGit(syncDir, "remote", "add", "--", "origin", w.GitUrl); err != nil {
+			return "", "", err
+		}
+	}
+
+	logger.Debugf("fetching %s from %s", ref, w.GitUrl)
+	if _, err = runGit(syncDir, "fetch", "--quiet", "--depth", "1", "--", "origin", ref); err != nil {
+		return "", "", err
+	}
+	if _, err = runGit(syncDir, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return "", "", err
+	}
+	if _, err = runGit(syncDir, "clean", "--quiet", "-fdx"); err != nil {
+		return "", "", err
+	}
+	revision, err = runGit(syncDir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	logger.Tracef("synced workspace %s to revision %s in: %s", w.Name, revision, syncDir)
+	return syncDir, revision, nil
+}
+
+// validateGitArg rejects a git URL or ref that git would parse as an
+// option, such as '--upload-pack=<command>', as workspace metadata may
+// come from an imported archive.
+func validateGitArg(setting string, value string) error {
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("invalid %s: %s - must not start with '-'", setting, value)
+	}
+	return nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
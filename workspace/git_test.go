@@ -0,0 +1,144 @@
+package workspace
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestPull(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := t.TempDir()
+	commitFile(t, repoDir, "imposter-config.yaml", "plugin: rest\n")
+
+	dir := t.TempDir()
+	if _, err := New(dir, "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Pull(dir, &Workspace{Name: "shared"}); err == nil {
+		t.Errorf("expected error pulling workspace without git URL")
+	}
+	w, err := SetSetting(dir, SettingGitUrl, repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncDir, revision, err := Pull(dir, w)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if revision == "" {
+		t.Errorf("expected revision to be returned")
+	}
+	assertFileContent(t, filepath.Join(syncDir, "imposter-config.yaml"), "plugin: rest\n")
+
+	// local changes are discarded, and new commits are checked out
+	if err := os.WriteFile(filepath.Join(syncDir, "local.txt"), []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repoDir, "imposter-config.yaml", "plugin: openapi\n")
+	if _, _, err = Pull(dir, w); err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	assertFileContent(t, filepath.Join(syncDir, "imposter-config.yaml"), "plugin: openapi\n")
+	if _, err := os.Stat(filepath.Join(syncDir, "local.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected local file to be removed, stat error: %v", err)
+	}
+
+	if err = Delete(dir, "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if IsGitSynced(dir, w) {
+		t.Errorf("expected synced copy to be removed with workspace")
+	}
+}
+
+func TestPull_rejectsOptionArgs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := t.TempDir()
+	commitFile(t, repoDir, "imposter-config.yaml", "plugin: rest\n")
+	marker := filepath.Join(t.TempDir(), "pwned")
+
+	tests := []struct {
+		name string
+		w    *Workspace
+	}{
+		{name: "ref", w: &Workspace{Name: "shared", GitUrl: repoDir, GitRef: "--upload-pack=touch " + marker}},
+		{name: "url", w: &Workspace{Name: "shared", GitUrl: "--upload-pack=touch " + marker}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, _, err := Pull(dir, tt.w); err == nil {
+				t.Errorf("expected error pulling workspace with %s starting with '-'", tt.name)
+			}
+			if _, err := os.Stat(marker); !os.IsNotExist(err) {
+				t.Errorf("expected upload-pack command not to run, stat error: %v", err)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	if _, err := New(dir, "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetSetting(dir, SettingGitRef, "--upload-pack=touch "+marker); err == nil {
+		t.Errorf("expected error setting git ref starting with '-'")
+	}
+}
+
+func TestGetGitSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "shared", want: filepath.Join(dir, metaDirName, gitSyncDirName, "shared")},
+		{name: "", wantErr: true},
+		{name: ".", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "../../other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetGitSyncDir(dir, &Workspace{Name: tt.name})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetGitSyncDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetGitSyncDir() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func commitFile(t *testing.T, repoDir string, name string, content string) {
+	if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", name},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "update " + name},
+	} {
+		if _, err := runGit(repoDir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertFileContent(t *testing.T, path string, want string) {
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("%s = %q, want %q", path, got, want)
+	}
+}
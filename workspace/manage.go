@@ -3,16 +3,20 @@ package workspace
 import (
 	"fmt"
 	"gatehill.io/imposter/logging"
+	"os"
+	"path/filepath"
 	"regexp"
 )
 
 const namePattern = "[a-zA-Z0-9_-]+"
 
+var nameRegexp = regexp.MustCompile("^" + namePattern + "$")
+
 var logger = logging.GetLogger()
 
 func New(dir string, name string) (*Workspace, error) {
-	if match, _ := regexp.MatchString("^"+namePattern+"$", name); !match {
-		return nil, fmt.Errorf("workspace name does not match pattern: %s", namePattern)
+	if err := validateName(name); err != nil {
+		return nil, err
 	}
 
 	m, err := createOrLoadMetadata(dir)
@@ -37,6 +41,7 @@ func Delete(dir string, name string) error {
 		return fmt.Errorf("workspace '%s' does not exist", name)
 	}
 	if m.Active == name {
+		logger.Debugf("deleting active workspace: %s - no workspace will be active", name)
 		m.Active = ""
 	}
 	var modified []*Workspace
@@ -50,10 +55,28 @@ func Delete(dir string, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete workspace: %s", err)
 	}
+	removeRemoteConfig(dir, w)
+	if syncDir, err := GetGitSyncDir(dir, w); err != nil {
+		logger.Warnf("failed to remove synced copy of workspace: %s: %s", name, err)
+	} else if err = os.RemoveAll(syncDir); err != nil {
+		logger.Warnf("failed to remove synced copy of workspace: %s: %s", name, err)
+	}
 	logger.Tracef("deleted workspace: %s", name)
 	return nil
 }
 
+// removeRemoteConfig removes the remote configuration file of the
+// workspace, if it exists.
+func removeRemoteConfig(dir string, w *Workspace) {
+	if w.RemoteType == "" {
+		return
+	}
+	remoteFilePath := filepath.Join(dir, metaDirName, fmt.Sprintf("%s_%s.json", w.RemoteType, w.Name))
+	if err := os.Remove(remoteFilePath); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("failed to remove remote config file: %s: %s", remoteFilePath, err)
+	}
+}
+
 func SetActive(dir string, name string) (*Workspace, error) {
 	m, err := createOrLoadMetadata(dir)
 	if err != nil {
@@ -89,7 +112,9 @@ func GetActiveWithMetadata(dir string) (*Workspace, *Metadata, error) {
 	}
 	w := getWorkspace(m.Workspaces, m.Active)
 	if w == nil {
-		return nil, nil, fmt.Errorf("active workspace: %s does not exist", m.Active)
+		// the metadata may have been edited by hand, or merged from another copy
+		logger.Warnf("active workspace '%s' does not exist - select another workspace", m.Active)
+		return nil, nil, nil
 	}
 	logger.Tracef("active workspace is: %s [%s]", w.Name, w.RemoteType)
 	return w, m, nil
@@ -103,6 +128,17 @@ func List(dir string) ([]*Workspace, error) {
 	return m.Workspaces, nil
 }
 
+// validateName returns an error if the workspace name does not match
+// namePattern. The name is used in file paths, so this is checked both
+// when a workspace is created and when the metadata is loaded, as the
+// metadata may have been edited by hand or come from an imported archive.
+func validateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("workspace name '%s' does not match pattern: %s", name, namePattern)
+	}
+	return nil
+}
+
 func getWorkspace(workspaces []*Workspace, name string) *Workspace {
 	for _, workspace := range workspaces {
 		if workspace.Name == name {
@@ -0,0 +1,102 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"first", "second"} {
+		if _, err := New(dir, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remoteFile := filepath.Join(dir, metaDirName, "cloudmocks_first.json")
+	if err := os.WriteFile(remoteFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Delete(dir, "first"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(remoteFile); !os.IsNotExist(err) {
+		t.Errorf("expected remote config file to be removed, stat error: %v", err)
+	}
+	active, err := GetActive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active != nil {
+		t.Errorf("expected no active workspace, got: %s", active.Name)
+	}
+	workspaces, _ := List(dir)
+	if len(workspaces) != 1 || workspaces[0].Name != "second" {
+		t.Errorf("expected only workspace 'second' to remain, got: %v", workspaces)
+	}
+
+	if err := Delete(dir, "first"); err == nil {
+		t.Errorf("expected error deleting missing workspace")
+	}
+}
+
+func TestSetActive(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetActive(dir, "missing"); err == nil {
+		t.Errorf("expected error selecting missing workspace")
+	}
+	active, err := GetActive(dir)
+	if err != nil || active == nil || active.Name != "first" {
+		t.Errorf("expected active workspace to be unchanged, got: %v, error: %v", active, err)
+	}
+}
+
+func TestGetActive_danglingPointer(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, "first"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := createOrLoadMetadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Active = "removed"
+	if err := SaveMetadata(dir, m); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := GetActive(dir)
+	if err != nil {
+		t.Fatalf("GetActive() error = %v", err)
+	}
+	if active != nil {
+		t.Errorf("expected no active workspace, got: %s", active.Name)
+	}
+	if _, err := SetActive(dir, "first"); err != nil {
+		t.Fatalf("SetActive() error = %v", err)
+	}
+	if active, _ := GetActive(dir); active == nil || active.Name != "first" {
+		t.Errorf("expected active workspace 'first', got: %v", active)
+	}
+}
+
+func TestList_invalidName(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := EnsureMetadataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	metadata := `{"workspaces":[{"name":"../../escape","remoteType":"cloudmocks"}],"active":"../../escape"}`
+	if err := os.WriteFile(filepath.Join(dir, metaDirName, metaFileName), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := List(dir); err == nil {
+		t.Errorf("expected error loading workspace with invalid name")
+	}
+	if err := Delete(dir, "../../escape"); err == nil {
+		t.Errorf("expected error deleting workspace with invalid name")
+	}
+}
@@ -3,6 +3,7 @@ package workspace
 import (
 	"encoding/json"
 	"fmt"
+	"gatehill.io/imposter/fileutil"
 	"gatehill.io/imposter/library"
 	"os"
 	"path/filepath"
@@ -11,6 +12,18 @@ import (
 type Workspace struct {
 	Name       string `json:"name"`
 	RemoteType string `json:"remoteType"`
+
+	// EngineType, EngineVersion, Port and Environment are used when
+	// starting a mock in the workspace directory, unless overridden.
+	EngineType    string            `json:"engineType,omitempty"`
+	EngineVersion string            `json:"engineVersion,omitempty"`
+	Port          int               `json:"port,omitempty"`
+	Environment   map[string]string `json:"environment,omitempty"`
+
+	// GitUrl and GitRef identify a git repository holding the shared
+	// configuration of the workspace, synced by 'imposter workspace pull'.
+	GitUrl string `json:"gitUrl,omitempty"`
+	GitRef string `json:"gitRef,omitempty"`
 }
 
 type Metadata struct {
@@ -19,6 +32,7 @@ type Metadata struct {
 }
 
 const metaDirName = ".imposter"
+const metaFileName = "workspaces.json"
 
 func createOrLoadMetadata(dir string) (m *Metadata, err error) {
 	metaFilePath, err := getMetaFilePath(dir)
@@ -41,6 +55,11 @@ func createOrLoadMetadata(dir string) (m *Metadata, err error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshall workspace file: %s: %s", metaFilePath, err)
 		}
+		for _, w := range m.Workspaces {
+			if err := validateName(w.Name); err != nil {
+				return nil, fmt.Errorf("invalid workspace file: %s: %s", metaFilePath, err)
+			}
+		}
 	}
 	return m, nil
 }
@@ -62,7 +81,7 @@ func SaveMetadata(dir string, m *Metadata) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshall metadata: %s", err)
 	}
-	err = os.WriteFile(metaFilePath, j, 0644)
+	err = fileutil.WriteFileAtomic(metaFilePath, j, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to save metadata to: %s: %s", metaFilePath, err)
 	}
@@ -74,6 +93,6 @@ func getMetaFilePath(dir string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	metaFilePath := filepath.Join(metaDir, "workspaces.json")
+	metaFilePath := filepath.Join(metaDir, metaFileName)
 	return metaFilePath, nil
 }
@@ -0,0 +1,230 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/library"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const recordingsDirName = "recordings"
+const recordingMetaFileName = "recording.json"
+const recordingFilesDirName = "files"
+
+const (
+	TagService  = "service"
+	TagDate     = "date"
+	TagScenario = "scenario"
+)
+
+// Recording is a recorded proxy session stored in the workspace library.
+type Recording struct {
+	ID        string            `json:"id"`
+	Workspace string            `json:"workspace,omitempty"`
+	Upstream  string            `json:"upstream"`
+	Created   time.Time         `json:"created"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// NewRecording creates an empty recording in the library, tagged with the
+// service and date, in addition to the given tags. It returns the recording
+// and the directory into which its files should be written.
+func NewRecording(dir string, upstream string, tags map[string]string) (*Recording, string, error) {
+	now := time.Now()
+	r := &Recording{
+		Upstream: upstream,
+		Created:  now,
+		Tags:     make(map[string]string),
+	}
+	if active, err := GetActive(dir); err != nil {
+		return nil, "", err
+	} else if active != nil {
+		r.Workspace = active.Name
+	}
+	if u, err := url.Parse(upstream); err == nil && u.Host != "" {
+		r.Tags[TagService] = u.Host
+	}
+	r.Tags[TagDate] = now.Format(time.DateOnly)
+	for k, v := range tags {
+		r.Tags[k] = v
+	}
+
+	recordingDir, err := createRecordingDir(dir, r, now)
+	if err != nil {
+		return nil, "", err
+	}
+	filesDir := filepath.Join(recordingDir, recordingFilesDirName)
+	if err := library.EnsureDir(filesDir); err != nil {
+		return nil, "", fmt.Errorf("failed to create recording directory: %s: %s", filesDir, err)
+	}
+	if err := saveRecording(recordingDir, r); err != nil {
+		return nil, "", err
+	}
+	logger.Tracef("created recording: %s", r.ID)
+	return r, filesDir, nil
+}
+
+// createRecordingDir creates the directory for a new recording, setting
+// its ID from the creation time. If another recording was created in the
+// same second, a counter is appended to the ID, such as '20240102-150405-2'.
+func createRecordingDir(dir string, r *Recording, created time.Time) (string, error) {
+	recordingsDir, err := getRecordingsDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := library.EnsureDir(recordingsDir); err != nil {
+		return "", fmt.Errorf("failed to create recordings directory: %s: %s", recordingsDir, err)
+	}
+	base := created.Format("20060102-150405")
+	for i := 1; ; i++ {
+		r.ID = base
+		if i > 1 {
+			r.ID = fmt.Sprintf("%s-%d", base, i)
+		}
+		recordingDir := filepath.Join(recordingsDir, r.ID)
+		if err := os.Mkdir(recordingDir, 0700); err == nil {
+			return recordingDir, nil
+		} else if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create recording directory: %s: %s", recordingDir, err)
+		}
+	}
+}
+
+// ListRecordings returns all recordings in the library, oldest first.
+func ListRecordings(dir string) ([]*Recording, error) {
+	recordingsDir, err := getRecordingsDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(recordingsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list recordings: %s: %s", recordingsDir, err)
+	}
+
+	var recordings []*Recording
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		r, err := loadRecording(filepath.Join(recordingsDir, entry.Name()))
+		if err != nil {
+			logger.Warn(err)
+			continue
+		}
+		recordings = append(recordings, r)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].Created.Before(recordings[j].Created)
+	})
+	return recordings, nil
+}
+
+// SearchRecordings returns the recordings having all the given tags, whose
+// ID, workspace, upstream or tag values contain the query, if not empty.
+func SearchRecordings(dir string, query string, tags map[string]string) ([]*Recording, error) {
+	recordings, err := ListRecordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Recording
+	for _, r := range recordings {
+		if r.hasTags(tags) && (query == "" || r.contains(query)) {
+			matches = append(matches, r)
+		}
+	}
+	return matches, nil
+}
+
+// RestoreRecording copies the files of the recording with the given ID
+// into the destination directory.
+func RestoreRecording(dir string, id string, dest string) (*Recording, error) {
+	recordingDir, err := getRecordingDir(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := loadRecording(recordingDir)
+	if err != nil {
+		return nil, fmt.Errorf("recording '%s' does not exist: %s", id, err)
+	}
+	if err := fileutil.CopyDir(filepath.Join(recordingDir, recordingFilesDirName), dest); err != nil {
+		return nil, fmt.Errorf("failed to restore recording '%s': %s", id, err)
+	}
+	logger.Tracef("restored recording %s to: %s", id, dest)
+	return r, nil
+}
+
+func (r *Recording) hasTags(tags map[string]string) bool {
+	for k, v := range tags {
+		if r.Tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recording) contains(query string) bool {
+	query = strings.ToLower(query)
+	candidates := []string{r.ID, r.Workspace, r.Upstream}
+	for _, v := range r.Tags {
+		candidates = append(candidates, v)
+	}
+	for _, candidate := range candidates {
+		if strings.Contains(strings.ToLower(candidate), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func getRecordingsDir(dir string) (string, error) {
+	metaDir, err := EnsureMetadataDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(metaDir, recordingsDirName), nil
+}
+
+func getRecordingDir(dir string, id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid recording ID: %s", id)
+	}
+	recordingsDir, err := getRecordingsDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(recordingsDir, id), nil
+}
+
+func loadRecording(recordingDir string) (*Recording, error) {
+	metaFilePath := filepath.Join(recordingDir, recordingMetaFileName)
+	j, err := os.ReadFile(metaFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording file: %s: %s", metaFilePath, err)
+	}
+	var r Recording
+	if err = json.Unmarshal(j, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall recording file: %s: %s", metaFilePath, err)
+	}
+	return &r, nil
+}
+
+func saveRecording(recordingDir string, r *Recording) error {
+	metaFilePath := filepath.Join(recordingDir, recordingMetaFileName)
+	j, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshall recording: %s", err)
+	}
+	if err = os.WriteFile(metaFilePath, j, 0644); err != nil {
+		return fmt.Errorf("failed to save recording to: %s: %s", metaFilePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRecording(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, "shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, filesDir, err := NewRecording(dir, "https://api.example.com/v1", map[string]string{TagScenario: "happy-path"})
+	if err != nil {
+		t.Fatalf("NewRecording() error = %v", err)
+	}
+	if r.Workspace != "shared" {
+		t.Errorf("NewRecording() workspace = %v, want shared", r.Workspace)
+	}
+	wantTags := map[string]string{
+		TagService:  "api.example.com",
+		TagDate:     r.Created.Format(time.DateOnly),
+		TagScenario: "happy-path",
+	}
+	if !reflect.DeepEqual(r.Tags, wantTags) {
+		t.Errorf("NewRecording() tags = %v, want %v", r.Tags, wantTags)
+	}
+	if info, err := os.Stat(filesDir); err != nil || !info.IsDir() {
+		t.Errorf("expected files directory to be created: %s, stat error: %v", filesDir, err)
+	}
+}
+
+func TestNewRecording_sameSecond(t *testing.T) {
+	dir := t.TempDir()
+	ids := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		r, _, err := NewRecording(dir, "http://localhost:8080", nil)
+		if err != nil {
+			t.Fatalf("NewRecording() error = %v", err)
+		}
+		if ids[r.ID] {
+			t.Errorf("NewRecording() returned duplicate ID: %s", r.ID)
+		}
+		ids[r.ID] = true
+	}
+	recordings, err := ListRecordings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 3 {
+		t.Errorf("ListRecordings() returned %d recordings, want 3", len(recordings))
+	}
+}
+
+func TestSearchRecordings(t *testing.T) {
+	dir := t.TempDir()
+	for _, rec := range []struct {
+		upstream string
+		tags     map[string]string
+	}{
+		{upstream: "https://orders.example.com", tags: map[string]string{TagScenario: "happy-path"}},
+		{upstream: "https://orders.example.com", tags: map[string]string{TagScenario: "out-of-stock"}},
+		{upstream: "https://payments.example.com", tags: map[string]string{TagScenario: "happy-path"}},
+	} {
+		if _, _, err := NewRecording(dir, rec.upstream, rec.tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		tags          map[string]string
+		wantUpstreams []string
+	}{
+		{
+			name:          "all, oldest first",
+			wantUpstreams: []string{"https://orders.example.com", "https://orders.example.com", "https://payments.example.com"},
+		},
+		{
+			name:          "by tag",
+			tags:          map[string]string{TagScenario: "happy-path"},
+			wantUpstreams: []string{"https://orders.example.com", "https://payments.example.com"},
+		},
+		{
+			name:          "by tags",
+			tags:          map[string]string{TagService: "orders.example.com", TagScenario: "happy-path"},
+			wantUpstreams: []string{"https://orders.example.com"},
+		},
+		{
+			name:          "by query, ignoring case",
+			query:         "PAYMENTS",
+			wantUpstreams: []string{"https://payments.example.com"},
+		},
+		{
+			name:          "by query matching a tag value",
+			query:         "stock",
+			wantUpstreams: []string{"https://orders.example.com"},
+		},
+		{
+			name:  "no match",
+			query: "inventory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordings, err := SearchRecordings(dir, tt.query, tt.tags)
+			if err != nil {
+				t.Fatalf("SearchRecordings() error = %v", err)
+			}
+			var upstreams []string
+			for _, r := range recordings {
+				upstreams = append(upstreams, r.Upstream)
+			}
+			if !reflect.DeepEqual(upstreams, tt.wantUpstreams) {
+				t.Errorf("SearchRecordings() upstreams = %v, want %v", upstreams, tt.wantUpstreams)
+			}
+		})
+	}
+}
+
+func TestListRecordings_empty(t *testing.T) {
+	recordings, err := ListRecordings(t.TempDir())
+	if err != nil {
+		t.Fatalf("ListRecordings() error = %v", err)
+	}
+	if len(recordings) != 0 {
+		t.Errorf("ListRecordings() = %v, want none", recordings)
+	}
+}
+
+func TestRestoreRecording(t *testing.T) {
+	dir := t.TempDir()
+	r, filesDir, err := NewRecording(dir, "http://localhost:8080", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(filesDir, "responses"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"localhost-8080-config.yaml": "plugin: rest\n",
+		"responses/GET-index.json":   `{"ok": true}`,
+	} {
+		if err := os.WriteFile(filepath.Join(filesDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dest := t.TempDir()
+	restored, err := RestoreRecording(dir, r.ID, dest)
+	if err != nil {
+		t.Fatalf("RestoreRecording() error = %v", err)
+	}
+	if restored.ID != r.ID {
+		t.Errorf("RestoreRecording() ID = %v, want %v", restored.ID, r.ID)
+	}
+	assertFileContent(t, filepath.Join(dest, "localhost-8080-config.yaml"), "plugin: rest\n")
+	assertFileContent(t, filepath.Join(dest, "responses", "GET-index.json"), `{"ok": true}`)
+
+	for _, id := range []string{"missing", "../recordings", ""} {
+		if _, err := RestoreRecording(dir, id, dest); err == nil {
+			t.Errorf("expected error restoring recording %q", id)
+		}
+	}
+}
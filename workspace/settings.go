@@ -0,0 +1,128 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SettingEngineType    = "engineType"
+	SettingEngineVersion = "engineVersion"
+	SettingPort          = "port"
+	SettingGitUrl        = "gitUrl"
+	SettingGitRef        = "gitRef"
+
+	// SettingEnvPrefix prefixes the name of an environment variable,
+	// such as 'env.IMPOSTER_LOG_LEVEL'.
+	SettingEnvPrefix = "env."
+)
+
+var SettingKeys = []string{
+	SettingEngineType,
+	SettingEngineVersion,
+	SettingPort,
+	SettingGitUrl,
+	SettingGitRef,
+	SettingEnvPrefix + "NAME",
+}
+
+// SetSetting sets the value of a setting of the active workspace.
+// An empty value removes the setting.
+func SetSetting(dir string, key string, value string) (*Workspace, error) {
+	active, m, err := GetActiveWithMetadata(dir)
+	if err != nil {
+		return nil, err
+	} else if active == nil {
+		return nil, fmt.Errorf("no active workspace")
+	}
+
+	switch {
+	case key == SettingEngineType:
+		active.EngineType = value
+	case key == SettingEngineVersion:
+		active.EngineVersion = value
+	case key == SettingPort:
+		port := 0
+		if value != "" {
+			if port, err = strconv.Atoi(value); err != nil || port < 0 {
+				return nil, fmt.Errorf("invalid port: %s", value)
+			}
+		}
+		active.Port = port
+	case key == SettingGitUrl:
+		if err = validateGitArg(key, value); err != nil {
+			return nil, err
+		}
+		active.GitUrl = value
+	case key == SettingGitRef:
+		if err = validateGitArg(key, value); err != nil {
+			return nil, err
+		}
+		active.GitRef = value
+	case strings.HasPrefix(key, SettingEnvPrefix) && len(key) > len(SettingEnvPrefix):
+		name := strings.TrimPrefix(key, SettingEnvPrefix)
+		if value == "" {
+			delete(active.Environment, name)
+		} else {
+			if active.Environment == nil {
+				active.Environment = make(map[string]string)
+			}
+			active.Environment[name] = value
+		}
+	default:
+		return nil, fmt.Errorf("unsupported setting: %s", key)
+	}
+
+	if err = SaveMetadata(dir, m); err != nil {
+		return nil, err
+	}
+	logger.Tracef("set %s for workspace: %s", key, active.Name)
+	return active, nil
+}
+
+// GetSettings returns the settings of the workspace that are set,
+// as key-value pairs, sorted by key.
+func GetSettings(w *Workspace) [][]string {
+	var settings [][]string
+	if w.EngineType != "" {
+		settings = append(settings, []string{SettingEngineType, w.EngineType})
+	}
+	if w.EngineVersion != "" {
+		settings = append(settings, []string{SettingEngineVersion, w.EngineVersion})
+	}
+	if w.Port != 0 {
+		settings = append(settings, []string{SettingPort, strconv.Itoa(w.Port)})
+	}
+	if w.GitUrl != "" {
+		settings = append(settings, []string{SettingGitUrl, w.GitUrl})
+	}
+	if w.GitRef != "" {
+		settings = append(settings, []string{SettingGitRef, w.GitRef})
+	}
+	var envNames []string
+	for name := range w.Environment {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		settings = append(settings, []string{SettingEnvPrefix + name, w.Environment[name]})
+	}
+	return settings
+}
+
+// FindActive returns the active workspace in dir, or nil if dir has no
+// workspace metadata or active workspace. Unlike GetActive, it does not
+// create the metadata directory.
+func FindActive(dir string) (*Workspace, error) {
+	if _, err := os.Stat(filepath.Join(dir, metaDirName, metaFileName)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to stat workspace file in: %s: %s", dir, err)
+	}
+	return GetActive(dir)
+}
@@ -0,0 +1,74 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetSetting(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{name: "engine type", key: SettingEngineType, value: "jvm"},
+		{name: "engine version", key: SettingEngineVersion, value: "4.2.0"},
+		{name: "port", key: SettingPort, value: "8081"},
+		{name: "invalid port", key: SettingPort, value: "abc", wantErr: true},
+		{name: "env var", key: "env.IMPOSTER_LOG_LEVEL", value: "DEBUG"},
+		{name: "env var with options", key: "env.JAVA_OPTS", value: "-Dfoo=bar"},
+		{name: "missing env var name", key: "env.", value: "x", wantErr: true},
+		{name: "unsupported", key: "colour", value: "blue", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SetSetting(dir, tt.key, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("SetSetting() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	active, err := FindActive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"engineType", "jvm"},
+		{"engineVersion", "4.2.0"},
+		{"port", "8081"},
+		{"env.IMPOSTER_LOG_LEVEL", "DEBUG"},
+		{"env.JAVA_OPTS", "-Dfoo=bar"},
+	}
+	if got := GetSettings(active); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSettings() = %v, want %v", got, want)
+	}
+
+	for _, key := range []string{SettingPort, "env.JAVA_OPTS"} {
+		if _, err := SetSetting(dir, key, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	active, _ = FindActive(dir)
+	if active.Port != 0 || len(active.Environment) != 1 {
+		t.Errorf("settings after unset = %+v, want port and JAVA_OPTS removed", active)
+	}
+}
+
+func TestFindActive_noMetadata(t *testing.T) {
+	dir := t.TempDir()
+	active, err := FindActive(dir)
+	if err != nil || active != nil {
+		t.Errorf("FindActive() = %v, %v, want nil", active, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, metaDirName)); !os.IsNotExist(err) {
+		t.Errorf("FindActive() created metadata directory")
+	}
+}
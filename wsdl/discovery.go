@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsdl
+
+import (
+	"encoding/xml"
+	"gatehill.io/imposter/fileutil"
+	"gatehill.io/imposter/logging"
+	"os"
+	"path/filepath"
+)
+
+var logger = logging.GetLogger()
+
+// DiscoverWsdlFiles finds WSDL 1.1 and 2.0 documents within the given
+// directory. It returns fully qualified paths to the files discovered.
+func DiscoverWsdlFiles(configDir string) []string {
+	var wsdlFiles []string
+
+	candidates := fileutil.FindFilesWithExtension(configDir, ".wsdl", ".xml")
+	for _, candidate := range candidates {
+		fullyQualifiedPath := filepath.Join(configDir, candidate)
+		isWsdl, err := isWsdlDocument(fullyQualifiedPath)
+		if err != nil {
+			logger.Warnf("skipping unreadable file: %v: %v", fullyQualifiedPath, err)
+			continue
+		}
+		if isWsdl {
+			wsdlFiles = append(wsdlFiles, fullyQualifiedPath)
+		}
+	}
+
+	return wsdlFiles
+}
+
+// isWsdlDocument checks the root element of the file, so XML files
+// that are not WSDL documents, such as sample responses, are ignored.
+func isWsdlDocument(filePath string) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	root, err := readRootElement(xml.NewDecoder(f))
+	if err != nil {
+		return false, nil
+	}
+	return (root.Space == wsdl11Namespace && root.Local == "definitions") ||
+		(root.Space == wsdl20Namespace && root.Local == "description"), nil
+}
+
+func readRootElement(decoder *xml.Decoder) (xml.Name, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"gatehill.io/imposter/stringutil"
+	"os"
+	"strings"
+)
+
+const (
+	wsdl11Namespace       = "http://schemas.xmlsoap.org/wsdl/"
+	wsdl11Soap11Namespace = "http://schemas.xmlsoap.org/wsdl/soap/"
+	wsdl11Soap12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	wsdl20Namespace       = "http://www.w3.org/ns/wsdl"
+	wsdl20SoapNamespace   = "http://www.w3.org/ns/wsdl/soap"
+)
+
+type SoapVersion string
+
+const (
+	Soap11 SoapVersion = "1.1"
+	Soap12 SoapVersion = "1.2"
+)
+
+// Document is the subset of a WSDL document needed to generate
+// configuration for the soap plugin.
+type Document struct {
+	TargetNamespace string
+	Bindings        []Binding
+	schemas         *schemaSet
+}
+
+type Binding struct {
+	Name        string
+	SoapVersion SoapVersion
+	Operations  []Operation
+}
+
+type Operation struct {
+	Name       string
+	SoapAction string
+
+	// rpc is true for RPC style operations, where the output parts
+	// are wrapped in an element named after the operation.
+	rpc           bool
+	bodyNamespace string
+	outputParts   []messagePart
+}
+
+// messagePart is described either by a global element, for document
+// style operations, or by a type, for RPC style operations.
+type messagePart struct {
+	name    string
+	element xml.Name
+	typ     xml.Name
+}
+
+type wsdlTypes struct {
+	Schemas []xsdSchema `xml:"http://www.w3.org/2001/XMLSchema schema"`
+}
+
+type wsdl11Definitions struct {
+	TargetNamespace string           `xml:"targetNamespace,attr"`
+	Attrs           []xml.Attr       `xml:",any,attr"`
+	Types           wsdlTypes        `xml:"types"`
+	Messages        []wsdl11Message  `xml:"message"`
+	PortTypes       []wsdl11PortType `xml:"portType"`
+	Bindings        []wsdl11Binding  `xml:"http://schemas.xmlsoap.org/wsdl/ binding"`
+}
+
+type wsdl11Message struct {
+	Name  string `xml:"name,attr"`
+	Parts []struct {
+		Name    string `xml:"name,attr"`
+		Element string `xml:"element,attr"`
+		Type    string `xml:"type,attr"`
+	} `xml:"part"`
+}
+
+type wsdl11PortType struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name   string `xml:"name,attr"`
+		Output struct {
+			Message string `xml:"message,attr"`
+		} `xml:"output"`
+	} `xml:"operation"`
+}
+
+type wsdl11Binding struct {
+	Name       string                   `xml:"name,attr"`
+	Type       string                   `xml:"type,attr"`
+	Soap11     *wsdl11SoapBinding       `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	Soap12     *wsdl11SoapBinding       `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations []wsdl11BindingOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+type wsdl11SoapBinding struct {
+	Style string `xml:"style,attr"`
+}
+
+type wsdl11BindingOperation struct {
+	Name   string               `xml:"name,attr"`
+	Soap11 *wsdl11SoapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	Soap12 *wsdl11SoapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+	Output struct {
+		Body *struct {
+			Namespace string `xml:"namespace,attr"`
+			Parts     string `xml:"parts,attr"`
+		} `xml:"body"`
+	} `xml:"output"`
+}
+
+type wsdl11SoapOperation struct {
+	SoapAction string `xml:"soapAction,attr"`
+	Style      string `xml:"style,attr"`
+}
+
+type wsdl20Description struct {
+	TargetNamespace string            `xml:"targetNamespace,attr"`
+	Attrs           []xml.Attr        `xml:",any,attr"`
+	Types           wsdlTypes         `xml:"types"`
+	Interfaces      []wsdl20Interface `xml:"interface"`
+	Bindings        []wsdl20Binding   `xml:"binding"`
+}
+
+type wsdl20Interface struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name   string `xml:"name,attr"`
+		Output struct {
+			Element string `xml:"element,attr"`
+		} `xml:"output"`
+	} `xml:"operation"`
+}
+
+type wsdl20Binding struct {
+	Name       string `xml:"name,attr"`
+	Interface  string `xml:"interface,attr"`
+	Type       string `xml:"type,attr"`
+	Version    string `xml:"http://www.w3.org/ns/wsdl/soap version,attr"`
+	Operations []struct {
+		Ref    string `xml:"ref,attr"`
+		Action string `xml:"http://www.w3.org/ns/wsdl/soap action,attr"`
+	} `xml:"operation"`
+}
+
+// Parse reads the SOAP bindings, their operations and the embedded
+// schemas from a WSDL 1.1 or 2.0 document. Bindings that do not use
+// SOAP, such as HTTP bindings, are ignored.
+func Parse(wsdlFilePath string) (*Document, error) {
+	content, err := os.ReadFile(wsdlFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading WSDL file: %v: %v", wsdlFilePath, err)
+	}
+	root, err := readRootElement(xml.NewDecoder(bytes.NewReader(content)))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing WSDL file: %v: %v", wsdlFilePath, err)
+	}
+
+	var document *Document
+	switch {
+	case root.Space == wsdl11Namespace && root.Local == "definitions":
+		var definitions wsdl11Definitions
+		if err = xml.Unmarshal(content, &definitions); err == nil {
+			document = parseWsdl11(definitions)
+		}
+	case root.Space == wsdl20Namespace && root.Local == "description":
+		var description wsdl20Description
+		if err = xml.Unmarshal(content, &description); err == nil {
+			document = parseWsdl20(description)
+		}
+	default:
+		return nil, fmt.Errorf("not a WSDL document: %v", wsdlFilePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing WSDL file: %v: %v", wsdlFilePath, err)
+	}
+	return document, nil
+}
+
+func parseWsdl11(definitions wsdl11Definitions) *Document {
+	scope := newNamespaces(nil, definitions.Attrs)
+	document := &Document{
+		TargetNamespace: definitions.TargetNamespace,
+		schemas:         newSchemaSet(definitions.Types.Schemas, scope),
+	}
+
+	messages := make(map[string]wsdl11Message)
+	for _, message := range definitions.Messages {
+		messages[message.Name] = message
+	}
+	portTypes := make(map[string]wsdl11PortType)
+	for _, portType := range definitions.PortTypes {
+		portTypes[portType.Name] = portType
+	}
+
+	for _, wsdlBinding := range definitions.Bindings {
+		var soapVersion SoapVersion
+		var soapBinding *wsdl11SoapBinding
+		if wsdlBinding.Soap11 != nil {
+			soapVersion, soapBinding = Soap11, wsdlBinding.Soap11
+		} else if wsdlBinding.Soap12 != nil {
+			soapVersion, soapBinding = Soap12, wsdlBinding.Soap12
+		} else {
+			logger.Debugf("skipping non-SOAP binding: %s", wsdlBinding.Name)
+			continue
+		}
+		portType := portTypes[scope.resolve(wsdlBinding.Type).Local]
+
+		binding := Binding{Name: wsdlBinding.Name, SoapVersion: soapVersion}
+		for _, bindingOperation := range wsdlBinding.Operations {
+			operation := Operation{
+				Name:          bindingOperation.Name,
+				bodyNamespace: definitions.TargetNamespace,
+			}
+			style := soapBinding.Style
+			soapOperation := bindingOperation.Soap11
+			if soapOperation == nil {
+				soapOperation = bindingOperation.Soap12
+			}
+			if soapOperation != nil {
+				operation.SoapAction = soapOperation.SoapAction
+				if soapOperation.Style != "" {
+					style = soapOperation.Style
+				}
+			}
+			operation.rpc = style == "rpc"
+
+			var partNames []string
+			if body := bindingOperation.Output.Body; body != nil {
+				if body.Namespace != "" {
+					operation.bodyNamespace = body.Namespace
+				}
+				partNames = strings.Fields(body.Parts)
+			}
+			for _, portTypeOperation := range portType.Operations {
+				if portTypeOperation.Name != bindingOperation.Name {
+					continue
+				}
+				message := messages[scope.resolve(portTypeOperation.Output.Message).Local]
+				for _, part := range message.Parts {
+					if len(partNames) > 0 && !stringutil.Contains(partNames, part.Name) {
+						continue
+					}
+					outputPart := messagePart{name: part.Name}
+					if part.Element != "" {
+						outputPart.element = scope.resolve(part.Element)
+					} else if part.Type != "" {
+						outputPart.typ = scope.resolve(part.Type)
+					}
+					operation.outputParts = append(operation.outputParts, outputPart)
+				}
+				break
+			}
+			binding.Operations = append(binding.Operations, operation)
+		}
+		document.Bindings = append(document.Bindings, binding)
+	}
+	return document
+}
+
+func parseWsdl20(description wsdl20Description) *Document {
+	scope := newNamespaces(nil, description.Attrs)
+	document := &Document{
+		TargetNamespace: description.TargetNamespace,
+		schemas:         newSchemaSet(description.Types.Schemas, scope),
+	}
+
+	interfaces := make(map[string]wsdl20Interface)
+	for _, wsdlInterface := range description.Interfaces {
+		interfaces[wsdlInterface.Name] = wsdlInterface
+	}
+
+	for _, wsdlBinding := range description.Bindings {
+		if wsdlBinding.Type != wsdl20SoapNamespace {
+			logger.Debugf("skipping non-SOAP binding: %s", wsdlBinding.Name)
+			continue
+		}
+		soapVersion := Soap12
+		if wsdlBinding.Version == string(Soap11) {
+			soapVersion = Soap11
+		}
+		actions := make(map[string]string)
+		for _, bindingOperation := range wsdlBinding.Operations {
+			actions[scope.resolve(bindingOperation.Ref).Local] = bindingOperation.Action
+		}
+
+		binding := Binding{Name: wsdlBinding.Name, SoapVersion: soapVersion}
+		for _, interfaceOperation := range interfaces[scope.resolve(wsdlBinding.Interface).Local].Operations {
+			operation := Operation{
+				Name:          interfaceOperation.Name,
+				SoapAction:    actions[interfaceOperation.Name],
+				bodyNamespace: description.TargetNamespace,
+			}
+			if element := interfaceOperation.Output.Element; element != "" && !strings.HasPrefix(element, "#") {
+				operation.outputParts = []messagePart{{element: scope.resolve(element)}}
+			}
+			binding.Operations = append(binding.Operations, operation)
+		}
+		document.Bindings = append(document.Bindings, binding)
+	}
+	return document
+}
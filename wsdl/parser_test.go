@@ -0,0 +1,174 @@
+package wsdl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const petstoreWsdl = `<?xml version="1.0" encoding="UTF-8"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+                  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+                  xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:tns="urn:com:example:petstore"
+                  targetNamespace="urn:com:example:petstore">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:com:example:petstore" elementFormDefault="qualified">
+      <xs:complexType name="petType">
+        <xs:sequence>
+          <xs:element name="id" type="xs:int"/>
+          <xs:element name="name" type="xs:string"/>
+          <xs:element name="status" type="tns:statusType"/>
+          <xs:element name="parent" type="tns:petType" minOccurs="0"/>
+        </xs:sequence>
+      </xs:complexType>
+      <xs:simpleType name="statusType">
+        <xs:restriction base="xs:string">
+          <xs:enumeration value="available"/>
+          <xs:enumeration value="sold"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:element name="getPetByIdRequest">
+        <xs:complexType>
+          <xs:sequence>
+            <xs:element name="id" type="xs:int"/>
+          </xs:sequence>
+        </xs:complexType>
+      </xs:element>
+      <xs:element name="getPetByIdResponse" type="tns:petType"/>
+    </xs:schema>
+  </wsdl:types>
+  <wsdl:message name="getPetByIdRequest">
+    <wsdl:part name="parameters" element="tns:getPetByIdRequest"/>
+  </wsdl:message>
+  <wsdl:message name="getPetByIdResponse">
+    <wsdl:part name="parameters" element="tns:getPetByIdResponse"/>
+  </wsdl:message>
+  <wsdl:portType name="PetPortType">
+    <wsdl:operation name="getPetById">
+      <wsdl:input message="tns:getPetByIdRequest"/>
+      <wsdl:output message="tns:getPetByIdResponse"/>
+    </wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="SoapBinding" type="tns:PetPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="getPetById">
+      <soap:operation soapAction="getPetById" style="document"/>
+      <wsdl:input><soap:body use="literal"/></wsdl:input>
+      <wsdl:output><soap:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:binding name="Soap12Binding" type="tns:PetPortType">
+    <soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="getPetById">
+      <soap12:operation soapAction="getPetById"/>
+      <wsdl:input><soap12:body use="literal"/></wsdl:input>
+      <wsdl:output><soap12:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+</wsdl:definitions>
+`
+
+func writeTestFile(t *testing.T, dir string, name string, content string) string {
+	filePath := filepath.Join(dir, name)
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestDiscoverWsdlFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "petstore.wsdl", petstoreWsdl)
+	writeTestFile(t, dir, "response.xml", `<pet><id>1</id></pet>`)
+
+	got := DiscoverWsdlFiles(dir)
+	if len(got) != 1 || filepath.Base(got[0]) != "petstore.wsdl" {
+		t.Errorf("DiscoverWsdlFiles() = %v, want [petstore.wsdl]", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	document, err := Parse(writeTestFile(t, t.TempDir(), "petstore.wsdl", petstoreWsdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Bindings) != 2 {
+		t.Fatalf("expected 2 bindings, got %d", len(document.Bindings))
+	}
+	wantVersions := []SoapVersion{Soap11, Soap12}
+	for i, binding := range document.Bindings {
+		if binding.SoapVersion != wantVersions[i] {
+			t.Errorf("binding %s version = %v, want %v", binding.Name, binding.SoapVersion, wantVersions[i])
+		}
+		if len(binding.Operations) != 1 || binding.Operations[0].Name != "getPetById" || binding.Operations[0].SoapAction != "getPetById" {
+			t.Errorf("unexpected operations for binding %s: %+v", binding.Name, binding.Operations)
+		}
+	}
+}
+
+func TestDocument_SampleResponse(t *testing.T) {
+	document, err := Parse(writeTestFile(t, t.TempDir(), "petstore.wsdl", petstoreWsdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := document.Bindings[0]
+	got := string(document.SampleResponse(binding, binding.Operations[0]))
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header/>
+  <soap:Body>
+    <getPetByIdResponse xmlns="urn:com:example:petstore">
+      <id>0</id>
+      <name>string</name>
+      <status>available</status>
+      <parent/>
+    </getPetByIdResponse>
+  </soap:Body>
+</soap:Envelope>
+`
+	if got != want {
+		t.Errorf("SampleResponse() = %v, want %v", got, want)
+	}
+
+	soap12 := document.Bindings[1]
+	if got := string(document.SampleResponse(soap12, soap12.Operations[0])); !strings.Contains(got, soap12EnvelopeNamespace) {
+		t.Errorf("expected SOAP 1.2 envelope, got %v", got)
+	}
+}
+
+func TestDocument_SampleResponse_rpc(t *testing.T) {
+	rpcWsdl := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+             xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+             xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+             xmlns:tns="urn:example:stock" targetNamespace="urn:example:stock">
+  <message name="GetPriceResponse">
+    <part name="price" type="xsd:decimal"/>
+  </message>
+  <portType name="StockPortType">
+    <operation name="GetPrice"><output message="tns:GetPriceResponse"/></operation>
+  </portType>
+  <binding name="StockBinding" type="tns:StockPortType">
+    <soap:binding style="rpc" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetPrice">
+      <soap:operation soapAction="urn:GetPrice"/>
+      <output><soap:body use="literal" namespace="urn:example:stock:rpc"/></output>
+    </operation>
+  </binding>
+</definitions>`
+	document, err := Parse(writeTestFile(t, t.TempDir(), "stock.wsdl", rpcWsdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := document.Bindings[0]
+	got := string(document.SampleResponse(binding, binding.Operations[0]))
+	want := `    <GetPriceResponse xmlns="urn:example:stock:rpc">
+      <price xmlns="">0.0</price>
+    </GetPriceResponse>
+`
+	if !strings.Contains(got, want) {
+		t.Errorf("SampleResponse() = %v, want body %v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	soap11EnvelopeNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNamespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// SampleResponse generates a SOAP envelope containing a sample output
+// message for the operation, populated from the schema types.
+func (d *Document) SampleResponse(binding Binding, operation Operation) []byte {
+	envelopeNamespace := soap11EnvelopeNamespace
+	if binding.SoapVersion == Soap12 {
+		envelopeNamespace = soap12EnvelopeNamespace
+	}
+
+	w := &sampleWriter{schemas: d.schemas, visiting: make(map[*xsdComplexType]bool)}
+	w.buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	w.line(0, "<soap:Envelope xmlns:soap=\""+escape(envelopeNamespace)+"\">")
+	w.line(1, "<soap:Header/>")
+	w.line(1, "<soap:Body>")
+	if operation.rpc {
+		wrapper := operation.Name + "Response"
+		if len(operation.outputParts) == 0 {
+			w.line(2, "<"+tag(wrapper, operation.bodyNamespace, "")+"/>")
+		} else {
+			w.line(2, "<"+tag(wrapper, operation.bodyNamespace, "")+">")
+			w.writeParts(operation.outputParts, 3, operation.bodyNamespace)
+			w.line(2, "</"+wrapper+">")
+		}
+	} else {
+		w.writeParts(operation.outputParts, 2, "")
+	}
+	w.line(1, "</soap:Body>")
+	w.line(0, "</soap:Envelope>")
+	return []byte(w.buf.String())
+}
+
+type sampleWriter struct {
+	schemas *schemaSet
+	buf     strings.Builder
+
+	// visiting holds the complex types being written, so recursive
+	// types are written once rather than indefinitely.
+	visiting map[*xsdComplexType]bool
+}
+
+func (w *sampleWriter) writeParts(parts []messagePart, depth int, parentNamespace string) {
+	for _, part := range parts {
+		if part.element.Local != "" {
+			if decl, found := w.schemas.element(part.element); found {
+				w.writeElement(decl.element, decl.schema, true, depth, parentNamespace)
+			} else {
+				logger.Debugf("element not found in WSDL schemas: %v", part.element)
+				w.line(depth, "<"+tag(part.element.Local, part.element.Space, parentNamespace)+"/>")
+			}
+		} else {
+			// RPC style parts are unqualified
+			w.writeTyped(part.name, "", part.typ, depth, parentNamespace)
+		}
+	}
+}
+
+func (w *sampleWriter) writeElement(element *xsdElement, schema *schemaContext, global bool, depth int, parentNamespace string) {
+	if element.Ref != "" {
+		if decl, found := w.schemas.element(schema.namespaces.resolve(element.Ref)); found {
+			w.writeElement(decl.element, decl.schema, true, depth, parentNamespace)
+		}
+		return
+	}
+
+	var namespace string
+	if global || element.Form == "qualified" || (schema.qualified && element.Form != "unqualified") {
+		namespace = schema.targetNamespace
+	}
+
+	switch {
+	case element.ComplexType != nil:
+		w.writeComplex(element.Name, namespace, element.ComplexType, schema, depth, parentNamespace)
+	case element.SimpleType != nil:
+		w.writeSimple(element.Name, namespace, w.simpleTypeSample(element.SimpleType, schema), depth, parentNamespace)
+	case element.Type != "":
+		w.writeTyped(element.Name, namespace, schema.namespaces.resolve(element.Type), depth, parentNamespace)
+	default:
+		w.writeSimple(element.Name, namespace, "", depth, parentNamespace)
+	}
+}
+
+func (w *sampleWriter) writeTyped(name string, namespace string, typeName xml.Name, depth int, parentNamespace string) {
+	if typeName.Space != xsdNamespace {
+		if decl, found := w.schemas.complexType(typeName); found {
+			w.writeComplex(name, namespace, decl.complexType, decl.schema, depth, parentNamespace)
+			return
+		}
+	}
+	w.writeSimple(name, namespace, w.typeSample(typeName), depth, parentNamespace)
+}
+
+func (w *sampleWriter) writeComplex(name string, namespace string, complexType *xsdComplexType, schema *schemaContext, depth int, parentNamespace string) {
+	if w.visiting[complexType] {
+		w.writeSimple(name, namespace, "", depth, parentNamespace)
+		return
+	}
+	w.visiting[complexType] = true
+	defer delete(w.visiting, complexType)
+
+	if complexType.SimpleContent != nil {
+		derivation := complexType.SimpleContent.Extension
+		if derivation == nil {
+			derivation = complexType.SimpleContent.Restriction
+		}
+		var value string
+		if derivation != nil {
+			value = w.typeSample(schema.namespaces.resolve(derivation.Base))
+		}
+		w.writeSimple(name, namespace, value, depth, parentNamespace)
+		return
+	}
+
+	children := w.childrenOf(complexType, schema)
+	if len(children) == 0 {
+		w.writeSimple(name, namespace, "", depth, parentNamespace)
+		return
+	}
+	w.line(depth, "<"+tag(name, namespace, parentNamespace)+">")
+	for _, child := range children {
+		w.writeElement(child.element, child.schema, false, depth+1, namespace)
+	}
+	w.line(depth, "</"+name+">")
+}
+
+// childrenOf returns the child elements of a complex type, including
+// those inherited from the base type of an extension.
+func (w *sampleWriter) childrenOf(complexType *xsdComplexType, schema *schemaContext) []elementDecl {
+	var children []elementDecl
+	if content := complexType.ComplexContent; content != nil {
+		if derivation := content.Extension; derivation != nil {
+			if base, found := w.schemas.complexType(schema.namespaces.resolve(derivation.Base)); found && !w.visiting[base.complexType] {
+				w.visiting[base.complexType] = true
+				children = append(children, w.childrenOf(base.complexType, base.schema)...)
+				delete(w.visiting, base.complexType)
+			}
+			children = append(children, particles(derivation.Sequence, derivation.All, derivation.Choice, schema)...)
+		} else if derivation := content.Restriction; derivation != nil {
+			children = append(children, particles(derivation.Sequence, derivation.All, derivation.Choice, schema)...)
+		}
+	}
+	return append(children, particles(complexType.Sequence, complexType.All, complexType.Choice, schema)...)
+}
+
+func particles(sequence *xsdGroup, all *xsdGroup, choice *xsdGroup, schema *schemaContext) []elementDecl {
+	var elements []elementDecl
+	if sequence != nil {
+		elements = append(elements, groupElements(*sequence, schema)...)
+	}
+	if all != nil {
+		elements = append(elements, groupElements(*all, schema)...)
+	}
+	if choice != nil {
+		elements = append(elements, choiceElements(*choice, schema)...)
+	}
+	return elements
+}
+
+func groupElements(group xsdGroup, schema *schemaContext) []elementDecl {
+	var elements []elementDecl
+	for i := range group.Elements {
+		elements = append(elements, elementDecl{element: &group.Elements[i], schema: schema})
+	}
+	for _, sequence := range group.Sequences {
+		elements = append(elements, groupElements(sequence, schema)...)
+	}
+	for _, choice := range group.Choices {
+		elements = append(elements, choiceElements(choice, schema)...)
+	}
+	return elements
+}
+
+// choiceElements returns the first option of a choice.
+func choiceElements(choice xsdGroup, schema *schemaContext) []elementDecl {
+	if len(choice.Elements) > 0 {
+		return []elementDecl{{element: &choice.Elements[0], schema: schema}}
+	} else if len(choice.Sequences) > 0 {
+		return groupElements(choice.Sequences[0], schema)
+	}
+	return nil
+}
+
+func (w *sampleWriter) typeSample(typeName xml.Name) string {
+	if typeName.Space != xsdNamespace {
+		if decl, found := w.schemas.simpleType(typeName); found {
+			return w.simpleTypeSample(decl.simpleType, decl.schema)
+		}
+	}
+	return builtinSample(typeName.Local)
+}
+
+// simpleTypeSample uses the first enumerated value, if any, otherwise
+// a sample of the base type.
+func (w *sampleWriter) simpleTypeSample(simpleType *xsdSimpleType, schema *schemaContext) string {
+	if restriction := simpleType.Restriction; restriction != nil {
+		if len(restriction.Enumerations) > 0 {
+			return restriction.Enumerations[0].Value
+		}
+		return w.typeSample(schema.namespaces.resolve(restriction.Base))
+	} else if simpleType.List != nil {
+		return w.typeSample(schema.namespaces.resolve(simpleType.List.ItemType))
+	}
+	return builtinSample("string")
+}
+
+func builtinSample(typeName string) string {
+	switch typeName {
+	case "boolean":
+		return "false"
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "nonPositiveInteger",
+		"unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+		return "0"
+	case "positiveInteger":
+		return "1"
+	case "negativeInteger":
+		return "-1"
+	case "decimal", "float", "double":
+		return "0.0"
+	case "date":
+		return "2000-01-01"
+	case "dateTime":
+		return "2000-01-01T00:00:00Z"
+	case "time":
+		return "00:00:00"
+	case "duration":
+		return "P0D"
+	case "anyType", "base64Binary", "hexBinary":
+		return ""
+	default:
+		return "string"
+	}
+}
+
+func (w *sampleWriter) writeSimple(name string, namespace string, value string, depth int, parentNamespace string) {
+	if value == "" {
+		w.line(depth, "<"+tag(name, namespace, parentNamespace)+"/>")
+	} else {
+		w.line(depth, "<"+tag(name, namespace, parentNamespace)+">"+escape(value)+"</"+name+">")
+	}
+}
+
+func (w *sampleWriter) line(depth int, content string) {
+	w.buf.WriteString(strings.Repeat("  ", depth))
+	w.buf.WriteString(content)
+	w.buf.WriteString("\n")
+}
+
+// tag returns the element name, declaring its namespace as the
+// default if it differs from that of the parent element.
+func tag(name string, namespace string, parentNamespace string) string {
+	if namespace != parentNamespace {
+		return name + " xmlns=\"" + escape(namespace) + "\""
+	}
+	return name
+}
+
+func escape(value string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2024 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsdl
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+type xsdSchema struct {
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Attrs              []xml.Attr       `xml:",any,attr"`
+	Elements           []xsdElement     `xml:"element"`
+	ComplexTypes       []xsdComplexType `xml:"complexType"`
+	SimpleTypes        []xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Ref         string          `xml:"ref,attr"`
+	Form        string          `xml:"form,attr"`
+	ComplexType *xsdComplexType `xml:"complexType"`
+	SimpleType  *xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdComplexType struct {
+	Name           string             `xml:"name,attr"`
+	Sequence       *xsdGroup          `xml:"sequence"`
+	All            *xsdGroup          `xml:"all"`
+	Choice         *xsdGroup          `xml:"choice"`
+	ComplexContent *xsdComplexContent `xml:"complexContent"`
+	SimpleContent  *xsdSimpleContent  `xml:"simpleContent"`
+}
+
+// xsdGroup is a sequence, all or choice model group.
+type xsdGroup struct {
+	Elements  []xsdElement `xml:"element"`
+	Sequences []xsdGroup   `xml:"sequence"`
+	Choices   []xsdGroup   `xml:"choice"`
+}
+
+type xsdComplexContent struct {
+	Extension   *xsdDerivation `xml:"extension"`
+	Restriction *xsdDerivation `xml:"restriction"`
+}
+
+type xsdSimpleContent struct {
+	Extension   *xsdDerivation `xml:"extension"`
+	Restriction *xsdDerivation `xml:"restriction"`
+}
+
+type xsdDerivation struct {
+	Base     string    `xml:"base,attr"`
+	Sequence *xsdGroup `xml:"sequence"`
+	All      *xsdGroup `xml:"all"`
+	Choice   *xsdGroup `xml:"choice"`
+}
+
+type xsdSimpleType struct {
+	Name        string          `xml:"name,attr"`
+	Restriction *xsdRestriction `xml:"restriction"`
+	List        *struct {
+		ItemType string `xml:"itemType,attr"`
+	} `xml:"list"`
+}
+
+type xsdRestriction struct {
+	Base         string `xml:"base,attr"`
+	Enumerations []struct {
+		Value string `xml:"value,attr"`
+	} `xml:"enumeration"`
+}
+
+// namespaces maps prefixes to namespace URIs. The default
+// namespace is held against the empty prefix.
+type namespaces map[string]string
+
+// newNamespaces returns the namespaces in scope for an element, given
+// those of its parent and the attributes declared on the element.
+func newNamespaces(parent namespaces, attrs []xml.Attr) namespaces {
+	scope := namespaces{}
+	for prefix, uri := range parent {
+		scope[prefix] = uri
+	}
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			scope[attr.Name.Local] = attr.Value
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			scope[""] = attr.Value
+		}
+	}
+	return scope
+}
+
+// resolve converts a prefixed name, such as tns:Pet, to a qualified name.
+func (n namespaces) resolve(prefixedName string) xml.Name {
+	prefix, local := "", prefixedName
+	if i := strings.Index(prefixedName, ":"); i >= 0 {
+		prefix, local = prefixedName[:i], prefixedName[i+1:]
+	}
+	return xml.Name{Space: n[prefix], Local: local}
+}
+
+type schemaContext struct {
+	targetNamespace string
+	qualified       bool
+	namespaces      namespaces
+}
+
+type elementDecl struct {
+	element *xsdElement
+	schema  *schemaContext
+}
+
+type complexTypeDecl struct {
+	complexType *xsdComplexType
+	schema      *schemaContext
+}
+
+type simpleTypeDecl struct {
+	simpleType *xsdSimpleType
+	schema     *schemaContext
+}
+
+// schemaSet holds the global declarations of the schemas embedded
+// in a WSDL document. Imported schemas are not resolved.
+type schemaSet struct {
+	elements     map[xml.Name]elementDecl
+	complexTypes map[xml.Name]complexTypeDecl
+	simpleTypes  map[xml.Name]simpleTypeDecl
+}
+
+func newSchemaSet(schemas []xsdSchema, parent namespaces) *schemaSet {
+	set := &schemaSet{
+		elements:     make(map[xml.Name]elementDecl),
+		complexTypes: make(map[xml.Name]complexTypeDecl),
+		simpleTypes:  make(map[xml.Name]simpleTypeDecl),
+	}
+	for i := range schemas {
+		schema := &schemas[i]
+		context := &schemaContext{
+			targetNamespace: schema.TargetNamespace,
+			qualified:       schema.ElementFormDefault == "qualified",
+			namespaces:      newNamespaces(parent, schema.Attrs),
+		}
+		for j := range schema.Elements {
+			name := xml.Name{Space: schema.TargetNamespace, Local: schema.Elements[j].Name}
+			set.elements[name] = elementDecl{element: &schema.Elements[j], schema: context}
+		}
+		for j := range schema.ComplexTypes {
+			name := xml.Name{Space: schema.TargetNamespace, Local: schema.ComplexTypes[j].Name}
+			set.complexTypes[name] = complexTypeDecl{complexType: &schema.ComplexTypes[j], schema: context}
+		}
+		for j := range schema.SimpleTypes {
+			name := xml.Name{Space: schema.TargetNamespace, Local: schema.SimpleTypes[j].Name}
+			set.simpleTypes[name] = simpleTypeDecl{simpleType: &schema.SimpleTypes[j], schema: context}
+		}
+	}
+	return set
+}
+
+// element looks up a global element. If no element exists in the
+// given namespace, such as when a prefix was not declared, the first
+// element with the same local name is used.
+func (s *schemaSet) element(name xml.Name) (elementDecl, bool) {
+	if decl, found := s.elements[name]; found {
+		return decl, true
+	}
+	for candidate, decl := range s.elements {
+		if candidate.Local == name.Local {
+			return decl, true
+		}
+	}
+	return elementDecl{}, false
+}
+
+func (s *schemaSet) complexType(name xml.Name) (complexTypeDecl, bool) {
+	if decl, found := s.complexTypes[name]; found {
+		return decl, true
+	}
+	for candidate, decl := range s.complexTypes {
+		if candidate.Local == name.Local {
+			return decl, true
+		}
+	}
+	return complexTypeDecl{}, false
+}
+
+func (s *schemaSet) simpleType(name xml.Name) (simpleTypeDecl, bool) {
+	if decl, found := s.simpleTypes[name]; found {
+		return decl, true
+	}
+	for candidate, decl := range s.simpleTypes {
+		if candidate.Local == name.Local {
+			return decl, true
+		}
+	}
+	return simpleTypeDecl{}, false
+}